@@ -4,12 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/jcdickinson/ferrisfetch/internal/config"
 	"github.com/jcdickinson/ferrisfetch/internal/daemon"
+	"github.com/jcdickinson/ferrisfetch/internal/db"
+	"github.com/jcdickinson/ferrisfetch/internal/humanize"
 	"github.com/jcdickinson/ferrisfetch/internal/rpc"
 	"github.com/spf13/cobra"
 )
@@ -17,25 +21,90 @@ import (
 var addCmd = &cobra.Command{
 	Use:   "add [crate[@version] ...]",
 	Short: "Index crate documentation from docs.rs",
-	Long:  `Fetch, parse, embed, and index Rust crate documentation. Version defaults to "latest".`,
+	Long: `Fetch, parse, embed, and index Rust crate documentation. Version defaults to "latest".
+
+With --file, index rustdoc JSON you built yourself (cargo +nightly rustdoc --
+-Z unstable-options --output-format json) instead of fetching it. The crate
+name and version are read from the JSON unless given as a single argument.
+
+With --with-deps, the crates' dependencies are indexed too, so links to
+their types resolve. --with-deps=2 also indexes the dependencies'
+dependencies, and so on.
+
+Items matching index.ignore are left out, along with everything nested in
+them. --ignore adds path patterns for these crates, and --ignore-hidden and
+--ignore-deprecated override the config for them; use -f to apply them to a
+crate that is already indexed.`,
 	Example: `  rsdoc add serde
   rsdoc add serde@1.0 tokio@1.0
-  rsdoc add -f serde   # force re-index`,
-	Args: cobra.MinimumNArgs(1),
-	Run:  runAdd,
+  rsdoc add -f serde   # force re-index
+  rsdoc add --registry corp billing@2.1
+  rsdoc add --with-deps axum
+  rsdoc add -f --ignore '*::test_utils' --ignore-deprecated mycrate
+  rsdoc add --max-duration 30s bevy   # finish the rest in the background
+  rsdoc add --file ./target/doc/mycrate.json
+  zstd -dc mycrate.json.zst | rsdoc add --file - my-crate@0.3.0`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if addFile != "" {
+			return cobra.MaximumNArgs(1)(cmd, args)
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
+	Run: runAdd,
 }
 
-var addForce bool
+var (
+	addForce       bool
+	addOverBudget  bool
+	addRegistry    string
+	addMaxDuration time.Duration
+	addFile        string
+	addWithDeps    int
+
+	addIgnore           []string
+	addIgnoreHidden     bool
+	addIgnoreDeprecated bool
+)
 
 func init() {
 	addCmd.Flags().BoolVarP(&addForce, "force", "f", false, "force re-index even if already processed")
+	addCmd.Flags().BoolVar(&addOverBudget, "over-budget", false, "index even over the embedding budget")
+	addCmd.Flags().StringVar(&addRegistry, "registry", "", "fetch from an alternative registry from the config instead of crates.io/docs.rs")
+	addCmd.Flags().DurationVar(&addMaxDuration, "max-duration", 0, "return after this long and keep indexing in the background (0 waits)")
+	addCmd.Flags().IntVar(&addWithDeps, "with-deps", 0, "also index dependencies, to this depth (1 for direct dependencies)")
+	addCmd.Flags().Lookup("with-deps").NoOptDefVal = "1"
+	addCmd.Flags().StringVar(&addFile, "file", "", "index rustdoc JSON (optionally zstd-compressed) from this file, or - for stdin")
+	addCmd.Flags().StringArrayVar(&addIgnore, "ignore", nil, "leave out items whose path matches this pattern, e.g. '*::__private' (repeatable)")
+	addCmd.Flags().BoolVar(&addIgnoreHidden, "ignore-hidden", false, "leave out #[doc(hidden)] items (default from index.ignore.hidden)")
+	addCmd.Flags().BoolVar(&addIgnoreDeprecated, "ignore-deprecated", false, "leave out deprecated items (default from index.ignore.deprecated)")
 }
 
 func runAdd(cmd *cobra.Command, args []string) {
+	var ignore *rpc.IgnoreOptions
+	if len(addIgnore) > 0 || cmd.Flags().Changed("ignore-hidden") || cmd.Flags().Changed("ignore-deprecated") {
+		ignore = &rpc.IgnoreOptions{Patterns: addIgnore}
+		if cmd.Flags().Changed("ignore-hidden") {
+			ignore.Hidden = &addIgnoreHidden
+		}
+		if cmd.Flags().Changed("ignore-deprecated") {
+			ignore.Deprecated = &addIgnoreDeprecated
+		}
+	}
 	var specs []rpc.CrateSpec
 	for _, arg := range args {
 		name, version, _ := strings.Cut(arg, "@")
-		specs = append(specs, rpc.CrateSpec{Name: name, Version: version, Force: addForce})
+		specs = append(specs, rpc.CrateSpec{Name: name, Version: version, Force: addForce, OverBudget: addOverBudget, Registry: addRegistry, Ignore: ignore})
+	}
+	if addFile != "" {
+		data, err := readAddFile(addFile)
+		if err != nil {
+			slog.Error("failed to read rustdoc JSON", "error", err)
+			os.Exit(1)
+		}
+		if len(specs) == 0 {
+			specs = append(specs, rpc.CrateSpec{OverBudget: addOverBudget, Ignore: ignore})
+		}
+		specs[0].RustdocJSON = data
 	}
 
 	client, err := connectDaemon()
@@ -44,7 +113,11 @@ func runAdd(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
-	resp, err := client.AddCrates(context.Background(), specs, func(msg string) {
+	req := rpc.AddCratesRequest{Crates: specs, DependencyDepth: addWithDeps}
+	if addMaxDuration > 0 {
+		req.MaxDuration = addMaxDuration.String()
+	}
+	resp, err := client.AddCrates(context.Background(), req, func(msg string) {
 		slog.Info(msg)
 	})
 	if err != nil {
@@ -52,33 +125,92 @@ func runAdd(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
-	for _, r := range resp.Results {
+	printCrateResults(resp.Results)
+}
+
+func printCrateResults(results []rpc.CrateResult) {
+	f := formatter()
+	for _, r := range results {
 		if r.Error != "" {
 			fmt.Printf("  %s@%s: error: %s\n", r.Name, r.Version, r.Error)
+		} else if r.Partial {
+			fmt.Printf("  %s@%s: partial, %d items so far, still indexing in the background\n", r.Name, r.Version, r.Items)
 		} else {
-			fmt.Printf("  %s@%s: %d items indexed\n", r.Name, r.Version, r.Items)
+			fmt.Printf("  %s@%s: %d items indexed", r.Name, r.Version, r.Items)
+			if r.Tokens > 0 {
+				fmt.Printf(" (%s)", usageNote(f, int64(r.Tokens), r.Cost))
+			}
+			fmt.Println()
 		}
 	}
 }
 
+// usageNote describes embedding tokens billed and, when a price is set,
+// their estimated cost: "1,204,331 tokens, ~$0.07".
+func usageNote(f humanize.Formatter, tokens int64, cost float64) string {
+	if cost > 0 {
+		return fmt.Sprintf("%s tokens, ~%s", f.Count(tokens), f.Dollars(cost))
+	}
+	return fmt.Sprintf("%s tokens", f.Count(tokens))
+}
+
+func readAddFile(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
 var searchCmd = &cobra.Command{
 	Use:   "search <query>",
 	Short: "Search indexed crate documentation",
 	Example: `  rsdoc search "serialize a struct to JSON"
   rsdoc search --crate serde "derive macro"
-  rsdoc search --limit 5 "async runtime"`,
+  rsdoc search --crate tokio@1.35 "spawn a task"
+  rsdoc search --limit 5 "async runtime"
+  rsdoc search --crate tokio --feature full "spawn a task"
+  rsdoc search --examples-only "read a file line by line"
+  rsdoc search --crate axum --with-deps "extract a JSON body"`,
 	Args: cobra.ExactArgs(1),
 	Run:  runSearch,
 }
 
 var (
-	searchCrates []string
-	searchLimit  int
+	searchCrates        []string
+	searchFeatures      []string
+	searchKinds         []string
+	searchNotCrates     []string
+	searchNotKinds      []string
+	searchNotDeprecated bool
+	searchNotUnstable   bool
+	searchLimit         int
+	searchExamplesOnly  bool
+	searchExplain       bool
+	searchWithDeps      bool
+	searchRerank        bool
+	searchFlat          bool
+	searchExpand        bool
+	searchSnippetLen    int
 )
 
 func init() {
-	searchCmd.Flags().StringSliceVar(&searchCrates, "crate", nil, "filter to specific crates (repeatable)")
+	searchCmd.Flags().StringSliceVar(&searchCrates, "crate", nil, "filter to specific crates, optionally pinned as name@version (repeatable)")
+	searchCmd.RegisterFlagCompletionFunc("crate", completeCrateSpecs)
+	searchCmd.Flags().StringSliceVar(&searchFeatures, "feature", nil, "only items gated behind these cargo features (repeatable)")
+	searchCmd.Flags().StringSliceVar(&searchKinds, "kind", nil, "only items of these kinds, e.g. struct, trait, fn, macro or crate (repeatable)")
+	searchCmd.Flags().StringSliceVar(&searchNotCrates, "exclude-crate", nil, "leave out these crates, optionally pinned as name@version (repeatable)")
+	searchCmd.RegisterFlagCompletionFunc("exclude-crate", completeCrateSpecs)
+	searchCmd.Flags().StringSliceVar(&searchNotKinds, "exclude-kind", nil, "leave out items of these kinds (repeatable)")
+	searchCmd.Flags().BoolVar(&searchNotDeprecated, "exclude-deprecated", false, "leave out deprecated items")
+	searchCmd.Flags().BoolVar(&searchNotUnstable, "exclude-unstable", false, "leave out nightly-only (#[unstable]) items")
 	searchCmd.Flags().IntVar(&searchLimit, "limit", 10, "max results")
+	searchCmd.Flags().BoolVar(&searchExamplesOnly, "examples-only", false, "search doc examples and return runnable snippets")
+	searchCmd.Flags().BoolVar(&searchExplain, "explain", false, "show how results were ranked")
+	searchCmd.Flags().BoolVar(&searchWithDeps, "with-deps", false, "also search the indexed direct dependencies of the --crate crates")
+	searchCmd.Flags().IntVar(&searchSnippetLen, "snippet-length", 0, "max snippet length in bytes (default from search.snippet_length)")
+	searchCmd.Flags().BoolVar(&searchExpand, "expand", false, "also search spelling variants and Rust synonyms of the query (e.g. future for async task)")
+	searchCmd.Flags().BoolVar(&searchFlat, "flat", false, "list every hit separately instead of grouping members under their parent item")
+	searchCmd.Flags().BoolVar(&searchRerank, "rerank", false, "always rerank (--rerank=false never does); default follows search.rerank")
 }
 
 func runSearch(cmd *cobra.Command, args []string) {
@@ -88,29 +220,108 @@ func runSearch(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
-	resp, err := client.Search(context.Background(), rpc.SearchRequest{
-		Query:  args[0],
-		Crates: searchCrates,
-		Limit:  searchLimit,
-	})
+	req := rpc.SearchRequest{
+		Query:             args[0],
+		Crates:            searchCrates,
+		Features:          searchFeatures,
+		Kinds:             searchKinds,
+		ExcludeCrates:     searchNotCrates,
+		ExcludeKinds:      searchNotKinds,
+		ExcludeDeprecated: searchNotDeprecated,
+		ExcludeUnstable:   searchNotUnstable,
+		Limit:             searchLimit,
+		ExamplesOnly:      searchExamplesOnly,
+		Explain:           searchExplain,
+		WithDependencies:  searchWithDeps,
+		Flat:              searchFlat,
+		Expand:            searchExpand,
+		SnippetLength:     searchSnippetLen,
+	}
+	if cmd.Flags().Changed("rerank") {
+		req.Rerank = &searchRerank
+	}
+	resp, err := client.Search(context.Background(), req)
 	if err != nil {
-		slog.Error("search failed", "error", err)
+		slog.Error("search failed", "error", explainSearchError(context.Background(), client, err))
 		os.Exit(1)
 	}
 
+	if e := resp.Explain; e != nil {
+		fmt.Printf("explain: %d candidates, top similarity %.3f, margin %.3f", e.Candidates, e.TopSimilarity, e.Margin)
+		switch {
+		case e.Reranked:
+			fmt.Print(", reranked")
+		case e.RerankSkipped != "":
+			fmt.Printf(", rerank skipped (%s)", e.RerankSkipped)
+		}
+		fmt.Println()
+		if len(e.Queries) > 1 {
+			fmt.Printf("expanded to: %s\n", strings.Join(e.Queries, " | "))
+		}
+	}
+	if resp.RerankError != "" {
+		slog.Warn("reranking failed; results are ordered by vector similarity", "error", resp.RerankError)
+	}
+	for _, e := range resp.FetchErrors {
+		slog.Warn("couldn't fetch crate", "error", e)
+	}
+
 	if len(resp.Results) == 0 {
 		fmt.Println("no results")
+		if sg := resp.Suggestions; sg != nil {
+			fmt.Printf("\n%s\n", sg.Message)
+			for _, c := range sg.Crates {
+				fmt.Printf("  %-30s %s  %s\n", c.Name, c.MaxVersion, c.Description)
+			}
+		}
 		return
 	}
 
-	for i, r := range resp.Results {
-		fmt.Printf("%d. [%.2f] %s (%s) — %s@%s\n", i+1, r.Score, r.Path, r.Kind, r.CrateName, r.CrateVersion)
+	printDocResults(resp.Results)
+}
+
+// printDocResults prints search-style results with their snippets, example
+// code and grouped children.
+func printDocResults(results []rpc.DocResult) {
+	for i, r := range results {
+		score := fmt.Sprintf("%.2f", r.Score)
+		if r.ScoreKind == rpc.ScoreVector {
+			// Similarities run higher than rerank scores; flag them so the
+			// two aren't compared.
+			score += " vector"
+		}
+		fmt.Printf("%d. [%s] %s (%s) — %s@%s", i+1, score, hitPath(r), r.Kind, r.CrateName, r.CrateVersion)
+		if len(r.Features) > 0 {
+			fmt.Printf(" [features: %s]", strings.Join(r.Features, ", "))
+		}
+		if r.Deprecated {
+			fmt.Print(" [deprecated]")
+		}
+		if strings.HasPrefix(r.Stability, "unstable") {
+			fmt.Printf(" [%s]", r.Stability)
+		}
+		fmt.Println()
+		if r.Code != "" {
+			fmt.Printf("   %s\n```rust\n%s\n```\n\n", r.URI, r.Code)
+			continue
+		}
 		if r.Snippet != "" {
 			fmt.Printf("   %s\n", r.Snippet)
 		}
+		for _, c := range r.Children {
+			fmt.Printf("   ↳ [%.2f] %s (%s)\n", c.Score, hitPath(c), c.Kind)
+		}
 	}
 }
 
+// hitPath is a search hit's item path, with the fragment it matched in.
+func hitPath(r rpc.DocResult) string {
+	if r.Fragment != "" {
+		return r.Path + "#" + r.Fragment
+	}
+	return r.Path
+}
+
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show indexed crates and daemon state",
@@ -142,20 +353,75 @@ func runStatus(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	f := formatter()
+	now := time.Now()
+	if !resp.StartedAt.IsZero() {
+		fmt.Printf("daemon up %s, database %s\n", f.Duration(now.Sub(resp.StartedAt)), f.Bytes(resp.DatabaseBytes))
+	}
+	if e := resp.Embeddings; e != nil && e.State != "ok" && e.State != "unknown" {
+		line := "embedding provider " + strings.ReplaceAll(e.State, "_", " ")
+		if e.RateLimited > 0 {
+			line += fmt.Sprintf(", %d rate-limited requests in the last 15m", e.RateLimited)
+		}
+		if e.LastError != "" {
+			line += ": " + e.LastError
+		}
+		fmt.Println(line)
+	}
+	if m := resp.Model; m != nil && m.Configured != "" {
+		fmt.Printf("index embedded with %s (%d dims) but voyage_ai selects %s (%d dims); run \"rsdoc reembed\" to switch\n", modelName(m.Model), m.Dimensions, m.Configured, m.ConfiguredDimensions)
+	}
+	for _, w := range resp.Watches {
+		fmt.Printf("watching %s: %s\n", w.Lockfile, watchStatusLine(f, w, now))
+	}
+
 	if len(resp.Crates) == 0 {
 		fmt.Println("no crates indexed")
 		return
 	}
 
-	for _, c := range resp.Crates {
-		state := "processing"
-		if c.Processed {
-			state = "ready"
+	// Crates arrive sorted by name; versions of the same crate are grouped.
+	for i := 0; i < len(resp.Crates); {
+		j := i + 1
+		for j < len(resp.Crates) && resp.Crates[j].Name == resp.Crates[i].Name {
+			j++
+		}
+		if j-i == 1 {
+			c := resp.Crates[i]
+			fmt.Printf("  %s@%s %s\n", c.Name, c.Version, crateStatusLine(f, c, now))
+		} else {
+			fmt.Printf("  %s (%d versions)\n", resp.Crates[i].Name, j-i)
+			for _, c := range resp.Crates[i:j] {
+				fmt.Printf("    %s %s\n", c.Version, crateStatusLine(f, c, now))
+			}
 		}
-		fmt.Printf("  %s@%s [%s]\n", c.Name, c.Version, state)
+		i = j
 	}
 }
 
+func crateStatusLine(f humanize.Formatter, c rpc.CrateStatus, now time.Time) string {
+	state := "processing"
+	if c.Processed {
+		state = "ready"
+	} else if c.Partial {
+		state = "partial, indexing in background"
+	}
+	lastUsed := f.Ago(c.LastUsedAt, now)
+	if utcTimes {
+		lastUsed = f.Time(c.LastUsedAt)
+	}
+	switch c.Source {
+	case db.SourceHTML:
+		state += ", html fallback"
+	case db.SourceFile:
+		state += ", imported"
+	}
+	if c.Registry != "" {
+		state += ", registry " + c.Registry
+	}
+	return fmt.Sprintf("[%s] %s items, last used %s", state, f.Count(int64(c.Items)), lastUsed)
+}
+
 var stopCmd = &cobra.Command{
 	Use:   "stop",
 	Short: "Stop the background daemon",
@@ -163,16 +429,15 @@ var stopCmd = &cobra.Command{
 }
 
 func runStop(cmd *cobra.Command, args []string) {
-	client := daemon.NewClient(config.SocketPath())
-	if !client.IsAvailable() {
+	if !daemon.Running(config.SocketPath()) {
 		fmt.Println("daemon is not running")
 		return
 	}
 
-	if err := client.Shutdown(context.Background()); err != nil {
-		// Connection reset is expected — daemon exits after responding
-		fmt.Println("daemon stopped")
-		return
+	fmt.Println("stopping daemon (in-flight adds finish first)")
+	if err := stopDaemon(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
 	fmt.Println("daemon stopped")
 }
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jcdickinson/ferrisfetch/internal/bench"
+	"github.com/spf13/cobra"
+)
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Measure indexing and search speed on a bundled crate",
+	Long: `Index a bundled synthetic crate of about 400 items in a throwaway daemon and
+report items and chunks indexed per second, search latency percentiles and
+memory use.
+
+Embeddings come from the fake provider, so the numbers cover parsing,
+chunking, storage and the vector index without network or API cost, and
+are comparable between machines and releases. Nothing touches your cache,
+database or running daemon.`,
+	Args: cobra.NoArgs,
+	Run:  runBench,
+}
+
+var (
+	benchSearches int
+	benchJSON     bool
+)
+
+func init() {
+	benchCmd.Flags().IntVar(&benchSearches, "searches", 200, "number of searches to time")
+	benchCmd.Flags().BoolVar(&benchJSON, "json", false, "output as JSON")
+	rootCmd.AddCommand(benchCmd)
+}
+
+func runBench(cmd *cobra.Command, args []string) {
+	dir, err := os.MkdirTemp("", "rsdoc-bench-")
+	if err != nil {
+		slog.Error("creating bench directory", "error", err)
+		os.Exit(1)
+	}
+	defer os.RemoveAll(dir)
+	for key, value := range map[string]string{
+		"FERRISFETCH_PATHS_CACHE_DIR":    dir,
+		"FERRISFETCH_PATHS_DB":           filepath.Join(dir, "db.db"),
+		"FERRISFETCH_PATHS_CAS":          filepath.Join(dir, "cas"),
+		"FERRISFETCH_PATHS_SOCKET":       filepath.Join(dir, "daemon.sock"),
+		"FERRISFETCH_VOYAGE_AI_PROVIDER": "fake",
+		"FERRISFETCH_VCR_MODE":           "off",
+	} {
+		os.Setenv(key, value)
+	}
+
+	// Per-item progress would drown the report.
+	slog.SetLogLoggerLevel(slog.LevelWarn)
+	stop, err := startStandalone(cmd.Context())
+	if err != nil {
+		slog.Error("starting bench daemon", "error", err)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+	report, err := bench.Run(cmd.Context(), standaloneClient, bench.Options{Searches: benchSearches})
+	stop()
+	if err != nil {
+		slog.Error("bench failed", "error", err)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+
+	if benchJSON {
+		out, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+	f := formatter()
+	fmt.Printf("Indexed %s items (%s chunks) in %s\n", f.Count(int64(report.Items)), f.Count(int64(report.Chunks)), f.Duration(report.IndexTime))
+	fmt.Printf("  %.0f items/s, %.0f chunks/s\n", report.ItemsPerSec, report.ChunksPerSec)
+	fmt.Printf("Search over %s queries: p50 %s, p95 %s\n", f.Count(int64(report.Searches)), latency(report.SearchP50), latency(report.SearchP95))
+	fmt.Printf("Memory: peak heap %s, allocated %s\n", f.Bytes(int64(report.PeakHeapBytes)), f.Bytes(int64(report.TotalAllocBytes)))
+}
+
+// latency formats a search time to the microsecond; most take well under
+// the millisecond the humanize formatter rounds to.
+func latency(d time.Duration) string {
+	return d.Round(time.Microsecond).String()
+}
@@ -7,7 +7,6 @@ import (
 	"os"
 
 	"github.com/jcdickinson/ferrisfetch/internal/config"
-	"github.com/jcdickinson/ferrisfetch/internal/daemon"
 	"github.com/spf13/cobra"
 )
 
@@ -18,7 +17,7 @@ var clearCacheCmd = &cobra.Command{
 }
 
 func runClearCache(cmd *cobra.Command, args []string) {
-	client := daemon.NewClient(config.SocketPath())
+	client := newDaemonClient(config.SocketPath())
 	if !client.IsAvailable() {
 		fmt.Println("daemon is not running")
 		return
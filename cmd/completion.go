@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jcdickinson/ferrisfetch/internal/config"
+	"github.com/jcdickinson/ferrisfetch/internal/daemon"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+)
+
+// completionTimeout bounds how long a shell completion waits on the daemon.
+const completionTimeout = time.Second
+
+var manCmd = &cobra.Command{
+	Use:   "man [dir]",
+	Short: "Generate man pages",
+	Long: `Write a man page for rsdoc and each of its commands to dir (default ./man),
+e.g. for ~/.local/share/man/man1.
+
+Shell completions come from "rsdoc completion bash|zsh|fish|powershell"; they
+complete indexed crate names when the daemon is running.`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runMan,
+}
+
+func init() {
+	rootCmd.AddCommand(manCmd)
+
+	for _, c := range []*cobra.Command{depsCmd, coverageCmd, diffCmd, reindexCmd} {
+		c.ValidArgsFunction = completeCrateSpecs
+	}
+	for _, c := range []*cobra.Command{getCmd, similarCmd, buildContextCmd} {
+		c.ValidArgsFunction = completeDocURIs
+	}
+}
+
+func runMan(cmd *cobra.Command, args []string) {
+	dir := "man"
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	header := &doc.GenManHeader{Title: "RSDOC", Section: "1", Source: "ferrisfetch"}
+	rootCmd.DisableAutoGenTag = true
+	if err := doc.GenManTree(rootCmd, header, dir); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("man pages written to %s\n", dir)
+}
+
+// indexedCrates lists indexed crates for completion. It only asks a daemon
+// that is already running: spawning one would make every tab press slow.
+func indexedCrates() []rpc.CrateStatus {
+	socketPath := config.SocketPath()
+	if !daemon.Running(socketPath) {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+	resp, err := newDaemonClient(socketPath).Status(ctx)
+	if err != nil {
+		return nil
+	}
+	return resp.Crates
+}
+
+// completeCrateSpecs completes indexed crate names, and their versions
+// after "name@".
+func completeCrateSpecs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	name, _, pinned := strings.Cut(toComplete, "@")
+	var out []string
+	seen := make(map[string]bool)
+	for _, c := range indexedCrates() {
+		switch {
+		case pinned && c.Name == name:
+			out = append(out, c.Name+"@"+c.Version)
+		case !pinned && !seen[c.Name] && strings.HasPrefix(c.Name, toComplete):
+			seen[c.Name] = true
+			out = append(out, c.Name)
+		}
+	}
+	return out, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeDocURIs completes the crate/version/ prefix of a doc URI; item
+// paths are left to the user.
+func completeDocURIs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	prefix := ""
+	rest := toComplete
+	if after, ok := strings.CutPrefix(toComplete, "rsdoc://"); ok {
+		prefix, rest = "rsdoc://", after
+	}
+	if strings.Count(rest, "/") >= 2 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var out []string
+	seen := make(map[string]bool)
+	for _, c := range indexedCrates() {
+		for _, version := range []string{"latest", c.Version} {
+			uri := prefix + c.Name + "/" + version + "/"
+			if !seen[uri] && strings.HasPrefix(uri, toComplete) {
+				seen[uri] = true
+				out = append(out, uri)
+			}
+		}
+	}
+	return out, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/jcdickinson/ferrisfetch/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Check or show the configuration",
+}
+
+var configCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Validate the configuration",
+	Long: `Load the configuration as the daemon would and report problems: settings that
+stop it loading, such as an unreadable API key file or a dimension the model
+doesn't support, and warnings such as misspelled keys or an unknown model.
+Exits non-zero if the configuration doesn't load.`,
+	Args: cobra.NoArgs,
+	Run:  runConfigCheck,
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration, secrets redacted",
+	Long: `Print every setting with the value in force after defaults, the config file,
+FERRISFETCH_* environment variables and flags are applied. Paths are shown
+resolved; API keys and tokens are shown only as "<redacted>" when set.`,
+	Args: cobra.NoArgs,
+	Run:  runConfigShow,
+}
+
+var configShowJSON bool
+
+func init() {
+	configShowCmd.Flags().BoolVar(&configShowJSON, "json", false, "output as JSON")
+	configCmd.AddCommand(configCheckCmd, configShowCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func runConfigCheck(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if file := config.File(); file != "" {
+		fmt.Printf("config file: %s\n", file)
+	} else {
+		fmt.Println("no config file found; using defaults and FERRISFETCH_* environment variables")
+	}
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
+		os.Exit(1)
+	}
+	for _, w := range cfg.Warnings() {
+		fmt.Printf("! %s\n", w)
+	}
+	if len(cfg.Warnings()) == 0 {
+		fmt.Println("✓ config ok")
+	}
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Error("failed to load config", "error", err)
+		os.Exit(1)
+	}
+	settings := cfg.Effective()
+	if configShowJSON {
+		out, _ := json.MarshalIndent(settings, "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+	for _, s := range settings {
+		fmt.Printf("%s = %s\n", s.Key, s.Value)
+	}
+}
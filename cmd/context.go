@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+	"github.com/spf13/cobra"
+)
+
+var buildContextCmd = &cobra.Command{
+	Use:   "build-context <uri> [uri ...]",
+	Short: "Bundle several docs into one markdown context, trimmed to a token budget",
+	Long: `Fetch several rsdoc:// URIs and concatenate them into one deduplicated
+markdown bundle. When the bundle exceeds the token budget, titles, signatures
+and summaries are kept for every item before any full docs are included.
+
+With --query, search instead and bundle the best hits, most relevant first:
+a context ready to answer the question from.`,
+	Example: `  rsdoc build-context tokio/latest/tokio::spawn tokio/latest/tokio::task::JoinHandle
+  rsdoc build-context --budget 2000 serde/latest/serde::Serialize serde/latest/serde::Deserialize
+  rsdoc build-context --query "how do I cancel a spawned task" --crate tokio`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if buildContextQuery != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
+	Run: runBuildContext,
+}
+
+var (
+	buildContextBudget int
+	buildContextQuery  string
+	buildContextCrates []string
+	buildContextLimit  int
+)
+
+func init() {
+	buildContextCmd.Flags().IntVar(&buildContextBudget, "budget", 8000, "approximate token budget")
+	buildContextCmd.Flags().StringVar(&buildContextQuery, "query", "", "search for this and bundle the top hits instead of URIs")
+	buildContextCmd.Flags().StringSliceVar(&buildContextCrates, "crate", nil, "with --query: filter to specific crates, optionally pinned as name@version (repeatable)")
+	buildContextCmd.RegisterFlagCompletionFunc("crate", completeCrateSpecs)
+	buildContextCmd.Flags().IntVar(&buildContextLimit, "limit", 8, "with --query: number of search hits to bundle")
+}
+
+func runBuildContext(cmd *cobra.Command, args []string) {
+	client, err := connectDaemon()
+	if err != nil {
+		slog.Error("failed to connect to daemon", "error", err)
+		os.Exit(1)
+	}
+
+	var resp *rpc.BuildContextResponse
+	if buildContextQuery != "" {
+		ctxResp, err := client.Context(context.Background(), rpc.ContextRequest{
+			Query:       buildContextQuery,
+			Crates:      buildContextCrates,
+			Limit:       buildContextLimit,
+			TokenBudget: buildContextBudget,
+		})
+		if err != nil {
+			slog.Error("build context failed", "error", explainSearchError(context.Background(), client, err))
+			os.Exit(1)
+		}
+		if len(ctxResp.Sources) == 0 {
+			fmt.Fprintln(os.Stderr, "no results")
+			if sg := ctxResp.Suggestions; sg != nil {
+				fmt.Fprintf(os.Stderr, "\n%s\n", sg.Message)
+			}
+			os.Exit(1)
+		}
+		resp = &ctxResp.BuildContextResponse
+	} else {
+		resp, err = client.BuildContext(context.Background(), rpc.BuildContextRequest{
+			URIs:        args,
+			TokenBudget: buildContextBudget,
+		})
+		if err != nil {
+			slog.Error("build context failed", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	fmt.Print(resp.Markdown)
+	if len(resp.Truncated) > 0 {
+		fmt.Fprintf(os.Stderr, "truncated: %s\n", strings.Join(resp.Truncated, ", "))
+	}
+	if len(resp.Omitted) > 0 {
+		fmt.Fprintf(os.Stderr, "omitted: %s\n", strings.Join(resp.Omitted, ", "))
+	}
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/jcdickinson/ferrisfetch/internal/humanize"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+	"github.com/spf13/cobra"
+)
+
+var coverageCmd = &cobra.Command{
+	Use:   "coverage <crate[@version]>",
+	Short: "Report how much of a crate is documented and embedded",
+	Long: `Count a crate's public items with docs, how many of those are embedded for
+search, and how many fragments (implementations, fields, ...) were generated,
+then list the undocumented items and any whose docs or fragments are missing
+embeddings. Missing embeddings usually mean an add was interrupted; run
+` + "`rsdoc add --force`" + ` to finish it. The crate is fetched first if it isn't indexed.`,
+	Example: `  rsdoc coverage tokio
+  rsdoc coverage serde@1.0.210 --all`,
+	Args: cobra.ExactArgs(1),
+	Run:  runCoverage,
+}
+
+var (
+	coverageJSON bool
+	coverageAll  bool
+)
+
+// coverageListLimit is how many items of each list are shown without --all.
+const coverageListLimit = 20
+
+func init() {
+	coverageCmd.Flags().BoolVar(&coverageJSON, "json", false, "output as JSON")
+	coverageCmd.Flags().BoolVar(&coverageAll, "all", false, "list every undocumented and unembedded item")
+	rootCmd.AddCommand(coverageCmd)
+}
+
+func runCoverage(cmd *cobra.Command, args []string) {
+	name, version, _ := strings.Cut(args[0], "@")
+
+	client, err := connectDaemon()
+	if err != nil {
+		slog.Error("failed to connect to daemon", "error", err)
+		os.Exit(1)
+	}
+
+	resp, err := client.Coverage(context.Background(), rpc.CoverageRequest{Crate: name, Version: version})
+	if err != nil {
+		slog.Error("coverage failed", "error", err)
+		os.Exit(1)
+	}
+
+	if coverageJSON {
+		out, _ := json.MarshalIndent(resp, "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+
+	f := formatter()
+	fmt.Printf("%s@%s: %s items\n", resp.Crate, resp.Version, f.Count(int64(resp.Items)))
+	fmt.Printf("  documented  %s\n", ratio(f, resp.Documented, resp.Items))
+	fmt.Printf("  embedded    %s of documented\n", ratio(f, resp.Embedded, resp.Documented))
+	fmt.Printf("  fragments   %s, %s embedded\n", f.Count(int64(resp.Fragments)), ratio(f, resp.EmbeddedFragments, resp.Fragments))
+
+	if len(resp.ByKind) > 1 {
+		fmt.Println("\nby kind:")
+		for _, k := range resp.ByKind {
+			fmt.Printf("  %-12s %s documented\n", k.Kind, ratio(f, k.Documented, k.Items))
+		}
+	}
+	printCoverageList(f, "undocumented", resp.Undocumented)
+	printCoverageList(f, "missing embeddings", resp.Unembedded)
+}
+
+// ratio formats n of total with its percentage.
+func ratio(f humanize.Formatter, n, total int) string {
+	if total == 0 {
+		return f.Count(int64(n))
+	}
+	return fmt.Sprintf("%s/%s (%.1f%%)", f.Count(int64(n)), f.Count(int64(total)), 100*float64(n)/float64(total))
+}
+
+func printCoverageList(f humanize.Formatter, title string, items []rpc.CoverageItem) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Printf("\n%s (%s):\n", title, f.Count(int64(len(items))))
+	shown := items
+	if !coverageAll && len(shown) > coverageListLimit {
+		shown = shown[:coverageListLimit]
+	}
+	for _, it := range shown {
+		line := fmt.Sprintf("  %s (%s)", it.Path, it.Kind)
+		if it.MissingFragments > 0 {
+			line += fmt.Sprintf(", %d fragments", it.MissingFragments)
+		}
+		fmt.Println(line)
+	}
+	if rest := len(items) - len(shown); rest > 0 {
+		fmt.Printf("  ... and %s more (--all to list)\n", f.Count(int64(rest)))
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"log/slog"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 
 	"github.com/jcdickinson/ferrisfetch/internal/config"
 	"github.com/jcdickinson/ferrisfetch/internal/daemon"
@@ -15,7 +17,16 @@ import (
 var daemonCmd = &cobra.Command{
 	Use:   "daemon",
 	Short: "Run the background daemon (usually spawned automatically)",
-	Run:   runDaemon,
+	Long: `Run the background daemon. It exits after daemon.expiration of inactivity
+(10 minutes by default), or never with --keep-alive. On SIGINT, SIGTERM or
+` + "`rsdoc stop`" + ` it finishes in-flight adds before exiting.`,
+	Run: runDaemon,
+}
+
+var daemonKeepAlive bool
+
+func init() {
+	daemonCmd.Flags().BoolVar(&daemonKeepAlive, "keep-alive", false, `never exit when idle (same as daemon.expiration = "never")`)
 }
 
 func runDaemon(cmd *cobra.Command, args []string) {
@@ -32,20 +43,32 @@ func runDaemon(cmd *cobra.Command, args []string) {
 	defer logFile.Close()
 	slog.SetDefault(slog.New(slog.NewTextHandler(logFile, nil)))
 
-	cfg, err := config.Load()
+	load := func() (*config.Config, error) {
+		cfg, err := config.Load()
+		if err == nil && daemonKeepAlive {
+			cfg.Daemon.Expiration = "never"
+		}
+		return cfg, err
+	}
+	cfg, err := load()
 	if err != nil {
 		slog.Error("failed to load config", "error", err)
 		os.Exit(1)
 	}
+	config.PinPaths()
 
-	database, err := db.New(config.DBPath())
+	database, err := db.NewWithOptions(config.DBPath(), db.Options{Storage: cfg.Index.Storage, Quantization: cfg.Index.Quantization})
 	if err != nil {
 		slog.Error("failed to open database", "error", err)
 		os.Exit(1)
 	}
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	srv := daemon.NewServer(cfg, database, config.SocketPath())
-	if err := srv.Start(context.Background()); err != nil {
+	srv.SetConfigLoader(load)
+	if err := srv.Start(ctx); err != nil {
 		slog.Error("daemon failed", "error", err)
 		os.Exit(1)
 	}
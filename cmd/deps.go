@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+	"github.com/spf13/cobra"
+)
+
+var depsCmd = &cobra.Command{
+	Use:   "deps <crate[@version]>",
+	Short: "Show a crate's dependencies and the indexed crates that depend on it",
+	Long: `List a crate's direct dependencies from its registry, with the version docs.rs
+built against and whether each one is indexed, plus the indexed crates that
+depend on it. The crate is fetched first if it isn't indexed.`,
+	Example: `  rsdoc deps axum
+  rsdoc deps tokio@1.38.0 --json`,
+	Args: cobra.ExactArgs(1),
+	Run:  runDeps,
+}
+
+var depsJSON bool
+
+func init() {
+	depsCmd.Flags().BoolVar(&depsJSON, "json", false, "output as JSON")
+}
+
+func runDeps(cmd *cobra.Command, args []string) {
+	name, version, _ := strings.Cut(args[0], "@")
+
+	client, err := connectDaemon()
+	if err != nil {
+		slog.Error("failed to connect to daemon", "error", err)
+		os.Exit(1)
+	}
+
+	resp, err := client.Deps(context.Background(), rpc.DepsRequest{Crate: name, Version: version})
+	if err != nil {
+		slog.Error("deps failed", "error", err)
+		os.Exit(1)
+	}
+
+	if depsJSON {
+		out, _ := json.MarshalIndent(resp, "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+
+	fmt.Printf("%s@%s: %d dependencies\n", resp.Crate, resp.Version, len(resp.Dependencies))
+	kind := ""
+	for _, d := range resp.Dependencies {
+		if d.Kind != kind {
+			kind = d.Kind
+			fmt.Printf("\n%s:\n", kind)
+		}
+		line := fmt.Sprintf("  %-30s %s", d.Name, d.Req)
+		if d.Version != "" {
+			line += " (built with " + d.Version + ")"
+		}
+		if d.Optional {
+			line += " optional"
+		}
+		if d.IndexedVersion != "" {
+			line += " [indexed: " + d.IndexedVersion + "]"
+		}
+		fmt.Println(line)
+	}
+
+	if len(resp.Dependents) > 0 {
+		fmt.Println("\nIndexed dependents:")
+		for _, d := range resp.Dependents {
+			fmt.Printf("  %s\n", d)
+		}
+	}
+}
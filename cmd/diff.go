@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <crate@from> <crate@to>",
+	Short: "Compare the API of two versions of a crate",
+	Long: `List items added, removed, or with a changed signature between two versions
+of a crate. Versions that aren't indexed yet are fetched first.`,
+	Example: `  rsdoc diff serde@1.0.190 serde@1.0.210
+  rsdoc diff tokio@1.38.0 tokio@latest`,
+	Args: cobra.ExactArgs(2),
+	Run:  runDiff,
+}
+
+var diffJSON bool
+
+func init() {
+	diffCmd.Flags().BoolVar(&diffJSON, "json", false, "output as JSON")
+}
+
+func runDiff(cmd *cobra.Command, args []string) {
+	fromName, fromVersion, _ := strings.Cut(args[0], "@")
+	toName, toVersion, _ := strings.Cut(args[1], "@")
+	if fromName != toName {
+		slog.Error("diff compares two versions of the same crate", "from", fromName, "to", toName)
+		os.Exit(1)
+	}
+	if fromVersion == "" || toVersion == "" {
+		slog.Error("both arguments need a version, e.g. serde@1.0.190")
+		os.Exit(1)
+	}
+
+	client, err := connectDaemon()
+	if err != nil {
+		slog.Error("failed to connect to daemon", "error", err)
+		os.Exit(1)
+	}
+
+	resp, err := client.Diff(context.Background(), rpc.DiffRequest{
+		Crate: fromName,
+		From:  fromVersion,
+		To:    toVersion,
+	})
+	if err != nil {
+		slog.Error("diff failed", "error", err)
+		os.Exit(1)
+	}
+
+	if diffJSON {
+		out, _ := json.MarshalIndent(resp, "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+
+	fmt.Printf("%s %s -> %s: %d added, %d removed, %d changed\n",
+		resp.Crate, resp.From, resp.To, len(resp.Added), len(resp.Removed), len(resp.Changed))
+
+	if len(resp.Added) > 0 {
+		fmt.Println("\nAdded:")
+		for _, it := range resp.Added {
+			fmt.Printf("  + %s (%s)\n", it.Path, it.Kind)
+		}
+	}
+	if len(resp.Removed) > 0 {
+		fmt.Println("\nRemoved:")
+		for _, it := range resp.Removed {
+			fmt.Printf("  - %s (%s)\n", it.Path, it.Kind)
+		}
+	}
+	if len(resp.Changed) > 0 {
+		fmt.Println("\nChanged:")
+		for _, c := range resp.Changed {
+			if c.OldKind != "" {
+				fmt.Printf("  ~ %s (%s -> %s)\n", c.Path, c.OldKind, c.Kind)
+			} else {
+				fmt.Printf("  ~ %s (%s)\n", c.Path, c.Kind)
+			}
+			if c.OldSignature != c.NewSignature {
+				fmt.Printf("      - %s\n      + %s\n", oneLine(c.OldSignature), oneLine(c.NewSignature))
+			}
+		}
+	}
+}
+
+// oneLine collapses a multi-line signature onto a single line.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jcdickinson/ferrisfetch/internal/config"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the configuration, daemon and its dependencies",
+	Long: `Load the configuration, start the daemon if needed, and check the database,
+vector index, content store and embedding provider, printing a fix for
+anything that isn't working. Exits non-zero if a check fails.`,
+	Args: cobra.NoArgs,
+	Run:  runDoctor,
+}
+
+var doctorJSON bool
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorJSON, "json", false, "output as JSON")
+	rootCmd.AddCommand(doctorCmd)
+}
+
+func runDoctor(cmd *cobra.Command, args []string) {
+	configCheck := rpc.HealthCheck{Name: "config", Status: rpc.HealthOK}
+	if cfg, err := config.Load(); err != nil {
+		configCheck = rpc.HealthCheck{Name: "config", Status: rpc.HealthFail, Detail: err.Error(), Fix: "fix ~/.config/ferrisfetch/config.toml"}
+	} else if w := cfg.Warnings(); len(w) > 0 {
+		configCheck = rpc.HealthCheck{Name: "config", Status: rpc.HealthWarn, Detail: strings.Join(w, "; "), Fix: "see `rsdoc config check`"}
+	}
+
+	// The daemon's checks follow the local ones.
+	resp := &rpc.HealthResponse{}
+	daemonCheck := rpc.HealthCheck{Name: "daemon", Status: rpc.HealthOK}
+	if client, err := connectDaemon(); err != nil {
+		daemonCheck = rpc.HealthCheck{Name: "daemon", Status: rpc.HealthFail, Detail: err.Error(), Fix: "check `rsdoc logs`"}
+	} else if health, err := client.Health(context.Background()); err != nil {
+		daemonCheck = rpc.HealthCheck{Name: "daemon", Status: rpc.HealthFail, Detail: err.Error(), Fix: "the daemon may predate `rsdoc doctor`; run `rsdoc stop` and try again"}
+	} else {
+		resp = health
+	}
+	resp.Checks = append([]rpc.HealthCheck{configCheck, daemonCheck}, resp.Checks...)
+
+	resp.OK = true
+	for _, c := range resp.Checks {
+		if c.Status == rpc.HealthFail {
+			resp.OK = false
+		}
+	}
+
+	if doctorJSON {
+		out, _ := json.MarshalIndent(resp, "", "  ")
+		fmt.Println(string(out))
+	} else {
+		printHealth(resp)
+	}
+	if !resp.OK {
+		os.Exit(1)
+	}
+}
+
+func printHealth(resp *rpc.HealthResponse) {
+	marks := map[string]string{rpc.HealthOK: "✓", rpc.HealthWarn: "!", rpc.HealthFail: "✗"}
+	for _, c := range resp.Checks {
+		line := fmt.Sprintf("%s %s", marks[c.Status], c.Name)
+		if c.Detail != "" {
+			line += ": " + c.Detail
+		}
+		fmt.Println(line)
+		if c.Fix != "" && c.Status != rpc.HealthOK {
+			fmt.Printf("    fix: %s\n", c.Fix)
+		}
+	}
+	if !resp.StartedAt.IsZero() {
+		f := formatter()
+		fmt.Printf("daemon up %s: %s crates, %s items, %s embeddings\n",
+			f.Duration(time.Since(resp.StartedAt)), f.Count(int64(resp.Crates)), f.Count(int64(resp.Items)), f.Count(int64(resp.Embeddings)))
+	}
+}
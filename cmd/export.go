@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jcdickinson/ferrisfetch/internal/bundle"
+	"github.com/jcdickinson/ferrisfetch/internal/config"
+	"github.com/jcdickinson/ferrisfetch/internal/daemon"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export <archive>",
+	Short: "Bundle the whole index into a portable archive",
+	Long: `Write the database, vector index, content store and rustdoc JSON cache to a
+zstd-compressed tar archive. Load it elsewhere with "rsdoc import" to skip
+re-fetching and re-embedding.`,
+	Example: `  rsdoc export index.tar.zst`,
+	Args:    cobra.ExactArgs(1),
+	Run:     runExport,
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import <archive>",
+	Short: "Replace the index with one from \"rsdoc export\"",
+	Long: `Stop the daemon and replace the local index with the contents of an archive
+written by "rsdoc export". Refuses to overwrite an existing index without --force.`,
+	Example: `  rsdoc import index.tar.zst
+  rsdoc import --force index.tar.zst`,
+	Args: cobra.ExactArgs(1),
+	Run:  runImport,
+}
+
+var importForce bool
+
+func init() {
+	importCmd.Flags().BoolVarP(&importForce, "force", "f", false, "replace an existing index")
+}
+
+func runExport(cmd *cobra.Command, args []string) {
+	path, err := filepath.Abs(args[0])
+	if err != nil {
+		slog.Error("invalid path", "error", err)
+		os.Exit(1)
+	}
+
+	client, err := connectDaemon()
+	if err != nil {
+		slog.Error("failed to connect to daemon", "error", err)
+		os.Exit(1)
+	}
+
+	resp, err := client.Export(context.Background(), rpc.ExportRequest{Path: path})
+	if err != nil {
+		slog.Error("export failed", "error", err)
+		os.Exit(1)
+	}
+	f := formatter()
+	fmt.Printf("exported %s files (%s) to %s\n", f.Count(int64(resp.Files)), f.Bytes(resp.Bytes), resp.Path)
+}
+
+func runImport(cmd *cobra.Command, args []string) {
+	in, err := os.Open(args[0])
+	if err != nil {
+		slog.Error("failed to open archive", "error", err)
+		os.Exit(1)
+	}
+	defer in.Close()
+
+	dbPath := config.DBPath()
+	if _, err := os.Stat(dbPath); err == nil && !importForce {
+		slog.Error("an index already exists; pass --force to replace it", "path", dbPath)
+		os.Exit(1)
+	}
+
+	if err := stopDaemon(); err != nil {
+		slog.Error("failed to stop daemon", "error", err)
+		os.Exit(1)
+	}
+
+	// Staged next to the database so installing is usually a rename.
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		slog.Error("failed to create cache directory", "error", err)
+		os.Exit(1)
+	}
+	staging, err := os.MkdirTemp(filepath.Dir(dbPath), "import-")
+	if err != nil {
+		slog.Error("failed to create staging directory", "error", err)
+		os.Exit(1)
+	}
+	defer os.RemoveAll(staging)
+
+	manifest, err := bundle.Extract(in, staging)
+	if err != nil {
+		slog.Error("failed to extract archive", "error", err)
+		os.Exit(1)
+	}
+	if cfg, err := config.Load(); err == nil && manifest.Model != "" && manifest.Model != cfg.VoyageAI.Model {
+		slog.Warn("archive was embedded with a different model; searches use it until \"rsdoc reembed\"", "archive", manifest.Model, "configured", cfg.VoyageAI.Model)
+	}
+
+	// Stale WAL files would be replayed into the imported database.
+	os.Remove(dbPath + "-wal")
+	os.Remove(dbPath + "-shm")
+	if err := bundle.Install(staging, bundle.Entries(dbPath)); err != nil {
+		slog.Error("failed to install index", "error", err)
+		os.Exit(1)
+	}
+	fmt.Printf("imported index built %s with %s\n", formatter().Time(manifest.CreatedAt), manifest.Model)
+}
+
+// stopDaemon shuts down a running daemon and waits for it to release the
+// database.
+func stopDaemon() error {
+	socketPath := config.SocketPath()
+	if !daemon.Running(socketPath) {
+		return nil
+	}
+	// The daemon stops right after responding, so errors here are expected.
+	newDaemonClient(socketPath).Shutdown(context.Background())
+	// It drains in-flight adds before closing the database.
+	return daemon.WaitStopped(socketPath, daemon.DrainTimeout+10*time.Second)
+}
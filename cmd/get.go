@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
-	"strings"
+	"strconv"
 
 	"github.com/jcdickinson/ferrisfetch/internal/rpc"
 	"github.com/spf13/cobra"
@@ -17,49 +17,44 @@ var getCmd = &cobra.Command{
 	Example: `  rsdoc get rsdoc://serde/latest/serde::Serialize
   rsdoc get rsdoc://tokio/1.0.0/tokio::spawn
   rsdoc get serde/latest/serde::Serialize
-  rsdoc get serde@1.0.0/serde::Serialize`,
+  rsdoc get serde@1.0.0/serde::Serialize
+  rsdoc get --format plain --width 60 tokio/latest/tokio::spawn
+  rsdoc get --include-linked 3 axum/latest/axum::Router::route
+  rsdoc get --links rewrite tokio/latest/tokio::sync::Mutex
+  rsdoc get --summary --budget 300 tokio/latest/tokio::spawn`,
 	Aliases: []string{"read"},
 	Args:    cobra.ExactArgs(1),
 	Run:     runGet,
 }
 
+var (
+	getFormat string
+	getWidth  int
+	getLinked int
+	getLinks  string
+
+	getSummary bool
+	getBudget  int
+)
+
 func init() {
+	getCmd.Flags().StringVar(&getFormat, "format", "markdown", "output format: markdown or plain (no markup, wrapped)")
+	getCmd.Flags().IntVar(&getWidth, "width", 0, "wrap plain output to this many columns (default $COLUMNS or 80)")
+	getCmd.Flags().IntVar(&getLinked, "include-linked", 0, "append summaries of up to N linked items (signature types, then doc links)")
+	getCmd.Flags().StringVar(&getLinks, "links", "", `check links against the index: "annotate" marks ones that aren't indexed, "rewrite" also points them at docs.rs`)
+	getCmd.Flags().BoolVar(&getSummary, "summary", false, "print an extractive summary: signature, summary line, key sections, fragments and first example")
+	getCmd.Flags().IntVar(&getBudget, "budget", 500, "with --summary, approximate token budget")
 	rootCmd.AddCommand(getCmd)
 }
 
 func runGet(cmd *cobra.Command, args []string) {
-	uri := strings.TrimPrefix(args[0], "rsdoc://")
-
-	// Support crate@version/path as alternative to crate/version/path
-	var crate, version, path string
-	if idx := strings.Index(uri, "@"); idx >= 0 {
-		crate = uri[:idx]
-		rest := strings.SplitN(uri[idx+1:], "/", 2)
-		version = rest[0]
-		if len(rest) == 2 {
-			path = rest[1]
-		}
-	} else {
-		parts := strings.SplitN(uri, "/", 3)
-		if len(parts) < 2 {
-			slog.Error("invalid URI: need crate/version/path or crate@version/path")
-			os.Exit(1)
-		}
-		crate = parts[0]
-		version = parts[1]
-		if len(parts) == 3 {
-			path = parts[2]
-		}
-	}
-
-	if path == "" {
-		path = crate
-		fmt.Printf("note: no path given, assuming %s/%s/%s\n\n", crate, version, path)
+	req, defaultPath, err := rpc.ParseDocURI(args[0])
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
 	}
-	var fragment string
-	if idx := strings.LastIndex(path, "#"); idx >= 0 {
-		fragment = path[idx+1:]
-		path = path[:idx]
+	if defaultPath {
+		fmt.Printf("note: no path given, assuming %s/%s/%s\n\n", req.Crate, req.Version, req.Path)
 	}
 
 	client, err := connectDaemon()
@@ -68,12 +63,24 @@ func runGet(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
-	resp, err := client.GetDoc(context.Background(), rpc.GetDocRequest{
-		Crate:    crate,
-		Version:  version,
-		Path:     path,
-		Fragment: fragment,
-	})
+	if getSummary {
+		resp, err := client.Summarize(context.Background(), rpc.SummarizeRequest{URI: args[0], TokenBudget: getBudget})
+		if err != nil {
+			slog.Error("summarize failed", "error", err)
+			os.Exit(1)
+		}
+		fmt.Print(resp.Markdown)
+		return
+	}
+
+	req.Format = getFormat
+	req.Width = getWidth
+	req.IncludeLinked = getLinked
+	req.ResolveLinks = getLinks
+	if req.Width == 0 {
+		req.Width, _ = strconv.Atoi(os.Getenv("COLUMNS"))
+	}
+	resp, err := client.GetDoc(context.Background(), req)
 	if err != nil {
 		slog.Error("get doc failed", "error", err)
 		os.Exit(1)
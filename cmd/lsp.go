@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/jcdickinson/ferrisfetch/internal/lsp"
+	"github.com/spf13/cobra"
+)
+
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Run as a language server for documentation hovers",
+	Long: `Serve indexed documentation to an editor over the Language Server Protocol
+on stdin/stdout, alongside rust-analyzer:
+
+  textDocument/hover  docs for the symbol under the cursor
+  workspace/symbol    indexed items by name
+
+The symbol is resolved through the file's use declarations, then looked up by
+path among indexed crates; nothing is fetched. Editors that can ask
+rust-analyzer for the symbol's full path may pass it as "rsdocPath" in the
+hover parameters instead.`,
+	Args: cobra.NoArgs,
+	Run:  runLSP,
+}
+
+func init() {
+	rootCmd.AddCommand(lspCmd)
+}
+
+func runLSP(cmd *cobra.Command, args []string) {
+	client, err := connectDaemon()
+	if err != nil {
+		slog.Error("failed to connect to daemon", "error", err)
+		os.Exit(1)
+	}
+	if err := lsp.NewServer(client).Serve(cmd.Context(), os.Stdin, os.Stdout); err != nil {
+		slog.Error("language server failed", "error", err)
+		os.Exit(1)
+	}
+}
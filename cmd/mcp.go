@@ -1,12 +1,18 @@
 package cmd
 
 import (
+	"context"
 	_ "embed"
+	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/spf13/cobra"
 )
@@ -14,20 +20,356 @@ import (
 //go:embed mcp_prelude.md
 var mcpPrelude string
 
+var mcpStandalone bool
+
 var mcpCmd = &cobra.Command{
-	Use:   "mcp",
-	Short: "Run as MCP server (publishes CLI instructions only)",
+	Use:     "mcp",
+	Aliases: []string{"serve"},
+	Short:   "Run as MCP server (CLI instructions plus a few native tools)",
+	Long: `Run as an MCP server on stdin and stdout.
+
+Tools call the daemon, spawning it if needed. With --standalone the daemon
+runs inside this process instead: no socket and no separate process, which
+suits containers. Logs go to stderr and the daemon stops with the server.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if mcpStandalone {
+			stop, err := startStandalone(cmd.Context())
+			if err != nil {
+				return err
+			}
+			defer stop()
+		}
 		name := binaryName()
 		instructions := fmt.Sprintf(mcpPrelude, name) + agentHelp
 
 		s := server.NewMCPServer("rsdoc", "1.0.0",
 			server.WithInstructions(instructions),
+			server.WithToolCapabilities(false),
+			server.WithResourceCapabilities(false, false),
 		)
+		// Only offer tools the daemon can serve. If it can't be asked,
+		// register everything and let calls report errors.
+		var caps *rpc.CapabilitiesResponse
+		if client, err := connectDaemon(); err == nil {
+			caps = daemonCapabilities(cmd.Context(), client)
+		}
+		if caps == nil || caps.Embeddings != "" {
+			s.AddTool(searchDocsTool, handleSearchDocs)
+			s.AddTool(searchExamplesTool, handleSearchExamples)
+		} else {
+			slog.Warn("no embedding provider configured; search tools disabled")
+		}
+		if caps == nil || caps.HasEndpoint("POST /build-context") {
+			s.AddTool(buildContextTool, handleBuildContext)
+		}
+		if caps == nil || caps.HasEndpoint("POST /summarize") {
+			s.AddTool(summarizeDocTool, handleSummarizeDoc)
+		}
+		if caps == nil || caps.HasEndpoint("POST /deps") {
+			s.AddTool(crateDependenciesTool, handleCrateDependencies)
+		}
+		s.AddResourceTemplate(docResourceTemplate, handleReadDoc)
 		return server.ServeStdio(s)
 	},
 }
 
+func init() {
+	mcpCmd.Flags().BoolVar(&mcpStandalone, "standalone", false, "run the daemon in this process instead of connecting to one over its socket")
+}
+
+var searchDocsTool = mcp.NewTool("search_docs",
+	mcp.WithDescription("Semantic search across indexed Rust crate documentation. Returns the results as JSON plus a resource link per hit that can be read directly."),
+	mcp.WithString("query", mcp.Required(), mcp.Description("natural language search query")),
+	mcp.WithArray("crates", mcp.WithStringItems(), mcp.Description("restrict to these crates, optionally pinned as name@version (auto-fetched if not indexed)")),
+	mcp.WithArray("features", mcp.WithStringItems(), mcp.Description("only items gated behind these cargo features")),
+	mcp.WithArray("kinds", mcp.WithStringItems(), mcp.Description("only items of these kinds, e.g. struct, trait, fn or macro (macro includes attribute and derive macros), or crate for crates matched on their description")),
+	mcp.WithArray("exclude_crates", mcp.WithStringItems(), mcp.Description("leave out these crates, e.g. a deprecated one")),
+	mcp.WithArray("exclude_kinds", mcp.WithStringItems(), mcp.Description("leave out items of these kinds, e.g. macro")),
+	mcp.WithBoolean("exclude_deprecated", mcp.Description("leave out #[deprecated] items; each result's deprecated field marks them otherwise")),
+	mcp.WithBoolean("exclude_unstable", mcp.Description("leave out nightly-only #[unstable] standard library items, for users on a stable toolchain; each result's stability field marks them otherwise")),
+	mcp.WithBoolean("with_dependencies", mcp.Description("also search the indexed direct dependencies of the given crates")),
+	mcp.WithBoolean("expand", mcp.Description("also search spelling variants and Rust synonyms of the query (future for async task, trait for interface); helps when the query uses terms from other languages")),
+	mcp.WithNumber("limit", mcp.DefaultNumber(10), mcp.Description("max results")),
+	mcp.WithBoolean("rerank", mcp.Description("force reranking on (true) or off (false); omit to use the daemon's setting. Each result's score_kind says which scores you got")),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+func handleSearchDocs(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, err := req.RequireString("query")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	client, err := connectDaemon()
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("connecting to daemon", err), nil
+	}
+
+	searchReq := rpc.SearchRequest{
+		Query:             query,
+		Crates:            req.GetStringSlice("crates", nil),
+		Features:          req.GetStringSlice("features", nil),
+		Kinds:             req.GetStringSlice("kinds", nil),
+		ExcludeCrates:     req.GetStringSlice("exclude_crates", nil),
+		ExcludeKinds:      req.GetStringSlice("exclude_kinds", nil),
+		ExcludeDeprecated: req.GetBool("exclude_deprecated", false),
+		ExcludeUnstable:   req.GetBool("exclude_unstable", false),
+		WithDependencies:  req.GetBool("with_dependencies", false),
+		Expand:            req.GetBool("expand", false),
+		Limit:             req.GetInt("limit", 10),
+	}
+	if rerank, ok := req.GetArguments()["rerank"].(bool); ok {
+		searchReq.Rerank = &rerank
+	}
+	resp, err := client.Search(ctx, searchReq)
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("search failed", explainSearchError(ctx, client, err)), nil
+	}
+	if len(resp.Results) == 0 {
+		return mcp.NewToolResultText(noResultsText(resp)), nil
+	}
+
+	out, err := json.MarshalIndent(resp.Results, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("encoding results", err), nil
+	}
+	text := string(out)
+	if resp.RerankError != "" {
+		text = fmt.Sprintf("Reranking failed (%s); results are ordered by vector similarity.\n\n%s", resp.RerankError, text)
+	}
+	return searchResultWithLinks(fetchErrorsText(resp)+text, resp.Results), nil
+}
+
+// fetchErrorsText lists the requested crates a search couldn't fetch, such
+// as misspelled names with their suggestions, or is empty.
+func fetchErrorsText(resp *rpc.SearchResponse) string {
+	if len(resp.FetchErrors) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Some requested crates couldn't be fetched:\n")
+	for _, e := range resp.FetchErrors {
+		fmt.Fprintf(&b, "- %s\n", e)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// noResultsText reports an empty search, including any suggested crates to index.
+func noResultsText(resp *rpc.SearchResponse) string {
+	sg := resp.Suggestions
+	if sg == nil {
+		return fetchErrorsText(resp) + "no results"
+	}
+	var b strings.Builder
+	b.WriteString(fetchErrorsText(resp))
+	fmt.Fprintf(&b, "no results\n\n%s\n\n", sg.Message)
+	for _, c := range sg.Crates {
+		fmt.Fprintf(&b, "- **%s** %s: %s\n", c.Name, c.MaxVersion, c.Description)
+	}
+	return b.String()
+}
+
+// searchResultWithLinks returns text followed by a resource_link per search
+// hit, so clients can open documents without parsing URIs out of the text.
+func searchResultWithLinks(text string, results []rpc.DocResult) *mcp.CallToolResult {
+	content := []mcp.Content{mcp.NewTextContent(text)}
+	for _, r := range results {
+		desc := fmt.Sprintf("%s in %s@%s", r.Kind, r.CrateName, r.CrateVersion)
+		if r.Snippet != "" {
+			desc += ": " + r.Snippet
+		}
+		content = append(content, mcp.NewResourceLink(r.URI, r.Path, desc, "text/markdown"))
+	}
+	return &mcp.CallToolResult{Content: content}
+}
+
+var docResourceTemplate = mcp.NewResourceTemplate("rsdoc://{crate}/{version}/{+path}", "rsdoc",
+	mcp.WithTemplateDescription("Rust documentation item; path may end in a #fragment"),
+	mcp.WithTemplateMIMEType("text/markdown"),
+)
+
+func handleReadDoc(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	docReq, _, err := rpc.ParseDocURI(req.Params.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := connectDaemon()
+	if err != nil {
+		return nil, fmt.Errorf("connecting to daemon: %w", err)
+	}
+
+	// Mark links into crates that aren't indexed, so agents know following
+	// one means waiting for a fetch.
+	docReq.ResolveLinks = "annotate"
+	resp, err := client.GetDoc(ctx, docReq)
+	if err != nil {
+		return nil, err
+	}
+	return []mcp.ResourceContents{mcp.TextResourceContents{
+		URI:      req.Params.URI,
+		MIMEType: "text/markdown",
+		Text:     resp.Markdown,
+	}}, nil
+}
+
+var searchExamplesTool = mcp.NewTool("search_examples",
+	mcp.WithDescription("Semantic search over Rust code examples extracted from indexed crate docs. Returns runnable snippets with the rsdoc:// URI of the item they document."),
+	mcp.WithString("query", mcp.Required(), mcp.Description("natural language description of what the code should do")),
+	mcp.WithArray("crates", mcp.WithStringItems(), mcp.Description("restrict to these crates, optionally pinned as name@version (auto-fetched if not indexed)")),
+	mcp.WithNumber("limit", mcp.DefaultNumber(5), mcp.Description("max results")),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+func handleSearchExamples(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, err := req.RequireString("query")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	client, err := connectDaemon()
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("connecting to daemon", err), nil
+	}
+
+	resp, err := client.Search(ctx, rpc.SearchRequest{
+		Query:        query,
+		Crates:       req.GetStringSlice("crates", nil),
+		Limit:        req.GetInt("limit", 5),
+		ExamplesOnly: true,
+	})
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("search failed", explainSearchError(ctx, client, err)), nil
+	}
+	if len(resp.Results) == 0 {
+		return mcp.NewToolResultText(noResultsText(resp)), nil
+	}
+
+	var b strings.Builder
+	b.WriteString(fetchErrorsText(resp))
+	for _, r := range resp.Results {
+		fmt.Fprintf(&b, "## %s (%s@%s)\n\n%s\n\n```rust\n%s\n```\n\n", r.Path, r.CrateName, r.CrateVersion, r.URI, r.Code)
+	}
+	return searchResultWithLinks(b.String(), resp.Results), nil
+}
+
+var buildContextTool = mcp.NewTool("build_context",
+	mcp.WithDescription("Bundle rsdoc:// documents into one deduplicated markdown context trimmed to a token budget, ready to answer a question from. Pass uris to bundle specific documents, or query to search and bundle the best hits. Titles, signatures and summaries of every item are kept before full docs."),
+	mcp.WithArray("uris", mcp.WithStringItems(), mcp.Description("rsdoc:// URIs to include, most important first")),
+	mcp.WithString("query", mcp.Description("natural language question; the top search hits are bundled instead of uris")),
+	mcp.WithArray("crates", mcp.WithStringItems(), mcp.Description("with query: restrict to these crates, optionally pinned as name@version")),
+	mcp.WithNumber("limit", mcp.DefaultNumber(8), mcp.Description("with query: how many search hits to bundle")),
+	mcp.WithNumber("token_budget", mcp.DefaultNumber(8000), mcp.Description("approximate token budget for the bundle")),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+func handleBuildContext(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query := req.GetString("query", "")
+	uris := req.GetStringSlice("uris", nil)
+	if query == "" && len(uris) == 0 {
+		return mcp.NewToolResultError("either uris or query is required"), nil
+	}
+
+	client, err := connectDaemon()
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("connecting to daemon", err), nil
+	}
+
+	if query != "" {
+		resp, err := client.Context(ctx, rpc.ContextRequest{
+			Query:       query,
+			Crates:      req.GetStringSlice("crates", nil),
+			Limit:       req.GetInt("limit", 8),
+			TokenBudget: req.GetInt("token_budget", 8000),
+		})
+		if err != nil {
+			return mcp.NewToolResultErrorFromErr("build context failed", explainSearchError(ctx, client, err)), nil
+		}
+		if len(resp.Sources) == 0 {
+			return mcp.NewToolResultText(noResultsText(&rpc.SearchResponse{Suggestions: resp.Suggestions})), nil
+		}
+		return searchResultWithLinks(contextText(resp.BuildContextResponse), resp.Sources), nil
+	}
+
+	resp, err := client.BuildContext(ctx, rpc.BuildContextRequest{
+		URIs:        uris,
+		TokenBudget: req.GetInt("token_budget", 8000),
+	})
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("build context failed", err), nil
+	}
+	return mcp.NewToolResultText(contextText(*resp)), nil
+}
+
+// contextText is a context bundle with its truncated and omitted URIs noted
+// in trailing comments.
+func contextText(resp rpc.BuildContextResponse) string {
+	text := resp.Markdown
+	if len(resp.Truncated) > 0 {
+		text += fmt.Sprintf("\n\n<!-- truncated: %s -->", strings.Join(resp.Truncated, ", "))
+	}
+	if len(resp.Omitted) > 0 {
+		text += fmt.Sprintf("\n\n<!-- omitted: %s -->", strings.Join(resp.Omitted, ", "))
+	}
+	return text
+}
+
+var summarizeDocTool = mcp.NewTool("summarize_doc",
+	mcp.WithDescription("Summarize an rsdoc:// document within a small token budget: its signature, summary line, the Safety, Panics and Errors sections, a line per fragment and the first example. Cheaper than reading the whole document; read it when the summary isn't enough."),
+	mcp.WithString("uri", mcp.Required(), mcp.Description("rsdoc:// URI of the item or fragment")),
+	mcp.WithNumber("token_budget", mcp.DefaultNumber(500), mcp.Description("approximate token budget for the summary")),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+func handleSummarizeDoc(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	uri, err := req.RequireString("uri")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	client, err := connectDaemon()
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("connecting to daemon", err), nil
+	}
+
+	resp, err := client.Summarize(ctx, rpc.SummarizeRequest{URI: uri, TokenBudget: req.GetInt("token_budget", 500)})
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("summarize failed", err), nil
+	}
+	return mcp.NewToolResultText(resp.Markdown), nil
+}
+
+var crateDependenciesTool = mcp.NewTool("crate_dependencies",
+	mcp.WithDescription("List a crate's direct dependencies (with the versions docs.rs built against and whether each is indexed) and the indexed crates that depend on it."),
+	mcp.WithString("crate", mcp.Required(), mcp.Description("crate name, optionally pinned as name@version (auto-fetched if not indexed)")),
+	mcp.WithReadOnlyHintAnnotation(true),
+)
+
+func handleCrateDependencies(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	spec, err := req.RequireString("crate")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	name, version, _ := strings.Cut(spec, "@")
+
+	client, err := connectDaemon()
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("connecting to daemon", err), nil
+	}
+
+	resp, err := client.Deps(ctx, rpc.DepsRequest{Crate: name, Version: version})
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("listing dependencies failed", err), nil
+	}
+
+	out, err := json.MarshalIndent(resp, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultErrorFromErr("encoding dependencies", err), nil
+	}
+	return mcp.NewToolResultText(string(out)), nil
+}
+
 // binaryName returns "rsdoc" if it's in PATH and points to the current binary,
 // otherwise returns the full path to the binary.
 func binaryName() string {
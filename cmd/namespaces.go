@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/jcdickinson/ferrisfetch/internal/config"
+	"github.com/jcdickinson/ferrisfetch/internal/daemon"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+	"github.com/spf13/cobra"
+)
+
+var namespacesCmd = &cobra.Command{
+	Use:   "namespaces",
+	Short: "List index namespaces",
+	Long: `List the namespaces holding crates. A namespace is an isolated index within the
+one daemon: crates added with --namespace (or FERRISFETCH_NAMESPACE) are only
+seen by commands and MCP sessions using the same namespace. Without one,
+commands use the default namespace, shown as "(default)".
+
+Docs and embeddings identical across namespaces are stored once, so indexing
+a crate another namespace already has is cheap.`,
+	Example: `  rsdoc --namespace work add tokio
+  rsdoc --namespace work search "spawn a task"
+  rsdoc namespaces`,
+	Args: cobra.NoArgs,
+	Run:  runNamespaces,
+}
+
+var deleteNamespaceCmd = &cobra.Command{
+	Use:   "delete <namespace>",
+	Short: "Delete a namespace and everything only it used",
+	Long: `Remove a namespace's crates, and the docs and embeddings no other namespace
+uses. Pass "" to empty the default namespace. Without --yes it only shows what
+would be deleted.`,
+	Example:           `  rsdoc namespaces delete work --yes`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeNamespaces,
+	Run:               runDeleteNamespace,
+}
+
+var (
+	namespacesJSON     bool
+	deleteNamespaceYes bool
+)
+
+func init() {
+	namespacesCmd.Flags().BoolVar(&namespacesJSON, "json", false, "output as JSON")
+	deleteNamespaceCmd.Flags().BoolVarP(&deleteNamespaceYes, "yes", "y", false, "go ahead and delete")
+	namespacesCmd.AddCommand(deleteNamespaceCmd)
+	rootCmd.AddCommand(namespacesCmd)
+}
+
+func namespaceLabel(ns string) string {
+	if ns == "" {
+		return "(default)"
+	}
+	return ns
+}
+
+func runNamespaces(cmd *cobra.Command, args []string) {
+	client, err := connectDaemon()
+	if err != nil {
+		slog.Error("failed to connect to daemon", "error", err)
+		os.Exit(1)
+	}
+	resp, err := client.Namespaces(context.Background())
+	if err != nil {
+		slog.Error("listing namespaces failed", "error", err)
+		os.Exit(1)
+	}
+
+	if namespacesJSON {
+		out, _ := json.MarshalIndent(resp, "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+	if len(resp.Namespaces) == 0 {
+		fmt.Println("no crates indexed")
+		return
+	}
+	f := formatter()
+	for _, ns := range resp.Namespaces {
+		fmt.Printf("  %s: %s crates, %s items\n", namespaceLabel(ns.Name), f.Count(int64(ns.Crates)), f.Count(int64(ns.Items)))
+	}
+}
+
+func runDeleteNamespace(cmd *cobra.Command, args []string) {
+	client, err := connectDaemon()
+	if err != nil {
+		slog.Error("failed to connect to daemon", "error", err)
+		os.Exit(1)
+	}
+	ctx := context.Background()
+	name := args[0]
+
+	if !deleteNamespaceYes {
+		resp, err := client.Namespaces(ctx)
+		if err != nil {
+			slog.Error("listing namespaces failed", "error", err)
+			os.Exit(1)
+		}
+		for _, ns := range resp.Namespaces {
+			if ns.Name == name {
+				fmt.Printf("delete namespace %s: %d crates, %d items\n", namespaceLabel(name), ns.Crates, ns.Items)
+				fmt.Println("run again with --yes to proceed")
+				return
+			}
+		}
+		fmt.Printf("namespace %s has no crates\n", namespaceLabel(name))
+		return
+	}
+
+	resp, err := client.DeleteNamespace(ctx, rpc.DeleteNamespaceRequest{Namespace: name})
+	if err != nil {
+		slog.Error("deleting namespace failed", "error", err)
+		os.Exit(1)
+	}
+	fmt.Printf("deleted namespace %s: %d crates, %d unshared documents\n", namespaceLabel(name), resp.Crates, resp.RemovedContent)
+}
+
+// completeNamespaces suggests the namespaces holding crates.
+func completeNamespaces(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	socketPath := config.SocketPath()
+	if !daemon.Running(socketPath) {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+	resp, err := newDaemonClient(socketPath).Namespaces(ctx)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []string
+	for _, ns := range resp.Namespaces {
+		if ns.Name != "" {
+			names = append(names, ns.Name)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+	"github.com/spf13/cobra"
+)
+
+var openAPICmd = &cobra.Command{
+	Use:   "openapi",
+	Short: "Print the daemon's OpenAPI document",
+	Long: `Print the OpenAPI 3.1 document for the daemon's HTTP API, for generating
+clients. A running daemon serves the same document at GET /v1/openapi.json
+on its socket.`,
+	Args: cobra.NoArgs,
+	Run:  runOpenAPI,
+}
+
+func init() {
+	rootCmd.AddCommand(openAPICmd)
+}
+
+func runOpenAPI(cmd *cobra.Command, args []string) {
+	doc, err := rpc.OpenAPI()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(doc))
+}
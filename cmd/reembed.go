@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/jcdickinson/ferrisfetch/internal/config"
+	"github.com/jcdickinson/ferrisfetch/internal/embeddings"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+	"github.com/spf13/cobra"
+)
+
+var reembedCmd = &cobra.Command{
+	Use:   "reembed",
+	Short: "Re-embed the whole index after changing voyage_ai.model",
+	Long: `Vectors from different embedding models can't be compared, so after changing
+voyage_ai.model (or voyage_ai.dimensions) the index keeps using the model it
+was built with. This discards every embedding and re-indexes all crates with
+the configured model. Without --yes it only shows what would happen. Crates
+an interrupted re-embed didn't reach are left unprocessed, so the next add,
+search or get-doc that needs them indexes them again.`,
+	Example: `  rsdoc reembed
+  rsdoc reembed --yes`,
+	Args: cobra.NoArgs,
+	Run:  runReembed,
+}
+
+var (
+	reembedYes   bool
+	reembedForce bool
+)
+
+func init() {
+	reembedCmd.Flags().BoolVarP(&reembedYes, "yes", "y", false, "go ahead and re-embed")
+	reembedCmd.Flags().BoolVarP(&reembedForce, "force", "f", false, "re-embed even if the index already uses the configured model")
+}
+
+func runReembed(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Error("failed to load config", "error", err)
+		os.Exit(1)
+	}
+	model := cfg.VoyageAI.Model
+	dim, ok := embeddings.ModelDimension(model, cfg.VoyageAI.Dimensions)
+	if !ok {
+		slog.Error("unknown embedding dimension; set voyage_ai.dimensions", "model", model)
+		os.Exit(1)
+	}
+
+	client, err := connectDaemon()
+	if err != nil {
+		slog.Error("failed to connect to daemon", "error", err)
+		os.Exit(1)
+	}
+	status, err := client.Status(context.Background())
+	if err != nil {
+		slog.Error("status failed", "error", err)
+		os.Exit(1)
+	}
+
+	current := rpc.EmbeddingModel{}
+	if status.Model != nil {
+		current = *status.Model
+	}
+	if current.Model == model && current.Dimensions == dim && !reembedForce {
+		fmt.Printf("index already embedded with %s (%d dims)\n", model, dim)
+		return
+	}
+
+	fmt.Printf("re-embed %d crates: %s (%d dims) -> %s (%d dims)\n", len(status.Crates), modelName(current.Model), current.Dimensions, model, dim)
+	if !reembedYes {
+		fmt.Println("every chunk is sent to the embedding provider again and searches return little until it finishes")
+		fmt.Println("run again with --yes to proceed")
+		return
+	}
+
+	req := rpc.ReembedRequest{Model: model, Dimensions: cfg.VoyageAI.Dimensions}
+	resp, err := client.Reembed(context.Background(), req, func(msg string) {
+		slog.Info(msg)
+	})
+	if err != nil {
+		slog.Error("reembed failed", "error", err)
+		os.Exit(1)
+	}
+	printCrateResults(resp.Results)
+}
+
+// modelName names an index's embedding model, which older builds didn't
+// record.
+func modelName(model string) string {
+	if model == "" {
+		return "an unrecorded model"
+	}
+	return model
+}
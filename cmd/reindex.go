@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+	"github.com/spf13/cobra"
+)
+
+var reindexCmd = &cobra.Command{
+	Use:   "reindex [crate[@version]...]",
+	Short: "Re-parse crates from the JSON cache with the current parser and chunker",
+	Long: `Re-index crates from their cached rustdoc JSON, without downloading their docs
+again, so they pick up improvements to parsing, fragments and chunking. Items,
+fragments and examples are rebuilt; content whose text is unchanged keeps its
+embeddings, so only what changed is sent to the embedding provider. With no
+crates named it covers every crate in every namespace. Crates indexed from
+docs.rs HTML have no JSON to re-parse and need "rsdoc add --force".
+
+Each embedding records the model and chunker version that produced it; after
+an upgrade changes chunking, or after changing index.chunk_max_tokens or
+index.chunk_overlap_tokens, the old embeddings are stale. --changed-only
+re-indexes only the crates using stale content and re-embeds just that
+content. Embeddings stored before versions were recorded count as stale.
+"rsdoc doctor" reports how many embeddings are stale.
+
+--pause waits between crates, leaving the embedding provider's rate limit to
+searches and other adds. Crates that would go over the embedding budget
+(embedding.max_tokens_per_crate, embedding.monthly_budget_tokens) aren't
+embedded unless --over-budget is given.`,
+	Example: `  rsdoc reindex --changed-only
+  rsdoc reindex tokio serde@1.0.210
+  rsdoc reindex --pause 5s`,
+	Run: runReindex,
+}
+
+var (
+	reindexChangedOnly bool
+	reindexPause       string
+	reindexOverBudget  bool
+)
+
+func init() {
+	reindexCmd.Flags().BoolVar(&reindexChangedOnly, "changed-only", false, "only re-index crates with content embedded by another model or chunker version")
+	reindexCmd.Flags().StringVar(&reindexPause, "pause", "", "wait this long between crates (e.g. 5s)")
+	reindexCmd.Flags().BoolVar(&reindexOverBudget, "over-budget", false, "re-index even over the embedding budget")
+}
+
+func runReindex(cmd *cobra.Command, args []string) {
+	client, err := connectDaemon()
+	if err != nil {
+		slog.Error("failed to connect to daemon", "error", err)
+		os.Exit(1)
+	}
+
+	req := rpc.ReindexRequest{Crates: args, ChangedOnly: reindexChangedOnly, Pause: reindexPause, OverBudget: reindexOverBudget}
+	resp, err := client.Reindex(context.Background(), req, func(msg string) {
+		slog.Info(msg)
+	})
+	if err != nil {
+		slog.Error("reindex failed", "error", err)
+		os.Exit(1)
+	}
+	printCrateResults(resp.Results)
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/jcdickinson/ferrisfetch/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var reloadCmd = &cobra.Command{
+	Use:   "reload",
+	Short: "Make the running daemon re-read its config",
+	Long: `Make the running daemon re-read config.toml and apply the changes: the API key,
+rate limits, batching, search tuning, registries, expiration and auth token.
+The daemon also does this by itself when the file changes. Paths,
+daemon.listen, daemon.provider_check_minutes, index and vcr settings are only
+read at startup; changes to them are listed but need "rsdoc stop".
+
+A new voyage_ai.model only takes over an empty index. A populated one keeps
+the model it was embedded with until "rsdoc reembed".`,
+	Args: cobra.NoArgs,
+	Run:  runReload,
+}
+
+var reloadJSON bool
+
+func init() {
+	reloadCmd.Flags().BoolVar(&reloadJSON, "json", false, "output as JSON")
+	rootCmd.AddCommand(reloadCmd)
+}
+
+func runReload(cmd *cobra.Command, args []string) {
+	client := newDaemonClient(config.SocketPath())
+	if !client.IsAvailable() {
+		fmt.Println("daemon is not running; it reads the config when it starts")
+		return
+	}
+
+	resp, err := client.ReloadConfig(context.Background())
+	if err != nil {
+		slog.Error("reload failed", "error", err)
+		os.Exit(1)
+	}
+
+	if reloadJSON {
+		out, _ := json.MarshalIndent(resp, "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+	if len(resp.Changes) == 0 {
+		fmt.Println("config unchanged")
+		return
+	}
+	for _, c := range resp.Changes {
+		line := fmt.Sprintf("%s: %q -> %q", c.Key, c.Old, c.New)
+		if c.Restart {
+			line += " (restart the daemon to apply)"
+		}
+		fmt.Println(line)
+	}
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+	"github.com/spf13/cobra"
+)
+
+var repackCmd = &cobra.Command{
+	Use:   "repack",
+	Short: "Move the content store's loose files into a pack",
+	Long: `Move the documents stored one file each in the content store into a single
+pack file with an index, as git does. Large indexes otherwise hold hundreds of
+thousands of small files, which some filesystems and backup tools handle
+badly. Packed and loose documents are read alike, and new documents are
+stored loose until the next repack. Indexing pauses while it runs.
+
+--all also rewrites the existing packs into the new one, dropping documents
+removed since they were packed (by "rsdoc namespaces delete" or "rsdoc verify
+--repair").`,
+	Example: `  rsdoc repack
+  rsdoc repack --all`,
+	Args: cobra.NoArgs,
+	Run:  runRepack,
+}
+
+var repackAll bool
+
+func init() {
+	repackCmd.Flags().BoolVar(&repackAll, "all", false, "also merge the existing packs, dropping removed documents")
+	rootCmd.AddCommand(repackCmd)
+}
+
+func runRepack(cmd *cobra.Command, args []string) {
+	client, err := connectDaemon()
+	if err != nil {
+		slog.Error("failed to connect to daemon", "error", err)
+		os.Exit(1)
+	}
+
+	resp, err := client.Repack(context.Background(), rpc.RepackRequest{All: repackAll})
+	if err != nil {
+		slog.Error("repack failed", "error", err)
+		os.Exit(1)
+	}
+	f := formatter()
+	if resp.Packed == 0 {
+		fmt.Printf("nothing to repack (%s packs)\n", f.Count(int64(resp.Packs)))
+		return
+	}
+	fmt.Printf("packed %s documents (%s from loose files) into %s; %s packs", f.Count(int64(resp.Packed)), f.Count(int64(resp.Loose)), f.Bytes(resp.Bytes), f.Count(int64(resp.Packs)))
+	if resp.Dropped > 0 {
+		fmt.Printf(", %s removed documents dropped", f.Count(int64(resp.Dropped)))
+	}
+	fmt.Println()
+}
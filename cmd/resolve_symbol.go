@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jcdickinson/ferrisfetch/internal/daemon"
+	"github.com/jcdickinson/ferrisfetch/internal/docs"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+	"github.com/spf13/cobra"
+)
+
+var resolveSymbolCmd = &cobra.Command{
+	Use:   "resolve-symbol <path>",
+	Short: "Print the docs for a Rust path at the version a project's Cargo.lock uses",
+	Long: `Resolve a fully-qualified Rust path, as rust-analyzer or rustdoc JSON name it
+(e.g. tokio::sync::Mutex), to the crate version the project's Cargo.lock
+locks, index that version if it isn't yet, and print the item's docs. It is a
+building block for "docs for the symbol under the cursor" in editors.
+
+The crate is the path's first segment, with "_" matching "-" in crate names.
+When the lockfile has several versions of it, the one the project depends on
+directly wins. A path through the module that defines an item rather than the
+one that re-exports it (tokio::sync::mutex::Mutex) falls back to the item's
+name, if the crate has only one item called that. The standard library isn't
+in Cargo.lock; index its rustdoc JSON with "rsdoc add --file" and use "rsdoc
+get" instead.
+
+--json prints the crate, version, URI and markdown, for plugins to parse.`,
+	Example: `  rsdoc resolve-symbol tokio::sync::Mutex
+  rsdoc resolve-symbol --manifest ../server/Cargo.toml serde_json::from_str
+  rsdoc resolve-symbol --json --format plain axum::Router::route`,
+	Args: cobra.ExactArgs(1),
+	Run:  runResolveSymbol,
+}
+
+var (
+	resolveManifest string
+	resolveLockfile string
+	resolveFormat   string
+	resolveWidth    int
+	resolveJSON     bool
+)
+
+func init() {
+	resolveSymbolCmd.Flags().StringVar(&resolveManifest, "manifest", "Cargo.toml", "the project's Cargo.toml, used to find its Cargo.lock")
+	resolveSymbolCmd.Flags().StringVar(&resolveLockfile, "lockfile", "", "the project's Cargo.lock (default: found from --manifest)")
+	resolveSymbolCmd.Flags().StringVar(&resolveFormat, "format", "markdown", "output format: markdown or plain (no markup, wrapped)")
+	resolveSymbolCmd.Flags().IntVar(&resolveWidth, "width", 0, "wrap plain output to this many columns (default $COLUMNS or 80)")
+	resolveSymbolCmd.Flags().BoolVar(&resolveJSON, "json", false, "output as JSON")
+	rootCmd.AddCommand(resolveSymbolCmd)
+}
+
+// resolvedSymbol is resolve-symbol's --json output.
+type resolvedSymbol struct {
+	Crate    string `json:"crate"`
+	Version  string `json:"version"`
+	URI      string `json:"uri"`
+	Markdown string `json:"markdown"`
+}
+
+func runResolveSymbol(cmd *cobra.Command, args []string) {
+	path := symbolPath(args[0])
+	crate, _, _ := strings.Cut(path, "::")
+	if crate == "" {
+		slog.Error("invalid path", "path", args[0])
+		os.Exit(1)
+	}
+
+	lockfile := resolveLockfile
+	if lockfile == "" {
+		var err error
+		if lockfile, err = docs.FindLockfile(resolveManifest); err != nil {
+			slog.Error("finding Cargo.lock", "error", err)
+			os.Exit(1)
+		}
+	}
+	data, err := os.ReadFile(lockfile)
+	if err != nil {
+		slog.Error("reading Cargo.lock", "error", err)
+		os.Exit(1)
+	}
+	dep, err := docs.LockedVersion(data, crate)
+	if err != nil {
+		slog.Error("resolving crate", "error", err)
+		os.Exit(1)
+	}
+
+	client, err := connectDaemon()
+	if err != nil {
+		slog.Error("failed to connect to daemon", "error", err)
+		os.Exit(1)
+	}
+	ctx := context.Background()
+
+	added, err := client.AddCrates(ctx, rpc.AddCratesRequest{Crates: []rpc.CrateSpec{{Name: dep.Name, Version: dep.Version}}}, func(msg string) {
+		slog.Info(msg)
+	})
+	if err != nil {
+		slog.Error("failed to add crate", "error", err)
+		os.Exit(1)
+	}
+	for _, r := range added.Results {
+		if r.Error != "" {
+			slog.Error("failed to add crate", "crate", dep.Name+"@"+dep.Version, "error", r.Error)
+			os.Exit(1)
+		}
+	}
+
+	req := rpc.GetDocRequest{Crate: dep.Name, Version: dep.Version, Path: path, Format: resolveFormat, Width: resolveWidth}
+	if req.Width == 0 {
+		req.Width, _ = strconv.Atoi(os.Getenv("COLUMNS"))
+	}
+	resp, err := client.GetDoc(ctx, req)
+	if err != nil {
+		// rust-analyzer may name the defining module's path rather than the
+		// re-export the crate documents the item under.
+		found, ok := lookupSymbol(ctx, client, dep, path)
+		if !ok {
+			slog.Error("get doc failed", "error", err)
+			os.Exit(1)
+		}
+		req.Path = found
+		if resp, err = client.GetDoc(ctx, req); err != nil {
+			slog.Error("get doc failed", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	if resolveJSON {
+		out, _ := json.MarshalIndent(resolvedSymbol{
+			Crate:    dep.Name,
+			Version:  dep.Version,
+			URI:      fmt.Sprintf("rsdoc://%s/%s/%s", dep.Name, dep.Version, req.Path),
+			Markdown: resp.Markdown,
+		}, "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+	fmt.Print(resp.Markdown)
+}
+
+// symbolPath trims what editors add around a path: surrounding space, a
+// leading "::" and generic arguments.
+func symbolPath(raw string) string {
+	path := strings.TrimPrefix(strings.TrimSpace(raw), "::")
+	var b strings.Builder
+	depth := 0
+	for _, r := range path {
+		switch {
+		case r == '<':
+			depth++
+		case r == '>' && depth > 0:
+			depth--
+		case depth == 0:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// lookupSymbol finds the indexed path of path's item in dep by the path
+// itself, then by its name when only one item in the crate has it.
+func lookupSymbol(ctx context.Context, client *daemon.Client, dep docs.LockedDependency, path string) (string, bool) {
+	crates := []string{dep.Name + "@" + dep.Version}
+	name := path
+	if i := strings.LastIndex(path, "::"); i >= 0 {
+		name = path[i+2:]
+	}
+	for _, query := range []string{path, name} {
+		resp, err := client.Symbols(ctx, rpc.SymbolsRequest{Query: query, Exact: true, Crates: crates, Limit: 2})
+		if err != nil {
+			slog.Debug("symbol lookup failed", "query", query, "error", err)
+			return "", false
+		}
+		if len(resp.Results) == 1 {
+			return resp.Results[0].Path, true
+		}
+	}
+	return "", false
+}
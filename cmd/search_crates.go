@@ -15,15 +15,20 @@ var searchCratesCmd = &cobra.Command{
 	Short: "Search crates.io for Rust crates",
 	Example: `  rsdoc search-crates serde
   rsdoc search-crates "async http client"
-  rsdoc search-crates --limit 5 tokio`,
+  rsdoc search-crates --limit 5 tokio
+  rsdoc search-crates --registry corp billing`,
 	Args: cobra.ExactArgs(1),
 	Run:  runSearchCrates,
 }
 
-var searchCratesLimit int
+var (
+	searchCratesLimit    int
+	searchCratesRegistry string
+)
 
 func init() {
 	searchCratesCmd.Flags().IntVar(&searchCratesLimit, "limit", 20, "max results")
+	searchCratesCmd.Flags().StringVar(&searchCratesRegistry, "registry", "", "search an alternative registry from the config instead of crates.io")
 }
 
 func runSearchCrates(cmd *cobra.Command, args []string) {
@@ -34,8 +39,9 @@ func runSearchCrates(cmd *cobra.Command, args []string) {
 	}
 
 	resp, err := client.SearchCrates(context.Background(), rpc.SearchCratesRequest{
-		Query: args[0],
-		Limit: searchCratesLimit,
+		Query:    args[0],
+		Limit:    searchCratesLimit,
+		Registry: searchCratesRegistry,
 	})
 	if err != nil {
 		slog.Error("search failed", "error", err)
@@ -11,17 +11,30 @@ import (
 	"github.com/jcdickinson/ferrisfetch/internal/config"
 	"github.com/jcdickinson/ferrisfetch/internal/daemon"
 	"github.com/jcdickinson/ferrisfetch/internal/db"
+	"github.com/jcdickinson/ferrisfetch/internal/humanize"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 //go:embed agent_help.md
 var agentHelp string
 
-var debug bool
+var (
+	debug     bool
+	utcTimes  bool
+	locale    string
+	namespace string
+)
 
 var rootCmd = &cobra.Command{
 	Use:   "rsdoc",
 	Short: "Rust documentation semantic search",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// Paths are needed before most commands load the config. A broken
+		// config file is reported when they do, or by rsdoc doctor.
+		config.InitializeViper()
+	},
 }
 
 func Execute() {
@@ -33,6 +46,19 @@ func Execute() {
 
 func init() {
 	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "run daemon in-process (visible log output)")
+	rootCmd.PersistentFlags().BoolVar(&utcTimes, "utc", false, "print absolute UTC timestamps (RFC 3339)")
+	rootCmd.PersistentFlags().StringVar(&locale, "locale", "", "locale for number formatting (default from LC_ALL/LC_NUMERIC/LANG; C disables grouping)")
+	rootCmd.PersistentFlags().StringVar(&namespace, "namespace", os.Getenv("FERRISFETCH_NAMESPACE"), "index namespace to use (see rsdoc namespaces)")
+	pathUsage := map[string]string{
+		"paths.cache_dir": "cache directory (paths.cache_dir)",
+		"paths.db":        "database file (paths.db)",
+		"paths.cas":       "content store directory (paths.cas)",
+		"paths.socket":    "daemon socket (paths.socket)",
+	}
+	for _, p := range config.PathFlags {
+		rootCmd.PersistentFlags().String(p.Flag, "", pathUsage[p.Key])
+		viper.BindPFlag(p.Key, rootCmd.PersistentFlags().Lookup(p.Flag))
+	}
 
 	rootCmd.AddCommand(daemonCmd)
 	rootCmd.AddCommand(addCmd)
@@ -42,6 +68,16 @@ func init() {
 	rootCmd.AddCommand(logsCmd)
 	rootCmd.AddCommand(clearCacheCmd)
 	rootCmd.AddCommand(searchCratesCmd)
+	rootCmd.AddCommand(buildContextCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(depsCmd)
+	rootCmd.AddCommand(verifyCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(snapshotCmd)
+	rootCmd.AddCommand(restoreCmd)
+	rootCmd.AddCommand(reembedCmd)
+	rootCmd.AddCommand(reindexCmd)
 	rootCmd.AddCommand(mcpCmd)
 
 	defaultHelp := rootCmd.HelpFunc()
@@ -54,6 +90,11 @@ func init() {
 	})
 }
 
+// formatter returns the number and time formatter selected by --locale and --utc.
+func formatter() humanize.Formatter {
+	return humanize.New(locale, utcTimes)
+}
+
 func isAgent() bool {
 	return os.Getenv("CLAUDECODE") == "1" || os.Getenv("AGENT") == "1"
 }
@@ -61,27 +102,35 @@ func isAgent() bool {
 // connectDaemon returns a daemon client. In debug mode, starts the daemon
 // in-process so all log output is visible in the terminal.
 func connectDaemon() (*daemon.Client, error) {
+	if standaloneClient != nil {
+		return standaloneClient, nil
+	}
 	socketPath := config.SocketPath()
 
 	if !debug {
-		return daemon.ConnectOrSpawn(socketPath)
+		client, err := daemon.ConnectOrSpawn(socketPath)
+		if err != nil {
+			return nil, err
+		}
+		configureClient(client)
+		if err := client.EnsureProtocol(context.Background()); err != nil {
+			return nil, err
+		}
+		return client, nil
 	}
 
 	// In debug mode: stop any existing daemon, then start in-process
-	client := daemon.NewClient(socketPath)
-	if client.IsAvailable() {
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		client.Shutdown(shutdownCtx)
-		cancel()
-		time.Sleep(200 * time.Millisecond)
+	if err := stopDaemon(); err != nil {
+		return nil, err
 	}
 
 	cfg, err := config.Load()
 	if err != nil {
 		return nil, fmt.Errorf("loading config: %w", err)
 	}
+	config.PinPaths()
 
-	database, err := db.New(config.DBPath())
+	database, err := db.NewWithOptions(config.DBPath(), db.Options{Storage: cfg.Index.Storage, Quantization: cfg.Index.Quantization})
 	if err != nil {
 		return nil, fmt.Errorf("opening database: %w", err)
 	}
@@ -93,6 +142,7 @@ func connectDaemon() (*daemon.Client, error) {
 		}
 	}()
 
+	client := newDaemonClient(socketPath)
 	deadline := time.Now().Add(5 * time.Second)
 	for time.Now().Before(deadline) {
 		time.Sleep(50 * time.Millisecond)
@@ -103,3 +153,80 @@ func connectDaemon() (*daemon.Client, error) {
 
 	return nil, fmt.Errorf("in-process daemon did not start within 5 seconds")
 }
+
+// standaloneClient is the client of the daemon startStandalone runs in
+// this process; connectDaemon returns it when set.
+var standaloneClient *daemon.Client
+
+// startStandalone runs the daemon in this process, without a socket, and
+// points connectDaemon at it. The returned function stops the daemon,
+// letting in-flight work drain first.
+func startStandalone(ctx context.Context) (func(), error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+	config.PinPaths()
+
+	database, err := db.NewWithOptions(config.DBPath(), db.Options{Storage: cfg.Index.Storage, Quantization: cfg.Index.Quantization})
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+
+	srv := daemon.NewServer(cfg, database, config.SocketPath())
+	srv.SetConfigLoader(config.Load)
+	client, err := srv.StartInProcess(ctx)
+	if err != nil {
+		database.Close()
+		return nil, fmt.Errorf("starting daemon: %w", err)
+	}
+	client.SetNamespace(namespace)
+	standaloneClient = client
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), daemon.DrainTimeout)
+		defer cancel()
+		if err := srv.Stop(ctx); err != nil {
+			slog.Error("stopping daemon", "error", err)
+		}
+	}, nil
+}
+
+// newDaemonClient returns a client for the daemon at socketPath that
+// authenticates with daemon.auth_token if one is configured and uses the
+// --namespace namespace.
+func newDaemonClient(socketPath string) *daemon.Client {
+	client := daemon.NewClient(socketPath)
+	configureClient(client)
+	return client
+}
+
+func configureClient(client *daemon.Client) {
+	client.SetNamespace(namespace)
+	token, err := config.AuthToken()
+	if err != nil {
+		slog.Warn("not authenticating to the daemon", "error", err)
+		return
+	}
+	client.SetAuthToken(token)
+}
+
+// daemonCapabilities asks the daemon what it supports. It returns nil when
+// that's unknown, e.g. the daemon predates GET /capabilities; callers then
+// assume everything is available.
+func daemonCapabilities(ctx context.Context, client *daemon.Client) *rpc.CapabilitiesResponse {
+	caps, err := client.Capabilities(ctx)
+	if err != nil {
+		slog.Debug("daemon capabilities unavailable", "error", err)
+		return nil
+	}
+	return caps
+}
+
+// explainSearchError replaces a search failure with a configuration hint
+// when the daemon has no embedding provider to search with.
+func explainSearchError(ctx context.Context, client *daemon.Client, err error) error {
+	if caps := daemonCapabilities(ctx, client); caps != nil && caps.Embeddings == "" {
+		return fmt.Errorf("semantic search is unavailable: no embedding provider is configured (set voyage_ai.api_key)")
+	}
+	return err
+}
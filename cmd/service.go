@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jcdickinson/ferrisfetch/internal/config"
+	"github.com/jcdickinson/ferrisfetch/internal/daemon"
+	"github.com/spf13/cobra"
+)
+
+var installServiceCmd = &cobra.Command{
+	Use:   "install-service",
+	Short: "Install systemd user units that start the daemon on demand",
+	Long: `Write ferrisfetch.socket and ferrisfetch.service to the systemd user unit
+directory. With the socket enabled, systemd listens on the daemon socket and
+starts the daemon on the first connection, instead of rsdoc spawning it.
+The daemon still exits when idle; systemd starts it again when needed.
+
+The service doesn't see your shell's environment, so configure the API key
+in ~/.config/ferrisfetch/config.toml rather than FERRISFETCH_* variables.`,
+	Args: cobra.NoArgs,
+	Run:  runInstallService,
+}
+
+var installServicePrint bool
+
+func init() {
+	installServiceCmd.Flags().BoolVar(&installServicePrint, "print", false, "print the units instead of writing them")
+	rootCmd.AddCommand(installServiceCmd)
+}
+
+func runInstallService(cmd *cobra.Command, args []string) {
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: finding executable path: %v\n", err)
+		os.Exit(1)
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+
+	units := []struct{ name, body string }{
+		{"ferrisfetch.socket", fmt.Sprintf(`[Unit]
+Description=rsdoc daemon socket
+
+[Socket]
+ListenStream=%s
+SocketMode=0600
+DirectoryMode=0700
+
+[Install]
+WantedBy=sockets.target
+`, config.SocketPath())},
+		{"ferrisfetch.service", fmt.Sprintf(`[Unit]
+Description=rsdoc daemon
+Requires=ferrisfetch.socket
+
+[Service]
+ExecStart=%s
+TimeoutStopSec=%d
+`, execStart(exe), int((daemon.DrainTimeout + 30*time.Second).Seconds()))},
+	}
+
+	if installServicePrint {
+		for _, u := range units {
+			fmt.Printf("# %s\n%s\n", u.name, u.body)
+		}
+		return
+	}
+
+	dir := systemdUserDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, u := range units {
+		path := filepath.Join(dir, u.name)
+		if err := os.WriteFile(path, []byte(u.body), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("wrote %s\n", path)
+	}
+	fmt.Println(`
+Enable it with:
+  rsdoc stop
+  systemctl --user daemon-reload
+  systemctl --user enable --now ferrisfetch.socket`)
+}
+
+// systemdUserDir is where systemd looks for a user's own units.
+func systemdUserDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "systemd", "user")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "systemd", "user")
+}
+
+// execStart is the service's command line: the daemon with any paths set by
+// flags, each argument quoted for systemd.
+func execStart(exe string) string {
+	args := []string{strconv.Quote(exe), "daemon"}
+	for _, arg := range config.PathArgs() {
+		args = append(args, strconv.Quote(arg))
+	}
+	return strings.Join(args, " ")
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+	"github.com/spf13/cobra"
+)
+
+var similarCmd = &cobra.Command{
+	Use:   "similar <rsdoc://crate/version/path>",
+	Short: "Find items related to a documentation item",
+	Long:  "Find items whose documentation is semantically close to the given item's, using the stored embeddings (no query is embedded).",
+	Example: `  rsdoc similar rsdoc://tokio/latest/tokio::sync::Mutex
+  rsdoc similar --crate tokio --crate parking_lot tokio/latest/tokio::sync::Mutex
+  rsdoc similar serde/latest/serde::Serialize#implementations`,
+	Args: cobra.ExactArgs(1),
+	Run:  runSimilar,
+}
+
+var (
+	similarCrates []string
+	similarLimit  int
+)
+
+func init() {
+	similarCmd.Flags().StringSliceVar(&similarCrates, "crate", nil, "only return items from these crates, optionally pinned as name@version (repeatable)")
+	similarCmd.RegisterFlagCompletionFunc("crate", completeCrateSpecs)
+	similarCmd.Flags().IntVar(&similarLimit, "limit", 10, "max results")
+	rootCmd.AddCommand(similarCmd)
+}
+
+func runSimilar(cmd *cobra.Command, args []string) {
+	client, err := connectDaemon()
+	if err != nil {
+		slog.Error("failed to connect to daemon", "error", err)
+		os.Exit(1)
+	}
+
+	resp, err := client.Similar(context.Background(), rpc.SimilarRequest{
+		URI:    args[0],
+		Crates: similarCrates,
+		Limit:  similarLimit,
+	})
+	if err != nil {
+		slog.Error("similar failed", "error", err)
+		os.Exit(1)
+	}
+
+	if len(resp.Results) == 0 {
+		fmt.Printf("no items similar to %s\n", resp.URI)
+		return
+	}
+	fmt.Printf("similar to %s:\n\n", resp.URI)
+	printDocResults(resp.Results)
+}
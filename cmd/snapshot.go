@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+	"github.com/spf13/cobra"
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot <path>",
+	Short: "Copy the database and vector index while the daemon keeps running",
+	Long: `Write a consistent copy of the database to <path> and the vector index next to
+it (same name, .hnsw extension). Indexing pauses while the copy is made. The
+content store and rustdoc JSON cache are not included; use "rsdoc export" for
+a self-contained archive.`,
+	Example: `  rsdoc snapshot ~/backups/ferrisfetch-$(date +%F).db`,
+	Args:    cobra.ExactArgs(1),
+	Run:     runSnapshot,
+}
+
+var restoreCmd = &cobra.Command{
+	Use:     "restore <path>",
+	Short:   "Replace the database and vector index with a snapshot",
+	Example: `  rsdoc restore ~/backups/ferrisfetch-2026-01-31.db`,
+	Args:    cobra.ExactArgs(1),
+	Run:     runRestore,
+}
+
+func runSnapshot(cmd *cobra.Command, args []string) {
+	path, err := filepath.Abs(args[0])
+	if err != nil {
+		slog.Error("invalid path", "error", err)
+		os.Exit(1)
+	}
+	client, err := connectDaemon()
+	if err != nil {
+		slog.Error("failed to connect to daemon", "error", err)
+		os.Exit(1)
+	}
+
+	resp, err := client.Snapshot(context.Background(), rpc.SnapshotRequest{Path: path})
+	if err != nil {
+		slog.Error("snapshot failed", "error", err)
+		os.Exit(1)
+	}
+	if resp.HNSWPath == "" {
+		fmt.Printf("snapshot written to %s (%s)\n", resp.Path, formatter().Bytes(resp.Bytes))
+		return
+	}
+	fmt.Printf("snapshot written to %s and %s (%s)\n", resp.Path, resp.HNSWPath, formatter().Bytes(resp.Bytes))
+}
+
+func runRestore(cmd *cobra.Command, args []string) {
+	path, err := filepath.Abs(args[0])
+	if err != nil {
+		slog.Error("invalid path", "error", err)
+		os.Exit(1)
+	}
+	client, err := connectDaemon()
+	if err != nil {
+		slog.Error("failed to connect to daemon", "error", err)
+		os.Exit(1)
+	}
+
+	resp, err := client.Restore(context.Background(), rpc.RestoreRequest{Path: path})
+	if err != nil {
+		slog.Error("restore failed", "error", err)
+		os.Exit(1)
+	}
+	fmt.Printf("restored %d crates from %s\n", resp.Crates, path)
+}
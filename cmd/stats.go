@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+	"github.com/spf13/cobra"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show what each indexed crate takes up on disk",
+	Long: `List each indexed crate's items, embedded chunks, and the space its embeddings,
+content store documents and cached rustdoc JSON take, largest first. Docs
+shared between crates (often unchanged between versions) are stored once;
+"shared" is how much of a crate's content other crates use too.
+
+Embedding cost is the tokens Voyage billed for every add of the crate, priced
+at voyage_ai.price_per_million_tokens when that is set.`,
+	Args: cobra.NoArgs,
+	Run:  runStats,
+}
+
+var (
+	statsJSON   bool
+	statsByName bool
+)
+
+func init() {
+	statsCmd.Flags().BoolVar(&statsJSON, "json", false, "output as JSON")
+	statsCmd.Flags().BoolVar(&statsByName, "by-name", false, "sort crates by name instead of size")
+	rootCmd.AddCommand(statsCmd)
+}
+
+func runStats(cmd *cobra.Command, args []string) {
+	client, err := connectDaemon()
+	if err != nil {
+		slog.Error("failed to connect to daemon", "error", err)
+		os.Exit(1)
+	}
+
+	resp, err := client.Stats(context.Background())
+	if err != nil {
+		slog.Error("stats failed", "error", err)
+		os.Exit(1)
+	}
+
+	if statsJSON {
+		out, _ := json.MarshalIndent(resp, "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+
+	f := formatter()
+	if !statsByName {
+		size := func(c rpc.CrateStats) int64 { return c.EmbeddingBytes + c.CASBytes + c.JSONCacheBytes }
+		sort.SliceStable(resp.Crates, func(i, j int) bool { return size(resp.Crates[i]) > size(resp.Crates[j]) })
+	}
+	for _, c := range resp.Crates {
+		label := c.Name + "@" + c.Version
+		if c.Namespace != "" {
+			label = c.Namespace + "/" + label
+		}
+		fmt.Printf("  %s: %s items, %s chunks; embeddings %s, docs %s",
+			label, f.Count(int64(c.Items)), f.Count(int64(c.Chunks)), f.Bytes(c.EmbeddingBytes), f.Bytes(c.CASBytes))
+		if c.SharedBytes > 0 {
+			fmt.Printf(" (%s shared)", f.Bytes(c.SharedBytes))
+		}
+		if c.JSONCacheBytes > 0 {
+			fmt.Printf(", json %s", f.Bytes(c.JSONCacheBytes))
+		}
+		if c.Tokens > 0 {
+			fmt.Printf("; embedding cost %s", usageNote(f, c.Tokens, c.Cost))
+		}
+		fmt.Println()
+	}
+	if len(resp.Crates) > 0 {
+		fmt.Println()
+	}
+
+	fmt.Printf("%d crates, %s items, %s documents, %s chunks\n",
+		len(resp.Crates), f.Count(int64(resp.Items)), f.Count(int64(resp.Documents)), f.Count(int64(resp.Chunks)))
+	fmt.Printf("database %s (embeddings %s), vector index %s (%s nodes)\n",
+		f.Bytes(resp.DatabaseBytes), f.Bytes(resp.EmbeddingBytes), f.Bytes(resp.HNSWBytes), f.Count(int64(resp.IndexNodes)))
+	fmt.Printf("content store %s", f.Bytes(resp.CASBytes))
+	if unreferenced := resp.CASDiskBytes - resp.CASBytes; unreferenced > 0 {
+		fmt.Printf(" (+%s unreferenced)", f.Bytes(unreferenced))
+	}
+	fmt.Printf(", json cache %s\n", f.Bytes(resp.JSONCacheBytes))
+	if resp.Tokens > 0 {
+		fmt.Printf("embedding cost %s\n", usageNote(f, resp.Tokens, resp.Cost))
+	}
+	if resp.DedupSavedBytes > 0 {
+		fmt.Printf("deduplication saves %s\n", f.Bytes(resp.DedupSavedBytes))
+	}
+}
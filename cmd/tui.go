@@ -0,0 +1,432 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jcdickinson/ferrisfetch/internal/daemon"
+	"github.com/jcdickinson/ferrisfetch/internal/markdown"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+	"github.com/spf13/cobra"
+)
+
+var tuiCmd = &cobra.Command{
+	Use:   "tui [query]",
+	Short: "Browse documentation interactively",
+	Long: `Search indexed documentation and read it in the terminal.
+
+Type a query and press enter to search. Move through results with ↑/↓ and
+press enter to read one. In the preview, tab and shift+tab select links
+(fragments first), enter follows the selected link and backspace goes back.
+Press / to search again, esc to switch panes, and ctrl+c to quit.`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runTUI,
+}
+
+var (
+	tuiCrates []string
+	tuiLimit  int
+)
+
+func init() {
+	tuiCmd.Flags().StringSliceVar(&tuiCrates, "crate", nil, "filter to specific crates, optionally pinned as name@version (repeatable)")
+	tuiCmd.RegisterFlagCompletionFunc("crate", completeCrateSpecs)
+	tuiCmd.Flags().IntVar(&tuiLimit, "limit", 20, "max results per search")
+	rootCmd.AddCommand(tuiCmd)
+}
+
+func runTUI(cmd *cobra.Command, args []string) {
+	client, err := connectDaemon()
+	if err != nil {
+		slog.Error("failed to connect to daemon", "error", err)
+		os.Exit(1)
+	}
+
+	m := newTUIModel(client)
+	if len(args) > 0 {
+		m.input.SetValue(args[0])
+	}
+	if _, err := tea.NewProgram(m, tea.WithAltScreen()).Run(); err != nil {
+		slog.Error("tui failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+// Panes of the TUI, in the order esc cycles through them.
+const (
+	paneSearch = iota
+	paneResults
+	panePreview
+)
+
+// tuiPage is a document shown in the preview pane.
+type tuiPage struct {
+	uri      string
+	markdown string
+	links    []markdown.Link
+	link     int // selected link, -1 for none
+	offset   int // scroll position, restored on back
+}
+
+type tuiModel struct {
+	client *daemon.Client
+
+	input   textinput.Model
+	preview viewport.Model
+	focus   int
+
+	results []rpc.DocResult
+	cursor  int
+
+	page    *tuiPage
+	history []*tuiPage
+	// loading is the URI being fetched; responses for others are stale.
+	loading string
+
+	status        string
+	width, height int
+}
+
+type tuiSearchMsg struct {
+	query string
+	resp  *rpc.SearchResponse
+	err   error
+}
+
+type tuiDocMsg struct {
+	uri  string
+	resp *rpc.GetDocResponse
+	err  error
+	// back restores this page's scroll position and selected link.
+	back *tuiPage
+}
+
+var (
+	tuiPaneStyle    = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("8"))
+	tuiFocusStyle   = tuiPaneStyle.BorderForeground(lipgloss.Color("12"))
+	tuiCursorStyle  = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
+	tuiDimStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	tuiLinkStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("14"))
+	tuiStatusStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
+	tuiSnippetStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("7"))
+)
+
+func newTUIModel(client *daemon.Client) tuiModel {
+	input := textinput.New()
+	input.Placeholder = "search docs, e.g. spawn a blocking task"
+	input.Prompt = "search: "
+	input.Focus()
+	return tuiModel{client: client, input: input, preview: viewport.New(0, 0)}
+}
+
+func (m tuiModel) Init() tea.Cmd {
+	if strings.TrimSpace(m.input.Value()) != "" {
+		return tea.Batch(textinput.Blink, m.search())
+	}
+	return textinput.Blink
+}
+
+func (m tuiModel) search() tea.Cmd {
+	query := strings.TrimSpace(m.input.Value())
+	client := m.client
+	return func() tea.Msg {
+		resp, err := client.Search(context.Background(), rpc.SearchRequest{Query: query, Crates: tuiCrates, Limit: tuiLimit})
+		if err != nil {
+			err = explainSearchError(context.Background(), client, err)
+		}
+		return tuiSearchMsg{query: query, resp: resp, err: err}
+	}
+}
+
+func (m *tuiModel) open(uri string, back *tuiPage) tea.Cmd {
+	m.loading = uri
+	m.status = "loading " + uri
+	client := m.client
+	return func() tea.Msg {
+		req, _, err := rpc.ParseDocURI(uri)
+		if err != nil {
+			return tuiDocMsg{uri: uri, err: err, back: back}
+		}
+		resp, err := client.GetDoc(context.Background(), req)
+		return tuiDocMsg{uri: uri, resp: resp, err: err, back: back}
+	}
+}
+
+func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.layout()
+		return m, nil
+
+	case tuiSearchMsg:
+		if msg.query != strings.TrimSpace(m.input.Value()) {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.status = "search failed: " + msg.err.Error()
+			return m, nil
+		}
+		m.results, m.cursor = msg.resp.Results, 0
+		m.status = fmt.Sprintf("%d results", len(m.results))
+		if len(m.results) == 0 {
+			m.status = "no results"
+			if sg := msg.resp.Suggestions; sg != nil {
+				m.status += "; " + sg.Message
+			}
+			return m, nil
+		}
+		m.setFocus(paneResults)
+		m.history = nil
+		return m, m.open(m.results[0].URI, nil)
+
+	case tuiDocMsg:
+		if msg.uri != m.loading {
+			return m, nil
+		}
+		m.loading = ""
+		if msg.err != nil {
+			m.status = "get failed: " + msg.err.Error()
+			return m, nil
+		}
+		m.status = ""
+		page := &tuiPage{uri: msg.uri, markdown: msg.resp.Markdown, links: markdown.Links(msg.resp.Markdown), link: -1}
+		if msg.back != nil {
+			page.link, page.offset = msg.back.link, msg.back.offset
+		}
+		m.page = page
+		m.render()
+		m.preview.SetYOffset(page.offset)
+		return m, nil
+
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		switch m.focus {
+		case paneSearch:
+			return m.updateSearch(msg)
+		case paneResults:
+			return m.updateResults(msg)
+		case panePreview:
+			return m.updatePreview(msg)
+		}
+	}
+	return m, nil
+}
+
+func (m tuiModel) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		if strings.TrimSpace(m.input.Value()) == "" {
+			return m, nil
+		}
+		m.status = "searching…"
+		return m, m.search()
+	case "esc":
+		if len(m.results) > 0 {
+			m.setFocus(paneResults)
+		}
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m tuiModel) updateResults(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q":
+		return m, tea.Quit
+	case "/":
+		m.setFocus(paneSearch)
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+			m.history = nil
+			return m, m.open(m.results[m.cursor].URI, nil)
+		}
+	case "down", "j":
+		if m.cursor < len(m.results)-1 {
+			m.cursor++
+			m.history = nil
+			return m, m.open(m.results[m.cursor].URI, nil)
+		}
+	case "enter", "right", "l", "esc", "tab":
+		if m.page != nil {
+			m.setFocus(panePreview)
+		}
+	}
+	return m, nil
+}
+
+func (m tuiModel) updatePreview(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q":
+		return m, tea.Quit
+	case "/":
+		m.setFocus(paneSearch)
+		return m, nil
+	case "esc", "left", "h":
+		m.setFocus(paneResults)
+		return m, nil
+	case "tab", "shift+tab":
+		if m.page == nil || len(m.page.links) == 0 {
+			return m, nil
+		}
+		n := len(m.page.links)
+		if msg.String() == "tab" {
+			m.page.link = (m.page.link + 1) % n
+		} else {
+			m.page.link = (m.page.link - 1 + n) % n
+		}
+		m.render()
+		return m, nil
+	case "enter":
+		if m.page == nil || m.page.link < 0 {
+			return m, nil
+		}
+		m.page.offset = m.preview.YOffset
+		m.history = append(m.history, m.page)
+		return m, m.open(m.page.links[m.page.link].URI, nil)
+	case "backspace":
+		if len(m.history) == 0 {
+			m.setFocus(paneResults)
+			return m, nil
+		}
+		prev := m.history[len(m.history)-1]
+		m.history = m.history[:len(m.history)-1]
+		return m, m.open(prev.uri, prev)
+	}
+	var cmd tea.Cmd
+	m.preview, cmd = m.preview.Update(msg)
+	return m, cmd
+}
+
+func (m *tuiModel) setFocus(pane int) {
+	m.focus = pane
+	if pane == paneSearch {
+		m.input.Focus()
+	} else {
+		m.input.Blur()
+	}
+}
+
+// listWidth is the width of the results pane, borders included.
+func (m tuiModel) listWidth() int {
+	return max(m.width*2/5, 24)
+}
+
+func (m *tuiModel) layout() {
+	m.input.Width = max(m.width-len(m.input.Prompt)-1, 10)
+	// Two rows for the search box and status line, two for borders.
+	m.preview.Width = max(m.width-m.listWidth()-2, 10)
+	m.preview.Height = max(m.height-4, 1)
+	m.render()
+}
+
+// render lays out the current page in the preview pane, with its links
+// listed at the end and the selected one highlighted.
+func (m *tuiModel) render() {
+	if m.page == nil {
+		return
+	}
+	var b strings.Builder
+	b.WriteString(tuiDimStyle.Render(m.page.uri))
+	b.WriteString("\n\n")
+	b.WriteString(markdown.PlainText(m.page.markdown, m.preview.Width))
+	if len(m.page.links) > 0 {
+		b.WriteString("\n")
+		b.WriteString(tuiDimStyle.Render("Links (tab to select, enter to follow):"))
+		b.WriteString("\n")
+		for i, l := range m.page.links {
+			line := fmt.Sprintf("  %s  %s", l.Text, strings.TrimPrefix(l.URI, "rsdoc://"))
+			if i == m.page.link {
+				line = tuiCursorStyle.Render("▸ " + line[2:])
+			} else {
+				line = tuiLinkStyle.Render(line)
+			}
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+	}
+	m.preview.SetContent(b.String())
+	if m.page.link >= 0 {
+		// Scroll the selected link into view.
+		row := strings.Count(b.String(), "\n") - len(m.page.links) + m.page.link
+		if row < m.preview.YOffset || row >= m.preview.YOffset+m.preview.Height {
+			m.preview.SetYOffset(row - m.preview.Height/2)
+		}
+	}
+}
+
+func (m tuiModel) View() string {
+	if m.width == 0 {
+		return ""
+	}
+	listStyle, previewStyle := tuiPaneStyle, tuiPaneStyle
+	switch m.focus {
+	case paneResults:
+		listStyle = tuiFocusStyle
+	case panePreview:
+		previewStyle = tuiFocusStyle
+	}
+
+	innerList := m.listWidth() - 2
+	list := listStyle.Width(innerList).Height(m.preview.Height).Render(m.viewResults(innerList))
+	preview := previewStyle.Width(m.preview.Width).Height(m.preview.Height).Render(m.preview.View())
+
+	status := m.status
+	if len(m.history) > 0 {
+		status = fmt.Sprintf("%d back · %s", len(m.history), status)
+	}
+	return lipgloss.JoinVertical(lipgloss.Left,
+		m.input.View(),
+		lipgloss.JoinHorizontal(lipgloss.Top, list, preview),
+		tuiStatusStyle.Render(truncateLine(status, m.width)),
+	)
+}
+
+// viewResults lists search results in width columns, keeping the cursor in
+// view.
+func (m tuiModel) viewResults(width int) string {
+	if len(m.results) == 0 {
+		return tuiDimStyle.Render("no results")
+	}
+	// Each result takes two lines: its path and its crate and snippet.
+	visible := max(m.preview.Height/2, 1)
+	start := max(m.cursor-visible+1, 0)
+	var lines []string
+	for i := start; i < len(m.results) && i < start+visible; i++ {
+		r := m.results[i]
+		title := truncateLine(fmt.Sprintf("%s (%s)", hitPath(r), r.Kind), width-2)
+		detail := truncateLine(fmt.Sprintf("%s@%s %s", r.CrateName, r.CrateVersion, strings.ReplaceAll(r.Snippet, "**", "")), width-2)
+		if i == m.cursor {
+			lines = append(lines, tuiCursorStyle.Render("▸ "+title))
+		} else {
+			lines = append(lines, "  "+title)
+		}
+		lines = append(lines, "  "+tuiSnippetStyle.Render(detail))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncateLine shortens s to one line of at most width runes.
+func truncateLine(s string, width int) string {
+	s, _, _ = strings.Cut(s, "\n")
+	r := []rune(s)
+	if width <= 0 || len(r) <= width {
+		return s
+	}
+	if width == 1 {
+		return "…"
+	}
+	return string(r[:width-1]) + "…"
+}
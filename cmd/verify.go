@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+	"github.com/spf13/cobra"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check the index for missing content, embeddings and cache files",
+	Long: `Cross-check indexed items against the content store, embeddings against the
+vector index, and crates against the rustdoc JSON cache. With --repair, patch
+the vector index and re-index only the crates with missing pieces.`,
+	Example: `  rsdoc verify
+  rsdoc verify --repair`,
+	Args: cobra.NoArgs,
+	Run:  runVerify,
+}
+
+var (
+	verifyRepair bool
+	verifyJSON   bool
+)
+
+func init() {
+	verifyCmd.Flags().BoolVar(&verifyRepair, "repair", false, "repair problems that are found")
+	verifyCmd.Flags().BoolVar(&verifyJSON, "json", false, "output as JSON")
+}
+
+func runVerify(cmd *cobra.Command, args []string) {
+	client, err := connectDaemon()
+	if err != nil {
+		slog.Error("failed to connect to daemon", "error", err)
+		os.Exit(1)
+	}
+
+	resp, err := client.Verify(context.Background(), rpc.VerifyRequest{Repair: verifyRepair})
+	if err != nil {
+		slog.Error("verify failed", "error", err)
+		os.Exit(1)
+	}
+
+	if verifyJSON {
+		out, _ := json.MarshalIndent(resp, "", "  ")
+		fmt.Println(string(out))
+	} else {
+		printVerify(resp)
+	}
+
+	if len(resp.Errors) > 0 || (!resp.OK() && !verifyRepair) {
+		os.Exit(1)
+	}
+}
+
+func printVerify(resp *rpc.VerifyResponse) {
+	f := formatter()
+	fmt.Printf("checked %s content hashes\n", f.Count(int64(resp.ContentHashes)))
+	report := func(label string, n int) {
+		if n > 0 {
+			fmt.Printf("  %s: %s\n", label, f.Count(int64(n)))
+		}
+	}
+	report("missing from content store", len(resp.MissingContent))
+	report("without embeddings", len(resp.Unembedded))
+	report("embeddings missing from vector index", resp.MissingFromIndex)
+	report("orphaned vector index entries", resp.OrphanedInIndex)
+	report("crates without cached rustdoc JSON", len(resp.MissingJSONCache))
+	report("orphaned rustdoc JSON cache files", len(resp.OrphanedJSONCache))
+
+	if resp.OK() {
+		fmt.Println("index is consistent")
+	} else if !verifyRepair {
+		fmt.Println("run `rsdoc verify --repair` to fix")
+	}
+	for _, r := range resp.Repaired {
+		fmt.Printf("repaired: %s\n", r)
+	}
+	for _, e := range resp.Errors {
+		fmt.Printf("error: %s\n", e)
+	}
+}
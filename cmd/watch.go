@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jcdickinson/ferrisfetch/internal/humanize"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+	"github.com/spf13/cobra"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Keep a Cargo project's dependencies indexed as Cargo.lock changes",
+	Long: `Have the daemon watch a project's Cargo.lock and index its dependencies in the
+background: every crates.io crate the project's own packages depend on
+directly, at the version the lockfile resolves. It indexes whatever is
+missing now, then again each time cargo rewrites the lockfile, so added and
+upgraded dependencies are ready to search by the time you need them. For a
+workspace member the workspace's Cargo.lock is watched.
+
+Progress goes to the daemon log ("rsdoc logs"), and "rsdoc status" lists the
+watches. The daemon stays up while it watches anything; --stop ends a watch.
+Watches don't survive a daemon restart.`,
+	Example: `  rsdoc watch
+  rsdoc watch --manifest ../server/Cargo.toml
+  rsdoc watch --stop`,
+	Args: cobra.NoArgs,
+	Run:  runWatch,
+}
+
+var (
+	watchManifest string
+	watchStop     bool
+	watchJSON     bool
+)
+
+func init() {
+	watchCmd.Flags().StringVar(&watchManifest, "manifest", "Cargo.toml", "the project's Cargo.toml")
+	watchCmd.Flags().BoolVar(&watchStop, "stop", false, "stop watching the project")
+	watchCmd.Flags().BoolVar(&watchJSON, "json", false, "output as JSON")
+	rootCmd.AddCommand(watchCmd)
+}
+
+func runWatch(cmd *cobra.Command, args []string) {
+	// The daemon resolves paths against its own working directory.
+	manifest, err := filepath.Abs(watchManifest)
+	if err != nil {
+		slog.Error("invalid manifest path", "error", err)
+		os.Exit(1)
+	}
+
+	client, err := connectDaemon()
+	if err != nil {
+		slog.Error("failed to connect to daemon", "error", err)
+		os.Exit(1)
+	}
+
+	resp, err := client.Watch(context.Background(), rpc.WatchRequest{Manifest: manifest, Stop: watchStop})
+	if err != nil {
+		slog.Error("watch failed", "error", err)
+		os.Exit(1)
+	}
+
+	if watchJSON {
+		out, _ := json.MarshalIndent(resp, "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+	if watchStop {
+		fmt.Printf("stopped watching %s\n", resp.Lockfile)
+		return
+	}
+	fmt.Printf("watching %s\n", resp.Lockfile)
+	fmt.Printf("  %s\n", watchStatusLine(formatter(), *resp, time.Now()))
+}
+
+func watchStatusLine(f humanize.Formatter, w rpc.WatchStatus, now time.Time) string {
+	line := fmt.Sprintf("%d/%d dependencies indexed", w.Indexed, w.Dependencies)
+	switch {
+	case w.Indexing != "":
+		line += ", indexing " + w.Indexing
+	case w.SyncedAt != nil:
+		line += ", synced " + f.Ago(*w.SyncedAt, now)
+	default:
+		line += ", syncing"
+	}
+	if n := len(w.Errors); n > 0 {
+		line += fmt.Sprintf("; %d failed, see rsdoc logs", n)
+	}
+	return line
+}
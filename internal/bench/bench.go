@@ -0,0 +1,183 @@
+// Package bench measures indexing and search on a bundled synthetic crate,
+// for "rsdoc bench" and the package benchmarks, so regressions in the
+// parser, chunker or vector index show up as numbers.
+package bench
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"io"
+	"runtime"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/jcdickinson/ferrisfetch/internal/daemon"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+	"github.com/klauspost/compress/zstd"
+)
+
+// fixture is rustdoc JSON for rsdoc_bench: ten modules of structs with
+// fields and methods, traits, functions and error enums, about 400 items
+// with generated docs and examples.
+//
+//go:embed fixture.json.zst
+var fixture []byte
+
+// The fixture crate's name and version.
+const (
+	CrateName    = "rsdoc_bench"
+	CrateVersion = "1.0.0"
+)
+
+// Queries are the searches Run times, cycled through in order.
+var Queries = []string{
+	"lock a mutex without blocking",
+	"thread-safe ring buffer",
+	"encode a frame into a buffer",
+	"resolve a socket address",
+	"zero-copy stream split",
+	"deadline timer that can be cancelled",
+	"watch a directory for file changes",
+	"verify a signature with a key pair",
+	"error when the connection is closed",
+	"spawn a task on the executor",
+	"parse a value from its text form",
+	"bounded semaphore guard",
+}
+
+// FixtureJSON returns the fixture crate's rustdoc JSON, decompressed.
+func FixtureJSON() ([]byte, error) {
+	dec, err := zstd.NewReader(bytes.NewReader(fixture))
+	if err != nil {
+		return nil, err
+	}
+	defer dec.Close()
+	return io.ReadAll(dec)
+}
+
+// Options configures Run.
+type Options struct {
+	// Searches is how many searches are timed; 0 times each query once.
+	Searches int
+}
+
+// Report is what Run measured.
+type Report struct {
+	Items        int           `json:"items"`
+	Chunks       int           `json:"chunks"`
+	IndexTime    time.Duration `json:"index_ns"`
+	ItemsPerSec  float64       `json:"items_per_sec"`
+	ChunksPerSec float64       `json:"chunks_per_sec"`
+	Searches     int           `json:"searches"`
+	SearchP50    time.Duration `json:"search_p50_ns"`
+	SearchP95    time.Duration `json:"search_p95_ns"`
+	// PeakHeapBytes is the most heap in use at any sample during the run,
+	// and TotalAllocBytes everything allocated, by this whole process.
+	PeakHeapBytes   uint64 `json:"peak_heap_bytes"`
+	TotalAllocBytes uint64 `json:"total_alloc_bytes"`
+}
+
+// Run indexes the fixture crate through client, replacing it if it's
+// already indexed, then times searches over it. Memory is measured in this
+// process, so it only covers the daemon when it runs in-process.
+func Run(ctx context.Context, client *daemon.Client, opts Options) (*Report, error) {
+	searches := opts.Searches
+	if searches <= 0 {
+		searches = len(Queries)
+	}
+
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+	peak := sampleHeap(ctx)
+
+	start := time.Now()
+	added, err := client.AddCrates(ctx, rpc.AddCratesRequest{
+		Crates: []rpc.CrateSpec{{Name: CrateName, Version: CrateVersion, Force: true, OverBudget: true, RustdocJSON: fixture}},
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("indexing %s: %w", CrateName, err)
+	}
+	if len(added.Results) != 1 || added.Results[0].Error != "" {
+		return nil, fmt.Errorf("indexing %s: %+v", CrateName, added.Results)
+	}
+	report := &Report{Items: added.Results[0].Items, IndexTime: time.Since(start)}
+
+	stats, err := client.Stats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range stats.Crates {
+		if c.Name == CrateName && c.Version == CrateVersion {
+			report.Chunks = c.Chunks
+		}
+	}
+	if secs := report.IndexTime.Seconds(); secs > 0 {
+		report.ItemsPerSec = float64(report.Items) / secs
+		report.ChunksPerSec = float64(report.Chunks) / secs
+	}
+
+	if _, err := client.Search(ctx, SearchRequest(Queries[0])); err != nil {
+		return nil, fmt.Errorf("searching %q: %w", Queries[0], err)
+	}
+	latencies := make([]time.Duration, searches)
+	for i := range latencies {
+		req := SearchRequest(Queries[i%len(Queries)])
+		start := time.Now()
+		if _, err := client.Search(ctx, req); err != nil {
+			return nil, fmt.Errorf("searching %q: %w", req.Query, err)
+		}
+		latencies[i] = time.Since(start)
+	}
+	slices.Sort(latencies)
+	report.Searches = searches
+	report.SearchP50 = percentile(latencies, 50)
+	report.SearchP95 = percentile(latencies, 95)
+
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+	report.PeakHeapBytes = max(peak.Load(), after.HeapAlloc)
+	report.TotalAllocBytes = after.TotalAlloc - before.TotalAlloc
+	return report, nil
+}
+
+// SearchRequest searches the fixture crate for query. Its low threshold
+// keeps every query finding candidates, so none fall through to looking up
+// crate suggestions on crates.io.
+func SearchRequest(query string) rpc.SearchRequest {
+	return rpc.SearchRequest{Query: query, Crates: []string{CrateName}, Threshold: 0.05, Limit: 10}
+}
+
+// sampleHeap records the largest HeapAlloc seen every few milliseconds
+// until ctx ends.
+func sampleHeap(ctx context.Context) *atomic.Uint64 {
+	var peak atomic.Uint64
+	go func() {
+		ticker := time.NewTicker(5 * time.Millisecond)
+		defer ticker.Stop()
+		var m runtime.MemStats
+		for {
+			runtime.ReadMemStats(&m)
+			if m.HeapAlloc > peak.Load() {
+				peak.Store(m.HeapAlloc)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return &peak
+}
+
+// percentile returns the pth percentile of sorted durations, nearest rank.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank-1, 0)]
+}
@@ -0,0 +1,140 @@
+package bench_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/jcdickinson/ferrisfetch/internal/bench"
+	"github.com/jcdickinson/ferrisfetch/internal/config"
+	"github.com/jcdickinson/ferrisfetch/internal/daemon"
+	"github.com/jcdickinson/ferrisfetch/internal/db"
+	"github.com/jcdickinson/ferrisfetch/internal/docs"
+	"github.com/jcdickinson/ferrisfetch/internal/embeddings"
+)
+
+// startDaemon runs a daemon in-process with a fresh cache and database and
+// the fake embedding provider.
+func startDaemon(tb testing.TB) *daemon.Client {
+	tb.Helper()
+	tb.Setenv("XDG_CONFIG_HOME", tb.TempDir())
+	tb.Setenv("XDG_CACHE_HOME", tb.TempDir())
+	tb.Setenv("XDG_RUNTIME_DIR", tb.TempDir())
+	tb.Setenv("FERRISFETCH_VOYAGE_AI_PROVIDER", "fake")
+
+	cfg, err := config.Load()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	database, err := db.NewWithOptions(filepath.Join(tb.TempDir(), "db.db"), db.Options{Storage: cfg.Index.Storage, Quantization: cfg.Index.Quantization})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	srv := daemon.NewServer(cfg, database, config.SocketPath())
+	srv.SetConfigLoader(func() (*config.Config, error) { return cfg, nil })
+	client, err := srv.StartInProcess(context.Background())
+	if err != nil {
+		database.Close()
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), daemon.DrainTimeout)
+		defer cancel()
+		if err := srv.Stop(ctx); err != nil {
+			tb.Errorf("stopping daemon: %v", err)
+		}
+	})
+	return client
+}
+
+func TestRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("indexes the whole fixture crate")
+	}
+	client := startDaemon(t)
+	report, err := bench.Run(t.Context(), client, bench.Options{Searches: 24})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Items < 300 || report.Chunks < report.Items {
+		t.Errorf("indexed %d items, %d chunks", report.Items, report.Chunks)
+	}
+	if report.Searches != 24 || report.SearchP50 <= 0 || report.SearchP95 < report.SearchP50 {
+		t.Errorf("searches %d, p50 %s, p95 %s", report.Searches, report.SearchP50, report.SearchP95)
+	}
+	if report.PeakHeapBytes == 0 || report.TotalAllocBytes == 0 {
+		t.Errorf("memory: peak %d, allocated %d", report.PeakHeapBytes, report.TotalAllocBytes)
+	}
+}
+
+func fixtureItems(b *testing.B) []docs.ParsedItem {
+	data, err := bench.FixtureJSON()
+	if err != nil {
+		b.Fatal(err)
+	}
+	_, items, err := docs.Parse(data, bench.CrateName, bench.CrateVersion)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return items
+}
+
+func BenchmarkParse(b *testing.B) {
+	data, err := bench.FixtureJSON()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(data)))
+	for b.Loop() {
+		if _, _, err := docs.Parse(data, bench.CrateName, bench.CrateVersion); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkChunk(b *testing.B) {
+	items := fixtureItems(b)
+	var chunks int
+	for b.Loop() {
+		chunks = 0
+		for _, it := range items {
+			chunks += len(embeddings.ChunkSections(it.Path, it.Docs))
+			for _, f := range it.Fragments {
+				chunks += len(embeddings.ChunkSections(it.Path, f.Content))
+			}
+		}
+	}
+	b.ReportMetric(float64(chunks), "chunks/op")
+}
+
+// BenchmarkIndex indexes the fixture crate end to end: parsing, storing,
+// chunking, fake embedding and inserting into the vector index. Only the
+// first iteration embeds; later ones find the chunks already embedded, as
+// re-indexing a crate does.
+func BenchmarkIndex(b *testing.B) {
+	client := startDaemon(b)
+	var report *bench.Report
+	for b.Loop() {
+		var err error
+		if report, err = bench.Run(b.Context(), client, bench.Options{Searches: 1}); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(report.ItemsPerSec, "items/s")
+	b.ReportMetric(report.ChunksPerSec, "chunks/s")
+}
+
+// BenchmarkSearch times searches over the indexed fixture crate.
+func BenchmarkSearch(b *testing.B) {
+	client := startDaemon(b)
+	if _, err := bench.Run(b.Context(), client, bench.Options{Searches: 1}); err != nil {
+		b.Fatal(err)
+	}
+	i := 0
+	for b.Loop() {
+		if _, err := client.Search(b.Context(), bench.SearchRequest(bench.Queries[i%len(bench.Queries)])); err != nil {
+			b.Fatal(err)
+		}
+		i++
+	}
+}
@@ -0,0 +1,269 @@
+// Package bundle packs the index (database, HNSW index, CAS and rustdoc JSON
+// cache) into a zstd-compressed tar archive and unpacks it again, so a
+// pre-built index can be shared without re-embedding.
+package bundle
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jcdickinson/ferrisfetch/internal/config"
+	"github.com/jcdickinson/ferrisfetch/internal/db"
+	"github.com/klauspost/compress/zstd"
+)
+
+// manifestName is the first entry of every archive.
+const manifestName = "manifest.json"
+
+// formatVersion is bumped when the archive layout changes incompatibly.
+const formatVersion = 1
+
+// Manifest describes an archive.
+type Manifest struct {
+	Format    int       `json:"format"`
+	CreatedAt time.Time `json:"created_at"`
+	// Model is the embedding model the index was built with. Searching it
+	// with a different model gives meaningless results.
+	Model string `json:"model"`
+}
+
+// Entry maps a file or directory on disk to a name in the archive.
+type Entry struct {
+	Name string
+	Path string
+}
+
+// Write archives entries to w. Directories are added recursively; missing
+// paths are skipped. It returns the number of files written.
+func Write(w io.Writer, m Manifest, entries []Entry) (int, error) {
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return 0, fmt.Errorf("creating zstd writer: %w", err)
+	}
+	tw := tar.NewWriter(zw)
+
+	m.Format = formatVersion
+	manifest, err := json.Marshal(m)
+	if err != nil {
+		return 0, err
+	}
+	if err := writeFile(tw, manifestName, int64(len(manifest)), m.CreatedAt, strings.NewReader(string(manifest))); err != nil {
+		return 0, err
+	}
+
+	files := 0
+	for _, e := range entries {
+		err := filepath.WalkDir(e.Path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) && p == e.Path {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(e.Path, p)
+			if err != nil {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			files++
+			return writeFile(tw, path.Join(e.Name, filepath.ToSlash(rel)), info.Size(), info.ModTime(), f)
+		})
+		if err != nil {
+			return files, fmt.Errorf("archiving %s: %w", e.Path, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return files, fmt.Errorf("finishing tar: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return files, fmt.Errorf("finishing zstd: %w", err)
+	}
+	return files, nil
+}
+
+func writeFile(tw *tar.Writer, name string, size int64, modTime time.Time, r io.Reader) error {
+	hdr := &tar.Header{Name: name, Mode: 0644, Size: size, ModTime: modTime, Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("writing header for %s: %w", name, err)
+	}
+	if _, err := io.CopyN(tw, r, size); err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	return nil
+}
+
+// Extract unpacks an archive into dir and returns its manifest.
+func Extract(r io.Reader, dir string) (*Manifest, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("creating zstd reader: %w", err)
+	}
+	defer zr.Close()
+	tr := tar.NewReader(zr)
+
+	var m *Manifest
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if m == nil {
+			if hdr.Name != manifestName {
+				return nil, fmt.Errorf("not a ferrisfetch index archive (first entry is %q)", hdr.Name)
+			}
+			m = &Manifest{}
+			if err := json.NewDecoder(tr).Decode(m); err != nil {
+				return nil, fmt.Errorf("reading manifest: %w", err)
+			}
+			if m.Format != formatVersion {
+				return nil, fmt.Errorf("unsupported archive format %d (want %d)", m.Format, formatVersion)
+			}
+			continue
+		}
+
+		name := path.Clean(hdr.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("unsafe path in archive: %s", hdr.Name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, err
+		}
+		f, err := os.Create(target)
+		if err != nil {
+			return nil, err
+		}
+		_, err = io.Copy(f, tr)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("extracting %s: %w", hdr.Name, err)
+		}
+	}
+	if m == nil {
+		return nil, fmt.Errorf("empty archive")
+	}
+	return m, nil
+}
+
+// Entries lists what goes into an index archive for the database at dbPath.
+// The downloads directory is left out: it only holds HTTP validators and
+// unfinished downloads, which the next fetch rebuilds.
+func Entries(dbPath string) []Entry {
+	return []Entry{
+		{Name: "db.db", Path: dbPath},
+		{Name: "db.hnsw", Path: db.HNSWPath(dbPath)},
+		{Name: "db.hnsw.log", Path: db.HNSWLogPath(dbPath)},
+		{Name: "cas", Path: config.CASDir()},
+		{Name: "json", Path: config.JSONCacheDir()},
+	}
+}
+
+// Install moves each entry extracted into dir over its path on disk,
+// replacing what was there. Entries missing from dir are removed on disk so
+// the result matches the archive. An entry on another filesystem than dir is
+// copied next to its path first, so it is only replaced once the copy is
+// complete.
+func Install(dir string, entries []Entry) error {
+	for _, e := range entries {
+		src := filepath.Join(dir, e.Name)
+		if _, err := os.Stat(src); os.IsNotExist(err) {
+			if err := os.RemoveAll(e.Path); err != nil {
+				return fmt.Errorf("removing %s: %w", e.Path, err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(e.Path), 0755); err != nil {
+			return err
+		}
+		staged := e.Path + ".import"
+		if err := stage(src, staged); err != nil {
+			return fmt.Errorf("installing %s: %w", e.Name, err)
+		}
+		if err := os.RemoveAll(e.Path); err != nil {
+			return fmt.Errorf("removing %s: %w", e.Path, err)
+		}
+		if err := os.Rename(staged, e.Path); err != nil {
+			return fmt.Errorf("installing %s: %w", e.Name, err)
+		}
+	}
+	return nil
+}
+
+// stage moves src to dst, copying it when they are on different
+// filesystems.
+func stage(src, dst string) error {
+	if err := os.RemoveAll(dst); err != nil {
+		return err
+	}
+	err := os.Rename(src, dst)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	if err := copyTree(src, dst); err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+	return nil
+}
+
+// copyTree copies the file or directory at src to dst.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		in, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.Create(target)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, in)
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	})
+}
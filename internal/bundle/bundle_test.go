@@ -0,0 +1,102 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestWriteExtract(t *testing.T) {
+	t.Parallel()
+	src := t.TempDir()
+	os.WriteFile(filepath.Join(src, "db.db"), []byte("database"), 0644)
+	os.MkdirAll(filepath.Join(src, "cas", "ab"), 0755)
+	os.WriteFile(filepath.Join(src, "cas", "ab", "abcd"), []byte("content"), 0644)
+
+	entries := []Entry{
+		{Name: "db.db", Path: filepath.Join(src, "db.db")},
+		{Name: "cas", Path: filepath.Join(src, "cas")},
+		{Name: "json", Path: filepath.Join(src, "missing")},
+	}
+	var buf bytes.Buffer
+	files, err := Write(&buf, Manifest{CreatedAt: time.Now(), Model: "voyage-3.5"}, entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files != 2 {
+		t.Errorf("wrote %d files, want 2", files)
+	}
+
+	dst := t.TempDir()
+	m, err := Extract(&buf, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Model != "voyage-3.5" {
+		t.Errorf("manifest model = %q", m.Model)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dst, "cas", "ab", "abcd")); string(got) != "content" {
+		t.Errorf("cas file = %q", got)
+	}
+
+	target := t.TempDir()
+	os.WriteFile(filepath.Join(target, "stale"), nil, 0644)
+	installed := []Entry{
+		{Name: "db.db", Path: filepath.Join(target, "db.db")},
+		{Name: "json", Path: filepath.Join(target, "stale")},
+	}
+	if err := Install(dst, installed); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(target, "db.db")); string(got) != "database" {
+		t.Errorf("installed db = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(target, "stale")); !os.IsNotExist(err) {
+		t.Error("entry missing from the archive was not removed")
+	}
+}
+
+func TestExtract_RejectsUnsafePaths(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	zw, _ := zstd.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	manifest := []byte(`{"format":1}`)
+	writeFile(tw, manifestName, int64(len(manifest)), time.Now(), bytes.NewReader(manifest))
+	writeFile(tw, "../escape", 1, time.Now(), bytes.NewReader([]byte("x")))
+	tw.Close()
+	zw.Close()
+
+	if _, err := Extract(&buf, t.TempDir()); err == nil {
+		t.Error("expected error for path outside the target directory")
+	}
+}
+
+// copyTree is Install's fallback when the staging directory is on another
+// filesystem than the index.
+func TestCopyTree(t *testing.T) {
+	t.Parallel()
+	src := t.TempDir()
+	os.MkdirAll(filepath.Join(src, "cas", "ab"), 0755)
+	os.WriteFile(filepath.Join(src, "cas", "ab", "abcd"), []byte("content"), 0644)
+	os.WriteFile(filepath.Join(src, "db.db"), []byte("database"), 0644)
+
+	dst := t.TempDir()
+	if err := copyTree(filepath.Join(src, "cas"), filepath.Join(dst, "cas")); err != nil {
+		t.Fatal(err)
+	}
+	if err := copyTree(filepath.Join(src, "db.db"), filepath.Join(dst, "db.db")); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dst, "cas", "ab", "abcd")); string(got) != "content" {
+		t.Errorf("copied cas file = %q", got)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dst, "db.db")); string(got) != "database" {
+		t.Errorf("copied db = %q", got)
+	}
+}
@@ -0,0 +1,90 @@
+package cas
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultCacheBytes bounds the read cache until SetCacheSize is called.
+const DefaultCacheBytes = 64 << 20
+
+// readCache keeps recently read documents decompressed, so rendering the
+// same snippets and docs again doesn't touch the disk or start a zstd
+// decoder. Content is addressed by hash and never changes, so entries only
+// go stale when Remove deletes them.
+type readCache struct {
+	mu      sync.Mutex
+	max     int64
+	size    int64
+	order   *list.List // of *cacheEntry, most recently used first
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	hash    string
+	content string
+}
+
+var cache = newReadCache(DefaultCacheBytes)
+
+func newReadCache(max int64) *readCache {
+	return &readCache{max: max, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// SetCacheSize bounds the memory the read cache holds, in bytes of
+// decompressed content; 0 disables it. Shrinking it evicts at once.
+func SetCacheSize(bytes int64) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.max = max(bytes, 0)
+	cache.evict()
+}
+
+func (c *readCache) get(hash string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[hash]
+	if !ok {
+		return "", false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*cacheEntry).content, true
+}
+
+// add caches content unless it alone would take more than an eighth of the
+// cache, which would push out many small documents for one large one.
+func (c *readCache) add(hash, content string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if int64(len(content)) > c.max/8 {
+		return
+	}
+	if _, ok := c.entries[hash]; ok {
+		return
+	}
+	c.entries[hash] = c.order.PushFront(&cacheEntry{hash: hash, content: content})
+	c.size += int64(len(content))
+	c.evict()
+}
+
+func (c *readCache) remove(hash string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[hash]; ok {
+		c.drop(el)
+	}
+}
+
+// evict drops the least recently used entries until the cache fits. The
+// caller holds c.mu.
+func (c *readCache) evict() {
+	for c.size > c.max {
+		c.drop(c.order.Back())
+	}
+}
+
+func (c *readCache) drop(el *list.Element) {
+	e := c.order.Remove(el).(*cacheEntry)
+	delete(c.entries, e.hash)
+	c.size -= int64(len(e.content))
+}
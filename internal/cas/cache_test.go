@@ -0,0 +1,65 @@
+package cas
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestReadCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Cleanup(func() { SetCacheSize(DefaultCacheBytes) })
+	SetCacheSize(1 << 10)
+
+	hash, err := Write("cached content")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(hash); err != nil {
+		t.Fatal(err)
+	}
+	// A cached document reads without its file; Check still goes to disk.
+	if err := os.Remove(path(hash)); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Read(hash); err != nil || got != "cached content" {
+		t.Fatalf("Read from cache = %q, %v", got, err)
+	}
+	if err := Check(hash); err == nil {
+		t.Error("Check found a document whose file was deleted")
+	}
+
+	// Remove evicts it.
+	if _, err := Write("cached content"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Remove(hash); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(hash); err == nil {
+		t.Error("Read found a removed document")
+	}
+}
+
+func TestReadCache_Eviction(t *testing.T) {
+	c := newReadCache(100)
+	c.add("a", strings.Repeat("a", 10))
+	c.add("b", strings.Repeat("b", 10))
+	c.add("big", strings.Repeat("x", 50)) // over an eighth of the cache
+	if _, ok := c.get("big"); ok {
+		t.Error("cached a document larger than an eighth of the cache")
+	}
+	for i := 0; i < 9; i++ {
+		c.get("a")
+		c.add(string(rune('c'+i)), strings.Repeat("c", 10))
+	}
+	if _, ok := c.get("a"); !ok {
+		t.Error("evicted the most recently used entry")
+	}
+	if _, ok := c.get("b"); ok {
+		t.Error("kept the least recently used entry past the limit")
+	}
+	if c.size > c.max {
+		t.Errorf("size %d over max %d", c.size, c.max)
+	}
+}
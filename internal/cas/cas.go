@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 
@@ -12,6 +11,9 @@ import (
 	"github.com/klauspost/compress/zstd"
 )
 
+// decoder is shared by every read; DecodeAll is safe for concurrent use.
+var decoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+
 // Dir returns the CAS directory path.
 func Dir() string {
 	return config.CASDir()
@@ -31,6 +33,9 @@ func Write(content string) (string, error) {
 	if _, err := os.Stat(p); err == nil {
 		return hash, nil
 	}
+	if _, ok := packedSize(hash); ok {
+		return hash, nil
+	}
 
 	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
 		return "", fmt.Errorf("creating CAS directory: %w", err)
@@ -56,23 +61,115 @@ func Write(content string) (string, error) {
 	return hash, nil
 }
 
-// Read retrieves content from the CAS by hash.
+// CheckWritable verifies that new content can be stored, by creating and
+// removing a temporary file in the CAS directory.
+func CheckWritable() error {
+	if err := os.MkdirAll(Dir(), 0755); err != nil {
+		return fmt.Errorf("creating CAS directory: %w", err)
+	}
+	f, err := os.CreateTemp(Dir(), ".probe-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// Read retrieves content from the CAS by hash: from the read cache, its
+// loose file or else a pack.
 func Read(hash string) (string, error) {
-	f, err := os.Open(path(hash))
+	if content, ok := cache.get(hash); ok {
+		return content, nil
+	}
+	content, err := load(hash)
 	if err != nil {
-		return "", fmt.Errorf("reading CAS file %s: %w", hash, err)
+		return "", err
 	}
-	defer f.Close()
+	cache.add(hash, content)
+	return content, nil
+}
 
-	r, err := zstd.NewReader(f)
+// Check reads content from disk, bypassing the read cache, to confirm it is
+// still stored and decompresses.
+func Check(hash string) error {
+	_, err := load(hash)
+	return err
+}
+
+func load(hash string) (string, error) {
+	compressed, err := os.ReadFile(path(hash))
+	if os.IsNotExist(err) {
+		compressed, err = readPacked(hash)
+	}
 	if err != nil {
-		return "", fmt.Errorf("creating zstd reader: %w", err)
+		return "", fmt.Errorf("reading CAS file %s: %w", hash, err)
 	}
-	defer r.Close()
 
-	data, err := io.ReadAll(r)
+	data, err := decoder.DecodeAll(compressed, nil)
 	if err != nil {
 		return "", fmt.Errorf("decompressing CAS file %s: %w", hash, err)
 	}
 	return string(data), nil
 }
+
+// Size returns the compressed size of the stored content for hash, or 0 if
+// it isn't stored.
+func Size(hash string) int64 {
+	if fi, err := os.Stat(path(hash)); err == nil {
+		return fi.Size()
+	}
+	size, _ := packedSize(hash)
+	return size
+}
+
+// DiskUsage returns the total size of everything in the CAS directory,
+// including content no crate references any more.
+func DiskUsage() (int64, error) {
+	var total int64
+	err := filepath.WalkDir(Dir(), func(_ string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.Type().IsRegular() {
+			if fi, err := d.Info(); err == nil {
+				total += fi.Size()
+			}
+		}
+		return nil
+	})
+	return total, err
+}
+
+// Remove deletes content from the CAS so a later Write stores it afresh.
+// Packed content is marked removed and left out by the next full repack.
+func Remove(hash string) error {
+	repackMu.Lock()
+	defer repackMu.Unlock()
+	cache.remove(hash)
+	if err := os.Remove(path(hash)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing CAS file %s: %w", hash, err)
+	}
+	if err := removePacked(hash); err != nil {
+		return fmt.Errorf("removing CAS file %s from its pack: %w", hash, err)
+	}
+	return nil
+}
+
+// Warm lists every shard directory and loads the pack indexes so the first
+// reads after startup find them cached, returning how many documents it saw.
+func Warm() (int, error) {
+	if err := store.refresh(); err != nil {
+		return 0, err
+	}
+	store.mu.RLock()
+	packed := 0
+	for _, p := range store.packs {
+		packed += len(p.entries)
+	}
+	store.mu.RUnlock()
+	loose, err := looseHashes()
+	return packed + len(loose), err
+}
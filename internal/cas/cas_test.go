@@ -85,3 +85,41 @@ func unwrapPathError(err error) error {
 		}
 	}
 }
+
+func TestSizeAndDiskUsage(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	if n, err := DiskUsage(); err != nil || n != 0 {
+		t.Fatalf("empty CAS: %d, %v", n, err)
+	}
+	hash, err := Write("some content")
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := Size(hash)
+	if size == 0 {
+		t.Fatal("stored content has size 0")
+	}
+	if Size("0000000000") != 0 {
+		t.Error("missing content has nonzero size")
+	}
+	if n, err := DiskUsage(); err != nil || n != size {
+		t.Errorf("disk usage = %d, %v; want %d", n, err, size)
+	}
+}
+
+func TestWarm(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	if n, err := Warm(); err != nil || n != 0 {
+		t.Fatalf("Warm on a missing CAS = %d, %v; want 0, nil", n, err)
+	}
+	for _, content := range []string{"one", "two", "three"} {
+		if _, err := Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, err := Warm(); err != nil || n != 3 {
+		t.Errorf("Warm = %d, %v; want 3, nil", n, err)
+	}
+}
@@ -0,0 +1,541 @@
+package cas
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Packs hold content that "rsdoc repack" moved out of loose files, so a
+// large index is a handful of files instead of one per document. A pack is
+// two files in cas/pack: pack-<id>.pack, the compressed documents back to
+// back exactly as they were stored loose, and pack-<id>.idx, which locates
+// each by hash. Packs are never modified; content removed from one is
+// listed in cas/pack/removed until a full repack rewrites it without.
+//
+// The .idx file is a header (idxMagic and the entry count as a big-endian
+// uint32), the entries sorted by hash (32-byte hash, uint64 offset, uint32
+// length), and a SHA-256 of everything before it. It is written last, so a
+// pack without one is an interrupted repack and is ignored.
+
+const (
+	idxMagic     = "RSDOCPK1"
+	idxEntrySize = sha256.Size + 8 + 4
+)
+
+// packDir returns the directory holding packs.
+func packDir() string {
+	return filepath.Join(Dir(), "pack")
+}
+
+func removedPath() string {
+	return filepath.Join(packDir(), "removed")
+}
+
+type packEntry struct {
+	hash   [sha256.Size]byte
+	offset uint64
+	size   uint32
+}
+
+type pack struct {
+	path    string
+	f       *os.File
+	entries []packEntry // sorted by hash
+}
+
+func (p *pack) find(hash [sha256.Size]byte) (packEntry, bool) {
+	i := sort.Search(len(p.entries), func(i int) bool {
+		return bytes.Compare(p.entries[i].hash[:], hash[:]) >= 0
+	})
+	if i < len(p.entries) && p.entries[i].hash == hash {
+		return p.entries[i], true
+	}
+	return packEntry{}, false
+}
+
+// packStore is the loaded pack indexes. It reloads whenever the pack
+// directory changes, so a CAS replaced underneath it (by "rsdoc import")
+// is picked up.
+type packStore struct {
+	mu      sync.RWMutex
+	dir     os.FileInfo
+	packs   []*pack
+	removed map[[sha256.Size]byte]bool
+}
+
+var store packStore
+
+// repackMu serializes Repack and Remove, so content removed while a repack
+// runs can't be carried into the new pack.
+var repackMu sync.Mutex
+
+// refresh reloads the pack indexes if the pack directory has changed since
+// they were loaded.
+func (s *packStore) refresh() error {
+	fi, err := os.Stat(packDir())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	s.mu.RLock()
+	current := s.dir == nil && fi == nil ||
+		s.dir != nil && fi != nil && os.SameFile(s.dir, fi) && s.dir.ModTime().Equal(fi.ModTime())
+	s.mu.RUnlock()
+	if current {
+		return nil
+	}
+	return s.reload()
+}
+
+// reload replaces the loaded packs with those on disk.
+func (s *packStore) reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.packs {
+		p.f.Close()
+	}
+	s.packs, s.removed, s.dir = nil, nil, nil
+
+	fi, err := os.Stat(packDir())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	idxs, err := filepath.Glob(filepath.Join(packDir(), "pack-*.idx"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(idxs)
+	for _, idx := range idxs {
+		p, err := openPack(strings.TrimSuffix(idx, ".idx") + ".pack")
+		if err != nil {
+			return err
+		}
+		s.packs = append(s.packs, p)
+	}
+	if s.removed, err = readRemoved(); err != nil {
+		return err
+	}
+	s.dir = fi
+	return nil
+}
+
+// lookup finds hash in the packs, skipping removed content.
+func (s *packStore) lookup(hash string) (*pack, packEntry, bool) {
+	raw, ok := parseHash(hash)
+	if !ok {
+		return nil, packEntry{}, false
+	}
+	if s.removed[raw] {
+		return nil, packEntry{}, false
+	}
+	for _, p := range s.packs {
+		if e, ok := p.find(raw); ok {
+			return p, e, true
+		}
+	}
+	return nil, packEntry{}, false
+}
+
+// readPacked returns the compressed content stored for hash in a pack.
+func readPacked(hash string) ([]byte, error) {
+	if err := store.refresh(); err != nil {
+		return nil, fmt.Errorf("loading CAS packs: %w", err)
+	}
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	p, e, ok := store.lookup(hash)
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	data := make([]byte, e.size)
+	if _, err := p.f.ReadAt(data, int64(e.offset)); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", filepath.Base(p.path), err)
+	}
+	return data, nil
+}
+
+// packedSize returns the compressed size of hash in a pack.
+func packedSize(hash string) (int64, bool) {
+	if store.refresh() != nil {
+		return 0, false
+	}
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	_, e, ok := store.lookup(hash)
+	return int64(e.size), ok
+}
+
+func parseHash(hash string) ([sha256.Size]byte, bool) {
+	var raw [sha256.Size]byte
+	if len(hash) != 2*sha256.Size {
+		return raw, false
+	}
+	_, err := hex.Decode(raw[:], []byte(hash))
+	return raw, err == nil
+}
+
+func openPack(path string) (*pack, error) {
+	entries, err := readIndex(strings.TrimSuffix(path, ".pack") + ".idx")
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening CAS pack: %w", err)
+	}
+	return &pack{path: path, f: f, entries: entries}, nil
+}
+
+func readIndex(path string) ([]packEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading CAS pack index: %w", err)
+	}
+	header := len(idxMagic) + 4
+	if len(data) < header+sha256.Size || string(data[:len(idxMagic)]) != idxMagic {
+		return nil, fmt.Errorf("%s is not a CAS pack index", filepath.Base(path))
+	}
+	body, sum := data[:len(data)-sha256.Size], data[len(data)-sha256.Size:]
+	if got := sha256.Sum256(body); !bytes.Equal(got[:], sum) {
+		return nil, fmt.Errorf("%s is corrupt: checksum mismatch", filepath.Base(path))
+	}
+	count := int(binary.BigEndian.Uint32(data[len(idxMagic):header]))
+	if len(body) != header+count*idxEntrySize {
+		return nil, fmt.Errorf("%s is corrupt: wrong size for %d entries", filepath.Base(path), count)
+	}
+	entries := make([]packEntry, count)
+	for i := range entries {
+		b := body[header+i*idxEntrySize:]
+		copy(entries[i].hash[:], b)
+		entries[i].offset = binary.BigEndian.Uint64(b[sha256.Size:])
+		entries[i].size = binary.BigEndian.Uint32(b[sha256.Size+8:])
+	}
+	return entries, nil
+}
+
+func writeIndex(path string, entries []packEntry) error {
+	var buf bytes.Buffer
+	buf.WriteString(idxMagic)
+	binary.Write(&buf, binary.BigEndian, uint32(len(entries)))
+	for _, e := range entries {
+		buf.Write(e.hash[:])
+		binary.Write(&buf, binary.BigEndian, e.offset)
+		binary.Write(&buf, binary.BigEndian, e.size)
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	buf.Write(sum[:])
+	return writeFileSync(path, buf.Bytes())
+}
+
+func readRemoved() (map[[sha256.Size]byte]bool, error) {
+	f, err := os.Open(removedPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	removed := make(map[[sha256.Size]byte]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if raw, ok := parseHash(strings.TrimSpace(scanner.Text())); ok {
+			removed[raw] = true
+		}
+	}
+	return removed, scanner.Err()
+}
+
+// removePacked marks hash removed from the packs, if it is in one. The
+// caller holds repackMu.
+func removePacked(hash string) error {
+	if err := store.refresh(); err != nil {
+		return err
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if _, _, ok := store.lookup(hash); !ok {
+		return nil
+	}
+	f, err := os.OpenFile(removedPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(f, hash); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	raw, _ := parseHash(hash)
+	if store.removed == nil {
+		store.removed = make(map[[sha256.Size]byte]bool)
+	}
+	store.removed[raw] = true
+	return nil
+}
+
+// RepackResult describes what Repack did.
+type RepackResult struct {
+	// Loose is how many loose files were moved into the new pack.
+	Loose int
+	// Packed is how many documents the new pack holds.
+	Packed int
+	// Dropped is how many removed documents a full repack left out of the
+	// packs it replaced.
+	Dropped int
+	// Packs is how many packs there are afterwards.
+	Packs int
+	// Bytes is the size of the new pack.
+	Bytes int64
+}
+
+// Repack moves the loose files into a new pack. With all, it also rewrites
+// the existing packs into that one, leaving out removed content. Content
+// stored while it runs stays loose for the next repack.
+func Repack(all bool) (*RepackResult, error) {
+	repackMu.Lock()
+	defer repackMu.Unlock()
+	if err := store.reload(); err != nil {
+		return nil, fmt.Errorf("loading CAS packs: %w", err)
+	}
+
+	loose, err := looseHashes()
+	if err != nil {
+		return nil, fmt.Errorf("listing loose CAS files: %w", err)
+	}
+	store.mu.RLock()
+	old := store.packs
+	removed := store.removed
+	store.mu.RUnlock()
+	result := &RepackResult{Packs: len(old)}
+	if len(loose) == 0 && (!all || len(old) <= 1 && len(removed) == 0) {
+		return result, nil
+	}
+
+	if err := os.MkdirAll(packDir(), 0755); err != nil {
+		return nil, fmt.Errorf("creating CAS pack directory: %w", err)
+	}
+	name := filepath.Join(packDir(), fmt.Sprintf("pack-%016x", time.Now().UnixNano()))
+	w, err := newPackWriter(name + ".pack")
+	if err != nil {
+		return nil, err
+	}
+	defer w.abort()
+
+	var packedLoose []string
+	for _, hash := range loose {
+		raw, ok := parseHash(hash)
+		if !ok {
+			continue
+		}
+		store.mu.RLock()
+		_, _, inPack := store.lookup(hash)
+		rewritten := store.removed[raw]
+		store.mu.RUnlock()
+		if !all {
+			if inPack {
+				// Already packed; the loose copy just goes.
+				packedLoose = append(packedLoose, hash)
+				continue
+			}
+			if rewritten {
+				// Written again after being removed from a pack. The
+				// removal would hide a packed copy, so it stays loose
+				// until a full repack.
+				continue
+			}
+		}
+		data, err := os.ReadFile(path(hash))
+		if err != nil {
+			return nil, fmt.Errorf("reading CAS file %s: %w", hash, err)
+		}
+		added, err := w.add(raw, data)
+		if err != nil {
+			return nil, err
+		}
+		if added {
+			result.Loose++
+		}
+		packedLoose = append(packedLoose, hash)
+	}
+	if all {
+		for _, p := range old {
+			for _, e := range p.entries {
+				if removed[e.hash] {
+					result.Dropped++
+					continue
+				}
+				data := make([]byte, e.size)
+				if _, err := p.f.ReadAt(data, int64(e.offset)); err != nil {
+					return nil, fmt.Errorf("reading %s: %w", filepath.Base(p.path), err)
+				}
+				if _, err := w.add(e.hash, data); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	result.Packed = len(w.entries)
+	if result.Bytes, err = w.finish(name + ".idx"); err != nil {
+		return nil, err
+	}
+	if err := store.reload(); err != nil {
+		return nil, fmt.Errorf("loading CAS packs: %w", err)
+	}
+
+	// The new pack is in use; what it replaces can go.
+	for _, hash := range packedLoose {
+		if err := os.Remove(path(hash)); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("removing packed CAS file: %w", err)
+		}
+	}
+	if all {
+		for _, p := range old {
+			for _, f := range []string{strings.TrimSuffix(p.path, ".pack") + ".idx", p.path} {
+				if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+					return nil, fmt.Errorf("removing old CAS pack: %w", err)
+				}
+			}
+		}
+		if err := os.Remove(removedPath()); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	if err := store.reload(); err != nil {
+		return nil, fmt.Errorf("loading CAS packs: %w", err)
+	}
+	store.mu.RLock()
+	result.Packs = len(store.packs)
+	store.mu.RUnlock()
+	return result, nil
+}
+
+// looseHashes lists the content stored as loose files.
+func looseHashes() ([]string, error) {
+	shards, err := os.ReadDir(Dir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var hashes []string
+	for _, shard := range shards {
+		if !shard.IsDir() || len(shard.Name()) != 2 {
+			continue
+		}
+		entries, err := os.ReadDir(filepath.Join(Dir(), shard.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if rest, ok := strings.CutSuffix(e.Name(), ".md.zst"); ok && e.Type().IsRegular() {
+				hashes = append(hashes, shard.Name()+rest)
+			}
+		}
+	}
+	return hashes, nil
+}
+
+// packWriter appends documents to a new pack file.
+type packWriter struct {
+	path    string
+	f       *os.File
+	offset  uint64
+	entries []packEntry
+	seen    map[[sha256.Size]byte]bool
+	closed  bool
+	done    bool
+}
+
+func newPackWriter(path string) (*packWriter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("creating CAS pack: %w", err)
+	}
+	return &packWriter{path: path, f: f, seen: make(map[[sha256.Size]byte]bool)}, nil
+}
+
+// add appends data under hash, reporting false for a duplicate.
+func (w *packWriter) add(hash [sha256.Size]byte, data []byte) (bool, error) {
+	if w.seen[hash] {
+		return false, nil
+	}
+	if _, err := w.f.Write(data); err != nil {
+		return false, fmt.Errorf("writing CAS pack: %w", err)
+	}
+	w.seen[hash] = true
+	w.entries = append(w.entries, packEntry{hash: hash, offset: w.offset, size: uint32(len(data))})
+	w.offset += uint64(len(data))
+	return true, nil
+}
+
+// finish syncs the pack and writes its index, returning the pack's size.
+func (w *packWriter) finish(idxPath string) (int64, error) {
+	if err := w.f.Sync(); err != nil {
+		return 0, fmt.Errorf("syncing CAS pack: %w", err)
+	}
+	w.closed = true
+	if err := w.f.Close(); err != nil {
+		return 0, fmt.Errorf("closing CAS pack: %w", err)
+	}
+	sort.Slice(w.entries, func(i, j int) bool {
+		return bytes.Compare(w.entries[i].hash[:], w.entries[j].hash[:]) < 0
+	})
+	if err := writeIndex(idxPath, w.entries); err != nil {
+		return 0, fmt.Errorf("writing CAS pack index: %w", err)
+	}
+	w.done = true
+	return int64(w.offset), nil
+}
+
+// abort removes the pack unless finish succeeded.
+func (w *packWriter) abort() {
+	if w.done {
+		return
+	}
+	if !w.closed {
+		w.f.Close()
+	}
+	os.Remove(w.path)
+}
+
+// writeFileSync writes data to path through a temporary file, so path
+// appears complete or not at all.
+func writeFileSync(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,119 @@
+package cas
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRepack(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	contents := []string{"# One\n\nfirst", "# Two\n\nsecond", "# Three\n\nthird"}
+	var hashes []string
+	for _, c := range contents {
+		h, err := Write(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, h)
+	}
+
+	res, err := Repack(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Loose != 3 || res.Packed != 3 || res.Packs != 1 {
+		t.Errorf("Repack = %+v, want 3 loose files in 1 pack", res)
+	}
+	for i, h := range hashes {
+		if _, err := os.Stat(path(h)); !os.IsNotExist(err) {
+			t.Errorf("loose file for %s still there: %v", h, err)
+		}
+		if got, err := Read(h); err != nil || got != contents[i] {
+			t.Errorf("Read(%s) from pack = %q, %v; want %q", h, got, err, contents[i])
+		}
+		if Size(h) == 0 {
+			t.Errorf("Size(%s) = 0 for packed content", h)
+		}
+	}
+	if n, err := Warm(); err != nil || n != 3 {
+		t.Errorf("Warm = %d, %v; want 3", n, err)
+	}
+
+	// Writing packed content doesn't store it again.
+	if _, err := Write(contents[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path(hashes[0])); !os.IsNotExist(err) {
+		t.Errorf("Write stored packed content loose: %v", err)
+	}
+
+	// Removed content is gone until written again, and a full repack
+	// leaves the removed copy out.
+	if err := Remove(hashes[1]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(hashes[1]); err == nil {
+		t.Errorf("Read of removed content succeeded")
+	}
+	fourth, err := Write("# Four\n\nfourth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Remove(hashes[2]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Write(contents[2]); err != nil {
+		t.Fatal(err)
+	}
+
+	if res, err = Repack(false); err != nil {
+		t.Fatal(err)
+	}
+	if res.Loose != 1 || res.Packs != 2 {
+		t.Errorf("incremental Repack = %+v, want 1 loose file (the other was removed from a pack) and 2 packs", res)
+	}
+	if res, err = Repack(true); err != nil {
+		t.Fatal(err)
+	}
+	if res.Packed != 3 || res.Dropped != 2 || res.Packs != 1 {
+		t.Errorf("full Repack = %+v, want 3 documents, 2 dropped, 1 pack", res)
+	}
+	for h, want := range map[string]string{hashes[0]: contents[0], hashes[2]: contents[2], fourth: "# Four\n\nfourth"} {
+		if got, err := Read(h); err != nil || got != want {
+			t.Errorf("Read(%s) after full repack = %q, %v; want %q", h, got, err, want)
+		}
+	}
+	if _, err := Read(hashes[1]); err == nil {
+		t.Errorf("removed content came back after a full repack")
+	}
+	if _, err := os.Stat(removedPath()); !os.IsNotExist(err) {
+		t.Errorf("removed list kept after a full repack: %v", err)
+	}
+}
+
+func TestRepack_CorruptIndex(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	hash, err := Write("content")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Repack(false); err != nil {
+		t.Fatal(err)
+	}
+	idxs, _ := filepath.Glob(filepath.Join(packDir(), "*.idx"))
+	if len(idxs) != 1 {
+		t.Fatalf("found %d pack indexes, want 1", len(idxs))
+	}
+	data, _ := os.ReadFile(idxs[0])
+	data[len(idxMagic)+5] ^= 0xff
+	if err := os.WriteFile(idxs[0], data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	store.reload()
+	if _, err := Read(hash); err == nil {
+		t.Errorf("Read through a corrupt index succeeded")
+	}
+}
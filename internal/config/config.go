@@ -6,34 +6,286 @@ import (
 	"path/filepath"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
 )
 
+// ApiKeyConfig is a secret given inline, or resolved from a file, a command's
+// output or the OS keychain so it never sits in plaintext config.
 type ApiKeyConfig struct {
 	Value string `mapstructure:"-"`
 	Path  string `mapstructure:"path"`
+	// Command is run with sh -c and its trimmed output used, e.g.
+	// "pass show voyage".
+	Command string `mapstructure:"command"`
+	// Keychain names a service in the macOS keychain or, elsewhere, the
+	// Secret Service (via secret-tool).
+	Keychain string `mapstructure:"keychain"`
 }
 
 type VoyageAIConfig struct {
+	// Provider is "voyage" or "fake": deterministic pseudo-embeddings made
+	// locally, with no key or network, for CI and trying rsdoc out. Fake
+	// embeddings are recorded as embeddings.FakeModel.
+	Provider    string       `mapstructure:"provider"`
 	ApiKey      ApiKeyConfig `mapstructure:"api_key"`
 	Model       string       `mapstructure:"model"`
 	RerankModel string       `mapstructure:"rerank_model"`
+	// Dimensions is the embedding size of Model. 0 uses the model's full
+	// size from the built-in registry. Smaller sizes are requested from
+	// models that support output_dimension; for models the registry doesn't
+	// know it just declares their size.
+	Dimensions int `mapstructure:"dimensions"`
+	// RequestsPerMinute and TokensPerMinute cap Voyage API usage; 0 leaves
+	// them unlimited. Indexing leaves a share of each for searches.
+	RequestsPerMinute int `mapstructure:"requests_per_minute"`
+	TokensPerMinute   int `mapstructure:"tokens_per_minute"`
+	// BatchSize and BatchTokens cap the texts and estimated tokens sent per
+	// embedding request while indexing; Concurrency is how many of those
+	// requests run at once.
+	BatchSize   int `mapstructure:"batch_size"`
+	BatchTokens int `mapstructure:"batch_tokens"`
+	Concurrency int `mapstructure:"concurrency"`
+	// PricePerMillionTokens is what Model costs, in dollars, for estimating
+	// what indexing a crate cost. 0 reports tokens only.
+	PricePerMillionTokens float64 `mapstructure:"price_per_million_tokens"`
+}
+
+// Fake reports whether embeddings come from the fake provider.
+func (v VoyageAIConfig) Fake() bool {
+	return v.Provider == "fake"
+}
+
+// Cost estimates what tokens cost at PricePerMillionTokens.
+func (v VoyageAIConfig) Cost(tokens int64) float64 {
+	return float64(tokens) * v.PricePerMillionTokens / 1e6
+}
+
+// EmbeddingConfig caps what indexing may spend on embeddings. Adds over a
+// cap are refused unless forced; 0 leaves it uncapped.
+type EmbeddingConfig struct {
+	// MaxTokensPerCrate caps the estimated tokens one add of a crate embeds.
+	MaxTokensPerCrate int `mapstructure:"max_tokens_per_crate"`
+	// MonthlyBudgetTokens caps the tokens billed this calendar month (UTC)
+	// across every crate, the add in question's estimate included.
+	MonthlyBudgetTokens int `mapstructure:"monthly_budget_tokens"`
+}
+
+type IndexConfig struct {
+	// Quantization stores new embeddings as "float16" or "int8" instead of
+	// float32 ("none"), shrinking the database 2x or 4x. It doesn't save
+	// memory: the in-memory vector index still holds float32.
+	Quantization string `mapstructure:"quantization"`
+	// Storage is "memory" to keep an HNSW index of every embedding in RAM,
+	// or "disk" to search the stored embeddings directly: exact but slower
+	// searches with flat memory use, for memory-constrained machines.
+	Storage string `mapstructure:"storage"`
+	// ChunkMaxTokens caps the estimated tokens of each embedded chunk; longer
+	// doc sections are split between paragraphs, keeping code blocks whole.
+	// 0 leaves chunks unlimited. ChunkOverlapTokens repeats the end of one
+	// piece of a split section at the start of the next.
+	ChunkMaxTokens     int `mapstructure:"chunk_max_tokens"`
+	ChunkOverlapTokens int `mapstructure:"chunk_overlap_tokens"`
+	// Ignore leaves items out of the index when a crate is indexed.
+	Ignore IgnoreConfig `mapstructure:"ignore"`
+}
+
+// IgnoreConfig picks the items indexing leaves out, along with everything
+// nested in them.
+type IgnoreConfig struct {
+	// Patterns match item paths; "*" matches anything, "::" included, as
+	// in "*::__private" or "*::test_utils".
+	Patterns []string `mapstructure:"patterns"`
+	// Hidden leaves out #[doc(hidden)] items, Deprecated #[deprecated] ones.
+	Hidden     bool `mapstructure:"hidden"`
+	Deprecated bool `mapstructure:"deprecated"`
 }
 
 type DaemonConfig struct {
-	ExpirationSeconds int `mapstructure:"expiration_seconds"`
+	// Expiration is how long the daemon waits while idle before exiting,
+	// as a duration ("10m", "2h") or "never". When unset,
+	// ExpirationSeconds applies.
+	Expiration        string `mapstructure:"expiration"`
+	ExpirationSeconds int    `mapstructure:"expiration_seconds"`
+	// AutoFetchMaxSeconds bounds how long search and get-doc wait for an
+	// unindexed crate before answering with what has been indexed so far.
+	// 0 waits for indexing to finish.
+	AutoFetchMaxSeconds int `mapstructure:"auto_fetch_max_seconds"`
+	// ProviderCheckMinutes is how often an idle daemon makes a one-token
+	// embedding request to keep the provider state in status current. 0
+	// disables the checks.
+	ProviderCheckMinutes int `mapstructure:"provider_check_minutes"`
+	// Listen is a TCP address ("127.0.0.1:7777") to serve the API on as
+	// well as the unix socket. It requires AuthToken.
+	Listen string `mapstructure:"listen"`
+	// AuthToken, when set, must be sent as a bearer token with every
+	// request except health checks, on the socket and TCP alike.
+	AuthToken ApiKeyConfig `mapstructure:"auth_token"`
+	// Preload reads the index, the latest indexed version of each crate
+	// and the CAS directories into memory in the background at startup,
+	// so the first search doesn't pay for a cold cache.
+	Preload bool `mapstructure:"preload"`
+	// CASCacheMB bounds the memory kept for recently read documents, so
+	// search snippets and doc renders skip the disk. 0 disables the cache.
+	CASCacheMB int `mapstructure:"cas_cache_mb"`
+}
+
+// defaultExpiration applies when neither expiration setting is positive.
+const defaultExpiration = 10 * time.Minute
+
+// IdleExpiration returns how long the daemon may sit idle before exiting,
+// or 0 if it should never exit on its own.
+func (d DaemonConfig) IdleExpiration() (time.Duration, error) {
+	switch strings.TrimSpace(d.Expiration) {
+	case "never":
+		return 0, nil
+	case "":
+		if d.ExpirationSeconds <= 0 {
+			return defaultExpiration, nil
+		}
+		return time.Duration(d.ExpirationSeconds) * time.Second, nil
+	}
+	exp, err := time.ParseDuration(strings.TrimSpace(d.Expiration))
+	if err != nil {
+		return 0, fmt.Errorf("expected a duration such as \"10m\" or \"never\": %w", err)
+	}
+	if exp <= 0 {
+		return 0, fmt.Errorf("%s is not positive; use \"never\" to disable expiration", d.Expiration)
+	}
+	return exp, nil
+}
+
+type SearchConfig struct {
+	// KindWeights multiplies result scores by item kind (e.g. "function" = 1.2,
+	// "module" = 0.7). Kinds not listed keep a weight of 1.
+	KindWeights map[string]float64 `mapstructure:"kind_weights"`
+	// RerankSkipSimilarity and RerankSkipMargin skip the rerank call when the
+	// top hit's similarity is at least RerankSkipSimilarity and leads the next
+	// hit by at least RerankSkipMargin. A similarity of 0 always reranks.
+	RerankSkipSimilarity float64 `mapstructure:"rerank_skip_similarity"`
+	RerankSkipMargin     float64 `mapstructure:"rerank_skip_margin"`
+	// Rerank is "off", "auto" (rerank unless the skip thresholds above are
+	// met) or "always".
+	Rerank string `mapstructure:"rerank"`
+	// SnippetLength caps result snippets, in bytes.
+	SnippetLength int `mapstructure:"snippet_length"`
+	// PopularityWeight boosts results from widely used crates, by their
+	// recent downloads on the registry, up to 1+PopularityWeight times.
+	// LatestWeight boosts results from a crate's newest version by
+	// 1+LatestWeight. 0 disables either.
+	PopularityWeight float64 `mapstructure:"popularity_weight"`
+	LatestWeight     float64 `mapstructure:"latest_weight"`
+	// DeprecatedPenalty lowers the scores of #[deprecated] items by this
+	// fraction, from 0 (ranked like the rest) to 1 (always last).
+	DeprecatedPenalty float64 `mapstructure:"deprecated_penalty"`
+}
+
+// CrawlConfig paces requests to docs.rs, crates.io and alternative
+// registries.
+type CrawlConfig struct {
+	// RequestsPerSecond caps requests to each host; 0 leaves them unpaced.
+	RequestsPerSecond float64 `mapstructure:"requests_per_second"`
+	// MaxRetries is how many times a request answered with 429 or a 5xx
+	// gateway error is retried, after the server's Retry-After or a
+	// jittered exponential backoff.
+	MaxRetries int `mapstructure:"max_retries"`
+}
+
+// VCRConfig enables recording or replaying outbound HTTP (docs.rs,
+// crates.io, Voyage) as fixtures.
+type VCRConfig struct {
+	Mode string `mapstructure:"mode"` // "record", "replay" or "" (off)
+	Dir  string `mapstructure:"dir"`
+}
+
+// RegistryConfig describes an alternative registry such as Kellnr or
+// Artifactory. IndexURL is the registry's web API base (the "api" value from
+// its index config.json); DocsURL is a docs.rs-style host, or a rustdoc JSON
+// URL template containing {name} and {version}.
+type RegistryConfig struct {
+	IndexURL string       `mapstructure:"index_url"`
+	DocsURL  string       `mapstructure:"docs_url"`
+	Token    ApiKeyConfig `mapstructure:"token"`
+}
+
+// PathsConfig relocates the daemon's data. Empty settings keep their
+// defaults under the XDG cache and runtime directories; "~/" expands to the
+// home directory.
+type PathsConfig struct {
+	// CacheDir replaces ~/.cache/ferrisfetch as the home of the database,
+	// content store, rustdoc JSON cache and log.
+	CacheDir string `mapstructure:"cache_dir"`
+	// DB is the SQLite database file. The HNSW index and its log sit next
+	// to it.
+	DB string `mapstructure:"db"`
+	// CAS is the content store directory, the largest part of the cache.
+	CAS string `mapstructure:"cas"`
+	// Socket is the daemon's Unix socket.
+	Socket string `mapstructure:"socket"`
 }
 
 type Config struct {
-	VoyageAI VoyageAIConfig `mapstructure:"voyage_ai"`
-	Daemon   DaemonConfig   `mapstructure:"daemon"`
+	Paths      PathsConfig               `mapstructure:"paths"`
+	VoyageAI   VoyageAIConfig            `mapstructure:"voyage_ai"`
+	Embedding  EmbeddingConfig           `mapstructure:"embedding"`
+	Daemon     DaemonConfig              `mapstructure:"daemon"`
+	Search     SearchConfig              `mapstructure:"search"`
+	Index      IndexConfig               `mapstructure:"index"`
+	Crawl      CrawlConfig               `mapstructure:"crawl"`
+	VCR        VCRConfig                 `mapstructure:"vcr"`
+	Registries map[string]RegistryConfig `mapstructure:"registries"`
+
+	// warnings are the problems validate found; see Warnings.
+	warnings []string
+}
+
+// PathFlags pairs each paths.* setting with the command-line flag that
+// overrides it.
+var PathFlags = []struct{ Key, Flag string }{
+	{"paths.cache_dir", "cache-dir"},
+	{"paths.db", "db"},
+	{"paths.cas", "cas-dir"},
+	{"paths.socket", "socket"},
+}
+
+// PathArgs returns flags reproducing the current paths.* settings, for
+// starting a daemon that must agree with this process on where things are.
+func PathArgs() []string {
+	var args []string
+	for _, p := range PathFlags {
+		if v := pathSetting(p.Key); v != "" {
+			args = append(args, "--"+p.Flag+"="+v)
+		}
+	}
+	return args
 }
 
-// cacheBase returns the base cache directory for ferrisfetch.
-// Checks XDG_CACHE_HOME, then ~/.cache, then /tmp/ferrisfetch as fallback.
+// pathSetting returns a paths.* setting with "~/" expanded.
+func pathSetting(key string) string {
+	if path, ok := pinned[key]; ok {
+		return path
+	}
+	return expandHome(viper.GetString(key))
+}
+
+// expandHome replaces a leading "~/" with the home directory.
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	return path
+}
+
+// cacheBase returns the base cache directory for ferrisfetch: paths.cache_dir
+// if set, else XDG_CACHE_HOME, then ~/.cache, then /tmp/ferrisfetch.
 func cacheBase() string {
+	if dir := pathSetting("paths.cache_dir"); dir != "" {
+		return dir
+	}
 	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
 		return filepath.Join(dir, "ferrisfetch")
 	}
@@ -43,13 +295,19 @@ func cacheBase() string {
 	return filepath.Join(os.TempDir(), "ferrisfetch")
 }
 
-// DBPath returns the path to the DuckDB database file.
+// DBPath returns the path to the database file.
 func DBPath() string {
+	if path := pathSetting("paths.db"); path != "" {
+		return path
+	}
 	return filepath.Join(cacheBase(), "db.db")
 }
 
 // CASDir returns the path to the content-addressable storage directory.
 func CASDir() string {
+	if dir := pathSetting("paths.cas"); dir != "" {
+		return dir
+	}
 	return filepath.Join(cacheBase(), "cas")
 }
 
@@ -58,13 +316,27 @@ func JSONCacheDir() string {
 	return filepath.Join(cacheBase(), "json")
 }
 
+// DownloadsDir returns the directory holding rustdoc JSON downloads as
+// served, for conditional and resumed fetches.
+func DownloadsDir() string {
+	return filepath.Join(cacheBase(), "downloads")
+}
+
 // LogPath returns the path to the daemon's log file.
 func LogPath() string {
 	return filepath.Join(cacheBase(), "daemon.log")
 }
 
+// VCRDir returns the default directory for recorded HTTP fixtures.
+func VCRDir() string {
+	return filepath.Join(cacheBase(), "vcr")
+}
+
 // SocketPath returns the path to the daemon's unix socket.
 func SocketPath() string {
+	if path := pathSetting("paths.socket"); path != "" {
+		return path
+	}
 	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
 		return filepath.Join(dir, "ferrisfetch", "daemon.sock")
 	}
@@ -82,9 +354,47 @@ func InitializeViper() error {
 		viper.AddConfigPath(filepath.Join(home, ".config", "ferrisfetch"))
 	}
 
+	viper.SetDefault("paths.cache_dir", "")
+	viper.SetDefault("paths.db", "")
+	viper.SetDefault("paths.cas", "")
+	viper.SetDefault("paths.socket", "")
+	viper.SetDefault("voyage_ai.provider", "voyage")
 	viper.SetDefault("voyage_ai.model", "voyage-3.5")
 	viper.SetDefault("voyage_ai.rerank_model", "rerank-lite-1")
+	viper.SetDefault("voyage_ai.dimensions", 0)
+	viper.SetDefault("voyage_ai.requests_per_minute", 0)
+	viper.SetDefault("voyage_ai.tokens_per_minute", 0)
+	viper.SetDefault("voyage_ai.batch_size", 50)
+	viper.SetDefault("voyage_ai.batch_tokens", 50000)
+	viper.SetDefault("voyage_ai.concurrency", 2)
+	viper.SetDefault("voyage_ai.price_per_million_tokens", 0.0)
+	viper.SetDefault("embedding.max_tokens_per_crate", 0)
+	viper.SetDefault("embedding.monthly_budget_tokens", 0)
+	viper.SetDefault("index.quantization", "none")
+	viper.SetDefault("index.storage", "memory")
+	viper.SetDefault("index.chunk_max_tokens", 2000)
+	viper.SetDefault("index.chunk_overlap_tokens", 200)
+	viper.SetDefault("index.ignore.patterns", []string{})
+	viper.SetDefault("index.ignore.hidden", true)
+	viper.SetDefault("index.ignore.deprecated", false)
+	viper.SetDefault("daemon.expiration", "")
 	viper.SetDefault("daemon.expiration_seconds", 600)
+	viper.SetDefault("daemon.auto_fetch_max_seconds", 0)
+	viper.SetDefault("daemon.provider_check_minutes", 15)
+	viper.SetDefault("daemon.listen", "")
+	viper.SetDefault("daemon.preload", false)
+	viper.SetDefault("daemon.cas_cache_mb", 64)
+	viper.SetDefault("search.rerank_skip_similarity", 0.85)
+	viper.SetDefault("search.rerank_skip_margin", 0.15)
+	viper.SetDefault("search.rerank", "auto")
+	viper.SetDefault("search.snippet_length", 200)
+	viper.SetDefault("search.popularity_weight", 0)
+	viper.SetDefault("search.latest_weight", 0)
+	viper.SetDefault("search.deprecated_penalty", 0)
+	viper.SetDefault("crawl.requests_per_second", 1)
+	viper.SetDefault("crawl.max_retries", 4)
+	viper.SetDefault("vcr.mode", "")
+	viper.SetDefault("vcr.dir", "")
 
 	viper.SetEnvPrefix("FERRISFETCH")
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
@@ -110,33 +420,92 @@ func stringToApiKeyConfigHookFunc() mapstructure.DecodeHookFunc {
 	}
 }
 
-func Load() (*Config, error) {
-	if err := InitializeViper(); err != nil {
-		return nil, err
-	}
-
-	var config Config
+// decode unmarshals settings read by viper into result, accepting a plain
+// string for an ApiKeyConfig.
+func decode(input, result any) error {
 	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
 		DecodeHook: stringToApiKeyConfigHookFunc(),
-		Result:     &config,
+		Result:     result,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create decoder: %w", err)
+		return fmt.Errorf("failed to create decoder: %w", err)
+	}
+	if err := decoder.Decode(input); err != nil {
+		return fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	return nil
+}
 
-	if err := decoder.Decode(viper.AllSettings()); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+func Load() (*Config, error) {
+	if err := InitializeViper(); err != nil {
+		return nil, err
 	}
 
-	if err := resolveApiKey(&config.VoyageAI.ApiKey); err != nil {
+	var config Config
+	if err := decode(viper.AllSettings(), &config); err != nil {
+		return nil, err
+	}
+
+	if err := resolveApiKey(&config.VoyageAI.ApiKey, "voyage_ai.api_key"); err != nil {
 		return nil, fmt.Errorf("failed to resolve VoyageAI API key: %w", err)
 	}
+	if _, err := config.Daemon.IdleExpiration(); err != nil {
+		return nil, fmt.Errorf("daemon.expiration: %w", err)
+	}
+	if err := resolveApiKey(&config.Daemon.AuthToken, "daemon.auth_token"); err != nil {
+		return nil, fmt.Errorf("failed to resolve daemon auth token: %w", err)
+	}
+	if err := config.Daemon.validateListen(); err != nil {
+		return nil, err
+	}
+	for name, reg := range config.Registries {
+		if reg.DocsURL == "" {
+			return nil, fmt.Errorf("registries.%s.docs_url is required", name)
+		}
+		if err := resolveApiKey(&reg.Token, "registries."+name+".token"); err != nil {
+			return nil, fmt.Errorf("failed to resolve token for registry %s: %w", name, err)
+		}
+		config.Registries[name] = reg
+	}
+	var err error
+	if config.warnings, err = config.validate(); err != nil {
+		return nil, err
+	}
 
 	return &config, nil
 }
 
-func resolveApiKey(apiKey *ApiKeyConfig) error {
-	if envKey := viper.GetString("voyage_ai.api_key"); envKey != "" {
+// validateListen refuses TCP listening without an auth token: anyone who
+// can reach the port could otherwise index crates or wipe the cache.
+func (d DaemonConfig) validateListen() error {
+	if d.Listen != "" && d.AuthToken.Value == "" {
+		return fmt.Errorf("daemon.listen requires daemon.auth_token")
+	}
+	return nil
+}
+
+// AuthToken returns daemon.auth_token for clients, without loading (and
+// validating) the rest of the configuration.
+func AuthToken() (string, error) {
+	if err := InitializeViper(); err != nil {
+		return "", err
+	}
+	var token ApiKeyConfig
+	if raw := viper.Get("daemon.auth_token"); raw != nil {
+		if err := decode(raw, &token); err != nil {
+			return "", fmt.Errorf("daemon.auth_token: %w", err)
+		}
+	}
+	if err := resolveApiKey(&token, "daemon.auth_token"); err != nil {
+		return "", fmt.Errorf("failed to resolve daemon auth token: %w", err)
+	}
+	return token.Value, nil
+}
+
+// resolveApiKey fills in apiKey.Value from the viper key (which also picks up
+// the environment) or from the file at apiKey.Path.
+func resolveApiKey(apiKey *ApiKeyConfig, key string) error {
+	if envKey := viper.GetString(key); envKey != "" {
 		if !strings.HasPrefix(envKey, "/") && !strings.HasPrefix(envKey, "./") && !strings.HasPrefix(envKey, "~/") {
 			apiKey.Value = envKey
 			return nil
@@ -144,18 +513,14 @@ func resolveApiKey(apiKey *ApiKeyConfig) error {
 		apiKey.Path = envKey
 	}
 
-	if apiKey.Path != "" {
-		if strings.HasPrefix(apiKey.Path, "~/") {
-			if home, err := os.UserHomeDir(); err == nil {
-				apiKey.Path = filepath.Join(home, apiKey.Path[2:])
-			}
-		}
-		keyBytes, err := os.ReadFile(apiKey.Path)
-		if err != nil {
-			return fmt.Errorf("failed to read API key from file %s: %w", apiKey.Path, err)
-		}
-		apiKey.Value = strings.TrimSpace(string(keyBytes))
+	apiKey.Path = expandHome(apiKey.Path)
+	if !apiKey.resolved() {
+		return nil
 	}
-
+	value, err := apiKey.resolve(true)
+	if err != nil {
+		return err
+	}
+	apiKey.Value = value
 	return nil
 }
@@ -3,8 +3,13 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/spf13/viper"
 )
 
 func TestCacheBase_XDGSet(t *testing.T) {
@@ -38,3 +43,202 @@ func TestCacheBase_TmpFallback(t *testing.T) {
 		t.Errorf("expected ferrisfetch in path, got %q", got)
 	}
 }
+
+func TestIdleExpiration(t *testing.T) {
+	tests := []struct {
+		cfg  DaemonConfig
+		want time.Duration
+	}{
+		{DaemonConfig{ExpirationSeconds: 600}, 10 * time.Minute},
+		{DaemonConfig{ExpirationSeconds: 0}, 10 * time.Minute},
+		{DaemonConfig{Expiration: "2h", ExpirationSeconds: 600}, 2 * time.Hour},
+		{DaemonConfig{Expiration: "never", ExpirationSeconds: 600}, 0},
+	}
+	for _, tt := range tests {
+		got, err := tt.cfg.IdleExpiration()
+		if err != nil || got != tt.want {
+			t.Errorf("%+v: got %v, %v; want %v", tt.cfg, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"soon", "0s", "-1m"} {
+		if _, err := (DaemonConfig{Expiration: bad}).IdleExpiration(); err == nil {
+			t.Errorf("expiration %q: expected an error", bad)
+		}
+	}
+}
+
+func TestValidateListen(t *testing.T) {
+	if err := (DaemonConfig{}).validateListen(); err != nil {
+		t.Errorf("no listen: %v", err)
+	}
+	if err := (DaemonConfig{Listen: "127.0.0.1:7777"}).validateListen(); err == nil {
+		t.Error("listen without a token: expected an error")
+	}
+	if err := (DaemonConfig{Listen: "127.0.0.1:7777", AuthToken: ApiKeyConfig{Value: "secret"}}).validateListen(); err != nil {
+		t.Errorf("listen with a token: %v", err)
+	}
+}
+
+func TestPathOverrides(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", "/xdg")
+	t.Cleanup(viper.Reset)
+	if got, want := CASDir(), filepath.Join("/xdg", "ferrisfetch", "cas"); got != want {
+		t.Errorf("default CASDir = %q, want %q", got, want)
+	}
+
+	viper.Set("paths.cache_dir", "/data/ff")
+	viper.Set("paths.cas", "~/bulk/cas")
+	if got, want := DBPath(), filepath.Join("/data/ff", "db.db"); got != want {
+		t.Errorf("DBPath = %q, want %q", got, want)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("cannot determine home dir")
+	}
+	if got, want := CASDir(), filepath.Join(home, "bulk", "cas"); got != want {
+		t.Errorf("CASDir = %q, want %q", got, want)
+	}
+	want := []string{"--cache-dir=/data/ff", "--cas-dir=" + filepath.Join(home, "bulk", "cas")}
+	if got := PathArgs(); !slices.Equal(got, want) {
+		t.Errorf("PathArgs = %q, want %q", got, want)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	old := &Config{
+		VoyageAI:   VoyageAIConfig{ApiKey: ApiKeyConfig{Value: "old-key"}, Model: "voyage-3.5"},
+		Registries: map[string]RegistryConfig{"corp": {DocsURL: "https://docs", Token: ApiKeyConfig{Value: "t1"}}},
+	}
+	new := &Config{
+		VoyageAI:   VoyageAIConfig{ApiKey: ApiKeyConfig{Value: "new-key"}, Model: "voyage-3-large"},
+		Search:     SearchConfig{KindWeights: map[string]float64{"function": 1.2}},
+		Registries: map[string]RegistryConfig{"corp": {DocsURL: "https://docs", Token: ApiKeyConfig{Value: "t2"}}, "other": {DocsURL: "https://x"}},
+	}
+	got := Diff(old, new)
+	want := []Change{
+		{"voyage_ai.api_key", "set", "set"},
+		{"voyage_ai.model", "voyage-3.5", "voyage-3-large"},
+		{"search.kind_weights.function", "", "1.2"},
+		{"registries.corp.token", "set", "set"},
+		{"registries.other.docs_url", "", "https://x"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Diff =\n%q\nwant\n%q", got, want)
+	}
+	if got := Diff(old, old); len(got) != 0 {
+		t.Errorf("Diff of identical configs = %q", got)
+	}
+}
+
+func TestKnownKey(t *testing.T) {
+	for key, want := range map[string]bool{
+		"voyage_ai.model":              true,
+		"voyage_ai.api_key":            true,
+		"voyage_ai.api_key.path":       true,
+		"search.kind_weights.function": true,
+		"registries.corp.token.path":   true,
+		"registries.corp.docs_url":     true,
+		"voyage_ai.modle":              false,
+		"voyage_ai.api_key.file":       false,
+		"registries.corp.url":          false,
+		"daemon.listen.port":           false,
+		"nonsense":                     false,
+	} {
+		if got := knownKey(reflect.TypeOf(Config{}), strings.Split(key, ".")); got != want {
+			t.Errorf("knownKey(%s) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	viper.Set("voyage_ai.modle", "voyage-3")
+
+	cfg := &Config{VoyageAI: VoyageAIConfig{Model: "voyage-9"}}
+	warnings, err := cfg.validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 3 || !strings.Contains(warnings[0], "voyage_ai.modle") || !strings.Contains(warnings[1], "voyage-9") || !strings.Contains(warnings[2], "api_key") {
+		t.Errorf("warnings = %q, want unknown key, unknown model and missing key", warnings)
+	}
+
+	cfg = &Config{VoyageAI: VoyageAIConfig{Model: "voyage-3", Dimensions: 256, ApiKey: ApiKeyConfig{Value: "k"}}}
+	if _, err := cfg.validate(); err == nil {
+		t.Error("validate accepted a dimension voyage-3 doesn't produce")
+	}
+
+	cfg = &Config{VoyageAI: VoyageAIConfig{Model: "voyage-3", ApiKey: ApiKeyConfig{Value: "k"}}, Index: IndexConfig{ChunkMaxTokens: 200, ChunkOverlapTokens: 200}}
+	if _, err := cfg.validate(); err == nil {
+		t.Error("validate accepted a chunk overlap as large as the chunks")
+	}
+
+	cfg = &Config{VoyageAI: VoyageAIConfig{Provider: "fake", Model: "voyage-3"}}
+	if warnings, err := cfg.validate(); err != nil || len(warnings) != 1 {
+		t.Errorf("fake provider without a key: warnings %q, %v; want only the unknown key", warnings, err)
+	}
+	cfg = &Config{VoyageAI: VoyageAIConfig{Provider: "openai", Model: "voyage-3"}}
+	if _, err := cfg.validate(); err == nil {
+		t.Error("validate accepted an unknown provider")
+	}
+
+	cfg = &Config{VoyageAI: VoyageAIConfig{Model: "voyage-3", ApiKey: ApiKeyConfig{Value: "k"}}, Index: IndexConfig{Ignore: IgnoreConfig{Patterns: []string{"*::__private", "*"}}}}
+	if _, err := cfg.validate(); err == nil {
+		t.Error("validate accepted an ignore pattern matching every item")
+	}
+
+	cfg = &Config{VoyageAI: VoyageAIConfig{Model: "voyage-3", ApiKey: ApiKeyConfig{Value: "k"}}, Embedding: EmbeddingConfig{MonthlyBudgetTokens: -1}}
+	if _, err := cfg.validate(); err == nil {
+		t.Error("validate accepted a negative monthly budget")
+	}
+}
+
+func TestApiKeyCommand(t *testing.T) {
+	runs := filepath.Join(t.TempDir(), "runs")
+	key := ApiKeyConfig{Command: "echo run >> " + runs + "; wc -l < " + runs}
+	if err := resolveApiKey(&key, "test.command_key"); err != nil {
+		t.Fatal(err)
+	}
+	if key.Value != "1" {
+		t.Errorf("Value = %q, want 1", key.Value)
+	}
+	again := ApiKeyConfig{Command: key.Command}
+	if err := resolveApiKey(&again, "test.command_key"); err != nil || again.Value != "1" {
+		t.Errorf("second resolve = %q, %v; want the cached 1", again.Value, err)
+	}
+	if v, err := key.Refresh(); err != nil || v != "2" {
+		t.Errorf("Refresh = %q, %v; want 2", v, err)
+	}
+
+	if err := resolveApiKey(&ApiKeyConfig{Command: "exit 3"}, "test.failing_key"); err == nil {
+		t.Error("failing command resolved")
+	}
+	if err := resolveApiKey(&ApiKeyConfig{Command: "true", Keychain: "x"}, "test.both_key"); err == nil {
+		t.Error("command and keychain together resolved")
+	}
+}
+
+func TestAuthToken(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	if err := os.MkdirAll(filepath.Join(dir, "ferrisfetch"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := filepath.Join(dir, "ferrisfetch", "config.toml")
+
+	for _, tc := range []struct {
+		name, toml string
+	}{
+		{"inline", "[daemon]\nauth_token = \"from-inline\"\n"},
+		{"command", "[daemon.auth_token]\ncommand = \"echo from-command\"\n"},
+	} {
+		viper.Reset()
+		if err := os.WriteFile(config, []byte(tc.toml), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := AuthToken(); err != nil || got != "from-"+tc.name {
+			t.Errorf("%s: AuthToken = %q, %v; want from-%s", tc.name, got, err, tc.name)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// pinned holds the paths.* settings fixed by PinPaths.
+var pinned map[string]string
+
+// PinPaths fixes the paths.* settings at their current values, so reloading
+// the config can't move the database or socket out from under a running
+// daemon. Call it once, before anything reads paths concurrently.
+func PinPaths() {
+	pinned = make(map[string]string, len(PathFlags))
+	for _, p := range PathFlags {
+		pinned[p.Key] = pathSetting(p.Key)
+	}
+}
+
+// Watch calls onChange whenever the config file is written. It does nothing
+// if no config file was found at startup.
+func Watch(onChange func()) {
+	if viper.ConfigFileUsed() == "" {
+		return
+	}
+	viper.OnConfigChange(func(fsnotify.Event) { onChange() })
+	viper.WatchConfig()
+}
+
+// Change is a setting that differs between two configs. Secrets are shown
+// only as "set" or "unset".
+type Change struct {
+	Key string
+	Old string
+	New string
+}
+
+// Diff lists the settings that differ between old and new, by config key,
+// in declaration order with removed map entries last.
+func Diff(old, new *Config) []Change {
+	before := make(map[string]setting)
+	for _, s := range flatten(old) {
+		before[s.key] = s
+	}
+	var changes []Change
+	for _, s := range flatten(new) {
+		prev, ok := before[s.key]
+		delete(before, s.key)
+		if !ok {
+			prev = setting{s.key, "", s.secret}
+		}
+		if c, changed := change(prev, s); changed {
+			changes = append(changes, c)
+		}
+	}
+	for _, s := range flatten(old) {
+		if _, removed := before[s.key]; removed {
+			c, _ := change(s, setting{s.key, "", s.secret})
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+func change(old, new setting) (Change, bool) {
+	o, n := fmt.Sprint(old.value), fmt.Sprint(new.value)
+	if o == n {
+		return Change{}, false
+	}
+	if new.secret {
+		o, n = secretState(o), secretState(n)
+	}
+	return Change{Key: new.key, Old: o, New: n}, true
+}
+
+func secretState(v string) string {
+	if v == "" {
+		return "unset"
+	}
+	return "set"
+}
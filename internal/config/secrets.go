@@ -0,0 +1,104 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// secretCommandTimeout bounds secret commands and keychain lookups, which
+// may wait on a password prompt.
+const secretCommandTimeout = time.Minute
+
+// secretCache holds secrets from commands and keychains, so reloading the
+// config doesn't run a password manager again.
+var (
+	secretCache   = map[string]string{}
+	secretCacheMu sync.Mutex
+)
+
+// resolved reports whether k's value comes from a file, command or
+// keychain rather than the config itself.
+func (k ApiKeyConfig) resolved() bool {
+	return k.Path != "" || k.Command != "" || k.Keychain != ""
+}
+
+// resolve reads k's secret from its source. Command and keychain results
+// are cached unless cached is false.
+func (k ApiKeyConfig) resolve(cached bool) (string, error) {
+	sources := 0
+	for _, s := range []string{k.Path, k.Command, k.Keychain} {
+		if s != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return "", fmt.Errorf("set only one of path, command and keychain")
+	}
+
+	switch {
+	case k.Path != "":
+		keyBytes, err := os.ReadFile(k.Path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read API key from file %s: %w", k.Path, err)
+		}
+		return strings.TrimSpace(string(keyBytes)), nil
+	case k.Command != "":
+		return cachedSecret("command:"+k.Command, cached, func(ctx context.Context) *exec.Cmd {
+			return exec.CommandContext(ctx, "sh", "-c", k.Command)
+		})
+	default:
+		if runtime.GOOS == "windows" {
+			return "", fmt.Errorf("keychain lookups need macOS or the Secret Service; use command instead")
+		}
+		return cachedSecret("keychain:"+k.Keychain, cached, func(ctx context.Context) *exec.Cmd {
+			if runtime.GOOS == "darwin" {
+				return exec.CommandContext(ctx, "security", "find-generic-password", "-s", k.Keychain, "-w")
+			}
+			return exec.CommandContext(ctx, "secret-tool", "lookup", "service", k.Keychain)
+		})
+	}
+}
+
+// Refresh resolves k's secret again, bypassing the cache, for when the
+// value in use was rejected. Inline values are returned as they are.
+func (k ApiKeyConfig) Refresh() (string, error) {
+	if !k.resolved() {
+		return k.Value, nil
+	}
+	return k.resolve(false)
+}
+
+// cachedSecret returns the trimmed output of the command newCmd builds,
+// from the cache under key unless cached is false.
+func cachedSecret(key string, cached bool, newCmd func(context.Context) *exec.Cmd) (string, error) {
+	secretCacheMu.Lock()
+	defer secretCacheMu.Unlock()
+	if v, ok := secretCache[key]; ok && cached {
+		return v, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), secretCommandTimeout)
+	defer cancel()
+	cmd := newCmd(ctx)
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			err = fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("running %s: %w", strings.Join(cmd.Args, " "), err)
+	}
+	v := strings.TrimSpace(string(out))
+	if v == "" {
+		return "", fmt.Errorf("%s printed nothing", strings.Join(cmd.Args, " "))
+	}
+	secretCache[key] = v
+	return v, nil
+}
@@ -0,0 +1,189 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jcdickinson/ferrisfetch/internal/embeddings"
+	"github.com/spf13/viper"
+)
+
+// validate checks settings that decode fine but can't work, returning
+// warnings for those the daemon can still run with.
+func (c *Config) validate() (warnings []string, err error) {
+	if p := c.VoyageAI.Provider; p != "" && p != "voyage" && p != "fake" {
+		return nil, fmt.Errorf("voyage_ai.provider: %q is not voyage or fake", p)
+	}
+	if _, err := embeddings.OutputDimension(c.VoyageAI.Model, c.VoyageAI.Dimensions); err != nil {
+		return nil, fmt.Errorf("voyage_ai.dimensions: %w", err)
+	}
+	if c.VoyageAI.PricePerMillionTokens < 0 {
+		return nil, fmt.Errorf("voyage_ai.price_per_million_tokens: %g is negative", c.VoyageAI.PricePerMillionTokens)
+	}
+	if c.Embedding.MaxTokensPerCrate < 0 || c.Embedding.MonthlyBudgetTokens < 0 {
+		return nil, fmt.Errorf("embedding.max_tokens_per_crate and embedding.monthly_budget_tokens can't be negative; use 0 for no cap")
+	}
+	if c.Crawl.RequestsPerSecond < 0 {
+		return nil, fmt.Errorf("crawl.requests_per_second: %v is negative; use 0 to disable pacing", c.Crawl.RequestsPerSecond)
+	}
+	if c.Crawl.MaxRetries < 0 {
+		return nil, fmt.Errorf("crawl.max_retries: %d is negative", c.Crawl.MaxRetries)
+	}
+	if c.Search.PopularityWeight < 0 || c.Search.LatestWeight < 0 {
+		return nil, fmt.Errorf("search.popularity_weight and search.latest_weight can't be negative")
+	}
+	if c.Search.DeprecatedPenalty < 0 || c.Search.DeprecatedPenalty > 1 {
+		return nil, fmt.Errorf("search.deprecated_penalty: %g must be between 0 and 1", c.Search.DeprecatedPenalty)
+	}
+	if c.Daemon.CASCacheMB < 0 {
+		return nil, fmt.Errorf("daemon.cas_cache_mb: %d is negative; use 0 to disable the cache", c.Daemon.CASCacheMB)
+	}
+	if c.Index.ChunkMaxTokens < 0 || c.Index.ChunkOverlapTokens < 0 {
+		return nil, fmt.Errorf("index.chunk_max_tokens and index.chunk_overlap_tokens can't be negative")
+	}
+	if c.Index.ChunkMaxTokens > 0 && c.Index.ChunkOverlapTokens >= c.Index.ChunkMaxTokens {
+		return nil, fmt.Errorf("index.chunk_overlap_tokens: %d must be less than index.chunk_max_tokens (%d)", c.Index.ChunkOverlapTokens, c.Index.ChunkMaxTokens)
+	}
+	for _, p := range c.Index.Ignore.Patterns {
+		if strings.Trim(p, "*:") == "" {
+			return nil, fmt.Errorf("index.ignore.patterns: %q would leave out every item", p)
+		}
+	}
+	for _, key := range unknownKeys() {
+		warnings = append(warnings, fmt.Sprintf("unknown setting %s", key))
+	}
+	if _, ok := embeddings.ModelDimension(c.VoyageAI.Model, c.VoyageAI.Dimensions); !ok {
+		warnings = append(warnings, fmt.Sprintf("voyage_ai.model %q isn't a known Voyage model; set voyage_ai.dimensions to its embedding size", c.VoyageAI.Model))
+	}
+	if c.VoyageAI.ApiKey.Value == "" && !c.VoyageAI.Fake() {
+		warnings = append(warnings, "voyage_ai.api_key is not set; adding crates and searching need it")
+	}
+	return warnings, nil
+}
+
+// Warnings lists problems Load found that don't stop the config loading,
+// such as misspelled keys.
+func (c *Config) Warnings() []string {
+	return c.warnings
+}
+
+// File returns the config file in use, or "" if none was found.
+func File() string {
+	return viper.ConfigFileUsed()
+}
+
+// unknownKeys returns the keys set in the config file that no setting
+// reads.
+func unknownKeys() []string {
+	var unknown []string
+	for _, key := range viper.AllKeys() {
+		if !knownKey(reflect.TypeOf(Config{}), strings.Split(key, ".")) {
+			unknown = append(unknown, key)
+		}
+	}
+	slices.Sort(unknown)
+	return unknown
+}
+
+func knownKey(t reflect.Type, parts []string) bool {
+	if len(parts) == 0 {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := range t.NumField() {
+			if f := t.Field(i); f.IsExported() && f.Tag.Get("mapstructure") == parts[0] {
+				return knownKey(f.Type, parts[1:])
+			}
+		}
+	case reflect.Map:
+		return knownKey(t.Elem(), parts[1:])
+	}
+	return false
+}
+
+// setting is one leaf of a Config, by config key.
+type setting struct {
+	key    string
+	value  any
+	secret bool
+}
+
+// flatten lists cfg's settings in declaration order, with map entries
+// sorted by name.
+func flatten(cfg *Config) []setting {
+	var out []setting
+	flattenValue("", reflect.ValueOf(*cfg), &out)
+	return out
+}
+
+var apiKeyType = reflect.TypeOf(ApiKeyConfig{})
+
+func flattenValue(key string, v reflect.Value, out *[]setting) {
+	switch {
+	case v.Type() == apiKeyType:
+		*out = append(*out, setting{key, v.Interface().(ApiKeyConfig).Value, true})
+	case v.Kind() == reflect.Struct:
+		for i := range v.NumField() {
+			if f := v.Type().Field(i); f.IsExported() {
+				flattenValue(joinKey(key, f.Tag.Get("mapstructure")), v.Field(i), out)
+			}
+		}
+	case v.Kind() == reflect.Map:
+		names := make([]string, 0, v.Len())
+		for _, k := range v.MapKeys() {
+			names = append(names, k.String())
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			flattenValue(joinKey(key, name), v.MapIndex(reflect.ValueOf(name)), out)
+		}
+	default:
+		*out = append(*out, setting{key, v.Interface(), false})
+	}
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// Setting is a config key with its value as a TOML literal.
+type Setting struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// resolvedPaths gives the paths.* settings the locations they resolve to.
+var resolvedPaths = map[string]func() string{
+	"paths.cache_dir": cacheBase,
+	"paths.db":        DBPath,
+	"paths.cas":       CASDir,
+	"paths.socket":    SocketPath,
+}
+
+// Effective lists every setting with the value in force: defaults filled
+// in, paths resolved and secrets redacted.
+func (c *Config) Effective() []Setting {
+	var out []Setting
+	for _, s := range flatten(c) {
+		value := s.value
+		switch {
+		case s.secret && value != "":
+			value = "<redacted>"
+		case resolvedPaths[s.key] != nil:
+			value = resolvedPaths[s.key]()
+		}
+		literal := fmt.Sprint(value)
+		if str, ok := value.(string); ok {
+			literal = strconv.Quote(str)
+		}
+		out = append(out, Setting{Key: s.key, Value: literal})
+	}
+	return out
+}
@@ -0,0 +1,42 @@
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// listenFDsStart is the first file descriptor systemd passes to an
+// activated service (SD_LISTEN_FDS_START).
+const listenFDsStart = 3
+
+// activationListener returns the socket systemd passed in under socket
+// activation (LISTEN_PID and LISTEN_FDS), or nil if the daemon wasn't
+// socket-activated. The variables are cleared so children don't inherit
+// them.
+func activationListener() (net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	raw := os.Getenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+	fds, err := strconv.Atoi(raw)
+	if err != nil || fds < 1 {
+		return nil, fmt.Errorf("socket activation: invalid LISTEN_FDS %q", raw)
+	}
+	if fds > 1 {
+		return nil, fmt.Errorf("socket activation: expected one socket, got %d", fds)
+	}
+
+	f := os.NewFile(listenFDsStart, "systemd-socket")
+	defer f.Close()
+	listener, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("socket activation: %w", err)
+	}
+	return listener, nil
+}
@@ -0,0 +1,61 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+)
+
+type mediaTypeKey struct{}
+
+// negotiate picks the response media type for route from the request's
+// Accept header, answering 406 if the route can't produce any it accepts.
+// Handlers read the choice with mediaType.
+func negotiate(route rpc.Route, next http.HandlerFunc) http.HandlerFunc {
+	offers := route.MediaTypes()
+	return func(w http.ResponseWriter, r *http.Request) {
+		media, ok := rpc.Negotiate(r.Header.Get("Accept"), offers)
+		if !ok {
+			writeError(w, http.StatusNotAcceptable, fmt.Sprintf("%s can only answer %s", route.Pattern, strings.Join(offers, ", ")))
+			return
+		}
+		w.Header().Add("Vary", "Accept")
+		next(w, r.WithContext(context.WithValue(r.Context(), mediaTypeKey{}, media)))
+	}
+}
+
+// mediaType is the response media type negotiated for r.
+func mediaType(r *http.Request) string {
+	if media, ok := r.Context().Value(mediaTypeKey{}).(string); ok {
+		return media
+	}
+	return rpc.MediaJSON
+}
+
+// writeMarkdown answers with markdown if that was negotiated, else with v
+// as JSON.
+func writeMarkdown(w http.ResponseWriter, r *http.Request, v any, markdown string) {
+	if mediaType(r) != rpc.MediaMarkdown {
+		writeJSON(w, http.StatusOK, v)
+		return
+	}
+	w.Header().Set("Content-Type", rpc.MediaMarkdown+"; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(markdown))
+}
+
+var openAPIDoc = sync.OnceValues(rpc.OpenAPI)
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	doc, err := openAPIDoc()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", rpc.MediaJSON)
+	w.Write(doc)
+}
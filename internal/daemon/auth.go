@@ -0,0 +1,34 @@
+package daemon
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+)
+
+// authExempt lists the paths served without a token, so monitoring can
+// check the daemon is up, and clients its protocol, without holding it.
+var authExempt = map[string]bool{
+	"/health":                         true,
+	"/" + rpc.APIVersion + "/health":  true,
+	"/version":                        true,
+	"/" + rpc.APIVersion + "/version": true,
+}
+
+// withAuth requires daemon.auth_token as a bearer token on every request
+// except those in authExempt. Without a token configured it lets everything
+// through: the socket's file permissions are the only guard. The token is
+// read per request, so a config reload can rotate it.
+func (s *Server) withAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := s.live().cfg.Daemon.AuthToken.Value
+		if token != "" && !authExempt[r.URL.Path] && subtle.ConstantTimeCompare([]byte(strings.TrimSpace(r.Header.Get("Authorization"))), []byte("Bearer "+token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="ferrisfetch"`)
+			writeError(w, http.StatusUnauthorized, "missing or wrong auth token; send daemon.auth_token as a bearer token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
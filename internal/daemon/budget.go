@@ -0,0 +1,43 @@
+package daemon
+
+import (
+	"fmt"
+	"time"
+)
+
+// budgetError is an add refused because embedding it would go over
+// embedding.max_tokens_per_crate or embedding.monthly_budget_tokens.
+type budgetError struct {
+	estimate int64  // estimated tokens the add would embed
+	setting  string // the cap it would exceed
+	limit    int64
+	used     int64 // tokens already billed this month, for the monthly cap
+}
+
+func (e *budgetError) Error() string {
+	over := fmt.Sprintf("embedding about %d tokens would exceed %s (%d", e.estimate, e.setting, e.limit)
+	if e.used > 0 {
+		over += fmt.Sprintf(", %d used this month", e.used)
+	}
+	return over + "); use --over-budget to index it anyway"
+}
+
+// checkBudget refuses an add estimated to embed estimate tokens when that
+// goes over the per-crate cap or what is left of this month's budget.
+func (s *Server) checkBudget(estimate int64) error {
+	caps := s.live().cfg.Embedding
+	if limit := int64(caps.MaxTokensPerCrate); limit > 0 && estimate > limit {
+		return &budgetError{estimate: estimate, setting: "embedding.max_tokens_per_crate", limit: limit}
+	}
+	if limit := int64(caps.MonthlyBudgetTokens); limit > 0 {
+		now := time.Now().UTC()
+		used, err := s.db.UsageSince(time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC))
+		if err != nil {
+			return fmt.Errorf("checking this month's usage: %w", err)
+		}
+		if used+estimate > limit {
+			return &budgetError{estimate: estimate, setting: "embedding.monthly_budget_tokens", limit: limit, used: used}
+		}
+	}
+	return nil
+}
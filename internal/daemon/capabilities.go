@@ -0,0 +1,38 @@
+package daemon
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+	"github.com/jcdickinson/ferrisfetch/internal/search"
+)
+
+func (s *Server) handleCapabilities(w http.ResponseWriter, r *http.Request) {
+	cfg := s.live().cfg
+	resp := rpc.CapabilitiesResponse{
+		Endpoints:   s.endpoints,
+		APIVersions: []string{rpc.APIVersion},
+		Auth:        cfg.Daemon.AuthToken.Value != "",
+	}
+	// Replayed fixtures stand in for the provider when there is no key.
+	if cfg.VoyageAI.ApiKey.Value != "" || s.vcr || cfg.VoyageAI.Fake() {
+		resp.Embeddings = "voyage"
+		if cfg.VoyageAI.Fake() {
+			resp.Embeddings = "fake"
+		}
+		resp.EmbeddingModel = s.embeddingModel()
+		if cfg.Search.Rerank != search.RerankOff {
+			resp.Rerank = cfg.VoyageAI.RerankModel
+		}
+	}
+	for name := range cfg.Registries {
+		resp.Registries = append(resp.Registries, name)
+	}
+	slices.Sort(resp.Registries)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, rpc.VersionResponse{Protocol: rpc.ProtocolVersion, APIVersion: rpc.APIVersion})
+}
@@ -7,8 +7,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/jcdickinson/ferrisfetch/internal/rpc"
@@ -17,6 +22,12 @@ import (
 type Client struct {
 	socketPath string
 	httpClient *http.Client
+	// legacy is set once the daemon turns out to predate versioned routes.
+	legacy atomic.Bool
+	// authToken is sent as a bearer token when set.
+	authToken string
+	// namespace fills in requests that don't name one; see SetNamespace.
+	namespace string
 }
 
 func NewClient(socketPath string) *Client {
@@ -33,6 +44,26 @@ func NewClient(socketPath string) *Client {
 	}
 }
 
+// SetAuthToken makes the client authenticate with token, the daemon's
+// daemon.auth_token.
+func (c *Client) SetAuthToken(token string) {
+	c.authToken = token
+}
+
+// SetNamespace makes requests that name crates use namespace ns unless they
+// set their own.
+func (c *Client) SetNamespace(ns string) {
+	c.namespace = ns
+}
+
+// scope returns ns, or the client's namespace if ns is empty.
+func (c *Client) scope(ns string) string {
+	if ns == "" {
+		return c.namespace
+	}
+	return ns
+}
+
 // ConnectOrSpawn tries to connect to the daemon, spawning it if necessary.
 func ConnectOrSpawn(socketPath string) (*Client, error) {
 	client := NewClient(socketPath)
@@ -65,30 +96,94 @@ func (c *Client) IsAvailable() bool {
 	return true
 }
 
-// do executes an HTTP request, respawning the daemon on connection failure and retrying once.
+// url is the daemon URL for an unversioned route path such as "/search".
+func (c *Client) url(path string) string {
+	if c.legacy.Load() {
+		return "http://unix" + path
+	}
+	return "http://unix/" + rpc.APIVersion + path
+}
+
+// do executes an HTTP request. A daemon that doesn't know the versioned
+// route, being older than this client, is asked again on the unversioned one.
 func (c *Client) do(req *http.Request) (*http.Response, error) {
+	resp, err := c.send(req)
+	if err != nil || !isMissingRoute(resp) || !strings.HasPrefix(req.URL.Path, "/"+rpc.APIVersion+"/") {
+		return resp, err
+	}
+	resp.Body.Close()
+	c.legacy.Store(true)
+	legacy := req.Clone(req.Context())
+	legacy.URL.Path = strings.TrimPrefix(req.URL.Path, "/"+rpc.APIVersion)
+	if req.GetBody != nil {
+		if legacy.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	return c.send(legacy)
+}
+
+// isMissingRoute reports whether resp is the mux's 404 for an unknown path,
+// rather than a handler's JSON 404 for something it couldn't find.
+func isMissingRoute(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusNotFound && !strings.HasPrefix(resp.Header.Get("Content-Type"), rpc.MediaJSON)
+}
+
+// send executes an HTTP request. If the daemon is gone, e.g. it expired
+// during a long MCP session, it is respawned and the request sent again:
+// always when the request never reached it, and only for idempotent routes
+// when the connection dropped mid-request, since the daemon may have acted
+// on it.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	if c.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
 	resp, err := c.httpClient.Do(req)
 	if err == nil {
 		return resp, nil
 	}
-	if !isConnError(err) {
+	switch {
+	case isConnError(err):
+	case isConnLost(err) && isIdempotent(req):
+	case isConnLost(err):
+		return nil, fmt.Errorf("lost the connection to the daemon mid-request; it may have stopped, and the request may or may not have completed: %w", err)
+	default:
 		return nil, err
 	}
 
-	// Daemon is gone — respawn and retry.
-	if spawnErr := Spawn(); spawnErr != nil {
-		return nil, fmt.Errorf("respawning daemon: %w (original: %w)", spawnErr, err)
+	if spawnErr := c.respawn(); spawnErr != nil {
+		return nil, fmt.Errorf("%w (original: %w)", spawnErr, err)
+	}
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	return c.httpClient.Do(retry)
+}
+
+// respawn starts the daemon unless one is already listening and waits for
+// it to accept connections.
+func (c *Client) respawn() error {
+	if c.IsAvailable() {
+		return nil
+	}
+	if err := Spawn(); err != nil {
+		return fmt.Errorf("respawning daemon: %w", err)
 	}
 	deadline := time.Now().Add(5 * time.Second)
 	for time.Now().Before(deadline) {
 		time.Sleep(100 * time.Millisecond)
 		if c.IsAvailable() {
-			return c.httpClient.Do(req)
+			return nil
 		}
 	}
-	return nil, fmt.Errorf("daemon did not restart: %w", err)
+	return errors.New("daemon did not restart")
 }
 
+// isConnError reports whether err is a failure to connect, so the request
+// never reached the daemon.
 func isConnError(err error) bool {
 	var opErr *net.OpError
 	if errors.As(err, &opErr) {
@@ -97,13 +192,51 @@ func isConnError(err error) bool {
 	return false
 }
 
-func (c *Client) AddCrates(ctx context.Context, crates []rpc.CrateSpec, onProgress func(string)) (*rpc.AddCratesResponse, error) {
-	jsonData, err := json.Marshal(rpc.AddCratesRequest{Crates: crates})
+// isConnLost reports whether err is the connection closing before the
+// response arrived, as when the daemon stops mid-request.
+func isConnLost(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE)
+}
+
+// isIdempotent reports whether req's route can safely be sent twice.
+func isIdempotent(req *http.Request) bool {
+	pattern := req.Method + " " + strings.TrimPrefix(req.URL.Path, "/"+rpc.APIVersion)
+	for _, route := range rpc.Routes {
+		if route.Pattern == pattern {
+			return route.Idempotent
+		}
+	}
+	return false
+}
+
+func (c *Client) AddCrates(ctx context.Context, addReq rpc.AddCratesRequest, onProgress func(string)) (*rpc.AddCratesResponse, error) {
+	addReq.Namespace = c.scope(addReq.Namespace)
+	return c.stream(ctx, "/add-crates", addReq, onProgress)
+}
+
+// Reindex re-indexes crates with the daemon's chunker, reporting progress
+// through onProgress.
+func (c *Client) Reindex(ctx context.Context, req rpc.ReindexRequest, onProgress func(string)) (*rpc.AddCratesResponse, error) {
+	req.Namespace = c.scope(req.Namespace)
+	return c.stream(ctx, "/reindex", req, onProgress)
+}
+
+// Reembed re-indexes every crate with a new embedding model, reporting
+// progress like AddCrates.
+func (c *Client) Reembed(ctx context.Context, req rpc.ReembedRequest, onProgress func(string)) (*rpc.AddCratesResponse, error) {
+	return c.stream(ctx, "/reembed", req, onProgress)
+}
+
+// stream posts body to path and collects the crate results from the
+// NDJSON progress stream it answers with.
+func (c *Client) stream(ctx context.Context, path string, body any, onProgress func(string)) (*rpc.AddCratesResponse, error) {
+	jsonData, err := json.Marshal(body)
 	if err != nil {
 		return nil, fmt.Errorf("marshaling request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "http://unix/add-crates", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.url(path), bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
@@ -143,31 +276,176 @@ func (c *Client) AddCrates(ctx context.Context, crates []rpc.CrateSpec, onProgre
 }
 
 func (c *Client) Search(ctx context.Context, req rpc.SearchRequest) (*rpc.SearchResponse, error) {
+	req.Namespace = c.scope(req.Namespace)
 	var resp rpc.SearchResponse
 	err := c.post(ctx, "/search", req, &resp)
 	return &resp, err
 }
 
 func (c *Client) GetDoc(ctx context.Context, req rpc.GetDocRequest) (*rpc.GetDocResponse, error) {
+	req.Namespace = c.scope(req.Namespace)
 	var resp rpc.GetDocResponse
 	err := c.post(ctx, "/get-doc", req, &resp)
 	return &resp, err
 }
 
+func (c *Client) Summarize(ctx context.Context, req rpc.SummarizeRequest) (*rpc.SummarizeResponse, error) {
+	req.Namespace = c.scope(req.Namespace)
+	var resp rpc.SummarizeResponse
+	err := c.post(ctx, "/summarize", req, &resp)
+	return &resp, err
+}
+
+func (c *Client) BuildContext(ctx context.Context, req rpc.BuildContextRequest) (*rpc.BuildContextResponse, error) {
+	req.Namespace = c.scope(req.Namespace)
+	var resp rpc.BuildContextResponse
+	err := c.post(ctx, "/build-context", req, &resp)
+	return &resp, err
+}
+
+func (c *Client) Diff(ctx context.Context, req rpc.DiffRequest) (*rpc.DiffResponse, error) {
+	req.Namespace = c.scope(req.Namespace)
+	var resp rpc.DiffResponse
+	err := c.post(ctx, "/diff", req, &resp)
+	return &resp, err
+}
+
+func (c *Client) Deps(ctx context.Context, req rpc.DepsRequest) (*rpc.DepsResponse, error) {
+	req.Namespace = c.scope(req.Namespace)
+	var resp rpc.DepsResponse
+	err := c.post(ctx, "/deps", req, &resp)
+	return &resp, err
+}
+
+func (c *Client) Context(ctx context.Context, req rpc.ContextRequest) (*rpc.ContextResponse, error) {
+	req.Namespace = c.scope(req.Namespace)
+	var resp rpc.ContextResponse
+	err := c.post(ctx, "/context", req, &resp)
+	return &resp, err
+}
+
+func (c *Client) Similar(ctx context.Context, req rpc.SimilarRequest) (*rpc.SimilarResponse, error) {
+	req.Namespace = c.scope(req.Namespace)
+	var resp rpc.SimilarResponse
+	err := c.post(ctx, "/similar", req, &resp)
+	return &resp, err
+}
+
+// Symbols looks indexed items up by name or path.
+func (c *Client) Symbols(ctx context.Context, req rpc.SymbolsRequest) (*rpc.SymbolsResponse, error) {
+	req.Namespace = c.scope(req.Namespace)
+	var resp rpc.SymbolsResponse
+	if err := c.post(ctx, "/symbols", req, &resp); err != nil {
+		return nil, fmt.Errorf("symbols request: %w", err)
+	}
+	return &resp, nil
+}
+
+func (c *Client) Verify(ctx context.Context, req rpc.VerifyRequest) (*rpc.VerifyResponse, error) {
+	var resp rpc.VerifyResponse
+	err := c.post(ctx, "/verify", req, &resp)
+	return &resp, err
+}
+
+func (c *Client) Export(ctx context.Context, req rpc.ExportRequest) (*rpc.ExportResponse, error) {
+	var resp rpc.ExportResponse
+	err := c.post(ctx, "/export", req, &resp)
+	return &resp, err
+}
+
+func (c *Client) Snapshot(ctx context.Context, req rpc.SnapshotRequest) (*rpc.SnapshotResponse, error) {
+	var resp rpc.SnapshotResponse
+	err := c.post(ctx, "/snapshot", req, &resp)
+	return &resp, err
+}
+
+func (c *Client) Restore(ctx context.Context, req rpc.RestoreRequest) (*rpc.RestoreResponse, error) {
+	var resp rpc.RestoreResponse
+	err := c.post(ctx, "/restore", req, &resp)
+	return &resp, err
+}
+
+func (c *Client) Repack(ctx context.Context, req rpc.RepackRequest) (*rpc.RepackResponse, error) {
+	var resp rpc.RepackResponse
+	err := c.post(ctx, "/repack", req, &resp)
+	return &resp, err
+}
+
 func (c *Client) Status(ctx context.Context) (*rpc.StatusResponse, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", "http://unix/status", nil)
-	if err != nil {
-		return nil, err
+	var resp rpc.StatusResponse
+	path := "/status"
+	if c.namespace != "" {
+		path += "?namespace=" + url.QueryEscape(c.namespace)
 	}
-	httpResp, err := c.do(req)
-	if err != nil {
+	if err := c.get(ctx, path, &resp); err != nil {
 		return nil, fmt.Errorf("status request: %w", err)
 	}
-	defer httpResp.Body.Close()
+	return &resp, nil
+}
 
-	var resp rpc.StatusResponse
-	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
-		return nil, fmt.Errorf("decoding status: %w", err)
+// Stats reports per-crate storage use.
+func (c *Client) Stats(ctx context.Context) (*rpc.StatsResponse, error) {
+	var resp rpc.StatsResponse
+	if err := c.get(ctx, "/stats", &resp); err != nil {
+		return nil, fmt.Errorf("stats request: %w", err)
+	}
+	return &resp, nil
+}
+
+// Coverage reports how much of a crate is documented and embedded.
+func (c *Client) Coverage(ctx context.Context, req rpc.CoverageRequest) (*rpc.CoverageResponse, error) {
+	req.Namespace = c.scope(req.Namespace)
+	var resp rpc.CoverageResponse
+	if err := c.post(ctx, "/coverage", req, &resp); err != nil {
+		return nil, fmt.Errorf("coverage request: %w", err)
+	}
+	return &resp, nil
+}
+
+// Watch starts, or with req.Stop ends, keeping a project's dependencies
+// indexed.
+func (c *Client) Watch(ctx context.Context, req rpc.WatchRequest) (*rpc.WatchStatus, error) {
+	req.Namespace = c.scope(req.Namespace)
+	var resp rpc.WatchStatus
+	if err := c.post(ctx, "/watch", req, &resp); err != nil {
+		return nil, fmt.Errorf("watch request: %w", err)
+	}
+	return &resp, nil
+}
+
+// Health runs the daemon's dependency checks.
+func (c *Client) Health(ctx context.Context) (*rpc.HealthResponse, error) {
+	var resp rpc.HealthResponse
+	if err := c.get(ctx, "/health", &resp); err != nil {
+		return nil, fmt.Errorf("health request: %w", err)
+	}
+	return &resp, nil
+}
+
+// Capabilities reports which optional subsystems the daemon has.
+func (c *Client) Capabilities(ctx context.Context) (*rpc.CapabilitiesResponse, error) {
+	var resp rpc.CapabilitiesResponse
+	if err := c.get(ctx, "/capabilities", &resp); err != nil {
+		return nil, fmt.Errorf("capabilities request: %w", err)
+	}
+	return &resp, nil
+}
+
+// Namespaces lists the namespaces holding crates.
+func (c *Client) Namespaces(ctx context.Context) (*rpc.NamespacesResponse, error) {
+	var resp rpc.NamespacesResponse
+	if err := c.get(ctx, "/namespaces", &resp); err != nil {
+		return nil, fmt.Errorf("namespaces request: %w", err)
+	}
+	return &resp, nil
+}
+
+// DeleteNamespace removes a namespace's crates and the content only they
+// used.
+func (c *Client) DeleteNamespace(ctx context.Context, req rpc.DeleteNamespaceRequest) (*rpc.DeleteNamespaceResponse, error) {
+	var resp rpc.DeleteNamespaceResponse
+	if err := c.post(ctx, "/delete-namespace", req, &resp); err != nil {
+		return nil, fmt.Errorf("delete-namespace request: %w", err)
 	}
 	return &resp, nil
 }
@@ -183,18 +461,99 @@ func (c *Client) ClearCache(ctx context.Context) error {
 	return c.post(ctx, "/clear-cache", nil, &resp)
 }
 
+// ReloadConfig asks the daemon to re-read its config file.
+func (c *Client) ReloadConfig(ctx context.Context) (*rpc.ReloadConfigResponse, error) {
+	var resp rpc.ReloadConfigResponse
+	if err := c.post(ctx, "/reload-config", nil, &resp); err != nil {
+		return nil, fmt.Errorf("reload-config request: %w", err)
+	}
+	return &resp, nil
+}
+
+// Version asks the daemon for its protocol version. A daemon that predates
+// GET /version reports protocol 0.
+func (c *Client) Version(ctx context.Context) (*rpc.VersionResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.url("/version"), nil)
+	if err != nil {
+		return nil, err
+	}
+	// Not c.do: a missing route here says nothing about /v1 support.
+	resp, err := c.send(req)
+	if err != nil {
+		return nil, fmt.Errorf("version request: %w", err)
+	}
+	defer resp.Body.Close()
+	if isMissingRoute(resp) {
+		return &rpc.VersionResponse{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("daemon returned %d: %s", resp.StatusCode, string(body))
+	}
+	var v rpc.VersionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	return &v, nil
+}
+
+// EnsureProtocol restarts the daemon if it speaks an older protocol than
+// this client, as one left running across an upgrade does. A newer daemon
+// is left alone: it still serves everything this client asks for.
+func (c *Client) EnsureProtocol(ctx context.Context) error {
+	v, err := c.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if v.Protocol >= rpc.ProtocolVersion {
+		return nil
+	}
+	slog.Info("restarting outdated daemon", "protocol", v.Protocol, "want", rpc.ProtocolVersion)
+	// The daemon stops right after responding, so errors here are expected.
+	c.Shutdown(ctx)
+	if err := WaitStopped(c.socketPath, DrainTimeout+10*time.Second); err != nil {
+		return fmt.Errorf("restarting outdated daemon: %w", err)
+	}
+	c.legacy.Store(false)
+	if err := c.respawn(); err != nil {
+		return fmt.Errorf("restarting outdated daemon: %w", err)
+	}
+	return nil
+}
+
 func (c *Client) Shutdown(ctx context.Context) error {
 	var resp map[string]string
 	return c.post(ctx, "/shutdown", nil, &resp)
 }
 
+func (c *Client) get(ctx context.Context, path string, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.url(path), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("daemon returned %d: %s", resp.StatusCode, string(body))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
 func (c *Client) post(ctx context.Context, path string, body, result interface{}) error {
 	jsonData, err := json.Marshal(body)
 	if err != nil {
 		return fmt.Errorf("marshaling request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "http://unix"+path, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.url(path), bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("creating request: %w", err)
 	}
@@ -0,0 +1,81 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+)
+
+// droppingDaemon listens on a unix socket and hangs up on the first request
+// after reading it, as a daemon stopping mid-request would. Later requests
+// get body as a JSON response. It returns a client for it and the number of
+// requests it has read.
+func droppingDaemon(t *testing.T, body string) (*Client, *atomic.Int32) {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "daemon.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	var requests atomic.Int32
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					req, err := http.ReadRequest(r)
+					if err != nil {
+						return
+					}
+					io.Copy(io.Discard, req.Body)
+					if requests.Add(1) == 1 {
+						return
+					}
+					fmt.Fprintf(conn, "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: %d\r\n\r\n%s", len(body), body)
+				}
+			}()
+		}
+	}()
+	return NewClient(socket), &requests
+}
+
+func TestClient_RetriesIdempotentRouteOnLostConnection(t *testing.T) {
+	t.Parallel()
+	client, requests := droppingDaemon(t, `{"results":[]}`)
+
+	if _, err := client.Search(context.Background(), rpc.SearchRequest{Query: "spawn"}); err != nil {
+		t.Fatalf("Search after a dropped connection: %v", err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("daemon read %d requests, want 2", n)
+	}
+}
+
+func TestClient_ReportsLostConnectionOnOtherRoutes(t *testing.T) {
+	t.Parallel()
+	client, requests := droppingDaemon(t, `{"status":"shutting down"}`)
+
+	err := client.Shutdown(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "may or may not have completed") {
+		t.Fatalf("Shutdown after a dropped connection = %v, want the may-or-may-not error", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("daemon read %d requests, want 1", n)
+	}
+}
@@ -0,0 +1,299 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+)
+
+const defaultContextBudget = 8000
+
+const contextSeparator = "\n---\n\n"
+
+// contextEntry is one document in a context bundle, split by priority:
+// head (title, kind, signature) first, then the summary paragraph, then the
+// rest of the docs.
+type contextEntry struct {
+	uri     string
+	head    string
+	summary string
+	rest    string
+
+	level     int // 0 omitted, 1 head, 2 head+summary, 3 complete
+	restPart  string
+	truncated bool
+}
+
+// estimateTokens approximates the token count of text at ~4 bytes per token.
+func estimateTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+func (s *Server) handleBuildContext(w http.ResponseWriter, r *http.Request) {
+	var req rpc.BuildContextRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(req.URIs) == 0 {
+		writeError(w, http.StatusBadRequest, "missing uris")
+		return
+	}
+	if req.TokenBudget <= 0 {
+		req.TokenBudget = defaultContextBudget
+	}
+	ctx, ok := scoped(w, r, req.Namespace)
+	if !ok {
+		return
+	}
+	resp := s.buildContext(ctx, req.URIs, req.TokenBudget)
+	writeMarkdown(w, r, resp, resp.Markdown)
+}
+
+// defaultContextResults is how many search hits /context bundles by default.
+const defaultContextResults = 8
+
+// handleContext answers a question-style query with a context bundle: it
+// searches, then bundles the hits (and their grouped children) best first.
+func (s *Server) handleContext(w http.ResponseWriter, r *http.Request) {
+	var req rpc.ContextRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Query == "" {
+		writeError(w, http.StatusBadRequest, "missing query")
+		return
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultContextResults
+	}
+	if req.TokenBudget <= 0 {
+		req.TokenBudget = defaultContextBudget
+	}
+	ctx, ok := scoped(w, r, req.Namespace)
+	if !ok {
+		return
+	}
+
+	search := rpc.SearchRequest{
+		Query:            req.Query,
+		Crates:           req.Crates,
+		Features:         req.Features,
+		WithDependencies: req.WithDependencies,
+		Limit:            req.Limit,
+		Namespace:        req.Namespace,
+	}
+	s.prepareSearch(ctx, &search)
+	results, _, err := s.live().searcher.Search(search)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, s.withProviderState(err))
+		return
+	}
+
+	var uris []string
+	for _, r := range results {
+		uris = append(uris, r.URI)
+		for _, c := range r.Children {
+			uris = append(uris, c.URI)
+		}
+	}
+	resp := rpc.ContextResponse{Sources: results}
+	if len(uris) > 0 {
+		resp.BuildContextResponse = s.buildContext(ctx, uris, req.TokenBudget)
+	} else {
+		resp.Suggestions = s.suggestCrates(ctx, req.Query)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// buildContext bundles uris, in priority order, into budget tokens of
+// markdown.
+func (s *Server) buildContext(ctx context.Context, uris []string, budget int) rpc.BuildContextResponse {
+	var resp rpc.BuildContextResponse
+	var entries []*contextEntry
+	seen := make(map[string]bool)
+	seenDocs := make(map[string]string)
+	for _, uri := range uris {
+		e, key, err := s.contextEntry(ctx, uri, seenDocs)
+		if err != nil {
+			slog.Warn("build-context: skipping uri", "uri", uri, "error", err)
+			resp.Omitted = append(resp.Omitted, uri)
+			continue
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		entries = append(entries, e)
+	}
+
+	resp.Markdown = packContext(entries, budget)
+	resp.Tokens = estimateTokens(resp.Markdown)
+	for _, e := range entries {
+		switch {
+		case e.level == 0:
+			resp.Omitted = append(resp.Omitted, e.uri)
+		case e.truncated:
+			resp.Truncated = append(resp.Truncated, e.uri)
+		}
+	}
+	return resp
+}
+
+// contextEntry resolves a URI into a bundle entry. The returned key
+// identifies the resolved document so duplicates (including re-exports of
+// the same item) are bundled once. seenDocs maps content hashes already
+// bundled to their URI so identical docs aren't repeated.
+func (s *Server) contextEntry(ctx context.Context, uri string, seenDocs map[string]string) (*contextEntry, string, error) {
+	req, _, err := rpc.ParseDocURI(uri)
+	if err != nil {
+		return nil, "", err
+	}
+	d, err := s.resolveDoc(ctx, req)
+	if err != nil {
+		return nil, "", err
+	}
+	canonical := fmt.Sprintf("rsdoc://%s/%s/%s", d.req.Crate, d.crate.Version, d.req.Path)
+	if d.req.Fragment != "" {
+		canonical += "#" + d.req.Fragment
+	}
+	e := &contextEntry{uri: uri}
+
+	if d.req.Fragment != "" {
+		text, err := s.renderFragment(d)
+		if err != nil {
+			return nil, "", err
+		}
+		e.head = fmt.Sprintf("<!-- %s -->\n", canonical)
+		e.summary, e.rest = splitSummary(text)
+		return e, canonical, nil
+	}
+
+	e.head = fmt.Sprintf("<!-- %s -->\n", canonical) + itemHeader(d.item)
+	if hash := d.item.ContentHash; hash != "" {
+		if prev, ok := seenDocs[hash]; ok {
+			e.summary = fmt.Sprintf("Docs are the same as %s.\n", prev)
+			return e, canonical, nil
+		}
+		seenDocs[hash] = canonical
+	}
+	e.summary, e.rest = splitSummary(itemDocs(d.item))
+	return e, canonical, nil
+}
+
+// splitSummary splits markdown into its first paragraph and the remainder.
+// Blank lines inside code fences don't end the paragraph.
+func splitSummary(text string) (summary, rest string) {
+	text = strings.TrimSpace(text)
+	lines := strings.Split(text, "\n")
+	inCode := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inCode = !inCode
+			continue
+		}
+		if !inCode && trimmed == "" && i > 0 {
+			return strings.Join(lines[:i], "\n") + "\n", strings.TrimSpace(strings.Join(lines[i+1:], "\n"))
+		}
+	}
+	return text + "\n", ""
+}
+
+// packContext fits entries into budget tokens. Every entry gets its head and
+// summary before any entry gets its full docs, so a tight budget keeps broad
+// coverage instead of spending everything on the first document.
+func packContext(entries []*contextEntry, budget int) string {
+	used := 0
+	fits := func(s string) bool {
+		return used+estimateTokens(s+contextSeparator) <= budget
+	}
+	take := func(s string) {
+		used += estimateTokens(s + contextSeparator)
+	}
+
+	for _, e := range entries {
+		if !fits(e.head) {
+			continue
+		}
+		take(e.head)
+		e.level = 1
+	}
+	for _, e := range entries {
+		if e.level == 0 || e.summary == "" {
+			continue
+		}
+		if !fits(e.summary) {
+			e.truncated = true
+			continue
+		}
+		take(e.summary)
+		e.level = 2
+	}
+	for _, e := range entries {
+		if e.level < 2 || e.rest == "" {
+			continue
+		}
+		if fits(e.rest) {
+			take(e.rest)
+			e.restPart = e.rest
+			e.level = 3
+			continue
+		}
+		e.truncated = true
+		if part := truncateParagraphs(e.rest, (budget-used)*4); part != "" {
+			take(part)
+			e.restPart = part
+		}
+	}
+
+	var parts []string
+	for _, e := range entries {
+		if e.level == 0 {
+			continue
+		}
+		var b strings.Builder
+		b.WriteString(e.head)
+		if e.level >= 2 {
+			b.WriteString("\n")
+			b.WriteString(e.summary)
+		}
+		if e.restPart != "" {
+			b.WriteString("\n")
+			b.WriteString(e.restPart)
+			b.WriteString("\n")
+		}
+		if e.truncated {
+			b.WriteString("\n*(truncated)*\n")
+		}
+		parts = append(parts, b.String())
+	}
+	return strings.Join(parts, contextSeparator)
+}
+
+// truncateParagraphs returns the longest prefix of whole paragraphs of text
+// that fits in maxBytes, leaving room for the truncation marker.
+func truncateParagraphs(text string, maxBytes int) string {
+	maxBytes -= len("\n*(truncated)*\n") + len(contextSeparator)
+	if maxBytes <= 0 {
+		return ""
+	}
+	var out string
+	for _, para := range strings.SplitAfter(text, "\n\n") {
+		if len(out)+len(para) > maxBytes {
+			break
+		}
+		out += para
+	}
+	out = strings.TrimSpace(out)
+	if strings.Count(out, "```")%2 == 1 {
+		out += "\n```"
+	}
+	return out
+}
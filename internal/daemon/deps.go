@@ -0,0 +1,227 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/jcdickinson/ferrisfetch/internal/cas"
+	"github.com/jcdickinson/ferrisfetch/internal/db"
+	"github.com/jcdickinson/ferrisfetch/internal/docs"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+)
+
+// handleDeps lists a crate's direct dependencies and the indexed crates that
+// depend on it.
+func (s *Server) handleDeps(w http.ResponseWriter, r *http.Request) {
+	var req rpc.DepsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Crate == "" {
+		writeError(w, http.StatusBadRequest, "missing crate")
+		return
+	}
+	ctx, ok := scoped(w, r, req.Namespace)
+	if !ok {
+		return
+	}
+
+	crate, err := s.resolveOrFetchCrate(ctx, req.Crate, req.Version)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if crate == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("crate %s not found", req.Crate))
+		return
+	}
+
+	deps, err := s.crateDependencies(ctx, crate)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	names := make([]string, len(deps))
+	for i, d := range deps {
+		names[i] = d.Name
+	}
+	indexed, err := s.index(ctx).GetIndexedVersions(names)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := rpc.DepsResponse{Crate: crate.Name, Version: crate.Version, Dependencies: make([]rpc.Dependency, len(deps))}
+	for i, d := range deps {
+		resp.Dependencies[i] = rpc.Dependency{
+			Name:           d.Name,
+			Req:            d.Req,
+			Version:        d.Version,
+			Kind:           d.Kind,
+			Optional:       d.Optional,
+			IndexedVersion: indexed[d.Name],
+		}
+	}
+
+	dependents, err := s.index(ctx).ListDependents(crate.Name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for _, c := range dependents {
+		resp.Dependents = append(resp.Dependents, c.Name+"@"+c.Version)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// crateDependencies returns the stored dependencies of a crate, fetching them
+// first for crates indexed before dependencies were recorded.
+func (s *Server) crateDependencies(ctx context.Context, crate *db.Crate) ([]db.Dependency, error) {
+	deps, err := s.db.ListDependencies(crate.ID)
+	if err != nil || len(deps) > 0 || crate.Source == db.SourceFile {
+		return deps, err
+	}
+	reg, err := docs.LookupRegistry(crate.Registry)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.recordDependencies(ctx, reg, crate, s.getCachedCrate(crate.Registry, crate.Name, crate.Version)); err != nil {
+		return nil, err
+	}
+	return s.db.ListDependencies(crate.ID)
+}
+
+// recordDependencies fetches a crate's dependencies from its registry and
+// stores them. The rustdoc JSON, when available, supplies the versions docs.rs
+// actually built against.
+func (s *Server) recordDependencies(ctx context.Context, reg *docs.Registry, crate *db.Crate, rustdocCrate *docs.RustdocCrate) error {
+	fetched, err := docs.FetchDependencies(ctx, reg, crate.Name, crate.Version)
+	if err != nil {
+		return err
+	}
+	var resolved map[string]string
+	if rustdocCrate != nil {
+		resolved = rustdocCrate.ExternalCrateVersions()
+	}
+	deps := make([]db.Dependency, len(fetched))
+	for i, d := range fetched {
+		deps[i] = db.Dependency{Name: d.Name, Req: d.Req, Version: resolved[d.Name], Kind: d.Kind, Optional: d.Optional}
+	}
+	return s.db.ReplaceDependencies(crate.ID, deps)
+}
+
+// recordCrateInfo fetches a crate's download counts, newest version and
+// description. Search uses the first two to favor widely used crates and
+// current versions; the description is returned for embedding, so searches
+// can find the crate itself, or nil when the registry has none.
+func (s *Server) recordCrateInfo(ctx context.Context, reg *docs.Registry, crate *db.Crate) (*embeddable, error) {
+	info, err := docs.FetchCrateInfo(ctx, reg, crate.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.SetCrateInfo(db.CrateInfo{
+		Registry:        reg.Name,
+		Name:            crate.Name,
+		Downloads:       info.Downloads,
+		RecentDownloads: info.RecentDownloads,
+		LatestVersion:   info.LatestVersion,
+	}); err != nil {
+		return nil, err
+	}
+
+	meta := db.CrateMeta{
+		CrateID:     crate.ID,
+		Description: info.Description,
+		Keywords:    info.Keywords,
+		Categories:  info.Categories,
+		Repository:  info.Repository,
+	}
+	if summary := info.Summary(); summary != "" {
+		if meta.ContentHash, err = cas.Write(summary); err != nil {
+			return nil, fmt.Errorf("writing crate summary to CAS: %w", err)
+		}
+	}
+	if err := s.db.SetCrateMeta(meta); err != nil {
+		return nil, err
+	}
+	if meta.ContentHash == "" {
+		return nil, nil
+	}
+	return &embeddable{contentHash: meta.ContentHash, preamble: crate.Name + " crate"}, nil
+}
+
+// withDependencies adds the indexed direct (non-dev) dependencies of the
+// crates matched by specs. A dependency is pinned to the version docs.rs
+// built against when that version is indexed.
+func (s *Server) withDependencies(ctx context.Context, specs []string) []string {
+	seen := make(map[string]bool, len(specs))
+	out := append([]string(nil), specs...)
+	for _, spec := range specs {
+		seen[spec] = true
+	}
+
+	for _, spec := range specs {
+		name, version, _ := strings.Cut(spec, "@")
+		var crate *db.Crate
+		var err error
+		if version == "" || version == "latest" {
+			crate, err = s.index(ctx).GetLatestCrate(name)
+		} else {
+			crate, err = s.index(ctx).GetCrate(name, version)
+		}
+		if err != nil || crate == nil {
+			continue
+		}
+
+		deps, err := s.crateDependencies(ctx, crate)
+		if err != nil {
+			slog.Warn("failed to load dependencies", "crate", spec, "error", err)
+			continue
+		}
+		for _, d := range deps {
+			if d.Kind == "dev" {
+				continue
+			}
+			dep := d.Name
+			if d.Version != "" {
+				if c, err := s.index(ctx).GetCrate(d.Name, d.Version); err == nil && c != nil {
+					dep = d.Name + "@" + d.Version
+				}
+			}
+			if !seen[dep] {
+				seen[dep] = true
+				out = append(out, dep)
+			}
+		}
+	}
+	return out
+}
+
+// dependencySpecs lists the dependencies of an added crate whose items its
+// docs can link to: normal dependencies, with optional ones only when docs.rs
+// built with them. Each is pinned to the version docs.rs built against when
+// known.
+func (s *Server) dependencySpecs(ctx context.Context, added rpc.CrateResult, registry string) []rpc.CrateSpec {
+	crate, err := s.index(ctx).GetCrate(added.Name, added.Version)
+	if err != nil || crate == nil {
+		return nil
+	}
+	deps, err := s.crateDependencies(ctx, crate)
+	if err != nil {
+		slog.Warn("failed to load dependencies", "crate", added.Name, "version", added.Version, "error", err)
+		return nil
+	}
+	var specs []rpc.CrateSpec
+	for _, d := range deps {
+		if d.Kind != "normal" || (d.Optional && d.Version == "") {
+			continue
+		}
+		specs = append(specs, rpc.CrateSpec{Name: d.Name, Version: d.Version, Registry: registry})
+	}
+	return specs
+}
@@ -0,0 +1,112 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/jcdickinson/ferrisfetch/internal/db"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+)
+
+func (s *Server) handleDiff(w http.ResponseWriter, r *http.Request) {
+	var req rpc.DiffRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Crate == "" || req.From == "" || req.To == "" {
+		writeError(w, http.StatusBadRequest, "missing crate, from or to")
+		return
+	}
+	ctx, ok := scoped(w, r, req.Namespace)
+	if !ok {
+		return
+	}
+
+	from, err := s.resolveOrFetchCrate(ctx, req.Crate, req.From)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	to, err := s.resolveOrFetchCrate(ctx, req.Crate, req.To)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if from == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("crate %s@%s not found", req.Crate, req.From))
+		return
+	}
+	if to == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("crate %s@%s not found", req.Crate, req.To))
+		return
+	}
+
+	oldItems, err := s.db.ListItems(from.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	newItems, err := s.db.ListItems(to.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := diffItems(oldItems, newItems, req.Crate, from.Version, to.Version)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// diffItems compares two versions' items by path. Items whose kind or
+// signature differ are reported as changed.
+func diffItems(oldItems, newItems []db.Item, crate, fromVersion, toVersion string) rpc.DiffResponse {
+	resp := rpc.DiffResponse{Crate: crate, From: fromVersion, To: toVersion}
+	uri := func(version, path string) string {
+		return fmt.Sprintf("rsdoc://%s/%s/%s", crate, version, path)
+	}
+
+	oldByPath := make(map[string]db.Item, len(oldItems))
+	for _, it := range oldItems {
+		if _, ok := oldByPath[it.Path]; !ok {
+			oldByPath[it.Path] = it
+		}
+	}
+	newByPath := make(map[string]bool, len(newItems))
+
+	for _, it := range newItems {
+		if newByPath[it.Path] {
+			continue
+		}
+		newByPath[it.Path] = true
+
+		old, ok := oldByPath[it.Path]
+		if !ok {
+			resp.Added = append(resp.Added, rpc.DiffItem{URI: uri(toVersion, it.Path), Path: it.Path, Kind: it.Kind, Signature: it.Signature})
+			continue
+		}
+		if old.Signature == it.Signature && old.Kind == it.Kind {
+			continue
+		}
+		change := rpc.DiffChange{
+			URI:          uri(toVersion, it.Path),
+			Path:         it.Path,
+			Kind:         it.Kind,
+			OldSignature: old.Signature,
+			NewSignature: it.Signature,
+		}
+		if old.Kind != it.Kind {
+			change.OldKind = old.Kind
+		}
+		resp.Changed = append(resp.Changed, change)
+	}
+
+	for _, it := range oldItems {
+		if newByPath[it.Path] {
+			continue
+		}
+		newByPath[it.Path] = true // report duplicates once
+		resp.Removed = append(resp.Removed, rpc.DiffItem{URI: uri(fromVersion, it.Path), Path: it.Path, Kind: it.Kind, Signature: it.Signature})
+	}
+	return resp
+}
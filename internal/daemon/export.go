@@ -0,0 +1,70 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jcdickinson/ferrisfetch/internal/bundle"
+	"github.com/jcdickinson/ferrisfetch/internal/config"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+)
+
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	var req rpc.ExportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !filepath.IsAbs(req.Path) {
+		writeError(w, http.StatusBadRequest, "export path must be absolute")
+		return
+	}
+
+	resp, err := s.export(req.Path)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// export writes a snapshot of the database and HNSW index, together with the
+// live CAS and JSON cache, to an archive at path.
+func (s *Server) export(path string) (*rpc.ExportResponse, error) {
+	tmp, err := os.MkdirTemp(filepath.Dir(config.DBPath()), "export-")
+	if err != nil {
+		return nil, fmt.Errorf("creating staging directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	snapshot := filepath.Join(tmp, "db.db")
+	s.writes.Lock()
+	err = s.db.Snapshot(snapshot)
+	s.writes.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, err
+	}
+	files, err := bundle.Write(f, bundle.Manifest{CreatedAt: time.Now().UTC(), Model: s.embeddingModel()}, bundle.Entries(snapshot))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	resp := &rpc.ExportResponse{Path: path, Files: files}
+	if fi, err := os.Stat(path); err == nil {
+		resp.Bytes = fi.Size()
+	}
+	return resp, nil
+}
@@ -0,0 +1,116 @@
+package daemon
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jcdickinson/ferrisfetch/internal/cas"
+	"github.com/jcdickinson/ferrisfetch/internal/embeddings"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+)
+
+// healthProbeInterval is how recent a provider request must be for
+// /health to trust it instead of probing again.
+const healthProbeInterval = time.Minute
+
+// handleHealth checks the database, vector index, content store and
+// embedding provider.
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	resp := rpc.HealthResponse{StartedAt: s.startedAt}
+	check := func(name, status, detail, fix string) {
+		resp.Checks = append(resp.Checks, rpc.HealthCheck{Name: name, Status: status, Detail: detail, Fix: fix})
+	}
+
+	if err := s.db.Ping(); err != nil {
+		check("database", rpc.HealthFail, err.Error(), "run `rsdoc stop` and check `rsdoc logs`; restore a snapshot if the file is damaged")
+	} else {
+		check("database", rpc.HealthOK, "", "")
+		if crates, err := s.db.ListAllCrates(); err == nil {
+			resp.Crates = len(crates)
+		}
+		if counts, err := s.db.CountItemsByCrate(); err == nil {
+			for _, n := range counts {
+				resp.Items += n
+			}
+		}
+	}
+
+	resp.IndexNodes = s.db.HNSWSize()
+	if n, err := s.db.CountEmbeddings(); err != nil {
+		check("vector index", rpc.HealthFail, err.Error(), "check `rsdoc logs`")
+	} else if resp.Embeddings = n; n != resp.IndexNodes {
+		check("vector index", rpc.HealthWarn,
+			fmt.Sprintf("%d embeddings stored but %d in the index", n, resp.IndexNodes),
+			"run `rsdoc verify --repair`")
+	} else {
+		check("vector index", rpc.HealthOK, "", "")
+	}
+
+	if err := cas.CheckWritable(); err != nil {
+		check("content store", rpc.HealthFail, err.Error(), fmt.Sprintf("make %s writable", cas.Dir()))
+	} else {
+		check("content store", rpc.HealthOK, "", "")
+	}
+
+	s.checkProvider(check)
+
+	if m := s.modelStatus(); m != nil && m.Model != "" && m.Configured != "" {
+		check("embedding model", rpc.HealthWarn,
+			fmt.Sprintf("index embedded with %s but voyage_ai.model is %s", m.Model, m.Configured),
+			"run `rsdoc reembed --yes`")
+	}
+
+	if n, err := s.db.CountStaleEmbeddings(s.embeddingModel(), s.chunkOptions().Version()); err == nil && n > 0 {
+		check("embedding versions", rpc.HealthWarn,
+			fmt.Sprintf("%d embeddings were chunked or embedded differently than this build would", n),
+			"run `rsdoc reindex --changed-only`")
+	}
+
+	resp.OK = true
+	for _, c := range resp.Checks {
+		if c.Status == rpc.HealthFail {
+			resp.OK = false
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// checkProvider reports the embedding provider's state, probing it first
+// unless a request reached it within healthProbeInterval.
+func (s *Server) checkProvider(check func(name, status, detail, fix string)) {
+	const name = "embedding provider"
+	live := s.live()
+	if live.cfg.VoyageAI.Fake() {
+		check(name, rpc.HealthOK, "fake pseudo-embeddings (voyage_ai.provider)", "")
+		return
+	}
+	if live.cfg.VoyageAI.ApiKey.Value == "" {
+		check(name, rpc.HealthFail, "no API key configured",
+			"set voyage_ai.api_key in ~/.config/ferrisfetch/config.toml or FERRISFETCH_VOYAGE_AI_API_KEY")
+		return
+	}
+	if s.vcr {
+		check(name, rpc.HealthOK, "not probed in VCR mode", "")
+		return
+	}
+
+	s.probeMu.Lock()
+	if time.Since(live.voyage.Health().LastRequestAt) >= healthProbeInterval {
+		live.voyage.Check(s.embeddingModel())
+	}
+	s.probeMu.Unlock()
+
+	h := live.voyage.Health()
+	switch h.State {
+	case embeddings.ProviderUnauthorized:
+		check(name, rpc.HealthFail, "API key rejected", "check voyage_ai.api_key")
+	case embeddings.ProviderOverQuota:
+		check(name, rpc.HealthWarn, fmt.Sprintf("%d rate-limited requests in the last 15m", h.RateLimited),
+			"wait, raise your Voyage AI limits, or set voyage_ai.requests_per_minute and tokens_per_minute to pace requests")
+	case embeddings.ProviderDegraded:
+		check(name, rpc.HealthWarn, h.LastError, "check network access to api.voyageai.com")
+	default:
+		check(name, rpc.HealthOK, "", "")
+	}
+}
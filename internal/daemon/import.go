@@ -0,0 +1,43 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jcdickinson/ferrisfetch/internal/db"
+	"github.com/jcdickinson/ferrisfetch/internal/docs"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+)
+
+// importCrate indexes user-provided rustdoc JSON, for crates that aren't on
+// docs.rs. Imports always re-index: the same version may have been rebuilt.
+func (s *Server) importCrate(ctx context.Context, reg *docs.Registry, spec rpc.CrateSpec, progress func(string)) rpc.CrateResult {
+	result := rpc.CrateResult{Name: spec.Name, Version: spec.Version}
+
+	data, err := docs.DecodeRustdocJSON(spec.RustdocJSON)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	name, version, err := docs.CrateIdentity(data)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if spec.Name != "" {
+		name = spec.Name
+	}
+	if spec.Version != "" && spec.Version != "latest" {
+		version = spec.Version
+	}
+	result.Name, result.Version = name, version
+	if version == "" {
+		result.Error = fmt.Sprintf("rustdoc JSON for %s has no crate version; give one as %s@<version>", name, name)
+		return result
+	}
+
+	progress(fmt.Sprintf("importing rustdoc for %s@%s", name, version))
+	return s.addOnce(ctx, registryName(reg.Name, name)+"@"+version, func(ctx context.Context) rpc.CrateResult {
+		return s.addCrateWork(ctx, reg, name, version, addOptions{force: true, overBudget: spec.OverBudget, data: data, dataSource: db.SourceFile, ignore: spec.Ignore}, progress)
+	})
+}
@@ -0,0 +1,137 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// StartInProcess runs the daemon without a socket and returns a client
+// that calls its handlers directly, for processes such as
+// "rsdoc mcp --standalone" that would otherwise spawn a daemon and talk to
+// it over the socket. It holds the daemon lock like Start, so a socket
+// daemon can't open the same index, but never expires: it runs until ctx
+// ends or Stop is called.
+func (s *Server) StartInProcess(ctx context.Context) (*Client, error) {
+	if err := os.MkdirAll(filepath.Dir(s.socketPath), 0755); err != nil {
+		return nil, fmt.Errorf("creating socket directory: %w", err)
+	}
+	lock, err := acquireLock(s.socketPath, cancelGrace)
+	if err != nil {
+		return nil, err
+	}
+	s.lock = lock
+	if err := s.checkEmbeddingModel(); err != nil {
+		lock.Close()
+		return nil, err
+	}
+
+	handler := s.withWorkCtx(s.routes())
+	context.AfterFunc(ctx, func() {
+		slog.Info("stopping", "reason", context.Cause(ctx))
+		s.stopAsync()
+	})
+	slog.Info("daemon running in-process")
+	s.startBackground(ctx)
+
+	return &Client{
+		httpClient: &http.Client{
+			Transport: handlerTransport{handler},
+			Timeout:   5 * time.Minute,
+		},
+	}, nil
+}
+
+// withWorkCtx cancels requests along with the daemon's work, as
+// http.Server's BaseContext does for requests over the socket.
+func (s *Server) withWorkCtx(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancelCause(r.Context())
+		defer cancel(nil)
+		stop := context.AfterFunc(s.workCtx, func() { cancel(context.Cause(s.workCtx)) })
+		defer stop()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// handlerTransport answers requests by calling handler in a goroutine. The
+// response is returned as soon as the handler writes its header, and its
+// body streams through a pipe, so NDJSON progress arrives as it's written.
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	if r.Body == nil {
+		r.Body = http.NoBody
+	}
+	r.RequestURI = req.URL.RequestURI()
+
+	pr, pw := io.Pipe()
+	w := &pipeResponseWriter{
+		header: http.Header{},
+		body:   pw,
+		ready:  make(chan struct{}),
+		resp: &http.Response{
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Body:       pr,
+			Request:    req,
+		},
+	}
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				slog.Error("handler panicked", "path", r.URL.Path, "panic", p)
+				w.WriteHeader(http.StatusInternalServerError)
+				pw.CloseWithError(fmt.Errorf("handler panicked: %v", p))
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			pw.Close()
+		}()
+		t.handler.ServeHTTP(w, r)
+	}()
+	<-w.ready
+	return w.resp, nil
+}
+
+// pipeResponseWriter writes a response's body into a pipe, publishing the
+// response when the header is written.
+type pipeResponseWriter struct {
+	header http.Header
+	body   *io.PipeWriter
+	resp   *http.Response
+	once   sync.Once
+	ready  chan struct{}
+}
+
+func (w *pipeResponseWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader publishes the response; only the first call has any effect.
+func (w *pipeResponseWriter) WriteHeader(status int) {
+	w.once.Do(func() {
+		w.resp.StatusCode = status
+		w.resp.Status = fmt.Sprintf("%d %s", status, http.StatusText(status))
+		w.resp.Header = w.header.Clone()
+		close(w.ready)
+	})
+}
+
+func (w *pipeResponseWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(p)
+}
+
+// Flush does nothing: writes to the pipe already block until they're read.
+func (w *pipeResponseWriter) Flush() {}
@@ -0,0 +1,218 @@
+//go:build integration
+
+package daemon_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jcdickinson/ferrisfetch/internal/config"
+	"github.com/jcdickinson/ferrisfetch/internal/daemon"
+	"github.com/jcdickinson/ferrisfetch/internal/db"
+	"github.com/jcdickinson/ferrisfetch/internal/docs/docstest"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+	"github.com/jcdickinson/ferrisfetch/internal/vcr"
+)
+
+// The pipeline tests index one small crate from the docs.rs and crates.io
+// responses docstest shares and the embeddings recorded in testdata/vcr.
+// Record them again with
+//
+//	RSDOC_RECORD=1 FERRISFETCH_VOYAGE_AI_API_KEY=... go test -tags integration ./internal/daemon
+const (
+	testCrate   = "itoa"
+	testVersion = "1.0.11"
+)
+
+// startDaemon runs a daemon in-process with a fresh cache and database,
+// talking to the network only through recorded fixtures.
+func startDaemon(t *testing.T) *daemon.Client {
+	t.Helper()
+	return startServer(t, testConfig(t), filepath.Join(t.TempDir(), "db.db"))
+}
+
+// testConfig points the daemon's directories at temporary ones and loads
+// its config from the environment.
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if docstest.Mode() == vcr.ModeReplay {
+		cfg.Crawl.RequestsPerSecond = 0
+	} else if cfg.VoyageAI.ApiKey.Value == "" && !cfg.VoyageAI.Fake() {
+		t.Fatal("recording needs FERRISFETCH_VOYAGE_AI_API_KEY")
+	}
+	return cfg
+}
+
+// startServer runs a daemon with cfg over the database at dbPath until t
+// ends.
+func startServer(t *testing.T, cfg *config.Config, dbPath string) *daemon.Client {
+	t.Helper()
+	fixtures, err := filepath.Abs(filepath.Join("testdata", "vcr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	database, err := db.NewWithOptions(dbPath, db.Options{Storage: cfg.Index.Storage, Quantization: cfg.Index.Quantization})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := daemon.NewServer(cfg, database, config.SocketPath())
+	srv.SetConfigLoader(func() (*config.Config, error) { return cfg, nil })
+	srv.SetHTTPTransport(docstest.Transport(fixtures, nil))
+	client, err := srv.StartInProcess(t.Context())
+	if err != nil {
+		database.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), daemon.DrainTimeout)
+		defer cancel()
+		if err := srv.Stop(ctx); err != nil {
+			t.Errorf("stopping daemon: %v", err)
+		}
+	})
+	return client
+}
+
+func TestPipeline_AddSearchGet(t *testing.T) {
+	client := startDaemon(t)
+	ctx := t.Context()
+
+	added, err := client.AddCrates(ctx, rpc.AddCratesRequest{
+		Crates: []rpc.CrateSpec{{Name: testCrate, Version: testVersion}},
+	}, func(msg string) { t.Log(msg) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(added.Results) != 1 || added.Results[0].Error != "" {
+		t.Fatalf("add: %+v", added.Results)
+	}
+	if got := added.Results[0].Version; got != testVersion {
+		t.Errorf("added version %q, want %q", got, testVersion)
+	}
+
+	found, err := client.Search(ctx, rpc.SearchRequest{
+		Query:  "format an integer into a string",
+		Crates: []string{testCrate},
+		Limit:  5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := make([]string, len(found.Results))
+	for i, r := range found.Results {
+		paths[i] = r.Path
+	}
+	if !strings.Contains(strings.Join(paths, " "), "itoa::Buffer") {
+		t.Errorf("search results %v don't include itoa::Buffer", paths)
+	}
+
+	doc, err := client.GetDoc(ctx, rpc.GetDocRequest{Crate: testCrate, Version: testVersion, Path: "itoa::Buffer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(doc.Markdown, "Buffer") || !strings.Contains(doc.Markdown, "format") {
+		t.Errorf("itoa::Buffer docs:\n%s", doc.Markdown)
+	}
+
+	// Indexing again finds everything already embedded, so it sends no
+	// requests that weren't recorded.
+	again, err := client.AddCrates(ctx, rpc.AddCratesRequest{
+		Crates: []rpc.CrateSpec{{Name: testCrate, Version: testVersion, Force: true}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Results[0].Error != "" {
+		t.Errorf("re-add: %s", again.Results[0].Error)
+	}
+}
+
+// TestPipeline_FakeProvider indexes and searches with the fake embedding
+// provider, which needs neither a key nor recorded Voyage responses.
+func TestPipeline_FakeProvider(t *testing.T) {
+	t.Setenv("FERRISFETCH_VOYAGE_AI_PROVIDER", "fake")
+	client := startDaemon(t)
+	ctx := t.Context()
+
+	caps, err := client.Capabilities(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caps.Embeddings != "fake" || caps.EmbeddingModel != "fake" {
+		t.Errorf("capabilities: embeddings %q, model %q", caps.Embeddings, caps.EmbeddingModel)
+	}
+
+	added, err := client.AddCrates(ctx, rpc.AddCratesRequest{
+		Crates: []rpc.CrateSpec{{Name: testCrate, Version: testVersion}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added.Results[0].Error != "" {
+		t.Fatalf("add: %s", added.Results[0].Error)
+	}
+
+	found, err := client.Search(ctx, rpc.SearchRequest{Query: "Buffer format integer", Crates: []string{testCrate}, Limit: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found.Results) == 0 || !strings.HasPrefix(found.Results[0].Path, "itoa::Buffer") {
+		t.Errorf("search results %+v, want itoa::Buffer first", found.Results)
+	}
+}
+
+func TestPipeline_RefusedAddKeepsStaleEmbeddings(t *testing.T) {
+	t.Setenv("FERRISFETCH_VOYAGE_AI_PROVIDER", "fake")
+	cfg := testConfig(t)
+	dbPath := filepath.Join(t.TempDir(), "db.db")
+
+	t.Run("index", func(t *testing.T) {
+		client := startServer(t, cfg, dbPath)
+		added, err := client.AddCrates(t.Context(), rpc.AddCratesRequest{
+			Crates: []rpc.CrateSpec{{Name: testCrate, Version: testVersion}},
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if added.Results[0].Error != "" {
+			t.Fatalf("add: %s", added.Results[0].Error)
+		}
+	})
+
+	// Another chunker version makes every stored embedding stale, and the
+	// per-crate cap refuses embedding them again.
+	stale := *cfg
+	stale.Index.ChunkMaxTokens = cfg.Index.ChunkMaxTokens / 2
+	stale.Embedding.MaxTokensPerCrate = 1
+	client := startServer(t, &stale, dbPath)
+	ctx := t.Context()
+
+	added, err := client.AddCrates(ctx, rpc.AddCratesRequest{
+		Crates: []rpc.CrateSpec{{Name: testCrate, Version: testVersion, Force: true}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(added.Results[0].Error, "max_tokens_per_crate") {
+		t.Fatalf("add error %q, want the per-crate cap", added.Results[0].Error)
+	}
+
+	found, err := client.Search(ctx, rpc.SearchRequest{Query: "Buffer format integer", Crates: []string{testCrate}, Limit: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found.Results) == 0 || !strings.HasPrefix(found.Results[0].Path, "itoa::Buffer") {
+		t.Errorf("search results after a refused add %+v, want itoa::Buffer first", found.Results)
+	}
+}
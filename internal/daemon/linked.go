@@ -0,0 +1,167 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jcdickinson/ferrisfetch/internal/db"
+	"github.com/jcdickinson/ferrisfetch/internal/docs"
+	md "github.com/jcdickinson/ferrisfetch/internal/markdown"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+)
+
+// maxIncludeLinked caps GetDocRequest.IncludeLinked.
+const maxIncludeLinked = 20
+
+// appendLinked appends the summaries of up to n items that text links to:
+// the types used in the item's signatures first, then its doc links. Only
+// crates that are already indexed are consulted; nothing is auto-fetched.
+func (s *Server) appendLinked(d *resolvedDoc, text string, n int) string {
+	n = min(n, maxIncludeLinked)
+	self := fmt.Sprintf("rsdoc://%s/%s/%s", d.req.Crate, d.crate.Version, d.req.Path)
+
+	var b strings.Builder
+	seen := map[string]bool{self: true}
+	found := 0
+	for _, uri := range append(s.typesUsed(d), docs.LinkedURIs(text)...) {
+		if found == n {
+			break
+		}
+		linked := s.indexedDoc(d.crate.Namespace, uri)
+		if linked == nil {
+			continue
+		}
+		canonical := fmt.Sprintf("rsdoc://%s/%s/%s", linked.req.Crate, linked.crate.Version, linked.item.Path)
+		if seen[canonical] {
+			continue
+		}
+		seen[canonical] = true
+		found++
+
+		if found == 1 {
+			b.WriteString("\n## Linked Items\n")
+		}
+		fmt.Fprintf(&b, "\n### %s (%s)\n\n%s\n\n", linked.item.Path, linked.item.Kind, canonical)
+		if linked.item.Signature != "" {
+			fmt.Fprintf(&b, "```rust\n%s\n```\n\n", linked.item.Signature)
+		}
+		if summary, _ := splitSummary(itemDocs(linked.item)); strings.TrimSpace(summary) != "" {
+			b.WriteString(summary)
+		}
+	}
+	return text + b.String()
+}
+
+// typesUsed returns the URIs listed under "Types Used" in the item's
+// fragments, i.e. the types in its signatures.
+func (s *Server) typesUsed(d *resolvedDoc) []string {
+	if d.req.Fragment != "" || d.crate.Source == db.SourceHTML {
+		return nil
+	}
+	cached := s.getCachedCrate(d.crate.Registry, d.req.Crate, d.crate.Version)
+	if cached == nil {
+		return nil
+	}
+	rustdocItem, ok := cached.Index[d.item.RustdocID]
+	if !ok {
+		return nil
+	}
+	var uris []string
+	for _, f := range docs.GenerateFragments(&rustdocItem, cached, d.req.Crate, d.crate.Version) {
+		if i := strings.Index(f.Content, "## Types Used"); i >= 0 {
+			uris = append(uris, docs.LinkedURIs(f.Content[i:])...)
+		}
+	}
+	return uris
+}
+
+// indexedDoc resolves uri against crates already indexed in namespace ns,
+// falling back to the latest indexed version when the linked one isn't.
+func (s *Server) indexedDoc(ns, uri string) *resolvedDoc {
+	req, _, err := rpc.ParseDocURI(uri)
+	if err != nil {
+		return nil
+	}
+	var crate *db.Crate
+	if req.Version != "" && req.Version != "latest" {
+		crate, _ = s.db.InNamespace(ns).GetCrate(req.Crate, req.Version)
+	}
+	if crate == nil || crate.ProcessedAt == nil {
+		if crate, _ = s.db.InNamespace(ns).GetLatestCrate(req.Crate); crate == nil {
+			return nil
+		}
+	}
+	item, err := s.db.GetItemByPath(crate.ID, req.Path)
+	if err != nil || item == nil {
+		return nil
+	}
+	return &resolvedDoc{req: req, crate: crate, item: item}
+}
+
+// resolveLinks checks the rsdoc:// links in text against the index of
+// namespace ns, annotating or rewriting those that won't resolve as mode
+// (see rpc.GetDocRequest.ResolveLinks) says.
+func (s *Server) resolveLinks(ns, text, mode string) (string, []rpc.LinkStatus) {
+	var links []rpc.LinkStatus
+	for _, uri := range docs.LinkedURIs(text) {
+		status := rpc.LinkStatus{URI: uri, Status: s.linkStatus(ns, uri)}
+		if status.Status == rpc.LinkUnindexed {
+			if req, _, err := rpc.ParseDocURI(uri); err == nil {
+				status.DocsURL = docs.DefaultRegistry.ItemURL(req.Crate, req.Version, req.Path)
+			}
+		}
+		links = append(links, status)
+	}
+	statuses := make(map[string]*rpc.LinkStatus, len(links))
+	for i := range links {
+		statuses[links[i].URI] = &links[i]
+	}
+
+	text = md.AnnotateLinks(text, func(uri string) (string, string) {
+		l := statuses[uri]
+		switch {
+		case l == nil || l.Status == rpc.LinkIndexed:
+			return "", ""
+		case l.Status == rpc.LinkMissing:
+			return "", "not found"
+		case mode == "rewrite" && l.DocsURL != "":
+			return l.DocsURL, ""
+		}
+		return "", "not indexed"
+	})
+	return text, links
+}
+
+// linkStatus reports whether get-doc can serve uri from the index: exactly
+// as linked, or through a re-export into an indexed crate.
+func (s *Server) linkStatus(ns, uri string) string {
+	req, _, err := rpc.ParseDocURI(uri)
+	if err != nil {
+		return rpc.LinkMissing
+	}
+	index := s.db.InNamespace(ns)
+	var crate *db.Crate
+	if req.Version == "" || req.Version == "latest" {
+		crate, _ = index.GetLatestCrate(req.Crate)
+	} else if crate, _ = index.GetCrate(req.Crate, req.Version); crate != nil && crate.ProcessedAt == nil {
+		crate = nil
+	}
+	if crate == nil {
+		return rpc.LinkUnindexed
+	}
+	if item, _ := s.db.GetItemByPath(crate.ID, req.Path); item != nil {
+		return rpc.LinkIndexed
+	}
+	srcCrate, srcPath, found := s.db.ResolveReexport(crate.ID, req.Path)
+	if !found {
+		return rpc.LinkMissing
+	}
+	source, _ := index.GetLatestCrate(srcCrate)
+	if source == nil {
+		return rpc.LinkUnindexed
+	}
+	if item, _ := s.db.GetItemByPath(source.ID, srcPath); item != nil {
+		return rpc.LinkIndexed
+	}
+	return rpc.LinkMissing
+}
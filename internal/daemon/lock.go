@@ -0,0 +1,64 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// lockPath is the file a running daemon holds locked. Under socket
+// activation the socket outlives the daemon, so the lock, not the socket,
+// says whether one is running.
+func lockPath(socketPath string) string {
+	return socketPath + ".lock"
+}
+
+// acquireLock locks the daemon lock file, waiting up to wait for a daemon
+// that is shutting down to release it. The lock is released by closing the
+// returned file or exiting.
+func acquireLock(socketPath string, wait time.Duration) (*os.File, error) {
+	f, err := os.OpenFile(lockPath(socketPath), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("opening daemon lock: %w", err)
+	}
+	deadline := time.Now().Add(wait)
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			return f, nil
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			f.Close()
+			return nil, fmt.Errorf("locking %s: %w", lockPath(socketPath), err)
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("another daemon is already running (%s is locked)", lockPath(socketPath))
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// Running reports whether a daemon holds the lock for socketPath.
+func Running(socketPath string) bool {
+	f, err := os.Open(lockPath(socketPath))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	return errors.Is(syscall.Flock(int(f.Fd()), syscall.LOCK_SH|syscall.LOCK_NB), syscall.EWOULDBLOCK)
+}
+
+// WaitStopped waits up to timeout for the daemon on socketPath to finish
+// shutting down and release the database.
+func WaitStopped(socketPath string, timeout time.Duration) error {
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); {
+		if !Running(socketPath) {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Errorf("daemon still running after %s", timeout)
+}
@@ -0,0 +1,178 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/jcdickinson/ferrisfetch/internal/embeddings"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+)
+
+// configuredModel returns voyage_ai.model, or embeddings.FakeModel for the
+// fake provider, and its embedding dimension; ok is false when the
+// dimension is unknown.
+func (s *Server) configuredModel() (model string, dim int, ok bool) {
+	cfg := s.live().cfg
+	model = cfg.VoyageAI.Model
+	if model == "" {
+		model = "voyage-3.5"
+	}
+	if cfg.VoyageAI.Fake() {
+		model = embeddings.FakeModel
+	}
+	dim, ok = embeddings.ModelDimension(model, cfg.VoyageAI.Dimensions)
+	return model, dim, ok
+}
+
+// embeddingModel returns the model new embeddings and queries use: the one
+// the index was built with. It stays in force after voyage_ai.model changes
+// until the index is re-embedded, since vectors from different models can't
+// be compared.
+func (s *Server) embeddingModel() string {
+	s.modelMu.RLock()
+	defer s.modelMu.RUnlock()
+	return s.model
+}
+
+// setEmbeddingModel switches embeddings and queries to model at dim
+// dimensions, requesting a reduced output dimension if dim calls for one.
+func (s *Server) setEmbeddingModel(model string, dim int) {
+	s.modelMu.Lock()
+	defer s.modelMu.Unlock()
+	s.model, s.modelDim = model, dim
+	s.live().useModel(model, dim)
+}
+
+// checkEmbeddingModel compares the model recorded in the index with the
+// configured one at startup. An empty index simply adopts the configured
+// model; a populated one built with another model keeps using it, with a
+// warning pointing at "rsdoc reembed".
+func (s *Server) checkEmbeddingModel() error {
+	configured, dim, known := s.configuredModel()
+	if _, err := embeddings.OutputDimension(configured, s.live().cfg.VoyageAI.Dimensions); err != nil {
+		return fmt.Errorf("voyage_ai.dimensions: %w", err)
+	}
+	stored, storedDim, err := s.db.EmbeddingModel()
+	if err != nil {
+		return err
+	}
+	if stored == configured && (!known || storedDim == dim) {
+		s.setEmbeddingModel(stored, storedDim)
+		return nil
+	}
+
+	count, err := s.db.CountEmbeddings()
+	if err != nil {
+		return err
+	}
+	switch {
+	case count == 0 || (stored == "" && (!known || storedDim == dim)):
+		// A new index, or one from a build that didn't record its model and
+		// whose vectors fit the configured one.
+		if !known {
+			if storedDim == 0 || count == 0 {
+				return fmt.Errorf("unknown embedding dimension for %s; set voyage_ai.dimensions", configured)
+			}
+			dim = storedDim
+		}
+		if err := s.db.SetEmbeddingModel(configured, dim); err != nil {
+			return err
+		}
+		s.setEmbeddingModel(configured, dim)
+	case stored == "":
+		slog.Warn("index was embedded with an unrecorded model whose dimension doesn't match voyage_ai.model; run \"rsdoc reembed\"",
+			"index_dimensions", storedDim, "configured", configured, "configured_dimensions", dim)
+		s.setEmbeddingModel(configured, storedDim)
+	default:
+		slog.Warn("voyage_ai settings differ from the model the index was embedded with; searches keep using the index's model until \"rsdoc reembed\"",
+			"index", stored, "index_dimensions", storedDim, "configured", configured, "configured_dimensions", dim)
+		s.setEmbeddingModel(stored, storedDim)
+	}
+	return nil
+}
+
+// modelStatus describes the index's model for GET /status.
+func (s *Server) modelStatus() *rpc.EmbeddingModel {
+	stored, dim, err := s.db.EmbeddingModel()
+	if err != nil {
+		slog.Error("failed to read embedding model", "error", err)
+		return nil
+	}
+	status := &rpc.EmbeddingModel{Model: stored, Dimensions: dim}
+	if configured, configuredDim, _ := s.configuredModel(); configured != stored || (configuredDim != 0 && configuredDim != dim) {
+		status.Configured, status.ConfiguredDimensions = configured, configuredDim
+	}
+	return status
+}
+
+func (s *Server) handleReembed(w http.ResponseWriter, r *http.Request) {
+	var req rpc.ReembedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	model, dim, ok := s.configuredModel()
+	outputDim, err := embeddings.OutputDimension(model, s.live().cfg.VoyageAI.Dimensions)
+	if req.Model != "" {
+		model = req.Model
+		dim, ok = embeddings.ModelDimension(model, req.Dimensions)
+		outputDim, err = embeddings.OutputDimension(model, req.Dimensions)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown embedding dimension for %s; set voyage_ai.dimensions", model))
+		return
+	}
+
+	// Probe the model before discarding anything, so a typo or a wrong
+	// dimension doesn't leave an empty index behind.
+	probe, err := s.live().voyage.EmbedTextsAt([]string{"ferrisfetch"}, model, outputDim)
+	if err == nil && len(probe) == 0 {
+		err = fmt.Errorf("no embeddings returned")
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Sprintf("embedding with %s: %s", model, s.withProviderState(err)))
+		return
+	}
+	if len(probe[0]) != dim {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("%s returned %d dimensions, expected %d; check voyage_ai.dimensions", model, len(probe[0]), dim))
+		return
+	}
+
+	crates, err := s.db.ListAllCrates()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.writes.Lock()
+	err = s.db.ResetEmbeddings(model, dim)
+	if err == nil {
+		s.setEmbeddingModel(model, dim)
+	}
+	s.writes.Unlock()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	send := progressStream(w)
+	send(rpc.ProgressLine{Type: "progress", Message: fmt.Sprintf("re-embedding %d crates with %s (%d dimensions)", len(crates), model, dim)})
+	for _, c := range crates {
+		result := rpc.CrateResult{Name: c.Name, Version: c.Version}
+		if spec, err := reindexSpec(c); err != nil {
+			result.Error = err.Error()
+		} else {
+			result = s.addCrate(withNamespace(r.Context(), c.Namespace), spec, func(msg string) {
+				send(rpc.ProgressLine{Type: "progress", Message: msg})
+			})
+		}
+		if !send(rpc.ProgressLine{Type: "result", Result: &result}) {
+			return
+		}
+	}
+}
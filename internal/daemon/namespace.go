@@ -0,0 +1,86 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/jcdickinson/ferrisfetch/internal/cas"
+	"github.com/jcdickinson/ferrisfetch/internal/db"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+)
+
+type namespaceKey struct{}
+
+// withNamespace scopes the work done under ctx to namespace ns.
+func withNamespace(ctx context.Context, ns string) context.Context {
+	return context.WithValue(ctx, namespaceKey{}, ns)
+}
+
+// namespaceOf returns the namespace ctx is scoped to; "" is the default.
+func namespaceOf(ctx context.Context) string {
+	ns, _ := ctx.Value(namespaceKey{}).(string)
+	return ns
+}
+
+// index returns the database scoped to ctx's namespace.
+func (s *Server) index(ctx context.Context) *db.DB {
+	return s.db.InNamespace(namespaceOf(ctx))
+}
+
+// scoped validates a request's namespace and scopes r's context to it,
+// writing a 400 and returning false if it's invalid.
+func scoped(w http.ResponseWriter, r *http.Request, ns string) (context.Context, bool) {
+	if err := db.ValidateNamespace(ns); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	return withNamespace(r.Context(), ns), true
+}
+
+func (s *Server) handleNamespaces(w http.ResponseWriter, r *http.Request) {
+	namespaces, err := s.db.ListNamespaces()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp := rpc.NamespacesResponse{Namespaces: []rpc.NamespaceInfo{}}
+	for _, ns := range namespaces {
+		resp.Namespaces = append(resp.Namespaces, rpc.NamespaceInfo{Name: ns.Name, Crates: ns.Crates, Items: ns.Items})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleDeleteNamespace drops a namespace's crates, then the embeddings and
+// CAS files of content no remaining crate uses. The default namespace can
+// be emptied the same way.
+func (s *Server) handleDeleteNamespace(w http.ResponseWriter, r *http.Request) {
+	var req rpc.DeleteNamespaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := db.ValidateNamespace(req.Namespace); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Like snapshot and restore, wait for indexing to finish so no add is
+	// writing into the namespace as it goes.
+	s.writes.Lock()
+	crates, unused, err := s.db.InNamespace(req.Namespace).DeleteNamespace()
+	s.writes.Unlock()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("deleting namespace: %v", err))
+		return
+	}
+	for _, h := range unused {
+		if err := cas.Remove(h); err != nil {
+			slog.Warn("failed to remove CAS file", "hash", h, "error", err)
+		}
+	}
+	slog.Info("deleted namespace", "namespace", req.Namespace, "crates", crates, "removed_content", len(unused))
+	writeJSON(w, http.StatusOK, rpc.DeleteNamespaceResponse{Crates: crates, RemovedContent: len(unused)})
+}
@@ -0,0 +1,49 @@
+package daemon
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jcdickinson/ferrisfetch/internal/cas"
+)
+
+// preload warms the caches a search reads from, for daemon.preload: the
+// database pages, the version cache with each indexed crate's latest
+// version, and the CAS directories. It runs after the daemon starts
+// listening, so requests aren't held up waiting for it.
+func (s *Server) preload(ctx context.Context) {
+	start := time.Now()
+	if err := s.db.Warm(); err != nil {
+		slog.Warn("preloading index", "error", err)
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	crates, err := s.db.ListCrates()
+	if err != nil {
+		slog.Warn("preloading crate versions", "error", err)
+	}
+	versions := 0
+	for _, c := range crates {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, ok := s.getCachedVersion(c.Registry, c.Name); ok {
+			continue
+		}
+		latest, err := s.db.GetLatestRegistryCrate(c.Registry, c.Name)
+		if err != nil || latest == nil {
+			continue
+		}
+		s.setCachedVersion(c.Registry, c.Name, latest.Version)
+		versions++
+	}
+
+	files, err := cas.Warm()
+	if err != nil {
+		slog.Warn("preloading CAS", "error", err)
+	}
+	slog.Info("preload finished", "crates", versions, "cas_files", files, "elapsed", time.Since(start).Round(time.Millisecond))
+}
@@ -0,0 +1,69 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jcdickinson/ferrisfetch/internal/embeddings"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+)
+
+// monitorProvider checks the embedding provider at startup and whenever no
+// request has reached it for interval, so status reflects a revoked key or
+// exhausted quota before the next search trips over it.
+func (s *Server) monitorProvider(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	last := ""
+	for {
+		voyage := s.live().voyage
+		if time.Since(voyage.Health().LastRequestAt) >= interval {
+			voyage.Check(s.embeddingModel())
+		}
+		if h := voyage.Health(); h.State != last {
+			if h.State == embeddings.ProviderOK {
+				slog.Info("embedding provider ok")
+			} else {
+				slog.Warn("embedding provider state changed", "state", h.State, "error", h.LastError)
+			}
+			last = h.State
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func providerHealth(h embeddings.Health) *rpc.ProviderHealth {
+	out := &rpc.ProviderHealth{
+		State:             h.State,
+		LastError:         h.LastError,
+		RateLimited:       h.RateLimited,
+		RemainingRequests: h.RemainingRequests,
+		RemainingTokens:   h.RemainingTokens,
+	}
+	if !h.LastErrorAt.IsZero() {
+		out.LastErrorAt = &h.LastErrorAt
+	}
+	return out
+}
+
+// withProviderState explains err in terms of the embedding provider's state
+// when that state is likely the cause.
+func (s *Server) withProviderState(err error) string {
+	h := s.live().voyage.Health()
+	switch h.State {
+	case embeddings.ProviderUnauthorized:
+		return fmt.Sprintf("%v (embedding provider unauthorized: check voyage_ai.api_key)", err)
+	case embeddings.ProviderOverQuota:
+		return fmt.Sprintf("%v (embedding provider over quota: %d rate-limited requests in the last 15m; wait or raise your Voyage AI limits)", err, h.RateLimited)
+	case embeddings.ProviderDegraded:
+		return fmt.Sprintf("%v (embedding provider degraded: recent errors, last: %s)", err, h.LastError)
+	}
+	return err.Error()
+}
@@ -0,0 +1,134 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/jcdickinson/ferrisfetch/internal/db"
+	"github.com/jcdickinson/ferrisfetch/internal/docs"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+)
+
+// handleReindex re-parses crates from the rustdoc JSON cache and rebuilds
+// their items, fragments and examples. Content whose text is unchanged
+// keeps its embeddings unless another model or chunker version produced
+// them, so a re-index mostly costs embedding requests for what changed.
+func (s *Server) handleReindex(w http.ResponseWriter, r *http.Request) {
+	var req rpc.ReindexRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var pause time.Duration
+	if req.Pause != "" {
+		var err error
+		if pause, err = time.ParseDuration(req.Pause); err != nil || pause < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid pause %q", req.Pause))
+			return
+		}
+	}
+	ctx, ok := scoped(w, r, req.Namespace)
+	if !ok {
+		return
+	}
+
+	crates, err := s.reindexTargets(ctx, req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	send := progressStream(w)
+	what := "crates"
+	if req.ChangedOnly {
+		what = "crates with stale embeddings"
+	}
+	send(rpc.ProgressLine{Type: "progress", Message: fmt.Sprintf("re-indexing %d %s", len(crates), what)})
+	for i, c := range crates {
+		if i > 0 && pause > 0 {
+			select {
+			case <-time.After(pause):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		send(rpc.ProgressLine{Type: "progress", Message: fmt.Sprintf("re-indexing %s@%s (%d/%d)", c.Name, c.Version, i+1, len(crates))})
+		result := s.reindexCrate(r.Context(), c, req.OverBudget, func(msg string) {
+			send(rpc.ProgressLine{Type: "progress", Message: msg})
+		})
+		if !send(rpc.ProgressLine{Type: "result", Result: &result}) {
+			return
+		}
+	}
+}
+
+// reindexTargets returns the crates a reindex request covers: the named
+// ones in ctx's namespace, or every crate, narrowed with ChangedOnly to
+// those with stale embeddings.
+func (s *Server) reindexTargets(ctx context.Context, req rpc.ReindexRequest) ([]db.Crate, error) {
+	var crates []db.Crate
+	var err error
+	if req.ChangedOnly {
+		crates, err = s.db.StaleEmbeddingCrates(s.embeddingModel(), s.chunkOptions().Version())
+	} else {
+		crates, err = s.db.ListAllCrates()
+	}
+	if err != nil || len(req.Crates) == 0 {
+		return crates, err
+	}
+
+	var ids []int
+	for _, spec := range req.Crates {
+		matched, err := s.index(ctx).GetCrateIDsForSpecs([]string{spec})
+		if err != nil {
+			return nil, err
+		}
+		if len(matched) == 0 {
+			return nil, fmt.Errorf("%s isn't indexed", spec)
+		}
+		ids = append(ids, matched...)
+	}
+	return slices.DeleteFunc(crates, func(c db.Crate) bool {
+		return !slices.Contains(ids, c.ID)
+	}), nil
+}
+
+// reindexCrate re-indexes c from its cached rustdoc JSON, fetching nothing
+// from docs.rs. Crates scraped from HTML have no JSON to re-parse. overBudget
+// lets it go over the embedding budget.
+func (s *Server) reindexCrate(ctx context.Context, c db.Crate, overBudget bool, progress func(string)) rpc.CrateResult {
+	result := rpc.CrateResult{Name: c.Name, Version: c.Version}
+	fix := fmt.Sprintf("re-add it with `rsdoc add %s@%s --force`", c.Name, c.Version)
+	if c.Source == db.SourceFile {
+		fix = fmt.Sprintf("re-add it with `rsdoc add --file` (%s@%s)", c.Name, c.Version)
+	}
+	if c.Source == db.SourceHTML {
+		result.Error = "indexed from docs.rs HTML, which isn't cached; " + fix
+		return result
+	}
+	raw, err := docs.ReadCrateCache(c.Registry, c.Name, c.Version)
+	if err != nil {
+		result.Error = fmt.Sprintf("no cached rustdoc JSON (%v); %s", err, fix)
+		return result
+	}
+	data, err := docs.DecodeRustdocJSON(raw)
+	if err != nil {
+		result.Error = fmt.Sprintf("cached rustdoc JSON: %v; %s", err, fix)
+		return result
+	}
+	reg, err := docs.LookupRegistry(c.Registry)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	s.writes.RLock()
+	defer s.writes.RUnlock()
+	return s.addOnce(withNamespace(ctx, c.Namespace), registryName(c.Registry, c.Name)+"@"+c.Version, func(ctx context.Context) rpc.CrateResult {
+		return s.addCrateWork(ctx, reg, c.Name, c.Version, addOptions{force: true, overBudget: overBudget, data: data, dataSource: c.Source}, progress)
+	})
+}
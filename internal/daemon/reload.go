@@ -0,0 +1,223 @@
+package daemon
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jcdickinson/ferrisfetch/internal/cas"
+	"github.com/jcdickinson/ferrisfetch/internal/config"
+	"github.com/jcdickinson/ferrisfetch/internal/docs"
+	"github.com/jcdickinson/ferrisfetch/internal/embeddings"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+	"github.com/jcdickinson/ferrisfetch/internal/search"
+)
+
+// liveConfig is the running config with the clients built from it. A
+// reload replaces it whole, so a request that takes it once sees one
+// consistent config throughout; clients it replaced finish their requests.
+type liveConfig struct {
+	cfg           *config.Config
+	voyage        *embeddings.VoyageClient
+	batchEmbedder *embeddings.BatchEmbedder
+	searcher      *search.Searcher
+}
+
+// live returns the running config and its clients.
+func (s *Server) live() *liveConfig {
+	return s.current.Load()
+}
+
+// build creates the clients for cfg. They use voyage_ai.model until
+// useModel says otherwise.
+func (s *Server) build(cfg *config.Config) *liveConfig {
+	voyage := embeddings.NewVoyageClient(cfg.VoyageAI.ApiKey.Value)
+	voyage.SetRateLimiter(embeddings.NewRateLimiter(cfg.VoyageAI.RequestsPerMinute, cfg.VoyageAI.TokensPerMinute))
+	voyage.SetKeyRefresher(cfg.VoyageAI.ApiKey.Refresh)
+	if cfg.VoyageAI.Fake() {
+		voyage.SetTransport(embeddings.FakeTransport{})
+	} else if s.transport != nil {
+		voyage.SetTransport(s.transport)
+	}
+	var registries []*docs.Registry
+	for name, reg := range cfg.Registries {
+		registries = append(registries, &docs.Registry{Name: name, IndexURL: reg.IndexURL, DocsURL: reg.DocsURL, Token: reg.Token.Value})
+	}
+	docs.SetRegistries(registries)
+	docs.SetCrawlLimits(cfg.Crawl.RequestsPerSecond, cfg.Crawl.MaxRetries)
+	cas.SetCacheSize(int64(cfg.Daemon.CASCacheMB) << 20)
+	batchEmbedder := embeddings.NewBatchEmbedder(voyage, embeddings.BatchOptions{
+		MaxTexts:    cfg.VoyageAI.BatchSize,
+		MaxTokens:   cfg.VoyageAI.BatchTokens,
+		Concurrency: cfg.VoyageAI.Concurrency,
+	})
+	kindWeights := make(map[string]float32, len(cfg.Search.KindWeights))
+	for kind, w := range cfg.Search.KindWeights {
+		kindWeights[kind] = float32(w)
+	}
+	rerank, err := search.ParseRerankMode(cfg.Search.Rerank)
+	if err != nil {
+		slog.Error("ignoring search.rerank", "error", err)
+		rerank = search.RerankAuto
+	}
+	searcher := search.NewSearcher(s.db, voyage, cfg.VoyageAI.Model, cfg.VoyageAI.RerankModel, search.Options{
+		KindWeights:          kindWeights,
+		RerankSkipSimilarity: float32(cfg.Search.RerankSkipSimilarity),
+		RerankSkipMargin:     float32(cfg.Search.RerankSkipMargin),
+		Rerank:               rerank,
+		SnippetLength:        cfg.Search.SnippetLength,
+		PopularityWeight:     float32(cfg.Search.PopularityWeight),
+		LatestWeight:         float32(cfg.Search.LatestWeight),
+		DeprecatedPenalty:    float32(cfg.Search.DeprecatedPenalty),
+	})
+	return &liveConfig{cfg: cfg, voyage: voyage, batchEmbedder: batchEmbedder, searcher: searcher}
+}
+
+// useModel points l's clients at model with dim dimensions.
+func (l *liveConfig) useModel(model string, dim int) {
+	outputDim, err := embeddings.OutputDimension(model, dim)
+	if err != nil {
+		slog.Warn("embedding dimension not supported by model", "model", model, "error", err)
+	}
+	l.voyage.SetOutputDimension(model, outputDim)
+	l.searcher.SetModel(model)
+}
+
+// SetConfigLoader replaces config.Load as the way reloads read the config,
+// e.g. to keep command-line overrides in force.
+func (s *Server) SetConfigLoader(load func() (*config.Config, error)) {
+	s.loadConfig = load
+}
+
+// restartKeys are the settings (or prefixes of them) only read at startup.
+// A reload reports changes to them but keeps the running values.
+var restartKeys = []string{"paths.", "vcr.", "index.", "voyage_ai.provider", "daemon.listen", "daemon.provider_check_minutes", "daemon.preload"}
+
+// index.ignore is read each time a crate is indexed, so it reloads.
+const ignoreKeys = "index.ignore."
+
+func needsRestart(key string) bool {
+	if strings.HasPrefix(key, ignoreKeys) {
+		return false
+	}
+	for _, k := range restartKeys {
+		if strings.HasPrefix(key, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// reloadConfig re-reads the config and swaps in clients built from it. A
+// changed voyage_ai.model only takes over an empty index; a populated one
+// keeps its model until "rsdoc reembed", as at startup.
+func (s *Server) reloadConfig() ([]rpc.ConfigChange, error) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	cfg, err := s.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	old := s.live().cfg
+	diff := config.Diff(old, cfg)
+	if len(diff) == 0 {
+		return nil, nil
+	}
+	// Keep what can't change while running, so the live config describes
+	// what the daemon is actually doing.
+	ignore := cfg.Index.Ignore
+	cfg.Paths, cfg.VCR, cfg.Index = old.Paths, old.VCR, old.Index
+	cfg.Index.Ignore = ignore
+	cfg.Daemon.Listen, cfg.Daemon.ProviderCheckMinutes = old.Daemon.Listen, old.Daemon.ProviderCheckMinutes
+	cfg.Daemon.Preload = old.Daemon.Preload
+	cfg.VoyageAI.Provider = old.VoyageAI.Provider
+	if s.tcpListener != nil && cfg.Daemon.AuthToken.Value == "" {
+		return nil, fmt.Errorf("daemon.auth_token can't be removed while serving daemon.listen; restart the daemon")
+	}
+	expiration, err := cfg.Daemon.IdleExpiration()
+	if err != nil {
+		return nil, fmt.Errorf("daemon.expiration: %w", err)
+	}
+	for _, w := range cfg.Warnings() {
+		slog.Warn("config problem", "warning", w)
+	}
+
+	changes := make([]rpc.ConfigChange, 0, len(diff))
+	modelChanged := false
+	for _, c := range diff {
+		change := rpc.ConfigChange{Key: c.Key, Old: c.Old, New: c.New, Restart: needsRestart(c.Key)}
+		if change.Restart {
+			slog.Warn("config changed; restart the daemon to apply", "key", c.Key, "old", c.Old, "new", c.New)
+		} else {
+			slog.Info("config changed", "key", c.Key, "old", c.Old, "new", c.New)
+		}
+		modelChanged = modelChanged || c.Key == "voyage_ai.model" || c.Key == "voyage_ai.dimensions"
+		changes = append(changes, change)
+	}
+
+	next := s.build(cfg)
+	s.modelMu.Lock()
+	if s.model != "" {
+		next.useModel(s.model, s.modelDim)
+	}
+	s.current.Store(next)
+	s.modelMu.Unlock()
+	s.setExpiration(expiration)
+
+	if modelChanged {
+		// Adopting a new model rewrites the index's record of it, which
+		// mustn't race an add storing vectors from the old one.
+		s.writes.Lock()
+		err := s.checkEmbeddingModel()
+		s.writes.Unlock()
+		if err != nil {
+			slog.Error("keeping the index's embedding model", "error", err)
+		}
+	}
+	return changes, nil
+}
+
+// setExpiration changes how long the daemon idles before stopping, starting
+// or stopping the timer if expiration was turned on or off.
+func (s *Server) setExpiration(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expiration = d
+	if s.stopping.Load() {
+		return
+	}
+	switch {
+	case d == 0 && s.expTimer != nil:
+		s.expTimer.Stop()
+		s.expTimer = nil
+	case d > 0 && s.expTimer == nil:
+		s.expTimer = time.AfterFunc(d, s.expire)
+	case d > 0:
+		s.expTimer.Reset(d)
+	}
+}
+
+// watchConfig reloads the config whenever its file changes.
+func (s *Server) watchConfig() {
+	config.Watch(func() {
+		if s.stopping.Load() {
+			return
+		}
+		slog.Info("config file changed, reloading")
+		if _, err := s.reloadConfig(); err != nil {
+			slog.Error("config reload failed; keeping the running config", "error", err)
+		}
+	})
+}
+
+func (s *Server) handleReloadConfig(w http.ResponseWriter, r *http.Request) {
+	changes, err := s.reloadConfig()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, rpc.ReloadConfigResponse{Changes: changes})
+}
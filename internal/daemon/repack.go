@@ -0,0 +1,35 @@
+package daemon
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/jcdickinson/ferrisfetch/internal/cas"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+)
+
+func (s *Server) handleRepack(w http.ResponseWriter, r *http.Request) {
+	var req rpc.RepackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Like snapshot, hold off indexing so the pack is a consistent cut.
+	s.writes.Lock()
+	res, err := cas.Repack(req.All)
+	s.writes.Unlock()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	slog.Info("repacked content store", "loose", res.Loose, "packed", res.Packed, "dropped", res.Dropped, "packs", res.Packs, "bytes", res.Bytes)
+	writeJSON(w, http.StatusOK, rpc.RepackResponse{
+		Loose:   res.Loose,
+		Packed:  res.Packed,
+		Dropped: res.Dropped,
+		Packs:   res.Packs,
+		Bytes:   res.Bytes,
+	})
+}
@@ -6,10 +6,12 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -22,143 +24,422 @@ import (
 	"github.com/jcdickinson/ferrisfetch/internal/embeddings"
 	md "github.com/jcdickinson/ferrisfetch/internal/markdown"
 	"github.com/jcdickinson/ferrisfetch/internal/rpc"
-	"github.com/jcdickinson/ferrisfetch/internal/search"
+	"github.com/jcdickinson/ferrisfetch/internal/vcr"
 	"golang.org/x/sync/singleflight"
 )
 
 type versionCacheEntry struct {
 	version  string // resolved real version; empty for 404s
 	notFound bool
-	expiry   time.Time
+	// suggestions are similar crate names for a 404.
+	suggestions []string
+	expiry      time.Time
 }
 
 type Server struct {
-	db            *db.DB
-	voyage        *embeddings.VoyageClient
-	batchEmbedder *embeddings.BatchEmbedder
-	searcher      *search.Searcher
-	cfg           *config.Config
-	socketPath    string
-	httpServer    *http.Server
-	listener      net.Listener
-
-	mu         sync.Mutex
-	expTimer   *time.Timer
+	db *db.DB
+	// current is the running config and the clients built from it; see
+	// live.
+	current atomic.Pointer[liveConfig]
+	// loadConfig reads the config on reload; see SetConfigLoader.
+	loadConfig func() (*config.Config, error)
+	// reloadMu serializes config reloads.
+	reloadMu sync.Mutex
+	// transport replaces the default HTTP transport of rebuilt clients when
+	// VCR is enabled.
+	transport  http.RoundTripper
+	socketPath string
+	httpServer *http.Server
+	listener   net.Listener
+	// tcpListener serves daemon.listen, if set.
+	tcpListener net.Listener
+	// activated is set when systemd owns the socket (socket activation),
+	// which must then be left in place on exit.
+	activated bool
+	// lock is held for the daemon's lifetime; see acquireLock.
+	lock      *os.File
+	startedAt time.Time
+	// endpoints lists the routes registered by Start, for GET /capabilities.
+	endpoints []string
+	// vcr disables periodic provider checks, which would pollute fixtures.
+	vcr bool
+	// probeMu keeps concurrent /health requests from probing the provider
+	// more than once.
+	probeMu sync.Mutex
+
+	mu       sync.Mutex
+	expTimer *time.Timer
+	// expiration is how long the daemon idles before stopping; 0 never.
 	expiration time.Duration
 	activeOps  atomic.Int64
 
+	// workCtx is the base context of requests and background indexing.
+	// Stop cancels it when draining runs out of time.
+	workCtx    context.Context
+	cancelWork context.CancelFunc
+	// stopping refuses new requests once Stop has begun draining.
+	stopping atomic.Bool
+	stopOnce sync.Once
+	stopped  chan struct{}
+
 	versionCache   map[string]versionCacheEntry
 	versionCacheMu sync.RWMutex
 	addCrateGroup  singleflight.Group
+	// addCrateRuns tracks the callers sharing each in-flight addCrateGroup
+	// call, so its work is cancelled only once all of them have gone.
+	addCrateRuns   map[string]*sharedAdd
+	addCrateRunsMu sync.Mutex
 
 	crateCache   map[string]*docs.RustdocCrate
 	crateCacheMu sync.RWMutex
+
+	// writes is held shared by anything that indexes and exclusively by
+	// snapshot, restore and reembed, which need the database and HNSW index
+	// quiet.
+	writes sync.RWMutex
+
+	// model and modelDim are the embedding model the index was built with
+	// and its dimension; see embeddingModel.
+	model    string
+	modelDim int
+	modelMu  sync.RWMutex
+
+	// background counts time-boxed adds still running per crate name.
+	background   map[string]int
+	backgroundMu sync.Mutex
+
+	// watches are the projects kept indexed by POST /watch, keyed by
+	// namespace and manifest.
+	watches   map[string]*projectWatch
+	watchesMu sync.Mutex
 }
 
 func NewServer(cfg *config.Config, database *db.DB, socketPath string) *Server {
-	voyage := embeddings.NewVoyageClient(cfg.VoyageAI.ApiKey.Value)
-	batchEmbedder := embeddings.NewBatchEmbedder(voyage, 50, 200*time.Millisecond)
-	searcher := search.NewSearcher(database, voyage, cfg.VoyageAI.Model, cfg.VoyageAI.RerankModel)
-
-	expSec := cfg.Daemon.ExpirationSeconds
-	if expSec <= 0 {
-		expSec = 600
+	for _, w := range cfg.Warnings() {
+		slog.Warn("config problem", "warning", w)
+	}
+	var transport http.RoundTripper
+	if mode, err := vcr.ParseMode(cfg.VCR.Mode); err != nil {
+		slog.Error("ignoring vcr config", "error", err)
+	} else if mode != vcr.ModeOff {
+		dir := cfg.VCR.Dir
+		if dir == "" {
+			dir = config.VCRDir()
+		}
+		slog.Info("http vcr enabled", "mode", mode, "dir", dir)
+		transport = vcr.New(mode, dir, nil)
+		docs.SetHTTPTransport(transport)
 	}
 
-	return &Server{
-		db:            database,
-		voyage:        voyage,
-		batchEmbedder: batchEmbedder,
-		searcher:      searcher,
-		cfg:           cfg,
-		socketPath:    socketPath,
-		expiration:    time.Duration(expSec) * time.Second,
-		versionCache:  make(map[string]versionCacheEntry),
-		crateCache:    make(map[string]*docs.RustdocCrate),
-	}
+	expiration, err := cfg.Daemon.IdleExpiration()
+	if err != nil {
+		slog.Error("ignoring daemon.expiration", "error", err)
+		expiration, _ = config.DaemonConfig{}.IdleExpiration()
+	}
+	workCtx, cancelWork := context.WithCancel(context.Background())
+
+	s := &Server{
+		db:           database,
+		loadConfig:   config.Load,
+		transport:    transport,
+		socketPath:   socketPath,
+		startedAt:    time.Now(),
+		vcr:          transport != nil,
+		expiration:   expiration,
+		workCtx:      workCtx,
+		cancelWork:   cancelWork,
+		stopped:      make(chan struct{}),
+		versionCache: make(map[string]versionCacheEntry),
+		crateCache:   make(map[string]*docs.RustdocCrate),
+		background:   make(map[string]int),
+		addCrateRuns: make(map[string]*sharedAdd),
+		watches:      make(map[string]*projectWatch),
+	}
+	s.current.Store(s.build(cfg))
+	return s
+}
+
+// SetHTTPTransport sends docs.rs, crates.io and Voyage requests through rt,
+// as vcr.mode does, e.g. to replay a test's recorded responses. Call it
+// before starting the server.
+func (s *Server) SetHTTPTransport(rt http.RoundTripper) {
+	s.transport = rt
+	s.vcr = true
+	docs.SetHTTPTransport(rt)
+	s.current.Store(s.build(s.live().cfg))
 }
 
+// DrainTimeout bounds how long Stop waits for in-flight requests and
+// background indexing before cancelling them.
+const DrainTimeout = time.Minute
+
+// cancelGrace is how long cancelled work gets to save its progress.
+const cancelGrace = 5 * time.Second
+
+// Start serves until the server is stopped, by Stop, POST /shutdown, idle
+// expiration or ctx ending, and returns once it has shut down.
 func (s *Server) Start(ctx context.Context) error {
 	if err := os.MkdirAll(filepath.Dir(s.socketPath), 0755); err != nil {
 		return fmt.Errorf("creating socket directory: %w", err)
 	}
-	os.Remove(s.socketPath)
+	// A daemon that is still shutting down holds the lock until it has
+	// closed the database.
+	lock, err := acquireLock(s.socketPath, cancelGrace)
+	if err != nil {
+		return err
+	}
+	s.lock = lock
 
-	listener, err := net.Listen("unix", s.socketPath)
+	listener, err := activationListener()
 	if err != nil {
-		return fmt.Errorf("listening on socket: %w", err)
+		lock.Close()
+		return err
 	}
-	if err := os.Chmod(s.socketPath, 0600); err != nil {
-		listener.Close()
-		return fmt.Errorf("setting socket permissions: %w", err)
+	if listener != nil {
+		s.activated = true
+	} else {
+		os.Remove(s.socketPath)
+		listener, err = net.Listen("unix", s.socketPath)
+		if err != nil {
+			lock.Close()
+			return fmt.Errorf("listening on socket: %w", err)
+		}
+		if err := os.Chmod(s.socketPath, 0600); err != nil {
+			listener.Close()
+			lock.Close()
+			return fmt.Errorf("setting socket permissions: %w", err)
+		}
 	}
 	s.listener = listener
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("POST /add-crates", s.withExpReset(s.handleAddCrates))
-	mux.HandleFunc("POST /search", s.withExpReset(s.handleSearch))
-	mux.HandleFunc("POST /get-doc", s.withExpReset(s.handleGetDoc))
-	mux.HandleFunc("GET /status", s.withExpReset(s.handleStatus))
-	mux.HandleFunc("POST /search-crates", s.withExpReset(s.handleSearchCrates))
-	mux.HandleFunc("POST /clear-cache", s.withExpReset(s.handleClearCache))
-	mux.HandleFunc("POST /shutdown", s.handleShutdown)
+	if err := s.checkEmbeddingModel(); err != nil {
+		listener.Close()
+		lock.Close()
+		return err
+	}
 
-	s.httpServer = &http.Server{Handler: mux}
+	if addr := s.live().cfg.Daemon.Listen; addr != "" {
+		if s.tcpListener, err = net.Listen("tcp", addr); err != nil {
+			listener.Close()
+			lock.Close()
+			return fmt.Errorf("listening on %s: %w", addr, err)
+		}
+	}
 
-	s.mu.Lock()
-	s.expTimer = time.AfterFunc(s.expiration, s.expire)
-	s.mu.Unlock()
+	s.httpServer = &http.Server{
+		Handler:     s.withAuth(s.routes()),
+		BaseContext: func(net.Listener) context.Context { return s.workCtx },
+	}
+
+	expiration := "never"
+	if s.expiration > 0 {
+		s.mu.Lock()
+		s.expTimer = time.AfterFunc(s.expiration, s.expire)
+		s.mu.Unlock()
+		expiration = s.expiration.String()
+	}
+	stopOnCancel := context.AfterFunc(ctx, func() {
+		slog.Info("stopping", "reason", context.Cause(ctx))
+		s.stopAsync()
+	})
+	defer stopOnCancel()
+
+	slog.Info("daemon listening", "socket", s.socketPath, "expiration", expiration, "socket_activated", s.activated)
 
-	slog.Info("daemon listening", "socket", s.socketPath, "expiration", s.expiration)
+	s.startBackground(ctx)
+
+	if s.tcpListener != nil {
+		slog.Info("daemon listening", "address", s.tcpListener.Addr().String())
+		go func() {
+			if err := s.httpServer.Serve(s.tcpListener); err != nil && err != http.ErrServerClosed {
+				slog.Error("serving TCP", "error", err)
+			}
+		}()
+	}
 
 	if err := s.httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
 		return fmt.Errorf("serving: %w", err)
 	}
+	<-s.stopped
 	return nil
 }
 
+// routes builds the mux serving every rpc route, on both its versioned and
+// unversioned path, and records the endpoints it serves.
+func (s *Server) routes() *http.ServeMux {
+	handlers := map[string]http.HandlerFunc{
+		"POST /add-crates":       s.handleAddCrates,
+		"POST /search":           s.handleSearch,
+		"POST /get-doc":          s.handleGetDoc,
+		"POST /build-context":    s.handleBuildContext,
+		"POST /summarize":        s.handleSummarize,
+		"POST /context":          s.handleContext,
+		"POST /diff":             s.handleDiff,
+		"POST /deps":             s.handleDeps,
+		"POST /similar":          s.handleSimilar,
+		"POST /symbols":          s.handleSymbols,
+		"POST /verify":           s.handleVerify,
+		"POST /export":           s.handleExport,
+		"POST /snapshot":         s.handleSnapshot,
+		"POST /restore":          s.handleRestore,
+		"POST /repack":           s.handleRepack,
+		"POST /reembed":          s.handleReembed,
+		"POST /reindex":          s.handleReindex,
+		"POST /watch":            s.handleWatch,
+		"GET /status":            s.handleStatus,
+		"POST /search-crates":    s.handleSearchCrates,
+		"POST /reload-config":    s.handleReloadConfig,
+		"POST /clear-cache":      s.handleClearCache,
+		"GET /version":           s.handleVersion,
+		"GET /capabilities":      s.handleCapabilities,
+		"GET /health":            s.handleHealth,
+		"GET /stats":             s.handleStats,
+		"POST /coverage":         s.handleCoverage,
+		"GET /openapi.json":      s.handleOpenAPI,
+		"GET /namespaces":        s.handleNamespaces,
+		"POST /delete-namespace": s.handleDeleteNamespace,
+	}
+	mux := http.NewServeMux()
+	for _, route := range rpc.Routes {
+		var handler http.HandlerFunc
+		if route.Pattern == "POST /shutdown" {
+			// Shutting down shouldn't keep the daemon alive longer.
+			handler = negotiate(route, s.handleShutdown)
+		} else if h, ok := handlers[route.Pattern]; ok {
+			handler = s.withExpReset(negotiate(route, h))
+		} else {
+			panic("no handler for " + route.Pattern)
+		}
+		// Unversioned paths stay as aliases for clients that predate /v1.
+		mux.HandleFunc(route.Method()+" "+route.Path(), handler)
+		mux.HandleFunc(route.Pattern, handler)
+		s.endpoints = append(s.endpoints, route.Pattern)
+	}
+
+	return mux
+}
+
+// startBackground starts the work the daemon does besides answering
+// requests: provider checks, preloading and watching the config file.
+func (s *Server) startBackground(ctx context.Context) {
+	if cfg := s.live().cfg; cfg.Daemon.ProviderCheckMinutes > 0 && !s.vcr && cfg.VoyageAI.ApiKey.Value != "" {
+		go s.monitorProvider(ctx, time.Duration(cfg.Daemon.ProviderCheckMinutes)*time.Minute)
+	}
+	if s.live().cfg.Daemon.Preload {
+		go s.preload(ctx)
+	}
+	s.watchConfig()
+}
+
+// Stop shuts the server down gracefully. New requests are refused while
+// in-flight requests and background indexing get until ctx ends to finish;
+// anything still running is then cancelled, keeping the items and
+// embeddings it has stored. Only then are the socket and database closed,
+// so another daemon can't open them while this one is still writing.
 func (s *Server) Stop(ctx context.Context) error {
+	var err error
+	s.stopOnce.Do(func() {
+		err = s.stop(ctx)
+		close(s.stopped)
+	})
+	return err
+}
+
+// stopAsync starts Stop with DrainTimeout without waiting for it.
+func (s *Server) stopAsync() {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), DrainTimeout)
+		defer cancel()
+		s.Stop(ctx)
+	}()
+}
+
+func (s *Server) stop(ctx context.Context) error {
+	s.stopping.Store(true)
+	s.mu.Lock()
+	if s.expTimer != nil {
+		s.expTimer.Stop()
+	}
+	s.mu.Unlock()
+
+	if n := s.activeOps.Load(); n > 0 {
+		slog.Info("draining before shutdown", "active_ops", n)
+	}
+	if !s.waitIdle(ctx) {
+		slog.Warn("cancelling unfinished work", "active_ops", s.activeOps.Load())
+		s.cancelWork()
+		grace, cancel := context.WithTimeout(context.Background(), cancelGrace)
+		s.waitIdle(grace)
+		cancel()
+	}
+	s.cancelWork()
+
 	var errs []error
 	if s.httpServer != nil {
-		if err := s.httpServer.Shutdown(ctx); err != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cancelGrace)
+		defer cancel()
+		if err := s.httpServer.Shutdown(shutdownCtx); err != nil {
 			slog.Error("shutdown error", "error", err)
 			errs = append(errs, err)
 		}
 	}
 	if s.listener != nil {
-		if err := s.listener.Close(); err != nil {
+		if err := s.listener.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
 			slog.Error("listener close error", "error", err)
 			errs = append(errs, err)
 		}
 	}
-	if err := os.Remove(s.socketPath); err != nil && !os.IsNotExist(err) {
-		slog.Error("socket remove error", "error", err)
-		errs = append(errs, err)
-	}
 	if err := s.db.Close(); err != nil {
 		slog.Error("db close error", "error", err)
 		errs = append(errs, err)
 	}
+	if !s.activated {
+		if err := os.Remove(s.socketPath); err != nil && !os.IsNotExist(err) {
+			slog.Error("socket remove error", "error", err)
+			errs = append(errs, err)
+		}
+	}
+	if s.lock != nil {
+		s.lock.Close()
+	}
+	slog.Info("daemon stopped")
 	return errors.Join(errs...)
 }
 
+// waitIdle waits for activeOps to reach zero, reporting false if ctx ends
+// first.
+func (s *Server) waitIdle(ctx context.Context) bool {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for s.activeOps.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+	return true
+}
+
 func (s *Server) expire() {
 	if n := s.activeOps.Load(); n > 0 {
 		slog.Info("expiration deferred", "active_ops", n)
 		s.resetExpiration()
 		return
 	}
+	if n := s.watching(); n > 0 {
+		slog.Info("expiration deferred", "watches", n)
+		s.resetExpiration()
+		return
+	}
 	slog.Info("expiring due to inactivity")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	s.Stop(ctx)
-	os.Exit(0)
+	s.stopAsync()
 }
 
 func (s *Server) resetExpiration() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if s.expTimer != nil {
+	if s.expTimer != nil && !s.stopping.Load() {
 		s.expTimer.Stop()
 		s.expTimer.Reset(s.expiration)
 	}
@@ -167,6 +448,11 @@ func (s *Server) resetExpiration() {
 func (s *Server) withExpReset(handler http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		s.activeOps.Add(1)
+		if s.stopping.Load() {
+			s.activeOps.Add(-1)
+			writeError(w, http.StatusServiceUnavailable, "daemon is shutting down; try again in a moment")
+			return
+		}
 		defer func() {
 			s.activeOps.Add(-1)
 			s.resetExpiration()
@@ -181,13 +467,73 @@ func (s *Server) handleAddCrates(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	ctx, ok := scoped(w, r, req.Namespace)
+	if !ok {
+		return
+	}
+
+	send := progressStream(w)
+
+	var deadline time.Time
+	if req.MaxDuration != "" {
+		d, err := time.ParseDuration(req.MaxDuration)
+		if err != nil {
+			send(rpc.ProgressLine{Type: "progress", Message: fmt.Sprintf("ignoring max_duration: %v", err)})
+		} else {
+			deadline = time.Now().Add(d)
+		}
+	}
 
+	progress := func(msg string) {
+		send(rpc.ProgressLine{Type: "progress", Message: msg})
+	}
+	seen := make(map[string]bool, len(req.Crates))
+	for _, spec := range req.Crates {
+		seen[spec.Name] = true
+	}
+	queue := req.Crates
+	for depth := 0; len(queue) > 0; depth++ {
+		if depth > 0 && !deadline.IsZero() && time.Now().After(deadline) {
+			progress(fmt.Sprintf("time box reached, not indexing %d more dependencies", len(queue)))
+			return
+		}
+		var next []rpc.CrateSpec
+		for _, spec := range queue {
+			var result rpc.CrateResult
+			if deadline.IsZero() {
+				result = s.addCrate(ctx, spec, progress)
+			} else {
+				result = s.addCrateWithin(ctx, spec, time.Until(deadline), progress)
+			}
+			if !send(rpc.ProgressLine{Type: "result", Result: &result}) {
+				return
+			}
+			if depth >= req.DependencyDepth || result.Error != "" {
+				continue
+			}
+			for _, dep := range s.dependencySpecs(ctx, result, spec.Registry) {
+				if !seen[dep.Name] {
+					seen[dep.Name] = true
+					next = append(next, dep)
+				}
+			}
+		}
+		if len(next) > 0 {
+			progress(fmt.Sprintf("indexing %d dependencies at depth %d", len(next), depth+1))
+		}
+		queue = next
+	}
+}
+
+// progressStream starts an NDJSON response and returns a function that
+// writes one line to it. The function reports false once the client is gone.
+func progressStream(w http.ResponseWriter) func(rpc.ProgressLine) bool {
 	flusher, _ := w.(http.Flusher)
 	w.Header().Set("Content-Type", "application/x-ndjson")
 	w.WriteHeader(http.StatusOK)
 
 	enc := json.NewEncoder(w)
-	send := func(line rpc.ProgressLine) bool {
+	return func(line rpc.ProgressLine) bool {
 		slog.Info(line.Message)
 		if err := enc.Encode(line); err != nil {
 			slog.Warn("client disconnected", "error", err)
@@ -198,37 +544,143 @@ func (s *Server) handleAddCrates(w http.ResponseWriter, r *http.Request) {
 		}
 		return true
 	}
+}
+
+// addCrateWithin runs addCrate but stops waiting after budget. Unfinished
+// work carries on in the background with progress going to the log; items
+// and embedding batches it has already stored stay searchable, and the
+// returned result is marked Partial. Cancelling ctx before the budget runs out
+// cancels the work too.
+func (s *Server) addCrateWithin(ctx context.Context, spec rpc.CrateSpec, budget time.Duration, progress func(string)) rpc.CrateResult {
+	var mu sync.Mutex
+	detached := false
+	guarded := func(msg string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if detached {
+			slog.Info(msg, "source", "background", "crate", spec.Name)
+			return
+		}
+		progress(msg)
+	}
 
-	for _, spec := range req.Crates {
-		progress := func(msg string) {
-			send(rpc.ProgressLine{Type: "progress", Message: msg})
+	// The work follows ctx only until it is detached, and the server's
+	// workCtx after that.
+	workCtx, cancel := context.WithCancel(withNamespace(s.workCtx, namespaceOf(ctx)))
+	stop := context.AfterFunc(ctx, cancel)
+
+	s.activeOps.Add(1)
+	done := make(chan rpc.CrateResult, 1)
+	go func() {
+		defer func() {
+			cancel()
+			s.activeOps.Add(-1)
+			s.resetExpiration()
+		}()
+		done <- s.addCrate(workCtx, spec, guarded)
+	}()
+
+	timer := time.NewTimer(max(budget, 0))
+	defer timer.Stop()
+	select {
+	case result := <-done:
+		return result
+	case <-timer.C:
+	}
+	if !stop() {
+		// ctx ended first, so the work is already winding down.
+		return <-done
+	}
+
+	mu.Lock()
+	detached = true
+	mu.Unlock()
+	s.trackBackground(spec.Name, 1)
+	go func() {
+		r := <-done
+		s.trackBackground(spec.Name, -1)
+		if r.Error != "" {
+			slog.Error("background indexing failed", "crate", r.Name, "version", r.Version, "error", r.Error)
+		} else {
+			slog.Info("background indexing finished", "crate", r.Name, "version", r.Version, "items", r.Items)
 		}
-		result := s.addCrate(spec, progress)
-		if !send(rpc.ProgressLine{Type: "result", Result: &result}) {
-			return
+	}()
+
+	result := rpc.CrateResult{Name: spec.Name, Version: spec.Version, Partial: true}
+	if result.Version == "" || result.Version == "latest" {
+		result.Version = "latest"
+		if entry, ok := s.getCachedVersion(spec.Registry, spec.Name); ok && !entry.notFound {
+			result.Version = entry.version
 		}
 	}
+	if c, err := s.index(ctx).GetRegistryCrate(spec.Registry, spec.Name, result.Version); err == nil && c != nil {
+		result.Items, _ = s.db.CountItems(c.ID)
+	}
+	progress(fmt.Sprintf("time box reached, %s@%s continues indexing in the background", spec.Name, result.Version))
+	return result
+}
+
+func (s *Server) trackBackground(name string, delta int) {
+	s.backgroundMu.Lock()
+	defer s.backgroundMu.Unlock()
+	if s.background[name] += delta; s.background[name] <= 0 {
+		delete(s.background, name)
+	}
+}
+
+// autoFetch adds a crate on behalf of search or get-doc, bounded by
+// daemon.auto_fetch_max_seconds when set.
+func (s *Server) autoFetch(ctx context.Context, name, version string) rpc.CrateResult {
+	spec := rpc.CrateSpec{Name: name, Version: version}
+	progress := func(msg string) {
+		slog.Info(msg, "source", "auto-fetch")
+	}
+	if secs := s.live().cfg.Daemon.AutoFetchMaxSeconds; secs > 0 {
+		return s.addCrateWithin(ctx, spec, time.Duration(secs)*time.Second, progress)
+	}
+	return s.addCrate(ctx, spec, progress)
 }
 
 const versionCacheTTL = 10 * time.Minute
 
-func (s *Server) getCachedVersion(name string) (versionCacheEntry, bool) {
+// registryName keys caches and in-flight adds by crate: the name alone for
+// crates.io, otherwise prefixed with the registry so that a crate from an
+// alternative registry is never mistaken for the crates.io one.
+func registryName(registry, name string) string {
+	if registry == "" {
+		return name
+	}
+	return registry + "/" + name
+}
+
+func (s *Server) getCachedVersion(registry, name string) (versionCacheEntry, bool) {
 	s.versionCacheMu.RLock()
 	defer s.versionCacheMu.RUnlock()
-	entry, ok := s.versionCache[name]
+	entry, ok := s.versionCache[registryName(registry, name)]
 	if !ok || time.Now().After(entry.expiry) {
 		return versionCacheEntry{}, false
 	}
 	return entry, true
 }
 
-func (s *Server) setCachedVersion(name, version string, notFound bool) {
+func (s *Server) setCachedVersion(registry, name, version string) {
 	s.versionCacheMu.Lock()
 	defer s.versionCacheMu.Unlock()
-	s.versionCache[name] = versionCacheEntry{
-		version:  version,
-		notFound: notFound,
-		expiry:   time.Now().Add(versionCacheTTL),
+	s.versionCache[registryName(registry, name)] = versionCacheEntry{
+		version: version,
+		expiry:  time.Now().Add(versionCacheTTL),
+	}
+}
+
+// cacheNotFound remembers that name couldn't be fetched from registry, along
+// with the similar names to suggest instead.
+func (s *Server) cacheNotFound(registry, name string, suggestions []string) {
+	s.versionCacheMu.Lock()
+	defer s.versionCacheMu.Unlock()
+	s.versionCache[registryName(registry, name)] = versionCacheEntry{
+		notFound:    true,
+		suggestions: suggestions,
+		expiry:      time.Now().Add(versionCacheTTL),
 	}
 }
 
@@ -239,8 +691,8 @@ func (s *Server) clearVersionCache() {
 }
 
 // getCachedCrate returns a cached RustdocCrate, checking in-memory first then disk.
-func (s *Server) getCachedCrate(name, version string) *docs.RustdocCrate {
-	key := name + "@" + version
+func (s *Server) getCachedCrate(registry, name, version string) *docs.RustdocCrate {
+	key := registryName(registry, name) + "@" + version
 	s.crateCacheMu.RLock()
 	c, ok := s.crateCache[key]
 	s.crateCacheMu.RUnlock()
@@ -248,7 +700,7 @@ func (s *Server) getCachedCrate(name, version string) *docs.RustdocCrate {
 		return c
 	}
 
-	c, err := docs.LoadCrateCache(name, version)
+	c, err := docs.LoadCrateCache(registry, name, version)
 	if err != nil {
 		return nil
 	}
@@ -259,7 +711,14 @@ func (s *Server) getCachedCrate(name, version string) *docs.RustdocCrate {
 	return c
 }
 
-func (s *Server) addCrate(spec rpc.CrateSpec, progress func(string)) rpc.CrateResult {
+// addCrate fetches and indexes a crate unless it is already indexed.
+// Cancelling ctx stops the work between stages and batches once no other
+// caller is waiting on the same crate; whatever was stored by then is kept and
+// the crate stays unprocessed, so the next add picks it up again.
+func (s *Server) addCrate(ctx context.Context, spec rpc.CrateSpec, progress func(string)) rpc.CrateResult {
+	s.writes.RLock()
+	defer s.writes.RUnlock()
+
 	version := spec.Version
 	if version == "" {
 		version = "latest"
@@ -267,16 +726,27 @@ func (s *Server) addCrate(spec rpc.CrateSpec, progress func(string)) rpc.CrateRe
 
 	result := rpc.CrateResult{Name: spec.Name, Version: version}
 
+	reg, err := docs.LookupRegistry(spec.Registry)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	if len(spec.RustdocJSON) > 0 {
+		return s.importCrate(ctx, reg, spec, progress)
+	}
+
 	if !spec.Force {
 		// Check version cache for "latest" requests
 		if version == "latest" {
-			if entry, ok := s.getCachedVersion(spec.Name); ok {
+			if entry, ok := s.getCachedVersion(reg.Name, spec.Name); ok {
 				if entry.notFound {
-					result.Error = fmt.Sprintf("crate %s not found on docs.rs (cached)", spec.Name)
+					result.Error = fmt.Sprintf("crate %s not found on docs.rs (cached)%s", spec.Name, didYouMean(entry.suggestions))
+					result.Suggestions = entry.suggestions
 					return result
 				}
 				// Use cached real version — check DB
-				existing, err := s.db.GetCrate(spec.Name, entry.version)
+				existing, err := s.index(ctx).GetRegistryCrate(reg.Name, spec.Name, entry.version)
 				if err != nil {
 					result.Error = err.Error()
 					return result
@@ -291,7 +761,7 @@ func (s *Server) addCrate(spec rpc.CrateSpec, progress func(string)) rpc.CrateRe
 
 		// For "latest", check if we already have any processed version in DB
 		if version == "latest" {
-			existing, err := s.db.GetLatestCrate(spec.Name)
+			existing, err := s.index(ctx).GetLatestRegistryCrate(reg.Name, spec.Name)
 			if err != nil {
 				result.Error = err.Error()
 				return result
@@ -303,7 +773,7 @@ func (s *Server) addCrate(spec rpc.CrateSpec, progress func(string)) rpc.CrateRe
 			}
 		} else {
 			// Exact version: check if already processed
-			existing, err := s.db.GetCrate(spec.Name, version)
+			existing, err := s.index(ctx).GetRegistryCrate(reg.Name, spec.Name, version)
 			if err != nil {
 				result.Error = err.Error()
 				return result
@@ -316,31 +786,103 @@ func (s *Server) addCrate(spec rpc.CrateSpec, progress func(string)) rpc.CrateRe
 	}
 
 	// Singleflight: dedup concurrent fetches for the same crate@version
-	key := spec.Name + "@" + version
-	v, _, _ := s.addCrateGroup.Do(key, func() (interface{}, error) {
-		return s.addCrateWork(spec.Name, version, spec.Force, progress), nil
+	return s.addOnce(ctx, registryName(reg.Name, spec.Name)+"@"+version, func(ctx context.Context) rpc.CrateResult {
+		return s.addCrateWork(ctx, reg, spec.Name, version, addOptions{force: spec.Force, overBudget: spec.OverBudget, ignore: spec.Ignore}, progress)
 	})
-	return v.(rpc.CrateResult)
+}
+
+// sharedAdd is the context of one in-flight addCrateGroup call and the
+// number of callers waiting on it.
+type sharedAdd struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	waiters int
+}
+
+// addOnce runs work through addCrateGroup under key within ctx's namespace.
+// The work gets a context of its own that is cancelled when every caller's
+// ctx is done, so one client disconnecting doesn't abort an add another
+// client is waiting on, or when Stop gives up draining.
+func (s *Server) addOnce(ctx context.Context, key string, work func(context.Context) rpc.CrateResult) rpc.CrateResult {
+	key = namespaceOf(ctx) + "/" + key
+	s.addCrateRunsMu.Lock()
+	run := s.addCrateRuns[key]
+	if run == nil {
+		runCtx, cancel := context.WithCancel(withNamespace(s.workCtx, namespaceOf(ctx)))
+		run = &sharedAdd{ctx: runCtx, cancel: cancel}
+		s.addCrateRuns[key] = run
+	}
+	run.waiters++
+	ch := s.addCrateGroup.DoChan(key, func() (interface{}, error) {
+		return work(run.ctx), nil
+	})
+	s.addCrateRunsMu.Unlock()
+
+	leave := func() {
+		s.addCrateRunsMu.Lock()
+		defer s.addCrateRunsMu.Unlock()
+		if run.waiters--; run.waiters == 0 {
+			run.cancel()
+			if s.addCrateRuns[key] == run {
+				delete(s.addCrateRuns, key)
+			}
+		}
+	}
+	stop := context.AfterFunc(ctx, leave)
+	// Wait for the work even when ctx ends: callers hold s.writes, and
+	// cancelled work returns at its next checkpoint.
+	res := <-ch
+	if stop() {
+		leave()
+	}
+	return res.Val.(rpc.CrateResult)
 }
 
 type embeddable struct {
 	contentHash string
 	preamble    string
 	docLinks    map[string]string // only set for main item docs
+	example     bool              // embed as a single code chunk instead of splitting sections
 }
 
-func (s *Server) addCrateWork(name, version string, force bool, progress func(string)) rpc.CrateResult {
+// addOptions are the per-add settings of addCrateWork.
+type addOptions struct {
+	force      bool // index again even if already processed
+	overBudget bool // embed past the embedding budgets
+	// data, if non-nil, is the decoded rustdoc JSON to index instead of
+	// fetching from the registry, and dataSource the source to record for
+	// the crate: db.SourceFile for an import, or what was recorded before
+	// for a re-index from the JSON cache.
+	data       []byte
+	dataSource string
+	ignore     *rpc.IgnoreOptions // adjusts index.ignore for this crate
+}
+
+// addCrateWork fetches and indexes a crate as opts says. It checks ctx
+// between stages; a cancelled add leaves the crate unprocessed.
+func (s *Server) addCrateWork(ctx context.Context, reg *docs.Registry, name, version string, opts addOptions, progress func(string)) rpc.CrateResult {
 	result := rpc.CrateResult{Name: name, Version: version}
 
-	realVersion, rustdocCrate, items, err := s.resolveVersion(name, version, progress)
+	realVersion, rustdocCrate, items, err := s.resolveVersion(ctx, reg, name, version, opts.data, progress)
 	if err != nil {
+		if ctx.Err() != nil {
+			return cancelledResult(result, err, progress)
+		}
 		result.Error = err.Error()
+		var nf *crateNotFoundError
+		if errors.As(err, &nf) {
+			result.Suggestions = nf.suggestions
+		}
+		var nj *noJSONError
+		if errors.As(err, &nj) {
+			result.SuggestedVersion = nj.suggested
+		}
 		return result
 	}
 
 	// Check if this resolved version is already processed
-	if !force && realVersion != version {
-		existing, err := s.db.GetCrate(name, realVersion)
+	if !opts.force && realVersion != version {
+		existing, err := s.index(ctx).GetRegistryCrate(reg.Name, name, realVersion)
 		if err != nil {
 			result.Error = err.Error()
 			return result
@@ -348,27 +890,68 @@ func (s *Server) addCrateWork(name, version string, force bool, progress func(st
 		if existing != nil && existing.ProcessedAt != nil {
 			result.Version = realVersion
 			result.Items, _ = s.db.CountItems(existing.ID)
-			s.setCachedVersion(name, realVersion, false)
+			s.setCachedVersion(reg.Name, name, realVersion)
 			return result
 		}
 	}
 	result.Version = realVersion
-	s.setCachedVersion(name, realVersion, false)
+	s.setCachedVersion(reg.Name, name, realVersion)
 
-	crate, err := s.db.UpsertCrate(name, realVersion)
+	crate, err := s.index(ctx).UpsertRegistryCrate(reg.Name, name, realVersion)
 	if err != nil {
 		result.Error = fmt.Sprintf("upserting crate: %v", err)
 		return result
 	}
 	s.db.MarkCrateFetched(crate.ID)
+	if err := ctx.Err(); err != nil {
+		return cancelledResult(result, err, progress)
+	}
+	source := ""
+	switch {
+	case opts.data != nil:
+		source = opts.dataSource
+	case rustdocCrate == nil:
+		source = db.SourceHTML
+	}
+	if err := s.db.SetCrateSource(crate.ID, reg.Name, source); err != nil {
+		slog.Error("failed to record crate source", "crate", name, "version", realVersion, "error", err)
+	}
 
-	toEmbed, err := s.indexItems(crate, rustdocCrate, items, name, progress)
+	if kept, n := docs.FilterItems(rustdocCrate, items, s.ignoreFor(opts.ignore)); n > 0 {
+		progress(fmt.Sprintf("leaving out %d ignored items of %s@%s", n, name, realVersion))
+		items = kept
+	}
+
+	toEmbed, err := s.indexItems(ctx, crate, rustdocCrate, items, name, progress)
 	if err != nil {
+		if ctx.Err() != nil {
+			return cancelledResult(result, err, progress)
+		}
 		result.Error = err.Error()
 		return result
 	}
+	if source != db.SourceFile {
+		if err := s.recordDependencies(ctx, reg, crate, rustdocCrate); err != nil {
+			slog.Warn("failed to record dependencies", "crate", name, "version", realVersion, "error", err)
+		}
+		if meta, err := s.recordCrateInfo(ctx, reg, crate); err != nil {
+			slog.Warn("failed to record crate info", "crate", name, "error", err)
+		} else if meta != nil {
+			toEmbed = append(toEmbed, *meta)
+		}
+	}
 
-	if err := s.embedItems(toEmbed, name, realVersion, progress); err != nil {
+	tokens, err := s.embedItems(ctx, toEmbed, crate, opts.overBudget, progress)
+	result.Tokens = tokens
+	result.Cost = s.live().cfg.VoyageAI.Cost(int64(tokens))
+	if err != nil {
+		if ctx.Err() != nil {
+			return cancelledResult(result, err, progress)
+		}
+		var be *budgetError
+		if errors.As(err, &be) {
+			result.EstimatedTokens = int(be.estimate)
+		}
 		result.Error = err.Error()
 		return result
 	}
@@ -379,25 +962,179 @@ func (s *Server) addCrateWork(name, version string, force bool, progress func(st
 	return result
 }
 
-// resolveVersion fetches rustdoc JSON, parses it, and resolves "latest" to a real version.
-func (s *Server) resolveVersion(name, version string, progress func(string)) (string, *docs.RustdocCrate, []docs.ParsedItem, error) {
-	progress(fmt.Sprintf("fetching rustdoc for %s@%s", name, version))
-	data, err := docs.FetchRustdocJSON(name, version)
+// cancelledResult marks result as a cancelled add. The items and embedding
+// batches stored before cancellation are kept; the crate stays unprocessed so
+// the next add finishes it, re-using the embeddings already stored.
+func cancelledResult(result rpc.CrateResult, err error, progress func(string)) rpc.CrateResult {
+	result.Error = fmt.Sprintf("indexing cancelled: %v", err)
+	progress(fmt.Sprintf("cancelled indexing %s@%s", result.Name, result.Version))
+	return result
+}
+
+// noJSONError is a release without rustdoc JSON that can be indexed, with
+// why, when docs.rs or the registry could say, and a release that has some.
+type noJSONError struct {
+	name, version string
+	reason        string
+	suggested     string
+	err           error
+}
+
+func (e *noJSONError) Error() string {
+	msg := e.err.Error()
+	if e.reason != "" {
+		msg = fmt.Sprintf("%s@%s has no rustdoc JSON that can be indexed: %s", e.name, e.version, e.reason)
+	}
+	if e.suggested != "" {
+		msg += fmt.Sprintf("; try %s@%s", e.name, e.suggested)
+	}
+	return msg
+}
+
+func (e *noJSONError) Unwrap() error { return e.err }
+
+// noUsableJSON looks into why name@version has no rustdoc JSON that can be
+// indexed: JSON too old to read, a failed docs.rs build, or a yanked or
+// missing release. It returns err unchanged if it learns nothing. Lookup
+// failures only cost the explanation.
+func (s *Server) noUsableJSON(ctx context.Context, reg *docs.Registry, name, version string, err error) error {
+	e := &noJSONError{name: name, version: version, err: err}
+	var formatErr *docs.FormatVersionError
+	if errors.As(err, &formatErr) {
+		e.reason = formatErr.Error()
+	} else if status, err := docs.FetchBuildStatus(ctx, reg, name, version); err == nil {
+		if status.Version != "" {
+			e.version = status.Version
+		}
+		if !status.Built {
+			e.reason = "its docs.rs build failed"
+		}
+	}
+
+	releases, err := docs.FetchReleases(ctx, reg, name)
 	if err != nil {
-		if version == "latest" {
-			s.setCachedVersion(name, "", true)
+		slog.Warn("release lookup failed", "crate", name, "error", err)
+	} else {
+		if e.reason == "" && e.version != "latest" {
+			i := slices.IndexFunc(releases, func(r docs.Release) bool { return r.Version == e.version })
+			switch {
+			case i < 0:
+				e.reason = "there is no such release"
+			case releases[i].Yanked:
+				e.reason = "it was yanked"
+			}
 		}
+		if e.suggested, err = docs.NewestWithJSON(ctx, reg, name, releases, e.version); err != nil {
+			slog.Warn("rustdoc JSON lookup failed", "crate", name, "error", err)
+		}
+	}
+	if e.reason == "" && e.suggested == "" {
+		return e.err
+	}
+	return e
+}
+
+// crateNotFoundError is a crate the docs host has never heard of, with
+// existing crates whose names are close to it.
+type crateNotFoundError struct {
+	name        string
+	suggestions []string
+	err         error
+}
+
+func (e *crateNotFoundError) Error() string {
+	return fmt.Sprintf("crate %s not found%s", e.name, didYouMean(e.suggestions))
+}
+
+func (e *crateNotFoundError) Unwrap() error { return e.err }
+
+// didYouMean formats suggestions as "; did you mean a, b or c?", or returns
+// "" without any.
+func didYouMean(suggestions []string) string {
+	switch len(suggestions) {
+	case 0:
+		return ""
+	case 1:
+		return "; did you mean " + suggestions[0] + "?"
+	}
+	last := len(suggestions) - 1
+	return "; did you mean " + strings.Join(suggestions[:last], ", ") + " or " + suggestions[last] + "?"
+}
+
+// similarCrates returns up to three registry crates named like name, for
+// when name isn't one. Lookup failures only cost the suggestions.
+func (s *Server) similarCrates(ctx context.Context, reg *docs.Registry, name string) []string {
+	names, err := docs.SimilarCrateNames(ctx, reg, name, 3)
+	if err != nil {
+		slog.Warn("crate name suggestion lookup failed", "crate", name, "error", err)
+	}
+	return names
+}
+
+// resolveVersion fetches rustdoc JSON (unless data is already given), parses
+// it, and resolves "latest" to a real version. When docs.rs has no rustdoc
+// JSON for the release it falls back to scraping the HTML pages, returning a
+// nil RustdocCrate.
+func (s *Server) resolveVersion(ctx context.Context, reg *docs.Registry, name, version string, data []byte, progress func(string)) (string, *docs.RustdocCrate, []docs.ParsedItem, error) {
+	var err error
+	imported := data != nil
+	if !imported {
+		progress(fmt.Sprintf("fetching rustdoc for %s@%s", name, version))
+		data, err = docs.FetchRustdocJSON(ctx, reg, name, version)
+	}
+	if err != nil && ctx.Err() != nil {
+		return "", nil, nil, ctx.Err()
+	}
+	// Only a missing JSON build is worth the HTML fallback; a docs.rs outage,
+	// rate limit or timeout would fail it too, and isn't the crate's fault.
+	if err != nil && !errors.Is(err, docs.ErrNotFound) {
 		return "", nil, nil, fmt.Errorf("fetching docs: %w", err)
 	}
+	if err != nil {
+		progress(fmt.Sprintf("no rustdoc JSON for %s@%s, falling back to docs.rs HTML", name, version))
+		realVersion, items, htmlErr := docs.FetchHTMLDocs(ctx, reg, name, version, progress)
+		if htmlErr == nil {
+			return realVersion, nil, items, nil
+		}
+		if ctx.Err() != nil {
+			return "", nil, nil, ctx.Err()
+		}
+		err = fmt.Errorf("fetching docs: %w (HTML fallback: %v)", err, htmlErr)
+		var suggestions []string
+		if errors.Is(err, docs.ErrNotFound) && errors.Is(htmlErr, docs.ErrNotFound) {
+			suggestions = s.similarCrates(ctx, reg, name)
+		}
+		if len(suggestions) > 0 {
+			err = &crateNotFoundError{name: name, suggestions: suggestions, err: err}
+		} else if errors.Is(err, docs.ErrNotFound) {
+			err = s.noUsableJSON(ctx, reg, name, version, err)
+		}
+		if version == "latest" && errors.Is(htmlErr, docs.ErrNotFound) {
+			s.cacheNotFound(reg.Name, name, suggestions)
+		}
+		return "", nil, nil, err
+	}
 
 	progress(fmt.Sprintf("parsing rustdoc for %s@%s", name, version))
 	rustdocCrate, items, err := docs.Parse(data, name, version)
+	var formatErr *docs.FormatVersionError
+	if errors.As(err, &formatErr) && !imported {
+		progress(fmt.Sprintf("%s@%s: %v, falling back to docs.rs HTML", name, version, err))
+		realVersion, items, htmlErr := docs.FetchHTMLDocs(ctx, reg, name, version, progress)
+		if htmlErr == nil {
+			return realVersion, nil, items, nil
+		}
+		if ctx.Err() != nil {
+			return "", nil, nil, ctx.Err()
+		}
+		return "", nil, nil, s.noUsableJSON(ctx, reg, name, version, fmt.Errorf("parsing docs: %w (HTML fallback: %v)", err, htmlErr))
+	}
 	if err != nil {
 		return "", nil, nil, fmt.Errorf("parsing docs: %w", err)
 	}
 
 	realVersion := version
-	if rustdocCrate.CrateVersion != nil && *rustdocCrate.CrateVersion != "" {
+	if !imported && rustdocCrate.CrateVersion != nil && *rustdocCrate.CrateVersion != "" {
 		realVersion = *rustdocCrate.CrateVersion
 	}
 
@@ -410,29 +1147,60 @@ func (s *Server) resolveVersion(name, version string, progress func(string)) (st
 	}
 
 	// Cache rustdoc JSON to disk for on-the-fly fragment generation
-	if err := docs.SaveCrateCache(data, name, realVersion); err != nil {
+	if err := docs.SaveCrateCache(data, reg.Name, name, realVersion); err != nil {
 		slog.Error("failed to cache rustdoc JSON", "crate", name, "version", realVersion, "error", err)
+	} else if !imported {
+		if err := docs.ReleaseDownload(reg, name, version, realVersion); err != nil {
+			slog.Warn("failed to release download", "crate", name, "version", realVersion, "error", err)
+		}
 	}
+	s.crateCacheMu.Lock()
+	delete(s.crateCache, registryName(reg.Name, name)+"@"+realVersion)
+	s.crateCacheMu.Unlock()
 
 	return realVersion, rustdocCrate, items, nil
 }
 
+// ignoreFor returns the items to leave out of a crate: index.ignore as
+// adjusted by opts, which may be nil.
+func (s *Server) ignoreFor(opts *rpc.IgnoreOptions) docs.Ignore {
+	cfg := s.live().cfg.Index.Ignore
+	ig := docs.Ignore{Patterns: cfg.Patterns, Hidden: cfg.Hidden, Deprecated: cfg.Deprecated}
+	if opts == nil {
+		return ig
+	}
+	ig.Patterns = append(slices.Clip(ig.Patterns), opts.Patterns...)
+	if opts.Hidden != nil {
+		ig.Hidden = *opts.Hidden
+	}
+	if opts.Deprecated != nil {
+		ig.Deprecated = *opts.Deprecated
+	}
+	return ig
+}
+
 // indexItems writes items to CAS and DB, returns embeddables for the embedding phase.
-func (s *Server) indexItems(crate *db.Crate, rustdocCrate *docs.RustdocCrate, items []docs.ParsedItem, crateName string, progress func(string)) ([]embeddable, error) {
+// The crate's items are replaced in a single transaction.
+func (s *Server) indexItems(ctx context.Context, crate *db.Crate, rustdocCrate *docs.RustdocCrate, items []docs.ParsedItem, crateName string, progress func(string)) ([]embeddable, error) {
 	progress(fmt.Sprintf("parsed %d items from %s@%s", len(items), crateName, crate.Version))
 
-	s.db.DeleteItemsByCrate(crate.ID)
 	s.db.DeleteReexportsByCrate(crate.ID)
 
-	reexports := docs.CollectReexports(rustdocCrate, crateName)
-	for _, re := range reexports {
-		if err := s.db.InsertReexport(crate.ID, re.LocalPrefix, re.SourceCrate, re.SourcePrefix); err != nil {
-			slog.Error("failed to insert reexport", "local", re.LocalPrefix, "source_crate", re.SourceCrate, "source_prefix", re.SourcePrefix, "error", err)
+	if rustdocCrate != nil {
+		for _, re := range docs.CollectReexports(rustdocCrate, crateName) {
+			if err := s.db.InsertReexport(crate.ID, re.LocalPrefix, re.SourceCrate, re.SourcePrefix); err != nil {
+				slog.Error("failed to insert reexport", "local", re.LocalPrefix, "source_crate", re.SourceCrate, "source_prefix", re.SourcePrefix, "error", err)
+			}
 		}
 	}
 
-	var toEmbed []embeddable
-	for _, parsed := range items {
+	records := make([]db.ItemRecord, 0, len(items))
+	sources := make([]*docs.ParsedItem, 0, len(items))
+	for i := range items {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		parsed := &items[i]
 		var contentHash string
 		if parsed.Docs != "" {
 			h, err := cas.Write(parsed.Docs)
@@ -449,6 +1217,24 @@ func (s *Server) indexItems(crate *db.Crate, rustdocCrate *docs.RustdocCrate, it
 			docLinksJSON = string(b)
 		}
 
+		var featuresJSON string
+		if len(parsed.Features) > 0 {
+			b, _ := json.Marshal(parsed.Features)
+			featuresJSON = string(b)
+		}
+
+		var deprecationJSON string
+		if parsed.Deprecation != nil {
+			b, _ := json.Marshal(parsed.Deprecation)
+			deprecationJSON = string(b)
+		}
+
+		var stabilityJSON string
+		if parsed.Stability != nil {
+			b, _ := json.Marshal(parsed.Stability)
+			stabilityJSON = string(b)
+		}
+
 		var fragNamesJSON string
 		if len(parsed.Fragments) > 0 {
 			names := make([]string, len(parsed.Fragments))
@@ -459,7 +1245,7 @@ func (s *Server) indexItems(crate *db.Crate, rustdocCrate *docs.RustdocCrate, it
 			fragNamesJSON = string(b)
 		}
 
-		dbItem := &db.Item{
+		rec := db.ItemRecord{Item: &db.Item{
 			CrateID:       crate.ID,
 			RustdocID:     parsed.RustdocID,
 			Name:          parsed.Name,
@@ -469,19 +1255,10 @@ func (s *Server) indexItems(crate *db.Crate, rustdocCrate *docs.RustdocCrate, it
 			Signature:     parsed.Signature,
 			DocLinks:      docLinksJSON,
 			FragmentNames: fragNamesJSON,
-		}
-		if err := s.db.InsertItem(dbItem); err != nil {
-			slog.Error("failed to insert item", "path", parsed.Path, "error", err)
-			continue
-		}
-
-		if contentHash != "" {
-			preamble := parsed.Path
-			if parsed.Signature != "" {
-				preamble += "\n" + parsed.Signature
-			}
-			toEmbed = append(toEmbed, embeddable{contentHash: contentHash, preamble: preamble, docLinks: parsed.DocLinks})
-		}
+			Features:      featuresJSON,
+			Deprecation:   deprecationJSON,
+			Stability:     stabilityJSON,
+		}}
 
 		for _, frag := range parsed.Fragments {
 			if frag.Content == "" {
@@ -492,19 +1269,66 @@ func (s *Server) indexItems(crate *db.Crate, rustdocCrate *docs.RustdocCrate, it
 				slog.Error("failed to write CAS for fragment", "path", parsed.Path, "fragment", frag.Name, "error", err)
 				continue
 			}
-			toEmbed = append(toEmbed, embeddable{contentHash: fragHash, preamble: parsed.Path + "#" + frag.Name})
+			rec.Fragments = append(rec.Fragments, db.Fragment{Name: frag.Name, ContentHash: fragHash})
+		}
+
+		for i, code := range parsed.Examples {
+			exHash, err := cas.Write(code)
+			if err != nil {
+				slog.Error("failed to write CAS for example", "path", parsed.Path, "example", i, "error", err)
+				continue
+			}
+			rec.Examples = append(rec.Examples, db.Example{Index: i, ContentHash: exHash})
+		}
+
+		records = append(records, rec)
+		sources = append(sources, parsed)
+	}
+
+	if err := s.db.ReplaceCrateItems(crate.ID, records); err != nil {
+		return nil, fmt.Errorf("storing items: %w", err)
+	}
+
+	var toEmbed []embeddable
+	for i, rec := range records {
+		if rec.Item.ID == 0 {
+			continue
+		}
+		parsed := sources[i]
+		if rec.Item.ContentHash != "" {
+			preamble := parsed.Path
+			if parsed.Signature != "" {
+				preamble += "\n" + parsed.Signature
+			}
+			toEmbed = append(toEmbed, embeddable{contentHash: rec.Item.ContentHash, preamble: preamble, docLinks: parsed.DocLinks})
+		}
+		for _, f := range rec.Fragments {
+			if f.ID != 0 {
+				toEmbed = append(toEmbed, embeddable{contentHash: f.ContentHash, preamble: parsed.Path + "#" + f.Name})
+			}
+		}
+		for _, ex := range rec.Examples {
+			if ex.ID != 0 {
+				toEmbed = append(toEmbed, embeddable{contentHash: ex.ContentHash, preamble: parsed.Path + " example", example: true})
+			}
 		}
 	}
 
 	return toEmbed, nil
 }
 
-// embedItems chunks, deduplicates, and embeds document content.
-func (s *Server) embedItems(toEmbed []embeddable, name, version string, progress func(string)) error {
-	model := s.cfg.VoyageAI.Model
-	if model == "" {
-		model = "voyage-3.5"
-	}
+// chunkOptions returns the index settings content is chunked with.
+func (s *Server) chunkOptions() embeddings.ChunkOptions {
+	index := s.live().cfg.Index
+	return embeddings.ChunkOptions{MaxTokens: index.ChunkMaxTokens, OverlapTokens: index.ChunkOverlapTokens}
+}
+
+// embedItems chunks, deduplicates, and embeds document content, recording
+// and returning the tokens the provider billed. Unless overBudget is set it
+// embeds nothing when that would go over an embedding budget.
+func (s *Server) embedItems(ctx context.Context, toEmbed []embeddable, crate *db.Crate, overBudget bool, progress func(string)) (int, error) {
+	model := s.embeddingModel()
+	name, version := crate.Name, crate.Version
 
 	type chunkMeta struct {
 		contentHash string
@@ -512,12 +1336,17 @@ func (s *Server) embedItems(toEmbed []embeddable, name, version string, progress
 		chunkText   string
 	}
 
+	// Content embedded by another model or chunker version is embedded
+	// again; InsertEmbeddings replaces the old chunks as the new ones are
+	// stored, so a refused or cancelled add leaves them searchable.
+	chunkOpts := s.chunkOptions()
+	chunker := chunkOpts.Version()
 	needsEmbedding := make(map[string]bool)
 	for _, e := range toEmbed {
 		if _, seen := needsEmbedding[e.contentHash]; seen {
 			continue
 		}
-		needsEmbedding[e.contentHash] = !s.db.HasEmbeddings(e.contentHash)
+		needsEmbedding[e.contentHash] = !s.db.HasCurrentEmbeddings(e.contentHash, model, chunker)
 	}
 
 	skipped := 0
@@ -545,9 +1374,13 @@ func (s *Server) embedItems(toEmbed []embeddable, name, version string, progress
 			continue
 		}
 
-		docsText = md.RewriteLinks(docsText, e.docLinks)
-
-		chunks := embeddings.ChunkSections(e.preamble, docsText)
+		var chunks []embeddings.Chunk
+		if e.example {
+			chunks = []embeddings.Chunk{{Text: e.preamble + "\n\n```rust\n" + docsText + "\n```", Index: 0}}
+		} else {
+			docsText = md.RewriteLinks(docsText, e.docLinks)
+			chunks = embeddings.ChunkSectionsWith(e.preamble, docsText, chunkOpts)
+		}
 		for _, chunk := range chunks {
 			allTexts = append(allTexts, chunk.Text)
 			metas = append(metas, chunkMeta{
@@ -559,26 +1392,41 @@ func (s *Server) embedItems(toEmbed []embeddable, name, version string, progress
 	}
 
 	if len(allTexts) == 0 {
-		return nil
+		return 0, nil
+	}
+	if !overBudget {
+		if err := s.checkBudget(int64(embeddings.EstimateTokens(allTexts...))); err != nil {
+			return 0, err
+		}
 	}
 
+	// Embeddings are stored batch by batch so a time-boxed add that returns
+	// early already has its finished batches searchable.
 	progress(fmt.Sprintf("embedding %d chunks for %s@%s", len(allTexts), name, version))
-	allEmbeddings, err := s.batchEmbedder.EmbedAll(allTexts, model, func(done, total int) {
-		progress(fmt.Sprintf("embedded %d/%d chunks for %s@%s", done, total, name, version))
+	embedded := 0
+	tokens, err := s.live().batchEmbedder.EmbedBatches(ctx, allTexts, model, func(offset int, batch [][]float32) error {
+		records := make([]db.EmbeddingRecord, len(batch))
+		for j, emb := range batch {
+			meta := metas[offset+j]
+			records[j] = db.EmbeddingRecord{ContentHash: meta.contentHash, ChunkText: meta.chunkText, ChunkIndex: meta.chunkIndex, Embedding: emb, Model: model, ChunkerVersion: chunker}
+		}
+		if err := s.db.InsertEmbeddings(records); err != nil {
+			slog.Error("failed to store embeddings", "crate", name, "version", version, "chunks", len(records), "error", err)
+		}
+		embedded += len(batch)
+		progress(fmt.Sprintf("embedded %d/%d chunks for %s@%s", embedded, len(allTexts), name, version))
+		return nil
 	})
-	if err != nil {
-		return fmt.Errorf("embedding: %w", err)
-	}
-
-	for j, emb := range allEmbeddings {
-		meta := metas[j]
-		if err := s.db.InsertEmbedding(meta.contentHash, meta.chunkText, meta.chunkIndex, emb); err != nil {
-			slog.Error("failed to store embedding", "hash", meta.contentHash, "chunk", meta.chunkIndex, "error", err)
+	// Tokens billed before a failure or cancellation are spent all the same.
+	if tokens > 0 {
+		if err := s.db.RecordUsage(crate.ID, model, tokens); err != nil {
+			slog.Error("failed to record usage", "crate", name, "version", version, "error", err)
 		}
 	}
-
-	s.db.SaveHNSW()
-	return nil
+	if err != nil {
+		return tokens, fmt.Errorf("embedding: %s", s.withProviderState(err))
+	}
+	return tokens, nil
 }
 
 func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
@@ -588,47 +1436,83 @@ func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Threshold <= 0 {
-		req.Threshold = 0.3
-	}
 	if req.Limit <= 0 {
 		req.Limit = 20
 	}
+	ctx, ok := scoped(w, r, req.Namespace)
+	if !ok {
+		return
+	}
+	fetchErrors := s.prepareSearch(ctx, &req)
+
+	results, explain, err := s.live().searcher.Search(req)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, s.withProviderState(err))
+		return
+	}
+
+	resp := rpc.SearchResponse{Results: results, FetchErrors: fetchErrors}
+	if explain != nil {
+		resp.RerankError = explain.RerankError
+	}
+	if len(results) == 0 {
+		resp.Suggestions = s.suggestCrates(ctx, req.Query)
+	}
+	if req.Explain {
+		resp.Explain = explain
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// prepareSearch applies the default threshold, auto-fetches requested
+// crates (or pinned versions) that aren't indexed yet and widens the crate
+// filter to dependencies when asked. It returns the errors of crates that
+// couldn't be fetched.
+func (s *Server) prepareSearch(ctx context.Context, req *rpc.SearchRequest) []string {
+	var fetchErrors []string
+	if req.Threshold <= 0 {
+		req.Threshold = 0.3
+	}
 
-	// Auto-fetch any requested crates that aren't indexed yet.
 	if len(req.Crates) > 0 {
-		indexed, err := s.db.GetIndexedVersions(req.Crates)
+		names := make([]string, len(req.Crates))
+		for i, spec := range req.Crates {
+			names[i], _, _ = strings.Cut(spec, "@")
+		}
+		indexed, err := s.index(ctx).GetIndexedVersions(names)
 		if err != nil {
 			slog.Error("failed to check indexed versions", "error", err)
 		} else {
-			for _, name := range req.Crates {
-				if _, ok := indexed[name]; !ok {
-					slog.Info("auto-fetching unindexed crate", "crate", name)
-					result := s.addCrate(rpc.CrateSpec{Name: name}, func(msg string) {
-						slog.Info(msg, "source", "auto-fetch")
-					})
-					if result.Error != "" {
-						slog.Error("auto-fetch failed", "crate", name, "error", result.Error)
+			for _, spec := range req.Crates {
+				name, version, pinned := strings.Cut(spec, "@")
+				if _, ok := indexed[name]; ok {
+					if !pinned || version == "" || version == "latest" {
+						continue
+					}
+					if ids, err := s.index(ctx).GetCrateIDsForSpecs([]string{spec}); err != nil || len(ids) > 0 {
+						continue
 					}
 				}
+				slog.Info("auto-fetching unindexed crate", "crate", name, "version", version)
+				result := s.autoFetch(ctx, name, version)
+				if result.Error != "" {
+					slog.Error("auto-fetch failed", "crate", spec, "error", result.Error)
+					fetchErrors = append(fetchErrors, fmt.Sprintf("%s: %s", spec, result.Error))
+				}
 			}
 		}
+		if req.WithDependencies {
+			req.Crates = s.withDependencies(ctx, req.Crates)
+		}
 	}
-
-	results, err := s.searcher.Search(req.Query, req.Crates, req.Threshold, req.Limit, req.RerankInstruction)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	writeJSON(w, http.StatusOK, rpc.SearchResponse{Results: results})
+	return fetchErrors
 }
 
 // resolveOrFetchCrate looks up a crate, resolving "latest" and auto-fetching if needed.
-func (s *Server) resolveOrFetchCrate(name, version string) (*db.Crate, error) {
+func (s *Server) resolveOrFetchCrate(ctx context.Context, name, version string) (*db.Crate, error) {
 	if version == "latest" || version == "" {
 		// Try to find any already-processed version
-		existing, err := s.db.GetLatestCrate(name)
+		existing, err := s.index(ctx).GetLatestCrate(name)
 		if err != nil {
 			return nil, err
 		}
@@ -636,7 +1520,7 @@ func (s *Server) resolveOrFetchCrate(name, version string) (*db.Crate, error) {
 			return existing, nil
 		}
 	} else {
-		existing, err := s.db.GetCrate(name, version)
+		existing, err := s.index(ctx).GetCrate(name, version)
 		if err != nil {
 			return nil, err
 		}
@@ -646,15 +1530,17 @@ func (s *Server) resolveOrFetchCrate(name, version string) (*db.Crate, error) {
 	}
 
 	// Not found — auto-fetch
-	result := s.addCrate(rpc.CrateSpec{Name: name, Version: version}, func(msg string) {
-		slog.Info(msg, "source", "auto-fetch")
-	})
+	result := s.autoFetch(ctx, name, version)
 	if result.Error != "" {
 		return nil, fmt.Errorf("%s", result.Error)
 	}
 
 	// Retry lookup with the resolved version
-	return s.db.GetCrate(name, result.Version)
+	crate, err := s.index(ctx).GetCrate(name, result.Version)
+	if err == nil && crate == nil && result.Partial {
+		return nil, fmt.Errorf("%s is still being indexed in the background, try again shortly", name)
+	}
+	return crate, err
 }
 
 func (s *Server) handleGetDoc(w http.ResponseWriter, r *http.Request) {
@@ -664,28 +1550,97 @@ func (s *Server) handleGetDoc(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Resolve crate: try exact version, then latest, then auto-fetch
-	crate, err := s.resolveOrFetchCrate(req.Crate, req.Version)
+	if req.Format != "" && req.Format != "markdown" && req.Format != "plain" {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown format %q (want markdown or plain)", req.Format))
+		return
+	}
+	if req.ResolveLinks != "" && req.ResolveLinks != "annotate" && req.ResolveLinks != "rewrite" {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown resolve_links %q (want annotate or rewrite)", req.ResolveLinks))
+		return
+	}
+	ctx, ok := scoped(w, r, req.Namespace)
+	if !ok {
+		return
+	}
+
+	d, err := s.resolveDoc(ctx, req)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeDocError(w, err)
 		return
 	}
-	if crate == nil {
-		writeError(w, http.StatusNotFound, fmt.Sprintf("crate %s@%s not found", req.Crate, req.Version))
+
+	// Fragment request: generate on-the-fly from cached rustdoc JSON
+	var text string
+	if d.req.Fragment != "" {
+		text, err = s.renderFragment(d)
+		if err != nil {
+			writeDocError(w, err)
+			return
+		}
+	} else {
+		text = s.renderItem(d)
+	}
+
+	if req.IncludeLinked > 0 {
+		text = s.appendLinked(d, text, req.IncludeLinked)
+	}
+	var links []rpc.LinkStatus
+	if req.ResolveLinks != "" {
+		text, links = s.resolveLinks(namespaceOf(ctx), text, req.ResolveLinks)
+	}
+	if req.Format == "plain" {
+		text = md.PlainText(text, req.Width)
+	}
+	writeMarkdown(w, r, rpc.GetDocResponse{Markdown: text, Links: links}, text)
+}
+
+// docError is a get-doc failure carrying the HTTP status to report.
+type docError struct {
+	status int
+	msg    string
+}
+
+func (e *docError) Error() string { return e.msg }
+
+func writeDocError(w http.ResponseWriter, err error) {
+	var de *docError
+	if errors.As(err, &de) {
+		writeError(w, de.status, de.msg)
 		return
 	}
+	writeError(w, http.StatusInternalServerError, err.Error())
+}
+
+// resolvedDoc is a documentation item located for a get-doc request. req is
+// rewritten to point at the source crate when the item is a re-export.
+type resolvedDoc struct {
+	req   rpc.GetDocRequest
+	crate *db.Crate
+	item  *db.Item
+}
+
+// resolveDoc finds the item a get-doc request refers to, fetching the crate
+// if it isn't indexed and following re-exports into their source crate.
+func (s *Server) resolveDoc(ctx context.Context, req rpc.GetDocRequest) (*resolvedDoc, error) {
+	// Resolve crate: try exact version, then latest, then auto-fetch
+	crate, err := s.resolveOrFetchCrate(ctx, req.Crate, req.Version)
+	if err != nil {
+		return nil, err
+	}
+	if crate == nil {
+		return nil, &docError{http.StatusNotFound, fmt.Sprintf("crate %s@%s not found", req.Crate, req.Version)}
+	}
 
 	item, err := s.db.GetItemByPath(crate.ID, req.Path)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
-		return
+		return nil, err
 	}
 
 	// If not found, check re-export mappings and redirect to the source crate
 	if item == nil {
 		srcCrate, srcPath, found := s.db.ResolveReexport(crate.ID, req.Path)
 		if found {
-			sourceCrate, err := s.resolveOrFetchCrate(srcCrate, "latest")
+			sourceCrate, err := s.resolveOrFetchCrate(ctx, srcCrate, "latest")
 			if err != nil {
 				slog.Error("re-export fetch failed", "crate", srcCrate, "error", err)
 			} else if sourceCrate != nil {
@@ -702,42 +1657,78 @@ func (s *Server) handleGetDoc(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if item == nil {
-		writeError(w, http.StatusNotFound, fmt.Sprintf("item %s not found in %s@%s", req.Path, req.Crate, crate.Version))
-		return
+		return nil, &docError{http.StatusNotFound, fmt.Sprintf("item %s not found in %s@%s", req.Path, req.Crate, crate.Version)}
 	}
+	return &resolvedDoc{req: req, crate: crate, item: item}, nil
+}
 
-	// Fragment request: generate on-the-fly from cached rustdoc JSON
-	if req.Fragment != "" {
-		cachedCrate := s.getCachedCrate(req.Crate, crate.Version)
-		if cachedCrate == nil {
-			writeError(w, http.StatusInternalServerError, fmt.Sprintf("rustdoc cache not available for %s@%s", req.Crate, crate.Version))
-			return
+// renderFragment generates the requested fragment from the cached rustdoc JSON.
+func (s *Server) renderFragment(d *resolvedDoc) (string, error) {
+	if d.crate.Source == db.SourceHTML {
+		return "", &docError{http.StatusNotFound, fmt.Sprintf("%s@%s was indexed from docs.rs HTML and has no fragments", d.req.Crate, d.crate.Version)}
+	}
+	cachedCrate := s.getCachedCrate(d.crate.Registry, d.req.Crate, d.crate.Version)
+	if cachedCrate == nil {
+		return "", fmt.Errorf("rustdoc cache not available for %s@%s", d.req.Crate, d.crate.Version)
+	}
+	rustdocItem, ok := cachedCrate.Index[d.item.RustdocID]
+	if !ok {
+		return "", &docError{http.StatusNotFound, fmt.Sprintf("item %s not found in rustdoc cache", d.item.RustdocID)}
+	}
+	frags := docs.GenerateFragments(&rustdocItem, cachedCrate, d.req.Crate, d.crate.Version)
+	for _, f := range frags {
+		if f.Name == d.req.Fragment && f.Content != "" {
+			return f.Content, nil
 		}
-		rustdocItem, ok := cachedCrate.Index[item.RustdocID]
-		if !ok {
-			writeError(w, http.StatusNotFound, fmt.Sprintf("item %s not found in rustdoc cache", item.RustdocID))
-			return
+	}
+	return "", &docError{http.StatusNotFound, fmt.Sprintf("fragment #%s not found for %s", d.req.Fragment, d.req.Path)}
+}
+
+// itemHeader renders the title, kind, stability, deprecation, features and
+// signature of an item.
+func itemHeader(item *db.Item) string {
+	var content strings.Builder
+	content.WriteString(fmt.Sprintf("# %s\n\n", item.Path))
+	content.WriteString(fmt.Sprintf("**Kind:** %s\n\n", item.Kind))
+	if item.Deprecation != "" {
+		var dep docs.Deprecation
+		if err := json.Unmarshal([]byte(item.Deprecation), &dep); err != nil {
+			slog.Error("failed to unmarshal deprecation", "path", item.Path, "error", err)
 		}
-		frags := docs.GenerateFragments(&rustdocItem, cachedCrate, req.Crate, crate.Version)
-		var fragContent string
-		for _, f := range frags {
-			if f.Name == req.Fragment {
-				fragContent = f.Content
-				break
-			}
+		content.WriteString("> " + dep.Banner() + "\n\n")
+	}
+	if item.Stability != "" {
+		var stab docs.Stability
+		if err := json.Unmarshal([]byte(item.Stability), &stab); err != nil {
+			slog.Error("failed to unmarshal stability", "path", item.Path, "error", err)
+		} else if stab.Unstable() {
+			content.WriteString("> " + stab.Banner() + "\n\n")
+		} else {
+			content.WriteString(stab.Banner() + "\n\n")
 		}
-		if fragContent == "" {
-			writeError(w, http.StatusNotFound, fmt.Sprintf("fragment #%s not found for %s", req.Fragment, req.Path))
-			return
+	}
+	if item.Features != "" {
+		var features []string
+		if err := json.Unmarshal([]byte(item.Features), &features); err != nil {
+			slog.Error("failed to unmarshal features", "path", item.Path, "error", err)
+		} else if len(features) > 0 {
+			content.WriteString(fmt.Sprintf("**Features:** `%s`\n\n", strings.Join(features, "`, `")))
 		}
-		writeJSON(w, http.StatusOK, rpc.GetDocResponse{Markdown: fragContent})
-		return
 	}
+	if item.Signature != "" {
+		content.WriteString(fmt.Sprintf("```rust\n%s\n```\n\n", item.Signature))
+	}
+	return content.String()
+}
 
-	// Full item: build rendered markdown
-	var docsText string
-	if item.ContentHash != "" {
-		docsText, _ = cas.Read(item.ContentHash)
+// itemDocs returns an item's docs with intra-doc links rewritten to rsdoc:// URIs.
+func itemDocs(item *db.Item) string {
+	if item.ContentHash == "" {
+		return ""
+	}
+	docsText, _ := cas.Read(item.ContentHash)
+	if docsText == "" {
+		return ""
 	}
 
 	var docLinks map[string]string
@@ -746,51 +1737,72 @@ func (s *Server) handleGetDoc(w http.ResponseWriter, r *http.Request) {
 			slog.Error("failed to unmarshal doc_links", "path", item.Path, "error", err)
 		}
 	}
+	return md.RewriteLinks(docsText, docLinks)
+}
 
-	var content strings.Builder
-	content.WriteString(fmt.Sprintf("# %s\n\n", item.Path))
-	content.WriteString(fmt.Sprintf("**Kind:** %s\n\n", item.Kind))
-	if item.Signature != "" {
-		content.WriteString(fmt.Sprintf("```rust\n%s\n```\n\n", item.Signature))
-	}
-	if docsText != "" {
-		content.WriteString(md.RewriteLinks(docsText, docLinks))
-		content.WriteString("\n")
+// renderItem builds the full markdown page for an item, with fragment URIs
+// in the front matter.
+func (s *Server) renderItem(d *resolvedDoc) string {
+	text := itemHeader(d.item)
+	if docsText := itemDocs(d.item); docsText != "" {
+		text += docsText + "\n"
 	}
 
-	text := content.String()
-
-	if item.FragmentNames != "" {
+	if d.item.FragmentNames != "" {
 		var fragNames []string
-		if json.Unmarshal([]byte(item.FragmentNames), &fragNames) == nil && len(fragNames) > 0 {
+		if json.Unmarshal([]byte(d.item.FragmentNames), &fragNames) == nil && len(fragNames) > 0 {
 			fragURIs := make(map[string]string, len(fragNames))
 			for _, name := range fragNames {
-				fragURIs[name] = fmt.Sprintf("rsdoc://%s/%s/%s#%s", req.Crate, crate.Version, req.Path, name)
+				fragURIs[name] = fmt.Sprintf("rsdoc://%s/%s/%s#%s", d.req.Crate, d.crate.Version, d.req.Path, name)
 			}
 			text = md.AddFrontMatter(text, fragURIs)
 		}
 	}
-
-	writeJSON(w, http.StatusOK, rpc.GetDocResponse{Markdown: text})
+	return text
 }
 
+// handleStatus lists the crates in the namespace given by the namespace
+// query parameter, the default if absent.
 func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
-	crates, err := s.db.ListCrates()
+	ctx, ok := scoped(w, r, r.URL.Query().Get("namespace"))
+	if !ok {
+		return
+	}
+	crates, err := s.index(ctx).ListCrates()
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	counts, err := s.db.CountItemsByCrate()
+	if err != nil {
+		slog.Error("failed to count items", "error", err)
+	}
+
+	s.backgroundMu.Lock()
+	background := maps.Clone(s.background)
+	s.backgroundMu.Unlock()
+
 	var status []rpc.CrateStatus
 	for _, c := range crates {
 		status = append(status, rpc.CrateStatus{
-			Name:      c.Name,
-			Version:   c.Version,
-			Processed: c.ProcessedAt != nil,
+			Name:        c.Name,
+			Version:     c.Version,
+			Processed:   c.ProcessedAt != nil,
+			Items:       counts[c.ID],
+			ProcessedAt: c.ProcessedAt,
+			LastUsedAt:  c.LastUsedAt,
+			Source:      c.Source,
+			Registry:    c.Registry,
+			Partial:     c.ProcessedAt == nil && background[c.Name] > 0,
 		})
 	}
 
-	writeJSON(w, http.StatusOK, rpc.StatusResponse{Crates: status})
+	resp := rpc.StatusResponse{Crates: status, StartedAt: s.startedAt, Embeddings: providerHealth(s.live().voyage.Health()), Model: s.modelStatus(), Watches: s.watchStatus(namespaceOf(ctx))}
+	if fi, err := os.Stat(config.DBPath()); err == nil {
+		resp.DatabaseBytes = fi.Size()
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
 func (s *Server) handleSearchCrates(w http.ResponseWriter, r *http.Request) {
@@ -808,21 +1820,36 @@ func (s *Server) handleSearchCrates(w http.ResponseWriter, r *http.Request) {
 		req.Limit = 20
 	}
 
-	cratesIO, err := docs.SearchCratesIO(req.Query, req.Limit)
+	reg, err := docs.LookupRegistry(req.Registry)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	results, err := s.searchCrates(r.Context(), reg, req.Query, req.Limit)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	writeJSON(w, http.StatusOK, rpc.SearchCratesResponse{Results: results})
+}
+
+// searchCrates searches the registry and marks which results are indexed locally.
+func (s *Server) searchCrates(ctx context.Context, reg *docs.Registry, query string, limit int) ([]rpc.CrateSearchResult, error) {
+	cratesIO, err := docs.SearchCratesIO(ctx, reg, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
 	names := make([]string, len(cratesIO))
 	for i, c := range cratesIO {
 		names[i] = c.Name
 	}
 
-	indexed, err := s.db.GetIndexedVersions(names)
+	indexed, err := s.index(ctx).GetIndexedVersions(names)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
-		return
+		return nil, err
 	}
 
 	results := make([]rpc.CrateSearchResult, len(cratesIO))
@@ -838,24 +1865,45 @@ func (s *Server) handleSearchCrates(w http.ResponseWriter, r *http.Request) {
 			results[i].IndexedVersion = ver
 		}
 	}
+	return results, nil
+}
 
-	writeJSON(w, http.StatusOK, rpc.SearchCratesResponse{Results: results})
+// suggestCrates looks up unindexed crates.io crates relevant to a query that
+// matched nothing locally, so callers get a next step instead of a dead end.
+func (s *Server) suggestCrates(ctx context.Context, query string) *rpc.CrateSuggestions {
+	results, err := s.searchCrates(ctx, docs.DefaultRegistry, query, 10)
+	if err != nil {
+		slog.Warn("crate suggestion lookup failed", "query", query, "error", err)
+		return nil
+	}
+
+	var crates []rpc.CrateSearchResult
+	for _, c := range results {
+		if c.IndexedVersion == "" {
+			crates = append(crates, c)
+		}
+		if len(crates) == 5 {
+			break
+		}
+	}
+	if len(crates) == 0 {
+		return nil
+	}
+	return &rpc.CrateSuggestions{
+		Message: "No indexed documentation matched. These crates are not indexed yet and may be relevant; index one with `rsdoc add <crate>` and search again.",
+		Crates:  crates,
+	}
 }
 
 func (s *Server) handleClearCache(w http.ResponseWriter, r *http.Request) {
 	s.clearVersionCache()
 	slog.Info("version cache cleared")
-	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	writeJSON(w, http.StatusOK, rpc.StatusMessage{Status: "ok"})
 }
 
 func (s *Server) handleShutdown(w http.ResponseWriter, r *http.Request) {
-	writeJSON(w, http.StatusOK, map[string]string{"status": "shutting down"})
-	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		s.Stop(ctx)
-		os.Exit(0)
-	}()
+	writeJSON(w, http.StatusOK, rpc.StatusMessage{Status: "shutting down"})
+	s.stopAsync()
 }
 
 func writeJSON(w http.ResponseWriter, status int, v interface{}) {
@@ -865,5 +1913,5 @@ func writeJSON(w http.ResponseWriter, status int, v interface{}) {
 }
 
 func writeError(w http.ResponseWriter, status int, msg string) {
-	writeJSON(w, status, map[string]string{"error": msg})
+	writeJSON(w, status, rpc.ErrorResponse{Error: msg})
 }
@@ -0,0 +1,60 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+)
+
+// handleSimilar returns the items whose embeddings are closest to an
+// indexed item's, without embedding a query.
+func (s *Server) handleSimilar(w http.ResponseWriter, r *http.Request) {
+	var req rpc.SimilarRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	docReq, _, err := rpc.ParseDocURI(req.URI)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Limit <= 0 {
+		req.Limit = 10
+	}
+	ctx, ok := scoped(w, r, req.Namespace)
+	if !ok {
+		return
+	}
+
+	d, err := s.resolveDoc(ctx, docReq)
+	if err != nil {
+		writeDocError(w, err)
+		return
+	}
+	hash := d.item.ContentHash
+	if d.req.Fragment != "" {
+		hash, err = s.db.GetFragmentHash(d.item.ID, d.req.Fragment)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	if hash == "" {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("%s has no indexed content to compare", req.URI))
+		return
+	}
+
+	results, err := s.live().searcher.Similar(req.Namespace, d.item, hash, req.Crates, req.Limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	uri := fmt.Sprintf("rsdoc://%s/%s/%s", d.crate.Name, d.crate.Version, d.item.Path)
+	if d.req.Fragment != "" {
+		uri += "#" + d.req.Fragment
+	}
+	writeJSON(w, http.StatusOK, rpc.SimilarResponse{URI: uri, Results: results})
+}
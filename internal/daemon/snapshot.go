@@ -0,0 +1,86 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/jcdickinson/ferrisfetch/internal/db"
+	"github.com/jcdickinson/ferrisfetch/internal/docs"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+)
+
+func (s *Server) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	var req rpc.SnapshotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !filepath.IsAbs(req.Path) {
+		writeError(w, http.StatusBadRequest, "snapshot path must be absolute")
+		return
+	}
+
+	// Waits for in-flight indexing to finish and holds off new indexing.
+	s.writes.Lock()
+	err := s.db.Snapshot(req.Path)
+	s.writes.Unlock()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := rpc.SnapshotResponse{Path: req.Path}
+	if fi, err := os.Stat(resp.Path); err == nil {
+		resp.Bytes += fi.Size()
+	}
+	// Disk mode has no HNSW index to write.
+	if fi, err := os.Stat(db.HNSWPath(req.Path)); err == nil {
+		resp.HNSWPath = db.HNSWPath(req.Path)
+		resp.Bytes += fi.Size()
+	}
+	slog.Info("snapshot written", "path", req.Path, "bytes", resp.Bytes)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleRestore(w http.ResponseWriter, r *http.Request) {
+	var req rpc.RestoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !filepath.IsAbs(req.Path) {
+		writeError(w, http.StatusBadRequest, "snapshot path must be absolute")
+		return
+	}
+	if _, err := os.Stat(req.Path); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("snapshot not found: %v", err))
+		return
+	}
+
+	s.writes.Lock()
+	err := s.db.Restore(req.Path)
+	if err == nil {
+		s.clearVersionCache()
+		s.crateCacheMu.Lock()
+		s.crateCache = make(map[string]*docs.RustdocCrate)
+		s.crateCacheMu.Unlock()
+		// The snapshot may have been embedded with another model.
+		err = s.checkEmbeddingModel()
+	}
+	s.writes.Unlock()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var resp rpc.RestoreResponse
+	if crates, err := s.db.ListAllCrates(); err == nil {
+		resp.Crates = len(crates)
+	}
+	slog.Info("restored snapshot", "path", req.Path, "crates", resp.Crates)
+	writeJSON(w, http.StatusOK, resp)
+}
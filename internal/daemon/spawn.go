@@ -5,17 +5,20 @@ import (
 	"os"
 	"os/exec"
 	"syscall"
+
+	"github.com/jcdickinson/ferrisfetch/internal/config"
 )
 
 // Spawn starts a daemon as a detached subprocess.
-// It runs the same binary with the "daemon" subcommand.
+// It runs the same binary with the "daemon" subcommand, passing on any
+// paths set by flags so the daemon uses the same socket and data.
 func Spawn() error {
 	exe, err := os.Executable()
 	if err != nil {
 		return fmt.Errorf("finding executable path: %w", err)
 	}
 
-	cmd := exec.Command(exe, "daemon")
+	cmd := exec.Command(exe, append([]string{"daemon"}, config.PathArgs()...)...)
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
 	cmd.Stdout = nil
 	cmd.Stderr = nil
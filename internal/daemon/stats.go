@@ -0,0 +1,171 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+
+	"github.com/jcdickinson/ferrisfetch/internal/cas"
+	"github.com/jcdickinson/ferrisfetch/internal/config"
+	"github.com/jcdickinson/ferrisfetch/internal/db"
+	"github.com/jcdickinson/ferrisfetch/internal/docs"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+)
+
+// handleStats reports what each crate takes up in the database, CAS and
+// JSON cache, what embedding it cost, and what deduplication saves.
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	crates, err := s.db.ListAllCrates()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	counts, err := s.db.CountItemsByCrate()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	hashes, err := s.db.CrateContentHashes()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	embeddings, err := s.db.EmbeddingSizes()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	usage, err := s.db.UsageByCrate()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	voyage := s.live().cfg.VoyageAI
+
+	refs := make(map[string]int)
+	for _, hs := range hashes {
+		for _, h := range hs {
+			refs[h]++
+		}
+	}
+	casBytes := make(map[string]int64, len(refs))
+	var resp rpc.StatsResponse
+	for h := range refs {
+		casBytes[h] = cas.Size(h)
+		resp.Documents++
+		resp.Chunks += embeddings[h].Chunks
+		resp.EmbeddingBytes += embeddings[h].Bytes
+		resp.CASBytes += casBytes[h]
+	}
+
+	var separate int64
+	for _, c := range crates {
+		cs := rpc.CrateStats{
+			Name:           c.Name,
+			Namespace:      c.Namespace,
+			Version:        c.Version,
+			Items:          counts[c.ID],
+			Documents:      len(hashes[c.ID]),
+			JSONCacheBytes: docs.CrateCacheSize(c.Registry, c.Name, c.Version),
+			Tokens:         usage[c.ID],
+			Cost:           voyage.Cost(usage[c.ID]),
+		}
+		for _, h := range hashes[c.ID] {
+			cs.Chunks += embeddings[h].Chunks
+			cs.EmbeddingBytes += embeddings[h].Bytes
+			cs.CASBytes += casBytes[h]
+			if refs[h] > 1 {
+				cs.SharedBytes += casBytes[h]
+			}
+		}
+		separate += cs.CASBytes + cs.EmbeddingBytes
+		resp.Items += cs.Items
+		resp.JSONCacheBytes += cs.JSONCacheBytes
+		resp.Tokens += cs.Tokens
+		resp.Crates = append(resp.Crates, cs)
+	}
+	resp.DedupSavedBytes = separate - resp.CASBytes - resp.EmbeddingBytes
+	resp.Cost = voyage.Cost(resp.Tokens)
+
+	resp.CASDiskBytes, _ = cas.DiskUsage()
+	if fi, err := os.Stat(config.DBPath()); err == nil {
+		resp.DatabaseBytes = fi.Size()
+	}
+	if fi, err := os.Stat(db.HNSWPath(config.DBPath())); err == nil {
+		resp.HNSWBytes = fi.Size()
+	}
+	resp.IndexNodes = s.db.HNSWSize()
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleCoverage reports how much of a crate is documented, embedded and
+// split into fragments, listing the gaps.
+func (s *Server) handleCoverage(w http.ResponseWriter, r *http.Request) {
+	var req rpc.CoverageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Crate == "" {
+		writeError(w, http.StatusBadRequest, "missing crate")
+		return
+	}
+	ctx, ok := scoped(w, r, req.Namespace)
+	if !ok {
+		return
+	}
+
+	crate, err := s.resolveOrFetchCrate(ctx, req.Crate, req.Version)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if crate == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("crate %s not found", req.Crate))
+		return
+	}
+	items, err := s.db.CrateCoverage(crate.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := rpc.CoverageResponse{Crate: crate.Name, Version: crate.Version, Items: len(items)}
+	kinds := make(map[string]*rpc.KindCoverage)
+	for _, it := range items {
+		k := kinds[it.Kind]
+		if k == nil {
+			k = &rpc.KindCoverage{Kind: it.Kind}
+			kinds[it.Kind] = k
+		}
+		k.Items++
+		resp.Fragments += it.Fragments
+		resp.EmbeddedFragments += it.EmbeddedFragments
+
+		if !it.Documented {
+			resp.Undocumented = append(resp.Undocumented, rpc.CoverageItem{Path: it.Path, Kind: it.Kind})
+		} else {
+			resp.Documented++
+			k.Documented++
+			if it.Embedded {
+				resp.Embedded++
+				k.Embedded++
+			}
+		}
+		if missing := it.Fragments - it.EmbeddedFragments; missing > 0 || (it.Documented && !it.Embedded) {
+			resp.Unembedded = append(resp.Unembedded, rpc.CoverageItem{Path: it.Path, Kind: it.Kind, MissingFragments: missing})
+		}
+	}
+	for _, k := range kinds {
+		resp.ByKind = append(resp.ByKind, *k)
+	}
+	sort.Slice(resp.ByKind, func(i, j int) bool {
+		if resp.ByKind[i].Items != resp.ByKind[j].Items {
+			return resp.ByKind[i].Items > resp.ByKind[j].Items
+		}
+		return resp.ByKind[i].Kind < resp.ByKind[j].Kind
+	})
+	writeJSON(w, http.StatusOK, resp)
+}
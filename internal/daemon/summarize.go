@@ -0,0 +1,199 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jcdickinson/ferrisfetch/internal/db"
+	"github.com/jcdickinson/ferrisfetch/internal/docs"
+	md "github.com/jcdickinson/ferrisfetch/internal/markdown"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+)
+
+const defaultSummaryBudget = 500
+
+// keySections are the doc sections a summary includes first, in this
+// order, since they say how an item can fail. Others follow in document
+// order once the fragments fit.
+var keySections = []string{"Safety", "Panics", "Errors"}
+
+// maxFragmentLine caps the description of each fragment in a summary.
+const maxFragmentLine = 160
+
+// handleSummarize returns an extractive summary of a document: its header
+// and signature, the first paragraph of the docs and of its key sections,
+// a line per fragment and the first example, in that order of priority, as
+// far as they fit the token budget. Nothing is generated, so it costs no
+// model calls.
+func (s *Server) handleSummarize(w http.ResponseWriter, r *http.Request) {
+	var req rpc.SummarizeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	docReq, _, err := rpc.ParseDocURI(req.URI)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.TokenBudget <= 0 {
+		req.TokenBudget = defaultSummaryBudget
+	}
+	ctx, ok := scoped(w, r, req.Namespace)
+	if !ok {
+		return
+	}
+	d, err := s.resolveDoc(ctx, docReq)
+	if err != nil {
+		writeDocError(w, err)
+		return
+	}
+
+	var head, text string
+	var fragments []docs.Fragment
+	if d.req.Fragment != "" {
+		if text, err = s.renderFragment(d); err != nil {
+			writeDocError(w, err)
+			return
+		}
+	} else {
+		head = itemHeader(d.item)
+		text = itemDocs(d.item)
+		fragments = s.itemFragments(d)
+	}
+	resp := summarize(docURI(d), head, text, fragments, req.TokenBudget)
+	writeMarkdown(w, r, resp, resp.Markdown)
+}
+
+// docURI is the canonical URI of a resolved document.
+func docURI(d *resolvedDoc) string {
+	uri := fmt.Sprintf("rsdoc://%s/%s/%s", d.req.Crate, d.crate.Version, d.req.Path)
+	if d.req.Fragment != "" {
+		uri += "#" + d.req.Fragment
+	}
+	return uri
+}
+
+// itemFragments generates an item's fragments from the rustdoc JSON
+// cache, or returns nil when there is none.
+func (s *Server) itemFragments(d *resolvedDoc) []docs.Fragment {
+	if d.crate.Source == db.SourceHTML {
+		return nil
+	}
+	cached := s.getCachedCrate(d.crate.Registry, d.req.Crate, d.crate.Version)
+	if cached == nil {
+		return nil
+	}
+	rustdocItem, ok := cached.Index[d.item.RustdocID]
+	if !ok {
+		return nil
+	}
+	return docs.GenerateFragments(&rustdocItem, cached, d.req.Crate, d.crate.Version)
+}
+
+// summarize packs the parts of a document into budget tokens, highest
+// priority first. head is always kept; a part that doesn't fit is skipped
+// in favor of smaller ones after it.
+func summarize(uri, head, text string, fragments []docs.Fragment, budget int) rpc.SummarizeResponse {
+	sections := md.Sections(text)
+	var intro string
+	if len(sections) > 0 && sections[0].Heading == "" {
+		intro, sections = md.FirstParagraph(sections[0].Body), sections[1:]
+	}
+
+	var parts []string
+	if intro != "" {
+		parts = append(parts, intro+"\n")
+	}
+	for _, name := range keySections {
+		for _, sec := range sections {
+			if strings.EqualFold(sec.Heading, name) && sec.Body != "" {
+				parts = append(parts, fmt.Sprintf("**%s:** %s\n", sec.Heading, md.FirstParagraph(sec.Body)))
+			}
+		}
+	}
+	if len(fragments) > 0 {
+		var b strings.Builder
+		b.WriteString("## Fragments\n\n")
+		for _, f := range fragments {
+			fmt.Fprintf(&b, "- [#%s](%s#%s)", f.Name, uri, f.Name)
+			if line := fragmentLine(f.Content); line != "" {
+				b.WriteString(": " + line)
+			}
+			b.WriteString("\n")
+		}
+		parts = append(parts, b.String())
+	}
+	if code := firstExample(sections); code != "" {
+		parts = append(parts, "## Example\n\n"+code+"\n")
+	}
+	for _, sec := range sections {
+		if sec.Body == "" || slices.ContainsFunc(keySections, func(k string) bool { return strings.EqualFold(k, sec.Heading) }) || isExamples(sec.Heading) {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("**%s:** %s\n", sec.Heading, md.FirstParagraph(sec.Body)))
+	}
+
+	footer := fmt.Sprintf("*Summary of %s; read it for the full docs.*\n", uri)
+	var b strings.Builder
+	b.WriteString(head)
+	used := estimateTokens(head + footer)
+	resp := rpc.SummarizeResponse{URI: uri}
+	for _, p := range parts {
+		if used+estimateTokens(p+"\n") > budget {
+			resp.Truncated = true
+			continue
+		}
+		used += estimateTokens(p + "\n")
+		b.WriteString(p)
+		b.WriteString("\n")
+	}
+	b.WriteString(footer)
+	resp.Markdown = b.String()
+	resp.Tokens = estimateTokens(resp.Markdown)
+	return resp
+}
+
+// fragmentLine is the first line of a fragment's content after its
+// heading, without list markup, cut to maxFragmentLine bytes.
+func fragmentLine(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "```") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "- ")
+		if len(line) > maxFragmentLine {
+			cut := strings.LastIndex(line[:maxFragmentLine], " ")
+			if cut <= 0 {
+				cut = maxFragmentLine
+				for !utf8.RuneStart(line[cut]) {
+					cut--
+				}
+			}
+			line = line[:cut] + " …"
+		}
+		return line
+	}
+	return ""
+}
+
+// firstExample returns the first code block of the Examples section.
+func firstExample(sections []md.Section) string {
+	for _, sec := range sections {
+		if isExamples(sec.Heading) {
+			if code := md.FirstCodeBlock(sec.Body); code != "" {
+				return code
+			}
+		}
+	}
+	return ""
+}
+
+func isExamples(heading string) bool {
+	return strings.EqualFold(heading, "Examples") || strings.EqualFold(heading, "Example")
+}
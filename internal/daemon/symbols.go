@@ -0,0 +1,28 @@
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+)
+
+// handleSymbols looks indexed items up by name or path. It never fetches
+// crates: editors call it on every hover.
+func (s *Server) handleSymbols(w http.ResponseWriter, r *http.Request) {
+	var req rpc.SymbolsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Limit <= 0 {
+		req.Limit = 20
+	}
+
+	results, err := s.live().searcher.Symbols(req)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, rpc.SymbolsResponse{Results: results})
+}
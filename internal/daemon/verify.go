@@ -0,0 +1,154 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/jcdickinson/ferrisfetch/internal/cas"
+	"github.com/jcdickinson/ferrisfetch/internal/db"
+	"github.com/jcdickinson/ferrisfetch/internal/docs"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+)
+
+// handleVerify cross-checks items against the CAS, embeddings against the
+// HNSW index, and crates against the rustdoc JSON cache. With repair set, the
+// HNSW index is patched in place, orphaned cache files are removed, and only
+// the crates with missing content, embeddings or JSON are re-indexed.
+func (s *Server) handleVerify(w http.ResponseWriter, r *http.Request) {
+	var req rpc.VerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var resp rpc.VerifyResponse
+	fail := func(step string, err error) {
+		slog.Error("verify failed", "step", step, "error", err)
+		resp.Errors = append(resp.Errors, fmt.Sprintf("%s: %v", step, err))
+	}
+
+	hashes, err := s.db.ContentHashes()
+	if err != nil {
+		fail("listing content hashes", err)
+	}
+	resp.ContentHashes = len(hashes)
+	for _, h := range hashes {
+		if err := cas.Check(h); err != nil {
+			resp.MissingContent = append(resp.MissingContent, h)
+		}
+	}
+
+	resp.Unembedded, err = s.db.UnembeddedHashes()
+	if err != nil {
+		fail("listing unembedded hashes", err)
+	}
+
+	missing, orphaned, err := s.db.CheckHNSW()
+	if err != nil {
+		fail("checking HNSW index", err)
+	}
+	resp.MissingFromIndex, resp.OrphanedInIndex = len(missing), len(orphaned)
+
+	crates, err := s.db.ListAllCrates()
+	if err != nil {
+		fail("listing crates", err)
+	}
+	known := make(map[string]bool, len(crates))
+	var uncached []db.Crate
+	for _, c := range crates {
+		known[registryName(c.Registry, c.Name)+"@"+c.Version] = true
+		if c.ProcessedAt != nil && c.Source != db.SourceHTML && !docs.HasCrateCache(c.Registry, c.Name, c.Version) {
+			resp.MissingJSONCache = append(resp.MissingJSONCache, c.Name+"@"+c.Version)
+			uncached = append(uncached, c)
+		}
+	}
+	cached, err := docs.ListCrateCaches()
+	if err != nil {
+		fail("listing JSON cache", err)
+	}
+	var orphanCaches []docs.CachedCrate
+	for _, c := range cached {
+		if label := registryName(c.Registry, c.Name) + "@" + c.Version; !known[label] {
+			resp.OrphanedJSONCache = append(resp.OrphanedJSONCache, label)
+			orphanCaches = append(orphanCaches, c)
+		}
+	}
+
+	if req.Repair {
+		s.repair(r.Context(), &resp, missing, orphaned, orphanCaches, uncached)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) repair(ctx context.Context, resp *rpc.VerifyResponse, missing, orphaned []int, orphanCaches []docs.CachedCrate, uncached []db.Crate) {
+	if len(missing) > 0 || len(orphaned) > 0 {
+		s.writes.RLock()
+		err := s.db.RepairHNSW(missing, orphaned)
+		s.writes.RUnlock()
+		if err != nil {
+			resp.Errors = append(resp.Errors, fmt.Sprintf("repairing HNSW index: %v", err))
+		} else {
+			resp.Repaired = append(resp.Repaired, fmt.Sprintf("HNSW index: added %d, removed %d", len(missing), len(orphaned)))
+		}
+	}
+
+	for _, c := range orphanCaches {
+		if err := docs.RemoveCrateCache(c.Registry, c.Name, c.Version); err != nil {
+			resp.Errors = append(resp.Errors, err.Error())
+			continue
+		}
+		resp.Repaired = append(resp.Repaired, fmt.Sprintf("removed orphaned JSON cache %s@%s", c.Name, c.Version))
+	}
+
+	// Unreadable CAS files must go before re-indexing, since cas.Write skips
+	// files that already exist.
+	for _, h := range resp.MissingContent {
+		if err := cas.Remove(h); err != nil {
+			resp.Errors = append(resp.Errors, err.Error())
+		}
+	}
+
+	affected, err := s.db.CratesForContentHashes(append(append([]string(nil), resp.MissingContent...), resp.Unembedded...))
+	if err != nil {
+		resp.Errors = append(resp.Errors, fmt.Sprintf("finding affected crates: %v", err))
+	}
+	seen := make(map[int]bool)
+	for _, c := range append(affected, uncached...) {
+		if seen[c.ID] {
+			continue
+		}
+		seen[c.ID] = true
+
+		spec, err := reindexSpec(c)
+		if err != nil {
+			resp.Errors = append(resp.Errors, fmt.Sprintf("re-indexing %s@%s: %v", c.Name, c.Version, err))
+			continue
+		}
+		result := s.addCrate(withNamespace(ctx, c.Namespace), spec, func(msg string) {
+			slog.Info(msg, "source", "verify")
+		})
+		if result.Error != "" {
+			resp.Errors = append(resp.Errors, fmt.Sprintf("re-indexing %s@%s: %s", c.Name, c.Version, result.Error))
+			continue
+		}
+		resp.Repaired = append(resp.Repaired, fmt.Sprintf("re-indexed %s@%s (%d items)", c.Name, c.Version, result.Items))
+	}
+}
+
+// reindexSpec returns the spec that re-indexes an indexed crate from scratch.
+// Imported crates can't be re-fetched, so they are re-indexed from the JSON
+// cache.
+func reindexSpec(c db.Crate) (rpc.CrateSpec, error) {
+	spec := rpc.CrateSpec{Name: c.Name, Version: c.Version, Force: true, Registry: c.Registry}
+	if c.Source == db.SourceFile {
+		data, err := docs.ReadCrateCache(c.Registry, c.Name, c.Version)
+		if err != nil {
+			return spec, fmt.Errorf("imported from a file, re-run add --file: %w", err)
+		}
+		spec.RustdocJSON = data
+	}
+	return spec, nil
+}
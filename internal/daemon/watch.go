@@ -0,0 +1,251 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/jcdickinson/ferrisfetch/internal/docs"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+)
+
+// watchDebounce lets cargo finish rewriting Cargo.lock before it is read.
+const watchDebounce = time.Second
+
+// projectWatch keeps one project's dependencies indexed in one namespace.
+type projectWatch struct {
+	lockfile string
+	watcher  *fsnotify.Watcher
+	cancel   context.CancelFunc
+
+	mu     sync.Mutex
+	status rpc.WatchStatus
+}
+
+func (w *projectWatch) snapshot() rpc.WatchStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	st := w.status
+	st.Errors = append([]string(nil), w.status.Errors...)
+	return st
+}
+
+func (w *projectWatch) update(fn func(*rpc.WatchStatus)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	fn(&w.status)
+}
+
+func watchKey(ns, manifest string) string {
+	return ns + "\x00" + manifest
+}
+
+// handleWatch starts watching a project's Cargo.lock, or with req.Stop ends
+// it. Watching a project already watched returns its status.
+func (s *Server) handleWatch(w http.ResponseWriter, r *http.Request) {
+	var req rpc.WatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, ok := scoped(w, r, req.Namespace); !ok {
+		return
+	}
+	if req.Manifest == "" {
+		writeError(w, http.StatusBadRequest, "missing manifest")
+		return
+	}
+	manifest, err := filepath.Abs(req.Manifest)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	key := watchKey(req.Namespace, manifest)
+
+	if req.Stop {
+		s.watchesMu.Lock()
+		pw, ok := s.watches[key]
+		delete(s.watches, key)
+		s.watchesMu.Unlock()
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("%s is not being watched", manifest))
+			return
+		}
+		pw.cancel()
+		slog.Info("stopped watching", "source", "watch", "manifest", manifest, "namespace", req.Namespace)
+		writeJSON(w, http.StatusOK, pw.snapshot())
+		return
+	}
+
+	lockfile, err := docs.FindLockfile(manifest)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.watchesMu.Lock()
+	defer s.watchesMu.Unlock()
+	if pw, ok := s.watches[key]; ok {
+		writeJSON(w, http.StatusOK, pw.snapshot())
+		return
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	// Watch the directory rather than the file: cargo replaces Cargo.lock,
+	// and it may not exist yet.
+	if err := watcher.Add(filepath.Dir(lockfile)); err != nil {
+		watcher.Close()
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ctx, cancel := context.WithCancel(withNamespace(s.workCtx, req.Namespace))
+	pw := &projectWatch{
+		lockfile: lockfile,
+		watcher:  watcher,
+		cancel:   cancel,
+		status:   rpc.WatchStatus{Manifest: manifest, Lockfile: lockfile, Namespace: req.Namespace},
+	}
+	s.watches[key] = pw
+	slog.Info("watching", "source", "watch", "manifest", manifest, "lockfile", lockfile, "namespace", req.Namespace)
+	go s.runWatch(ctx, pw)
+	writeJSON(w, http.StatusOK, pw.snapshot())
+}
+
+// runWatch syncs pw now and again whenever its Cargo.lock changes, until
+// ctx ends.
+func (s *Server) runWatch(ctx context.Context, pw *projectWatch) {
+	defer pw.watcher.Close()
+	s.syncWatch(ctx, pw)
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-pw.watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(ev.Name) == pw.lockfile && ev.Has(fsnotify.Write|fsnotify.Create) {
+				debounce = time.After(watchDebounce)
+			}
+		case err, ok := <-pw.watcher.Errors:
+			if !ok {
+				return
+			}
+			slog.Warn("watching lockfile", "source", "watch", "lockfile", pw.lockfile, "error", err)
+		case <-debounce:
+			debounce = nil
+			s.syncWatch(ctx, pw)
+		}
+	}
+}
+
+// syncWatch adds, one at a time, the locked dependencies not yet indexed at
+// their locked version. Failures are recorded in the watch's status and
+// retried on the next change.
+func (s *Server) syncWatch(ctx context.Context, pw *projectWatch) {
+	if s.stopping.Load() {
+		return
+	}
+	s.activeOps.Add(1)
+	defer func() {
+		s.activeOps.Add(-1)
+		s.resetExpiration()
+	}()
+
+	deps, err := readLockfile(pw.lockfile)
+	if err != nil {
+		slog.Warn("reading lockfile", "source", "watch", "lockfile", pw.lockfile, "error", err)
+		pw.update(func(st *rpc.WatchStatus) { st.Errors = []string{err.Error()} })
+		return
+	}
+
+	var missing []docs.LockedDependency
+	for _, d := range deps {
+		if c, err := s.index(ctx).GetRegistryCrate("", d.Name, d.Version); err != nil || c == nil || c.ProcessedAt == nil {
+			missing = append(missing, d)
+		}
+	}
+	indexed := len(deps) - len(missing)
+	pw.update(func(st *rpc.WatchStatus) {
+		st.Dependencies = len(deps)
+		st.Indexed = indexed
+	})
+	if len(missing) > 0 {
+		slog.Info("indexing dependencies", "source", "watch", "lockfile", pw.lockfile, "missing", len(missing))
+	}
+
+	var errs []string
+	for _, d := range missing {
+		if ctx.Err() != nil {
+			pw.update(func(st *rpc.WatchStatus) { st.Indexing = "" })
+			return
+		}
+		label := d.Name + "@" + d.Version
+		pw.update(func(st *rpc.WatchStatus) { st.Indexing = label })
+		result := s.addCrate(ctx, rpc.CrateSpec{Name: d.Name, Version: d.Version}, func(msg string) {
+			slog.Info(msg, "source", "watch", "crate", label)
+		})
+		if result.Error != "" {
+			slog.Error("watch indexing failed", "source", "watch", "crate", label, "error", result.Error)
+			errs = append(errs, label+": "+result.Error)
+			continue
+		}
+		indexed++
+		pw.update(func(st *rpc.WatchStatus) { st.Indexed = indexed })
+	}
+
+	now := time.Now()
+	pw.update(func(st *rpc.WatchStatus) {
+		st.Indexing = ""
+		st.SyncedAt = &now
+		st.Errors = errs
+	})
+	slog.Info("watch synced", "source", "watch", "lockfile", pw.lockfile, "dependencies", len(deps), "indexed", indexed, "failed", len(errs))
+}
+
+// readLockfile returns a lockfile's direct dependencies; none while cargo
+// hasn't written it yet.
+func readLockfile(path string) ([]docs.LockedDependency, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return docs.DirectDependencies(data)
+}
+
+// watchStatus lists the watches in namespace ns, sorted by manifest.
+func (s *Server) watchStatus(ns string) []rpc.WatchStatus {
+	s.watchesMu.Lock()
+	defer s.watchesMu.Unlock()
+	var status []rpc.WatchStatus
+	for _, pw := range s.watches {
+		if st := pw.snapshot(); st.Namespace == ns {
+			status = append(status, st)
+		}
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].Manifest < status[j].Manifest })
+	return status
+}
+
+// watching counts the projects being watched, in any namespace.
+func (s *Server) watching() int {
+	s.watchesMu.Lock()
+	defer s.watchesMu.Unlock()
+	return len(s.watches)
+}
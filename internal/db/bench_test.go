@@ -0,0 +1,66 @@
+package db
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"path/filepath"
+	"testing"
+)
+
+// randomEmbeddings returns n random unit-length vectors.
+func randomEmbeddings(rng *rand.Rand, n, dim int) [][]float32 {
+	vecs := make([][]float32, n)
+	for i := range vecs {
+		vec := make([]float32, dim)
+		var norm float64
+		for j := range vec {
+			vec[j] = rng.Float32()*2 - 1
+			norm += float64(vec[j]) * float64(vec[j])
+		}
+		scale := float32(1 / math.Sqrt(norm))
+		for j := range vec {
+			vec[j] *= scale
+		}
+		vecs[i] = vec
+	}
+	return vecs
+}
+
+func benchDB(b *testing.B, vecs [][]float32) *DB {
+	b.Helper()
+	db, err := New(filepath.Join(b.TempDir(), "bench.db"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { db.Close() })
+	records := make([]EmbeddingRecord, len(vecs))
+	for i, vec := range vecs {
+		records[i] = EmbeddingRecord{ContentHash: fmt.Sprintf("hash%d", i), ChunkText: "chunk", Embedding: vec}
+	}
+	if err := db.InsertEmbeddings(records); err != nil {
+		b.Fatal(err)
+	}
+	return db
+}
+
+func BenchmarkInsertEmbeddings(b *testing.B) {
+	vecs := randomEmbeddings(rand.New(rand.NewPCG(1, 2)), 1000, 1024)
+	for b.Loop() {
+		benchDB(b, vecs)
+	}
+	b.ReportMetric(float64(len(vecs)), "vectors/op")
+}
+
+func BenchmarkVectorSearch(b *testing.B) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	db := benchDB(b, randomEmbeddings(rng, 5000, 1024))
+	queries := randomEmbeddings(rng, 64, 1024)
+	i := 0
+	for b.Loop() {
+		if _, err := db.VectorSearch(queries[i%len(queries)], 0, 30, Filter{}); err != nil {
+			b.Fatal(err)
+		}
+		i++
+	}
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CrateInfo is registry metadata about a crate as a whole, shared by all
+// its indexed versions.
+type CrateInfo struct {
+	Registry string // "" for crates.io
+	Name     string
+	// Downloads is the all-time download count; RecentDownloads covers the
+	// last 90 days.
+	Downloads       int
+	RecentDownloads int
+	// LatestVersion is the newest stable version the registry knows.
+	LatestVersion string
+	FetchedAt     time.Time
+}
+
+// addCrateInfo is migration 3.
+func addCrateInfo(tx *sql.Tx) error {
+	_, err := tx.Exec(`CREATE TABLE crate_info (
+		registry TEXT NOT NULL DEFAULT '',
+		name TEXT NOT NULL,
+		downloads INTEGER NOT NULL DEFAULT 0,
+		recent_downloads INTEGER NOT NULL DEFAULT 0,
+		latest_version TEXT NOT NULL DEFAULT '',
+		fetched_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (registry, name)
+	)`)
+	return err
+}
+
+// SetCrateInfo stores info, replacing what was known about the crate.
+func (db *DB) SetCrateInfo(info CrateInfo) error {
+	if info.FetchedAt.IsZero() {
+		info.FetchedAt = time.Now().UTC()
+	}
+	_, err := db.conn.Exec(
+		`INSERT INTO crate_info (registry, name, downloads, recent_downloads, latest_version, fetched_at) VALUES (?, ?, ?, ?, ?, ?)
+		 ON CONFLICT (registry, name) DO UPDATE SET downloads = EXCLUDED.downloads, recent_downloads = EXCLUDED.recent_downloads,
+			latest_version = EXCLUDED.latest_version, fetched_at = EXCLUDED.fetched_at`,
+		info.Registry, info.Name, info.Downloads, info.RecentDownloads, info.LatestVersion, info.FetchedAt)
+	if err != nil {
+		return fmt.Errorf("storing crate info for %s: %w", info.Name, err)
+	}
+	return nil
+}
+
+// CrateInfoByID returns the registry metadata of the given crates, keyed by
+// crate ID. Crates without metadata are left out.
+func (db *DB) CrateInfoByID(crateIDs []int) (map[int]CrateInfo, error) {
+	if len(crateIDs) == 0 {
+		return nil, nil
+	}
+	placeholders := make([]string, len(crateIDs))
+	params := make([]interface{}, len(crateIDs))
+	for i, id := range crateIDs {
+		placeholders[i] = "?"
+		params[i] = id
+	}
+	rows, err := db.reader.Query(fmt.Sprintf(
+		`SELECT c.id, i.registry, i.name, i.downloads, i.recent_downloads, i.latest_version, i.fetched_at
+		 FROM crates c JOIN crate_info i ON i.registry = c.registry AND i.name = c.name
+		 WHERE c.id IN (%s)`, strings.Join(placeholders, ",")), params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	infos := make(map[int]CrateInfo)
+	for rows.Next() {
+		var id int
+		var info CrateInfo
+		if err := rows.Scan(&id, &info.Registry, &info.Name, &info.Downloads, &info.RecentDownloads, &info.LatestVersion, &info.FetchedAt); err != nil {
+			return nil, err
+		}
+		infos[id] = info
+	}
+	return infos, rows.Err()
+}
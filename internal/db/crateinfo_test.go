@@ -0,0 +1,31 @@
+package db
+
+import "testing"
+
+func TestCrateInfo(t *testing.T) {
+	t.Parallel()
+	db := testDB(t)
+	old, _ := db.UpsertCrate("serde", "1.0.100")
+	cur, _ := db.UpsertCrate("serde", "1.0.200")
+	other, _ := db.UpsertCrate("serde_fork", "0.1.0")
+
+	if err := db.SetCrateInfo(CrateInfo{Name: "serde", Downloads: 10, LatestVersion: "1.0.199"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetCrateInfo(CrateInfo{Name: "serde", Downloads: 500, RecentDownloads: 50, LatestVersion: "1.0.200"}); err != nil {
+		t.Fatal(err)
+	}
+
+	infos, err := db.CrateInfoByID([]int{old.ID, cur.ID, other.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 {
+		t.Fatalf("got info for %d crates, want 2 (serde's versions): %v", len(infos), infos)
+	}
+	for _, id := range []int{old.ID, cur.ID} {
+		if info := infos[id]; info.Downloads != 500 || info.RecentDownloads != 50 || info.LatestVersion != "1.0.200" {
+			t.Errorf("info for crate %d = %+v, want the latest stored", id, info)
+		}
+	}
+}
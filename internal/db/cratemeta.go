@@ -0,0 +1,124 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// KindCrate is the kind search gives hits on a crate's own metadata, which
+// point at its root module.
+const KindCrate = "crate"
+
+// CrateMeta is a crate's registry description, embedded so searches like "a
+// crate for argument parsing" can find the crate itself.
+type CrateMeta struct {
+	CrateID     int
+	Description string
+	Keywords    []string
+	Categories  []string
+	Repository  string
+	// ContentHash is the CAS hash of the embedded summary, or "" when the
+	// registry had nothing to embed.
+	ContentHash string
+}
+
+// addCrateMeta is migration 4.
+func addCrateMeta(tx *sql.Tx) error {
+	for _, stmt := range []string{
+		`CREATE TABLE crate_meta (
+			crate_id INTEGER PRIMARY KEY REFERENCES crates(id),
+			description TEXT NOT NULL DEFAULT '',
+			keywords TEXT NOT NULL DEFAULT 'null',
+			categories TEXT NOT NULL DEFAULT 'null',
+			repository TEXT NOT NULL DEFAULT '',
+			content_hash TEXT NOT NULL DEFAULT ''
+		)`,
+		`CREATE INDEX idx_crate_meta_hash ON crate_meta (content_hash)`,
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetCrateMeta stores meta, replacing what was stored for its crate.
+func (db *DB) SetCrateMeta(meta CrateMeta) error {
+	keywords, _ := json.Marshal(meta.Keywords)
+	categories, _ := json.Marshal(meta.Categories)
+	_, err := db.conn.Exec(
+		`INSERT INTO crate_meta (crate_id, description, keywords, categories, repository, content_hash) VALUES (?, ?, ?, ?, ?, ?)
+		 ON CONFLICT (crate_id) DO UPDATE SET description = EXCLUDED.description, keywords = EXCLUDED.keywords,
+			categories = EXCLUDED.categories, repository = EXCLUDED.repository, content_hash = EXCLUDED.content_hash`,
+		meta.CrateID, meta.Description, string(keywords), string(categories), meta.Repository, meta.ContentHash)
+	if err != nil {
+		return fmt.Errorf("storing crate metadata for crate %d: %w", meta.CrateID, err)
+	}
+	return nil
+}
+
+// GetCrateMeta returns a crate's metadata, or nil if none is stored.
+func (db *DB) GetCrateMeta(crateID int) (*CrateMeta, error) {
+	return scanCrateMeta(db.reader.QueryRow(`SELECT `+crateMetaColumns+` FROM crate_meta WHERE crate_id = ?`, crateID))
+}
+
+// GetCrateMetaForHash returns the metadata embedded under contentHash for a
+// crate the filter allows, preferring the most recently processed crate.
+func (db *DB) GetCrateMetaForHash(contentHash string, filter Filter) (*CrateMeta, error) {
+	where, params, ok := filter.metaWhere()
+	if !ok {
+		return nil, nil
+	}
+	query := `SELECT ` + crateMetaColumns + ` FROM crate_meta WHERE content_hash = ?`
+	if where != "" {
+		query += " AND " + where
+	}
+	query += ` ORDER BY (SELECT processed_at FROM crates WHERE crates.id = crate_meta.crate_id) DESC, crate_id LIMIT 1`
+	return scanCrateMeta(db.reader.QueryRow(query, append([]interface{}{contentHash}, params...)...))
+}
+
+const crateMetaColumns = `crate_id, description, keywords, categories, repository, content_hash`
+
+func scanCrateMeta(row *sql.Row) (*CrateMeta, error) {
+	var m CrateMeta
+	var keywords, categories string
+	err := row.Scan(&m.CrateID, &m.Description, &keywords, &categories, &m.Repository, &m.ContentHash)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	json.Unmarshal([]byte(keywords), &m.Keywords)
+	json.Unmarshal([]byte(categories), &m.Categories)
+	return &m, nil
+}
+
+// metaWhere returns a SQL condition over the crate_meta table and its
+// parameters, and false when the filter rules out crate hits: it matches
+// examples or features, or leaves KindCrate out.
+func (f Filter) metaWhere() (string, []interface{}, bool) {
+	if f.Examples || len(f.Features) > 0 || len(f.Kinds) > 0 && !slices.Contains(f.Kinds, KindCrate) || slices.Contains(f.ExcludeKinds, KindCrate) {
+		return "", nil, false
+	}
+	var conds []string
+	var params []interface{}
+	for _, c := range []struct {
+		op  string
+		ids []int
+	}{{"IN", f.CrateIDs}, {"NOT IN", f.ExcludeCrateIDs}} {
+		if len(c.ids) == 0 {
+			continue
+		}
+		placeholders := make([]string, len(c.ids))
+		for i, id := range c.ids {
+			placeholders[i] = "?"
+			params = append(params, id)
+		}
+		conds = append(conds, fmt.Sprintf(`crate_meta.crate_id %s (%s)`, c.op, strings.Join(placeholders, ",")))
+	}
+	return strings.Join(conds, " AND "), params, true
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCrateMeta(t *testing.T) {
+	t.Parallel()
+	db := testDB(t)
+	clap, _ := db.UpsertCrate("clap", "4.5.0")
+	serde, _ := db.UpsertCrate("serde", "1.0.0")
+	if err := db.ReplaceCrateItems(clap.ID, []ItemRecord{
+		{Item: &Item{RustdocID: "0", Name: "clap", Path: "clap", Kind: "module", ContentHash: "clap-docs"}},
+		{Item: &Item{RustdocID: "1", Name: "Parser", Path: "clap::Parser", Kind: "trait"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []CrateMeta{
+		{CrateID: clap.ID, Description: "argument parser", ContentHash: "stale"},
+		{CrateID: clap.ID, Description: "A command line argument parser", Keywords: []string{"cli", "arg"}, Categories: []string{"command-line-interface"}, ContentHash: "clap-meta"},
+		{CrateID: serde.ID, Description: "A serialization framework", ContentHash: "serde-meta"},
+	} {
+		if err := db.SetCrateMeta(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	meta, err := db.GetCrateMeta(clap.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta == nil || meta.ContentHash != "clap-meta" || !slices.Equal(meta.Keywords, []string{"cli", "arg"}) || !slices.Equal(meta.Categories, []string{"command-line-interface"}) {
+		t.Errorf("GetCrateMeta = %+v, want the latest stored", meta)
+	}
+	if root, err := db.GetRootItem(clap.ID); err != nil || root == nil || root.Path != "clap" {
+		t.Errorf("GetRootItem = %+v, %v; want the clap module", root, err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"unfiltered", Filter{}, true},
+		{"crate filter", Filter{CrateIDs: []int{clap.ID}}, true},
+		{"other crate", Filter{CrateIDs: []int{serde.ID}}, false},
+		{"excluded crate", Filter{ExcludeCrateIDs: []int{clap.ID}}, false},
+		{"crate kind", Filter{Kinds: []string{KindCrate, "trait"}}, true},
+		{"item kinds", Filter{Kinds: []string{"trait"}}, false},
+		{"excluded kind", Filter{ExcludeKinds: []string{KindCrate}}, false},
+		{"features", Filter{Features: []string{"derive"}}, false},
+		{"examples", Filter{Examples: true}, false},
+	} {
+		got, err := db.GetCrateMetaForHash("clap-meta", tc.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (got != nil) != tc.want {
+			t.Errorf("%s: GetCrateMetaForHash = %+v, want found %v", tc.name, got, tc.want)
+		}
+		if tc.filter.IsEmpty() {
+			continue
+		}
+		hashes, err := db.contentHashesForFilter(tc.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hashes["clap-meta"] != tc.want || hashes["serde-meta"] && slices.Equal(tc.filter.CrateIDs, []int{clap.ID}) {
+			t.Errorf("%s: filtered hashes = %v", tc.name, hashes)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+package db
+
+import "database/sql"
+
+// addItemDeprecation is migration 6. Items indexed before it count as not
+// deprecated until their crate is indexed again.
+func addItemDeprecation(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE items ADD COLUMN deprecation TEXT NOT NULL DEFAULT ''`)
+	return err
+}
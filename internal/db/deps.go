@@ -0,0 +1,84 @@
+package db
+
+import "fmt"
+
+// Dependency is one edge of the crate dependency graph. Req is the version
+// requirement from the registry; Version is the version docs.rs actually built
+// against, when the rustdoc JSON names it.
+type Dependency struct {
+	Name     string
+	Req      string
+	Version  string
+	Kind     string // "normal", "build" or "dev"
+	Optional bool
+}
+
+// ReplaceDependencies stores deps as the crate's complete dependency list.
+func (db *DB) ReplaceDependencies(crateID int, deps []Dependency) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM dependencies WHERE crate_id = ?`, crateID); err != nil {
+		return fmt.Errorf("clearing dependencies: %w", err)
+	}
+	for _, d := range deps {
+		kind := d.Kind
+		if kind == "" {
+			kind = "normal"
+		}
+		if _, err := tx.Exec(
+			`INSERT INTO dependencies (crate_id, name, req, version, kind, optional) VALUES (?, ?, ?, ?, ?, ?)
+			 ON CONFLICT (crate_id, name, kind) DO UPDATE SET req = EXCLUDED.req, version = EXCLUDED.version, optional = EXCLUDED.optional`,
+			crateID, d.Name, d.Req, d.Version, kind, d.Optional,
+		); err != nil {
+			return fmt.Errorf("inserting dependency %s: %w", d.Name, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ListDependencies returns a crate's dependencies ordered by kind, then name.
+func (db *DB) ListDependencies(crateID int) ([]Dependency, error) {
+	rows, err := db.reader.Query(
+		`SELECT name, req, version, kind, optional FROM dependencies WHERE crate_id = ?
+		 ORDER BY CASE kind WHEN 'normal' THEN 0 WHEN 'build' THEN 1 ELSE 2 END, name`, crateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deps []Dependency
+	for rows.Next() {
+		var d Dependency
+		if err := rows.Scan(&d.Name, &d.Req, &d.Version, &d.Kind, &d.Optional); err != nil {
+			return nil, err
+		}
+		deps = append(deps, d)
+	}
+	return deps, rows.Err()
+}
+
+// ListDependents returns the crates in db's namespace with a non-dev
+// dependency on name.
+func (db *DB) ListDependents(name string) ([]Crate, error) {
+	rows, err := db.reader.Query(`SELECT `+crateColumns+` FROM crates
+		WHERE namespace = ? AND id IN (SELECT crate_id FROM dependencies WHERE name = ? AND kind != 'dev')
+		ORDER BY name, id`, db.namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var crates []Crate
+	for rows.Next() {
+		c, err := scanCrate(rows)
+		if err != nil {
+			return nil, err
+		}
+		crates = append(crates, *c)
+	}
+	return crates, rows.Err()
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDependencies(t *testing.T) {
+	db := testDB(t)
+
+	app, err := db.UpsertCrate("app", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.UpsertCrate("serde", "1.0.200"); err != nil {
+		t.Fatal(err)
+	}
+
+	deps := []Dependency{
+		{Name: "tempfile", Req: "^3", Kind: "dev"},
+		{Name: "serde", Req: "^1.0", Version: "1.0.200"},
+		{Name: "cc", Req: "^1", Kind: "build"},
+		{Name: "log", Req: "^0.4", Optional: true},
+	}
+	if err := db.ReplaceDependencies(app.ID, deps); err != nil {
+		t.Fatal(err)
+	}
+	// Replacing again must not duplicate rows.
+	if err := db.ReplaceDependencies(app.ID, deps); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.ListDependencies(app.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, d := range got {
+		names = append(names, d.Kind+":"+d.Name)
+	}
+	want := "normal:log normal:serde build:cc dev:tempfile"
+	if g := strings.Join(names, " "); g != want {
+		t.Errorf("got %q, want %q", g, want)
+	}
+	if !got[0].Optional || got[1].Version != "1.0.200" {
+		t.Errorf("unexpected dependency fields: %+v", got)
+	}
+
+	dependents, err := db.ListDependents("serde")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dependents) != 1 || dependents[0].Name != "app" {
+		t.Errorf("expected app to depend on serde, got %+v", dependents)
+	}
+	if dependents, _ := db.ListDependents("tempfile"); len(dependents) != 0 {
+		t.Errorf("dev dependencies should not count as dependents, got %+v", dependents)
+	}
+}
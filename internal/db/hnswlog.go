@@ -0,0 +1,148 @@
+package db
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+// The HNSW index is only written out whole at checkpoints. Vectors added in
+// between are appended to a log next to it, and the log is replayed into the
+// index on load, so a crash loses nothing that was committed to SQLite.
+const (
+	// checkpointEvery is how many logged vectors trigger a checkpoint.
+	checkpointEvery = 20000
+	// checkpointInterval is how old the last checkpoint may get before new
+	// vectors trigger one.
+	checkpointInterval = 5 * time.Minute
+	// maxLogDim bounds entry sizes read back, well above any embedding size.
+	maxLogDim = 1 << 16
+)
+
+// HNSWLogPath returns where vectors added since the last checkpoint of the
+// HNSW index for the database at dbPath are logged.
+func HNSWLogPath(dbPath string) string {
+	return HNSWPath(dbPath) + ".log"
+}
+
+// hnswLog is the append log of vectors added since the last checkpoint.
+// Each entry is a frame of payload length, CRC-32 of the payload, and the
+// payload: the embedding ID and its float32 components, little-endian.
+type hnswLog struct {
+	// mu is held across appending and adding to the index, and across
+	// checkpoints, so a checkpoint never truncates an entry missing from
+	// the index file it just wrote.
+	mu             sync.Mutex
+	path           string
+	f              *os.File
+	entries        int
+	lastCheckpoint time.Time
+}
+
+func newHNSWLog(path string) *hnswLog {
+	return &hnswLog{path: path, lastCheckpoint: time.Now()}
+}
+
+// append writes entries for ids and vecs and syncs them to disk. Callers
+// hold l.mu.
+func (l *hnswLog) append(ids []int, vecs [][]float32) error {
+	if l.f == nil {
+		f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("opening HNSW log: %w", err)
+		}
+		l.f = f
+	}
+	var buf []byte
+	for i, vec := range vecs {
+		payload := make([]byte, 8+4*len(vec))
+		binary.LittleEndian.PutUint64(payload, uint64(ids[i]))
+		for j, v := range vec {
+			binary.LittleEndian.PutUint32(payload[8+4*j:], math.Float32bits(v))
+		}
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(payload)))
+		buf = binary.LittleEndian.AppendUint32(buf, crc32.ChecksumIEEE(payload))
+		buf = append(buf, payload...)
+	}
+	if _, err := l.f.Write(buf); err != nil {
+		return fmt.Errorf("writing HNSW log: %w", err)
+	}
+	if err := l.f.Sync(); err != nil {
+		return fmt.Errorf("syncing HNSW log: %w", err)
+	}
+	l.entries += len(vecs)
+	return nil
+}
+
+// due reports whether enough has been logged to warrant a checkpoint.
+// Callers hold l.mu.
+func (l *hnswLog) due() bool {
+	return l.entries >= checkpointEvery || (l.entries > 0 && time.Since(l.lastCheckpoint) >= checkpointInterval)
+}
+
+// reset empties the log after a checkpoint. Callers hold l.mu.
+func (l *hnswLog) reset() error {
+	l.close()
+	l.entries = 0
+	l.lastCheckpoint = time.Now()
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing HNSW log: %w", err)
+	}
+	return nil
+}
+
+func (l *hnswLog) close() {
+	if l.f != nil {
+		l.f.Close()
+		l.f = nil
+	}
+}
+
+// replay calls add for each intact entry of dimension dim, in order. A torn
+// or corrupt entry, as left by a crash mid-append, ends the replay.
+func (l *hnswLog) replay(dim int, add func(id int, vec []float32)) (int, error) {
+	f, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("opening HNSW log: %w", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	header := make([]byte, 8)
+	n := 0
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if !errors.Is(err, io.EOF) {
+				slog.Warn("ignoring torn HNSW log entry", "after", n)
+			}
+			return n, nil
+		}
+		size := binary.LittleEndian.Uint32(header)
+		if size < 8 || (size-8)%4 != 0 || size > 8+4*maxLogDim {
+			slog.Warn("ignoring corrupt HNSW log entry", "after", n)
+			return n, nil
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(r, payload); err != nil || crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(header[4:]) {
+			slog.Warn("ignoring torn HNSW log entry", "after", n)
+			return n, nil
+		}
+		id := int(binary.LittleEndian.Uint64(payload))
+		vec := deserializeFloat32(payload[8:])
+		if len(vec) != dim {
+			continue
+		}
+		add(id, vec)
+		n++
+	}
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// crash closes db without checkpointing the HNSW index, as if the process
+// had died.
+func crash(db *DB) {
+	db.hnswLog.close()
+	db.reader.Close()
+	db.conn.Close()
+}
+
+func TestHNSWLog_ReplayAfterCrash(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.saveHNSW()
+
+	records := make([]EmbeddingRecord, 3)
+	for i := range records {
+		records[i] = EmbeddingRecord{ContentHash: "hash", ChunkText: "chunk", ChunkIndex: i, Embedding: testEmbedding(1024)}
+		records[i].Embedding[i] = 2
+	}
+	if err := db.InsertEmbeddings(records); err != nil {
+		t.Fatal(err)
+	}
+	crash(db)
+
+	// A crash mid-append leaves a torn entry at the end of the log.
+	f, err := os.OpenFile(HNSWLogPath(path), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("log missing: %v", err)
+	}
+	f.Write([]byte{1, 2, 3})
+	f.Close()
+
+	db, err = New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if got := db.HNSWSize(); got != len(records) {
+		t.Errorf("index has %d vectors after replay, want %d", got, len(records))
+	}
+	if _, err := os.Stat(HNSWLogPath(path)); !os.IsNotExist(err) {
+		t.Errorf("log not emptied after replay: %v", err)
+	}
+	results, err := db.VectorSearch(records[2].Embedding, 0, 10, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) == 0 || results[0].ChunkIndex != 2 {
+		t.Errorf("expected chunk 2 first, got %+v", results)
+	}
+}
+
+func TestHNSWLog_Checkpoint(t *testing.T) {
+	t.Parallel()
+	db := testDB(t)
+	if err := db.InsertEmbedding("hash", "chunk", 0, testEmbedding(1024)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(HNSWLogPath(db.path)); err != nil {
+		t.Fatalf("insert not logged: %v", err)
+	}
+	db.saveHNSW()
+	if _, err := os.Stat(HNSWLogPath(db.path)); !os.IsNotExist(err) {
+		t.Errorf("log not emptied by checkpoint: %v", err)
+	}
+	if _, err := os.Stat(db.hnswPath + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary index file left behind: %v", err)
+	}
+}
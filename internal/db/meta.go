@@ -0,0 +1,161 @@
+package db
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"strconv"
+)
+
+// DefaultEmbeddingDim is assumed for databases with neither recorded
+// metadata nor embeddings to measure.
+const DefaultEmbeddingDim = 1024
+
+const (
+	metaEmbeddingModel = "embedding_model"
+	metaEmbeddingDim   = "embedding_dim"
+)
+
+// getMeta returns the metadata value for key, or "" if it isn't set.
+func (db *DB) getMeta(key string) (string, error) {
+	var value string
+	err := db.conn.QueryRow(`SELECT value FROM metadata WHERE key = ?`, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading metadata %s: %w", key, err)
+	}
+	return value, nil
+}
+
+func (db *DB) setMeta(tx *sql.Tx, key, value string) error {
+	if _, err := tx.Exec(`INSERT INTO metadata (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value`, key, value); err != nil {
+		return fmt.Errorf("writing metadata %s: %w", key, err)
+	}
+	return nil
+}
+
+// loadEmbeddingDim sets db.dim from the recorded metadata. Databases from
+// older builds have none, so the dimension is measured from a stored
+// embedding instead.
+func (db *DB) loadEmbeddingDim() error {
+	value, err := db.getMeta(metaEmbeddingDim)
+	if err != nil {
+		return err
+	}
+	if value != "" {
+		dim, err := strconv.Atoi(value)
+		if err != nil || dim <= 0 {
+			return fmt.Errorf("invalid embedding dimension %q in metadata", value)
+		}
+		db.dim = dim
+		return nil
+	}
+
+	var size int
+	// Only float32 rows predate the metadata table.
+	err = db.conn.QueryRow(`SELECT length(embedding) FROM embeddings WHERE encoding = '' LIMIT 1`).Scan(&size)
+	switch {
+	case err == sql.ErrNoRows:
+		db.dim = DefaultEmbeddingDim
+	case err != nil:
+		return fmt.Errorf("measuring stored embeddings: %w", err)
+	default:
+		db.dim = size / 4
+	}
+	return nil
+}
+
+// EmbeddingModel returns the model and dimension the stored embeddings were
+// produced with. The model is "" until SetEmbeddingModel records one.
+func (db *DB) EmbeddingModel() (string, int, error) {
+	model, err := db.getMeta(metaEmbeddingModel)
+	if err != nil {
+		return "", 0, err
+	}
+	return model, db.dim, nil
+}
+
+// CountEmbeddings returns the number of stored embedding chunks.
+func (db *DB) CountEmbeddings() (int, error) {
+	var count int
+	if err := db.reader.QueryRow(`SELECT COUNT(*) FROM embeddings`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("counting embeddings: %w", err)
+	}
+	return count, nil
+}
+
+// SetEmbeddingModel records the model and dimension of new embeddings. The
+// dimension can only change while there are no embeddings; use
+// ResetEmbeddings to switch a populated index.
+func (db *DB) SetEmbeddingModel(model string, dim int) error {
+	if dim != db.dim {
+		count, err := db.CountEmbeddings()
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("cannot change embedding dimension from %d to %d with %d embeddings stored", db.dim, dim, count)
+		}
+	}
+	return db.recordEmbeddingModel(model, dim, false)
+}
+
+// ResetEmbeddings deletes every embedding, empties the HNSW index and
+// records model and dim for the embeddings that replace them. Items and
+// content are kept; re-indexing the crates embeds them again. Every crate
+// is marked unprocessed, so one a re-embed doesn't reach is added again
+// rather than skipped.
+func (db *DB) ResetEmbeddings(model string, dim int) error {
+	return db.recordEmbeddingModel(model, dim, true)
+}
+
+func (db *DB) recordEmbeddingModel(model string, dim int, clear bool) error {
+	if dim <= 0 {
+		return fmt.Errorf("invalid embedding dimension %d", dim)
+	}
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if clear {
+		if _, err := tx.Exec(`DELETE FROM embeddings`); err != nil {
+			return fmt.Errorf("deleting embeddings: %w", err)
+		}
+		if _, err := tx.Exec(`UPDATE crates SET processed_at = NULL`); err != nil {
+			return fmt.Errorf("marking crates unprocessed: %w", err)
+		}
+	}
+	if err := db.setMeta(tx, metaEmbeddingModel, model); err != nil {
+		return err
+	}
+	if err := db.setMeta(tx, metaEmbeddingDim, strconv.Itoa(dim)); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if !clear && dim == db.dim {
+		return nil
+	}
+	if db.diskIndex() {
+		db.dim = dim
+		return nil
+	}
+	// Load replaces the index in place under its own lock, so concurrent
+	// searches see either the old index or the empty one.
+	var buf bytes.Buffer
+	if err := newHNSW(dim).Save(&buf); err != nil {
+		return fmt.Errorf("creating HNSW index: %w", err)
+	}
+	if err := db.hnsw.Load(&buf); err != nil {
+		return fmt.Errorf("resetting HNSW index: %w", err)
+	}
+	db.dim = dim
+	db.saveHNSW()
+	return nil
+}
@@ -0,0 +1,90 @@
+package db
+
+import "testing"
+
+func TestEmbeddingModel(t *testing.T) {
+	t.Parallel()
+	db := testDB(t)
+
+	model, dim, err := db.EmbeddingModel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model != "" || dim != DefaultEmbeddingDim {
+		t.Fatalf("fresh database: got %q/%d, want \"\"/%d", model, dim, DefaultEmbeddingDim)
+	}
+
+	// The dimension can change freely while the index is empty.
+	if err := db.SetEmbeddingModel("voyage-3-lite", 512); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.InsertEmbedding("hash", "chunk", 0, testEmbedding(512)); err != nil {
+		t.Fatalf("insert at new dimension: %v", err)
+	}
+	if err := db.SetEmbeddingModel("voyage-3.5", 1024); err == nil {
+		t.Error("expected error changing dimension with embeddings stored")
+	}
+
+	crate, err := db.UpsertCrate("serde", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.MarkCrateProcessed(crate.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.ResetEmbeddings("voyage-3.5", 1024); err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := db.GetCrate("serde", "1.0.0"); c == nil || c.ProcessedAt != nil {
+		t.Errorf("crate still processed after reset: %+v", c)
+	}
+	model, dim, _ = db.EmbeddingModel()
+	if model != "voyage-3.5" || dim != 1024 {
+		t.Errorf("after reset: got %q/%d", model, dim)
+	}
+	if db.HasEmbeddings("hash") {
+		t.Error("embeddings survived reset")
+	}
+	if err := db.InsertEmbedding("hash", "chunk", 0, testEmbedding(1024)); err != nil {
+		t.Fatalf("insert after reset: %v", err)
+	}
+}
+
+func TestEmbeddingModel_Reopen(t *testing.T) {
+	t.Parallel()
+	path := t.TempDir() + "/test.db"
+	db, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetEmbeddingModel("voyage-3-lite", 512); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.InsertEmbedding("hash", "chunk", 0, testEmbedding(512)); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	db, err = New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if model, dim, _ := db.EmbeddingModel(); model != "voyage-3-lite" || dim != 512 {
+		t.Errorf("after reopen: got %q/%d", model, dim)
+	}
+	hits, err := db.knnSearch(testEmbedding(512), 1, 0, nil)
+	if err != nil || len(hits) != 1 {
+		t.Errorf("search after reopen: %v, %v", hits, err)
+	}
+}
+
+// testEmbedding returns a non-zero embedding of the given dimension.
+func testEmbedding(dim int) []float32 {
+	emb := make([]float32, dim)
+	for i := range emb {
+		emb[i] = float32(i+1) / float32(dim)
+	}
+	return emb
+}
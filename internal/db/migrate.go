@@ -0,0 +1,138 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// migration upgrades the schema by one version. Released migrations are
+// never edited; schema changes go in a new migration appended to
+// migrations.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+// migrations are applied in order, each in its own transaction.
+var migrations = []migration{
+	{1, "initial schema", createSchema},
+	{2, "crate namespaces", addCrateNamespaces},
+	{3, "crate info", addCrateInfo},
+	{4, "crate metadata", addCrateMeta},
+	{5, "embedding versions", addEmbeddingVersions},
+	{6, "item deprecation", addItemDeprecation},
+	{7, "item stability", addItemStability},
+	{8, "embedding usage", addEmbeddingUsage},
+	{9, "crate registries", addCrateRegistries},
+	{10, "detached embedding usage", detachEmbeddingUsage},
+}
+
+// SchemaVersion is the schema version this build creates and upgrades to.
+func SchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// BackupPath returns where the database at dbPath is copied before a
+// migration changes it.
+func BackupPath(dbPath string) string {
+	return dbPath + ".bak"
+}
+
+// schemaVersion returns the version recorded in conn's schema_version
+// table: 0 when there is none, including for databases from builds before
+// migrations were versioned. populated reports whether conn has any tables
+// at all, i.e. whether there is anything worth backing up.
+func schemaVersion(conn *sql.DB) (version int, populated bool, err error) {
+	var tables int
+	if err := conn.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'`).Scan(&tables); err != nil {
+		return 0, false, fmt.Errorf("inspecting schema: %w", err)
+	}
+	if tables == 0 {
+		return 0, false, nil
+	}
+	var hasVersions int
+	if err := conn.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_version'`).Scan(&hasVersions); err != nil {
+		return 0, true, fmt.Errorf("inspecting schema: %w", err)
+	}
+	if hasVersions == 0 {
+		return 0, true, nil
+	}
+	if err := conn.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version); err != nil {
+		return 0, true, fmt.Errorf("reading schema version: %w", err)
+	}
+	return version, true, nil
+}
+
+// checkSchemaVersion rejects databases written by a newer build, whose
+// schema this one can't know how to use.
+func checkSchemaVersion(version int) error {
+	if version > SchemaVersion() {
+		return fmt.Errorf("database schema version %d is newer than this build supports (%d); upgrade rsdoc", version, SchemaVersion())
+	}
+	return nil
+}
+
+// migrate brings the schema up to SchemaVersion. When backup is set and an
+// existing database needs upgrading, it is first copied to BackupPath so a
+// failed or unwanted upgrade can be undone by hand.
+func (db *DB) migrate(backup bool) error {
+	version, populated, err := schemaVersion(db.conn)
+	if err != nil {
+		return err
+	}
+	if err := checkSchemaVersion(version); err != nil {
+		return err
+	}
+	if version == SchemaVersion() {
+		return nil
+	}
+
+	if backup && populated {
+		path := BackupPath(db.path)
+		os.Remove(path)
+		if _, err := db.conn.Exec(`VACUUM INTO ?`, path); err != nil {
+			return fmt.Errorf("backing up database before migrating: %w", err)
+		}
+		slog.Info("backed up database before migrating", "path", path, "from", version, "to", SchemaVersion())
+	}
+
+	if _, err := db.conn.Exec(`CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("creating schema_version: %w", err)
+	}
+	for _, m := range migrations {
+		if m.version <= version {
+			continue
+		}
+		if err := db.applyMigration(m); err != nil {
+			return err
+		}
+		if populated {
+			slog.Info("migrated database", "version", m.version, "migration", m.name)
+		}
+	}
+	return nil
+}
+
+func (db *DB) applyMigration(m migration) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := m.up(tx); err != nil {
+		return fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)`,
+		m.version, m.name, time.Now().UTC()); err != nil {
+		return fmt.Errorf("recording migration %d: %w", m.version, err)
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func recordedVersion(t *testing.T, db *DB) int {
+	t.Helper()
+	version, _, err := schemaVersion(db.conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return version
+}
+
+func TestMigrate_Fresh(t *testing.T) {
+	t.Parallel()
+	db := testDB(t)
+	if got := recordedVersion(t, db); got != SchemaVersion() {
+		t.Errorf("schema version = %d, want %d", got, SchemaVersion())
+	}
+	if _, err := os.Stat(BackupPath(db.path)); !os.IsNotExist(err) {
+		t.Errorf("fresh database was backed up: %v", err)
+	}
+}
+
+func TestMigrate_Legacy(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	// A database from a build before crate sources and schema versions.
+	conn, err := sql.Open("sqlite3", "file:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []string{
+		`CREATE TABLE crates (
+			id INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			version TEXT NOT NULL,
+			fetched_at TIMESTAMP,
+			processed_at TIMESTAMP,
+			last_used_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(name, version)
+		)`,
+		`INSERT INTO crates (name, version) VALUES ('serde', '1.0.0')`,
+	} {
+		if _, err := conn.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conn.Close()
+
+	db, err := New(path)
+	if err != nil {
+		t.Fatalf("opening legacy database: %v", err)
+	}
+	defer db.Close()
+
+	if got := recordedVersion(t, db); got != SchemaVersion() {
+		t.Errorf("schema version = %d, want %d", got, SchemaVersion())
+	}
+	crate, err := db.GetCrate("serde", "1.0.0")
+	if err != nil || crate == nil {
+		t.Fatalf("legacy crate after migration: %v, %v", crate, err)
+	}
+	if crate.Source != "" || crate.Registry != "" {
+		t.Errorf("added columns: source %q, registry %q", crate.Source, crate.Registry)
+	}
+
+	backup, err := sql.Open("sqlite3", "file:"+BackupPath(path)+"?mode=ro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backup.Close()
+	version, populated, err := schemaVersion(backup)
+	if err != nil {
+		t.Fatalf("reading backup: %v", err)
+	}
+	if version != 0 || !populated {
+		t.Errorf("backup: version %d, populated %v; want the pre-migration database", version, populated)
+	}
+}
+
+func TestMigrate_Newer(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.conn.Exec(`INSERT INTO schema_version (version, name, applied_at) VALUES (?, 'future', CURRENT_TIMESTAMP)`, SchemaVersion()+1); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	if db, err := New(path); err == nil {
+		db.Close()
+		t.Fatal("expected an error opening a database from a newer build")
+	}
+}
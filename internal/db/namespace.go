@@ -0,0 +1,227 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"regexp"
+)
+
+// Namespaces keep separate indexes in one database: each crate version is
+// indexed once per namespace, and crates are only looked up by name within
+// their own. Items, fragments and examples belong to crates and follow
+// them; embeddings and CAS content are keyed by content hash and shared.
+
+var namespaceRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// ValidateNamespace rejects namespace names other than "" (the default) or
+// up to 64 lowercase letters, digits, '-' and '_', starting with a letter
+// or digit.
+func ValidateNamespace(ns string) error {
+	if ns != "" && !namespaceRe.MatchString(ns) {
+		return fmt.Errorf("invalid namespace %q: use lowercase letters, digits, '-' and '_'", ns)
+	}
+	return nil
+}
+
+// InNamespace returns a view of db whose crate lookups are scoped to ns. It
+// shares db's connections and vector index, so only the original needs
+// closing.
+func (db *DB) InNamespace(ns string) *DB {
+	if ns == db.namespace {
+		return db
+	}
+	return &DB{store: db.store, namespace: ns}
+}
+
+// Namespace returns the namespace db is scoped to.
+func (db *DB) Namespace() string {
+	return db.namespace
+}
+
+// Scope restricts filter to db's namespace. Filters naming crates were
+// resolved within it already; otherwise, while other namespaces exist, the
+// filter is pinned to this namespace's crates. ok is false when the
+// namespace has none, so there is nothing to search.
+func (db *DB) Scope(filter Filter) (scoped Filter, ok bool, err error) {
+	if len(filter.CrateIDs) > 0 {
+		return filter, true, nil
+	}
+	var others bool
+	if err := db.reader.QueryRow(`SELECT EXISTS (SELECT 1 FROM crates WHERE namespace != ?)`, db.namespace).Scan(&others); err != nil {
+		return filter, false, fmt.Errorf("checking namespaces: %w", err)
+	}
+	if !others {
+		return filter, true, nil
+	}
+	rows, err := db.reader.Query(`SELECT id FROM crates WHERE namespace = ?`, db.namespace)
+	if err != nil {
+		return filter, false, fmt.Errorf("listing namespace crates: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return filter, false, err
+		}
+		filter.CrateIDs = append(filter.CrateIDs, id)
+	}
+	return filter, len(filter.CrateIDs) > 0, rows.Err()
+}
+
+// NamespaceInfo summarizes one namespace.
+type NamespaceInfo struct {
+	Name   string
+	Crates int
+	Items  int
+}
+
+// ListNamespaces returns every namespace holding crates, the default first.
+func (db *DB) ListNamespaces() ([]NamespaceInfo, error) {
+	rows, err := db.reader.Query(`
+		SELECT c.namespace, COUNT(DISTINCT c.id), COUNT(i.id)
+		FROM crates c LEFT JOIN items i ON i.crate_id = c.id
+		GROUP BY c.namespace
+		ORDER BY c.namespace`)
+	if err != nil {
+		return nil, fmt.Errorf("listing namespaces: %w", err)
+	}
+	defer rows.Close()
+
+	var out []NamespaceInfo
+	for rows.Next() {
+		var ns NamespaceInfo
+		if err := rows.Scan(&ns.Name, &ns.Crates, &ns.Items); err != nil {
+			return nil, err
+		}
+		out = append(out, ns)
+	}
+	return out, rows.Err()
+}
+
+// DeleteNamespace removes db's namespace: its crates with their items,
+// re-exports and dependencies, then the embeddings no crate uses any more.
+// Their embedding usage is kept, so the monthly budget still counts it.
+// It returns how many crates went and the content hashes left unused,
+// whose CAS files the caller may remove.
+func (db *DB) DeleteNamespace() (crates int, unused []string, err error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback()
+
+	ids, err := txInts(tx, `SELECT id FROM crates WHERE namespace = ?`, db.namespace)
+	if err != nil {
+		return 0, nil, fmt.Errorf("listing namespace crates: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil, nil
+	}
+	for _, id := range ids {
+		if err := deleteCrateItems(tx, id); err != nil {
+			return 0, nil, fmt.Errorf("deleting crate %d items: %w", id, err)
+		}
+		for _, table := range []string{"reexports", "dependencies", "crate_meta"} {
+			if _, err := tx.Exec(`DELETE FROM `+table+` WHERE crate_id = ?`, id); err != nil {
+				return 0, nil, fmt.Errorf("deleting crate %d %s: %w", id, table, err)
+			}
+		}
+		// Usage stays, counted against the budget, but no longer belongs to
+		// the crate: SQLite may reuse its ID.
+		if _, err := tx.Exec(`UPDATE embedding_usage SET crate_id = NULL WHERE crate_id = ?`, id); err != nil {
+			return 0, nil, fmt.Errorf("detaching crate %d usage: %w", id, err)
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM crates WHERE namespace = ?`, db.namespace); err != nil {
+		return 0, nil, fmt.Errorf("deleting crates: %w", err)
+	}
+
+	const unusedHashes = `
+		SELECT DISTINCT content_hash FROM embeddings e
+		WHERE NOT EXISTS (SELECT 1 FROM items WHERE content_hash = e.content_hash)
+		  AND NOT EXISTS (SELECT 1 FROM fragments WHERE content_hash = e.content_hash)
+		  AND NOT EXISTS (SELECT 1 FROM examples WHERE content_hash = e.content_hash)
+		  AND NOT EXISTS (SELECT 1 FROM crate_meta WHERE content_hash = e.content_hash)`
+	rows, err := tx.Query(unusedHashes)
+	if err != nil {
+		return 0, nil, fmt.Errorf("finding unused embeddings: %w", err)
+	}
+	for rows.Next() {
+		var h string
+		if err := rows.Scan(&h); err != nil {
+			rows.Close()
+			return 0, nil, err
+		}
+		unused = append(unused, h)
+	}
+	rows.Close()
+	embeddingIDs, err := txInts(tx, `SELECT id FROM embeddings WHERE content_hash IN (`+unusedHashes+`)`)
+	if err != nil {
+		return 0, nil, fmt.Errorf("finding unused embeddings: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM embeddings WHERE content_hash IN (` + unusedHashes + `)`); err != nil {
+		return 0, nil, fmt.Errorf("deleting unused embeddings: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, nil, err
+	}
+
+	if db.hnsw != nil {
+		for _, id := range embeddingIDs {
+			if err := db.hnsw.Delete(id); err != nil {
+				slog.Warn("failed to delete HNSW node", "id", id, "error", err)
+			}
+		}
+		db.saveHNSW()
+	}
+	return len(ids), unused, nil
+}
+
+func txInts(tx *sql.Tx, query string, args ...interface{}) ([]int, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []int
+	for rows.Next() {
+		var n int
+		if err := rows.Scan(&n); err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, rows.Err()
+}
+
+// addCrateNamespaces is migration 2. SQLite can't alter a UNIQUE
+// constraint, so crates is rebuilt with namespace in it; crate IDs are
+// kept, so nothing referring to them changes.
+func addCrateNamespaces(tx *sql.Tx) error {
+	queries := []string{
+		`CREATE TABLE crates_new (
+			id INTEGER PRIMARY KEY,
+			namespace TEXT NOT NULL DEFAULT '',
+			name TEXT NOT NULL,
+			version TEXT NOT NULL,
+			fetched_at TIMESTAMP,
+			processed_at TIMESTAMP,
+			last_used_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			source TEXT NOT NULL DEFAULT '',
+			registry TEXT NOT NULL DEFAULT '',
+			UNIQUE(namespace, name, version)
+		)`,
+		`INSERT INTO crates_new (id, name, version, fetched_at, processed_at, last_used_at, source, registry)
+			SELECT id, name, version, fetched_at, processed_at, last_used_at, source, registry FROM crates`,
+		`DROP TABLE crates`,
+		`ALTER TABLE crates_new RENAME TO crates`,
+		`CREATE INDEX idx_crates_name ON crates (name)`,
+	}
+	for _, q := range queries {
+		if _, err := tx.Exec(q); err != nil {
+			return fmt.Errorf("executing %q: %w", q, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNamespaces(t *testing.T) {
+	t.Parallel()
+	db := testDB(t)
+	work := db.InNamespace("work")
+
+	def, err := db.UpsertCrate("tokio", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scoped, err := work.UpsertCrate("tokio", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def.ID == scoped.ID {
+		t.Fatal("same crate version shared across namespaces")
+	}
+	if c, _ := work.GetCrate("tokio", "1.0.0"); c == nil || c.ID != scoped.ID || c.Namespace != "work" {
+		t.Errorf("work tokio = %+v, want crate %d in work", c, scoped.ID)
+	}
+	if _, err := work.UpsertCrate("serde", "1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := db.GetCrate("serde", "1.0.0"); c != nil {
+		t.Errorf("default namespace sees work's serde: %+v", c)
+	}
+
+	records := func(hash string) []ItemRecord {
+		return []ItemRecord{{Item: &Item{RustdocID: "0", Name: "x", Path: "tokio::x", Kind: "fn", ContentHash: hash}}}
+	}
+	if err := db.ReplaceCrateItems(def.ID, records("shared")); err != nil {
+		t.Fatal(err)
+	}
+	if err := work.ReplaceCrateItems(scoped.ID, append(records("shared"), ItemRecord{Item: &Item{RustdocID: "1", Name: "y", Path: "tokio::y", Kind: "fn", ContentHash: "own"}})); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.InsertEmbeddings([]EmbeddingRecord{
+		{ContentHash: "shared", ChunkText: "x", Embedding: testEmbedding(1024)},
+		{ContentHash: "own", ChunkText: "y", Embedding: testEmbedding(1024)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	filter, ok, err := db.Scope(Filter{})
+	if err != nil || !ok || !slices.Equal(filter.CrateIDs, []int{def.ID}) {
+		t.Errorf("Scope = %v, %v, %v; want crate %d", filter.CrateIDs, ok, err, def.ID)
+	}
+	if _, ok, _ := db.InNamespace("empty").Scope(Filter{}); ok {
+		t.Error("Scope of an empty namespace reports crates")
+	}
+	if got, _ := work.FindItems("y", true, 10, Filter{}); len(got) != 1 {
+		t.Errorf("work FindItems(y) = %d matches, want 1", len(got))
+	}
+	if got, _ := db.FindItems("y", true, 10, Filter{}); len(got) != 0 {
+		t.Errorf("default FindItems(y) = %d matches, want 0", len(got))
+	}
+
+	namespaces, err := db.ListNamespaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []NamespaceInfo{{"", 1, 1}, {"work", 2, 2}}; !slices.Equal(namespaces, want) {
+		t.Errorf("ListNamespaces = %+v, want %+v", namespaces, want)
+	}
+
+	if err := work.RecordUsage(scoped.ID, "voyage-3.5", 1000); err != nil {
+		t.Fatal(err)
+	}
+
+	crates, unused, err := work.DeleteNamespace()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if crates != 2 || !slices.Equal(unused, []string{"own"}) {
+		t.Errorf("DeleteNamespace = %d crates, unused %v; want 2, [own]", crates, unused)
+	}
+	if !db.HasEmbeddings("shared") || db.HasEmbeddings("own") {
+		t.Error("DeleteNamespace should keep shared embeddings and drop its own")
+	}
+	if c, _ := db.GetCrate("tokio", "1.0.0"); c == nil {
+		t.Error("DeleteNamespace removed the default namespace's crate")
+	}
+	if used, _ := db.UsageSince(time.Time{}); used != 1000 {
+		t.Errorf("usage after DeleteNamespace = %d, want 1000 still counted", used)
+	}
+	if usage, _ := db.UsageByCrate(); len(usage) != 0 {
+		t.Errorf("UsageByCrate after DeleteNamespace = %v, want none", usage)
+	}
+}
+
+func TestValidateNamespace(t *testing.T) {
+	t.Parallel()
+	for _, ns := range []string{"", "work", "agent-1", "a_b"} {
+		if err := ValidateNamespace(ns); err != nil {
+			t.Errorf("ValidateNamespace(%q) = %v", ns, err)
+		}
+	}
+	for _, ns := range []string{"Work", "-x", "a/b", "a b"} {
+		if err := ValidateNamespace(ns); err == nil {
+			t.Errorf("ValidateNamespace(%q) accepted", ns)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package db
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Embedding encodings, selected by index.quantization. Each row records its
+// own encoding, so changing the setting only affects new embeddings.
+const (
+	QuantizeNone    = "none"
+	QuantizeFloat16 = "float16"
+	QuantizeInt8    = "int8"
+)
+
+// SetQuantization selects how new embeddings are stored. Vectors are
+// round-tripped through the encoding before they enter the HNSW index, so
+// it matches what a rebuild from SQLite would produce. The HNSW index
+// itself always holds float32.
+func (db *DB) SetQuantization(q string) error {
+	q, err := quantization(q)
+	if err != nil {
+		return err
+	}
+	db.quantization = q
+	return nil
+}
+
+// quantization returns the encoding stored for the index.quantization
+// setting q: empty for float32.
+func quantization(q string) (string, error) {
+	switch q {
+	case "", QuantizeNone:
+		return "", nil
+	case QuantizeFloat16, QuantizeInt8:
+		return q, nil
+	}
+	return "", fmt.Errorf("unknown quantization %q (want none, float16 or int8)", q)
+}
+
+// encodeEmbedding serializes v for the embeddings table.
+func encodeEmbedding(v []float32, encoding string) []byte {
+	switch encoding {
+	case QuantizeFloat16:
+		buf := make([]byte, len(v)*2)
+		for i, f := range v {
+			binary.LittleEndian.PutUint16(buf[i*2:], float32ToFloat16(f))
+		}
+		return buf
+	case QuantizeInt8:
+		// A float32 scale followed by one signed byte per component.
+		var maxAbs float32
+		for _, f := range v {
+			maxAbs = max(maxAbs, float32(math.Abs(float64(f))))
+		}
+		scale := maxAbs / 127
+		buf := make([]byte, 4+len(v))
+		binary.LittleEndian.PutUint32(buf, math.Float32bits(scale))
+		if scale == 0 {
+			return buf
+		}
+		for i, f := range v {
+			buf[4+i] = byte(int8(math.Round(float64(f / scale))))
+		}
+		return buf
+	default:
+		return serializeFloat32(v)
+	}
+}
+
+// decodeEmbedding reverses encodeEmbedding.
+func decodeEmbedding(blob []byte, encoding string) ([]float32, error) {
+	switch encoding {
+	case QuantizeFloat16:
+		if len(blob)%2 != 0 {
+			return nil, fmt.Errorf("float16 embedding has odd length %d", len(blob))
+		}
+		v := make([]float32, len(blob)/2)
+		for i := range v {
+			v[i] = float16ToFloat32(binary.LittleEndian.Uint16(blob[i*2:]))
+		}
+		return v, nil
+	case QuantizeInt8:
+		if len(blob) < 4 {
+			return nil, fmt.Errorf("int8 embedding too short (%d bytes)", len(blob))
+		}
+		scale := math.Float32frombits(binary.LittleEndian.Uint32(blob))
+		v := make([]float32, len(blob)-4)
+		for i := range v {
+			v[i] = float32(int8(blob[4+i])) * scale
+		}
+		return v, nil
+	case "", QuantizeNone:
+		if len(blob)%4 != 0 {
+			return nil, fmt.Errorf("float32 embedding length %d is not a multiple of 4", len(blob))
+		}
+		return deserializeFloat32(blob), nil
+	default:
+		return nil, fmt.Errorf("unknown embedding encoding %q", encoding)
+	}
+}
+
+// float32ToFloat16 converts f to IEEE 754 half precision, rounding to
+// nearest. Out-of-range values saturate to infinity.
+func float32ToFloat16(f float32) uint16 {
+	b := math.Float32bits(f)
+	sign := uint16(b>>16) & 0x8000
+	exp := int(b>>23&0xff) - 127 + 15
+	mant := b & 0x7fffff
+
+	switch {
+	case exp >= 0x1f:
+		return sign | 0x7c00
+	case exp <= 0:
+		if exp < -10 {
+			return sign
+		}
+		// Subnormal: shift the mantissa, implicit bit included, into place.
+		mant |= 0x800000
+		shift := uint(14 - exp)
+		half := uint16(mant >> shift)
+		if mant>>(shift-1)&1 != 0 {
+			half++
+		}
+		return sign | half
+	}
+	half := sign | uint16(exp)<<10 | uint16(mant>>13)
+	if mant&0x1000 != 0 {
+		// A carry out of the mantissa correctly bumps the exponent.
+		half++
+	}
+	return half
+}
+
+func float16ToFloat32(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+
+	switch exp {
+	case 0:
+		v := float32(mant) / (1 << 24)
+		if sign != 0 {
+			v = -v
+		}
+		return v
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	}
+	return math.Float32frombits(sign | (exp-15+127)<<23 | mant<<13)
+}
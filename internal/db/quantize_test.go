@@ -0,0 +1,109 @@
+package db
+
+import (
+	"math"
+	"os"
+	"testing"
+)
+
+func TestEncodeEmbedding_RoundTrip(t *testing.T) {
+	t.Parallel()
+	v := []float32{0, 1, -1, 0.5, -0.031, 1e-6, 123.25, -65504}
+	tests := []struct {
+		encoding string
+		size     int
+		tol      float64 // relative to the vector's max magnitude
+	}{
+		{QuantizeNone, len(v) * 4, 0},
+		{QuantizeFloat16, len(v) * 2, 1e-3},
+		{QuantizeInt8, len(v) + 4, 1.0 / 127},
+	}
+	for _, tt := range tests {
+		blob := encodeEmbedding(v, tt.encoding)
+		if len(blob) != tt.size {
+			t.Errorf("%s: %d bytes, want %d", tt.encoding, len(blob), tt.size)
+		}
+		got, err := decodeEmbedding(blob, tt.encoding)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.encoding, err)
+		}
+		for i := range v {
+			diff := math.Abs(float64(got[i] - v[i]))
+			if tt.encoding == QuantizeFloat16 {
+				diff /= math.Max(math.Abs(float64(v[i])), 1e-3)
+			} else {
+				diff /= 65504
+			}
+			if diff > tt.tol {
+				t.Errorf("%s: component %d = %g, want %g", tt.encoding, i, got[i], v[i])
+			}
+		}
+	}
+}
+
+func TestFloat16_Specials(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		in   float32
+		want uint16
+	}{
+		{0, 0x0000},
+		{1, 0x3c00},
+		{-2, 0xc000},
+		{65504, 0x7bff},
+		{1e6, 0x7c00},         // overflow saturates to +Inf
+		{5.960464e-8, 0x0001}, // smallest subnormal
+		{1e-10, 0x0000},       // underflow
+	}
+	for _, tt := range tests {
+		if got := float32ToFloat16(tt.in); got != tt.want {
+			t.Errorf("float32ToFloat16(%g) = %#04x, want %#04x", tt.in, got, tt.want)
+		}
+	}
+	if got := float16ToFloat32(0x0001); got != 5.960464477539063e-8 {
+		t.Errorf("smallest subnormal decoded to %g", got)
+	}
+}
+
+func TestInsertEmbedding_Quantized(t *testing.T) {
+	t.Parallel()
+	path := t.TempDir() + "/test.db"
+	if _, err := NewWithOptions(path, Options{Quantization: "bogus"}); err == nil {
+		t.Fatal("opened with an unknown quantization")
+	}
+	db, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetQuantization("bogus"); err == nil {
+		t.Error("expected error for unknown quantization")
+	}
+	if err := db.SetQuantization(QuantizeInt8); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.InsertEmbedding("int8", "chunk", 0, testEmbedding(1024)); err != nil {
+		t.Fatal(err)
+	}
+	db.SetQuantization(QuantizeNone)
+	if err := db.InsertEmbedding("f32", "chunk", 0, testEmbedding(1024)); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	// Rebuilding the HNSW index from SQLite decodes both encodings.
+	if err := os.Remove(HNSWPath(path)); err != nil {
+		t.Fatal(err)
+	}
+	db, err = New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	hits, err := db.knnSearch(testEmbedding(1024), 2, 0, nil)
+	if err != nil || len(hits) != 2 {
+		t.Fatalf("search after rebuild: %v, %v", hits, err)
+	}
+	if sim := hits["int8"].similarity; sim < 0.999 {
+		t.Errorf("int8 embedding similarity %f, want ~1", sim)
+	}
+}
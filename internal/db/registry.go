@@ -0,0 +1,38 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// addCrateRegistries is migration 9. Like addCrateNamespaces it rebuilds
+// crates, this time with registry in its UNIQUE constraint, so a crate from
+// an alternative registry doesn't collide with the crates.io crate of the
+// same name and version. Crate IDs are kept.
+func addCrateRegistries(tx *sql.Tx) error {
+	queries := []string{
+		`CREATE TABLE crates_new (
+			id INTEGER PRIMARY KEY,
+			namespace TEXT NOT NULL DEFAULT '',
+			registry TEXT NOT NULL DEFAULT '',
+			name TEXT NOT NULL,
+			version TEXT NOT NULL,
+			fetched_at TIMESTAMP,
+			processed_at TIMESTAMP,
+			last_used_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			source TEXT NOT NULL DEFAULT '',
+			UNIQUE(namespace, registry, name, version)
+		)`,
+		`INSERT INTO crates_new (id, namespace, registry, name, version, fetched_at, processed_at, last_used_at, source)
+			SELECT id, namespace, registry, name, version, fetched_at, processed_at, last_used_at, source FROM crates`,
+		`DROP TABLE crates`,
+		`ALTER TABLE crates_new RENAME TO crates`,
+		`CREATE INDEX idx_crates_name ON crates (name)`,
+	}
+	for _, q := range queries {
+		if _, err := tx.Exec(q); err != nil {
+			return fmt.Errorf("executing %q: %w", q, err)
+		}
+	}
+	return nil
+}
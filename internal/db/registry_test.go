@@ -0,0 +1,45 @@
+package db
+
+import "testing"
+
+func TestCrateRegistries(t *testing.T) {
+	t.Parallel()
+	db := testDB(t)
+
+	// The same name and version from another registry is another crate.
+	corp, err := db.UpsertRegistryCrate("corp", "billing", "2.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := db.GetRegistryCrate("", "billing", "2.1.0"); c != nil {
+		t.Fatalf("crates.io lookup found the corp crate: %+v", c)
+	}
+	if c, _ := db.GetCrate("billing", "2.1.0"); c == nil || c.ID != corp.ID || c.Registry != "corp" {
+		t.Fatalf("GetCrate = %+v, want the corp crate", c)
+	}
+	public, err := db.UpsertCrate("billing", "2.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if public.ID == corp.ID {
+		t.Fatal("crates.io crate reused the corp crate's row")
+	}
+	if again, _ := db.UpsertRegistryCrate("corp", "billing", "2.1.0"); again == nil || again.ID != corp.ID {
+		t.Errorf("upserting the corp crate again = %+v, want ID %d", again, corp.ID)
+	}
+
+	// Without a registry, crates.io's comes first.
+	if c, _ := db.GetCrate("billing", "2.1.0"); c == nil || c.ID != public.ID {
+		t.Errorf("GetCrate = %+v, want the crates.io crate", c)
+	}
+
+	if err := db.MarkCrateProcessed(corp.ID); err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := db.GetLatestRegistryCrate("", "billing"); c != nil {
+		t.Errorf("latest crates.io billing = %+v, want none processed", c)
+	}
+	if c, _ := db.GetLatestRegistryCrate("corp", "billing"); c == nil || c.ID != corp.ID {
+		t.Errorf("latest corp billing = %+v", c)
+	}
+}
@@ -0,0 +1,136 @@
+package db
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+)
+
+// Vector storage modes, selected by index.storage.
+const (
+	// StorageMemory keeps an HNSW index of every embedding in RAM.
+	StorageMemory = "memory"
+	// StorageDisk keeps no index in RAM and scans the embeddings table for
+	// each search. Searches are exact but slower, and memory stays flat
+	// however many crates are indexed.
+	StorageDisk = "disk"
+)
+
+// Options configures New.
+type Options struct {
+	// Storage is StorageMemory (the default) or StorageDisk.
+	Storage string
+	// Quantization is how new embeddings are stored; see SetQuantization.
+	Quantization string
+}
+
+func (o Options) validate() error {
+	switch o.Storage {
+	case "", StorageMemory, StorageDisk:
+	default:
+		return fmt.Errorf("unknown index storage %q (want memory or disk)", o.Storage)
+	}
+	_, err := quantization(o.Quantization)
+	return err
+}
+
+// diskIndex reports whether searches scan the embeddings table rather than
+// an HNSW index.
+func (db *DB) diskIndex() bool {
+	return db.hnsw == nil
+}
+
+// dropHNSWFiles removes the HNSW index and its log. Disk mode does this on
+// open, as embeddings stored meanwhile would be missing from them, so
+// switching back to memory mode rebuilds the index from SQLite.
+func (db *DB) dropHNSWFiles() error {
+	for _, p := range []string{db.hnswPath, HNSWLogPath(db.path)} {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanSearch is knnSearch for disk mode: it compares embedding against
+// every stored embedding whose content hash is eligible and keeps the
+// fetchLimit best-matching hashes.
+func (db *DB) scanSearch(embedding []float32, fetchLimit int, threshold float32, eligible func(hash string) bool) (map[string]knnHit, error) {
+	query := normalized(embedding)
+
+	rows, err := db.reader.Query(`SELECT id, content_hash, embedding, encoding FROM embeddings`)
+	if err != nil {
+		return nil, fmt.Errorf("scanning embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	best := make(map[string]knnHit)
+	for rows.Next() {
+		var id int
+		var hash, encoding string
+		var blob []byte
+		if err := rows.Scan(&id, &hash, &blob, &encoding); err != nil {
+			return nil, err
+		}
+		if eligible != nil && !eligible(hash) {
+			continue
+		}
+		vec, err := decodeEmbedding(blob, encoding)
+		if err != nil || len(vec) != len(query) {
+			continue
+		}
+		sim := cosine(query, vec)
+		if sim <= threshold {
+			continue
+		}
+		if prev, ok := best[hash]; !ok || sim > prev.similarity {
+			best[hash] = knnHit{id: id, similarity: sim}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(best) > fetchLimit {
+		hashes := make([]string, 0, len(best))
+		for hash := range best {
+			hashes = append(hashes, hash)
+		}
+		sort.Slice(hashes, func(i, j int) bool { return best[hashes[i]].similarity > best[hashes[j]].similarity })
+		for _, hash := range hashes[fetchLimit:] {
+			delete(best, hash)
+		}
+	}
+	return best, nil
+}
+
+// normalized returns a unit-length copy of v.
+func normalized(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	out := make([]float32, len(v))
+	if sum == 0 {
+		return out
+	}
+	inv := 1 / math.Sqrt(sum)
+	for i, x := range v {
+		out[i] = float32(float64(x) * inv)
+	}
+	return out
+}
+
+// cosine returns the cosine similarity of unit vector q and v.
+func cosine(q, v []float32) float32 {
+	var dot, norm float64
+	for i, x := range v {
+		dot += float64(q[i]) * float64(x)
+		norm += float64(x) * float64(x)
+	}
+	if norm == 0 {
+		return 0
+	}
+	return float32(dot / math.Sqrt(norm))
+}
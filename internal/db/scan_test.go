@@ -0,0 +1,69 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiskStorage(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	db, err := NewWithOptions(filepath.Join(dir, "test.db"), Options{Storage: StorageDisk})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records := make([]EmbeddingRecord, 3)
+	for i := range records {
+		records[i] = EmbeddingRecord{ContentHash: "hash" + string(rune('a'+i)), ChunkText: "chunk", Embedding: testEmbedding(1024)}
+		records[i].Embedding[i] = 2
+	}
+	if err := db.InsertEmbeddings(records); err != nil {
+		t.Fatal(err)
+	}
+	if got := db.HNSWSize(); got != len(records) {
+		t.Errorf("searchable vectors = %d, want %d", got, len(records))
+	}
+
+	results, err := db.VectorSearch(records[1].Embedding, 0, 2, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].ContentHash != "hashb" {
+		t.Errorf("expected hashb first of 2 results, got %+v", results)
+	}
+
+	db.Close()
+	if _, err := os.Stat(db.hnswPath); !os.IsNotExist(err) {
+		t.Errorf("disk mode wrote an HNSW index: %v", err)
+	}
+
+	t.Run("snapshot_restores_into_memory_mode", func(t *testing.T) {
+		src, err := NewWithOptions(filepath.Join(dir, "test.db"), Options{Storage: StorageDisk})
+		if err != nil {
+			t.Fatal(err)
+		}
+		snap := filepath.Join(dir, "snap.db")
+		if err := src.Snapshot(snap); err != nil {
+			t.Fatal(err)
+		}
+		src.Close()
+
+		mem := testDB(t)
+		if err := mem.Restore(snap); err != nil {
+			t.Fatal(err)
+		}
+		if got := mem.HNSWSize(); got != len(records) {
+			t.Errorf("rebuilt index has %d vectors, want %d", got, len(records))
+		}
+	})
+}
+
+func TestNewWithOptions_UnknownStorage(t *testing.T) {
+	t.Parallel()
+	if db, err := NewWithOptions(filepath.Join(t.TempDir(), "test.db"), Options{Storage: "tape"}); err == nil {
+		db.Close()
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Snapshot writes a consistent copy of the database to dbPath, with the HNSW
+// index alongside it at HNSWPath(dbPath) unless in disk mode, which has
+// none. dbPath must not exist. Both files
+// are written under temporary names and renamed into place, so a crash never
+// leaves a half-written snapshot. Callers must stop embedding writes for the
+// duration so the two files agree.
+func (db *DB) Snapshot(dbPath string) error {
+	if _, err := os.Stat(dbPath); err == nil {
+		return fmt.Errorf("%s already exists", dbPath)
+	}
+	if _, err := db.conn.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`); err != nil {
+		return fmt.Errorf("checkpointing WAL: %w", err)
+	}
+
+	tmpDB, tmpHNSW := dbPath+".tmp", HNSWPath(dbPath)+".tmp"
+	os.Remove(tmpDB)
+	defer os.Remove(tmpDB)
+	defer os.Remove(tmpHNSW)
+	if _, err := db.conn.Exec(`VACUUM INTO ?`, tmpDB); err != nil {
+		return fmt.Errorf("copying database: %w", err)
+	}
+	if !db.diskIndex() {
+		if err := db.saveHNSWTo(tmpHNSW); err != nil {
+			return fmt.Errorf("saving HNSW index: %w", err)
+		}
+		if err := os.Rename(tmpHNSW, HNSWPath(dbPath)); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmpDB, dbPath)
+}
+
+// Restore replaces the contents of the database and HNSW index with a
+// snapshot written by Snapshot, without closing the database. Callers must
+// stop all writes for the duration. Snapshots taken in disk mode have no
+// HNSW index; in memory mode one is rebuilt from the restored embeddings.
+func (db *DB) Restore(dbPath string) error {
+	var hnswFile io.Reader
+	if !db.diskIndex() {
+		f, err := os.Open(HNSWPath(dbPath))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("opening snapshot HNSW index: %w", err)
+		}
+		if f != nil {
+			defer f.Close()
+			hnswFile = f
+		}
+	}
+
+	src, err := sql.Open("sqlite3", "file:"+dbPath+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("opening snapshot: %w", err)
+	}
+	defer src.Close()
+	version, _, err := schemaVersion(src)
+	if err != nil {
+		return fmt.Errorf("reading snapshot: %w", err)
+	}
+	if err := checkSchemaVersion(version); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("opening snapshot: %w", err)
+	}
+	defer srcConn.Close()
+	destConn, err := db.conn.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer destConn.Close()
+
+	err = destConn.Raw(func(dest any) error {
+		return srcConn.Raw(func(src any) error {
+			backup, err := dest.(*sqlite3.SQLiteConn).Backup("main", src.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return err
+			}
+			if _, err := backup.Step(-1); err != nil {
+				backup.Finish()
+				return err
+			}
+			return backup.Finish()
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("restoring database: %w", err)
+	}
+
+	// Snapshots from older builds may predate newer migrations. The
+	// snapshot file itself is the backup.
+	if err := db.migrate(false); err != nil {
+		return err
+	}
+	if err := db.loadEmbeddingDim(); err != nil {
+		return err
+	}
+	if db.diskIndex() {
+		return nil
+	}
+	if hnswFile == nil {
+		// Load replaces the index in place under its own lock, so
+		// concurrent searches see either the old index or the new one.
+		idx := newHNSW(db.dim)
+		if err := db.fillHNSW(idx); err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := idx.Save(&buf); err != nil {
+			return fmt.Errorf("rebuilding HNSW index: %w", err)
+		}
+		hnswFile = &buf
+	}
+	if err := db.hnsw.Load(hnswFile); err != nil {
+		return fmt.Errorf("loading snapshot HNSW index: %w", err)
+	}
+	db.saveHNSW()
+	return nil
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+	db := testDB(t)
+	if _, err := db.UpsertCrate("serde", "1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(t.TempDir(), "export.db")
+	if err := db.Snapshot(out); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(HNSWPath(out)); err != nil {
+		t.Errorf("HNSW index not exported: %v", err)
+	}
+	if err := db.Snapshot(out); err == nil {
+		t.Error("expected error when the target exists")
+	}
+
+	exported, err := New(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer exported.Close()
+	c, err := exported.GetCrate("serde", "1.0.0")
+	if err != nil || c == nil {
+		t.Fatalf("crate missing from export: %v, %v", c, err)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	t.Parallel()
+	db := testDB(t)
+	if _, err := db.UpsertCrate("serde", "1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	snap := filepath.Join(t.TempDir(), "snap.db")
+	if err := db.Snapshot(snap); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.UpsertCrate("tokio", "1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Restore(snap); err != nil {
+		t.Fatal(err)
+	}
+
+	if c, err := db.GetCrate("tokio", "1.0.0"); err != nil || c != nil {
+		t.Errorf("crate added after the snapshot survived restore: %v, %v", c, err)
+	}
+	if c, err := db.GetCrate("serde", "1.0.0"); err != nil || c == nil {
+		t.Errorf("crate from the snapshot missing after restore: %v, %v", c, err)
+	}
+}
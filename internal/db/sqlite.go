@@ -1,6 +1,7 @@
 package db
 
 import (
+	"bufio"
 	"database/sql"
 	"encoding/binary"
 	"fmt"
@@ -8,6 +9,7 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
 	"time"
@@ -18,18 +20,44 @@ import (
 )
 
 const (
-	embeddingDim = 1024
-	hnswM        = 16
-	hnswEf       = 100
+	hnswM  = 16
+	hnswEf = 100
 )
 
+// DB is a view of the index scoped to one namespace; see InNamespace.
 type DB struct {
-	conn     *sql.DB
+	*store
+	// namespace scopes crate lookups by name; "" is the default namespace.
+	namespace string
+}
+
+// store is the database and vector index shared by every namespace view.
+type store struct {
+	conn *sql.DB
+	// reader is a read-only pool for queries. WAL lets it read the last
+	// committed state while conn is writing, so searches don't queue behind
+	// indexing.
+	reader   *sql.DB
+	path     string
 	hnsw     *hnsw.HNSWIndex
 	hnswPath string
+	hnswLog  *hnswLog
+	// dim is the dimension of the stored embeddings; see EmbeddingModel.
+	dim int
+	// quantization is the encoding for new embeddings; see SetQuantization.
+	quantization string
 }
 
 func New(dbPath string) (*DB, error) {
+	return NewWithOptions(dbPath, Options{})
+}
+
+// NewWithOptions opens the database at dbPath, creating and migrating it as
+// needed, with the vector storage mode and quantization in opts.
+func NewWithOptions(dbPath string, opts Options) (*DB, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
 	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
 		return nil, fmt.Errorf("creating cache directory: %w", err)
 	}
@@ -48,21 +76,41 @@ func New(dbPath string) (*DB, error) {
 		}
 	}
 
-	hnswPath := strings.TrimSuffix(dbPath, filepath.Ext(dbPath)) + ".hnsw"
+	hnswPath := HNSWPath(dbPath)
 
-	dsn := "file:" + dbPath + "?_txlock=immediate&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	dsn := "file:" + dbPath + "?_txlock=immediate&_busy_timeout=5000&_journal_mode=WAL"
 	conn, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("opening database: %w", err)
 	}
 
-	d := &DB{conn: conn, hnswPath: hnswPath}
-	if err := d.initSchema(); err != nil {
+	d := &DB{store: &store{conn: conn, path: dbPath, hnswPath: hnswPath, hnswLog: newHNSWLog(HNSWLogPath(dbPath))}}
+	d.SetQuantization(opts.Quantization) // validated above
+	if err := d.migrate(true); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("migrating schema: %w", err)
+	}
+	// Opened after migrating so the file and its WAL exist.
+	d.reader, err = sql.Open("sqlite3", "file:"+dbPath+"?mode=ro&_busy_timeout=5000")
+	if err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("initializing schema: %w", err)
+		return nil, fmt.Errorf("opening read-only database: %w", err)
+	}
+	d.reader.SetMaxOpenConns(max(4, runtime.NumCPU()))
+	if err := d.loadEmbeddingDim(); err != nil {
+		d.reader.Close()
+		conn.Close()
+		return nil, err
 	}
 
-	if err := d.loadOrCreateHNSW(); err != nil {
+	if opts.Storage == StorageDisk {
+		if err := d.dropHNSWFiles(); err != nil {
+			d.reader.Close()
+			conn.Close()
+			return nil, fmt.Errorf("removing HNSW index: %w", err)
+		}
+	} else if err := d.loadOrCreateHNSW(); err != nil {
+		d.reader.Close()
 		conn.Close()
 		return nil, fmt.Errorf("initializing HNSW index: %w", err)
 	}
@@ -70,12 +118,22 @@ func New(dbPath string) (*DB, error) {
 	return d, nil
 }
 
+// HNSWPath returns where the HNSW index for the database at dbPath lives.
+func HNSWPath(dbPath string) string {
+	return strings.TrimSuffix(dbPath, filepath.Ext(dbPath)) + ".hnsw"
+}
+
 func (db *DB) Close() error {
 	db.saveHNSW()
+	db.hnswLog.close()
+	db.reader.Close()
 	return db.conn.Close()
 }
 
-func (db *DB) initSchema() error {
+// createSchema is migration 1. Databases from builds before migrations were
+// versioned arrive here with some or all of the schema already in place, so
+// every statement is idempotent.
+func createSchema(tx *sql.Tx) error {
 	queries := []string{
 		`CREATE TABLE IF NOT EXISTS crates (
 			id INTEGER PRIMARY KEY,
@@ -84,6 +142,8 @@ func (db *DB) initSchema() error {
 			fetched_at TIMESTAMP,
 			processed_at TIMESTAMP,
 			last_used_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			source TEXT NOT NULL DEFAULT '',
+			registry TEXT NOT NULL DEFAULT '',
 			UNIQUE(name, version)
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_crates_name ON crates (name)`,
@@ -99,6 +159,7 @@ func (db *DB) initSchema() error {
 			signature TEXT,
 			doc_links TEXT,
 			fragment_names TEXT,
+			features TEXT,
 			UNIQUE(crate_id, rustdoc_id)
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_items_crate ON items (crate_id)`,
@@ -123,13 +184,90 @@ func (db *DB) initSchema() error {
 			UNIQUE(crate_id, local_prefix)
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_reexports_crate ON reexports (crate_id)`,
+
+		`CREATE TABLE IF NOT EXISTS dependencies (
+			crate_id INTEGER NOT NULL REFERENCES crates(id),
+			name TEXT NOT NULL,
+			req TEXT NOT NULL DEFAULT '',
+			version TEXT NOT NULL DEFAULT '',
+			kind TEXT NOT NULL DEFAULT 'normal',
+			optional INTEGER NOT NULL DEFAULT 0,
+			UNIQUE(crate_id, name, kind)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_dependencies_name ON dependencies (name)`,
+
+		`CREATE TABLE IF NOT EXISTS examples (
+			id INTEGER PRIMARY KEY,
+			item_id INTEGER NOT NULL REFERENCES items(id),
+			example_index INTEGER NOT NULL,
+			content_hash TEXT NOT NULL,
+			UNIQUE(item_id, example_index)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_examples_item ON examples (item_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_examples_hash ON examples (content_hash)`,
+
+		`CREATE TABLE IF NOT EXISTS fragments (
+			id INTEGER PRIMARY KEY,
+			item_id INTEGER NOT NULL REFERENCES items(id),
+			name TEXT NOT NULL,
+			content_hash TEXT NOT NULL,
+			UNIQUE(item_id, name)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_fragments_hash ON fragments (content_hash)`,
+
+		`CREATE TABLE IF NOT EXISTS metadata (
+			key TEXT PRIMARY KEY,
+			value TEXT NOT NULL
+		)`,
 	}
 
 	for _, q := range queries {
-		if _, err := db.conn.Exec(q); err != nil {
+		if _, err := tx.Exec(q); err != nil {
 			return fmt.Errorf("executing %q: %w", q, err)
 		}
 	}
+
+	// Columns added after the initial schema; CREATE TABLE IF NOT EXISTS
+	// won't add them to databases created by older builds.
+	if err := ensureColumn(tx, "items", "features", "TEXT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(tx, "crates", "source", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := ensureColumn(tx, "crates", "registry", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := ensureColumn(tx, "embeddings", "encoding", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ensureColumn adds a column to an existing table if it's missing.
+func ensureColumn(tx *sql.Tx, table, column, decl string) error {
+	rows, err := tx.Query(fmt.Sprintf(`SELECT name FROM pragma_table_info('%s')`, table))
+	if err != nil {
+		return fmt.Errorf("inspecting %s columns: %w", table, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	if _, err := tx.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, decl)); err != nil {
+		return fmt.Errorf("adding column %s.%s: %w", table, column, err)
+	}
 	return nil
 }
 
@@ -142,25 +280,50 @@ type Crate struct {
 	FetchedAt   *time.Time
 	ProcessedAt *time.Time
 	LastUsedAt  time.Time
+	Source      string // "" for rustdoc JSON, SourceHTML for the docs.rs HTML fallback
+	Registry    string // "" for crates.io, otherwise a configured registry name
+	Namespace   string // "" for the default namespace
 }
 
-func (db *DB) UpsertCrate(name, version string) (*Crate, error) {
-	var c Crate
-	err := db.conn.QueryRow(
-		`SELECT id, name, version, fetched_at, processed_at, last_used_at FROM crates WHERE name = ? AND version = ?`,
-		name, version,
-	).Scan(&c.ID, &c.Name, &c.Version, &c.FetchedAt, &c.ProcessedAt, &c.LastUsedAt)
+// SourceHTML marks crates indexed by scraping docs.rs HTML because no
+// rustdoc JSON was available. Their items have no fragments or resolved links.
+const SourceHTML = "html"
+
+// SourceFile marks crates imported from user-provided rustdoc JSON. They can't
+// be re-fetched, only re-indexed from the JSON cache.
+const SourceFile = "file"
+
+const crateColumns = `id, name, version, fetched_at, processed_at, last_used_at, source, registry, namespace`
 
-	if err == nil {
-		return &c, nil
+// scanCrate reads a row selected with crateColumns.
+func scanCrate(row rowScanner) (*Crate, error) {
+	var c Crate
+	if err := row.Scan(&c.ID, &c.Name, &c.Version, &c.FetchedAt, &c.ProcessedAt, &c.LastUsedAt, &c.Source, &c.Registry, &c.Namespace); err != nil {
+		return nil, err
 	}
-	if err != sql.ErrNoRows {
+	return &c, nil
+}
+
+// UpsertCrate returns crates.io's name@version in db's namespace, adding it
+// if it isn't there yet.
+func (db *DB) UpsertCrate(name, version string) (*Crate, error) {
+	return db.UpsertRegistryCrate("", name, version)
+}
+
+// UpsertRegistryCrate is UpsertCrate for a crate from registry, "" being
+// crates.io. The same name and version from two registries are two crates.
+func (db *DB) UpsertRegistryCrate(registry, name, version string) (*Crate, error) {
+	c, err := db.GetRegistryCrate(registry, name, version)
+	if err != nil {
 		return nil, fmt.Errorf("checking crate: %w", err)
 	}
+	if c != nil {
+		return c, nil
+	}
 
 	result, err := db.conn.Exec(
-		`INSERT INTO crates (name, version) VALUES (?, ?)`,
-		name, version,
+		`INSERT INTO crates (namespace, registry, name, version) VALUES (?, ?, ?, ?)`,
+		db.namespace, registry, name, version,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("inserting crate: %w", err)
@@ -172,7 +335,7 @@ func (db *DB) UpsertCrate(name, version string) (*Crate, error) {
 	}
 
 	now := time.Now()
-	return &Crate{ID: int(id), Name: name, Version: version, LastUsedAt: now}, nil
+	return &Crate{ID: int(id), Name: name, Version: version, LastUsedAt: now, Registry: registry, Namespace: db.namespace}, nil
 }
 
 func (db *DB) MarkCrateFetched(crateID int) error {
@@ -180,6 +343,13 @@ func (db *DB) MarkCrateFetched(crateID int) error {
 	return err
 }
 
+// SetCrateSource records where a crate's items came from: the registry it
+// was fetched from and whether it was scraped from HTML (see SourceHTML).
+func (db *DB) SetCrateSource(crateID int, registry, source string) error {
+	_, err := db.conn.Exec(`UPDATE crates SET registry = ?, source = ? WHERE id = ?`, registry, source, crateID)
+	return err
+}
+
 func (db *DB) MarkCrateProcessed(crateID int) error {
 	_, err := db.conn.Exec(`UPDATE crates SET processed_at = CURRENT_TIMESTAMP WHERE id = ?`, crateID)
 	return err
@@ -190,40 +360,63 @@ func (db *DB) TouchCrate(crateID int) error {
 	return err
 }
 
+// GetCrate returns name@version in db's namespace from any registry, or nil
+// if it isn't indexed.
 func (db *DB) GetCrate(name, version string) (*Crate, error) {
-	var c Crate
-	err := db.conn.QueryRow(
-		`SELECT id, name, version, fetched_at, processed_at, last_used_at FROM crates WHERE name = ? AND version = ?`,
-		name, version,
-	).Scan(&c.ID, &c.Name, &c.Version, &c.FetchedAt, &c.ProcessedAt, &c.LastUsedAt)
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
-	if err != nil {
-		return nil, err
-	}
-	return &c, nil
+	return db.getCrate(`name = ? AND version = ?`, ``, name, version)
+}
+
+// GetRegistryCrate returns name@version from registry ("" for crates.io) in
+// db's namespace, or nil if it isn't indexed.
+func (db *DB) GetRegistryCrate(registry, name, version string) (*Crate, error) {
+	return db.getCrate(`registry = ? AND name = ? AND version = ?`, ``, registry, name, version)
 }
 
-// GetLatestCrate returns the most recently processed crate with the given name.
+// GetLatestCrate returns the most recently processed crate with the given
+// name in db's namespace.
 func (db *DB) GetLatestCrate(name string) (*Crate, error) {
-	var c Crate
-	err := db.conn.QueryRow(
-		`SELECT id, name, version, fetched_at, processed_at, last_used_at
-		 FROM crates WHERE name = ? AND processed_at IS NOT NULL
-		 ORDER BY processed_at DESC LIMIT 1`, name,
-	).Scan(&c.ID, &c.Name, &c.Version, &c.FetchedAt, &c.ProcessedAt, &c.LastUsedAt)
+	return db.getCrate(`name = ? AND processed_at IS NOT NULL`, `processed_at DESC`, name)
+}
+
+// GetLatestRegistryCrate is GetLatestCrate for crates from registry.
+func (db *DB) GetLatestRegistryCrate(registry, name string) (*Crate, error) {
+	return db.getCrate(`registry = ? AND name = ? AND processed_at IS NOT NULL`, `processed_at DESC`, registry, name)
+}
+
+// getCrate returns the first crate in db's namespace matching where, or nil.
+// Where a name is indexed from several registries and the caller didn't say
+// which, crates.io's comes first.
+func (db *DB) getCrate(where, order string, args ...interface{}) (*Crate, error) {
+	if order != "" {
+		order = ", " + order
+	}
+	c, err := scanCrate(db.reader.QueryRow(
+		`SELECT `+crateColumns+` FROM crates WHERE namespace = ? AND `+where+`
+		 ORDER BY registry != ''`+order+` LIMIT 1`,
+		append([]interface{}{db.namespace}, args...)...,
+	))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	return &c, nil
+	return c, nil
 }
 
+// ListCrates returns the crates in db's namespace.
 func (db *DB) ListCrates() ([]Crate, error) {
-	rows, err := db.conn.Query(`SELECT id, name, version, fetched_at, processed_at, last_used_at FROM crates ORDER BY name`)
+	return db.listCrates(`WHERE namespace = ?`, db.namespace)
+}
+
+// ListAllCrates returns the crates in every namespace, for maintenance that
+// spans the whole index.
+func (db *DB) ListAllCrates() ([]Crate, error) {
+	return db.listCrates(``)
+}
+
+func (db *DB) listCrates(where string, args ...interface{}) ([]Crate, error) {
+	rows, err := db.reader.Query(`SELECT `+crateColumns+` FROM crates `+where+` ORDER BY name, id`, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -231,11 +424,11 @@ func (db *DB) ListCrates() ([]Crate, error) {
 
 	var crates []Crate
 	for rows.Next() {
-		var c Crate
-		if err := rows.Scan(&c.ID, &c.Name, &c.Version, &c.FetchedAt, &c.ProcessedAt, &c.LastUsedAt); err != nil {
+		c, err := scanCrate(rows)
+		if err != nil {
 			return nil, err
 		}
-		crates = append(crates, c)
+		crates = append(crates, *c)
 	}
 	return crates, nil
 }
@@ -253,13 +446,44 @@ type Item struct {
 	Signature     string
 	DocLinks      string // JSON-encoded map[string]string
 	FragmentNames string // JSON-encoded []string
+	Features      string // JSON-encoded []string
+	// Deprecation is the item's JSON-encoded #[deprecated] since and note,
+	// empty when it isn't deprecated.
+	Deprecation string
+	// Stability is the item's JSON-encoded #[stable] or #[unstable]
+	// attribute, empty when it has neither.
+	Stability string
+}
+
+const itemColumns = `id, crate_id, rustdoc_id, name, path, kind, content_hash, signature, doc_links, fragment_names, features, deprecation, stability`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
 }
 
+// scanItem reads a row selected with itemColumns. Nullable text columns
+// are normalized to empty strings.
+func scanItem(row rowScanner) (*Item, error) {
+	var it Item
+	var contentHash, signature, docLinks, fragNames, features sql.NullString
+	if err := row.Scan(&it.ID, &it.CrateID, &it.RustdocID, &it.Name, &it.Path, &it.Kind,
+		&contentHash, &signature, &docLinks, &fragNames, &features, &it.Deprecation, &it.Stability); err != nil {
+		return nil, err
+	}
+	it.ContentHash = contentHash.String
+	it.Signature = signature.String
+	it.DocLinks = docLinks.String
+	it.FragmentNames = fragNames.String
+	it.Features = features.String
+	return &it, nil
+}
+
+const insertItemSQL = `INSERT INTO items (crate_id, rustdoc_id, name, path, kind, content_hash, signature, doc_links, fragment_names, features, deprecation, stability)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
 func (db *DB) InsertItem(item *Item) error {
-	result, err := db.conn.Exec(
-		`INSERT INTO items (crate_id, rustdoc_id, name, path, kind, content_hash, signature, doc_links, fragment_names)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		item.CrateID, item.RustdocID, item.Name, item.Path, item.Kind, item.ContentHash, item.Signature, item.DocLinks, item.FragmentNames,
+	result, err := db.conn.Exec(insertItemSQL,
+		item.CrateID, item.RustdocID, item.Name, item.Path, item.Kind, item.ContentHash, item.Signature, item.DocLinks, item.FragmentNames, item.Features, item.Deprecation, item.Stability,
 	)
 	if err != nil {
 		return fmt.Errorf("inserting item: %w", err)
@@ -274,108 +498,469 @@ func (db *DB) InsertItem(item *Item) error {
 }
 
 func (db *DB) GetItem(itemID int) (*Item, error) {
-	var it Item
-	err := db.conn.QueryRow(
-		`SELECT id, crate_id, rustdoc_id, name, path, kind, content_hash, signature, doc_links, fragment_names FROM items WHERE id = ?`,
+	it, err := scanItem(db.reader.QueryRow(
+		`SELECT `+itemColumns+` FROM items WHERE id = ?`,
 		itemID,
-	).Scan(&it.ID, &it.CrateID, &it.RustdocID, &it.Name, &it.Path, &it.Kind, &it.ContentHash, &it.Signature, &it.DocLinks, &it.FragmentNames)
+	))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	return &it, nil
+	return it, nil
 }
 
 func (db *DB) GetItemByPath(crateID int, path string) (*Item, error) {
-	var it Item
-	err := db.conn.QueryRow(
-		`SELECT id, crate_id, rustdoc_id, name, path, kind, content_hash, signature, doc_links, fragment_names
-		 FROM items WHERE crate_id = ? AND path = ?`,
+	it, err := scanItem(db.reader.QueryRow(
+		`SELECT `+itemColumns+` FROM items WHERE crate_id = ? AND path = ?`,
 		crateID, path,
-	).Scan(&it.ID, &it.CrateID, &it.RustdocID, &it.Name, &it.Path, &it.Kind, &it.ContentHash, &it.Signature, &it.DocLinks, &it.FragmentNames)
+	))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	return &it, nil
+	return it, nil
 }
 
-// GetItemForHash picks a representative item for a content hash.
-// When crateIDs are specified, it prefers items from those crates.
-func (db *DB) GetItemForHash(contentHash string, crateIDs []int) (*Item, error) {
-	query := `SELECT id, crate_id, rustdoc_id, name, path, kind, content_hash, signature, doc_links, fragment_names
-		 FROM items WHERE content_hash = ?`
-	var params []interface{}
-	params = append(params, contentHash)
+// ListItems returns every item of a crate, ordered by path.
+func (db *DB) ListItems(crateID int) ([]Item, error) {
+	rows, err := db.reader.Query(`SELECT `+itemColumns+` FROM items WHERE crate_id = ? ORDER BY path, id`, crateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	if len(crateIDs) > 0 {
-		placeholders := make([]string, len(crateIDs))
-		for i, id := range crateIDs {
-			placeholders[i] = "?"
-			params = append(params, id)
+	var items []Item
+	for rows.Next() {
+		it, err := scanItem(rows)
+		if err != nil {
+			return nil, err
 		}
-		query += fmt.Sprintf(` AND crate_id IN (%s)`, strings.Join(placeholders, ","))
+		items = append(items, *it)
 	}
-	query += ` LIMIT 1`
+	return items, rows.Err()
+}
 
-	var it Item
-	err := db.conn.QueryRow(query, params...).Scan(&it.ID, &it.CrateID, &it.RustdocID, &it.Name, &it.Path, &it.Kind, &it.ContentHash, &it.Signature, &it.DocLinks, &it.FragmentNames)
+// GetRootItem returns a crate's root module, or nil if it has none.
+func (db *DB) GetRootItem(crateID int) (*Item, error) {
+	it, err := scanItem(db.reader.QueryRow(
+		`SELECT `+itemColumns+` FROM items WHERE crate_id = ? AND kind = 'module' AND path NOT LIKE '%::%' ORDER BY id LIMIT 1`,
+		crateID,
+	))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	return &it, nil
+	return it, nil
+}
+
+// GetItemForHash picks a representative item for a content hash, preferring
+// the most recently processed crate version. Only items matching the filter
+// are considered.
+func (db *DB) GetItemForHash(contentHash string, filter Filter) (*Item, error) {
+	query := `SELECT ` + itemColumns + ` FROM items WHERE content_hash = ?`
+	params := []interface{}{contentHash}
+
+	where, filterParams := filter.where()
+	if where != "" {
+		query += " AND " + where
+		params = append(params, filterParams...)
+	}
+	query += ` ORDER BY (SELECT processed_at FROM crates WHERE crates.id = items.crate_id) DESC, id LIMIT 1`
+
+	it, err := scanItem(db.reader.QueryRow(query, params...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return it, nil
 }
 
 func (db *DB) DeleteItemsByCrate(crateID int) error {
-	_, err := db.conn.Exec(`DELETE FROM items WHERE crate_id = ?`, crateID)
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := deleteCrateItems(tx, crateID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func deleteCrateItems(tx *sql.Tx, crateID int) error {
+	if _, err := tx.Exec(`DELETE FROM examples WHERE item_id IN (SELECT id FROM items WHERE crate_id = ?)`, crateID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM fragments WHERE item_id IN (SELECT id FROM items WHERE crate_id = ?)`, crateID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`DELETE FROM items WHERE crate_id = ?`, crateID)
 	return err
 }
 
-// --- Embedding operations ---
+// ItemRecord is an item with the fragments and examples stored alongside
+// it, for ReplaceCrateItems.
+type ItemRecord struct {
+	Item      *Item
+	Fragments []Fragment
+	Examples  []Example
+}
 
-func (db *DB) InsertEmbedding(contentHash string, chunkText string, chunkIndex int, embedding []float32) error {
-	if len(embedding) != embeddingDim {
-		return fmt.Errorf("expected embedding dimension %d, got %d", embeddingDim, len(embedding))
+// ReplaceCrateItems replaces a crate's items, fragments and examples with
+// records in a single transaction and fills in their IDs. A row that fails
+// to insert is logged and left with ID 0; its item's fragments and
+// examples are skipped with it.
+func (db *DB) ReplaceCrateItems(crateID int, records []ItemRecord) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
 	}
-	if err := validateEmbedding(embedding); err != nil {
+	defer tx.Rollback()
+	if err := deleteCrateItems(tx, crateID); err != nil {
+		return fmt.Errorf("deleting old items: %w", err)
+	}
+
+	insertItem, err := tx.Prepare(insertItemSQL)
+	if err != nil {
 		return err
 	}
+	defer insertItem.Close()
+	insertFragment, err := tx.Prepare(insertFragmentSQL)
+	if err != nil {
+		return err
+	}
+	defer insertFragment.Close()
+	insertExample, err := tx.Prepare(insertExampleSQL)
+	if err != nil {
+		return err
+	}
+	defer insertExample.Close()
 
-	blob := serializeFloat32(embedding)
-	result, err := db.conn.Exec(
-		`INSERT INTO embeddings (content_hash, chunk_text, chunk_index, embedding) VALUES (?, ?, ?, ?)`,
-		contentHash, chunkText, chunkIndex, blob,
-	)
+	for _, rec := range records {
+		item := rec.Item
+		item.CrateID = crateID
+		result, err := insertItem.Exec(item.CrateID, item.RustdocID, item.Name, item.Path, item.Kind, item.ContentHash, item.Signature, item.DocLinks, item.FragmentNames, item.Features, item.Deprecation, item.Stability)
+		if err != nil {
+			slog.Error("failed to insert item", "path", item.Path, "error", err)
+			continue
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("getting item id: %w", err)
+		}
+		item.ID = int(id)
+
+		for i := range rec.Fragments {
+			f := &rec.Fragments[i]
+			f.ItemID = item.ID
+			result, err := insertFragment.Exec(f.ItemID, f.Name, f.ContentHash)
+			if err != nil {
+				slog.Error("failed to insert fragment", "path", item.Path, "fragment", f.Name, "error", err)
+				continue
+			}
+			id, _ := result.LastInsertId()
+			f.ID = int(id)
+		}
+		for i := range rec.Examples {
+			ex := &rec.Examples[i]
+			ex.ItemID = item.ID
+			result, err := insertExample.Exec(ex.ItemID, ex.Index, ex.ContentHash)
+			if err != nil {
+				slog.Error("failed to insert example", "path", item.Path, "example", ex.Index, "error", err)
+				continue
+			}
+			id, _ := result.LastInsertId()
+			ex.ID = int(id)
+		}
+	}
+	return tx.Commit()
+}
+
+// --- Example operations ---
+
+// Example is a Rust code block extracted from an item's docs.
+// The code itself lives in the CAS under ContentHash.
+type Example struct {
+	ID          int
+	ItemID      int
+	Index       int
+	ContentHash string
+}
+
+const insertExampleSQL = `INSERT INTO examples (item_id, example_index, content_hash) VALUES (?, ?, ?)
+	ON CONFLICT (item_id, example_index) DO UPDATE SET content_hash = EXCLUDED.content_hash`
+
+func (db *DB) InsertExample(itemID, index int, contentHash string) error {
+	_, err := db.conn.Exec(insertExampleSQL, itemID, index, contentHash)
 	if err != nil {
-		return fmt.Errorf("inserting embedding: %w", err)
+		return fmt.Errorf("inserting example: %w", err)
 	}
+	return nil
+}
 
-	id, err := result.LastInsertId()
+// GetExampleForHash picks a representative example for a content hash,
+// considering only examples whose parent item matches the filter.
+func (db *DB) GetExampleForHash(contentHash string, filter Filter) (*Example, error) {
+	query := `SELECT examples.id, examples.item_id, examples.example_index, examples.content_hash
+		FROM examples JOIN items ON items.id = examples.item_id
+		WHERE examples.content_hash = ?`
+	params := []interface{}{contentHash}
+
+	where, filterParams := filter.where()
+	if where != "" {
+		query += " AND " + where
+		params = append(params, filterParams...)
+	}
+	query += ` LIMIT 1`
+
+	var ex Example
+	err := db.reader.QueryRow(query, params...).Scan(&ex.ID, &ex.ItemID, &ex.Index, &ex.ContentHash)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ex, nil
+}
+
+// --- Fragment operations ---
+
+// Fragment records the content hash an item's fragment (e.g. #fields) was
+// embedded under, so search hits on it can be traced back to the item.
+type Fragment struct {
+	ID          int
+	ItemID      int
+	Name        string
+	ContentHash string
+}
+
+const insertFragmentSQL = `INSERT INTO fragments (item_id, name, content_hash) VALUES (?, ?, ?)
+	ON CONFLICT (item_id, name) DO UPDATE SET content_hash = EXCLUDED.content_hash`
+
+func (db *DB) InsertFragment(itemID int, name, contentHash string) error {
+	_, err := db.conn.Exec(insertFragmentSQL, itemID, name, contentHash)
+	if err != nil {
+		return fmt.Errorf("inserting fragment: %w", err)
+	}
+	return nil
+}
+
+// GetFragmentHash returns the content hash of an item's fragment, or "" if
+// it wasn't recorded.
+func (db *DB) GetFragmentHash(itemID int, name string) (string, error) {
+	var hash string
+	err := db.reader.QueryRow(`SELECT content_hash FROM fragments WHERE item_id = ? AND name = ?`, itemID, name).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("looking up fragment: %w", err)
+	}
+	return hash, nil
+}
+
+// GetFragmentForHash picks a representative fragment for a content hash,
+// considering only fragments whose item matches the filter. Like
+// GetItemForHash, it prefers the most recently processed crate.
+func (db *DB) GetFragmentForHash(contentHash string, filter Filter) (*Fragment, error) {
+	query := `SELECT fragments.id, fragments.item_id, fragments.name, fragments.content_hash
+		FROM fragments JOIN items ON items.id = fragments.item_id
+		WHERE fragments.content_hash = ?`
+	params := []interface{}{contentHash}
+
+	where, filterParams := filter.where()
+	if where != "" {
+		query += " AND " + where
+		params = append(params, filterParams...)
+	}
+	query += ` ORDER BY (SELECT processed_at FROM crates WHERE crates.id = items.crate_id) DESC, fragments.id LIMIT 1`
+
+	var f Fragment
+	err := db.reader.QueryRow(query, params...).Scan(&f.ID, &f.ItemID, &f.Name, &f.ContentHash)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
 	if err != nil {
-		return fmt.Errorf("getting embedding id: %w", err)
+		return nil, err
 	}
+	return &f, nil
+}
+
+// --- Embedding operations ---
+
+func (db *DB) InsertEmbedding(contentHash string, chunkText string, chunkIndex int, embedding []float32) error {
+	return db.InsertEmbeddings([]EmbeddingRecord{{
+		ContentHash: contentHash,
+		ChunkText:   chunkText,
+		ChunkIndex:  chunkIndex,
+		Embedding:   embedding,
+	}})
+}
 
-	// Copy to avoid hann's in-place normalization mutating our slice.
-	vec := make([]float32, len(embedding))
-	copy(vec, embedding)
-	if err := db.hnsw.Add(int(id), vec); err != nil {
-		return fmt.Errorf("adding to HNSW index: %w", err)
+// EmbeddingRecord is one chunk embedding for InsertEmbeddings. Model and
+// ChunkerVersion record what produced it, so content can be re-embedded
+// when either changes.
+type EmbeddingRecord struct {
+	ContentHash    string
+	ChunkText      string
+	ChunkIndex     int
+	Embedding      []float32
+	Model          string
+	ChunkerVersion string
+}
+
+// InsertEmbeddings stores records in a single transaction, then adds them
+// to the HNSW index. Invalid records are logged and skipped, so one bad
+// vector from the provider doesn't cost the rest of its batch; it is an
+// error only when none is valid. The same transaction deletes embeddings of
+// the records' content hashes that another model or chunker version
+// produced, so stale content stays searchable until its new chunks land.
+func (db *DB) InsertEmbeddings(records []EmbeddingRecord) error {
+	valid := records[:0:0]
+	var invalid error
+	for _, r := range records {
+		var err error
+		if len(r.Embedding) != db.dim {
+			err = fmt.Errorf("expected embedding dimension %d, got %d", db.dim, len(r.Embedding))
+		} else {
+			err = validateEmbedding(r.Embedding)
+		}
+		if err != nil {
+			slog.Warn("skipping invalid embedding", "content_hash", r.ContentHash, "chunk", r.ChunkIndex, "error", err)
+			invalid = err
+			continue
+		}
+		valid = append(valid, r)
 	}
+	if len(valid) == 0 {
+		return invalid
+	}
+	records = valid
 
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(`INSERT INTO embeddings (content_hash, chunk_text, chunk_index, embedding, encoding, model, chunker_version) VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	ids := make([]int, len(records))
+	blobs := make([][]byte, len(records))
+	for i, r := range records {
+		blobs[i] = encodeEmbedding(r.Embedding, db.quantization)
+		result, err := stmt.Exec(r.ContentHash, r.ChunkText, r.ChunkIndex, blobs[i], db.quantization, r.Model, r.ChunkerVersion)
+		if err != nil {
+			return fmt.Errorf("inserting embedding: %w", err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("getting embedding id: %w", err)
+		}
+		ids[i] = int(id)
+	}
+	// Only rows older than this call's are stale; the new ones' ids are
+	// past every existing row's, so none can reuse a deleted id.
+	type version struct{ hash, model, chunker string }
+	var stale []int
+	seen := make(map[version]bool)
+	for _, r := range records {
+		v := version{r.ContentHash, r.Model, r.ChunkerVersion}
+		if seen[v] {
+			continue
+		}
+		seen[v] = true
+		const where = `WHERE content_hash = ? AND id < ? AND (model != ? OR chunker_version != ?)`
+		hashStale, err := txInts(tx, `SELECT id FROM embeddings `+where, v.hash, ids[0], v.model, v.chunker)
+		if err != nil {
+			return fmt.Errorf("finding stale embeddings of %s: %w", v.hash, err)
+		}
+		if _, err := tx.Exec(`DELETE FROM embeddings `+where, v.hash, ids[0], v.model, v.chunker); err != nil {
+			return fmt.Errorf("deleting stale embeddings of %s: %w", v.hash, err)
+		}
+		stale = append(stale, hashStale...)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if db.diskIndex() {
+		return nil
+	}
+	for _, id := range stale {
+		if err := db.hnsw.Delete(id); err != nil {
+			slog.Warn("failed to delete HNSW node", "id", id, "error", err)
+		}
+	}
+
+	// Decoding copies, so hann's in-place normalization can't mutate the
+	// caller's slices, and logs exactly what a rebuild would read back.
+	vecs := make([][]float32, len(blobs))
+	for i, blob := range blobs {
+		if vecs[i], err = decodeEmbedding(blob, db.quantization); err != nil {
+			return err
+		}
+	}
+
+	db.hnswLog.mu.Lock()
+	defer db.hnswLog.mu.Unlock()
+	if err := db.hnswLog.append(ids, vecs); err != nil {
+		return err
+	}
+	// hann's BulkAdd prints a progress bar to stdout and skips the level
+	// bookkeeping Add does, so vectors are added one at a time.
+	for i, vec := range vecs {
+		if err := db.hnsw.Add(ids[i], vec); err != nil {
+			return fmt.Errorf("adding to HNSW index: %w", err)
+		}
+	}
+	if db.hnswLog.due() {
+		if err := db.checkpointHNSWLocked(); err != nil {
+			slog.Error("failed to checkpoint HNSW index", "error", err)
+		}
+	}
 	return nil
 }
 
+// EmbeddingsForHash returns the stored chunk embeddings of a content hash in
+// chunk order.
+func (db *DB) EmbeddingsForHash(contentHash string) ([][]float32, error) {
+	rows, err := db.reader.Query(`SELECT embedding, encoding FROM embeddings WHERE content_hash = ? ORDER BY chunk_index, id`, contentHash)
+	if err != nil {
+		return nil, fmt.Errorf("loading embeddings: %w", err)
+	}
+	defer rows.Close()
+	var vecs [][]float32
+	for rows.Next() {
+		var blob []byte
+		var encoding string
+		if err := rows.Scan(&blob, &encoding); err != nil {
+			return nil, err
+		}
+		vec, err := decodeEmbedding(blob, encoding)
+		if err != nil {
+			return nil, err
+		}
+		vecs = append(vecs, vec)
+	}
+	return vecs, rows.Err()
+}
+
 // HasEmbeddings checks if a content hash already has embeddings stored.
 func (db *DB) HasEmbeddings(contentHash string) bool {
 	var count int
-	db.conn.QueryRow(`SELECT COUNT(*) FROM embeddings WHERE content_hash = ?`, contentHash).Scan(&count)
+	db.reader.QueryRow(`SELECT COUNT(*) FROM embeddings WHERE content_hash = ?`, contentHash).Scan(&count)
 	return count > 0
 }
 
@@ -389,11 +974,26 @@ type SearchResult struct {
 	Kind        string
 	Signature   string
 	Similarity  float32
+	// ChunkText and ChunkIndex are the embedded chunk that matched best.
+	ChunkText  string
+	ChunkIndex int
+
+	embeddingID int
 }
 
-// knnSearch runs a KNN query against the HNSW index and returns content_hash + similarity pairs,
-// grouped by content_hash (keeping the best similarity per hash).
-func (db *DB) knnSearch(embedding []float32, fetchLimit int, threshold float32, allowedHashes map[string]bool) (map[string]float32, error) {
+// knnHit is the best-matching embedding row for a content hash.
+type knnHit struct {
+	id         int
+	similarity float32
+}
+
+// knnSearch runs a KNN query against the HNSW index, or scans the
+// embeddings table in disk mode, and returns the best matching embedding
+// row per content_hash.
+func (db *DB) knnSearch(embedding []float32, fetchLimit int, threshold float32, eligible func(hash string) bool) (map[string]knnHit, error) {
+	if db.diskIndex() {
+		return db.scanSearch(embedding, fetchLimit, threshold, eligible)
+	}
 	stats := db.hnsw.Stats()
 	if stats.Count == 0 {
 		return nil, nil
@@ -419,7 +1019,7 @@ func (db *DB) knnSearch(embedding []float32, fetchLimit int, threshold float32,
 		placeholders[i] = "?"
 		params[i] = h.ID
 	}
-	hashRows, err := db.conn.Query(
+	hashRows, err := db.reader.Query(
 		fmt.Sprintf(`SELECT id, content_hash FROM embeddings WHERE id IN (%s)`, strings.Join(placeholders, ",")),
 		params...,
 	)
@@ -439,7 +1039,7 @@ func (db *DB) knnSearch(embedding []float32, fetchLimit int, threshold float32,
 	}
 
 	// Post-process: convert distance → similarity, filter, group by content_hash.
-	best := make(map[string]float32)
+	best := make(map[string]knnHit)
 	for _, h := range hits {
 		hash, ok := idToHash[h.ID]
 		if !ok {
@@ -449,29 +1049,113 @@ func (db *DB) knnSearch(embedding []float32, fetchLimit int, threshold float32,
 		if sim <= threshold {
 			continue
 		}
-		if allowedHashes != nil && !allowedHashes[hash] {
+		if eligible != nil && !eligible(hash) {
 			continue
 		}
-		if prev, ok := best[hash]; !ok || sim > prev {
-			best[hash] = sim
+		if prev, ok := best[hash]; !ok || sim > prev.similarity {
+			best[hash] = knnHit{id: h.ID, similarity: sim}
 		}
 	}
 
 	return best, nil
 }
 
-func (db *DB) VectorSearch(embedding []float32, threshold float32, limit int, crateIDs []int) ([]SearchResult, error) {
-	// Load allowed content hashes if filtering by crate.
-	var allowedHashes map[string]bool
-	if len(crateIDs) > 0 {
-		var err error
-		allowedHashes, err = db.contentHashesForCrates(crateIDs)
+// Filter restricts which items are eligible in a search. Zero values match everything.
+type Filter struct {
+	CrateIDs []int
+	Features []string // item must be gated behind at least one of these
+	Kinds    []string // item kind must be one of these
+	Examples bool     // match example code blocks instead of item docs
+
+	ExcludeCrateIDs   []int    // item must not be in these crates
+	ExcludeKinds      []string // item kind must not be one of these
+	ExcludeDeprecated bool     // item must not be #[deprecated]
+	ExcludeUnstable   bool     // item must not be #[unstable]
+}
+
+func (f Filter) IsEmpty() bool {
+	return len(f.CrateIDs) == 0 && len(f.Features) == 0 && len(f.Kinds) == 0 && !f.Examples &&
+		len(f.ExcludeCrateIDs) == 0 && len(f.ExcludeKinds) == 0 && !f.ExcludeDeprecated && !f.ExcludeUnstable
+}
+
+// where returns a SQL condition over the items table and its parameters.
+func (f Filter) where() (string, []interface{}) {
+	var conds []string
+	var params []interface{}
+	if len(f.CrateIDs) > 0 {
+		placeholders := make([]string, len(f.CrateIDs))
+		for i, id := range f.CrateIDs {
+			placeholders[i] = "?"
+			params = append(params, id)
+		}
+		conds = append(conds, fmt.Sprintf(`crate_id IN (%s)`, strings.Join(placeholders, ",")))
+	}
+	if len(f.Features) > 0 {
+		placeholders := make([]string, len(f.Features))
+		for i, feat := range f.Features {
+			placeholders[i] = "?"
+			params = append(params, feat)
+		}
+		conds = append(conds, fmt.Sprintf(
+			`features IS NOT NULL AND features != '' AND EXISTS (SELECT 1 FROM json_each(items.features) WHERE value IN (%s))`,
+			strings.Join(placeholders, ",")))
+	}
+	if len(f.Kinds) > 0 {
+		placeholders := make([]string, len(f.Kinds))
+		for i, kind := range f.Kinds {
+			placeholders[i] = "?"
+			params = append(params, kind)
+		}
+		conds = append(conds, fmt.Sprintf(`items.kind IN (%s)`, strings.Join(placeholders, ",")))
+	}
+	if len(f.ExcludeCrateIDs) > 0 {
+		placeholders := make([]string, len(f.ExcludeCrateIDs))
+		for i, id := range f.ExcludeCrateIDs {
+			placeholders[i] = "?"
+			params = append(params, id)
+		}
+		conds = append(conds, fmt.Sprintf(`crate_id NOT IN (%s)`, strings.Join(placeholders, ",")))
+	}
+	if len(f.ExcludeKinds) > 0 {
+		placeholders := make([]string, len(f.ExcludeKinds))
+		for i, kind := range f.ExcludeKinds {
+			placeholders[i] = "?"
+			params = append(params, kind)
+		}
+		conds = append(conds, fmt.Sprintf(`items.kind NOT IN (%s)`, strings.Join(placeholders, ",")))
+	}
+	if f.ExcludeDeprecated {
+		conds = append(conds, `items.deprecation = ''`)
+	}
+	if f.ExcludeUnstable {
+		// The level is the first field of the encoded stability.
+		conds = append(conds, `items.stability NOT LIKE '{"level":"unstable"%'`)
+	}
+	return strings.Join(conds, " AND "), params
+}
+
+func (db *DB) VectorSearch(embedding []float32, threshold float32, limit int, filter Filter) ([]SearchResult, error) {
+	// Load allowed content hashes if filtering. Unfiltered searches still
+	// leave out code examples, which only compete in example searches, so
+	// they don't take the places of docs that match.
+	var eligible func(hash string) bool
+	if !filter.IsEmpty() {
+		allowed, err := db.contentHashesForFilter(filter)
 		if err != nil {
-			return nil, fmt.Errorf("loading crate hashes: %w", err)
+			return nil, fmt.Errorf("loading filtered hashes: %w", err)
 		}
-		if len(allowedHashes) == 0 {
+		if len(allowed) == 0 {
 			return nil, nil
 		}
+		eligible = func(hash string) bool { return allowed[hash] }
+	} else {
+		examples, err := db.exampleOnlyHashes()
+		if err != nil {
+			return nil, fmt.Errorf("loading example hashes: %w", err)
+		}
+		if len(examples) > 0 {
+			eligible = func(hash string) bool { return !examples[hash] }
+		}
 	}
 
 	fetchLimit := limit * 10
@@ -479,14 +1163,14 @@ func (db *DB) VectorSearch(embedding []float32, threshold float32, limit int, cr
 		fetchLimit = 5000
 	}
 
-	best, err := db.knnSearch(embedding, fetchLimit, threshold, allowedHashes)
+	best, err := db.knnSearch(embedding, fetchLimit, threshold, eligible)
 	if err != nil {
 		return nil, err
 	}
 
 	results := make([]SearchResult, 0, len(best))
-	for hash, sim := range best {
-		results = append(results, SearchResult{ContentHash: hash, Similarity: sim})
+	for hash, hit := range best {
+		results = append(results, SearchResult{ContentHash: hash, Similarity: hit.similarity, embeddingID: hit.id})
 	}
 	sort.Slice(results, func(i, j int) bool {
 		return results[i].Similarity > results[j].Similarity
@@ -494,21 +1178,73 @@ func (db *DB) VectorSearch(embedding []float32, threshold float32, limit int, cr
 	if len(results) > limit {
 		results = results[:limit]
 	}
+	if err := db.fillChunks(results); err != nil {
+		return nil, err
+	}
 	return results, nil
 }
 
-// contentHashesForCrates returns the set of content hashes belonging to the given crate IDs.
-func (db *DB) contentHashesForCrates(crateIDs []int) (map[string]bool, error) {
-	placeholders := make([]string, len(crateIDs))
-	params := make([]interface{}, len(crateIDs))
-	for i, id := range crateIDs {
+// fillChunks sets the matched chunk's text and index on each result.
+func (db *DB) fillChunks(results []SearchResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	placeholders := make([]string, len(results))
+	params := make([]interface{}, len(results))
+	byID := make(map[int]*SearchResult, len(results))
+	for i := range results {
 		placeholders[i] = "?"
-		params[i] = id
+		params[i] = results[i].embeddingID
+		byID[results[i].embeddingID] = &results[i]
+	}
+	rows, err := db.reader.Query(
+		fmt.Sprintf(`SELECT id, chunk_text, chunk_index FROM embeddings WHERE id IN (%s)`, strings.Join(placeholders, ",")),
+		params...,
+	)
+	if err != nil {
+		return fmt.Errorf("looking up matched chunks: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var text string
+		var index int
+		if err := rows.Scan(&id, &text, &index); err != nil {
+			return err
+		}
+		if r := byID[id]; r != nil {
+			r.ChunkText, r.ChunkIndex = text, index
+		}
+	}
+	return rows.Err()
+}
+
+// contentHashesForFilter returns the set of content hashes belonging to items,
+// their fragments and crate metadata (or their examples, when
+// filter.Examples is set) matching the filter.
+func (db *DB) contentHashesForFilter(filter Filter) (map[string]bool, error) {
+	where, params := filter.where()
+	var query string
+	if filter.Examples {
+		query = `SELECT DISTINCT examples.content_hash FROM examples JOIN items ON items.id = examples.item_id`
+		if where != "" {
+			query += " WHERE " + where
+		}
+	} else {
+		query = fmt.Sprintf(
+			`SELECT content_hash FROM items WHERE %[1]s AND content_hash IS NOT NULL
+			UNION
+			SELECT fragments.content_hash FROM fragments JOIN items ON items.id = fragments.item_id WHERE %[1]s`, where)
+		params = append(params, params...)
+		if metaWhere, metaParams, ok := filter.metaWhere(); ok {
+			query += ` UNION SELECT content_hash FROM crate_meta WHERE content_hash != ''`
+			if metaWhere != "" {
+				query += " AND " + metaWhere
+			}
+			params = append(params, metaParams...)
+		}
 	}
-	query := fmt.Sprintf(
-		`SELECT DISTINCT content_hash FROM items WHERE crate_id IN (%s) AND content_hash IS NOT NULL`,
-		strings.Join(placeholders, ","))
-	rows, err := db.conn.Query(query, params...)
+	rows, err := db.reader.Query(query, params...)
 	if err != nil {
 		return nil, err
 	}
@@ -525,6 +1261,49 @@ func (db *DB) contentHashesForCrates(crateIDs []int) (map[string]bool, error) {
 	return hashes, nil
 }
 
+// exampleOnlyHashes returns the content hashes of code examples that aren't
+// also the content of an item, fragment or crate's metadata.
+func (db *DB) exampleOnlyHashes() (map[string]bool, error) {
+	rows, err := db.reader.Query(`
+		SELECT DISTINCT content_hash FROM examples e
+		WHERE NOT EXISTS (SELECT 1 FROM items WHERE content_hash = e.content_hash)
+		  AND NOT EXISTS (SELECT 1 FROM fragments WHERE content_hash = e.content_hash)
+		  AND NOT EXISTS (SELECT 1 FROM crate_meta WHERE content_hash = e.content_hash)`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hashes := make(map[string]bool)
+	for rows.Next() {
+		var h string
+		if err := rows.Scan(&h); err != nil {
+			return nil, err
+		}
+		hashes[h] = true
+	}
+	return hashes, rows.Err()
+}
+
+// CountItemsByCrate returns the number of indexed items for each crate ID.
+func (db *DB) CountItemsByCrate() (map[int]int, error) {
+	rows, err := db.reader.Query(`SELECT crate_id, COUNT(*) FROM items GROUP BY crate_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int]int)
+	for rows.Next() {
+		var crateID, n int
+		if err := rows.Scan(&crateID, &n); err != nil {
+			return nil, err
+		}
+		counts[crateID] = n
+	}
+	return counts, rows.Err()
+}
+
 // GetCratesForItems returns a map from item ID to Crate for the given item IDs in a single query.
 func (db *DB) GetCratesForItems(itemIDs []int) (map[int]*Crate, error) {
 	if len(itemIDs) == 0 {
@@ -537,10 +1316,10 @@ func (db *DB) GetCratesForItems(itemIDs []int) (map[int]*Crate, error) {
 		params[i] = id
 	}
 	query := fmt.Sprintf(`
-		SELECT i.id, c.id, c.name, c.version, c.fetched_at, c.processed_at, c.last_used_at
+		SELECT i.id, c.id, c.name, c.version, c.fetched_at, c.processed_at, c.last_used_at, c.source, c.registry, c.namespace
 		FROM items i JOIN crates c ON c.id = i.crate_id
 		WHERE i.id IN (%s)`, strings.Join(placeholders, ","))
-	rows, err := db.conn.Query(query, params...)
+	rows, err := db.reader.Query(query, params...)
 	if err != nil {
 		return nil, err
 	}
@@ -550,7 +1329,7 @@ func (db *DB) GetCratesForItems(itemIDs []int) (map[int]*Crate, error) {
 	for rows.Next() {
 		var itemID int
 		var c Crate
-		if err := rows.Scan(&itemID, &c.ID, &c.Name, &c.Version, &c.FetchedAt, &c.ProcessedAt, &c.LastUsedAt); err != nil {
+		if err := rows.Scan(&itemID, &c.ID, &c.Name, &c.Version, &c.FetchedAt, &c.ProcessedAt, &c.LastUsedAt, &c.Source, &c.Registry, &c.Namespace); err != nil {
 			return nil, err
 		}
 		result[itemID] = &c
@@ -563,13 +1342,13 @@ func (db *DB) GetCrateIDsByNames(names []string) ([]int, error) {
 		return nil, nil
 	}
 	placeholders := make([]string, len(names))
-	params := make([]interface{}, len(names))
+	params := []interface{}{db.namespace}
 	for i, n := range names {
 		placeholders[i] = "?"
-		params[i] = n
+		params = append(params, n)
 	}
-	query := fmt.Sprintf(`SELECT id FROM crates WHERE name IN (%s)`, strings.Join(placeholders, ","))
-	rows, err := db.conn.Query(query, params...)
+	query := fmt.Sprintf(`SELECT id FROM crates WHERE namespace = ? AND name IN (%s)`, strings.Join(placeholders, ","))
+	rows, err := db.reader.Query(query, params...)
 	if err != nil {
 		return nil, err
 	}
@@ -586,6 +1365,55 @@ func (db *DB) GetCrateIDsByNames(names []string) ([]int, error) {
 	return ids, nil
 }
 
+// GetCrateIDsForSpecs resolves crate specs to crate IDs. A bare name matches
+// every indexed version; "name@version" pins a version, where a partial
+// version like "1.35" matches any "1.35.x"; "name@latest" is the most
+// recently processed version.
+func (db *DB) GetCrateIDsForSpecs(specs []string) ([]int, error) {
+	var names []string
+	var ids []int
+	for _, spec := range specs {
+		name, version, pinned := strings.Cut(spec, "@")
+		if !pinned || version == "" {
+			names = append(names, name)
+			continue
+		}
+		if version == "latest" {
+			c, err := db.GetLatestCrate(name)
+			if err != nil {
+				return nil, err
+			}
+			if c != nil {
+				ids = append(ids, c.ID)
+			}
+			continue
+		}
+
+		rows, err := db.reader.Query(
+			`SELECT id FROM crates WHERE namespace = ? AND name = ? AND (version = ? OR version LIKE ?)`,
+			db.namespace, name, version, version+".%",
+		)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+	}
+
+	byName, err := db.GetCrateIDsByNames(names)
+	if err != nil {
+		return nil, err
+	}
+	return append(ids, byName...), nil
+}
+
 // GetIndexedVersions returns name->version for processed crates matching the given names.
 // If multiple versions exist for the same name, the one with the latest processed_at wins.
 func (db *DB) GetIndexedVersions(names []string) (map[string]string, error) {
@@ -593,21 +1421,21 @@ func (db *DB) GetIndexedVersions(names []string) (map[string]string, error) {
 		return nil, nil
 	}
 	placeholders := make([]string, len(names))
-	params := make([]interface{}, len(names))
+	params := []interface{}{db.namespace}
 	for i, n := range names {
 		placeholders[i] = "?"
-		params[i] = n
+		params = append(params, n)
 	}
 	query := fmt.Sprintf(`
 		SELECT name, version
 		FROM (
 			SELECT name, version, ROW_NUMBER() OVER (PARTITION BY name ORDER BY processed_at DESC) as rn
 			FROM crates
-			WHERE name IN (%s) AND processed_at IS NOT NULL
+			WHERE namespace = ? AND name IN (%s) AND processed_at IS NOT NULL
 		)
 		WHERE rn = 1`, strings.Join(placeholders, ","))
 
-	rows, err := db.conn.Query(query, params...)
+	rows, err := db.reader.Query(query, params...)
 	if err != nil {
 		return nil, fmt.Errorf("getting indexed versions: %w", err)
 	}
@@ -626,7 +1454,7 @@ func (db *DB) GetIndexedVersions(names []string) (map[string]string, error) {
 
 func (db *DB) CountItems(crateID int) (int, error) {
 	var count int
-	err := db.conn.QueryRow(`SELECT COUNT(*) FROM items WHERE crate_id = ?`, crateID).Scan(&count)
+	err := db.reader.QueryRow(`SELECT COUNT(*) FROM items WHERE crate_id = ?`, crateID).Scan(&count)
 	return count, err
 }
 
@@ -652,7 +1480,7 @@ func (db *DB) DeleteReexportsByCrate(crateID int) error {
 // Returns the source crate name and resolved source path.
 func (db *DB) ResolveReexport(crateID int, path string) (sourceCrate, sourcePath string, found bool) {
 	var localPrefix, srcCrate, srcPrefix string
-	err := db.conn.QueryRow(
+	err := db.reader.QueryRow(
 		`SELECT local_prefix, source_crate, source_prefix FROM reexports
 		 WHERE crate_id = ? AND (local_prefix = ? OR ? LIKE local_prefix || '::%')
 		 ORDER BY length(local_prefix) DESC LIMIT 1`,
@@ -669,35 +1497,52 @@ func (db *DB) ResolveReexport(crateID int, path string) (sourceCrate, sourcePath
 	return srcCrate, srcPrefix + suffix, true
 }
 
-func newHNSW() *hnsw.HNSWIndex {
-	return hnsw.NewHNSW(embeddingDim, hnswM, hnswEf, core.Distances["cosine"], "cosine")
+func newHNSW(dim int) *hnsw.HNSWIndex {
+	return hnsw.NewHNSW(dim, hnswM, hnswEf, core.Distances["cosine"], "cosine")
 }
 
-// loadOrCreateHNSW loads the HNSW index from disk, or creates a new one.
-// If embeddings exist in SQLite but the HNSW file is missing, rebuilds from SQLite.
+// loadOrCreateHNSW loads the HNSW index from its last checkpoint and
+// replays the vectors logged since, or creates a new one. If embeddings
+// exist in SQLite but the HNSW file is missing, unreadable or was built for
+// another dimension, rebuilds from SQLite.
 func (db *DB) loadOrCreateHNSW() error {
 	if f, err := os.Open(db.hnswPath); err == nil {
-		db.hnsw = newHNSW()
-		if err := db.hnsw.Load(f); err != nil {
-			f.Close()
-			return fmt.Errorf("loading HNSW index: %w", err)
-		}
+		db.hnsw = newHNSW(db.dim)
+		err := db.hnsw.Load(f)
 		f.Close()
-		return nil
+		switch {
+		case err != nil:
+			slog.Warn("HNSW index unreadable", "error", err)
+		case db.hnsw.Dimension == db.dim:
+			return db.replayHNSWLog()
+		default:
+			slog.Warn("HNSW index dimension does not match embeddings", "index", db.hnsw.Dimension, "embeddings", db.dim)
+		}
 	}
 
-	db.hnsw = newHNSW()
+	db.hnsw = newHNSW(db.dim)
 
-	// Rebuild from SQLite if embeddings exist.
+	// Rebuild from SQLite if embeddings exist. The log only holds vectors
+	// SQLite has too.
 	var count int
 	db.conn.QueryRow(`SELECT COUNT(*) FROM embeddings`).Scan(&count)
 	if count == 0 {
-		return nil
+		db.hnswLog.mu.Lock()
+		defer db.hnswLog.mu.Unlock()
+		return db.hnswLog.reset()
 	}
 
 	slog.Info("rebuilding HNSW index", "embeddings", count)
+	if err := db.fillHNSW(db.hnsw); err != nil {
+		return err
+	}
+	db.saveHNSW()
+	return nil
+}
 
-	rows, err := db.conn.Query(`SELECT id, embedding FROM embeddings`)
+// fillHNSW adds every stored embedding of the current dimension to idx.
+func (db *DB) fillHNSW(idx *hnsw.HNSWIndex) error {
+	rows, err := db.conn.Query(`SELECT id, embedding, encoding FROM embeddings`)
 	if err != nil {
 		return fmt.Errorf("reading embeddings for HNSW rebuild: %w", err)
 	}
@@ -706,41 +1551,95 @@ func (db *DB) loadOrCreateHNSW() error {
 	for rows.Next() {
 		var id int
 		var blob []byte
-		if err := rows.Scan(&id, &blob); err != nil {
+		var encoding string
+		if err := rows.Scan(&id, &blob, &encoding); err != nil {
 			return fmt.Errorf("scanning embedding row: %w", err)
 		}
-		vec := deserializeFloat32(blob)
-		if len(vec) != embeddingDim {
-			slog.Warn("skipping embedding with wrong dimension", "id", id, "got", len(vec), "want", embeddingDim)
+		vec, err := decodeEmbedding(blob, encoding)
+		if err != nil {
+			slog.Warn("skipping undecodable embedding", "id", id, "error", err)
 			continue
 		}
-		if err := db.hnsw.Add(id, vec); err != nil {
+		if len(vec) != db.dim {
+			slog.Warn("skipping embedding with wrong dimension", "id", id, "got", len(vec), "want", db.dim)
+			continue
+		}
+		if err := idx.Add(id, vec); err != nil {
 			slog.Warn("skipping embedding", "id", id, "error", err)
 		}
 	}
-
-	db.saveHNSW()
-	return nil
+	return rows.Err()
 }
 
-// SaveHNSW persists the HNSW index to disk.
-func (db *DB) SaveHNSW() {
-	db.saveHNSW()
+// replayHNSWLog adds the vectors logged since the last checkpoint to the
+// index, checkpointing if there were any.
+func (db *DB) replayHNSWLog() error {
+	db.hnswLog.mu.Lock()
+	defer db.hnswLog.mu.Unlock()
+	n, err := db.hnswLog.replay(db.dim, func(id int, vec []float32) {
+		// Entries already in the index were logged before a checkpoint
+		// whose log truncation didn't happen.
+		if err := db.hnsw.Add(id, vec); err != nil {
+			slog.Debug("skipping logged vector", "id", id, "error", err)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		// Drop any torn tail so new entries aren't appended after it.
+		return db.hnswLog.reset()
+	}
+	slog.Info("replayed HNSW log", "vectors", n)
+	return db.checkpointHNSWLocked()
 }
 
+// saveHNSW checkpoints the HNSW index: writes it to disk in full and
+// empties the log.
 func (db *DB) saveHNSW() {
+	db.hnswLog.mu.Lock()
+	defer db.hnswLog.mu.Unlock()
+	if err := db.checkpointHNSWLocked(); err != nil {
+		slog.Error("failed to save HNSW index", "error", err)
+	}
+}
+
+func (db *DB) checkpointHNSWLocked() error {
+	if err := db.saveHNSWTo(db.hnswPath); err != nil {
+		return err
+	}
+	return db.hnswLog.reset()
+}
+
+// saveHNSWTo writes the index to path atomically, via a temporary file
+// renamed into place, so a crash mid-save leaves the previous file intact.
+func (db *DB) saveHNSWTo(path string) error {
 	if db.hnsw == nil {
-		return
+		return nil
 	}
-	f, err := os.Create(db.hnswPath)
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
 	if err != nil {
-		slog.Error("failed to create HNSW file", "error", err)
-		return
+		return fmt.Errorf("creating HNSW file: %w", err)
 	}
-	if err := db.hnsw.Save(f); err != nil {
-		slog.Error("failed to save HNSW index", "error", err)
+	defer os.Remove(tmp)
+	w := bufio.NewWriter(f)
+	if err := db.hnsw.Save(w); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
 	}
-	f.Close()
+	return os.Rename(tmp, path)
 }
 
 func serializeFloat32(v []float32) []byte {
@@ -1,9 +1,12 @@
 package db
 
 import (
+	"database/sql"
+	"fmt"
 	"math"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 )
 
@@ -101,7 +104,7 @@ func TestVectorSearch(t *testing.T) {
 	}
 
 	// Search with emb1 — should find hash_a as most similar
-	results, err := db.VectorSearch(emb1, 0.0, 10, nil)
+	results, err := db.VectorSearch(emb1, 0.0, 10, Filter{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -113,7 +116,7 @@ func TestVectorSearch(t *testing.T) {
 	}
 
 	// Search with high threshold — should filter out dissimilar
-	results, err = db.VectorSearch(emb1, 0.99, 10, nil)
+	results, err = db.VectorSearch(emb1, 0.99, 10, Filter{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -131,7 +134,7 @@ func TestVectorSearch(t *testing.T) {
 	if err := db.InsertItem(&Item{CrateID: crate.ID, RustdocID: "1", Name: "A", Path: "A", Kind: "struct", ContentHash: "hash_a"}); err != nil {
 		t.Fatal(err)
 	}
-	results, err = db.VectorSearch(emb1, 0.0, 10, []int{crate.ID})
+	results, err = db.VectorSearch(emb1, 0.0, 10, Filter{CrateIDs: []int{crate.ID}})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -139,8 +142,54 @@ func TestVectorSearch(t *testing.T) {
 		t.Errorf("crate filter: expected only hash_a, got %v", results)
 	}
 
+	// Feature filter — only hash_b's item is gated behind "full"
+	if err := db.InsertItem(&Item{CrateID: crate.ID, RustdocID: "2", Name: "B", Path: "B", Kind: "fn", ContentHash: "hash_b", Features: `["full","rt"]`}); err != nil {
+		t.Fatal(err)
+	}
+	results, err = db.VectorSearch(emb1, -2.0, 10, Filter{Features: []string{"full"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].ContentHash != "hash_b" {
+		t.Errorf("feature filter: expected only hash_b, got %v", results)
+	}
+	item, err := db.GetItemForHash("hash_b", Filter{Features: []string{"rt"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item == nil || item.Features != `["full","rt"]` {
+		t.Errorf("GetItemForHash with feature filter: got %+v", item)
+	}
+
+	// Deprecation filter — hash_b's item is deprecated
+	if _, err := db.conn.Exec(`UPDATE items SET deprecation = '{"since":"1.1","note":null}' WHERE content_hash = 'hash_b'`); err != nil {
+		t.Fatal(err)
+	}
+	results, err = db.VectorSearch(emb1, -2.0, 10, Filter{CrateIDs: []int{crate.ID}, ExcludeDeprecated: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].ContentHash != "hash_a" {
+		t.Errorf("deprecation filter: expected only hash_a, got %v", results)
+	}
+	if item, err := db.GetItemByPath(crate.ID, "B"); err != nil || item == nil || item.Deprecation != `{"since":"1.1","note":null}` {
+		t.Errorf("GetItemByPath deprecation: got %+v, %v", item, err)
+	}
+
+	// Stability filter — hash_a's item is nightly-only
+	if _, err := db.conn.Exec(`UPDATE items SET stability = '{"level":"unstable","feature":"x"}' WHERE content_hash = 'hash_a'`); err != nil {
+		t.Fatal(err)
+	}
+	results, err = db.VectorSearch(emb1, -2.0, 10, Filter{CrateIDs: []int{crate.ID}, ExcludeUnstable: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].ContentHash != "hash_b" {
+		t.Errorf("stability filter: expected only hash_b, got %v", results)
+	}
+
 	// Limit
-	results, err = db.VectorSearch(emb1, 0.0, 1, nil)
+	results, err = db.VectorSearch(emb1, 0.0, 1, Filter{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -198,6 +247,84 @@ func TestGetCratesForItems(t *testing.T) {
 			t.Fatalf("expected 2 results, got %d", len(result))
 		}
 	})
+
+	t.Run("list_items", func(t *testing.T) {
+		items, err := db.ListItems(crate.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != 2 || items[0].Path != "mycrate::Bar" || items[1].Path != "mycrate::Foo" {
+			t.Errorf("expected items ordered by path, got %+v", items)
+		}
+	})
+}
+
+func TestGetCrateIDsForSpecs(t *testing.T) {
+	db := testDB(t)
+
+	old, err := db.UpsertCrate("tokio", "1.35.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newer, err := db.UpsertCrate("tokio", "1.40.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.MarkCrateProcessed(old.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.conn.Exec(`UPDATE crates SET processed_at = datetime('now', '+1 minute') WHERE id = ?`, newer.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		spec string
+		want []int
+	}{
+		{"tokio", []int{old.ID, newer.ID}},
+		{"tokio@1.35", []int{old.ID}},
+		{"tokio@1.35.1", []int{old.ID}},
+		{"tokio@1.3", nil},
+		{"tokio@latest", []int{newer.ID}},
+	}
+	for _, tt := range tests {
+		got, err := db.GetCrateIDsForSpecs([]string{tt.spec})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.spec, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: got %v, want %v", tt.spec, got, tt.want)
+				break
+			}
+		}
+	}
+
+	t.Run("representative_prefers_latest", func(t *testing.T) {
+		for _, c := range []*Crate{old, newer} {
+			if err := db.InsertItem(&Item{CrateID: c.ID, RustdocID: "1", Name: "spawn", Path: "tokio::spawn", Kind: "function", ContentHash: "shared"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		it, err := db.GetItemForHash("shared", Filter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if it == nil || it.CrateID != newer.ID {
+			t.Errorf("expected item from newest crate %d, got %+v", newer.ID, it)
+		}
+		it, err = db.GetItemForHash("shared", Filter{CrateIDs: []int{old.ID}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if it == nil || it.CrateID != old.ID {
+			t.Errorf("expected item from pinned crate %d, got %+v", old.ID, it)
+		}
+	})
 }
 
 func TestResolveReexport(t *testing.T) {
@@ -247,6 +374,307 @@ func TestResolveReexport(t *testing.T) {
 	})
 }
 
+func TestExamples(t *testing.T) {
+	db := testDB(t)
+	crate, err := db.UpsertCrate("mycrate", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := &Item{CrateID: crate.ID, RustdocID: "1", Name: "spawn", Path: "mycrate::spawn", Kind: "function", ContentHash: "doc_hash"}
+	if err := db.InsertItem(item); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.InsertExample(item.ID, 0, "example_hash"); err != nil {
+		t.Fatal(err)
+	}
+
+	emb := make([]float32, 1024)
+	for i := range emb {
+		emb[i] = 1.0
+	}
+	if err := db.InsertEmbedding("doc_hash", "docs", 0, emb); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.InsertEmbedding("example_hash", "code", 0, emb); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("examples_filter", func(t *testing.T) {
+		results, err := db.VectorSearch(emb, 0.0, 10, Filter{Examples: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0].ContentHash != "example_hash" {
+			t.Errorf("expected only example_hash, got %v", results)
+		}
+	})
+
+	t.Run("unfiltered_skips_examples", func(t *testing.T) {
+		// Examples outnumbering the docs, and matching better, don't
+		// crowd the docs out of a search.
+		other := &Item{CrateID: crate.ID, RustdocID: "2", Name: "block_on", Path: "mycrate::block_on", Kind: "function", ContentHash: "other_doc_hash"}
+		if err := db.InsertItem(other); err != nil {
+			t.Fatal(err)
+		}
+		near := slices.Clone(emb)
+		near[0] = 0.5
+		if err := db.InsertEmbedding("other_doc_hash", "docs", 0, near); err != nil {
+			t.Fatal(err)
+		}
+		for i := 1; i <= 12; i++ {
+			hash := fmt.Sprintf("example_hash_%d", i)
+			if err := db.InsertExample(other.ID, i, hash); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.InsertEmbedding(hash, "code", 0, emb); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		results, err := db.VectorSearch(emb, 0.0, 2, Filter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, r := range results {
+			got = append(got, r.ContentHash)
+		}
+		if want := []string{"doc_hash", "other_doc_hash"}; !slices.Equal(got, want) {
+			t.Errorf("unfiltered search = %v, want %v", got, want)
+		}
+		if results, _ := db.VectorSearch(emb, 0.0, 20, Filter{Examples: true}); len(results) != 13 {
+			t.Errorf("examples search found %d examples, want 13", len(results))
+		}
+	})
+
+	t.Run("get_for_hash", func(t *testing.T) {
+		ex, err := db.GetExampleForHash("example_hash", Filter{CrateIDs: []int{crate.ID}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ex == nil || ex.ItemID != item.ID || ex.Index != 0 {
+			t.Errorf("unexpected example: %+v", ex)
+		}
+		ex, err = db.GetExampleForHash("example_hash", Filter{CrateIDs: []int{crate.ID + 1}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ex != nil {
+			t.Errorf("expected no example for other crate, got %+v", ex)
+		}
+	})
+
+	t.Run("deleted_with_items", func(t *testing.T) {
+		if err := db.DeleteItemsByCrate(crate.ID); err != nil {
+			t.Fatal(err)
+		}
+		ex, err := db.GetExampleForHash("example_hash", Filter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ex != nil {
+			t.Errorf("expected example to be deleted, got %+v", ex)
+		}
+	})
+}
+
+func TestFragments(t *testing.T) {
+	db := testDB(t)
+	crate, err := db.UpsertCrate("mycrate", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := &Item{CrateID: crate.ID, RustdocID: "1", Name: "Widget", Path: "mycrate::Widget", Kind: "struct", ContentHash: "doc_hash"}
+	if err := db.InsertItem(item); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.InsertFragment(item.ID, "fields", "fields_hash"); err != nil {
+		t.Fatal(err)
+	}
+
+	emb := make([]float32, 1024)
+	for i := range emb {
+		emb[i] = 1.0
+	}
+	if err := db.InsertEmbedding("fields_hash", "mycrate::Widget#fields\n\nsize", 0, emb); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("crate_filter_includes_fragments", func(t *testing.T) {
+		results, err := db.VectorSearch(emb, 0.0, 10, Filter{CrateIDs: []int{crate.ID}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0].ContentHash != "fields_hash" {
+			t.Fatalf("expected fields_hash, got %v", results)
+		}
+		if results[0].ChunkText != "mycrate::Widget#fields\n\nsize" || results[0].ChunkIndex != 0 {
+			t.Errorf("unexpected chunk %q (%d)", results[0].ChunkText, results[0].ChunkIndex)
+		}
+	})
+
+	t.Run("get_for_hash", func(t *testing.T) {
+		f, err := db.GetFragmentForHash("fields_hash", Filter{CrateIDs: []int{crate.ID}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f == nil || f.ItemID != item.ID || f.Name != "fields" {
+			t.Errorf("unexpected fragment: %+v", f)
+		}
+		f, err = db.GetFragmentForHash("fields_hash", Filter{CrateIDs: []int{crate.ID + 1}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f != nil {
+			t.Errorf("expected no fragment for other crate, got %+v", f)
+		}
+	})
+
+	t.Run("deleted_with_items", func(t *testing.T) {
+		if err := db.DeleteItemsByCrate(crate.ID); err != nil {
+			t.Fatal(err)
+		}
+		f, err := db.GetFragmentForHash("fields_hash", Filter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f != nil {
+			t.Errorf("expected fragment to be deleted, got %+v", f)
+		}
+	})
+}
+
+func TestReplaceCrateItems(t *testing.T) {
+	db := testDB(t)
+	crate, err := db.UpsertCrate("mycrate", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := &Item{CrateID: crate.ID, RustdocID: "old", Name: "Old", Path: "mycrate::Old", Kind: "struct"}
+	if err := db.InsertItem(old); err != nil {
+		t.Fatal(err)
+	}
+
+	records := []ItemRecord{
+		{
+			Item:      &Item{RustdocID: "1", Name: "Widget", Path: "mycrate::Widget", Kind: "struct", ContentHash: "doc_hash"},
+			Fragments: []Fragment{{Name: "fields", ContentHash: "fields_hash"}},
+			Examples:  []Example{{Index: 0, ContentHash: "example_hash"}},
+		},
+		// Same rustdoc ID: skipped, leaving the first record in place.
+		{Item: &Item{RustdocID: "1", Name: "Dup", Path: "mycrate::Dup", Kind: "struct"}},
+	}
+	if err := db.ReplaceCrateItems(crate.ID, records); err != nil {
+		t.Fatal(err)
+	}
+
+	widget := records[0]
+	if widget.Item.ID == 0 || widget.Fragments[0].ID == 0 || widget.Examples[0].ID == 0 {
+		t.Fatalf("IDs not filled in: %+v", widget)
+	}
+	if records[1].Item.ID != 0 {
+		t.Errorf("duplicate item got ID %d", records[1].Item.ID)
+	}
+	items, err := db.ListItems(crate.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Path != "mycrate::Widget" {
+		t.Errorf("expected only mycrate::Widget, got %+v", items)
+	}
+	if hash, _ := db.GetFragmentHash(widget.Item.ID, "fields"); hash != "fields_hash" {
+		t.Errorf("fragment hash = %q", hash)
+	}
+	if ex, _ := db.GetExampleForHash("example_hash", Filter{}); ex == nil || ex.ItemID != widget.Item.ID {
+		t.Errorf("unexpected example: %+v", ex)
+	}
+}
+
+func TestInsertEmbeddings(t *testing.T) {
+	db := testDB(t)
+
+	records := make([]EmbeddingRecord, 3)
+	for i := range records {
+		records[i] = EmbeddingRecord{ContentHash: "hash", ChunkText: "chunk", ChunkIndex: i, Embedding: testEmbedding(1024)}
+		records[i].Embedding[i] = 2
+	}
+	if err := db.InsertEmbeddings(records); err != nil {
+		t.Fatal(err)
+	}
+	vecs, err := db.EmbeddingsForHash("hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vecs) != 3 {
+		t.Fatalf("expected 3 embeddings, got %d", len(vecs))
+	}
+	results, err := db.VectorSearch(records[1].Embedding, 0, 10, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) == 0 || results[0].ChunkIndex != 1 {
+		t.Errorf("expected chunk 1 first, got %+v", results)
+	}
+
+	t.Run("invalid_record_skipped", func(t *testing.T) {
+		mixed := []EmbeddingRecord{
+			{ContentHash: "other", ChunkText: "ok", Embedding: testEmbedding(1024)},
+			{ContentHash: "other", ChunkText: "bad", ChunkIndex: 1, Embedding: []float32{1, 2, 3}},
+		}
+		if err := db.InsertEmbeddings(mixed); err != nil {
+			t.Fatalf("batch with a valid record: %v", err)
+		}
+		if vecs, _ := db.EmbeddingsForHash("other"); len(vecs) != 1 {
+			t.Errorf("stored %d embeddings of the batch, want the valid 1", len(vecs))
+		}
+		if err := db.InsertEmbeddings(mixed[1:]); err == nil {
+			t.Error("expected error for a batch with no valid record")
+		}
+	})
+}
+
+func TestReaderPool(t *testing.T) {
+	db := testDB(t)
+
+	// Readers only see the last committed state during a write under WAL.
+	for name, conn := range map[string]*sql.DB{"writer": db.conn, "reader": db.reader} {
+		var mode string
+		if err := conn.QueryRow(`PRAGMA journal_mode`).Scan(&mode); err != nil {
+			t.Fatal(err)
+		}
+		if mode != "wal" {
+			t.Errorf("%s journal_mode = %q, want wal", name, mode)
+		}
+	}
+
+	// Hold the write lock with an uncommitted crate.
+	tx, err := db.conn.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`INSERT INTO crates (name, version) VALUES ('serde', '1.0.0')`); err != nil {
+		t.Fatal(err)
+	}
+	c, err := db.GetCrate("serde", "1.0.0")
+	if err != nil {
+		t.Fatalf("read during write: %v", err)
+	}
+	if c != nil {
+		t.Error("reader saw an uncommitted crate")
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := db.GetCrate("serde", "1.0.0"); c == nil {
+		t.Error("reader missed a committed crate")
+	}
+
+	if _, err := db.reader.Exec(`DELETE FROM crates`); err == nil {
+		t.Error("reader pool accepted a write")
+	}
+}
+
 func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
@@ -0,0 +1,10 @@
+package db
+
+import "database/sql"
+
+// addItemStability is migration 7. Items indexed before it have no
+// stability until their crate is indexed again.
+func addItemStability(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE items ADD COLUMN stability TEXT NOT NULL DEFAULT ''`)
+	return err
+}
@@ -0,0 +1,90 @@
+package db
+
+// CrateContentHashes returns the distinct content hashes each crate's
+// items, fragments, examples and metadata reference, keyed by crate ID.
+func (db *DB) CrateContentHashes() (map[int][]string, error) {
+	rows, err := db.reader.Query(`SELECT crate_id, content_hash FROM items WHERE content_hash IS NOT NULL AND content_hash != ''
+		UNION SELECT items.crate_id, fragments.content_hash FROM fragments JOIN items ON items.id = fragments.item_id
+		UNION SELECT items.crate_id, examples.content_hash FROM examples JOIN items ON items.id = examples.item_id
+		UNION SELECT crate_id, content_hash FROM crate_meta WHERE content_hash != ''`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hashes := make(map[int][]string)
+	for rows.Next() {
+		var crateID int
+		var hash string
+		if err := rows.Scan(&crateID, &hash); err != nil {
+			return nil, err
+		}
+		hashes[crateID] = append(hashes[crateID], hash)
+	}
+	return hashes, rows.Err()
+}
+
+// EmbeddingSize is the stored embeddings of one content hash.
+type EmbeddingSize struct {
+	Chunks int
+	Bytes  int64
+}
+
+// EmbeddingSizes returns the chunk count and encoded size of the embeddings
+// stored for each content hash.
+func (db *DB) EmbeddingSizes() (map[string]EmbeddingSize, error) {
+	rows, err := db.reader.Query(`SELECT content_hash, COUNT(*), SUM(LENGTH(embedding)) FROM embeddings GROUP BY content_hash`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sizes := make(map[string]EmbeddingSize)
+	for rows.Next() {
+		var hash string
+		var s EmbeddingSize
+		if err := rows.Scan(&hash, &s.Chunks, &s.Bytes); err != nil {
+			return nil, err
+		}
+		sizes[hash] = s
+	}
+	return sizes, rows.Err()
+}
+
+// ItemCoverage is how completely one item is documented and embedded.
+type ItemCoverage struct {
+	Path       string
+	Kind       string
+	Documented bool // has doc content
+	Embedded   bool // its doc content has embeddings
+	// Fragments counts the item's fragments and EmbeddedFragments those
+	// with embeddings.
+	Fragments         int
+	EmbeddedFragments int
+}
+
+// CrateCoverage returns the documentation and embedding coverage of each
+// item of a crate, ordered by path.
+func (db *DB) CrateCoverage(crateID int) ([]ItemCoverage, error) {
+	rows, err := db.reader.Query(`SELECT items.path, items.kind,
+			COALESCE(items.content_hash, '') != '',
+			EXISTS (SELECT 1 FROM embeddings WHERE embeddings.content_hash = items.content_hash),
+			(SELECT COUNT(*) FROM fragments WHERE fragments.item_id = items.id),
+			(SELECT COUNT(*) FROM fragments WHERE fragments.item_id = items.id
+				AND EXISTS (SELECT 1 FROM embeddings WHERE embeddings.content_hash = fragments.content_hash))
+		FROM items WHERE crate_id = ? ORDER BY items.path`, crateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ItemCoverage
+	for rows.Next() {
+		var c ItemCoverage
+		if err := rows.Scan(&c.Path, &c.Kind, &c.Documented, &c.Embedded, &c.Fragments, &c.EmbeddedFragments); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
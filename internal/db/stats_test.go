@@ -0,0 +1,82 @@
+package db
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCrateContentHashes(t *testing.T) {
+	t.Parallel()
+	db := testDB(t)
+	a, _ := db.UpsertCrate("a", "1.0.0")
+	b, _ := db.UpsertCrate("b", "1.0.0")
+	records := func(doc string) []ItemRecord {
+		return []ItemRecord{{
+			Item:      &Item{RustdocID: "1", Name: "X", Path: "x::X", Kind: "struct", ContentHash: doc},
+			Fragments: []Fragment{{Name: "fields", ContentHash: "shared_hash"}},
+			Examples:  []Example{{Index: 0, ContentHash: doc + "_example"}},
+		}}
+	}
+	if err := db.ReplaceCrateItems(a.ID, records("a_doc")); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.ReplaceCrateItems(b.ID, records("b_doc")); err != nil {
+		t.Fatal(err)
+	}
+
+	hashes, err := db.CrateContentHashes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := hashes[a.ID]
+	slices.Sort(got)
+	if want := []string{"a_doc", "a_doc_example", "shared_hash"}; !slices.Equal(got, want) {
+		t.Errorf("crate a hashes = %v, want %v", got, want)
+	}
+
+	for i := range 2 {
+		if err := db.InsertEmbedding("shared_hash", "chunk", i, testEmbedding(1024)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sizes, err := db.EmbeddingSizes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := sizes["shared_hash"]; s.Chunks != 2 || s.Bytes != 2*1024*4 {
+		t.Errorf("shared_hash embeddings = %+v", s)
+	}
+}
+
+func TestCrateCoverage(t *testing.T) {
+	t.Parallel()
+	db := testDB(t)
+	crate, _ := db.UpsertCrate("a", "1.0.0")
+	records := []ItemRecord{
+		{
+			Item:      &Item{RustdocID: "1", Name: "Documented", Path: "a::Documented", Kind: "struct", ContentHash: "doc_hash"},
+			Fragments: []Fragment{{Name: "fields", ContentHash: "fields_hash"}, {Name: "implementations", ContentHash: "impls_hash"}},
+		},
+		{Item: &Item{RustdocID: "2", Name: "bare", Path: "a::bare", Kind: "function"}},
+	}
+	if err := db.ReplaceCrateItems(crate.ID, records); err != nil {
+		t.Fatal(err)
+	}
+	for _, hash := range []string{"doc_hash", "fields_hash"} {
+		if err := db.InsertEmbedding(hash, "chunk", 0, testEmbedding(1024)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cov, err := db.CrateCoverage(crate.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ItemCoverage{
+		{Path: "a::Documented", Kind: "struct", Documented: true, Embedded: true, Fragments: 2, EmbeddedFragments: 1},
+		{Path: "a::bare", Kind: "function"},
+	}
+	if !slices.Equal(cov, want) {
+		t.Errorf("coverage = %+v, want %+v", cov, want)
+	}
+}
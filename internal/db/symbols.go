@@ -0,0 +1,96 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Symbol match ranks for FindItems, best first.
+const (
+	symbolExactPath = iota
+	symbolExactName
+	symbolPrefix
+	symbolSubstring
+)
+
+// SymbolMatch is an item found by name, with its crate.
+type SymbolMatch struct {
+	Item
+	CrateName    string
+	CrateVersion string
+}
+
+// FindItems looks items up by name, for editors and symbol search rather
+// than by meaning. A query containing "::" matches item paths, exactly or as
+// a suffix ("sync::Mutex" finds tokio::sync::Mutex); otherwise it matches
+// item names. Exact matches come first, then (unless exact is set) names or
+// paths starting with the query, then containing it, case-insensitively.
+// Each crate's item is only returned from its most recently processed
+// version, unless the filter pins others. Only db's namespace is searched.
+func (db *DB) FindItems(query string, exact bool, limit int, filter Filter) ([]SymbolMatch, error) {
+	if query == "" {
+		return nil, nil
+	}
+	field := "items.name"
+	if strings.Contains(query, "::") {
+		field = "items.path"
+	}
+	rank := fmt.Sprintf(`CASE
+		WHEN items.path = ?1 OR substr(items.path, -length(?2)) = ?2 THEN %[1]d
+		WHEN %[5]s = ?1 THEN %[2]d
+		WHEN substr(lower(%[5]s), 1, length(?1)) = lower(?1) THEN %[3]d
+		WHEN instr(lower(%[5]s), lower(?1)) > 0 THEN %[4]d
+		END`, symbolExactPath, symbolExactName, symbolPrefix, symbolSubstring, field)
+	maxRank := symbolSubstring
+	if exact {
+		maxRank = symbolExactName
+	}
+
+	q := `SELECT items.id, crate_id, rustdoc_id, items.name, path, kind, content_hash, signature, doc_links, fragment_names, features, deprecation, stability,
+		crates.name, crates.version, ` + rank + ` AS rank
+		FROM items JOIN crates ON crates.id = items.crate_id
+		WHERE rank <= ?3 AND crates.namespace = ?4`
+	params := []interface{}{query, "::" + query, maxRank, db.namespace}
+	if where, filterParams := filter.where(); where != "" {
+		q += " AND " + where
+		params = append(params, filterParams...)
+	}
+	q += ` ORDER BY rank, length(items.path), crates.processed_at DESC, items.id`
+
+	rows, err := db.reader.Query(q, params...)
+	if err != nil {
+		return nil, fmt.Errorf("finding items: %w", err)
+	}
+	defer rows.Close()
+
+	var out []SymbolMatch
+	seen := make(map[string]bool)
+	for rows.Next() && len(out) < limit {
+		var m SymbolMatch
+		var rank int
+		it, err := scanItem(scanWith(rows, &m.CrateName, &m.CrateVersion, &rank))
+		if err != nil {
+			return nil, err
+		}
+		m.Item = *it
+		if key := m.CrateName + "\x00" + m.Path; !seen[key] {
+			seen[key] = true
+			out = append(out, m)
+		}
+	}
+	return out, rows.Err()
+}
+
+// extraScanner scans a row of item columns followed by extra columns.
+type extraScanner struct {
+	row   rowScanner
+	extra []interface{}
+}
+
+func scanWith(row rowScanner, extra ...interface{}) rowScanner {
+	return extraScanner{row: row, extra: extra}
+}
+
+func (s extraScanner) Scan(dest ...interface{}) error {
+	return s.row.Scan(append(dest, s.extra...)...)
+}
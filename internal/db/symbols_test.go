@@ -0,0 +1,66 @@
+package db
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFindItems(t *testing.T) {
+	t.Parallel()
+	db := testDB(t)
+	tokio, _ := db.UpsertCrate("tokio", "1.0.0")
+	other, _ := db.UpsertCrate("parking_lot", "0.12.0")
+	items := func(paths ...string) []ItemRecord {
+		var records []ItemRecord
+		for i, p := range paths {
+			name := p[strings.LastIndex(p, ":")+1:]
+			records = append(records, ItemRecord{Item: &Item{RustdocID: string(rune('a' + i)), Name: name, Path: p, Kind: "struct"}})
+		}
+		return records
+	}
+	if err := db.ReplaceCrateItems(tokio.ID, items("tokio::sync::Mutex", "tokio::sync::MutexGuard", "tokio::sync::RwLock", "tokio::sync::OwnedMutexGuard")); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.ReplaceCrateItems(other.ID, items("parking_lot::Mutex")); err != nil {
+		t.Fatal(err)
+	}
+
+	paths := func(query string, exact bool, filter Filter) []string {
+		t.Helper()
+		matches, err := db.FindItems(query, exact, 10, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, m := range matches {
+			out = append(out, m.Path)
+		}
+		return out
+	}
+
+	if got, want := paths("mutex", false, Filter{}), []string{"tokio::sync::Mutex", "parking_lot::Mutex", "tokio::sync::MutexGuard", "tokio::sync::OwnedMutexGuard"}; !slices.Equal(got, want) {
+		t.Errorf("mutex = %v, want %v", got, want)
+	}
+	if got, want := paths("Mutex", true, Filter{}), []string{"tokio::sync::Mutex", "parking_lot::Mutex"}; !slices.Equal(got, want) {
+		t.Errorf("exact Mutex = %v, want %v", got, want)
+	}
+	if got, want := paths("sync::Mutex", true, Filter{}), []string{"tokio::sync::Mutex"}; !slices.Equal(got, want) {
+		t.Errorf("sync::Mutex = %v, want %v", got, want)
+	}
+	if got, want := paths("Mutex", true, Filter{CrateIDs: []int{tokio.ID}}), []string{"tokio::sync::Mutex"}; !slices.Equal(got, want) {
+		t.Errorf("Mutex in tokio = %v, want %v", got, want)
+	}
+	if got, want := paths("Mutex", true, Filter{ExcludeCrateIDs: []int{tokio.ID}}), []string{"parking_lot::Mutex"}; !slices.Equal(got, want) {
+		t.Errorf("Mutex outside tokio = %v, want %v", got, want)
+	}
+	if got := paths("Mutex", false, Filter{ExcludeKinds: []string{"struct"}}); len(got) != 0 {
+		t.Errorf("Mutex non-structs = %v, want none", got)
+	}
+	if got := paths("Mutex", false, Filter{Kinds: []string{"macro"}}); len(got) != 0 {
+		t.Errorf("Mutex macros = %v, want none", got)
+	}
+	if got := paths("Mutex_", false, Filter{}); len(got) != 0 {
+		t.Errorf("Mutex_ = %v, want none", got)
+	}
+}
@@ -0,0 +1,90 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// addEmbeddingUsage is migration 8.
+func addEmbeddingUsage(tx *sql.Tx) error {
+	for _, stmt := range []string{
+		`CREATE TABLE embedding_usage (
+			id INTEGER PRIMARY KEY,
+			crate_id INTEGER NOT NULL REFERENCES crates(id),
+			model TEXT NOT NULL,
+			tokens INTEGER NOT NULL,
+			recorded_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX idx_embedding_usage_crate ON embedding_usage (crate_id)`,
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// detachEmbeddingUsage is migration 10. It rebuilds embedding_usage with a
+// nullable crate_id, so usage outlives the crates it was billed for and
+// deleting a namespace doesn't reset the monthly budget.
+func detachEmbeddingUsage(tx *sql.Tx) error {
+	for _, stmt := range []string{
+		`CREATE TABLE embedding_usage_new (
+			id INTEGER PRIMARY KEY,
+			crate_id INTEGER REFERENCES crates(id),
+			model TEXT NOT NULL,
+			tokens INTEGER NOT NULL,
+			recorded_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`INSERT INTO embedding_usage_new (id, crate_id, model, tokens, recorded_at)
+			SELECT id, crate_id, model, tokens, recorded_at FROM embedding_usage`,
+		`DROP TABLE embedding_usage`,
+		`ALTER TABLE embedding_usage_new RENAME TO embedding_usage`,
+		`CREATE INDEX idx_embedding_usage_crate ON embedding_usage (crate_id)`,
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("executing %q: %w", stmt, err)
+		}
+	}
+	return nil
+}
+
+// RecordUsage logs the tokens the embedding provider billed for one add of
+// a crate. Each add is a row, so re-indexing adds to what a crate has cost.
+func (db *DB) RecordUsage(crateID int, model string, tokens int) error {
+	if _, err := db.conn.Exec(`INSERT INTO embedding_usage (crate_id, model, tokens) VALUES (?, ?, ?)`, crateID, model, tokens); err != nil {
+		return fmt.Errorf("recording usage for crate %d: %w", crateID, err)
+	}
+	return nil
+}
+
+// UsageByCrate returns the tokens billed for each crate, summed over every
+// add. Usage of deleted crates isn't included.
+func (db *DB) UsageByCrate() (map[int]int64, error) {
+	rows, err := db.reader.Query(`SELECT crate_id, SUM(tokens) FROM embedding_usage WHERE crate_id IS NOT NULL GROUP BY crate_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usage := make(map[int]int64)
+	for rows.Next() {
+		var crateID int
+		var tokens int64
+		if err := rows.Scan(&crateID, &tokens); err != nil {
+			return nil, err
+		}
+		usage[crateID] = tokens
+	}
+	return usage, rows.Err()
+}
+
+// UsageSince returns the tokens billed across every crate since t, deleted
+// ones included.
+func (db *DB) UsageSince(t time.Time) (int64, error) {
+	var tokens int64
+	err := db.reader.QueryRow(`SELECT COALESCE(SUM(tokens), 0) FROM embedding_usage WHERE recorded_at >= ?`,
+		t.UTC().Format(time.DateTime)).Scan(&tokens)
+	return tokens, err
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"maps"
+	"testing"
+	"time"
+)
+
+func TestUsageByCrate(t *testing.T) {
+	t.Parallel()
+	db := testDB(t)
+	ec2, _ := db.UpsertCrate("aws-sdk-ec2", "1.0.0")
+	serde, _ := db.UpsertCrate("serde", "1.0.0")
+	for _, u := range []struct {
+		crateID, tokens int
+	}{
+		{ec2.ID, 1_500_000},
+		{serde.ID, 20_000},
+		{ec2.ID, 300},
+	} {
+		if err := db.RecordUsage(u.crateID, "voyage-3.5", u.tokens); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	usage, err := db.UsageByCrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[int]int64{ec2.ID: 1_500_300, serde.ID: 20_000}; !maps.Equal(usage, want) {
+		t.Errorf("UsageByCrate = %v, want %v", usage, want)
+	}
+
+	if _, err := db.conn.Exec(`UPDATE embedding_usage SET recorded_at = '2020-01-31 23:59:59' WHERE tokens = 20000`); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		since time.Time
+		want  int64
+	}{
+		{time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), 1_520_300},
+		{time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC), 1_500_300},
+		{time.Now().Add(time.Hour), 0},
+	} {
+		if got, err := db.UsageSince(tc.since); err != nil || got != tc.want {
+			t.Errorf("UsageSince(%s) = %d, %v; want %d", tc.since, got, err, tc.want)
+		}
+	}
+}
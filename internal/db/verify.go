@@ -0,0 +1,184 @@
+package db
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// ContentHashes returns the distinct content hashes referenced by items,
+// fragments, examples and crate metadata. Each must be readable from the CAS.
+func (db *DB) ContentHashes() ([]string, error) {
+	return db.queryStrings(`
+		SELECT content_hash FROM items WHERE content_hash IS NOT NULL AND content_hash != ''
+		UNION
+		SELECT content_hash FROM fragments
+		UNION
+		SELECT content_hash FROM examples
+		UNION
+		SELECT content_hash FROM crate_meta WHERE content_hash != ''`)
+}
+
+// UnembeddedHashes returns item, fragment, example and crate metadata content
+// hashes with no embedding rows.
+func (db *DB) UnembeddedHashes() ([]string, error) {
+	return db.queryStrings(`
+		SELECT h FROM (
+			SELECT content_hash AS h FROM items WHERE content_hash IS NOT NULL AND content_hash != ''
+			UNION
+			SELECT content_hash FROM fragments
+			UNION
+			SELECT content_hash FROM examples
+			UNION
+			SELECT content_hash FROM crate_meta WHERE content_hash != ''
+		)
+		WHERE NOT EXISTS (SELECT 1 FROM embeddings e WHERE e.content_hash = h)`)
+}
+
+// CratesForContentHashes returns the crates with items, fragments, examples or metadata using any of the hashes.
+func (db *DB) CratesForContentHashes(hashes []string) ([]Crate, error) {
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+	placeholders := make([]string, len(hashes))
+	params := make([]interface{}, 0, 4*len(hashes))
+	for i, h := range hashes {
+		placeholders[i] = "?"
+		params = append(params, h)
+	}
+	for range 3 {
+		params = append(params, params[:len(hashes)]...)
+	}
+	in := strings.Join(placeholders, ",")
+	rows, err := db.conn.Query(fmt.Sprintf(`
+		SELECT `+crateColumns+` FROM crates
+		WHERE id IN (SELECT crate_id FROM items WHERE content_hash IN (%s))
+		   OR id IN (SELECT i.crate_id FROM fragments f JOIN items i ON i.id = f.item_id WHERE f.content_hash IN (%s))
+		   OR id IN (SELECT i.crate_id FROM examples ex JOIN items i ON i.id = ex.item_id WHERE ex.content_hash IN (%s))
+		   OR id IN (SELECT crate_id FROM crate_meta WHERE content_hash IN (%s))
+		ORDER BY name, id`, in, in, in, in), params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var crates []Crate
+	for rows.Next() {
+		c, err := scanCrate(rows)
+		if err != nil {
+			return nil, err
+		}
+		crates = append(crates, *c)
+	}
+	return crates, rows.Err()
+}
+
+// Ping checks that the database answers queries on both connection pools.
+func (db *DB) Ping() error {
+	var n int
+	if err := db.conn.QueryRow(`SELECT 1`).Scan(&n); err != nil {
+		return fmt.Errorf("database: %w", err)
+	}
+	if err := db.reader.QueryRow(`SELECT 1`).Scan(&n); err != nil {
+		return fmt.Errorf("read-only pool: %w", err)
+	}
+	return nil
+}
+
+// HNSWSize returns the number of vectors in the HNSW index. In disk mode
+// every stored embedding is searchable, so it is the number of embeddings.
+func (db *DB) HNSWSize() int {
+	if db.diskIndex() {
+		n, _ := db.CountEmbeddings()
+		return n
+	}
+	db.hnsw.Mu.RLock()
+	defer db.hnsw.Mu.RUnlock()
+	return len(db.hnsw.Nodes)
+}
+
+// CheckHNSW compares embedding rows against the HNSW index. missing are
+// embedding IDs absent from the index; orphaned are index IDs with no row.
+func (db *DB) CheckHNSW() (missing, orphaned []int, err error) {
+	if db.diskIndex() {
+		return nil, nil, nil
+	}
+	rows, err := db.conn.Query(`SELECT id FROM embeddings`)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	db.hnsw.Mu.RLock()
+	defer db.hnsw.Mu.RUnlock()
+
+	seen := make(map[int]bool, len(db.hnsw.Nodes))
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, nil, err
+		}
+		seen[id] = true
+		if _, ok := db.hnsw.Nodes[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	for id := range db.hnsw.Nodes {
+		if !seen[id] {
+			orphaned = append(orphaned, id)
+		}
+	}
+	return missing, orphaned, nil
+}
+
+// RepairHNSW adds missing embedding rows to the HNSW index, drops orphaned
+// nodes, and saves the index.
+func (db *DB) RepairHNSW(missing, orphaned []int) error {
+	for _, id := range orphaned {
+		if err := db.hnsw.Delete(id); err != nil {
+			slog.Warn("failed to delete orphaned HNSW node", "id", id, "error", err)
+		}
+	}
+	for _, id := range missing {
+		var blob []byte
+		var encoding string
+		if err := db.conn.QueryRow(`SELECT embedding, encoding FROM embeddings WHERE id = ?`, id).Scan(&blob, &encoding); err != nil {
+			return fmt.Errorf("reading embedding %d: %w", id, err)
+		}
+		vec, err := decodeEmbedding(blob, encoding)
+		if err != nil {
+			slog.Warn("skipping undecodable embedding", "id", id, "error", err)
+			continue
+		}
+		if len(vec) != db.dim {
+			slog.Warn("skipping embedding with wrong dimension", "id", id, "got", len(vec), "want", db.dim)
+			continue
+		}
+		if err := db.hnsw.Add(id, vec); err != nil {
+			return fmt.Errorf("adding embedding %d to HNSW index: %w", id, err)
+		}
+	}
+	db.saveHNSW()
+	return nil
+}
+
+func (db *DB) queryStrings(query string, args ...interface{}) ([]string, error) {
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
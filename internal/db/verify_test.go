@@ -0,0 +1,92 @@
+package db
+
+import "testing"
+
+func TestVerify(t *testing.T) {
+	db := testDB(t)
+
+	crate, err := db.UpsertCrate("mycrate", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, hash := range []string{"embedded", "unembedded"} {
+		item := &Item{CrateID: crate.ID, RustdocID: string(rune('a' + i)), Name: hash, Path: "mycrate::" + hash, Kind: "function", ContentHash: hash}
+		if err := db.InsertItem(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	emb := make([]float32, 1024)
+	for i := range emb {
+		emb[i] = 1.0
+	}
+	if err := db.InsertEmbedding("embedded", "text", 0, emb); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("content_hashes", func(t *testing.T) {
+		hashes, err := db.ContentHashes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hashes) != 2 {
+			t.Errorf("expected 2 hashes, got %v", hashes)
+		}
+	})
+
+	t.Run("unembedded", func(t *testing.T) {
+		hashes, err := db.UnembeddedHashes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hashes) != 1 || hashes[0] != "unembedded" {
+			t.Errorf("expected [unembedded], got %v", hashes)
+		}
+		crates, err := db.CratesForContentHashes(hashes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(crates) != 1 || crates[0].ID != crate.ID {
+			t.Errorf("expected mycrate, got %+v", crates)
+		}
+	})
+
+	t.Run("hnsw", func(t *testing.T) {
+		missing, orphaned, err := db.CheckHNSW()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(missing) != 0 || len(orphaned) != 0 {
+			t.Fatalf("expected consistent index, got missing=%v orphaned=%v", missing, orphaned)
+		}
+
+		var id int
+		if err := db.conn.QueryRow(`SELECT id FROM embeddings`).Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.hnsw.Delete(id); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.hnsw.Add(9999, append([]float32(nil), emb...)); err != nil {
+			t.Fatal(err)
+		}
+
+		missing, orphaned, err = db.CheckHNSW()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(missing) != 1 || missing[0] != id || len(orphaned) != 1 || orphaned[0] != 9999 {
+			t.Fatalf("got missing=%v orphaned=%v", missing, orphaned)
+		}
+
+		if err := db.RepairHNSW(missing, orphaned); err != nil {
+			t.Fatal(err)
+		}
+		missing, orphaned, err = db.CheckHNSW()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(missing) != 0 || len(orphaned) != 0 {
+			t.Errorf("expected repaired index, got missing=%v orphaned=%v", missing, orphaned)
+		}
+	})
+}
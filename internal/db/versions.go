@@ -0,0 +1,72 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// addEmbeddingVersions is migration 5. Embeddings stored before it have
+// no chunker version, so they count as stale; their model is the one the
+// index recorded.
+func addEmbeddingVersions(tx *sql.Tx) error {
+	for _, stmt := range []string{
+		`ALTER TABLE embeddings ADD COLUMN model TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE embeddings ADD COLUMN chunker_version TEXT NOT NULL DEFAULT ''`,
+		`UPDATE embeddings SET model = COALESCE((SELECT value FROM metadata WHERE key = '` + metaEmbeddingModel + `'), '')`,
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// staleEmbeddings matches embedding rows produced by another model or
+// chunker version than the two parameters.
+const staleEmbeddings = `SELECT content_hash FROM embeddings WHERE model != ? OR chunker_version != ?`
+
+// HasCurrentEmbeddings reports whether contentHash has embeddings and all
+// of them were produced by model and chunker version chunker.
+func (db *DB) HasCurrentEmbeddings(contentHash, model, chunker string) bool {
+	var total, current int
+	db.reader.QueryRow(`SELECT COUNT(*), COALESCE(SUM(model = ? AND chunker_version = ?), 0) FROM embeddings WHERE content_hash = ?`,
+		model, chunker, contentHash).Scan(&total, &current)
+	return total > 0 && current == total
+}
+
+// CountStaleEmbeddings counts the embedding rows produced by another model
+// or chunker version.
+func (db *DB) CountStaleEmbeddings(model, chunker string) (int, error) {
+	var n int
+	if err := db.reader.QueryRow(`SELECT COUNT(*) FROM (`+staleEmbeddings+`)`, model, chunker).Scan(&n); err != nil {
+		return 0, fmt.Errorf("counting stale embeddings: %w", err)
+	}
+	return n, nil
+}
+
+// StaleEmbeddingCrates returns the crates, in every namespace, using content
+// with embeddings from another model or chunker version.
+func (db *DB) StaleEmbeddingCrates(model, chunker string) ([]Crate, error) {
+	rows, err := db.conn.Query(`
+		WITH stale AS (`+staleEmbeddings+`)
+		SELECT `+crateColumns+` FROM crates
+		WHERE id IN (SELECT crate_id FROM items WHERE content_hash IN stale)
+		   OR id IN (SELECT i.crate_id FROM fragments f JOIN items i ON i.id = f.item_id WHERE f.content_hash IN stale)
+		   OR id IN (SELECT i.crate_id FROM examples ex JOIN items i ON i.id = ex.item_id WHERE ex.content_hash IN stale)
+		   OR id IN (SELECT crate_id FROM crate_meta WHERE content_hash IN stale)
+		ORDER BY name, id`, model, chunker)
+	if err != nil {
+		return nil, fmt.Errorf("finding crates with stale embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	var crates []Crate
+	for rows.Next() {
+		c, err := scanCrate(rows)
+		if err != nil {
+			return nil, err
+		}
+		crates = append(crates, *c)
+	}
+	return crates, rows.Err()
+}
@@ -0,0 +1,67 @@
+package db
+
+import "testing"
+
+func TestEmbeddingVersions(t *testing.T) {
+	t.Parallel()
+	db := testDB(t)
+	tokio, _ := db.UpsertCrate("tokio", "1.0.0")
+	serde, _ := db.UpsertCrate("serde", "1.0.0")
+	if err := db.ReplaceCrateItems(tokio.ID, []ItemRecord{
+		{Item: &Item{RustdocID: "0", Name: "spawn", Path: "tokio::spawn", Kind: "function", ContentHash: "old"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.ReplaceCrateItems(serde.ID, []ItemRecord{
+		{Item: &Item{RustdocID: "0", Name: "Serialize", Path: "serde::Serialize", Kind: "trait", ContentHash: "current"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.InsertEmbeddings([]EmbeddingRecord{
+		{ContentHash: "old", ChunkText: "a", ChunkIndex: 0, Embedding: testEmbedding(1024), Model: "voyage-3.5", ChunkerVersion: "0"},
+		{ContentHash: "old", ChunkText: "b", ChunkIndex: 1, Embedding: testEmbedding(1024), Model: "voyage-3.5", ChunkerVersion: "1"},
+		{ContentHash: "current", ChunkText: "c", ChunkIndex: 0, Embedding: testEmbedding(1024), Model: "voyage-3.5", ChunkerVersion: "1"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if db.HasCurrentEmbeddings("old", "voyage-3.5", "1") {
+		t.Error("content with one stale chunk counts as current")
+	}
+	if !db.HasCurrentEmbeddings("current", "voyage-3.5", "1") {
+		t.Error("current content counts as stale")
+	}
+	if db.HasCurrentEmbeddings("current", "voyage-3-lite", "1") {
+		t.Error("content from another model counts as current")
+	}
+	if db.HasCurrentEmbeddings("missing", "voyage-3.5", "1") {
+		t.Error("content without embeddings counts as current")
+	}
+	if n, err := db.CountStaleEmbeddings("voyage-3.5", "1"); err != nil || n != 1 {
+		t.Errorf("CountStaleEmbeddings = %d, %v; want 1", n, err)
+	}
+	crates, err := db.StaleEmbeddingCrates("voyage-3.5", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(crates) != 1 || crates[0].Name != "tokio" {
+		t.Errorf("StaleEmbeddingCrates = %+v, want tokio", crates)
+	}
+
+	// Embedding "old" afresh replaces its stale chunks, leaving other
+	// content alone.
+	if err := db.InsertEmbeddings([]EmbeddingRecord{
+		{ContentHash: "old", ChunkText: "a", ChunkIndex: 0, Embedding: testEmbedding(1024), Model: "voyage-3.5", ChunkerVersion: "1"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !db.HasCurrentEmbeddings("old", "voyage-3.5", "1") || !db.HasCurrentEmbeddings("current", "voyage-3.5", "1") {
+		t.Error("re-embedded content still counts as stale")
+	}
+	if n, err := db.CountStaleEmbeddings("voyage-3.5", "1"); err != nil || n != 0 {
+		t.Errorf("CountStaleEmbeddings after replacing = %d, %v; want 0", n, err)
+	}
+	if missing, orphaned, err := db.CheckHNSW(); err != nil || len(missing) > 0 || len(orphaned) > 0 {
+		t.Errorf("CheckHNSW after replacing = %v, %v, %v", missing, orphaned, err)
+	}
+}
@@ -0,0 +1,38 @@
+package db
+
+import "fmt"
+
+// warmQueries read every page of the tables and indexes a search touches,
+// so the first search after startup doesn't wait on the disk.
+var warmQueries = []string{
+	`SELECT COUNT(*), SUM(LENGTH(path)), SUM(LENGTH(content_hash)) FROM items`,
+	`SELECT COUNT(*) FROM items INDEXED BY idx_items_crate WHERE crate_id > 0`,
+	`SELECT COUNT(*) FROM items INDEXED BY idx_items_hash WHERE content_hash > ''`,
+	`SELECT COUNT(*), SUM(LENGTH(content_hash)) FROM fragments`,
+	`SELECT COUNT(*), SUM(LENGTH(content_hash)) FROM examples`,
+	`SELECT COUNT(*) FROM embeddings INDEXED BY idx_embeddings_hash WHERE content_hash > ''`,
+}
+
+// Warm reads the tables and indexes searches use into the page cache. In
+// disk mode, where searches scan the embeddings, it reads those too; in
+// memory mode the HNSW index was already loaded by New.
+func (db *DB) Warm() error {
+	queries := warmQueries
+	if db.diskIndex() {
+		queries = append(queries[:len(queries):len(queries)], `SELECT COUNT(*), SUM(LENGTH(embedding)) FROM embeddings`)
+	}
+	for _, q := range queries {
+		rows, err := db.reader.Query(q)
+		if err != nil {
+			return fmt.Errorf("warming database: %w", err)
+		}
+		for rows.Next() {
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return fmt.Errorf("warming database: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestWarm(t *testing.T) {
+	t.Parallel()
+	for _, storage := range []string{StorageMemory, StorageDisk} {
+		t.Run(storage, func(t *testing.T) {
+			t.Parallel()
+			db, err := NewWithOptions(filepath.Join(t.TempDir(), "test.db"), Options{Storage: storage})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			if err := db.InsertEmbeddings([]EmbeddingRecord{{ContentHash: "hash", ChunkText: "chunk", Embedding: testEmbedding(1024)}}); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.Warm(); err != nil {
+				t.Fatalf("Warm: %v", err)
+			}
+		})
+	}
+}
@@ -5,23 +5,28 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/jcdickinson/ferrisfetch/internal/config"
 	"github.com/klauspost/compress/zstd"
 )
 
-func crateCachePath(name, version string) string {
-	return filepath.Join(config.JSONCacheDir(), name+"_"+version+".json.zst")
+// crateCachePath is where a crate's rustdoc JSON is cached. Crates from an
+// alternative registry go in a directory of the registry's name, so they
+// don't overwrite the crates.io crate of the same name and version.
+func crateCachePath(registry, name, version string) string {
+	return filepath.Join(config.JSONCacheDir(), registry, name+"_"+version+".json.zst")
 }
 
-// SaveCrateCache compresses and saves rustdoc JSON bytes to disk.
-func SaveCrateCache(data []byte, name, version string) error {
-	dir := config.JSONCacheDir()
+// SaveCrateCache compresses and saves rustdoc JSON bytes to disk. registry
+// is "" for crates.io, as in the rest of this file.
+func SaveCrateCache(data []byte, registry, name, version string) error {
+	dir := filepath.Join(config.JSONCacheDir(), registry)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("creating json cache dir: %w", err)
 	}
 
-	f, err := os.Create(crateCachePath(name, version))
+	f, err := os.Create(crateCachePath(registry, name, version))
 	if err != nil {
 		return fmt.Errorf("creating cache file: %w", err)
 	}
@@ -43,8 +48,8 @@ func SaveCrateCache(data []byte, name, version string) error {
 }
 
 // LoadCrateCache loads and decompresses cached rustdoc JSON from disk.
-func LoadCrateCache(name, version string) (*RustdocCrate, error) {
-	f, err := os.Open(crateCachePath(name, version))
+func LoadCrateCache(registry, name, version string) (*RustdocCrate, error) {
+	f, err := os.Open(crateCachePath(registry, name, version))
 	if err != nil {
 		return nil, fmt.Errorf("opening cache file: %w", err)
 	}
@@ -60,11 +65,90 @@ func LoadCrateCache(name, version string) (*RustdocCrate, error) {
 	if err := json.NewDecoder(r).Decode(&crate); err != nil {
 		return nil, fmt.Errorf("decoding cached rustdoc JSON: %w", err)
 	}
+	crate.adaptFormat()
 	return &crate, nil
 }
 
+// ReadCrateCache returns the cached rustdoc JSON for a crate, still
+// zstd-compressed.
+func ReadCrateCache(registry, name, version string) ([]byte, error) {
+	data, err := os.ReadFile(crateCachePath(registry, name, version))
+	if err != nil {
+		return nil, fmt.Errorf("reading cache file: %w", err)
+	}
+	return data, nil
+}
+
 // HasCrateCache checks whether a cached rustdoc JSON file exists on disk.
-func HasCrateCache(name, version string) bool {
-	_, err := os.Stat(crateCachePath(name, version))
+func HasCrateCache(registry, name, version string) bool {
+	_, err := os.Stat(crateCachePath(registry, name, version))
 	return err == nil
 }
+
+// CachedCrate identifies a rustdoc JSON cache file.
+type CachedCrate struct {
+	Registry string
+	Name     string
+	Version  string
+}
+
+// ListCrateCaches returns every crate with a cached rustdoc JSON file.
+func ListCrateCaches() ([]CachedCrate, error) {
+	dir := config.JSONCacheDir()
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading json cache dir: %w", err)
+	}
+
+	out := cachedCrates("", entries)
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		regEntries, err := os.ReadDir(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("reading json cache dir: %w", err)
+		}
+		out = append(out, cachedCrates(e.Name(), regEntries)...)
+	}
+	return out, nil
+}
+
+// cachedCrates returns the cache files among one directory's entries.
+func cachedCrates(registry string, entries []os.DirEntry) []CachedCrate {
+	var out []CachedCrate
+	for _, e := range entries {
+		base, ok := strings.CutSuffix(e.Name(), ".json.zst")
+		if !ok || e.IsDir() {
+			continue
+		}
+		// Versions never contain underscores, so the last one separates name and version.
+		i := strings.LastIndex(base, "_")
+		if i <= 0 {
+			continue
+		}
+		out = append(out, CachedCrate{Registry: registry, Name: base[:i], Version: base[i+1:]})
+	}
+	return out
+}
+
+// CrateCacheSize returns the size of a cached rustdoc JSON file, or 0 if
+// there is none.
+func CrateCacheSize(registry, name, version string) int64 {
+	fi, err := os.Stat(crateCachePath(registry, name, version))
+	if err != nil {
+		return 0
+	}
+	return fi.Size()
+}
+
+// RemoveCrateCache deletes a cached rustdoc JSON file.
+func RemoveCrateCache(registry, name, version string) error {
+	if err := os.Remove(crateCachePath(registry, name, version)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing cache file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package docs
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// cfgRe finds where cfg predicates start inside an attribute.
+var cfgRe = regexp.MustCompile(`\bcfg\(`)
+
+// ExtractFeatures returns the cargo features an item is gated behind, taken from
+// `#[cfg(feature = ...)]` and `#[doc(cfg(feature = ...))]` attributes.
+// Features under not(...) are skipped, as the item is there without them.
+// Results are deduplicated and sorted.
+func ExtractFeatures(item *RustdocItem) []string {
+	seen := make(map[string]bool)
+	for _, attr := range attrStrings(item.Attrs) {
+		for _, loc := range cfgRe.FindAllStringIndex(attr, -1) {
+			p := &cfgParser{src: attr, pos: loc[1]}
+			p.predicate(false, seen)
+		}
+	}
+	if len(seen) == 0 {
+		return nil
+	}
+
+	features := make([]string, 0, len(seen))
+	for f := range seen {
+		features = append(features, f)
+	}
+	sort.Strings(features)
+	return features
+}
+
+// cfgParser reads a cfg predicate: `name`, `name = "value"`, or
+// `all(...)`, `any(...)` and `not(...)` of further predicates. It stops
+// quietly at anything else, keeping what it has read.
+type cfgParser struct {
+	src string
+	pos int
+}
+
+// predicate reads one predicate, adding the features it names to seen
+// unless negated.
+func (p *cfgParser) predicate(negated bool, seen map[string]bool) bool {
+	name := p.ident()
+	if name == "" {
+		return false
+	}
+	switch {
+	case p.consume('='):
+		value, ok := p.str()
+		if !ok {
+			return false
+		}
+		if name == "feature" && !negated {
+			seen[value] = true
+		}
+		return true
+	case p.consume('('):
+		if name == "not" {
+			negated = !negated
+		}
+		for !p.consume(')') {
+			if !p.predicate(negated, seen) {
+				return false
+			}
+			if !p.consume(',') && p.peek() != ')' {
+				return false
+			}
+		}
+		return true
+	}
+	return true
+}
+
+func (p *cfgParser) skipSpace() {
+	for p.pos < len(p.src) && strings.ContainsRune(" \t\r\n", rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *cfgParser) peek() byte {
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+func (p *cfgParser) consume(c byte) bool {
+	if p.peek() != c {
+		return false
+	}
+	p.pos++
+	return true
+}
+
+func (p *cfgParser) ident() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			break
+		}
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// str reads a double-quoted string. Feature names have no escapes, so
+// none are decoded.
+func (p *cfgParser) str() (string, bool) {
+	if !p.consume('"') {
+		return "", false
+	}
+	end := strings.IndexByte(p.src[p.pos:], '"')
+	if end < 0 {
+		return "", false
+	}
+	s := p.src[p.pos : p.pos+end]
+	p.pos += end + 1
+	return s, true
+}
+
+// attrStrings flattens rustdoc attributes to their source text.
+// Older format versions encode attrs as plain strings; newer ones use tagged
+// objects like {"other": "#[doc(cfg(...))]"}, so any string value is taken.
+func attrStrings(attrs []json.RawMessage) []string {
+	var out []string
+	for _, raw := range attrs {
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			out = append(out, s)
+			continue
+		}
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			continue
+		}
+		for _, v := range obj {
+			if err := json.Unmarshal(v, &s); err == nil {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
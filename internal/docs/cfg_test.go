@@ -0,0 +1,40 @@
+package docs
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestExtractFeatures(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		attrs string
+		want  []string
+	}{
+		{"none", `[]`, nil},
+		{"string_attr", `["#[doc(cfg(feature = \"full\"))]"]`, []string{"full"}},
+		{"tagged_attr", `[{"other": "#[doc(cfg(feature = \"rt\"))]"}]`, []string{"rt"}},
+		{"all_any", `["#[cfg(all(feature = \"rt\", any(feature = \"net\", feature = \"rt\")))]"]`, []string{"net", "rt"}},
+		{"not", `["#[cfg(not(feature = \"std\"))]"]`, nil},
+		{"any_not", `["#[doc(cfg(any(feature = \"alloc\", not(feature = \"std\"))))]"]`, []string{"alloc"}},
+		{"double_not", `["#[cfg(all(unix, not(not(feature = \"rt\"))))]"]`, []string{"rt"}},
+		{"cfg_attr_ignored", `["#[cfg_attr(feature = \"serde\", derive(Serialize))]"]`, nil},
+		{"non_cfg_ignored", `["#[doc(alias = \"feature = \\\"x\\\"\")]", "#[must_use]"]`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attrs []json.RawMessage
+			if err := json.Unmarshal([]byte(tt.attrs), &attrs); err != nil {
+				t.Fatal(err)
+			}
+			got := ExtractFeatures(&RustdocItem{Attrs: attrs})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
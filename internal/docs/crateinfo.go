@@ -0,0 +1,100 @@
+package docs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// CrateInfo is a crate's registry-wide metadata.
+type CrateInfo struct {
+	Downloads       int
+	RecentDownloads int
+	// LatestVersion is the newest stable version, or the newest of any
+	// kind when the crate has no stable release.
+	LatestVersion string
+
+	Description string
+	Keywords    []string
+	// Categories are category slugs, e.g. "command-line-interface".
+	Categories []string
+	Repository string
+}
+
+// FetchCrateInfo reads a crate's download counts, newest version and
+// description from the registry's crates.io-compatible API.
+func FetchCrateInfo(ctx context.Context, reg *Registry, name string) (*CrateInfo, error) {
+	req, err := reg.newRequest(ctx, fmt.Sprintf("%s/api/v1/crates/%s",
+		strings.TrimSuffix(reg.IndexURL, "/"), url.PathEscape(name)))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching crate info for %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("%s returned %d for %s: %s", req.URL.Host, resp.StatusCode, name, string(body))
+	}
+
+	var payload struct {
+		Crate struct {
+			Downloads        int      `json:"downloads"`
+			RecentDownloads  int      `json:"recent_downloads"`
+			MaxStableVersion string   `json:"max_stable_version"`
+			MaxVersion       string   `json:"max_version"`
+			Description      string   `json:"description"`
+			Keywords         []string `json:"keywords"`
+			Categories       []string `json:"categories"`
+			Repository       string   `json:"repository"`
+		} `json:"crate"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("decoding crate info: %w", err)
+	}
+	c := payload.Crate
+	info := &CrateInfo{
+		Downloads:       c.Downloads,
+		RecentDownloads: c.RecentDownloads,
+		LatestVersion:   c.MaxStableVersion,
+		Description:     strings.TrimSpace(c.Description),
+		Keywords:        c.Keywords,
+		Categories:      c.Categories,
+		Repository:      c.Repository,
+	}
+	if info.LatestVersion == "" {
+		info.LatestVersion = c.MaxVersion
+	}
+	return info, nil
+}
+
+// Summary renders the crate's description, keywords and categories as the
+// markdown embedded for crate-level search, or "" when the registry has none
+// of them.
+func (c *CrateInfo) Summary() string {
+	if c.Description == "" && len(c.Keywords) == 0 && len(c.Categories) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	if c.Description != "" {
+		b.WriteString(c.Description + "\n\n")
+	}
+	if len(c.Keywords) > 0 {
+		fmt.Fprintf(&b, "Keywords: %s\n", strings.Join(c.Keywords, ", "))
+	}
+	if len(c.Categories) > 0 {
+		fmt.Fprintf(&b, "Categories: %s\n", strings.Join(c.Categories, ", "))
+	}
+	if c.Repository != "" {
+		fmt.Fprintf(&b, "Repository: %s\n", c.Repository)
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
@@ -0,0 +1,20 @@
+package docs
+
+import "testing"
+
+func TestCrateInfoSummary(t *testing.T) {
+	info := CrateInfo{
+		Description: "A simple to use, efficient, and full-featured Command Line Argument Parser",
+		Keywords:    []string{"argument", "cli"},
+		Categories:  []string{"command-line-interface"},
+		Repository:  "https://github.com/clap-rs/clap",
+	}
+	want := "A simple to use, efficient, and full-featured Command Line Argument Parser\n\n" +
+		"Keywords: argument, cli\nCategories: command-line-interface\nRepository: https://github.com/clap-rs/clap\n"
+	if got := info.Summary(); got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+	if got := (&CrateInfo{Repository: "https://example.com"}).Summary(); got != "" {
+		t.Errorf("Summary() without a description = %q, want empty", got)
+	}
+}
@@ -0,0 +1,72 @@
+package docs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// CrateDependency is a dependency as listed by the registry API.
+type CrateDependency struct {
+	Name     string
+	Req      string
+	Kind     string // "normal", "build" or "dev"
+	Optional bool
+}
+
+// FetchDependencies lists a crate version's dependencies from the registry's
+// crates.io-compatible API.
+func FetchDependencies(ctx context.Context, reg *Registry, name, version string) ([]CrateDependency, error) {
+	req, err := reg.newRequest(ctx, fmt.Sprintf("%s/api/v1/crates/%s/%s/dependencies",
+		strings.TrimSuffix(reg.IndexURL, "/"), url.PathEscape(name), url.PathEscape(version)))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching dependencies of %s@%s: %w", name, version, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("%s returned %d for %s/%s dependencies: %s", req.URL.Host, resp.StatusCode, name, version, string(body))
+	}
+
+	var payload struct {
+		Dependencies []struct {
+			CrateID  string `json:"crate_id"`
+			Req      string `json:"req"`
+			Kind     string `json:"kind"`
+			Optional bool   `json:"optional"`
+		} `json:"dependencies"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("decoding dependencies: %w", err)
+	}
+
+	deps := make([]CrateDependency, len(payload.Dependencies))
+	for i, d := range payload.Dependencies {
+		deps[i] = CrateDependency{Name: d.CrateID, Req: d.Req, Kind: d.Kind, Optional: d.Optional}
+	}
+	return deps, nil
+}
+
+// ExternalCrateVersions maps the registry names of external crates to the
+// version docs.rs built against, read from their html_root_url. Crates
+// without a docs.rs root (std, core, alloc) are skipped.
+func (c *RustdocCrate) ExternalCrateVersions() map[string]string {
+	versions := make(map[string]string)
+	for _, ext := range c.ExternalCrates {
+		name, version := extractDocsRsCrateName(ext.HTMLRootURL), docsRsRootVersion(ext.HTMLRootURL)
+		if name != "" && version != "" {
+			versions[name] = version
+		}
+	}
+	return versions
+}
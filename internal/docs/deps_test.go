@@ -0,0 +1,56 @@
+package docs
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExternalCrateVersions(t *testing.T) {
+	t.Parallel()
+	crate := &RustdocCrate{
+		ExternalCrates: map[string]ExternalCrate{
+			"1": {Name: "tracing_core", HTMLRootURL: "https://docs.rs/tracing-core/0.1.36/x86_64-unknown-linux-gnu/"},
+			"2": {Name: "serde", HTMLRootURL: "https://docs.rs/serde/latest/"},
+			"3": {Name: "std", HTMLRootURL: "https://doc.rust-lang.org/nightly/"},
+		},
+	}
+	got := crate.ExternalCrateVersions()
+	if len(got) != 1 || got["tracing-core"] != "0.1.36" {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestFetchDependencies(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/crates/app/1.0.0/dependencies" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"dependencies":[
+			{"crate_id":"serde","req":"^1.0","kind":"normal","optional":false},
+			{"crate_id":"tempfile","req":"^3","kind":"dev","optional":false},
+			{"crate_id":"log","req":"^0.4","kind":"normal","optional":true}]}`))
+	}))
+	defer srv.Close()
+
+	deps, err := FetchDependencies(context.Background(), &Registry{IndexURL: srv.URL}, "app", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []CrateDependency{
+		{Name: "serde", Req: "^1.0", Kind: "normal"},
+		{Name: "tempfile", Req: "^3", Kind: "dev"},
+		{Name: "log", Req: "^0.4", Kind: "normal", Optional: true},
+	}
+	if len(deps) != len(want) {
+		t.Fatalf("got %+v", deps)
+	}
+	for i := range want {
+		if deps[i] != want[i] {
+			t.Errorf("dep %d = %+v, want %+v", i, deps[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Package docstest replays docs.rs and crates.io responses recorded under
+// its testdata directory, so tests of code that fetches documentation run
+// without the network. Every suite shares the one set of recordings.
+//
+// Fixtures are recorded by running the tests with RSDOC_RECORD=1, which
+// sends the requests for real and saves each response, zstd-compressed, in
+// place of the old one.
+package docstest
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/jcdickinson/ferrisfetch/internal/docs"
+	"github.com/jcdickinson/ferrisfetch/internal/vcr"
+)
+
+// RecordEnv is the environment variable that switches fixtures from being
+// replayed to being recorded.
+const RecordEnv = "RSDOC_RECORD"
+
+// Mode returns vcr.ModeRecord when RSDOC_RECORD is set, else
+// vcr.ModeReplay.
+func Mode() vcr.Mode {
+	if os.Getenv(RecordEnv) != "" {
+		return vcr.ModeRecord
+	}
+	return vcr.ModeReplay
+}
+
+// Dir returns the directory holding the docs.rs and crates.io fixtures.
+func Dir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "testdata", "vcr")
+}
+
+// Transport returns a transport that replays docs.rs and crates.io from
+// Dir and other hosts, such as the embedding provider, from the fixtures in
+// dir, or records them through next (http.DefaultTransport if nil).
+func Transport(dir string, next http.RoundTripper) http.RoundTripper {
+	return router{
+		docs:  vcr.NewCompressed(Mode(), Dir(), next),
+		other: vcr.NewCompressed(Mode(), dir, next),
+	}
+}
+
+// router sends requests for documentation to docs and the rest to other.
+type router struct {
+	docs, other http.RoundTripper
+}
+
+func (r router) RoundTrip(req *http.Request) (*http.Response, error) {
+	if isDocsHost(req.URL.Hostname()) {
+		return r.docs.RoundTrip(req)
+	}
+	return r.other.RoundTrip(req)
+}
+
+func isDocsHost(host string) bool {
+	for _, h := range []string{"docs.rs", "crates.io"} {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// Use sends package docs' requests through the fixtures in Dir for the
+// rest of the test, unpaced when replaying. The transport is global, so
+// tests that call Use can't run in parallel.
+func Use(t testing.TB) {
+	t.Helper()
+	docs.SetHTTPTransport(vcr.NewCompressed(Mode(), Dir(), nil))
+	if Mode() == vcr.ModeReplay {
+		docs.SetCrawlLimits(0, 0)
+	}
+	t.Cleanup(func() {
+		docs.SetHTTPTransport(nil)
+		docs.SetCrawlLimits(1, 4)
+	})
+}
@@ -0,0 +1,223 @@
+package docs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Downloads of rustdoc JSON are kept as served (usually zstd-compressed) in
+// config.DownloadsDir. Each URL has a small record of the validators the
+// server sent, so the next fetch of the same URL is a conditional request
+// that costs nothing if the docs haven't changed, and an interrupted
+// download picks up where it stopped. Once the rustdoc JSON cache holds a
+// download, ReleaseDownload drops the body and keeps the record, so the
+// docs aren't stored twice.
+
+// downloadResumes is how many times one fetch resumes a download that broke
+// off, before giving up until the next fetch.
+const downloadResumes = 3
+
+// download records a URL's last response.
+type download struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	// Blob names the file holding the body, keyed by ETag when there is
+	// one, so URLs serving the same docs ("latest" and its version) share
+	// it.
+	Blob string `json:"blob"`
+	// Size is the full body size, or -1 if the server didn't say.
+	Size     int64 `json:"size"`
+	Complete bool  `json:"complete"`
+	// Released is the version the body is cached as in the rustdoc JSON
+	// cache, once ReleaseDownload dropped the blob.
+	Released string `json:"released,omitempty"`
+}
+
+func hashKey(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:16])
+}
+
+func recordPath(dir, url string) string {
+	return filepath.Join(dir, hashKey(url)+".json")
+}
+
+func loadDownload(dir, url string) *download {
+	data, err := os.ReadFile(recordPath(dir, url))
+	if err != nil {
+		return nil
+	}
+	var d download
+	if json.Unmarshal(data, &d) != nil || d.URL != url || d.Blob == "" {
+		return nil
+	}
+	return &d
+}
+
+func (d *download) save(dir string) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(recordPath(dir, d.URL), data, 0644)
+}
+
+// validator returns the If-Range value that lets d resume: its ETag if
+// strong, else its Last-Modified date. It is empty when d can't resume,
+// including when its size is unknown, as for bodies the transport
+// decompressed.
+func (d *download) validator() string {
+	switch {
+	case d.Size <= 0:
+		return ""
+	case d.ETag != "" && !strings.HasPrefix(d.ETag, "W/"):
+		return d.ETag
+	}
+	return d.LastModified
+}
+
+// fetchCached GETs url through the download cache in dir. what names the
+// download in errors. cached, if not nil, reads the body of a released
+// download back from the rustdoc JSON cache when the server says it hasn't
+// changed.
+func fetchCached(ctx context.Context, reg *Registry, url, dir, what string, cached func(version string) ([]byte, error)) ([]byte, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating downloads dir: %w", err)
+	}
+	d := loadDownload(dir, url)
+	for attempt := 0; ; attempt++ {
+		req, err := reg.newRequest(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+		var have int64
+		switch {
+		case d == nil:
+		case d.Complete:
+			if d.ETag != "" {
+				req.Header.Set("If-None-Match", d.ETag)
+			}
+			if d.LastModified != "" {
+				req.Header.Set("If-Modified-Since", d.LastModified)
+			}
+		case d.validator() != "":
+			if fi, err := os.Stat(filepath.Join(dir, d.Blob)); err == nil && fi.Size() > 0 {
+				have = fi.Size()
+				req.Header.Set("Range", fmt.Sprintf("bytes=%d-", have))
+				req.Header.Set("If-Range", d.validator())
+			}
+		}
+
+		resp, err := do(req)
+		if err != nil {
+			return nil, fmt.Errorf("fetching %s: %w", url, err)
+		}
+
+		var f *os.File
+		switch {
+		case resp.StatusCode == http.StatusNotModified && d != nil && d.Complete:
+			resp.Body.Close()
+			data, err := os.ReadFile(filepath.Join(dir, d.Blob))
+			if err == nil {
+				return data, nil
+			}
+			if d.Released != "" && cached != nil {
+				if data, cacheErr := cached(d.Released); cacheErr == nil {
+					return data, nil
+				}
+			}
+			// The body went missing; fetch it again unconditionally.
+			slog.Warn("cached download missing, fetching again", "url", url, "error", err)
+			os.Remove(recordPath(dir, url))
+			if d = nil; attempt < downloadResumes {
+				continue
+			}
+			return nil, fmt.Errorf("reading cached download: %w", err)
+		case resp.StatusCode == http.StatusPartialContent && have > 0 && strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", have)):
+			f, err = os.OpenFile(filepath.Join(dir, d.Blob), os.O_WRONLY|os.O_APPEND, 0644)
+		case resp.StatusCode == http.StatusOK:
+			if d != nil && d.Blob != blobName(resp, url) {
+				os.Remove(filepath.Join(dir, d.Blob))
+			}
+			d = &download{URL: url, ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified"), Blob: blobName(resp, url), Size: resp.ContentLength}
+			if resp.Uncompressed {
+				d.Size = -1
+			}
+			if err = d.save(dir); err == nil {
+				f, err = os.Create(filepath.Join(dir, d.Blob))
+			}
+		case resp.StatusCode == http.StatusNotFound:
+			resp.Body.Close()
+			return nil, fmt.Errorf("%s returned %d for %s: %w", req.URL.Host, resp.StatusCode, what, ErrNotFound)
+		default:
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			resp.Body.Close()
+			return nil, fmt.Errorf("%s returned %d for %s: %s", req.URL.Host, resp.StatusCode, what, string(body))
+		}
+		if err != nil {
+			resp.Body.Close()
+			return nil, fmt.Errorf("caching download: %w", err)
+		}
+
+		_, err = io.Copy(f, resp.Body)
+		resp.Body.Close()
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			if ctx.Err() == nil && attempt < downloadResumes && d.validator() != "" {
+				slog.Info("download interrupted, resuming", "url", url, "error", err)
+				continue
+			}
+			return nil, fmt.Errorf("reading %s: %w", what, err)
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, d.Blob))
+		if err != nil {
+			return nil, fmt.Errorf("reading cached download: %w", err)
+		}
+		if d.Size >= 0 && int64(len(data)) != d.Size {
+			// Don't resume from a body that doesn't add up.
+			os.Remove(recordPath(dir, url))
+			return nil, fmt.Errorf("reading %s: got %d of %d bytes", what, len(data), d.Size)
+		}
+		d.Complete = true
+		if err := d.save(dir); err != nil {
+			slog.Warn("failed to record download", "url", url, "error", err)
+		}
+		return data, nil
+	}
+}
+
+// blobName names the file for a response body: by ETag when the server
+// sent one, else by URL.
+func blobName(resp *http.Response, url string) string {
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		return hashKey("etag:"+etag) + ".raw"
+	}
+	return hashKey("url:"+url) + ".raw"
+}
+
+// releaseDownload drops the body of url's complete download in dir, which
+// the rustdoc JSON cache now holds as version.
+func releaseDownload(dir, url, version string) error {
+	d := loadDownload(dir, url)
+	if d == nil || !d.Complete || d.Released != "" {
+		return nil
+	}
+	if err := os.Remove(filepath.Join(dir, d.Blob)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	d.Released = version
+	return d.save(dir)
+}
@@ -0,0 +1,130 @@
+package docs
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// FetchRustdocJSON caches downloads; keep them out of the real cache.
+	dir, err := os.MkdirTemp("", "ferrisfetch-docs-test")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("XDG_CACHE_HOME", dir)
+	// Test servers are local; don't pace requests to them.
+	SetCrawlLimits(0, 0)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestFetchCached_Conditional(t *testing.T) {
+	t.Parallel()
+	body := []byte(`{"root":0}`)
+	var full, notModified atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+		} else {
+			full.Add(1)
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	for range 2 {
+		data, err := fetchCached(context.Background(), DefaultRegistry, srv.URL+"/x", dir, "x", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, body) {
+			t.Fatalf("got %q", data)
+		}
+	}
+	if full.Load() != 1 || notModified.Load() != 1 {
+		t.Errorf("served %d full bodies and %d not-modified, want 1 and 1", full.Load(), notModified.Load())
+	}
+}
+
+func TestFetchCached_Resume(t *testing.T) {
+	t.Parallel()
+	body := bytes.Repeat([]byte("rustdoc "), 1000)
+	var ranges atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("Range") == "" {
+			// Break off halfway through the body.
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			w.Write(body[:len(body)/2])
+			return
+		}
+		ranges.Add(1)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+	}))
+	defer srv.Close()
+
+	data, err := fetchCached(context.Background(), DefaultRegistry, srv.URL+"/x", t.TempDir(), "x", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, body) {
+		t.Errorf("got %d bytes, want %d", len(data), len(body))
+	}
+	if ranges.Load() != 1 {
+		t.Errorf("%d range requests, want 1", ranges.Load())
+	}
+}
+
+func TestFetchCached_Released(t *testing.T) {
+	t.Parallel()
+	body := []byte(`{"root":0}`)
+	var full, notModified atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+		} else {
+			full.Add(1)
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+	}))
+	defer srv.Close()
+
+	dir, url := t.TempDir(), srv.URL+"/x"
+	if _, err := fetchCached(context.Background(), DefaultRegistry, url, dir, "x", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := releaseDownload(dir, url, "1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, loadDownload(dir, url).Blob)); !os.IsNotExist(err) {
+		t.Errorf("released body still on disk: %v", err)
+	}
+
+	// Not modified: the body comes from the JSON cache.
+	var asked string
+	data, err := fetchCached(context.Background(), DefaultRegistry, url, dir, "x", func(version string) ([]byte, error) {
+		asked = version
+		return body, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, body) || asked != "1.0.0" {
+		t.Errorf("got %q from the cache of version %q", data, asked)
+	}
+	if full.Load() != 1 || notModified.Load() != 1 {
+		t.Errorf("served %d full bodies and %d not-modified, want 1 and 1", full.Load(), notModified.Load())
+	}
+}
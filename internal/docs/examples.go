@@ -0,0 +1,96 @@
+package docs
+
+import (
+	"strings"
+)
+
+// rustFenceTags are rustdoc code block attributes that still denote Rust code.
+var rustFenceTags = map[string]bool{
+	"rust":             true,
+	"no_run":           true,
+	"should_panic":     true,
+	"ignore":           true,
+	"compile_fail":     true,
+	"edition2015":      true,
+	"edition2018":      true,
+	"edition2021":      true,
+	"edition2024":      true,
+	"test_harness":     true,
+	"standalone":       true,
+	"allow_fail":       true,
+	"unstable":         true,
+	"standalone_crate": true,
+}
+
+// ExtractRustCodeBlocks returns the Rust fenced code blocks in a doc comment.
+// Untagged fences are Rust by rustdoc convention; fences tagged with another
+// language (text, toml, sh, ...) are skipped. Lines rustdoc hides from
+// rendered output (`# use foo;`) are stripped.
+func ExtractRustCodeBlocks(docs string) []string {
+	var blocks []string
+	var cur []string
+	var fence string
+	inBlock, isRust := false, false
+
+	for _, line := range strings.Split(docs, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if !inBlock {
+			marker := fenceMarker(trimmed)
+			if marker == "" {
+				continue
+			}
+			inBlock, fence = true, marker
+			isRust = isRustFence(strings.TrimSpace(trimmed[len(marker):]))
+			cur = cur[:0]
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, fence) && strings.TrimLeft(trimmed, fence[:1]) == "" {
+			inBlock = false
+			if isRust {
+				if code := strings.TrimSpace(strings.Join(cur, "\n")); code != "" {
+					blocks = append(blocks, code)
+				}
+			}
+			continue
+		}
+
+		if isRust && isHiddenLine(trimmed) {
+			continue
+		}
+		cur = append(cur, line)
+	}
+	return blocks
+}
+
+// fenceMarker returns the opening fence (``` or ~~~, possibly longer) of a line, or "".
+func fenceMarker(line string) string {
+	for _, ch := range []byte{'`', '~'} {
+		n := 0
+		for n < len(line) && line[n] == ch {
+			n++
+		}
+		if n >= 3 {
+			return line[:n]
+		}
+	}
+	return ""
+}
+
+// isRustFence reports whether a fence info string (e.g. "rust,no_run") denotes Rust.
+func isRustFence(info string) bool {
+	if info == "" {
+		return true
+	}
+	for _, tag := range strings.FieldsFunc(info, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if !rustFenceTags[tag] {
+			return false
+		}
+	}
+	return true
+}
+
+// isHiddenLine reports whether rustdoc hides a code line (`# foo` or a bare `#`).
+func isHiddenLine(trimmed string) bool {
+	return trimmed == "#" || strings.HasPrefix(trimmed, "# ")
+}
@@ -1,53 +1,76 @@
 package docs
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"os"
 	"time"
 
-	"github.com/klauspost/compress/zstd"
+	"github.com/jcdickinson/ferrisfetch/internal/config"
 )
 
 var httpClient = &http.Client{Timeout: 60 * time.Second}
 
-// FetchRustdocJSON downloads and decompresses rustdoc JSON from docs.rs.
-// The version "latest" is resolved by docs.rs via redirect.
-func FetchRustdocJSON(name, version string) ([]byte, error) {
+// ErrNotFound is wrapped by errors for registry or docs host responses of
+// 404 Not Found.
+var ErrNotFound = errors.New("not found")
+
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// cacheDownloads is cleared when a transport is set: recorded fixtures must
+// hold full responses, not 304s that depend on what was cached locally.
+var cacheDownloads = true
+
+// SetHTTPTransport replaces the transport used for docs.rs and crates.io
+// requests. Downloads then bypass the download cache.
+func SetHTTPTransport(rt http.RoundTripper) {
+	httpClient.Transport = rt
+	cacheDownloads = false
+}
+
+// FetchRustdocJSON downloads and decompresses rustdoc JSON from the
+// registry's docs host. The version "latest" is resolved by docs.rs via
+// redirect. Downloads are cached as served: a repeated fetch is a
+// conditional request, and an interrupted one resumes.
+func FetchRustdocJSON(ctx context.Context, reg *Registry, name, version string) ([]byte, error) {
 	if version == "" {
 		version = "latest"
 	}
 
-	url := fmt.Sprintf("https://docs.rs/crate/%s/%s/json", name, version)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+	dir := config.DownloadsDir()
+	if !cacheDownloads {
+		tmp, err := os.MkdirTemp("", "ferrisfetch-download-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(tmp)
+		dir = tmp
 	}
-	req.Header.Set("User-Agent", "ferrisfetch/0.1.0")
 
-	resp, err := httpClient.Do(req)
+	// docs.rs returns zstd-compressed JSON; self-hosted docs may serve it plain.
+	cached := func(version string) ([]byte, error) { return ReadCrateCache(reg.Name, name, version) }
+	data, err := fetchCached(ctx, reg, reg.rustdocJSONURL(name, version), dir, name+"/"+version, cached)
 	if err != nil {
-		return nil, fmt.Errorf("fetching %s: %w", url, err)
+		return nil, err
 	}
-	defer resp.Body.Close()
+	return DecodeRustdocJSON(data)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
-		return nil, fmt.Errorf("docs.rs returned %d for %s/%s: %s", resp.StatusCode, name, version, string(body))
+// ReleaseDownload drops the downloaded body of name@requested, fetched as
+// FetchRustdocJSON(reg, name, requested), once it is saved to the rustdoc
+// JSON cache as version. The download's validators stay, so fetching it
+// again is still a conditional request.
+func ReleaseDownload(reg *Registry, name, requested, version string) error {
+	if !cacheDownloads {
+		return nil
 	}
-
-	// docs.rs returns zstd-compressed JSON
-	decoder, err := zstd.NewReader(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("creating zstd decoder: %w", err)
+	if requested == "" {
+		requested = "latest"
 	}
-	defer decoder.Close()
-
-	data, err := io.ReadAll(decoder)
-	if err != nil {
-		return nil, fmt.Errorf("decompressing rustdoc JSON: %w", err)
+	if err := releaseDownload(config.DownloadsDir(), reg.rustdocJSONURL(name, requested), version); err != nil {
+		return fmt.Errorf("releasing download of %s@%s: %w", name, requested, err)
 	}
-
-	return data, nil
+	return nil
 }
@@ -0,0 +1,153 @@
+package docs
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// formatChange is a change to the rustdoc JSON format that an adapter can
+// undo, bringing items from older formats into the shape of the newest so
+// the rest of the package reads one shape.
+type formatChange struct {
+	// Since is the first format version with the new shape.
+	Since   int
+	Summary string
+	adapt   func(item *RustdocItem)
+}
+
+// formatChanges lists the changes adapted, oldest first. Each adapter
+// checks the item's shape as well, so JSON whose format_version is off by
+// a release, or a hand-built index, is left as it is when it's already new.
+var formatChanges = []formatChange{
+	{36, "function decl renamed to sig and header flags to is_const, is_unsafe and is_async", adaptFnSig},
+	{38, "associated constant default renamed to value and associated type default to type", adaptAssocDefaults},
+	{54, "attributes became tagged objects instead of source strings", adaptAttrs},
+}
+
+// adaptFormat rewrites items from an older format version into the newest
+// shape. Crates that don't declare a format version are left alone.
+func (c *RustdocCrate) adaptFormat() {
+	if c.FormatVersion == 0 {
+		return
+	}
+	for _, change := range formatChanges {
+		if c.FormatVersion >= change.Since {
+			continue
+		}
+		for id, item := range c.Index {
+			change.adapt(&item)
+			c.Index[id] = item
+		}
+	}
+}
+
+// adaptFnSig renames a function's decl to sig, with its c_variadic flag,
+// and the const, unsafe and async header flags to their is_ forms.
+func adaptFnSig(item *RustdocItem) {
+	editInner(item, "function", func(fn map[string]json.RawMessage) bool {
+		changed := renameKey(fn, "decl", "sig")
+		if sig, ok := fn["sig"]; ok {
+			if edited, ok := editObject(sig, func(m map[string]json.RawMessage) bool {
+				return renameKey(m, "c_variadic", "is_c_variadic")
+			}); ok {
+				fn["sig"], changed = edited, true
+			}
+		}
+		if header, ok := fn["header"]; ok {
+			if edited, ok := editObject(header, func(m map[string]json.RawMessage) bool {
+				renamed := false
+				for _, flag := range []string{"const", "unsafe", "async"} {
+					renamed = renameKey(m, flag, "is_"+flag) || renamed
+				}
+				return renamed
+			}); ok {
+				fn["header"], changed = edited, true
+			}
+		}
+		return changed
+	})
+}
+
+// adaptAssocDefaults renames the defaults of a trait's associated constants
+// and types.
+func adaptAssocDefaults(item *RustdocItem) {
+	editInner(item, "assoc_const", func(c map[string]json.RawMessage) bool {
+		return renameKey(c, "default", "value")
+	})
+	editInner(item, "assoc_type", func(t map[string]json.RawMessage) bool {
+		return renameKey(t, "default", "type")
+	})
+}
+
+// adaptAttrs turns attributes written as source strings into the tagged
+// form: "#[automatically_derived]" and "#[non_exhaustive]" become bare
+// tags and anything else {"other": "#[...]"}.
+func adaptAttrs(item *RustdocItem) {
+	for i, raw := range item.Attrs {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			continue
+		}
+		var attr any
+		switch strings.TrimSpace(s) {
+		case "#[automatically_derived]":
+			attr = "automatically_derived"
+		case "#[non_exhaustive]":
+			attr = "non_exhaustive"
+		default:
+			attr = map[string]string{"other": s}
+		}
+		if data, err := json.Marshal(attr); err == nil {
+			item.Attrs[i] = data
+		}
+	}
+}
+
+// editInner calls edit on the fields of an item's inner data when it's of
+// the given kind, and stores them back if edit reports a change.
+func editInner(item *RustdocItem, kind string, edit func(map[string]json.RawMessage) bool) {
+	var inner map[string]json.RawMessage
+	if err := json.Unmarshal(item.Inner, &inner); err != nil {
+		return
+	}
+	data, ok := inner[kind]
+	if !ok {
+		return
+	}
+	edited, ok := editObject(data, edit)
+	if !ok {
+		return
+	}
+	inner[kind] = edited
+	if out, err := json.Marshal(inner); err == nil {
+		item.Inner = out
+	}
+}
+
+// editObject calls edit on the fields of a JSON object and returns the
+// object re-encoded, or false if it isn't an object or edit changed nothing.
+func editObject(data json.RawMessage, edit func(map[string]json.RawMessage) bool) (json.RawMessage, bool) {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil || m == nil || !edit(m) {
+		return nil, false
+	}
+	out, err := json.Marshal(m)
+	if err != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+// renameKey moves m[from] to m[to] unless m already has to.
+func renameKey(m map[string]json.RawMessage, from, to string) bool {
+	v, ok := m[from]
+	if !ok {
+		return false
+	}
+	if _, exists := m[to]; exists {
+		return false
+	}
+	m[to] = v
+	delete(m, from)
+	return true
+}
@@ -0,0 +1,90 @@
+package docs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// TestParse_FormatVersions parses the same crate as written by each format
+// version in testdata/formats and expects every one to give the same items
+// as the newest.
+func TestParse_FormatVersions(t *testing.T) {
+	t.Parallel()
+	files, err := filepath.Glob(filepath.Join("testdata", "formats", "format_*.json"))
+	if err != nil || len(files) < 2 {
+		t.Fatalf("fixtures: %v %v", files, err)
+	}
+	sort.Strings(files)
+
+	parse := func(t *testing.T, file string) map[string]ParsedItem {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, items, err := Parse(data, "fixture", "1.0.0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		byPath := map[string]ParsedItem{}
+		for _, it := range items {
+			byPath[it.Path] = it
+		}
+		return byPath
+	}
+
+	newest := files[len(files)-1]
+	want := parse(t, newest)
+	frag := func(path, name string) string {
+		for _, f := range want[path].Fragments {
+			if f.Name == name {
+				return f.Content
+			}
+		}
+		return ""
+	}
+	if got := want["fixture::parse"].Features; !reflect.DeepEqual(got, []string{"full"}) {
+		t.Errorf("parse features = %v", got)
+	}
+	for _, c := range []struct{ path, frag, want string }{
+		{"fixture::parse", FragArguments, "- **n**: usize"},
+		{"fixture::Limits", FragRequiredMethods, "unsafe fn check(n: usize) -> bool"},
+		{"fixture::Point", FragTraitImpls, "## Derived\n\nClone"},
+		{"fixture::Limits", FragAssocConstants, "const MAX: usize = 64;"},
+		{"fixture::Limits", FragAssocTypes, "type Item = u8;"},
+	} {
+		if got := frag(c.path, c.frag); !strings.Contains(got, c.want) {
+			t.Errorf("%s %s missing %q:\n%s", c.path, c.frag, c.want, got)
+		}
+	}
+
+	for _, file := range files[:len(files)-1] {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			got := parse(t, file)
+			if len(got) != len(want) {
+				t.Fatalf("got %d items, want %d", len(got), len(want))
+			}
+			for path, w := range want {
+				if g := got[path]; !reflect.DeepEqual(g, w) {
+					t.Errorf("%s differs from %s:\ngot  %+v\nwant %+v", path, filepath.Base(newest), g, w)
+				}
+			}
+		})
+	}
+}
+
+func TestAdaptFormat_LeavesNewShapes(t *testing.T) {
+	t.Parallel()
+	items := map[string]RustdocItem{
+		"1": {ID: 1, Inner: []byte(`{"assoc_const":{"type":{"primitive":"u8"},"value":"1","default":"2"}}`)},
+	}
+	crate := makeCrateWithItems(items)
+	crate.FormatVersion = 37
+	crate.adaptFormat()
+	if got := string(crate.Index["1"].Inner); got != `{"assoc_const":{"type":{"primitive":"u8"},"value":"1","default":"2"}}` {
+		t.Errorf("inner rewritten: %s", got)
+	}
+}
@@ -12,9 +12,20 @@ const (
 	FragFields          = "fields"
 	FragVariants        = "variants"
 	FragImplementations = "implementations"
+	FragTraitImpls      = "trait-implementations"
+	FragAutoImpls       = "auto-implementations"
+	FragBlanketImpls    = "blanket-implementations"
 	FragImplementors    = "implementors"
 	FragRequiredMethods = "required-methods"
 	FragProvidedMethods = "provided-methods"
+	FragAssocTypes      = "associated-types"
+	FragAssocConstants  = "associated-constants"
+	FragArguments       = "arguments"
+	FragReturns         = "returns"
+	FragExamples        = "examples"
+	FragErrors          = "errors"
+	FragSyntax          = "syntax"
+	FragReexports       = "re-exports"
 )
 
 // moduleCategory maps a rustdoc kind to its fragment name and heading.
@@ -42,7 +53,11 @@ var moduleCategoryOrder = []moduleCategory{
 
 // GenerateFragments creates sub-documents for an item based on its kind.
 // Fragment names match docs.rs sections: #fields, #variants, #implementors,
-// #required-methods, #provided-methods, #implementations.
+// #required-methods, #provided-methods, #associated-types,
+// #associated-constants, #implementations, #trait-implementations,
+// #auto-implementations, #blanket-implementations. Modules also get
+// #re-exports; functions #arguments, #returns, and #examples; macros
+// #syntax and #examples.
 func GenerateFragments(item *RustdocItem, crate *RustdocCrate, crateName, version string) []Fragment {
 	kind := innerKind(item.Inner)
 	switch kind {
@@ -54,6 +69,10 @@ func GenerateFragments(item *RustdocItem, crate *RustdocCrate, crateName, versio
 		return generateEnumFragments(item, crate, crateName, version)
 	case "trait":
 		return generateTraitFragments(item, crate, crateName, version)
+	case "function":
+		return generateFunctionFragments(item, crate, crateName, version)
+	case "macro", "proc_macro":
+		return generateMacroFragments(item)
 	default:
 		return nil
 	}
@@ -103,10 +122,12 @@ func generateModuleFragments(item *RustdocItem, crate *RustdocCrate, crateName,
 				continue
 			}
 			var use struct {
-				Name string `json:"name"`
-				ID   *int  `json:"id"`
+				Name   string `json:"name"`
+				ID     *int   `json:"id"`
+				IsGlob bool   `json:"is_glob"`
 			}
-			if err := json.Unmarshal(useData, &use); err != nil || use.ID == nil || use.Name == "" {
+			// Globs are listed in #re-exports.
+			if err := json.Unmarshal(useData, &use); err != nil || use.ID == nil || use.Name == "" || use.IsGlob {
 				continue
 			}
 			targetSummary, ok := crate.Paths[strconv.Itoa(*use.ID)]
@@ -173,6 +194,15 @@ func generateModuleFragments(item *RustdocItem, crate *RustdocCrate, crateName,
 		}
 		fragments = append(fragments, Fragment{Name: cat.fragment, Content: b.String()})
 	}
+	if f := reexportsFragment(modulePath, mod.Items, crate, crateName, version); f != nil {
+		fragments = append(fragments, *f)
+	}
+
+	if item.ID == crate.Root {
+		if f := errorsFragment(crate, crateName, version); f != nil {
+			fragments = append(fragments, *f)
+		}
+	}
 
 	return fragments
 }
@@ -199,9 +229,7 @@ func generateStructFragments(item *RustdocItem, crate *RustdocCrate, crateName,
 	if f := fieldsFragment(inner, crate); f != nil {
 		fragments = append(fragments, *f)
 	}
-	if f := implsFragment(inner, crate, item, crateName, version); f != nil {
-		fragments = append(fragments, *f)
-	}
+	fragments = append(fragments, implFragments(inner, crate, crateName, version)...)
 
 	return fragments
 }
@@ -217,9 +245,7 @@ func generateEnumFragments(item *RustdocItem, crate *RustdocCrate, crateName, ve
 	if f := variantsFragment(inner, crate); f != nil {
 		fragments = append(fragments, *f)
 	}
-	if f := implsFragment(inner, crate, item, crateName, version); f != nil {
-		fragments = append(fragments, *f)
-	}
+	fragments = append(fragments, implFragments(inner, crate, crateName, version)...)
 
 	return fragments
 }
@@ -234,13 +260,12 @@ func generateTraitFragments(item *RustdocItem, crate *RustdocCrate, crateName, v
 
 	frags := traitMethodFragments(inner, crate, crateName, version)
 	fragments = append(fragments, frags...)
+	fragments = append(fragments, traitAssocFragments(item, inner, crate, crateName, version)...)
 
 	if f := traitImplementorsFragment(inner, crate, crateName, version); f != nil {
 		fragments = append(fragments, *f)
 	}
-	if f := implsFragment(inner, crate, item, crateName, version); f != nil {
-		fragments = append(fragments, *f)
-	}
+	fragments = append(fragments, implFragments(inner, crate, crateName, version)...)
 
 	return fragments
 }
@@ -333,93 +358,6 @@ func variantsFragment(enumData json.RawMessage, crate *RustdocCrate) *Fragment {
 	return &Fragment{Name: FragVariants, Content: content}
 }
 
-// implsFragment generates a #implementations fragment listing methods from impl blocks.
-func implsFragment(typeData json.RawMessage, crate *RustdocCrate, item *RustdocItem, crateName, version string) *Fragment {
-	var t struct {
-		Impls []int `json:"impls"`
-	}
-	if err := json.Unmarshal(typeData, &t); err != nil || len(t.Impls) == 0 {
-		return nil
-	}
-
-	var b strings.Builder
-	b.WriteString("# Implementations\n\n")
-	count := 0
-	var allURIs []string
-
-	for _, implID := range t.Impls {
-		implItem, ok := crate.Index[strconv.Itoa(implID)]
-		if !ok {
-			continue
-		}
-		implInner := unwrapInner(implItem.Inner, "impl")
-		if implInner == nil {
-			continue
-		}
-
-		var impl struct {
-			Trait *json.RawMessage `json:"trait"`
-			Items []int            `json:"items"`
-		}
-		if err := json.Unmarshal(implInner, &impl); err != nil {
-			continue
-		}
-
-		// Group header: "impl Type" or "impl Trait for Type"
-		header := "impl"
-		if impl.Trait != nil {
-			var traitPath struct {
-				Name string `json:"name"`
-				ID   int    `json:"id"`
-			}
-			if err := json.Unmarshal(*impl.Trait, &traitPath); err == nil && traitPath.Name != "" {
-				if uri := ResolveItemURI(traitPath.ID, crate, crateName, version); uri != "" {
-					header = fmt.Sprintf("impl [%s](%s)", traitPath.Name, uri)
-					allURIs = append(allURIs, uri)
-				} else {
-					header = fmt.Sprintf("impl %s", traitPath.Name)
-				}
-			}
-		}
-
-		methods := listMethodSummaries(impl.Items, crate, crateName, version)
-		if len(methods) == 0 {
-			continue
-		}
-
-		b.WriteString(fmt.Sprintf("## %s\n\n", header))
-		for _, m := range methods {
-			display := m.name
-			if m.sig != "" {
-				display = m.sig
-			}
-			b.WriteString(fmt.Sprintf("- `%s`", display))
-			if m.docs != "" {
-				b.WriteString(": " + m.docs)
-			}
-			b.WriteString("\n")
-		}
-		b.WriteString("\n")
-		count++
-
-		for _, id := range impl.Items {
-			methodItem, ok := crate.Index[strconv.Itoa(id)]
-			if !ok {
-				continue
-			}
-			if fnData := unwrapInner(methodItem.Inner, "function"); fnData != nil {
-				allURIs = append(allURIs, collectFnURIs(fnData, crate, crateName, version)...)
-			}
-		}
-	}
-
-	if count == 0 {
-		return nil
-	}
-	appendTypesUsed(&b, allURIs)
-	return &Fragment{Name: FragImplementations, Content: b.String()}
-}
-
 // traitImplementorsFragment generates a #implementors fragment listing types that implement this trait.
 func traitImplementorsFragment(traitData json.RawMessage, crate *RustdocCrate, crateName, version string) *Fragment {
 	var t struct {
@@ -487,27 +425,25 @@ func traitMethodFragments(traitData json.RawMessage, crate *RustdocCrate, crateN
 			m.docs = *item.Docs
 		}
 
+		// Associated types and constants get their own fragments.
 		fnData := unwrapInner(item.Inner, "function")
-		if fnData != nil {
-			var fn struct {
-				HasBody bool `json:"has_body"`
-			}
-			if err := json.Unmarshal(fnData, &fn); err != nil {
-				continue
-			}
-			m.sig = renderFnSig(*item.Name, fnData, crate, crateName, version)
-			uris := collectFnURIs(fnData, crate, crateName, version)
-			if fn.HasBody {
-				provided = append(provided, m)
-				providedURIs = append(providedURIs, uris...)
-			} else {
-				required = append(required, m)
-				requiredURIs = append(requiredURIs, uris...)
-			}
+		if fnData == nil {
+			continue
+		}
+		var fn struct {
+			HasBody bool `json:"has_body"`
+		}
+		if err := json.Unmarshal(fnData, &fn); err != nil {
+			continue
+		}
+		m.sig = renderFnSig(*item.Name, fnData, crate, crateName, version)
+		uris := collectFnURIs(fnData, crate, crateName, version)
+		if fn.HasBody {
+			provided = append(provided, m)
+			providedURIs = append(providedURIs, uris...)
 		} else {
-			// Associated types, constants — put with required
-			m.sig = extractAssociatedItemSig(&item)
 			required = append(required, m)
+			requiredURIs = append(requiredURIs, uris...)
 		}
 	}
 
@@ -578,16 +514,6 @@ type methodSummary struct {
 	docs string
 }
 
-func extractAssociatedItemSig(item *RustdocItem) string {
-	if data := unwrapInner(item.Inner, "type_alias"); data != nil {
-		return "type " + *item.Name
-	}
-	if data := unwrapInner(item.Inner, "constant"); data != nil {
-		return "const " + *item.Name
-	}
-	return ""
-}
-
 // unwrapInner extracts the inner data for a given kind from a rustdoc Item's Inner field.
 // Inner is shaped like {"struct": {...}} or {"enum": {...}}.
 func unwrapInner(inner json.RawMessage, kind string) json.RawMessage {
@@ -0,0 +1,180 @@
+package docs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// assocItem is an associated type or constant declared by a trait.
+type assocItem struct {
+	name string
+	sig  string
+	docs string
+	uris []string
+}
+
+// traitAssocFragments generates #associated-types and #associated-constants
+// listings for a trait, plus a fragment per item named like docs.rs anchors
+// (#associatedtype.Item, #associatedconstant.MAX) holding its full docs.
+func traitAssocFragments(trait *RustdocItem, traitData json.RawMessage, crate *RustdocCrate, crateName, version string) []Fragment {
+	var t struct {
+		Items []int `json:"items"`
+	}
+	if err := json.Unmarshal(traitData, &t); err != nil || len(t.Items) == 0 {
+		return nil
+	}
+
+	var types, consts []assocItem
+	for _, id := range t.Items {
+		item, ok := crate.Index[strconv.Itoa(id)]
+		if !ok || item.Name == nil {
+			continue
+		}
+		a := assocItem{name: *item.Name}
+		if item.Docs != nil {
+			a.docs = *item.Docs
+		}
+		if data := innerOf(item.Inner, "assoc_type", "type_alias"); data != nil {
+			a.sig, a.uris = renderAssocType(a.name, data, crate, crateName, version)
+			types = append(types, a)
+		} else if data := innerOf(item.Inner, "assoc_const", "constant"); data != nil {
+			a.sig, a.uris = renderAssocConst(a.name, data, crate, crateName, version)
+			consts = append(consts, a)
+		}
+	}
+
+	traitURI := ResolveItemURI(trait.ID, crate, crateName, version)
+	var fragments []Fragment
+	fragments = append(fragments, assocFragments(FragAssocTypes, "Associated Types", "associatedtype.", types, traitURI)...)
+	fragments = append(fragments, assocFragments(FragAssocConstants, "Associated Constants", "associatedconstant.", consts, traitURI)...)
+	return fragments
+}
+
+// assocFragments lists items under heading in the fragment named name, each
+// with a summary and the URI of its own fragment, named prefix+item.
+func assocFragments(name, heading, prefix string, items []assocItem, traitURI string) []Fragment {
+	if len(items) == 0 {
+		return nil
+	}
+	var list strings.Builder
+	fmt.Fprintf(&list, "# %s\n\n", heading)
+	fragments := []Fragment{{Name: name}}
+	var uris []string
+	for _, a := range items {
+		fmt.Fprintf(&list, "## %s\n\n", a.name)
+		if a.sig != "" {
+			fmt.Fprintf(&list, "```rust\n%s\n```\n\n", a.sig)
+		}
+		if traitURI != "" {
+			fmt.Fprintf(&list, "%s#%s%s\n\n", traitURI, prefix, a.name)
+		}
+		if summary := strings.TrimSpace(strings.SplitN(a.docs, "\n\n", 2)[0]); summary != "" {
+			list.WriteString(summary)
+			list.WriteString("\n\n")
+		}
+		uris = append(uris, a.uris...)
+
+		var b strings.Builder
+		writeTraitMethods(&b, []traitMethodInfo{{name: a.name, sig: a.sig, docs: a.docs}})
+		appendTypesUsed(&b, a.uris)
+		fragments = append(fragments, Fragment{Name: prefix + a.name, Content: b.String()})
+	}
+	appendTypesUsed(&list, uris)
+	fragments[0].Content = list.String()
+	return fragments
+}
+
+// renderAssocType renders "type Name<'a>: Bounds = Default;" and returns the
+// URIs of the types it names.
+func renderAssocType(name string, data json.RawMessage, crate *RustdocCrate, crateName, version string) (string, []string) {
+	var a struct {
+		Generics struct {
+			Params []struct {
+				Name string `json:"name"`
+			} `json:"params"`
+		} `json:"generics"`
+		Bounds []json.RawMessage `json:"bounds"`
+		Type   json.RawMessage   `json:"type"`
+	}
+	if err := json.Unmarshal(data, &a); err != nil {
+		return "type " + name + ";", nil
+	}
+
+	var b strings.Builder
+	var linked []string
+	b.WriteString("type ")
+	b.WriteString(name)
+	var params []string
+	for _, p := range a.Generics.Params {
+		if p.Name != "" {
+			params = append(params, p.Name)
+		}
+	}
+	if len(params) > 0 {
+		fmt.Fprintf(&b, "<%s>", strings.Join(params, ", "))
+	}
+	if bounds := formatBounds(a.Bounds, crate, crateName, version); bounds != "" {
+		b.WriteString(": ")
+		b.WriteString(plainType(bounds))
+		linked = append(linked, bounds)
+	}
+	if def := resolveTypeName(a.Type, crate, crateName, version); def != "" {
+		b.WriteString(" = ")
+		b.WriteString(plainType(def))
+		linked = append(linked, def)
+	}
+	b.WriteString(";")
+	return b.String(), extractRsdocURIs(strings.Join(linked, " "))
+}
+
+// renderAssocConst renders "const NAME: Type = value;" and returns the URIs
+// of the types it names.
+func renderAssocConst(name string, data json.RawMessage, crate *RustdocCrate, crateName, version string) (string, []string) {
+	var c struct {
+		Type  json.RawMessage `json:"type"`
+		Value *string         `json:"value"`
+		// Free constants (the "constant" kind) nest their value.
+		Const struct {
+			Expr string `json:"expr"`
+		} `json:"const"`
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return "const " + name + ";", nil
+	}
+
+	typ := resolveTypeName(c.Type, crate, crateName, version)
+	sig := "const " + name
+	if typ != "" {
+		sig += ": " + plainType(typ)
+	}
+	switch {
+	case c.Value != nil:
+		sig += " = " + *c.Value
+	case c.Const.Expr != "":
+		sig += " = " + c.Const.Expr
+	}
+	return sig + ";", extractRsdocURIs(typ)
+}
+
+// innerOf returns the inner data of the first of kinds that inner holds.
+// Rustdoc renamed some kinds between format versions.
+func innerOf(inner json.RawMessage, kinds ...string) json.RawMessage {
+	for _, kind := range kinds {
+		if data := unwrapInner(inner, kind); data != nil {
+			return data
+		}
+	}
+	return nil
+}
+
+// firstNonNull returns the first value that isn't absent or JSON null.
+func firstNonNull(values ...json.RawMessage) json.RawMessage {
+	for _, v := range values {
+		if len(v) > 0 && string(v) != "null" {
+			return v
+		}
+	}
+	return nil
+}
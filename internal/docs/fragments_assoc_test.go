@@ -0,0 +1,65 @@
+package docs
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestGenerateFragments_TraitAssocItems(t *testing.T) {
+	t.Parallel()
+
+	items := map[string]RustdocItem{
+		"1": {ID: 1, Name: strPtr("Item"), Docs: strPtr("The type of the elements.\n\nMore detail."),
+			Inner: json.RawMessage(`{"assoc_type":{"generics":{"params":[],"where_predicates":[]},
+				"bounds":[{"trait_bound":{"trait":{"path":"Clone","id":40,"args":null},"modifier":"none"}}],"type":null}}`)},
+		"2": {ID: 2, Name: strPtr("Output"), Docs: strPtr("Result of the operation."),
+			Inner: json.RawMessage(`{"assoc_type":{"generics":{"params":[],"where_predicates":[]},
+				"bounds":[],"type":{"resolved_path":{"path":"Wrapper","id":30,"args":null}}}}`)},
+		"3": {ID: 3, Name: strPtr("MAX"), Docs: strPtr("Largest value."),
+			Inner: json.RawMessage(`{"assoc_const":{"type":{"primitive":"usize"},"value":"64"}}`)},
+		"4": {ID: 4, Name: strPtr("required_fn"),
+			Inner: json.RawMessage(`{"function":{"has_body":false,"sig":{"inputs":[],"output":null},"generics":{"params":[]},"header":{}}}`)},
+	}
+	crate := makeCrateWithItems(items)
+	crate.Paths["0"] = RustdocSummary{CrateID: 0, Path: []string{"mycrate", "MyTrait"}, Kind: "trait"}
+	crate.Paths["30"] = RustdocSummary{CrateID: 0, Path: []string{"mycrate", "Wrapper"}, Kind: "struct"}
+	crate.Paths["40"] = RustdocSummary{CrateID: 0, Path: []string{"mycrate", "Clone"}, Kind: "trait"}
+
+	item := &RustdocItem{ID: 0, Name: strPtr("MyTrait"), Inner: json.RawMessage(`{"trait":{"items":[1,2,3,4],"implementations":[]}}`)}
+	byName := map[string]string{}
+	for _, f := range GenerateFragments(item, crate, "mycrate", "1.0.0") {
+		byName[f.Name] = f.Content
+	}
+
+	if strings.Contains(byName[FragRequiredMethods], "Item") {
+		t.Errorf("associated type listed with required methods:\n%s", byName[FragRequiredMethods])
+	}
+
+	types := byName[FragAssocTypes]
+	for _, want := range []string{
+		"type Item: Clone;",
+		"type Output = Wrapper;",
+		"rsdoc://mycrate/1.0.0/mycrate::MyTrait#associatedtype.Item",
+		"The type of the elements.",
+		"- rsdoc://mycrate/1.0.0/mycrate::Wrapper",
+	} {
+		if !strings.Contains(types, want) {
+			t.Errorf("associated-types missing %q:\n%s", want, types)
+		}
+	}
+	if strings.Contains(types, "More detail.") {
+		t.Errorf("associated-types should only summarize each item:\n%s", types)
+	}
+
+	if own := byName["associatedtype.Item"]; !strings.Contains(own, "More detail.") || !strings.Contains(own, "- rsdoc://mycrate/1.0.0/mycrate::Clone") {
+		t.Errorf("associatedtype.Item fragment:\n%s", own)
+	}
+
+	if consts := byName[FragAssocConstants]; !strings.Contains(consts, "const MAX: usize = 64;") {
+		t.Errorf("associated-constants:\n%s", consts)
+	}
+	if _, ok := byName["associatedconstant.MAX"]; !ok {
+		t.Error("expected associatedconstant.MAX fragment")
+	}
+}
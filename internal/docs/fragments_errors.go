@@ -0,0 +1,130 @@
+package docs
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxErrorVariants caps how many variants an errors catalog entry lists.
+const maxErrorVariants = 12
+
+// errorsFragment generates the crate root's #errors fragment: a catalog of
+// the crate's error types, i.e. structs and enums that implement
+// std::error::Error or whose names end in "Error". Listing them in one place
+// answers "what errors can this return" better than any single item.
+func errorsFragment(crate *RustdocCrate, crateName, version string) *Fragment {
+	type entry struct {
+		path string
+		line string
+	}
+	var entries []entry
+
+	for idStr, item := range crate.Index {
+		if item.CrateID != 0 || item.Name == nil {
+			continue
+		}
+		kind := innerKind(item.Inner)
+		if kind != "struct" && kind != "enum" {
+			continue
+		}
+		inner := unwrapInner(item.Inner, kind)
+		if !strings.HasSuffix(*item.Name, "Error") && !implementsError(inner, crate) {
+			continue
+		}
+		uri := ResolveItemURI(item.ID, crate, crateName, version)
+		if uri == "" {
+			continue
+		}
+
+		path := *item.Name
+		if summary, ok := crate.Paths[idStr]; ok {
+			path = strings.Join(summary.Path, "::")
+		}
+		line := fmt.Sprintf("- [%s](%s) (%s)", path, uri, kind)
+		if item.Docs != nil && *item.Docs != "" {
+			line += ": " + strings.SplitN(*item.Docs, "\n", 2)[0]
+		}
+		if kind == "enum" {
+			if names := variantNames(inner, crate); len(names) > 0 {
+				line += " Variants: `" + strings.Join(names, "`, `") + "`"
+			}
+		}
+		entries = append(entries, entry{path: path, line: line})
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].path < entries[j].path })
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Errors\n\nError types defined by %s.\n\n", crateName)
+	for _, e := range entries {
+		b.WriteString(e.line + "\n")
+	}
+	return &Fragment{Name: FragErrors, Content: b.String()}
+}
+
+// implementsError reports whether a struct or enum has an impl of
+// std::error::Error (core::error::Error since Rust 1.81).
+func implementsError(typeData json.RawMessage, crate *RustdocCrate) bool {
+	var t struct {
+		Impls []int `json:"impls"`
+	}
+	if err := json.Unmarshal(typeData, &t); err != nil {
+		return false
+	}
+	for _, implID := range t.Impls {
+		implItem, ok := crate.Index[strconv.Itoa(implID)]
+		if !ok {
+			continue
+		}
+		var impl struct {
+			Trait *struct {
+				Name string `json:"name"`
+				Path string `json:"path"`
+				ID   int    `json:"id"`
+			} `json:"trait"`
+		}
+		if err := json.Unmarshal(unwrapInner(implItem.Inner, "impl"), &impl); err != nil || impl.Trait == nil {
+			continue
+		}
+		if summary, ok := crate.Paths[strconv.Itoa(impl.Trait.ID)]; ok {
+			if p := strings.Join(summary.Path, "::"); p == "std::error::Error" || p == "core::error::Error" {
+				return true
+			}
+			continue
+		}
+		name := impl.Trait.Name
+		if name == "" {
+			name = impl.Trait.Path
+		}
+		if name == "Error" || strings.HasSuffix(name, "error::Error") {
+			return true
+		}
+	}
+	return false
+}
+
+// variantNames returns up to maxErrorVariants variant names of an enum.
+func variantNames(enumData json.RawMessage, crate *RustdocCrate) []string {
+	var e struct {
+		Variants []int `json:"variants"`
+	}
+	if err := json.Unmarshal(enumData, &e); err != nil {
+		return nil
+	}
+	var names []string
+	for _, id := range e.Variants {
+		if len(names) == maxErrorVariants {
+			names = append(names, "…")
+			break
+		}
+		if v, ok := crate.Index[strconv.Itoa(id)]; ok && v.Name != nil {
+			names = append(names, *v.Name)
+		}
+	}
+	return names
+}
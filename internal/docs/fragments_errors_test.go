@@ -0,0 +1,56 @@
+package docs
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestGenerateFragments_Errors(t *testing.T) {
+	t.Parallel()
+
+	items := map[string]RustdocItem{
+		"0": {ID: 0, Name: strPtr("mycrate"), Inner: json.RawMessage(`{"module":{"items":[1,2,3]}}`)},
+		// Named *Error: listed with its variants.
+		"1": {ID: 1, Name: strPtr("ParseError"), Docs: strPtr("Failed to parse input.\n\nMore detail."),
+			Inner: json.RawMessage(`{"enum":{"variants":[10,11],"impls":[]}}`)},
+		// Implements std::error::Error without the suffix.
+		"2": {ID: 2, Name: strPtr("Timeout"), Inner: json.RawMessage(`{"struct":{"kind":"unit","impls":[20]}}`)},
+		// Neither.
+		"3":  {ID: 3, Name: strPtr("Config"), Inner: json.RawMessage(`{"struct":{"kind":"unit","impls":[]}}`)},
+		"10": {ID: 10, Name: strPtr("Eof")},
+		"11": {ID: 11, Name: strPtr("Syntax")},
+		"20": {ID: 20, Inner: json.RawMessage(`{"impl":{"trait":{"path":"Error","id":99},"for":null,"items":[]}}`)},
+	}
+	crate := makeCrateWithItems(items)
+	crate.Paths = map[string]RustdocSummary{
+		"1":  {Path: []string{"mycrate", "ParseError"}, Kind: "enum"},
+		"2":  {Path: []string{"mycrate", "Timeout"}, Kind: "struct"},
+		"3":  {Path: []string{"mycrate", "Config"}, Kind: "struct"},
+		"99": {CrateID: 1, Path: []string{"core", "error", "Error"}, Kind: "trait"},
+	}
+	root := items["0"]
+
+	var errors *Fragment
+	for _, f := range GenerateFragments(&root, crate, "mycrate", "1.0.0") {
+		if f.Name == FragErrors {
+			errors = &f
+		}
+	}
+	if errors == nil {
+		t.Fatal("expected errors fragment on the crate root")
+	}
+	want := "- [mycrate::ParseError](rsdoc://mycrate/1.0.0/mycrate::ParseError) (enum): Failed to parse input. Variants: `Eof`, `Syntax`\n" +
+		"- [mycrate::Timeout](rsdoc://mycrate/1.0.0/mycrate::Timeout) (struct)\n"
+	if !strings.HasSuffix(errors.Content, want) {
+		t.Errorf("got:\n%s\nwant suffix:\n%s", errors.Content, want)
+	}
+
+	// Only the root module gets the catalog.
+	crate.Root = 5
+	for _, f := range GenerateFragments(&root, crate, "mycrate", "1.0.0") {
+		if f.Name == FragErrors {
+			t.Error("errors fragment on a non-root module")
+		}
+	}
+}
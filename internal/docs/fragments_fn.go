@@ -0,0 +1,248 @@
+package docs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+func generateFunctionFragments(item *RustdocItem, crate *RustdocCrate, crateName, version string) []Fragment {
+	fnData := unwrapInner(item.Inner, "function")
+	if fnData == nil {
+		return nil
+	}
+
+	var docs string
+	if item.Docs != nil {
+		docs = *item.Docs
+	}
+
+	var fragments []Fragment
+	if f := argumentsFragment(fnData, crate, crateName, version); f != nil {
+		fragments = append(fragments, *f)
+	}
+	if f := returnsFragment(fnData, docs, crate, crateName, version); f != nil {
+		fragments = append(fragments, *f)
+	}
+	if f := examplesFragment(docs); f != nil {
+		fragments = append(fragments, *f)
+	}
+	return fragments
+}
+
+// argumentsFragment generates an #arguments fragment listing parameters and
+// the bounds on any generic parameters they use.
+func argumentsFragment(fnData json.RawMessage, crate *RustdocCrate, crateName, version string) *Fragment {
+	var fn struct {
+		Sig struct {
+			Inputs []json.RawMessage `json:"inputs"`
+		} `json:"sig"`
+		Generics struct {
+			Params []struct {
+				Name string `json:"name"`
+				Kind struct {
+					Type *struct {
+						Bounds      []json.RawMessage `json:"bounds"`
+						IsSynthetic bool              `json:"is_synthetic"`
+					} `json:"type"`
+				} `json:"kind"`
+			} `json:"params"`
+			WherePredicates []struct {
+				BoundPredicate *struct {
+					Type   json.RawMessage   `json:"type"`
+					Bounds []json.RawMessage `json:"bounds"`
+				} `json:"bound_predicate"`
+			} `json:"where_predicates"`
+		} `json:"generics"`
+	}
+	if err := json.Unmarshal(fnData, &fn); err != nil || len(fn.Sig.Inputs) == 0 {
+		return nil
+	}
+
+	var b strings.Builder
+	b.WriteString("# Arguments\n\n")
+	var uris []string
+	count := 0
+	for _, input := range fn.Sig.Inputs {
+		var pair []json.RawMessage
+		if err := json.Unmarshal(input, &pair); err != nil || len(pair) < 2 {
+			continue
+		}
+		var paramName string
+		json.Unmarshal(pair[0], &paramName)
+		if paramName == "self" {
+			fmt.Fprintf(&b, "- `%s`\n", selfShorthand(pair[1]))
+			count++
+			continue
+		}
+		typeStr := resolveTypeName(pair[1], crate, crateName, version)
+		fmt.Fprintf(&b, "- **%s**: %s\n", paramName, typeStr)
+		uris = append(uris, extractRsdocURIs(typeStr)...)
+		count++
+	}
+	if count == 0 {
+		return nil
+	}
+
+	// Generic bounds, from both inline params and where clauses. Synthetic
+	// params come from `impl Trait` arguments and are already shown inline.
+	var bounds []string
+	for _, p := range fn.Generics.Params {
+		if p.Kind.Type == nil || p.Kind.Type.IsSynthetic || len(p.Kind.Type.Bounds) == 0 {
+			continue
+		}
+		if s := formatBounds(p.Kind.Type.Bounds, crate, crateName, version); s != "" {
+			bounds = append(bounds, fmt.Sprintf("- **%s**: %s", p.Name, s))
+			uris = append(uris, extractRsdocURIs(s)...)
+		}
+	}
+	for _, wp := range fn.Generics.WherePredicates {
+		if wp.BoundPredicate == nil {
+			continue
+		}
+		target := resolveTypeName(wp.BoundPredicate.Type, crate, crateName, version)
+		if s := formatBounds(wp.BoundPredicate.Bounds, crate, crateName, version); target != "" && s != "" {
+			bounds = append(bounds, fmt.Sprintf("- **%s**: %s", target, s))
+			uris = append(uris, extractRsdocURIs(s)...)
+		}
+	}
+	if len(bounds) > 0 {
+		b.WriteString("\n## Bounds\n\n")
+		b.WriteString(strings.Join(bounds, "\n"))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	appendTypesUsed(&b, uris)
+	return &Fragment{Name: FragArguments, Content: b.String()}
+}
+
+// returnsFragment generates a #returns fragment with the return type and any
+// "Returns" section from the docs.
+func returnsFragment(fnData json.RawMessage, docs string, crate *RustdocCrate, crateName, version string) *Fragment {
+	var fn struct {
+		Sig struct {
+			Output json.RawMessage `json:"output"`
+		} `json:"sig"`
+	}
+	if err := json.Unmarshal(fnData, &fn); err != nil {
+		return nil
+	}
+	if len(fn.Sig.Output) == 0 || string(fn.Sig.Output) == "null" {
+		return nil
+	}
+	typeStr := resolveTypeName(fn.Sig.Output, crate, crateName, version)
+	if typeStr == "" {
+		return nil
+	}
+
+	var b strings.Builder
+	b.WriteString("# Returns\n\n")
+	b.WriteString(typeStr)
+	b.WriteString("\n\n")
+	if section := docSection(docs, "Returns"); section != "" {
+		b.WriteString(section)
+		b.WriteString("\n\n")
+	}
+	appendTypesUsed(&b, extractRsdocURIs(typeStr))
+	return &Fragment{Name: FragReturns, Content: b.String()}
+}
+
+// examplesFragment generates an #examples fragment from the Rust code blocks in the docs.
+func examplesFragment(docs string) *Fragment {
+	blocks := ExtractRustCodeBlocks(docs)
+	if len(blocks) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString("# Examples\n\n")
+	for _, code := range blocks {
+		fmt.Fprintf(&b, "```rust\n%s\n```\n\n", code)
+	}
+	return &Fragment{Name: FragExamples, Content: b.String()}
+}
+
+// formatBounds renders generic bounds like "[Future](uri) + Send + 'static".
+func formatBounds(bounds []json.RawMessage, crate *RustdocCrate, crateName, version string) string {
+	var parts []string
+	for _, raw := range bounds {
+		var bound struct {
+			TraitBound *struct {
+				Trait struct {
+					Name string           `json:"name"`
+					Path string           `json:"path"`
+					ID   int              `json:"id"`
+					Args *json.RawMessage `json:"args"`
+				} `json:"trait"`
+				Modifier string `json:"modifier"`
+			} `json:"trait_bound"`
+			Outlives *string `json:"outlives"`
+		}
+		if err := json.Unmarshal(raw, &bound); err != nil {
+			continue
+		}
+		if bound.Outlives != nil {
+			parts = append(parts, *bound.Outlives)
+			continue
+		}
+		if bound.TraitBound == nil {
+			continue
+		}
+		tr := bound.TraitBound.Trait
+		name := tr.Name
+		if name == "" {
+			name = tr.Path
+		}
+		if name == "" {
+			continue
+		}
+		s := name
+		if uri := ResolveItemURI(tr.ID, crate, crateName, version); uri != "" {
+			s = fmt.Sprintf("[%s](%s)", name, uri)
+		}
+		if tr.Args != nil {
+			s += formatGenericArgs(*tr.Args, crate, crateName, version)
+		}
+		if bound.TraitBound.Modifier == "maybe" {
+			s = "?" + s
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, " + ")
+}
+
+// docSection returns the body of the first markdown section whose heading
+// matches title (any level), up to the next heading of the same or higher level.
+func docSection(docs, title string) string {
+	lines := strings.Split(docs, "\n")
+	start, level := -1, 0
+	inCode := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if fenceMarker(trimmed) != "" {
+			inCode = !inCode
+			continue
+		}
+		if inCode || !strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		n := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+		if n == len(trimmed) || trimmed[n] != ' ' {
+			continue
+		}
+		heading := strings.TrimSpace(trimmed[n:])
+		if start < 0 {
+			if strings.EqualFold(heading, title) {
+				start, level = i+1, n
+			}
+			continue
+		}
+		if n <= level {
+			return strings.TrimSpace(strings.Join(lines[start:i], "\n"))
+		}
+	}
+	if start < 0 {
+		return ""
+	}
+	return strings.TrimSpace(strings.Join(lines[start:], "\n"))
+}
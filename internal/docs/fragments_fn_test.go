@@ -0,0 +1,128 @@
+package docs
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGenerateFragments_Function(t *testing.T) {
+	t.Parallel()
+
+	crate := &RustdocCrate{
+		Index: map[string]RustdocItem{},
+		Paths: map[string]RustdocSummary{
+			"20": {CrateID: 0, Path: []string{"mycrate", "task", "JoinHandle"}, Kind: "struct"},
+			"30": {CrateID: 0, Path: []string{"mycrate", "Future"}, Kind: "trait"},
+		},
+		ExternalCrates: map[string]ExternalCrate{},
+	}
+
+	docs := "Spawns a task.\n\n# Returns\n\nA handle to the task.\n\n# Examples\n\n```\n# use mycrate::spawn;\nspawn(async {});\n```\n\n```text\nnot rust\n```\n"
+	item := &RustdocItem{
+		ID:   1,
+		Name: strPtr("spawn"),
+		Docs: &docs,
+		Inner: json.RawMessage(`{"function":{
+			"sig":{"inputs":[["future",{"generic":"F"}]],"output":{"resolved_path":{"name":"JoinHandle","id":20,"args":null}}},
+			"generics":{"params":[{"name":"F","kind":{"type":{"bounds":[
+				{"trait_bound":{"trait":{"path":"Future","id":30,"args":null},"modifier":"none"}},
+				{"outlives":"'static"}
+			],"is_synthetic":false}}}],"where_predicates":[]},
+			"header":{}}}`),
+	}
+
+	fragments := GenerateFragments(item, crate, "mycrate", "1.0.0")
+	byName := make(map[string]string)
+	for _, f := range fragments {
+		byName[f.Name] = f.Content
+	}
+
+	args, ok := byName[FragArguments]
+	if !ok {
+		t.Fatal("expected arguments fragment")
+	}
+	if !strings.Contains(args, "- **future**: F") {
+		t.Errorf("arguments fragment missing param: %s", args)
+	}
+	if !strings.Contains(args, "- **F**: [Future](rsdoc://mycrate/1.0.0/mycrate::Future) + 'static") {
+		t.Errorf("arguments fragment missing bounds: %s", args)
+	}
+
+	ret, ok := byName[FragReturns]
+	if !ok {
+		t.Fatal("expected returns fragment")
+	}
+	if !strings.Contains(ret, "[JoinHandle](rsdoc://mycrate/1.0.0/mycrate::task::JoinHandle)") {
+		t.Errorf("returns fragment missing linked type: %s", ret)
+	}
+	if !strings.Contains(ret, "A handle to the task.") || strings.Contains(ret, "spawn(async") {
+		t.Errorf("returns fragment should contain only the Returns doc section: %s", ret)
+	}
+
+	ex, ok := byName[FragExamples]
+	if !ok {
+		t.Fatal("expected examples fragment")
+	}
+	if !strings.Contains(ex, "spawn(async {});") {
+		t.Errorf("examples fragment missing code: %s", ex)
+	}
+	if strings.Contains(ex, "# use") || strings.Contains(ex, "not rust") {
+		t.Errorf("examples fragment should drop hidden lines and non-Rust blocks: %s", ex)
+	}
+}
+
+func TestGenerateFragments_FunctionNoOutput(t *testing.T) {
+	t.Parallel()
+
+	item := &RustdocItem{
+		ID:    1,
+		Name:  strPtr("noop"),
+		Inner: json.RawMessage(`{"function":{"sig":{"inputs":[],"output":null},"generics":{"params":[]},"header":{}}}`),
+	}
+	fragments := GenerateFragments(item, makeCrateWithItems(nil), "mycrate", "1.0.0")
+	if len(fragments) != 0 {
+		t.Errorf("expected no fragments, got %v", fragments)
+	}
+}
+
+func TestExtractRustCodeBlocks(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		docs string
+		want []string
+	}{
+		{"untagged", "text\n```\nlet x = 1;\n```\n", []string{"let x = 1;"}},
+		{"tagged_rust", "```rust,no_run\nmain();\n```", []string{"main();"}},
+		{"other_lang", "```toml\n[dependencies]\n```", nil},
+		{"tilde_fence", "~~~\nfoo();\n~~~", []string{"foo();"}},
+		{"hidden_lines", "```\n# fn main() {\nbar();\n#\n# }\n```", []string{"bar();"}},
+		{"longer_fence", "````\n```\ninner\n```\n````", []string{"```\ninner\n```"}},
+		{"unterminated", "```\nlost", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtractRustCodeBlocks(tt.docs)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocSection(t *testing.T) {
+	t.Parallel()
+
+	docs := "Intro\n\n# Errors\n\nFails when closed.\n\n## Details\n\nMore.\n\n```\n# not a heading\n```\n\n# Panics\n\nNever."
+	got := docSection(docs, "errors")
+	if !strings.HasPrefix(got, "Fails when closed.") || !strings.Contains(got, "More.") || strings.Contains(got, "Never.") {
+		t.Errorf("unexpected section: %q", got)
+	}
+	if docSection(docs, "Safety") != "" {
+		t.Error("expected empty section for missing heading")
+	}
+}
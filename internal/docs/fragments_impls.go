@@ -0,0 +1,176 @@
+package docs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// implFragments sorts a type's impl blocks the way docs.rs does:
+// #implementations for inherent methods, #trait-implementations for impls
+// written by hand with derived ones named in one line, and
+// #auto-implementations and #blanket-implementations, which only name the
+// traits, since every type has the same dozen of them.
+func implFragments(typeData json.RawMessage, crate *RustdocCrate, crateName, version string) []Fragment {
+	var t struct {
+		Impls []int `json:"impls"`
+	}
+	if err := json.Unmarshal(typeData, &t); err != nil || len(t.Impls) == 0 {
+		return nil
+	}
+
+	var inherent, traits, auto, blanket strings.Builder
+	var inherentURIs, traitURIs []string
+	var derived []string
+	for _, implID := range t.Impls {
+		implItem, ok := crate.Index[strconv.Itoa(implID)]
+		if !ok {
+			continue
+		}
+		implInner := unwrapInner(implItem.Inner, "impl")
+		if implInner == nil {
+			continue
+		}
+		var impl struct {
+			Trait       json.RawMessage `json:"trait"`
+			Items       []int           `json:"items"`
+			IsSynthetic bool            `json:"is_synthetic"`
+			IsNegative  bool            `json:"is_negative"`
+			BlanketImpl json.RawMessage `json:"blanket_impl"`
+		}
+		if err := json.Unmarshal(implInner, &impl); err != nil {
+			continue
+		}
+
+		if firstNonNull(impl.Trait) == nil {
+			if writeImplMethods(&inherent, "impl", impl.Items, crate, crateName, version) {
+				inherentURIs = append(inherentURIs, implItemURIs(impl.Items, crate, crateName, version)...)
+			}
+			continue
+		}
+
+		trait := formatImplTrait(impl.Trait, crate, crateName, version)
+		if trait == "" {
+			continue
+		}
+		if impl.IsNegative {
+			trait = "!" + trait
+		}
+		switch {
+		case impl.IsSynthetic:
+			fmt.Fprintf(&auto, "- %s\n", trait)
+		case firstNonNull(impl.BlanketImpl) != nil:
+			fmt.Fprintf(&blanket, "- %s\n", trait)
+		case isDerived(&implItem):
+			derived = append(derived, trait)
+		default:
+			traitURIs = append(traitURIs, extractRsdocURIs(trait)...)
+			if writeImplMethods(&traits, "impl "+trait, impl.Items, crate, crateName, version) {
+				traitURIs = append(traitURIs, implItemURIs(impl.Items, crate, crateName, version)...)
+			} else {
+				fmt.Fprintf(&traits, "## impl %s\n\n", trait)
+			}
+		}
+	}
+	if len(derived) > 0 {
+		fmt.Fprintf(&traits, "## Derived\n\n%s\n\n", strings.Join(derived, ", "))
+	}
+
+	var fragments []Fragment
+	add := func(name, heading string, b *strings.Builder, uris []string) {
+		if b.Len() == 0 {
+			return
+		}
+		var f strings.Builder
+		fmt.Fprintf(&f, "# %s\n\n%s", heading, b.String())
+		if !strings.HasSuffix(b.String(), "\n\n") {
+			f.WriteString("\n")
+		}
+		appendTypesUsed(&f, uris)
+		fragments = append(fragments, Fragment{Name: name, Content: f.String()})
+	}
+	add(FragImplementations, "Implementations", &inherent, inherentURIs)
+	add(FragTraitImpls, "Trait Implementations", &traits, traitURIs)
+	add(FragAutoImpls, "Auto Trait Implementations", &auto, nil)
+	add(FragBlanketImpls, "Blanket Implementations", &blanket, nil)
+	return fragments
+}
+
+// writeImplMethods writes a "## header" section listing the impl's methods
+// with their signatures and summaries, reporting false if it has none.
+func writeImplMethods(b *strings.Builder, header string, items []int, crate *RustdocCrate, crateName, version string) bool {
+	methods := listMethodSummaries(items, crate, crateName, version)
+	if len(methods) == 0 {
+		return false
+	}
+	fmt.Fprintf(b, "## %s\n\n", header)
+	for _, m := range methods {
+		display := m.name
+		if m.sig != "" {
+			display = m.sig
+		}
+		fmt.Fprintf(b, "- `%s`", display)
+		if m.docs != "" {
+			b.WriteString(": " + m.docs)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	return true
+}
+
+// implItemURIs collects the types used in the signatures of an impl's
+// methods.
+func implItemURIs(items []int, crate *RustdocCrate, crateName, version string) []string {
+	var uris []string
+	for _, id := range items {
+		methodItem, ok := crate.Index[strconv.Itoa(id)]
+		if !ok {
+			continue
+		}
+		if fnData := unwrapInner(methodItem.Inner, "function"); fnData != nil {
+			uris = append(uris, collectFnURIs(fnData, crate, crateName, version)...)
+		}
+	}
+	return uris
+}
+
+// formatImplTrait renders the trait an impl implements, linked when it
+// resolves, with its generic arguments.
+func formatImplTrait(traitJSON json.RawMessage, crate *RustdocCrate, crateName, version string) string {
+	var tr struct {
+		Name string           `json:"name"`
+		Path string           `json:"path"`
+		ID   int              `json:"id"`
+		Args *json.RawMessage `json:"args"`
+	}
+	if err := json.Unmarshal(traitJSON, &tr); err != nil {
+		return ""
+	}
+	name := tr.Name
+	if name == "" {
+		name = tr.Path
+	}
+	if name == "" {
+		return ""
+	}
+	if uri := ResolveItemURI(tr.ID, crate, crateName, version); uri != "" {
+		name = fmt.Sprintf("[%s](%s)", name, uri)
+	}
+	if tr.Args != nil {
+		name += formatGenericArgs(*tr.Args, crate, crateName, version)
+	}
+	return name
+}
+
+// isDerived reports whether an impl came from #[derive]. Format versions
+// spell the attribute as source text or as a tag, so the raw JSON is searched.
+func isDerived(impl *RustdocItem) bool {
+	for _, raw := range impl.Attrs {
+		if strings.Contains(string(raw), "automatically_derived") {
+			return true
+		}
+	}
+	return false
+}
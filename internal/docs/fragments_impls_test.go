@@ -0,0 +1,65 @@
+package docs
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestGenerateFragments_ImplSections(t *testing.T) {
+	t.Parallel()
+
+	fn := func(name string) RustdocItem {
+		return RustdocItem{Name: strPtr(name),
+			Inner: json.RawMessage(`{"function":{"sig":{"inputs":[],"output":null},"generics":{"params":[]},"header":{}}}`)}
+	}
+	items := map[string]RustdocItem{
+		"1":  fn("new"),
+		"2":  fn("fmt"),
+		"3":  fn("clone"),
+		"4":  fn("from"),
+		"10": {ID: 10, Inner: json.RawMessage(`{"impl":{"trait":null,"items":[1],"is_synthetic":false,"blanket_impl":null}}`)},
+		"11": {ID: 11, Inner: json.RawMessage(`{"impl":{"trait":{"path":"Display","id":60,"args":null},"items":[2],"is_synthetic":false,"blanket_impl":null}}`)},
+		"12": {ID: 12, Attrs: []json.RawMessage{json.RawMessage(`"#[automatically_derived]"`)},
+			Inner: json.RawMessage(`{"impl":{"trait":{"path":"Clone","id":61,"args":null},"items":[3],"is_synthetic":false,"blanket_impl":null}}`)},
+		"13": {ID: 13, Inner: json.RawMessage(`{"impl":{"trait":{"path":"Send","id":62,"args":null},"items":[],"is_synthetic":true,"blanket_impl":null}}`)},
+		"14": {ID: 14, Inner: json.RawMessage(`{"impl":{"trait":{"path":"From","id":63,"args":{"angle_bracketed":{"args":[{"type":{"generic":"T"}}]}}},"items":[4],"is_synthetic":false,"blanket_impl":{"generic":"T"}}}`)},
+		"15": {ID: 15, Inner: json.RawMessage(`{"impl":{"trait":{"path":"Eq","id":64,"args":null},"items":[],"is_synthetic":false,"blanket_impl":null}}`)},
+	}
+	crate := makeCrateWithItems(items)
+	crate.Paths["60"] = RustdocSummary{CrateID: 0, Path: []string{"mycrate", "Display"}, Kind: "trait"}
+
+	item := &RustdocItem{ID: 0, Name: strPtr("Point"),
+		Inner: json.RawMessage(`{"struct":{"kind":{"unit":null},"impls":[10,11,12,13,14,15]}}`)}
+	byName := map[string]string{}
+	for _, f := range GenerateFragments(item, crate, "mycrate", "1.0.0") {
+		byName[f.Name] = f.Content
+	}
+
+	checks := []struct {
+		frag        string
+		want, avoid []string
+	}{
+		{FragImplementations, []string{"## impl\n", "`fn new()`"}, []string{"fmt", "clone", "Send", "From"}},
+		{FragTraitImpls, []string{"## impl [Display](rsdoc://mycrate/1.0.0/mycrate::Display)", "`fn fmt()`", "## impl Eq", "## Derived\n\nClone", "- rsdoc://mycrate/1.0.0/mycrate::Display"}, []string{"fn clone", "Send", "From"}},
+		{FragAutoImpls, []string{"- Send\n"}, nil},
+		{FragBlanketImpls, []string{`- From\<T>`}, []string{"fn from"}},
+	}
+	for _, c := range checks {
+		content, ok := byName[c.frag]
+		if !ok {
+			t.Errorf("missing #%s fragment", c.frag)
+			continue
+		}
+		for _, w := range c.want {
+			if !strings.Contains(content, w) {
+				t.Errorf("#%s missing %q:\n%s", c.frag, w, content)
+			}
+		}
+		for _, a := range c.avoid {
+			if strings.Contains(content, a) {
+				t.Errorf("#%s should not contain %q:\n%s", c.frag, a, content)
+			}
+		}
+	}
+}
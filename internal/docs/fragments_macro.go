@@ -0,0 +1,137 @@
+package docs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// maxUsageLines caps the invocations #syntax collects from examples.
+const maxUsageLines = 10
+
+// macroSignature renders a macro's declaration: the macro_rules! matchers
+// (rustdoc elides the bodies) or, for proc macros, how they are invoked.
+func macroSignature(name string, inner json.RawMessage) string {
+	if data := unwrapInner(inner, "macro"); data != nil {
+		var src string
+		if json.Unmarshal(data, &src) == nil {
+			return strings.TrimSpace(src)
+		}
+		return ""
+	}
+	data := unwrapInner(inner, "proc_macro")
+	if data == nil {
+		return ""
+	}
+	var pm struct {
+		Kind    string   `json:"kind"`
+		Helpers []string `json:"helpers"`
+	}
+	if json.Unmarshal(data, &pm) != nil {
+		return ""
+	}
+	switch pm.Kind {
+	case "attr":
+		return fmt.Sprintf("#[%s]", name)
+	case "derive":
+		sig := fmt.Sprintf("#[derive(%s)]", name)
+		if len(pm.Helpers) > 0 {
+			sig += fmt.Sprintf("\n// helper attributes: #[%s]", strings.Join(pm.Helpers, "], #["))
+		}
+		return sig
+	}
+	return name + "!(...)"
+}
+
+// generateMacroFragments creates #syntax and #examples for a macro.
+func generateMacroFragments(item *RustdocItem) []Fragment {
+	if item.Name == nil {
+		return nil
+	}
+	var docs string
+	if item.Docs != nil {
+		docs = *item.Docs
+	}
+
+	var fragments []Fragment
+	if f := syntaxFragment(*item.Name, macroSignature(*item.Name, item.Inner), docs); f != nil {
+		fragments = append(fragments, *f)
+	}
+	if f := examplesFragment(docs); f != nil {
+		fragments = append(fragments, *f)
+	}
+	return fragments
+}
+
+// syntaxFragment pairs a macro's declaration with the ways its doc examples
+// invoke it.
+func syntaxFragment(name, sig, docs string) *Fragment {
+	usage := macroUsage(name, docs)
+	if sig == "" && len(usage) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString("# Syntax\n\n")
+	if sig != "" {
+		fmt.Fprintf(&b, "```rust\n%s\n```\n\n", sig)
+	}
+	if len(usage) > 0 {
+		fmt.Fprintf(&b, "## Usage\n\n```rust\n%s\n```\n", strings.Join(usage, "\n"))
+	}
+	return &Fragment{Name: FragSyntax, Content: b.String()}
+}
+
+// macroUsage returns the distinct lines of the doc examples that invoke
+// macro name, as name!, #[name] or a derive.
+func macroUsage(name, docs string) []string {
+	var usage []string
+	seen := map[string]bool{}
+	for _, block := range ExtractRustCodeBlocks(docs) {
+		for _, line := range strings.Split(block, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "//") || seen[line] || !invokesMacro(line, name) {
+				continue
+			}
+			seen[line] = true
+			usage = append(usage, line)
+			if len(usage) == maxUsageLines {
+				return usage
+			}
+		}
+	}
+	return usage
+}
+
+func invokesMacro(line, name string) bool {
+	if containsIdent(line, name+"!") || strings.Contains(line, "#["+name) {
+		return true
+	}
+	if i := strings.Index(line, "derive("); i >= 0 {
+		for _, d := range strings.Split(strings.TrimSuffix(line[i+len("derive("):], ")]"), ",") {
+			if strings.TrimSpace(d) == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// containsIdent reports whether s contains sub not preceded by an
+// identifier character, so "vec!" doesn't match "smallvec!".
+func containsIdent(s, sub string) bool {
+	for i := 0; ; {
+		j := strings.Index(s[i:], sub)
+		if j < 0 {
+			return false
+		}
+		at := i + j
+		if at == 0 || !isIdentByte(s[at-1]) {
+			return true
+		}
+		i = at + 1
+	}
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,71 @@
+package docs
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGenerateFragments_MacroRules(t *testing.T) {
+	t.Parallel()
+
+	docs := "Creates a vector.\n\n# Examples\n\n```\nlet v = vec![1, 2, 3];\nlet w = vec![0; 5];\nlet s = smallvec![1];\n# let hidden = vec![9];\n```\n"
+	item := RustdocItem{
+		ID:    1,
+		Name:  strPtr("vec"),
+		Docs:  strPtr(docs),
+		Inner: json.RawMessage(`{"macro": "macro_rules! vec {\n    () => { ... };\n    ($elem:expr; $n:expr) => { ... };\n}"}`),
+	}
+	crate := makeCrateWithItems(map[string]RustdocItem{"1": item})
+
+	fragments := GenerateFragments(&item, crate, "alloc", "1.0.0")
+	var names []string
+	for _, f := range fragments {
+		names = append(names, f.Name)
+	}
+	if want := []string{FragSyntax, FragExamples}; !slices.Equal(names, want) {
+		t.Fatalf("fragments = %v, want %v", names, want)
+	}
+
+	syntax := fragments[0].Content
+	for _, want := range []string{"macro_rules! vec {", "($elem:expr; $n:expr) => { ... };", "let v = vec![1, 2, 3];", "let w = vec![0; 5];"} {
+		if !strings.Contains(syntax, want) {
+			t.Errorf("#syntax missing %q:\n%s", want, syntax)
+		}
+	}
+	for _, unwanted := range []string{"smallvec!", "hidden"} {
+		if strings.Contains(syntax, unwanted) {
+			t.Errorf("#syntax should not contain %q:\n%s", unwanted, syntax)
+		}
+	}
+}
+
+func TestMacroSignature_ProcMacros(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		inner string
+		want  string
+	}{
+		{`{"proc_macro": {"kind": "bang", "helpers": []}}`, "sql!(...)"},
+		{`{"proc_macro": {"kind": "attr", "helpers": []}}`, "#[sql]"},
+		{`{"proc_macro": {"kind": "derive", "helpers": ["sql", "table"]}}`, "#[derive(sql)]\n// helper attributes: #[sql], #[table]"},
+		{`{"function": {}}`, ""},
+	}
+	for _, tt := range tests {
+		if got := macroSignature("sql", json.RawMessage(tt.inner)); got != tt.want {
+			t.Errorf("macroSignature(%s) = %q, want %q", tt.inner, got, tt.want)
+		}
+	}
+}
+
+func TestMacroUsage_Derive(t *testing.T) {
+	t.Parallel()
+
+	docs := "```rust\n#[derive(Debug, Serialize)]\nstruct A;\n#[derive(Serialize)]\nstruct B;\n#[derive(Serialize)]\nstruct C;\n```"
+	want := []string{"#[derive(Debug, Serialize)]", "#[derive(Serialize)]"}
+	if got := macroUsage("Serialize", docs); !slices.Equal(got, want) {
+		t.Errorf("macroUsage = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,128 @@
+package docs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// reexport is a `pub use` in a module: one item, or every item of a module
+// for a glob.
+type reexport struct {
+	name   string
+	glob   bool
+	target int
+}
+
+// moduleUses returns the `pub use` items among a module's children.
+func moduleUses(items []int, crate *RustdocCrate) []reexport {
+	var uses []reexport
+	for _, id := range items {
+		child, ok := crate.Index[strconv.Itoa(id)]
+		if !ok {
+			continue
+		}
+		data := unwrapInner(child.Inner, "use")
+		if data == nil {
+			continue
+		}
+		var use struct {
+			Name   string `json:"name"`
+			ID     *int   `json:"id"`
+			IsGlob bool   `json:"is_glob"`
+		}
+		if err := json.Unmarshal(data, &use); err != nil || use.ID == nil || use.Name == "" && !use.IsGlob {
+			continue
+		}
+		uses = append(uses, reexport{name: use.Name, glob: use.IsGlob, target: *use.ID})
+	}
+	return uses
+}
+
+// reexportsFragment lists what a module re-exports, under the names it
+// gives them, each linked to the item it comes from. Globs of this crate's
+// own modules are expanded inline, since their items are otherwise only
+// listed on the module they come from; globs of other crates' modules are
+// linked.
+func reexportsFragment(modulePath string, items []int, crate *RustdocCrate, crateName, version string) *Fragment {
+	uses := moduleUses(items, crate)
+	if len(uses) == 0 {
+		return nil
+	}
+
+	var b strings.Builder
+	b.WriteString("# Re-exports\n\n")
+	for _, u := range uses {
+		summary, ok := crate.Paths[strconv.Itoa(u.target)]
+		if !ok {
+			continue
+		}
+		source := ResolveItemURI(u.target, crate, crateName, version)
+		sourcePath := strings.Join(summary.Path, "::")
+		if !u.glob {
+			local := fmt.Sprintf("rsdoc://%s/%s/%s::%s", crateName, version, modulePath, u.name)
+			writeReexport(&b, "", u.name, local, summary.Kind, sourcePath, source, summaryLine(crate, u.target))
+			continue
+		}
+
+		if source == "" {
+			fmt.Fprintf(&b, "- `%s::*` (glob)\n", sourcePath)
+			continue
+		}
+		fmt.Fprintf(&b, "- [%s::*](%s) (glob)\n", sourcePath, source)
+		if summary.CrateID != 0 {
+			continue
+		}
+		target, ok := crate.Index[strconv.Itoa(u.target)]
+		if !ok {
+			continue
+		}
+		var mod struct {
+			Items []int `json:"items"`
+		}
+		if data := unwrapInner(target.Inner, "module"); data == nil || json.Unmarshal(data, &mod) != nil {
+			continue
+		}
+		for _, id := range mod.Items {
+			child, ok := crate.Index[strconv.Itoa(id)]
+			if !ok || child.Name == nil {
+				continue
+			}
+			kind := innerKind(child.Inner)
+			if kind == "impl" || kind == "use" || kind == "" {
+				continue
+			}
+			uri := ResolveItemURI(id, crate, crateName, version)
+			if uri == "" {
+				continue
+			}
+			local := fmt.Sprintf("rsdoc://%s/%s/%s::%s", crateName, version, modulePath, *child.Name)
+			writeReexport(&b, "  ", *child.Name, local, kind, sourceLabel(uri), uri, summaryLine(crate, id))
+		}
+	}
+	return &Fragment{Name: FragReexports, Content: b.String()}
+}
+
+// writeReexport writes one re-exported item as a list entry.
+func writeReexport(b *strings.Builder, indent, name, uri, kind, sourcePath, source, docs string) {
+	fmt.Fprintf(b, "%s- [%s](%s) (%s", indent, name, uri, kind)
+	if source != "" {
+		fmt.Fprintf(b, ", from [%s](%s)", sourcePath, source)
+	}
+	b.WriteString(")")
+	if docs != "" {
+		b.WriteString(": " + docs)
+	}
+	b.WriteString("\n")
+}
+
+// summaryLine returns the first line of an item's docs, or "" if the item
+// isn't in this crate's index.
+func summaryLine(crate *RustdocCrate, id int) string {
+	item, ok := crate.Index[strconv.Itoa(id)]
+	if !ok || item.Docs == nil {
+		return ""
+	}
+	return strings.SplitN(*item.Docs, "\n", 2)[0]
+}
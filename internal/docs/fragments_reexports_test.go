@@ -0,0 +1,62 @@
+package docs
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestGenerateFragments_ModuleReexports(t *testing.T) {
+	t.Parallel()
+
+	// mycrate::prelude re-exports an item of its own, one from a
+	// dependency, and globs of a module of its own and of a dependency's.
+	items := map[string]RustdocItem{
+		"0": {ID: 0, Name: strPtr("prelude"),
+			Inner: json.RawMessage(`{"module":{"items":[1,2,3,4]}}`)},
+		"1": {ID: 1, Inner: json.RawMessage(`{"use":{"source":"crate::rt::Runtime","name":"Runtime","id":10,"is_glob":false}}`)},
+		"2": {ID: 2, Inner: json.RawMessage(`{"use":{"source":"bytes::Bytes","name":"Bytes","id":20,"is_glob":false}}`)},
+		"3": {ID: 3, Inner: json.RawMessage(`{"use":{"source":"crate::io","name":"io","id":11,"is_glob":true}}`)},
+		"4": {ID: 4, Inner: json.RawMessage(`{"use":{"source":"futures::stream","name":"stream","id":21,"is_glob":true}}`)},
+		"10": {ID: 10, Name: strPtr("Runtime"), Docs: strPtr("The runtime.\n\nMore."),
+			Inner: json.RawMessage(`{"struct":{}}`)},
+		"11": {ID: 11, Name: strPtr("io"),
+			Inner: json.RawMessage(`{"module":{"items":[12,13]}}`)},
+		"12": {ID: 12, Name: strPtr("AsyncRead"), Docs: strPtr("Reads bytes."),
+			Inner: json.RawMessage(`{"trait":{}}`)},
+		"13": {ID: 13, Inner: json.RawMessage(`{"impl":{}}`)},
+	}
+	crate := &RustdocCrate{
+		Index:          items,
+		ExternalCrates: map[string]ExternalCrate{"5": {Name: "bytes"}, "6": {Name: "futures"}},
+		Paths: map[string]RustdocSummary{
+			"0":  {Path: []string{"mycrate", "prelude"}, Kind: "module"},
+			"10": {Path: []string{"mycrate", "rt", "Runtime"}, Kind: "struct"},
+			"11": {Path: []string{"mycrate", "io"}, Kind: "module"},
+			"12": {Path: []string{"mycrate", "io", "AsyncRead"}, Kind: "trait"},
+			"20": {CrateID: 5, Path: []string{"bytes", "Bytes"}, Kind: "struct"},
+			"21": {CrateID: 6, Path: []string{"futures", "stream"}, Kind: "module"},
+		},
+	}
+
+	item := items["0"]
+	var got string
+	for _, f := range GenerateFragments(&item, crate, "mycrate", "1.0.0") {
+		if f.Name == FragReexports {
+			got = f.Content
+		}
+		if strings.Contains(f.Content, "[io]") || strings.Contains(f.Content, "[stream]") {
+			t.Errorf("glob listed as a module in #%s:\n%s", f.Name, f.Content)
+		}
+	}
+
+	want := "# Re-exports\n\n" +
+		"- [Runtime](rsdoc://mycrate/1.0.0/mycrate::prelude::Runtime) (struct, from [mycrate::rt::Runtime](rsdoc://mycrate/1.0.0/mycrate::rt::Runtime)): The runtime.\n" +
+		"- [Bytes](rsdoc://mycrate/1.0.0/mycrate::prelude::Bytes) (struct, from [bytes::Bytes](rsdoc://bytes/latest/bytes::Bytes))\n" +
+		"- [mycrate::io::*](rsdoc://mycrate/1.0.0/mycrate::io) (glob)\n" +
+		"  - [AsyncRead](rsdoc://mycrate/1.0.0/mycrate::prelude::AsyncRead) (trait, from [mycrate::io::AsyncRead](rsdoc://mycrate/1.0.0/mycrate::io::AsyncRead)): Reads bytes.\n" +
+		"- [futures::stream::*](rsdoc://futures/latest/futures::stream) (glob)\n"
+	if got != want {
+		t.Errorf("#re-exports =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -383,8 +383,8 @@ func TestGenerateFragments_Module_ResolvesExternalUseItems(t *testing.T) {
 	item := items["2"]
 	fragments := GenerateFragments(&item, crate, "mycrate", "1.0.0")
 
-	if len(fragments) != 1 {
-		t.Fatalf("expected 1 fragment, got %d", len(fragments))
+	if len(fragments) != 2 || fragments[1].Name != FragReexports {
+		t.Fatalf("expected attribute-macros and re-exports fragments, got %d", len(fragments))
 	}
 	f := fragments[0]
 	if f.Name != "attribute-macros" {
@@ -441,13 +441,13 @@ func TestGenerateFragments_UnknownKind(t *testing.T) {
 
 	item := &RustdocItem{
 		ID:    0,
-		Name:  strPtr("my_fn"),
-		Inner: json.RawMessage(`{"function":{}}`),
+		Name:  strPtr("MY_CONST"),
+		Inner: json.RawMessage(`{"constant":{}}`),
 	}
 	crate := makeCrateWithItems(nil)
 
 	fragments := GenerateFragments(item, crate, "mycrate", "1.0.0")
 	if fragments != nil {
-		t.Errorf("expected nil for function kind, got %v", fragments)
+		t.Errorf("expected nil for constant kind, got %v", fragments)
 	}
 }
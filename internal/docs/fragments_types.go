@@ -56,6 +56,15 @@ func resolveTypeName(typeJSON json.RawMessage, crate *RustdocCrate, crateName, v
 		return formatTuple(tp, crate, crateName, version)
 	}
 
+	if it, ok := outer["impl_trait"]; ok {
+		var bounds []json.RawMessage
+		if err := json.Unmarshal(it, &bounds); err == nil {
+			if s := formatBounds(bounds, crate, crateName, version); s != "" {
+				return "impl " + s
+			}
+		}
+	}
+
 	return ""
 }
 
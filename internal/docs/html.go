@@ -0,0 +1,623 @@
+package docs
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxHTMLPages caps how many item pages the HTML fallback fetches per crate.
+const maxHTMLPages = 1000
+
+// FetchHTMLDocs scrapes the docs.rs HTML pages for a crate whose build has no
+// rustdoc JSON (older releases, failed JSON builds). It returns the resolved
+// version and items with reduced metadata: docs converted to markdown and a
+// plain-text signature, but no fragments, features or re-exports. Links are
+// rewritten to rsdoc:// URIs inline rather than through DocLinks.
+func FetchHTMLDocs(ctx context.Context, reg *Registry, name, version string, progress func(string)) (string, []ParsedItem, error) {
+	if version == "" {
+		version = "latest"
+	}
+	if reg.isTemplate() {
+		return "", nil, fmt.Errorf("HTML fallback needs a docs.rs-style docs_url, not a JSON URL template")
+	}
+
+	// docs.rs redirects /{name}/{version}/ to /{name}/{real version}/{lib}/index.html,
+	// or to the /crate/ info page when there is no documentation at all.
+	rootURL, rootPage, err := fetchHTML(ctx, reg, fmt.Sprintf("%s/%s/%s/", strings.TrimSuffix(reg.DocsURL, "/"), name, version))
+	if err != nil {
+		return "", nil, err
+	}
+	parts := strings.Split(strings.Trim(rootURL.Path, "/"), "/")
+	if len(parts) < 3 || parts[0] == "crate" {
+		return "", nil, fmt.Errorf("docs.rs has no documentation pages for %s/%s", name, version)
+	}
+	realVersion, lib := parts[1], parts[2]
+	base := &url.URL{Scheme: rootURL.Scheme, Host: rootURL.Host, Path: fmt.Sprintf("/%s/%s/%s/", name, realVersion, lib)}
+
+	allURL, _ := base.Parse("all.html")
+	_, allPage, err := fetchHTML(ctx, reg, allURL.String())
+	if err != nil {
+		return "", nil, err
+	}
+	pages := htmlItemPages(parseHTML(allPage), lib)
+	if len(pages) > maxHTMLPages {
+		progress(fmt.Sprintf("%s@%s has %d HTML pages, indexing the first %d", name, realVersion, len(pages), maxHTMLPages))
+		pages = pages[:maxHTMLPages]
+	}
+
+	sig, doc := parseHTMLItemPage(rootPage, base)
+	items := []ParsedItem{htmlParsedItem(htmlPage{href: "index.html", path: lib, name: lib, kind: "module"}, sig, doc)}
+
+	var failed int
+	for i, p := range pages {
+		if err := ctx.Err(); err != nil {
+			return "", nil, err
+		}
+		if i > 0 && i%50 == 0 {
+			progress(fmt.Sprintf("scraped %d/%d HTML pages for %s@%s", i, len(pages), name, realVersion))
+		}
+		pageURL, _ := base.Parse(p.href)
+		_, page, err := fetchHTML(ctx, reg, pageURL.String())
+		if err != nil {
+			failed++
+			continue
+		}
+		sig, doc := parseHTMLItemPage(page, pageURL)
+		items = append(items, htmlParsedItem(p, sig, doc))
+	}
+	if failed > 0 {
+		progress(fmt.Sprintf("skipped %d HTML pages for %s@%s that failed to fetch", failed, name, realVersion))
+	}
+
+	return realVersion, items, nil
+}
+
+func htmlParsedItem(p htmlPage, sig, doc string) ParsedItem {
+	return ParsedItem{
+		RustdocID: "html:" + p.href,
+		Name:      p.name,
+		Path:      p.path,
+		Kind:      p.kind,
+		Docs:      doc,
+		Signature: sig,
+		Examples:  ExtractRustCodeBlocks(doc),
+	}
+}
+
+// fetchHTML GETs a page and returns the final URL after redirects.
+func fetchHTML(ctx context.Context, reg *Registry, rawURL string) (*url.URL, string, error) {
+	req, err := reg.newRequest(ctx, rawURL)
+	if err != nil {
+		return nil, "", err
+	}
+
+	resp, err := do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("fetching %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, "", fmt.Errorf("%s returned %d for %s: %w", req.URL.Host, resp.StatusCode, rawURL, ErrNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("%s returned %d for %s", req.URL.Host, resp.StatusCode, rawURL)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return nil, "", fmt.Errorf("reading %s: %w", rawURL, err)
+	}
+	return resp.Request.URL, string(data), nil
+}
+
+// htmlPage is one item page relative to the crate's documentation root.
+type htmlPage struct {
+	href string
+	path string
+	name string
+	kind string
+}
+
+// htmlKinds maps rustdoc HTML file prefixes (struct.Foo.html) to rustdoc JSON kinds.
+var htmlKinds = map[string]string{
+	"struct":     "struct",
+	"enum":       "enum",
+	"union":      "union",
+	"trait":      "trait",
+	"traitalias": "trait_alias",
+	"fn":         "function",
+	"type":       "type_alias",
+	"constant":   "constant",
+	"static":     "static",
+	"macro":      "macro",
+	"attr":       "proc_attribute",
+	"derive":     "proc_derive",
+}
+
+var itemHrefRe = regexp.MustCompile(`^((?:[A-Za-z0-9_]+/)*)([a-z]+)\.([A-Za-z0-9_]+)\.html$`)
+
+// htmlItemPages collects the item pages linked from all.html, plus an
+// index.html page for every module directory they live in.
+func htmlItemPages(all *htmlNode, lib string) []htmlPage {
+	seen := make(map[string]bool)
+	var pages []htmlPage
+	add := func(p htmlPage) {
+		if !seen[p.href] {
+			seen[p.href] = true
+			pages = append(pages, p)
+		}
+	}
+
+	walkHTML(all, func(n *htmlNode) {
+		if n.tag != "a" {
+			return
+		}
+		m := itemHrefRe.FindStringSubmatch(n.attrs["href"])
+		if m == nil {
+			return
+		}
+		kind, ok := htmlKinds[m[2]]
+		if !ok {
+			return
+		}
+		segments := []string{lib}
+		dir := ""
+		for _, mod := range strings.Split(strings.TrimSuffix(m[1], "/"), "/") {
+			if mod == "" {
+				continue
+			}
+			segments = append(segments, mod)
+			dir += mod + "/"
+			add(htmlPage{href: dir + "index.html", path: strings.Join(segments, "::"), name: mod, kind: "module"})
+		}
+		add(htmlPage{href: n.attrs["href"], path: strings.Join(append(segments, m[3]), "::"), name: m[3], kind: kind})
+	})
+
+	sort.Slice(pages, func(i, j int) bool { return pages[i].path < pages[j].path })
+	return pages
+}
+
+// parseHTMLItemPage extracts the declaration and top-level docs from a rustdoc
+// item page. pageURL resolves relative links.
+func parseHTMLItemPage(page string, pageURL *url.URL) (sig, doc string) {
+	root := parseHTML(page)
+	main := findHTML(root, func(n *htmlNode) bool { return n.attrs["id"] == "main-content" })
+	if main == nil {
+		main = root
+	}
+
+	if decl := findHTML(main, isDeclBlock); decl != nil {
+		sig = strings.TrimSpace(htmlText(decl))
+	}
+	if block := topDocblock(main); block != nil {
+		doc = htmlToMarkdown(block.children, pageURL)
+	}
+	return sig, doc
+}
+
+// topDocblock returns the item's own docblock. Docblocks after the first
+// section heading belong to fields, variants or methods.
+func topDocblock(main *htmlNode) *htmlNode {
+	var found *htmlNode
+	var walk func(n *htmlNode) bool
+	walk = func(n *htmlNode) bool {
+		for _, c := range n.children {
+			if c.tag == "h2" {
+				return false
+			}
+			if c.hasClass("docblock") && !isDeclBlock(c) {
+				found = c
+				return false
+			}
+			if !walk(c) {
+				return false
+			}
+		}
+		return true
+	}
+	walk(main)
+	return found
+}
+
+// isDeclBlock matches the item declaration. Newer rustdoc emits
+// <pre class="rust item-decl">; older versions wrap the <pre> in
+// <div class="docblock item-decl"> or <div class="docblock type-decl">.
+func isDeclBlock(n *htmlNode) bool {
+	return n.hasClass("item-decl") || n.hasClass("type-decl")
+}
+
+// --- HTML parsing ---
+
+// htmlNode is a minimal DOM node. Text nodes have an empty tag.
+type htmlNode struct {
+	tag      string
+	attrs    map[string]string
+	text     string
+	parent   *htmlNode
+	children []*htmlNode
+}
+
+func (n *htmlNode) hasClass(class string) bool {
+	for _, c := range strings.Fields(n.attrs["class"]) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// parseHTML builds a loose DOM from rustdoc output. It is not a conforming
+// HTML parser: unmatched end tags are ignored and unclosed elements are
+// closed by the nearest matching ancestor's end tag.
+func parseHTML(src string) *htmlNode {
+	root := &htmlNode{tag: "#root"}
+	cur := root
+	text := func(s string) {
+		if s != "" {
+			cur.children = append(cur.children, &htmlNode{text: html.UnescapeString(s), parent: cur})
+		}
+	}
+
+	for len(src) > 0 {
+		lt := strings.IndexByte(src, '<')
+		if lt < 0 {
+			text(src)
+			break
+		}
+		text(src[:lt])
+		src = src[lt:]
+
+		switch {
+		case strings.HasPrefix(src, "<!--"):
+			end := strings.Index(src, "-->")
+			if end < 0 {
+				return root
+			}
+			src = src[end+3:]
+		case strings.HasPrefix(src, "<!"), strings.HasPrefix(src, "<?"):
+			end := strings.IndexByte(src, '>')
+			if end < 0 {
+				return root
+			}
+			src = src[end+1:]
+		case strings.HasPrefix(src, "</"):
+			end := strings.IndexByte(src, '>')
+			if end < 0 {
+				return root
+			}
+			name := strings.ToLower(strings.TrimSpace(src[2:end]))
+			src = src[end+1:]
+			for n := cur; n != root; n = n.parent {
+				if n.tag == name {
+					cur = n.parent
+					break
+				}
+			}
+		default:
+			n, selfClosing, rest, ok := parseTag(src)
+			if !ok {
+				text("<")
+				src = src[1:]
+				continue
+			}
+			src = rest
+			n.parent = cur
+			cur.children = append(cur.children, n)
+			if n.tag == "script" || n.tag == "style" {
+				end := strings.Index(src, "</"+n.tag)
+				if end < 0 {
+					return root
+				}
+				src = src[end:]
+				continue
+			}
+			if !selfClosing && !voidElements[n.tag] {
+				cur = n
+			}
+		}
+	}
+	return root
+}
+
+// parseTag parses a start tag at the beginning of src.
+func parseTag(src string) (n *htmlNode, selfClosing bool, rest string, ok bool) {
+	i := 1
+	for i < len(src) && isTagNameByte(src[i]) {
+		i++
+	}
+	if i == 1 {
+		return nil, false, src, false
+	}
+	n = &htmlNode{tag: strings.ToLower(src[1:i]), attrs: map[string]string{}}
+
+	for i < len(src) {
+		for i < len(src) && isHTMLSpace(src[i]) {
+			i++
+		}
+		if i >= len(src) {
+			break
+		}
+		switch {
+		case src[i] == '>':
+			return n, selfClosing, src[i+1:], true
+		case src[i] == '/':
+			selfClosing = true
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(src) && !isHTMLSpace(src[i]) && src[i] != '=' && src[i] != '>' && src[i] != '/' {
+			i++
+		}
+		name := strings.ToLower(src[start:i])
+		value := ""
+		if i < len(src) && src[i] == '=' {
+			i++
+			if i < len(src) && (src[i] == '"' || src[i] == '\'') {
+				quote := src[i]
+				end := strings.IndexByte(src[i+1:], quote)
+				if end < 0 {
+					return nil, false, src, false
+				}
+				value = src[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				start := i
+				for i < len(src) && !isHTMLSpace(src[i]) && src[i] != '>' {
+					i++
+				}
+				value = src[start:i]
+			}
+		}
+		if name != "" {
+			n.attrs[name] = html.UnescapeString(value)
+		}
+		selfClosing = false
+	}
+	return nil, false, src, false
+}
+
+func isTagNameByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b == '-'
+}
+
+func isHTMLSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
+
+func walkHTML(n *htmlNode, fn func(*htmlNode)) {
+	fn(n)
+	for _, c := range n.children {
+		walkHTML(c, fn)
+	}
+}
+
+// findHTML returns the first node in document order matching pred.
+func findHTML(n *htmlNode, pred func(*htmlNode) bool) *htmlNode {
+	if pred(n) {
+		return n
+	}
+	for _, c := range n.children {
+		if found := findHTML(c, pred); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// htmlText concatenates all text below n without collapsing whitespace.
+func htmlText(n *htmlNode) string {
+	if n.tag == "" {
+		return n.text
+	}
+	var b strings.Builder
+	for _, c := range n.children {
+		b.WriteString(htmlText(c))
+	}
+	return b.String()
+}
+
+// --- HTML to markdown ---
+
+var blockElements = map[string]bool{
+	"p": true, "div": true, "section": true, "details": true, "summary": true,
+	"pre": true, "ul": true, "ol": true, "blockquote": true, "table": true, "hr": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"dl": true, "dt": true, "dd": true,
+}
+
+var skippedElements = map[string]bool{"script": true, "style": true, "button": true, "img": true}
+
+var htmlSpaceRe = regexp.MustCompile(`\s+`)
+
+// htmlToMarkdown converts rendered rustdoc markdown back to markdown. docs.rs
+// links become rsdoc:// URIs; same-page anchors are dropped.
+func htmlToMarkdown(nodes []*htmlNode, pageURL *url.URL) string {
+	var parts []string
+	var inline strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(inline.String()); s != "" {
+			parts = append(parts, s)
+		}
+		inline.Reset()
+	}
+
+	for _, n := range nodes {
+		if n.tag != "" && blockElements[n.tag] {
+			flush()
+			if s := htmlBlock(n, pageURL); s != "" {
+				parts = append(parts, s)
+			}
+			continue
+		}
+		inline.WriteString(htmlInline(n, pageURL))
+	}
+	flush()
+	return strings.Join(parts, "\n\n")
+}
+
+func htmlBlock(n *htmlNode, pageURL *url.URL) string {
+	switch n.tag {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level := int(n.tag[1] - '0')
+		return strings.Repeat("#", level) + " " + strings.TrimSpace(htmlInlineChildren(n, pageURL))
+	case "pre":
+		return "```" + codeLang(n) + "\n" + strings.TrimRight(htmlText(n), "\n") + "\n```"
+	case "hr":
+		return "---"
+	case "ul", "ol":
+		var lines []string
+		i := 0
+		for _, li := range n.children {
+			if li.tag != "li" {
+				continue
+			}
+			i++
+			marker := "- "
+			if n.tag == "ol" {
+				marker = fmt.Sprintf("%d. ", i)
+			}
+			body := strings.Split(htmlToMarkdown(li.children, pageURL), "\n")
+			indent := strings.Repeat(" ", len(marker))
+			for j := 1; j < len(body); j++ {
+				if body[j] != "" {
+					body[j] = indent + body[j]
+				}
+			}
+			lines = append(lines, marker+strings.Join(body, "\n"))
+		}
+		return strings.Join(lines, "\n")
+	case "blockquote":
+		lines := strings.Split(htmlToMarkdown(n.children, pageURL), "\n")
+		for i, l := range lines {
+			lines[i] = strings.TrimRight("> "+l, " ")
+		}
+		return strings.Join(lines, "\n")
+	case "table":
+		var rows []string
+		walkHTML(n, func(tr *htmlNode) {
+			if tr.tag != "tr" {
+				return
+			}
+			var cells []string
+			for _, c := range tr.children {
+				if c.tag == "td" || c.tag == "th" {
+					cells = append(cells, strings.ReplaceAll(strings.TrimSpace(htmlInlineChildren(c, pageURL)), "|", `\|`))
+				}
+			}
+			rows = append(rows, "| "+strings.Join(cells, " | ")+" |")
+			if len(rows) == 1 {
+				rows = append(rows, "|"+strings.Repeat(" --- |", len(cells)))
+			}
+		})
+		return strings.Join(rows, "\n")
+	default:
+		return htmlToMarkdown(n.children, pageURL)
+	}
+}
+
+func htmlInline(n *htmlNode, pageURL *url.URL) string {
+	if n.tag == "" {
+		return htmlSpaceRe.ReplaceAllString(n.text, " ")
+	}
+	if skippedElements[n.tag] {
+		return ""
+	}
+	switch n.tag {
+	case "br":
+		return "\n"
+	case "code":
+		code := htmlText(n)
+		if strings.Contains(code, "`") {
+			return "`` " + code + " ``"
+		}
+		return "`" + code + "`"
+	case "em", "i":
+		return wrapInline(htmlInlineChildren(n, pageURL), "*")
+	case "strong", "b":
+		return wrapInline(htmlInlineChildren(n, pageURL), "**")
+	case "a":
+		if n.hasClass("doc-anchor") || n.hasClass("anchor") {
+			return ""
+		}
+		text := htmlInlineChildren(n, pageURL)
+		target := resolveHTMLLink(n.attrs["href"], pageURL)
+		if target == "" || strings.TrimSpace(text) == "" {
+			return text
+		}
+		return "[" + text + "](" + target + ")"
+	default:
+		return htmlInlineChildren(n, pageURL)
+	}
+}
+
+func htmlInlineChildren(n *htmlNode, pageURL *url.URL) string {
+	var b strings.Builder
+	for _, c := range n.children {
+		b.WriteString(htmlInline(c, pageURL))
+	}
+	return b.String()
+}
+
+// wrapInline wraps text in emphasis markers, keeping surrounding spaces outside.
+func wrapInline(text, marker string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	lead := text[:strings.Index(text, trimmed)]
+	trail := text[len(lead)+len(trimmed):]
+	return lead + marker + trimmed + marker + trail
+}
+
+// codeLang picks the fence language for a <pre> block.
+func codeLang(pre *htmlNode) string {
+	for _, n := range []*htmlNode{pre, findHTML(pre, func(n *htmlNode) bool { return n.tag == "code" })} {
+		if n == nil {
+			continue
+		}
+		for _, c := range strings.Fields(n.attrs["class"]) {
+			if c == "rust" {
+				return "rust"
+			}
+			if lang, ok := strings.CutPrefix(c, "language-"); ok {
+				return lang
+			}
+		}
+	}
+	return ""
+}
+
+// resolveHTMLLink makes href absolute against the page, converting docs.rs
+// and same-host links to rsdoc:// URIs. Same-page anchors and non-web links return "".
+func resolveHTMLLink(href string, pageURL *url.URL) string {
+	if href == "" || strings.HasPrefix(href, "#") {
+		return ""
+	}
+	u, err := pageURL.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if u.Host == "docs.rs" || u.Host == pageURL.Host {
+		if uri := docsRsToRsdoc(u.String()); uri != "" {
+			return uri
+		}
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	return u.String()
+}
@@ -0,0 +1,92 @@
+package docs
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const htmlStructPage = `<!DOCTYPE html><html><head><script>if (a < b) {}</script></head><body>
+<section id="main-content" class="content">
+<div class="main-heading"><h1>Struct <span class="struct">Widget</span></h1></div>
+<pre class="rust item-decl"><code>pub struct Widget {
+    pub size: <a class="primitive" href="https://doc.rust-lang.org/nightly/std/primitive.usize.html">usize</a>,
+}</code></pre>
+<details class="toggle top-doc" open><summary class="hideme"><span>Expand description</span></summary>
+<div class="docblock"><p>A <em>small</em> widget built by <a href="fn.build.html"><code>build</code></a>.
+See <a href="https://docs.rs/other/1.0.0/other/struct.Thing.html">Thing</a> and <a href="#examples">below</a>.</p>
+<h2 id="examples"><a class="doc-anchor" href="#examples">§</a>Examples</h2>
+<div class="example-wrap"><pre class="rust rust-example-rendered"><code><span class="kw">let </span>w = Widget { size: <span class="number">1</span> };
+</code></pre></div>
+<ul><li>first &amp; foremost</li><li>second<ul><li>nested</li></ul></li></ul>
+<table><thead><tr><th>A</th><th>B</th></tr></thead><tbody><tr><td>1</td><td>x|y</td></tr></tbody></table>
+</div></details>
+<h2 id="fields" class="fields section-header">Fields</h2>
+<span id="structfield.size" class="structfield section-header"><code>size: usize</code></span>
+<div class="docblock"><p>Field docs.</p></div>
+</section></body></html>`
+
+func TestParseHTMLItemPage(t *testing.T) {
+	t.Parallel()
+	pageURL, _ := url.Parse("https://docs.rs/widgets/0.3.1/widgets/struct.Widget.html")
+	sig, doc := parseHTMLItemPage(htmlStructPage, pageURL)
+
+	if sig != "pub struct Widget {\n    pub size: usize,\n}" {
+		t.Errorf("unexpected signature: %q", sig)
+	}
+
+	want := "A *small* widget built by [`build`](rsdoc://widgets/0.3.1/widgets::build). " +
+		"See [Thing](rsdoc://other/1.0.0/other::Thing) and below.\n\n" +
+		"## Examples\n\n" +
+		"```rust\nlet w = Widget { size: 1 };\n```\n\n" +
+		"- first & foremost\n- second\n\n  - nested\n\n" +
+		"| A | B |\n| --- | --- |\n| 1 | x\\|y |"
+	if doc != want {
+		t.Errorf("unexpected docs:\n%s\n--- want ---\n%s", doc, want)
+	}
+	if strings.Contains(doc, "Field docs") {
+		t.Error("field docs leaked into the item docs")
+	}
+	if ex := ExtractRustCodeBlocks(doc); len(ex) != 1 {
+		t.Errorf("expected 1 example, got %d", len(ex))
+	}
+}
+
+func TestParseHTMLItemPage_NoDocs(t *testing.T) {
+	t.Parallel()
+	page := `<section id="main-content"><pre class="rust item-decl"><code>pub fn f()</code></pre>
+<h2 class="section-header">Implementations</h2><div class="docblock"><p>Method docs.</p></div></section>`
+	pageURL, _ := url.Parse("https://docs.rs/a/1.0.0/a/fn.f.html")
+	sig, doc := parseHTMLItemPage(page, pageURL)
+	if sig != "pub fn f()" || doc != "" {
+		t.Errorf("got sig %q docs %q", sig, doc)
+	}
+}
+
+func TestHTMLItemPages(t *testing.T) {
+	t.Parallel()
+	all := parseHTML(`<section id="main-content"><h3 id="structs">Structs</h3><ul class="all-items">
+<li><a href="de/value/struct.Deserializer.html">de::value::Deserializer</a></li>
+<li><a href="struct.Top.html">Top</a></li>
+<li><a href="fn.run.html">run</a></li>
+<li><a href="derive.Serialize.html">Serialize</a></li>
+<li><a href="primitive.str.html">str</a></li>
+<li><a href="https://example.com/">elsewhere</a></li>
+</ul></section>`)
+
+	var got []string
+	for _, p := range htmlItemPages(all, "serde") {
+		got = append(got, p.kind+" "+p.path+" "+p.href)
+	}
+	want := []string{
+		"proc_derive serde::Serialize derive.Serialize.html",
+		"struct serde::Top struct.Top.html",
+		"module serde::de de/index.html",
+		"module serde::de::value de/value/index.html",
+		"struct serde::de::value::Deserializer de/value/struct.Deserializer.html",
+		"function serde::run fn.run.html",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected pages:\n%s\n--- want ---\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,92 @@
+package docs
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Ignore describes items to leave out of the index. Leaving out an item
+// leaves out everything nested in it too: a module's items, a type's
+// methods.
+type Ignore struct {
+	// Patterns match item paths, where "*" matches any run of characters,
+	// "::" included: "*::__private" or "tokio::runtime::*".
+	Patterns []string
+	// Hidden leaves out #[doc(hidden)] items.
+	Hidden bool
+	// Deprecated leaves out #[deprecated] items.
+	Deprecated bool
+}
+
+// IsEmpty reports whether ig leaves out nothing.
+func (ig Ignore) IsEmpty() bool {
+	return len(ig.Patterns) == 0 && !ig.Hidden && !ig.Deprecated
+}
+
+// compilePattern turns an ignore pattern into a regexp matching whole paths.
+func compilePattern(pattern string) *regexp.Regexp {
+	quoted := strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*")
+	return regexp.MustCompile("^" + quoted + "$")
+}
+
+// FilterItems returns the items ig doesn't leave out and how many it did.
+// crate supplies the attributes ig.Hidden looks at; with a nil crate (docs
+// scraped from HTML) hidden items can't be told apart.
+func FilterItems(crate *RustdocCrate, items []ParsedItem, ig Ignore) ([]ParsedItem, int) {
+	if ig.IsEmpty() || len(items) == 0 {
+		return items, 0
+	}
+	patterns := make([]*regexp.Regexp, len(ig.Patterns))
+	for i, p := range ig.Patterns {
+		patterns[i] = compilePattern(p)
+	}
+
+	flagged := make(map[string]bool)
+	for _, it := range items {
+		if ig.Deprecated && it.Deprecation != nil {
+			flagged[it.Path] = true
+		}
+		if ig.Hidden && crate != nil {
+			if item, ok := crate.Index[it.RustdocID]; ok && isDocHidden(&item) {
+				flagged[it.Path] = true
+			}
+		}
+	}
+
+	ignored := func(path string) bool {
+		// Check the path and each of its parents: a::b::c, a::b, a.
+		for p := path; ; {
+			if flagged[p] {
+				return true
+			}
+			for _, re := range patterns {
+				if re.MatchString(p) {
+					return true
+				}
+			}
+			i := strings.LastIndex(p, "::")
+			if i < 0 {
+				return false
+			}
+			p = p[:i]
+		}
+	}
+
+	kept := items[:0:0]
+	for _, it := range items {
+		if !ignored(it.Path) {
+			kept = append(kept, it)
+		}
+	}
+	return kept, len(items) - len(kept)
+}
+
+// isDocHidden reports whether item has a #[doc(hidden)] attribute.
+func isDocHidden(item *RustdocItem) bool {
+	for _, attr := range attrStrings(item.Attrs) {
+		if strings.Contains(strings.ReplaceAll(attr, " ", ""), "doc(hidden)") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package docs
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestFilterItems(t *testing.T) {
+	t.Parallel()
+	crate := makeCrateWithItems(map[string]RustdocItem{
+		"1": {ID: 1},
+		"2": {ID: 2, Attrs: []json.RawMessage{json.RawMessage(`{"other":"#[doc(hidden)]"}`)}},
+		"3": {ID: 3},
+		"4": {ID: 4},
+		"5": {ID: 5},
+		"6": {ID: 6, Attrs: []json.RawMessage{json.RawMessage(`"#[doc(hidden)]"`)}},
+	})
+	items := []ParsedItem{
+		{RustdocID: "1", Path: "mycrate::Client"},
+		{RustdocID: "2", Path: "mycrate::__private"},
+		{RustdocID: "3", Path: "mycrate::__private::Helper"},
+		{RustdocID: "4", Path: "mycrate::Client::old_send", Deprecation: &Deprecation{Since: strPtr("1.2.0")}},
+		{RustdocID: "5", Path: "mycrate::test_utils::mock"},
+		{RustdocID: "6", Path: "mycrate::Client::internal"},
+	}
+	paths := func(items []ParsedItem) []string {
+		var out []string
+		for _, it := range items {
+			out = append(out, it.Path)
+		}
+		return out
+	}
+
+	for _, c := range []struct {
+		name   string
+		crate  *RustdocCrate
+		ignore Ignore
+		want   []string
+	}{
+		{"nothing", crate, Ignore{}, paths(items)},
+		{"hidden", crate, Ignore{Hidden: true}, []string{"mycrate::Client", "mycrate::Client::old_send", "mycrate::test_utils::mock"}},
+		{"deprecated", crate, Ignore{Deprecated: true}, []string{"mycrate::Client", "mycrate::__private", "mycrate::__private::Helper", "mycrate::test_utils::mock", "mycrate::Client::internal"}},
+		{"pattern", crate, Ignore{Patterns: []string{"*::test_utils"}}, []string{"mycrate::Client", "mycrate::__private", "mycrate::__private::Helper", "mycrate::Client::old_send", "mycrate::Client::internal"}},
+		{"pattern with wildcard child", crate, Ignore{Patterns: []string{"mycrate::Client::*"}}, []string{"mycrate::Client", "mycrate::__private", "mycrate::__private::Helper", "mycrate::test_utils::mock"}},
+		{"parent pattern", crate, Ignore{Patterns: []string{"mycrate::Client"}}, []string{"mycrate::__private", "mycrate::__private::Helper", "mycrate::test_utils::mock"}},
+		{"no crate", nil, Ignore{Hidden: true, Deprecated: true, Patterns: []string{"*::test_utils::*"}}, []string{"mycrate::Client", "mycrate::__private", "mycrate::__private::Helper", "mycrate::Client::internal"}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			kept, dropped := FilterItems(c.crate, items, c.ignore)
+			if got := paths(kept); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("kept %v, want %v", got, c.want)
+			}
+			if dropped != len(items)-len(c.want) {
+				t.Errorf("dropped %d, want %d", dropped, len(items)-len(c.want))
+			}
+		})
+	}
+}
@@ -48,7 +48,7 @@ func ResolveItemURI(itemID int, crate *RustdocCrate, crateName, version string)
 	if depName == "" {
 		return ""
 	}
-	return fmt.Sprintf("rsdoc://%s/latest/%s", depName, fullPath)
+	return fmt.Sprintf("rsdoc://%s/%s/%s", depName, crate.ExternalCrateVersion(summary.CrateID), fullPath)
 }
 
 // ExternalCrateName looks up the Cargo package name for a dependency by crate_id.
@@ -66,10 +66,43 @@ func (c *RustdocCrate) ExternalCrateName(crateID int) string {
 	return ext.Name
 }
 
+// ExternalCrateVersion returns the dependency version recorded in a crate's
+// docs.rs html_root_url, or "latest" when it's unknown.
+func (c *RustdocCrate) ExternalCrateVersion(crateID int) string {
+	ext, ok := c.ExternalCrates[strconv.Itoa(crateID)]
+	if !ok {
+		return "latest"
+	}
+	if v := docsRsRootVersion(ext.HTMLRootURL); v != "" {
+		return v
+	}
+	return "latest"
+}
+
+// docsRsRootVersion returns the concrete version in a docs.rs html_root_url,
+// or "" if it has none.
+func docsRsRootVersion(rootURL string) string {
+	m := docsRsCrateVersionRe.FindStringSubmatch(rootURL)
+	if len(m) < 2 || !semverish(m[1]) {
+		return ""
+	}
+	return m[1]
+}
+
 // docsRsCrateNameRe extracts the crate name from a docs.rs html_root_url.
 // Example: "https://docs.rs/tracing-core/0.1.36/x86_64-unknown-linux-gnu/" → "tracing-core"
 var docsRsCrateNameRe = regexp.MustCompile(`^https?://docs\.rs/([^/]+)/`)
 
+// docsRsCrateVersionRe extracts the version from a docs.rs html_root_url.
+// Example: "https://docs.rs/tracing-core/0.1.36/x86_64-unknown-linux-gnu/" → "0.1.36"
+var docsRsCrateVersionRe = regexp.MustCompile(`^https?://docs\.rs/[^/]+/([^/]+)`)
+
+// semverish reports whether v looks like a concrete version (digits and dots,
+// optionally with a pre-release or build suffix) rather than "latest" or "*".
+func semverish(v string) bool {
+	return v != "" && v[0] >= '0' && v[0] <= '9' && strings.Count(v, ".") >= 1
+}
+
 func extractDocsRsCrateName(rootURL string) string {
 	m := docsRsCrateNameRe.FindStringSubmatch(rootURL)
 	if len(m) < 2 {
@@ -157,3 +190,22 @@ func docsRsToRsdoc(rawURL string) string {
 	rustPath := strings.Join(segments, "::")
 	return fmt.Sprintf("rsdoc://%s/%s/%s", crateName, version, rustPath)
 }
+
+// rsdocURIRe matches rsdoc:// URIs in markdown, whether link targets or bare
+// list entries such as those under "Types Used".
+var rsdocURIRe = regexp.MustCompile(`rsdoc://[^\s)\]>"]+`)
+
+// LinkedURIs returns the distinct rsdoc:// URIs in markdown in order of first
+// appearance, with fragments stripped.
+func LinkedURIs(markdown string) []string {
+	var uris []string
+	seen := make(map[string]bool)
+	for _, uri := range rsdocURIRe.FindAllString(markdown, -1) {
+		uri, _, _ = strings.Cut(uri, "#")
+		if !seen[uri] {
+			seen[uri] = true
+			uris = append(uris, uri)
+		}
+	}
+	return uris
+}
@@ -89,3 +89,49 @@ and [serde](https://docs.rs/serde/latest/serde/) for more info.`
 		}
 	}
 }
+
+func TestResolveItemURI_ExternalVersion(t *testing.T) {
+	t.Parallel()
+	crate := &RustdocCrate{
+		ExternalCrates: map[string]ExternalCrate{
+			"1": {Name: "tracing_core", HTMLRootURL: "https://docs.rs/tracing-core/0.1.36/x86_64-unknown-linux-gnu/"},
+			"2": {Name: "serde", HTMLRootURL: "https://docs.rs/serde/latest/"},
+			"3": {Name: "libc"},
+		},
+		Paths: map[string]RustdocSummary{
+			"10": {CrateID: 1, Path: []string{"tracing_core", "Event"}},
+			"11": {CrateID: 2, Path: []string{"serde", "Serialize"}},
+			"12": {CrateID: 3, Path: []string{"libc", "c_int"}},
+		},
+	}
+
+	tests := []struct {
+		id   int
+		want string
+	}{
+		{10, "rsdoc://tracing-core/0.1.36/tracing_core::Event"},
+		{11, "rsdoc://serde/latest/serde::Serialize"},
+		{12, "rsdoc://libc/latest/libc::c_int"},
+	}
+	for _, tt := range tests {
+		if got := ResolveItemURI(tt.id, crate, "mycrate", "1.0.0"); got != tt.want {
+			t.Errorf("ResolveItemURI(%d) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestLinkedURIs(t *testing.T) {
+	t.Parallel()
+	md := "See [`Value`](rsdoc://serde_json/1.0.0/serde_json::Value) and [Value](rsdoc://serde_json/1.0.0/serde_json::Value#variants).\n\n" +
+		"## Types Used\n\n- rsdoc://serde/1.0.0/serde::Serializer\n"
+	got := LinkedURIs(md)
+	want := []string{"rsdoc://serde_json/1.0.0/serde_json::Value", "rsdoc://serde/1.0.0/serde::Serializer"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
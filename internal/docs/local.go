@@ -0,0 +1,54 @@
+package docs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// DecodeRustdocJSON returns rustdoc JSON as-is, or decompressed if it is
+// zstd-compressed as served by docs.rs.
+func DecodeRustdocJSON(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, zstdMagic) {
+		return data, nil
+	}
+	decoder, err := zstd.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("creating zstd decoder: %w", err)
+	}
+	defer decoder.Close()
+
+	out, err := io.ReadAll(decoder)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing rustdoc JSON: %w", err)
+	}
+	return out, nil
+}
+
+// CrateIdentity reads the crate name and version from decoded rustdoc JSON.
+// The name is the library name of the root module, so hyphens in the package
+// name come back as underscores. Version is empty if rustdoc wasn't given one.
+func CrateIdentity(data []byte) (name, version string, err error) {
+	var crate struct {
+		Root         int     `json:"root"`
+		CrateVersion *string `json:"crate_version"`
+		Index        map[string]struct {
+			Name *string `json:"name"`
+		} `json:"index"`
+	}
+	if err := json.Unmarshal(data, &crate); err != nil {
+		return "", "", fmt.Errorf("unmarshaling rustdoc JSON: %w", err)
+	}
+	root, ok := crate.Index[strconv.Itoa(crate.Root)]
+	if !ok || root.Name == nil {
+		return "", "", fmt.Errorf("rustdoc JSON has no root module")
+	}
+	if crate.CrateVersion != nil {
+		version = *crate.CrateVersion
+	}
+	return *root.Name, version, nil
+}
@@ -0,0 +1,44 @@
+package docs
+
+import (
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const localJSON = `{"root":0,"crate_version":"0.3.0","index":{"0":{"id":0,"crate_id":0,"name":"my_crate"}}}`
+
+func TestDecodeRustdocJSON(t *testing.T) {
+	t.Parallel()
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed := enc.EncodeAll([]byte(localJSON), nil)
+	enc.Close()
+
+	for name, in := range map[string][]byte{"plain": []byte(localJSON), "zstd": compressed} {
+		got, err := DecodeRustdocJSON(in)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if string(got) != localJSON {
+			t.Errorf("%s: got %q", name, got)
+		}
+	}
+}
+
+func TestCrateIdentity(t *testing.T) {
+	t.Parallel()
+	name, version, err := CrateIdentity([]byte(localJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "my_crate" || version != "0.3.0" {
+		t.Errorf("got %s@%s", name, version)
+	}
+
+	if _, _, err := CrateIdentity([]byte(`{"root":1,"index":{}}`)); err == nil {
+		t.Error("expected error for missing root module")
+	}
+}
@@ -0,0 +1,231 @@
+package docs
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// crates.io's index, as Cargo.lock records it for the git and sparse
+// protocols.
+var cratesIOSources = []string{
+	"registry+https://github.com/rust-lang/crates.io-index",
+	"sparse+https://index.crates.io/",
+}
+
+// LockedDependency is a crates.io dependency of a project at the version its
+// Cargo.lock resolved.
+type LockedDependency struct {
+	Name    string
+	Version string
+}
+
+type cargoLock struct {
+	Package []lockedPackage `toml:"package"`
+}
+
+type lockedPackage struct {
+	Name    string `toml:"name"`
+	Version string `toml:"version"`
+	// Source is empty for the project's own (path) packages.
+	Source string `toml:"source"`
+	// Dependencies are "name", or "name version" and "name version
+	// (source)" where the name alone is ambiguous.
+	Dependencies []string `toml:"dependencies"`
+}
+
+// FindLockfile returns the Cargo.lock for the Cargo.toml at manifest: next to
+// it, or for a workspace member, in the nearest directory above that has
+// one. When there is none yet it returns the path next to the manifest,
+// where cargo will write it.
+func FindLockfile(manifest string) (string, error) {
+	manifest, err := filepath.Abs(manifest)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(manifest); err != nil {
+		return "", err
+	}
+	start := filepath.Dir(manifest)
+	for dir := start; ; {
+		lockfile := filepath.Join(dir, "Cargo.lock")
+		if _, err := os.Stat(lockfile); err == nil {
+			return lockfile, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return filepath.Join(start, "Cargo.lock"), nil
+		}
+		dir = parent
+	}
+}
+
+// DirectDependencies returns the crates.io crates the project's own packages
+// depend on directly, normal, build and dev dependencies alike, at their
+// locked versions and sorted by name. Path and git dependencies, and crates
+// from other registries, are left out.
+func DirectDependencies(lockfile []byte) ([]LockedDependency, error) {
+	lock, err := parseLockfile(lockfile)
+	if err != nil {
+		return nil, err
+	}
+	deps := lock.direct()
+	sort.Slice(deps, func(i, j int) bool {
+		if deps[i].Name != deps[j].Name {
+			return deps[i].Name < deps[j].Name
+		}
+		return deps[i].Version < deps[j].Version
+	})
+	return deps, nil
+}
+
+// LockedVersion returns the version of crate the lockfile resolves. crate
+// may be spelled as in a Rust path, with "_" for "-". When several versions
+// are locked, the one the project depends on directly wins, then the newest.
+func LockedVersion(lockfile []byte, crate string) (LockedDependency, error) {
+	lock, err := parseLockfile(lockfile)
+	if err != nil {
+		return LockedDependency{}, err
+	}
+	want := strings.ReplaceAll(crate, "-", "_")
+	var candidates []lockedPackage
+	for _, p := range lock.Package {
+		if strings.ReplaceAll(p.Name, "-", "_") == want {
+			candidates = append(candidates, p)
+		}
+	}
+	if len(candidates) == 0 {
+		return LockedDependency{}, fmt.Errorf("%s is not in Cargo.lock", crate)
+	}
+
+	direct := make(map[LockedDependency]bool)
+	for _, d := range lock.direct() {
+		direct[d] = true
+	}
+	var best *lockedPackage
+	for i, c := range candidates {
+		if !fromCratesIO(c.Source) {
+			continue
+		}
+		if best == nil {
+			best = &candidates[i]
+			continue
+		}
+		cDirect := direct[LockedDependency{Name: c.Name, Version: c.Version}]
+		bestDirect := direct[LockedDependency{Name: best.Name, Version: best.Version}]
+		if cDirect != bestDirect {
+			if cDirect {
+				best = &candidates[i]
+			}
+			continue
+		}
+		if compareVersions(c.Version, best.Version) > 0 {
+			best = &candidates[i]
+		}
+	}
+	if best == nil {
+		return LockedDependency{}, fmt.Errorf("%s is not a crates.io dependency", candidates[0].Name)
+	}
+	return LockedDependency{Name: best.Name, Version: best.Version}, nil
+}
+
+func parseLockfile(lockfile []byte) (*cargoLock, error) {
+	var lock cargoLock
+	if err := toml.Unmarshal(lockfile, &lock); err != nil {
+		return nil, fmt.Errorf("parsing Cargo.lock: %w", err)
+	}
+	return &lock, nil
+}
+
+// direct returns the crates.io dependencies of the project's own packages,
+// deduplicated.
+func (lock *cargoLock) direct() []LockedDependency {
+	byName := make(map[string][]lockedPackage)
+	for _, p := range lock.Package {
+		byName[p.Name] = append(byName[p.Name], p)
+	}
+	seen := make(map[LockedDependency]bool)
+	var deps []LockedDependency
+	for _, p := range lock.Package {
+		if p.Source != "" {
+			continue
+		}
+		for _, d := range p.Dependencies {
+			dep, ok := resolveLocked(d, byName)
+			if !ok || !fromCratesIO(dep.Source) {
+				continue
+			}
+			key := LockedDependency{Name: dep.Name, Version: dep.Version}
+			if !seen[key] {
+				seen[key] = true
+				deps = append(deps, key)
+			}
+		}
+	}
+	return deps
+}
+
+// compareVersions orders two semver versions by their numeric parts; a
+// pre-release sorts before its release.
+func compareVersions(a, b string) int {
+	aCore, aPre, _ := strings.Cut(a, "-")
+	bCore, bPre, _ := strings.Cut(b, "-")
+	aParts, bParts := strings.Split(aCore, "."), strings.Split(bCore, ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var x, y int
+		if i < len(aParts) {
+			x, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			y, _ = strconv.Atoi(bParts[i])
+		}
+		if x != y {
+			return cmp.Compare(x, y)
+		}
+	}
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	return strings.Compare(aPre, bPre)
+}
+
+// resolveLocked finds the package a dependencies entry refers to.
+func resolveLocked(entry string, byName map[string][]lockedPackage) (lockedPackage, bool) {
+	fields := strings.Fields(entry)
+	if len(fields) == 0 {
+		return lockedPackage{}, false
+	}
+	candidates := byName[fields[0]]
+	if len(fields) == 1 {
+		if len(candidates) != 1 {
+			return lockedPackage{}, false
+		}
+		return candidates[0], true
+	}
+	for _, c := range candidates {
+		if c.Version == fields[1] {
+			return c, true
+		}
+	}
+	return lockedPackage{}, false
+}
+
+func fromCratesIO(source string) bool {
+	for _, s := range cratesIOSources {
+		if source == s {
+			return true
+		}
+	}
+	return false
+}
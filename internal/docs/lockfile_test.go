@@ -0,0 +1,167 @@
+package docs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testLockfile = `# This file is automatically @generated by Cargo.
+version = 4
+
+[[package]]
+name = "app"
+version = "0.1.0"
+dependencies = [
+ "helper",
+ "rand 0.8.5",
+ "serde",
+]
+
+[[package]]
+name = "helper"
+version = "0.1.0"
+dependencies = [
+ "rand 0.9.0",
+ "private",
+ "forked",
+]
+
+[[package]]
+name = "private"
+version = "2.0.0"
+source = "registry+https://my-registry.example/index"
+
+[[package]]
+name = "forked"
+version = "1.0.0"
+source = "git+https://github.com/someone/forked#abc123"
+
+[[package]]
+name = "rand"
+version = "0.8.5"
+source = "registry+https://github.com/rust-lang/crates.io-index"
+dependencies = [
+ "rand_core",
+]
+
+[[package]]
+name = "rand"
+version = "0.9.0"
+source = "sparse+https://index.crates.io/"
+
+[[package]]
+name = "rand_core"
+version = "0.6.4"
+source = "registry+https://github.com/rust-lang/crates.io-index"
+
+[[package]]
+name = "serde"
+version = "1.0.210"
+source = "registry+https://github.com/rust-lang/crates.io-index"
+`
+
+func TestDirectDependencies(t *testing.T) {
+	t.Parallel()
+	deps, err := DirectDependencies([]byte(testLockfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []LockedDependency{{"rand", "0.8.5"}, {"rand", "0.9.0"}, {"serde", "1.0.210"}}
+	if !reflect.DeepEqual(deps, want) {
+		t.Errorf("got %v, want %v", deps, want)
+	}
+
+	if _, err := DirectDependencies([]byte("[[package]\n")); err == nil {
+		t.Error("parsed a malformed lockfile")
+	}
+}
+
+func TestLockedVersion(t *testing.T) {
+	t.Parallel()
+	lock := []byte(testLockfile + `
+[[package]]
+name = "rand_core"
+version = "0.10.0"
+source = "registry+https://github.com/rust-lang/crates.io-index"
+
+[[package]]
+name = "rand_core"
+version = "0.10.0-beta.1"
+source = "registry+https://github.com/rust-lang/crates.io-index"
+`)
+	for _, tt := range []struct {
+		crate string
+		want  LockedDependency
+	}{
+		{"serde", LockedDependency{"serde", "1.0.210"}},
+		// Both locked rand versions are direct dependencies; the newest wins.
+		{"rand", LockedDependency{"rand", "0.9.0"}},
+		{"rand_core", LockedDependency{"rand_core", "0.10.0"}},
+	} {
+		got, err := LockedVersion(lock, tt.crate)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %v, %v, want %v", tt.crate, got, err, tt.want)
+		}
+	}
+
+	for _, crate := range []string{"tokio", "forked", "app"} {
+		if got, err := LockedVersion(lock, crate); err == nil {
+			t.Errorf("%s: got %v, want an error", crate, got)
+		}
+	}
+}
+
+func TestLockedVersionHyphenated(t *testing.T) {
+	t.Parallel()
+	lock := []byte(`
+[[package]]
+name = "app"
+version = "0.1.0"
+dependencies = ["serde-json 1.0.0"]
+
+[[package]]
+name = "serde-json"
+version = "2.0.0"
+source = "registry+https://github.com/rust-lang/crates.io-index"
+
+[[package]]
+name = "serde-json"
+version = "1.0.0"
+source = "registry+https://github.com/rust-lang/crates.io-index"
+`)
+	// The direct dependency beats the newer transitive one.
+	got, err := LockedVersion(lock, "serde_json")
+	if want := (LockedDependency{"serde-json", "1.0.0"}); err != nil || got != want {
+		t.Errorf("got %v, %v, want %v", got, err, want)
+	}
+}
+
+func TestFindLockfile(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	member := filepath.Join(root, "crates", "member")
+	if err := os.MkdirAll(member, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{filepath.Join(root, "Cargo.toml"), filepath.Join(member, "Cargo.toml")} {
+		if err := os.WriteFile(f, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// No lockfile yet: where cargo will write it.
+	if got, err := FindLockfile(filepath.Join(member, "Cargo.toml")); err != nil || got != filepath.Join(member, "Cargo.lock") {
+		t.Errorf("without a lockfile: got %q, %v", got, err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "Cargo.lock"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := FindLockfile(filepath.Join(member, "Cargo.toml")); err != nil || got != filepath.Join(root, "Cargo.lock") {
+		t.Errorf("workspace member: got %q, %v", got, err)
+	}
+	if _, err := FindLockfile(filepath.Join(root, "missing", "Cargo.toml")); err == nil {
+		t.Error("found a lockfile for a missing manifest")
+	}
+}
@@ -1,9 +1,15 @@
 package docs
 
 import (
+	"cmp"
 	"encoding/json"
 	"fmt"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 // Parse extracts items from rustdoc JSON bytes.
@@ -11,40 +17,102 @@ import (
 func Parse(data []byte, crateName, version string) (*RustdocCrate, []ParsedItem, error) {
 	var crate RustdocCrate
 	if err := json.Unmarshal(data, &crate); err != nil {
+		if err := checkFormatVersion(formatVersion(data)); err != nil {
+			return nil, nil, err
+		}
 		return nil, nil, fmt.Errorf("unmarshaling rustdoc JSON: %w", err)
 	}
+	if err := checkFormatVersion(crate.FormatVersion); err != nil {
+		return nil, nil, err
+	}
+	crate.adaptFormat()
 
-	var items []ParsedItem
-	for id, item := range crate.Index {
+	// Items come out in ID order, so the same JSON always gives the same
+	// items in the same order, down to which of two same-named methods wins.
+	ids := make([]string, 0, len(crate.Index))
+	for id := range crate.Index {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, compareIDs)
+
+	// Items are parsed and their fragments generated in parallel, each into
+	// its own slot, so the order stays that of ids.
+	parsed := make([]*ParsedItem, len(ids))
+	parallel(len(ids), func(i int) {
+		item := crate.Index[ids[i]]
 		if item.CrateID != 0 {
-			continue
+			return
 		}
-		parsed := parseItem(id, &item, &crate)
-		if parsed == nil {
-			continue
+		p := parseItem(ids[i], &item, &crate)
+		if p == nil {
+			return
 		}
-		parsed.DocLinks = ResolveDocLinks(&item, &crate, crateName, version)
-		for k, v := range ResolveDocsRsURLs(parsed.Docs) {
-			if parsed.DocLinks == nil {
-				parsed.DocLinks = make(map[string]string)
+		p.DocLinks = ResolveDocLinks(&item, &crate, crateName, version)
+		for k, v := range ResolveDocsRsURLs(p.Docs) {
+			if p.DocLinks == nil {
+				p.DocLinks = make(map[string]string)
 			}
-			parsed.DocLinks[k] = v
+			p.DocLinks[k] = v
+		}
+		parsed[i] = p
+	})
+	var items []ParsedItem
+	for _, p := range parsed {
+		if p != nil {
+			items = append(items, *p)
 		}
-		items = append(items, *parsed)
 	}
+	items = append(items, parseMethods(items, &crate, crateName, version)...)
 
 	// Generate fragments after all items are parsed (needs full crate context)
-	for i, parsed := range items {
-		item, ok := crate.Index[parsed.RustdocID]
+	parallel(len(items), func(i int) {
+		item, ok := crate.Index[items[i].RustdocID]
 		if !ok {
-			continue
+			return
 		}
 		items[i].Fragments = GenerateFragments(&item, &crate, crateName, version)
-	}
+	})
 
 	return &crate, items, nil
 }
 
+// parallel calls fn(i) for each i in [0, n) on up to GOMAXPROCS
+// goroutines and waits for them all. fn may only read the crate and write
+// to slot i of its results.
+func parallel(n int, fn func(i int)) {
+	workers := min(runtime.GOMAXPROCS(0), n)
+	if workers <= 1 {
+		for i := range n {
+			fn(i)
+		}
+		return
+	}
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for range workers {
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= n {
+					return
+				}
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// compareIDs orders item IDs numerically; they're integers as strings.
+func compareIDs(a, b string) int {
+	if len(a) != len(b) {
+		return cmp.Compare(len(a), len(b))
+	}
+	return strings.Compare(a, b)
+}
+
 func parseItem(id string, item *RustdocItem, crate *RustdocCrate) *ParsedItem {
 	if item.Name == nil {
 		return nil
@@ -74,17 +142,128 @@ func parseItem(id string, item *RustdocItem, crate *RustdocCrate) *ParsedItem {
 	}
 
 	sig := extractSignature(item.Inner, kind)
+	if sig == "" {
+		sig = macroSignature(name, item.Inner)
+	}
 
 	return &ParsedItem{
-		RustdocID: id,
-		Name:      name,
-		Path:      path,
-		Kind:      kind,
-		Docs:      docs,
-		Signature: sig,
+		RustdocID:   id,
+		Name:        name,
+		Path:        path,
+		Kind:        kind,
+		Docs:        docs,
+		Signature:   sig,
+		Features:    ExtractFeatures(item),
+		Examples:    ExtractRustCodeBlocks(docs),
+		Deprecation: item.Deprecation,
+		Stability:   ExtractStability(item),
 	}
 }
 
+// parseMethods returns an item of kind "method" for each method declared
+// in an inherent impl of, or by, the types and traits in items, at path
+// Type::method. Trait impl methods are left to the trait's docs. The first
+// method with a path wins, so impls of a generic type for different
+// parameters don't repeat a name, nor does one shadow a real item.
+func parseMethods(items []ParsedItem, crate *RustdocCrate, crateName, version string) []ParsedItem {
+	taken := make(map[string]bool, len(items))
+	for _, it := range items {
+		taken[it.Path] = true
+	}
+
+	// Which method wins a path depends on order, so that's decided here;
+	// the methods themselves are built in parallel below.
+	type candidate struct {
+		path string
+		id   string
+		item RustdocItem
+		fn   json.RawMessage
+	}
+	var candidates []candidate
+	add := func(parent string, id int) {
+		idStr := strconv.Itoa(id)
+		item, ok := crate.Index[idStr]
+		if !ok || item.Name == nil || item.CrateID != 0 {
+			return
+		}
+		fnData := unwrapInner(item.Inner, "function")
+		path := parent + "::" + *item.Name
+		if fnData == nil || taken[path] {
+			return
+		}
+		taken[path] = true
+		candidates = append(candidates, candidate{path, idStr, item, fnData})
+	}
+
+	for _, parent := range items {
+		item, ok := crate.Index[parent.RustdocID]
+		if !ok {
+			continue
+		}
+		switch parent.Kind {
+		case "trait":
+			var t struct {
+				Items []int `json:"items"`
+			}
+			if json.Unmarshal(unwrapInner(item.Inner, "trait"), &t) == nil {
+				for _, id := range t.Items {
+					add(parent.Path, id)
+				}
+			}
+		case "struct", "enum", "union":
+			var t struct {
+				Impls []int `json:"impls"`
+			}
+			if json.Unmarshal(unwrapInner(item.Inner, parent.Kind), &t) != nil {
+				continue
+			}
+			for _, implID := range t.Impls {
+				var impl struct {
+					Trait json.RawMessage `json:"trait"`
+					Items []int           `json:"items"`
+				}
+				implItem, ok := crate.Index[strconv.Itoa(implID)]
+				if !ok || json.Unmarshal(unwrapInner(implItem.Inner, "impl"), &impl) != nil || firstNonNull(impl.Trait) != nil {
+					continue
+				}
+				for _, id := range impl.Items {
+					add(parent.Path, id)
+				}
+			}
+		}
+	}
+
+	methods := make([]ParsedItem, len(candidates))
+	parallel(len(candidates), func(i int) {
+		c := &candidates[i]
+		var docs string
+		if c.item.Docs != nil {
+			docs = *c.item.Docs
+		}
+		m := ParsedItem{
+			RustdocID:   c.id,
+			Name:        *c.item.Name,
+			Path:        c.path,
+			Kind:        "method",
+			Docs:        docs,
+			Signature:   renderFnSig(*c.item.Name, c.fn, crate, crateName, version),
+			Features:    ExtractFeatures(&c.item),
+			Examples:    ExtractRustCodeBlocks(docs),
+			DocLinks:    ResolveDocLinks(&c.item, crate, crateName, version),
+			Deprecation: c.item.Deprecation,
+			Stability:   ExtractStability(&c.item),
+		}
+		for k, v := range ResolveDocsRsURLs(docs) {
+			if m.DocLinks == nil {
+				m.DocLinks = make(map[string]string)
+			}
+			m.DocLinks[k] = v
+		}
+		methods[i] = m
+	})
+	return methods
+}
+
 // innerKind extracts the kind from the inner JSON's single key.
 func innerKind(inner json.RawMessage) string {
 	if len(inner) == 0 {
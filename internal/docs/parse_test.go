@@ -0,0 +1,128 @@
+package docs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestParse_Methods(t *testing.T) {
+	t.Parallel()
+	data := []byte(`{
+		"root": 0, "format_version": 39,
+		"index": {
+			"1": {"id": 1, "crate_id": 0, "name": "Mutex", "docs": "A mutex.",
+				"inner": {"struct": {"kind": {"unit": null}, "generics": {"params": []}, "impls": [10, 11]}}},
+			"10": {"id": 10, "crate_id": 0, "inner": {"impl": {"trait": null, "items": [2], "for": {"resolved_path": {"path": "Mutex", "id": 1}}}}},
+			"11": {"id": 11, "crate_id": 0, "inner": {"impl": {"trait": {"path": "Clone", "id": 50}, "items": [3], "for": {"resolved_path": {"path": "Mutex", "id": 1}}}}},
+			"2": {"id": 2, "crate_id": 0, "name": "lock", "docs": "Locks the mutex.\n\n` + "```" + `\nm.lock();\n` + "```" + `",
+				"inner": {"function": {"has_body": true, "sig": {"inputs": [["self", {"borrowed_ref": {"is_mutable": false, "type": {"generic": "Self"}}}]], "output": null}, "generics": {"params": []}, "header": {}}}},
+			"3": {"id": 3, "crate_id": 0, "name": "clone", "docs": null,
+				"inner": {"function": {"has_body": true, "sig": {"inputs": [], "output": null}, "generics": {"params": []}, "header": {}}}},
+			"4": {"id": 4, "crate_id": 0, "name": "Lockable", "docs": "Things that lock.",
+				"inner": {"trait": {"items": [5], "implementations": []}}},
+			"5": {"id": 5, "crate_id": 0, "name": "try_lock", "docs": "Tries to lock.",
+				"inner": {"function": {"has_body": false, "sig": {"inputs": [], "output": null}, "generics": {"params": []}, "header": {}}}}
+		},
+		"paths": {
+			"1": {"crate_id": 0, "path": ["sync", "Mutex"], "kind": "struct"},
+			"4": {"crate_id": 0, "path": ["sync", "Lockable"], "kind": "trait"}
+		},
+		"external_crates": {}
+	}`)
+
+	_, items, err := Parse(data, "sync", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	byPath := map[string]ParsedItem{}
+	for _, it := range items {
+		byPath[it.Path] = it
+	}
+
+	lock, ok := byPath["sync::Mutex::lock"]
+	if !ok {
+		t.Fatalf("no item for inherent method; got %v", byPath)
+	}
+	if lock.Kind != "method" || lock.Signature != "fn lock(&self)" || lock.Docs == "" || len(lock.Examples) != 1 {
+		t.Errorf("lock = %+v", lock)
+	}
+	names := map[string]bool{}
+	for _, f := range lock.Fragments {
+		names[f.Name] = true
+	}
+	if !names[FragExamples] {
+		t.Errorf("lock fragments = %v, want #examples", names)
+	}
+
+	if _, ok := byPath["sync::Mutex::clone"]; ok {
+		t.Error("trait impl methods should not become items")
+	}
+	if m, ok := byPath["sync::Lockable::try_lock"]; !ok || m.Kind != "method" {
+		t.Errorf("trait method = %+v, %v", m, ok)
+	}
+}
+
+// TestParse_ParallelMatchesSerial parses the same crate on one goroutine and
+// on several and expects identical items in identical order.
+func TestParse_ParallelMatchesSerial(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "formats", "format_57.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parse := func(procs int) []ParsedItem {
+		defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(procs))
+		_, items, err := Parse(data, "fixture", "1.0.0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return items
+	}
+	serial := parse(1)
+	for range 4 {
+		if parallel := parse(8); !reflect.DeepEqual(parallel, serial) {
+			t.Fatalf("parallel parse differs:\ngot  %+v\nwant %+v", parallel, serial)
+		}
+	}
+}
+
+func TestParse_Deprecation(t *testing.T) {
+	t.Parallel()
+	data := []byte(`{
+		"root": 0, "format_version": 57,
+		"index": {
+			"1": {"id": 1, "crate_id": 0, "name": "old", "docs": "Old.", "deprecation": {"since": "1.2.0", "note": "use ` + "`new`" + `\n instead"},
+				"inner": {"function": {"has_body": true, "sig": {"inputs": [], "output": null}, "generics": {"params": []}, "header": {}}}},
+			"2": {"id": 2, "crate_id": 0, "name": "new", "docs": "New.", "deprecation": null,
+				"inner": {"function": {"has_body": true, "sig": {"inputs": [], "output": null}, "generics": {"params": []}, "header": {}}}}
+		},
+		"paths": {
+			"1": {"crate_id": 0, "path": ["c", "old"], "kind": "function"},
+			"2": {"crate_id": 0, "path": ["c", "new"], "kind": "function"}
+		},
+		"external_crates": {}
+	}`)
+	_, items, err := Parse(data, "c", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	byPath := map[string]ParsedItem{}
+	for _, it := range items {
+		byPath[it.Path] = it
+	}
+	if byPath["c::new"].Deprecation != nil {
+		t.Errorf("c::new deprecated: %+v", byPath["c::new"].Deprecation)
+	}
+	dep := byPath["c::old"].Deprecation
+	if dep == nil {
+		t.Fatal("c::old not deprecated")
+	}
+	if got, want := dep.Banner(), "**Deprecated** since 1.2.0: use `new` instead"; got != want {
+		t.Errorf("banner %q, want %q", got, want)
+	}
+	if got := (&Deprecation{}).Banner(); got != "**Deprecated**" {
+		t.Errorf("bare banner %q", got)
+	}
+}
@@ -0,0 +1,178 @@
+package docs
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Requests to docs.rs, crates.io and other registries go through one
+// crawler, so a batch add of many crates paces itself per host instead of
+// each fetch hammering the services independently. Responses saying the
+// server is overloaded (429, 502-504) are retried after a backoff that also
+// holds back every other request to that host.
+
+const (
+	// backoffBase is the first backoff when the server doesn't send
+	// Retry-After; it doubles with each retry.
+	backoffBase = time.Second
+	// backoffMax caps a backoff. A Retry-After longer than this isn't
+	// waited out: the response is returned as is.
+	backoffMax = 2 * time.Minute
+)
+
+// crawler paces requests per host. Its zero interval leaves them unpaced.
+type crawler struct {
+	mu       sync.Mutex
+	interval time.Duration
+	retries  int
+	// next is when each host may next be sent a request.
+	next map[string]time.Time
+
+	now    func() time.Time
+	sleep  func(context.Context, time.Duration) error
+	jitter func(time.Duration) time.Duration
+}
+
+func newCrawler(requestsPerSecond float64, retries int) *crawler {
+	c := &crawler{retries: retries, next: map[string]time.Time{}, now: time.Now, sleep: sleepContext, jitter: fullJitter}
+	if requestsPerSecond > 0 {
+		c.interval = time.Duration(float64(time.Second) / requestsPerSecond)
+	}
+	return c
+}
+
+var (
+	crawlMu sync.RWMutex
+	crawl   = newCrawler(1, 4)
+)
+
+// SetCrawlLimits paces requests to each registry and docs host to
+// requestsPerSecond (0 for no pacing) and retries overloaded responses up to
+// retries times.
+func SetCrawlLimits(requestsPerSecond float64, retries int) {
+	c := newCrawler(requestsPerSecond, retries)
+	crawlMu.Lock()
+	// Keep backoffs already in force: the server hasn't forgotten them.
+	c.next = crawl.snapshot()
+	crawl = c
+	crawlMu.Unlock()
+}
+
+// do sends req through the current crawler.
+func do(req *http.Request) (*http.Response, error) {
+	crawlMu.RLock()
+	c := crawl
+	crawlMu.RUnlock()
+	return c.do(req)
+}
+
+func (c *crawler) snapshot() map[string]time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	next := make(map[string]time.Time, len(c.next))
+	for host, t := range c.next {
+		next[host] = t
+	}
+	return next
+}
+
+// do sends req once its host's turn comes, retrying overloaded responses.
+// req must have no body, so it can be sent again.
+func (c *crawler) do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		if err := c.wait(ctx, req.URL.Host); err != nil {
+			return nil, err
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil || !overloaded(resp.StatusCode) || attempt >= c.retries {
+			return resp, err
+		}
+		delay, ok := retryAfter(resp, c.now())
+		if !ok {
+			delay = c.jitter(min(backoffBase<<attempt, backoffMax))
+		}
+		if delay > backoffMax {
+			return resp, nil
+		}
+		resp.Body.Close()
+		slog.Info("registry overloaded, backing off", "host", req.URL.Host, "status", resp.StatusCode, "delay", delay, "attempt", attempt+1)
+		c.backoff(req.URL.Host, delay)
+	}
+}
+
+// wait blocks until host may be sent a request, then claims that slot.
+func (c *crawler) wait(ctx context.Context, host string) error {
+	c.mu.Lock()
+	now := c.now()
+	at := now
+	if next := c.next[host]; next.After(now) {
+		at = next
+	}
+	if c.interval > 0 || at.After(now) {
+		c.next[host] = at.Add(c.interval)
+	}
+	c.mu.Unlock()
+	if d := at.Sub(now); d > 0 {
+		return c.sleep(ctx, d)
+	}
+	return ctx.Err()
+}
+
+// backoff holds every request to host for at least d.
+func (c *crawler) backoff(host string, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if until := c.now().Add(d); until.After(c.next[host]) {
+		c.next[host] = until
+	}
+}
+
+// overloaded reports whether status asks the client to come back later.
+func overloaded(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter parses resp's Retry-After header, in seconds or as a date.
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// fullJitter picks a delay uniformly up to d, so clients backing off
+// together don't retry together.
+func fullJitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(d))) + 1
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
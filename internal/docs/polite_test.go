@@ -0,0 +1,110 @@
+package docs
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClock advances only when the crawler sleeps.
+type fakeClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (f *fakeClock) crawler(requestsPerSecond float64, retries int) *crawler {
+	c := newCrawler(requestsPerSecond, retries)
+	c.now = func() time.Time { return f.now }
+	c.sleep = func(_ context.Context, d time.Duration) error {
+		f.sleeps = append(f.sleeps, d)
+		f.now = f.now.Add(d)
+		return nil
+	}
+	c.jitter = func(d time.Duration) time.Duration { return d }
+	return c
+}
+
+func TestCrawler_PacesPerHost(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	c := clock.crawler(2, 0)
+	ctx := context.Background()
+
+	for range 3 {
+		if err := c.wait(ctx, "docs.rs"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A different host has its own budget.
+	if err := c.wait(ctx, "crates.io"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []time.Duration{500 * time.Millisecond, 500 * time.Millisecond}
+	if len(clock.sleeps) != len(want) {
+		t.Fatalf("sleeps = %v, want %v", clock.sleeps, want)
+	}
+	for i := range want {
+		if clock.sleeps[i] != want[i] {
+			t.Errorf("sleep %d = %v, want %v", i, clock.sleeps[i], want[i])
+		}
+	}
+}
+
+func TestCrawler_RetriesOverloaded(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.Header().Set("Retry-After", "3")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer srv.Close()
+
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	c := clock.crawler(0, 4)
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	resp, err := c.do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || calls.Load() != 3 {
+		t.Fatalf("status %d after %d calls, want 200 after 3", resp.StatusCode, calls.Load())
+	}
+	// Retry-After is honored, then the backoff doubles from its base.
+	want := []time.Duration{3 * time.Second, 2 * backoffBase}
+	if len(clock.sleeps) != len(want) || clock.sleeps[0] != want[0] || clock.sleeps[1] != want[1] {
+		t.Errorf("sleeps = %v, want %v", clock.sleeps, want)
+	}
+}
+
+func TestCrawler_GivesUp(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	c := clock.crawler(0, 2)
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	resp, err := c.do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusTooManyRequests || calls.Load() != 3 {
+		t.Errorf("status %d after %d calls, want 429 after 3", resp.StatusCode, calls.Load())
+	}
+}
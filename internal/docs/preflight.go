@@ -0,0 +1,159 @@
+package docs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// MinFormatVersion is the oldest rustdoc JSON format Parse reads: the first
+// with integer item IDs. Older JSON is refused with a FormatVersionError
+// rather than an unmarshaling error about some field deep in the index.
+const MinFormatVersion = 35
+
+// maxJSONProbes caps the releases NewestWithJSON asks the docs host about.
+const maxJSONProbes = 5
+
+// FormatVersionError is rustdoc JSON in a format older than
+// MinFormatVersion, as docs.rs serves for releases built before the format
+// settled.
+type FormatVersionError struct {
+	Version int
+}
+
+func (e *FormatVersionError) Error() string {
+	return fmt.Sprintf("rustdoc JSON format version %d is older than the oldest supported, %d", e.Version, MinFormatVersion)
+}
+
+// checkFormatVersion returns a FormatVersionError for JSON declaring a
+// format older than MinFormatVersion. JSON that doesn't declare one passes.
+func checkFormatVersion(version int) error {
+	if version > 0 && version < MinFormatVersion {
+		return &FormatVersionError{Version: version}
+	}
+	return nil
+}
+
+// formatVersion reads only the format_version of rustdoc JSON, or 0.
+func formatVersion(data []byte) int {
+	var v struct {
+		FormatVersion int `json:"format_version"`
+	}
+	json.Unmarshal(data, &v)
+	return v.FormatVersion
+}
+
+// Release is one published version of a crate.
+type Release struct {
+	Version string
+	Yanked  bool
+}
+
+// FetchReleases lists a crate's releases from the registry, newest first.
+func FetchReleases(ctx context.Context, reg *Registry, name string) ([]Release, error) {
+	var payload struct {
+		Versions []struct {
+			Num    string `json:"num"`
+			Yanked bool   `json:"yanked"`
+		} `json:"versions"`
+	}
+	rawURL := fmt.Sprintf("%s/api/v1/crates/%s/versions?sort=semver",
+		strings.TrimSuffix(reg.IndexURL, "/"), url.PathEscape(name))
+	if err := fetchJSON(ctx, reg, rawURL, name+" releases", &payload); err != nil {
+		return nil, err
+	}
+	releases := make([]Release, len(payload.Versions))
+	for i, v := range payload.Versions {
+		releases[i] = Release{Version: v.Num, Yanked: v.Yanked}
+	}
+	return releases, nil
+}
+
+// BuildStatus is what docs.rs reports about a release's documentation
+// build. Version is the release "latest" resolved to.
+type BuildStatus struct {
+	Version string `json:"version"`
+	Built   bool   `json:"doc_status"`
+}
+
+// FetchBuildStatus asks docs.rs whether a release's documentation built.
+// Registries whose DocsURL is a JSON template have no such API.
+func FetchBuildStatus(ctx context.Context, reg *Registry, name, version string) (*BuildStatus, error) {
+	if reg.isTemplate() {
+		return nil, fmt.Errorf("%s has no build status API", reg.DocsURL)
+	}
+	var status BuildStatus
+	rawURL := fmt.Sprintf("%s/crate/%s/%s/status.json",
+		strings.TrimSuffix(reg.DocsURL, "/"), url.PathEscape(name), url.PathEscape(version))
+	if err := fetchJSON(ctx, reg, rawURL, name+"@"+version+" build status", &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// NewestWithJSON returns the newest unyanked release, other than except,
+// whose rustdoc JSON the docs host has, or "" if none of the newest few do.
+// It can't tell the JSON's format without downloading it, but the newest
+// releases are the ones most likely built recently enough.
+func NewestWithJSON(ctx context.Context, reg *Registry, name string, releases []Release, except string) (string, error) {
+	probes := 0
+	for _, r := range releases {
+		if r.Yanked || r.Version == except {
+			continue
+		}
+		if probes++; probes > maxJSONProbes {
+			break
+		}
+		if ok, err := hasRustdocJSON(ctx, reg, name, r.Version); err != nil {
+			return "", err
+		} else if ok {
+			return r.Version, nil
+		}
+	}
+	return "", nil
+}
+
+// hasRustdocJSON asks the docs host, without downloading it, whether it
+// has rustdoc JSON for a release.
+func hasRustdocJSON(ctx context.Context, reg *Registry, name, version string) (bool, error) {
+	req, err := reg.newRequest(ctx, reg.rustdocJSONURL(name, version))
+	if err != nil {
+		return false, err
+	}
+	req.Method = http.MethodHead
+	resp, err := do(req)
+	if err != nil {
+		return false, fmt.Errorf("checking rustdoc JSON for %s@%s: %w", name, version, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK, nil
+}
+
+// fetchJSON GETs rawURL and decodes the JSON response into v.
+func fetchJSON(ctx context.Context, reg *Registry, rawURL, what string, v any) error {
+	req, err := reg.newRequest(ctx, rawURL)
+	if err != nil {
+		return err
+	}
+	resp, err := do(req)
+	if err != nil {
+		return fmt.Errorf("fetching %s: %w", what, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s returned %d for %s: %w", req.URL.Host, resp.StatusCode, what, ErrNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s returned %d for %s: %s", req.URL.Host, resp.StatusCode, what, string(body))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding %s: %w", what, err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package docs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParse_OldFormatVersion(t *testing.T) {
+	t.Parallel()
+	tests := map[string]string{
+		// String IDs don't unmarshal into the current types at all.
+		"string ids": `{"root": "0:0", "format_version": 24, "index": {"0:0": {"id": "0:0", "crate_id": 0}}}`,
+		"int ids":    `{"root": 0, "format_version": 34, "index": {}}`,
+	}
+	for name, data := range tests {
+		_, _, err := Parse([]byte(data), "old", "0.1.0")
+		var fv *FormatVersionError
+		if !errors.As(err, &fv) {
+			t.Errorf("%s: Parse error = %v, want a FormatVersionError", name, err)
+		}
+	}
+	if _, _, err := Parse([]byte(`{"root": 0, "index": {}}`), "new", "0.1.0"); err != nil {
+		t.Errorf("Parse without format_version = %v, want no error", err)
+	}
+}
+
+func TestNewestWithJSON(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/crates/old/versions":
+			w.Write([]byte(`{"versions":[
+				{"num":"0.4.0","yanked":true},
+				{"num":"0.3.0","yanked":false},
+				{"num":"0.2.0","yanked":false},
+				{"num":"0.1.0","yanked":false}]}`))
+		case "/crate/old/0.2.0/json", "/crate/old/0.4.0/json":
+			if r.Method != http.MethodHead {
+				t.Errorf("%s %s, want HEAD", r.Method, r.URL.Path)
+			}
+		case "/crate/old/0.1.0/status.json":
+			w.Write([]byte(`{"version":"0.1.0","doc_status":false}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	reg := &Registry{IndexURL: srv.URL, DocsURL: srv.URL}
+	ctx := context.Background()
+
+	releases, err := FetchReleases(ctx, reg, "old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(releases) != 4 || !releases[0].Yanked || releases[1].Version != "0.3.0" {
+		t.Fatalf("releases = %+v", releases)
+	}
+	got, err := NewestWithJSON(ctx, reg, "old", releases, "0.1.0")
+	if err != nil || got != "0.2.0" {
+		t.Errorf("NewestWithJSON = %q, %v; want 0.2.0", got, err)
+	}
+	if got, _ := NewestWithJSON(ctx, reg, "old", releases, "0.2.0"); got != "" {
+		t.Errorf("NewestWithJSON except 0.2.0 = %q, want none", got)
+	}
+
+	status, err := FetchBuildStatus(ctx, reg, "old", "0.1.0")
+	if err != nil || status.Built || status.Version != "0.1.0" {
+		t.Errorf("FetchBuildStatus = %+v, %v", status, err)
+	}
+	if _, err := FetchBuildStatus(ctx, reg, "old", "9.9.9"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FetchBuildStatus of a missing release = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,109 @@
+package docs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Registry is a crates.io-compatible registry paired with a docs.rs-compatible
+// documentation host, e.g. a Kellnr or Artifactory instance.
+type Registry struct {
+	Name string
+	// IndexURL is the base of the registry's web API (the "api" value from
+	// its index config.json). Crate search uses {IndexURL}/api/v1/crates.
+	IndexURL string
+	// DocsURL is either a docs.rs-style base URL, or a template containing
+	// {name} and {version} that points straight at the rustdoc JSON.
+	DocsURL string
+	// Token is sent verbatim in the Authorization header, as cargo does.
+	Token string
+}
+
+// DefaultRegistry is crates.io with docs.rs.
+var DefaultRegistry = &Registry{IndexURL: "https://crates.io", DocsURL: "https://docs.rs"}
+
+var (
+	registriesMu sync.RWMutex
+	registries   = map[string]*Registry{}
+)
+
+// SetRegistries replaces the configured alternative registries.
+func SetRegistries(regs []*Registry) {
+	m := make(map[string]*Registry, len(regs))
+	for _, r := range regs {
+		m[r.Name] = r
+	}
+	registriesMu.Lock()
+	registries = m
+	registriesMu.Unlock()
+}
+
+// LookupRegistry returns the named registry, or DefaultRegistry for "".
+func LookupRegistry(name string) (*Registry, error) {
+	if name == "" {
+		return DefaultRegistry, nil
+	}
+	registriesMu.RLock()
+	r, ok := registries[name]
+	registriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown registry %q (add it under [registries.%s] in the config)", name, name)
+	}
+	return r, nil
+}
+
+// isTemplate reports whether DocsURL is a rustdoc JSON URL template rather
+// than a docs.rs-style host.
+func (r *Registry) isTemplate() bool {
+	return strings.Contains(r.DocsURL, "{name}")
+}
+
+func (r *Registry) rustdocJSONURL(name, version string) string {
+	if r.isTemplate() {
+		return strings.NewReplacer("{name}", url.PathEscape(name), "{version}", url.PathEscape(version)).Replace(r.DocsURL)
+	}
+	return fmt.Sprintf("%s/crate/%s/%s/json", strings.TrimSuffix(r.DocsURL, "/"), name, version)
+}
+
+// ItemURL returns the docs host page for an item, for readers following a
+// link to a crate that isn't indexed. Without the item's kind the exact page
+// can't be named, so items other than the crate root open rustdoc's search.
+// It returns "" for registries whose DocsURL is a JSON template.
+func (r *Registry) ItemURL(crate, version, path string) string {
+	if r.isTemplate() {
+		return ""
+	}
+	if version == "" {
+		version = "latest"
+	}
+	lib := strings.ReplaceAll(crate, "-", "_")
+	root := fmt.Sprintf("%s/%s/%s/%s/", strings.TrimSuffix(r.DocsURL, "/"), crate, url.PathEscape(version), lib)
+	if path == "" || path == crate || path == lib {
+		return root
+	}
+	return root + "?search=" + url.QueryEscape(path)
+}
+
+func (r *Registry) searchURL(query string, limit int) string {
+	return fmt.Sprintf("%s/api/v1/crates?q=%s&per_page=%s",
+		strings.TrimSuffix(r.IndexURL, "/"), url.QueryEscape(query), strconv.Itoa(limit))
+}
+
+// newRequest creates a GET request bound to ctx, carrying the user agent
+// and, for private registries, the auth token.
+func (r *Registry) newRequest(ctx context.Context, rawURL string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", "ferrisfetch/0.1.0")
+	if r.Token != "" {
+		req.Header.Set("Authorization", r.Token)
+	}
+	return req, nil
+}
@@ -0,0 +1,76 @@
+package docs
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegistryURLs(t *testing.T) {
+	t.Parallel()
+	docsRs := &Registry{IndexURL: "https://crates.io/", DocsURL: "https://docs.rs/"}
+	if got := docsRs.rustdocJSONURL("serde", "1.0.0"); got != "https://docs.rs/crate/serde/1.0.0/json" {
+		t.Errorf("docs.rs JSON URL = %q", got)
+	}
+	if got := docsRs.searchURL("async http", 5); got != "https://crates.io/api/v1/crates?q=async+http&per_page=5" {
+		t.Errorf("search URL = %q", got)
+	}
+	if got := docsRs.ItemURL("tokio-util", "", "tokio_util"); got != "https://docs.rs/tokio-util/latest/tokio_util/" {
+		t.Errorf("crate root URL = %q", got)
+	}
+	if got := docsRs.ItemURL("tokio", "1.0.0", "tokio::sync::Mutex"); got != "https://docs.rs/tokio/1.0.0/tokio/?search=tokio%3A%3Async%3A%3AMutex" {
+		t.Errorf("item URL = %q", got)
+	}
+
+	tmpl := &Registry{DocsURL: "https://kellnr.corp/docs/{name}/{version}/doc.json"}
+	if got := tmpl.rustdocJSONURL("billing", "2.1.0"); got != "https://kellnr.corp/docs/billing/2.1.0/doc.json" {
+		t.Errorf("template JSON URL = %q", got)
+	}
+	if got := tmpl.ItemURL("billing", "2.1.0", "billing::Invoice"); got != "" {
+		t.Errorf("template item URL = %q", got)
+	}
+}
+
+func TestLookupRegistry(t *testing.T) {
+	SetRegistries([]*Registry{{Name: "corp", DocsURL: "https://docs.corp"}})
+	if r, err := LookupRegistry(""); err != nil || r != DefaultRegistry {
+		t.Errorf("empty name: got %v, %v", r, err)
+	}
+	if r, err := LookupRegistry("corp"); err != nil || r.DocsURL != "https://docs.corp" {
+		t.Errorf("corp: got %v, %v", r, err)
+	}
+	if _, err := LookupRegistry("nope"); err == nil {
+		t.Error("expected error for unknown registry")
+	}
+}
+
+func TestFetchRustdocJSON_PrivateRegistry(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/billing/2.1.0.json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"root":0}`))
+	}))
+	defer srv.Close()
+
+	reg := &Registry{Name: "corp", DocsURL: srv.URL + "/{name}/{version}.json", Token: "secret"}
+	data, err := FetchRustdocJSON(context.Background(), reg, "billing", "2.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"root":0}` {
+		t.Errorf("got %q", data)
+	}
+
+	reg.Token = ""
+	if _, err := FetchRustdocJSON(context.Background(), reg, "billing", "2.1.0"); err == nil {
+		t.Error("expected error without token")
+	}
+}
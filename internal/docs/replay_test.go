@@ -0,0 +1,54 @@
+package docs_test
+
+import (
+	"testing"
+
+	"github.com/jcdickinson/ferrisfetch/internal/docs"
+	"github.com/jcdickinson/ferrisfetch/internal/docs/docstest"
+)
+
+// TestFetch_Replay fetches a crate's docs, metadata and dependencies from
+// the responses recorded in docstest's testdata.
+func TestFetch_Replay(t *testing.T) {
+	docstest.Use(t)
+	ctx := t.Context()
+	reg := docs.DefaultRegistry
+
+	data, err := docs.FetchRustdocJSON(ctx, reg, "itoa", "1.0.11")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, items, err := docs.Parse(data, "itoa", "1.0.11")
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := map[string]string{}
+	for _, it := range items {
+		paths[it.Path] = it.Kind
+	}
+	for path, kind := range map[string]string{"itoa::Buffer": "struct", "itoa::Integer": "trait", "itoa::Buffer::format": "method"} {
+		if paths[path] != kind {
+			t.Errorf("%s: kind %q, want %q (items %v)", path, paths[path], kind, paths)
+		}
+	}
+
+	info, err := docs.FetchCrateInfo(ctx, reg, "itoa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.LatestVersion != "1.0.11" {
+		t.Errorf("latest version %q", info.LatestVersion)
+	}
+
+	deps, err := docs.FetchDependencies(ctx, reg, "itoa", "1.0.11")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deps) != 1 || deps[0].Name != "no-panic" || !deps[0].Optional {
+		t.Errorf("dependencies %+v", deps)
+	}
+
+	if _, err := docs.FetchRustdocJSON(ctx, reg, "itoa", "0.0.1"); err == nil {
+		t.Error("fetching an unrecorded release succeeded")
+	}
+}
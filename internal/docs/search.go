@@ -1,12 +1,13 @@
 package docs
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
-	"strconv"
+	"slices"
+	"strings"
 )
 
 type CratesIOResult struct {
@@ -16,30 +17,27 @@ type CratesIOResult struct {
 	Downloads   int    `json:"downloads"`
 }
 
-// SearchCratesIO searches crates.io for crates matching the query.
-func SearchCratesIO(query string, limit int) ([]CratesIOResult, error) {
+// SearchCratesIO searches the registry (crates.io by default) for crates
+// matching the query.
+func SearchCratesIO(ctx context.Context, reg *Registry, query string, limit int) ([]CratesIOResult, error) {
 	if limit <= 0 {
 		limit = 20
 	}
 
-	u := fmt.Sprintf("https://crates.io/api/v1/crates?q=%s&per_page=%s",
-		url.QueryEscape(query), strconv.Itoa(limit))
-
-	req, err := http.NewRequest("GET", u, nil)
+	req, err := reg.newRequest(ctx, reg.searchURL(query, limit))
 	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+		return nil, err
 	}
-	req.Header.Set("User-Agent", "ferrisfetch/0.1.0")
 
-	resp, err := httpClient.Do(req)
+	resp, err := do(req)
 	if err != nil {
-		return nil, fmt.Errorf("searching crates.io: %w", err)
+		return nil, fmt.Errorf("searching %s: %w", req.URL.Host, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
-		return nil, fmt.Errorf("crates.io returned %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("%s returned %d: %s", req.URL.Host, resp.StatusCode, string(body))
 	}
 
 	var payload struct {
@@ -65,3 +63,67 @@ func SearchCratesIO(query string, limit int) ([]CratesIOResult, error) {
 	}
 	return results, nil
 }
+
+// SimilarCrateNames searches the registry for name and returns up to limit
+// crate names near enough to it to be what was meant, closest first. It
+// returns nil when name itself exists, so a wrong version isn't answered
+// with other crates.
+func SimilarCrateNames(ctx context.Context, reg *Registry, name string, limit int) ([]string, error) {
+	results, err := SearchCratesIO(ctx, reg, name, 20)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(results))
+	for i, r := range results {
+		names[i] = r.Name
+	}
+	return closeNames(name, names, limit), nil
+}
+
+// closeNames picks the candidates within a few edits of name, closest
+// first and otherwise in their original order. Registries treat - and _ as
+// the same, so they count as equal; nil means name is among the candidates.
+func closeNames(name string, candidates []string, limit int) []string {
+	type scored struct {
+		name string
+		dist int
+	}
+	norm := func(s string) string { return strings.ReplaceAll(strings.ToLower(s), "-", "_") }
+	maxDist := max(2, len(name)/3)
+	var close []scored
+	for _, c := range candidates {
+		if c == name {
+			return nil
+		}
+		if d := editDistance(norm(name), norm(c)); d <= maxDist {
+			close = append(close, scored{c, d})
+		}
+	}
+	slices.SortStableFunc(close, func(a, b scored) int { return a.dist - b.dist })
+	var names []string
+	for _, c := range close[:min(len(close), limit)] {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+// editDistance is the Levenshtein distance between a and b, in bytes.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,43 @@
+package docs
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCloseNames(t *testing.T) {
+	candidates := []string{"serde", "serde_json", "serde-json-core", "serde_yaml", "sered", "miniserde"}
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"serde-json", []string{"serde_json"}},
+		{"serdejson", []string{"serde_json"}},
+		{"serd", []string{"serde", "sered"}},
+		{"sedre", []string{"serde", "sered"}},
+		{"serde", nil},
+		{"tokio", nil},
+	}
+	for _, tt := range tests {
+		if got := closeNames(tt.name, candidates, 3); !slices.Equal(got, tt.want) {
+			t.Errorf("closeNames(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+		{"serde", "serde", 0},
+		{"serd", "serde", 1},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package docs
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Stability is an item's #[stable] or #[unstable] attribute. Only the
+// standard library and other crates built with staged_api carry them, so
+// most items have none.
+type Stability struct {
+	Level   string `json:"level"`             // "stable" or "unstable"
+	Feature string `json:"feature,omitempty"` // feature gate, e.g. "async_iterator"
+	Since   string `json:"since,omitempty"`   // Rust version it was stabilized in
+	Issue   string `json:"issue,omitempty"`   // tracking issue number
+}
+
+// Unstable reports whether the item needs a nightly toolchain.
+func (s *Stability) Unstable() bool {
+	return s.Level == "unstable"
+}
+
+// Label renders the stability in a few words: "stable since 1.0.0" or
+// "unstable: async_iterator".
+func (s *Stability) Label() string {
+	if s.Unstable() {
+		if s.Feature != "" {
+			return "unstable: " + s.Feature
+		}
+		return "unstable"
+	}
+	if s.Since != "" {
+		return "stable since " + s.Since
+	}
+	return "stable"
+}
+
+// Banner renders the stability as one line of markdown: the version a
+// stable item arrived in, or the feature gate an unstable one sits behind.
+func (s *Stability) Banner() string {
+	if !s.Unstable() {
+		return "**Stable since:** " + s.Since
+	}
+	banner := "**Nightly-only**"
+	if s.Feature != "" {
+		banner += ": needs `#![feature(" + s.Feature + ")]`"
+	}
+	if s.Issue != "" {
+		banner += " (tracking issue https://github.com/rust-lang/rust/issues/" + s.Issue + ")"
+	}
+	return banner
+}
+
+var (
+	stabilityAttrRe = regexp.MustCompile(`^#\[(stable|unstable)\((.*)\)\]$`)
+	stabilityArgRe  = regexp.MustCompile(`(\w+)\s*=\s*"([^"]*)"`)
+)
+
+// ExtractStability reads an item's #[stable(...)] or #[unstable(...)]
+// attribute, returning nil when it has neither. rustc_const_stable and
+// friends are about const use, not the item, and are skipped.
+func ExtractStability(item *RustdocItem) *Stability {
+	for _, attr := range attrStrings(item.Attrs) {
+		m := stabilityAttrRe.FindStringSubmatch(strings.TrimSpace(attr))
+		if m == nil {
+			continue
+		}
+		s := &Stability{Level: m[1]}
+		for _, arg := range stabilityArgRe.FindAllStringSubmatch(m[2], -1) {
+			switch arg[1] {
+			case "feature":
+				s.Feature = arg[2]
+			case "since":
+				// Items stabilized in the unreleased version say so with a
+				// placeholder rather than a version.
+				if arg[2] != "CURRENT_RUSTC_VERSION" {
+					s.Since = arg[2]
+				}
+			case "issue":
+				if arg[2] != "none" && arg[2] != "0" {
+					s.Issue = arg[2]
+				}
+			}
+		}
+		if !s.Unstable() && s.Since == "" {
+			// Nothing worth showing on a stable item without a version.
+			return nil
+		}
+		return s
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package docs
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestExtractStability(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		attrs  string
+		want   *Stability
+		banner string
+	}{
+		{"none", `[]`, nil, ""},
+		{"stable", `["#[stable(feature = \"rust1\", since = \"1.0.0\")]"]`,
+			&Stability{Level: "stable", Feature: "rust1", Since: "1.0.0"}, "**Stable since:** 1.0.0"},
+		{"unstable", `[{"other": "#[unstable(feature = \"async_iterator\", reason = \"soon\", issue = \"79024\")]"}]`,
+			&Stability{Level: "unstable", Feature: "async_iterator", Issue: "79024"},
+			"**Nightly-only**: needs `#![feature(async_iterator)]` (tracking issue https://github.com/rust-lang/rust/issues/79024)"},
+		{"no_issue", `["#[unstable(feature = \"fmt_internals\", issue = \"none\")]"]`,
+			&Stability{Level: "unstable", Feature: "fmt_internals"}, "**Nightly-only**: needs `#![feature(fmt_internals)]`"},
+		{"current_version", `["#[stable(feature = \"new_api\", since = \"CURRENT_RUSTC_VERSION\")]"]`, nil, ""},
+		{"const_only", `["#[rustc_const_unstable(feature = \"const_x\", issue = \"1\")]", "#[must_use]"]`, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attrs []json.RawMessage
+			if err := json.Unmarshal([]byte(tt.attrs), &attrs); err != nil {
+				t.Fatal(err)
+			}
+			got := ExtractStability(&RustdocItem{Attrs: attrs})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			if got != nil && got.Banner() != tt.banner {
+				t.Errorf("banner %q, want %q", got.Banner(), tt.banner)
+			}
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package docs
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"strings"
+)
 
 // RustdocCrate is the top-level structure of rustdoc JSON output.
 type RustdocCrate struct {
@@ -20,12 +23,32 @@ type ExternalCrate struct {
 
 // RustdocItem is a single item in the rustdoc index.
 type RustdocItem struct {
-	ID      int             `json:"id"`
-	CrateID int             `json:"crate_id"`
-	Name    *string         `json:"name"`
-	Docs    *string         `json:"docs"`
-	Links   map[string]int  `json:"links"` // markdown text → item ID (u32)
-	Inner   json.RawMessage `json:"inner"`
+	ID          int               `json:"id"`
+	CrateID     int               `json:"crate_id"`
+	Name        *string           `json:"name"`
+	Docs        *string           `json:"docs"`
+	Links       map[string]int    `json:"links"` // markdown text → item ID (u32)
+	Attrs       []json.RawMessage `json:"attrs"`
+	Deprecation *Deprecation      `json:"deprecation"`
+	Inner       json.RawMessage   `json:"inner"`
+}
+
+// Deprecation is an item's #[deprecated] attribute.
+type Deprecation struct {
+	Since *string `json:"since"`
+	Note  *string `json:"note"`
+}
+
+// Banner renders the deprecation as a one-line markdown notice.
+func (d *Deprecation) Banner() string {
+	banner := "**Deprecated**"
+	if d.Since != nil && *d.Since != "" {
+		banner += " since " + *d.Since
+	}
+	if d.Note != nil && *d.Note != "" {
+		banner += ": " + strings.Join(strings.Fields(*d.Note), " ")
+	}
+	return banner
 }
 
 // RustdocSummary provides the path and kind for an item.
@@ -43,12 +66,16 @@ type Fragment struct {
 
 // ParsedItem is a processed doc item ready for indexing.
 type ParsedItem struct {
-	RustdocID string
-	Name      string
-	Path      string
-	Kind      string
-	Docs      string
-	Signature string
-	DocLinks  map[string]string // resolved: markdown target → rsdoc URI
-	Fragments []Fragment
+	RustdocID   string
+	Name        string
+	Path        string
+	Kind        string
+	Docs        string
+	Signature   string
+	Features    []string          // cargo features the item is gated behind
+	DocLinks    map[string]string // resolved: markdown target → rsdoc URI
+	Fragments   []Fragment
+	Examples    []string     // Rust code blocks extracted from Docs
+	Deprecation *Deprecation // nil unless the item is #[deprecated]
+	Stability   *Stability   // nil unless the item is #[stable] or #[unstable]
 }
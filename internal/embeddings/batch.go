@@ -0,0 +1,136 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Batch defaults. Voyage accepts up to 1000 texts and 120K tokens per
+// request for its larger models; the token estimate is rough, so the default
+// stays well below that.
+const (
+	DefaultBatchTexts       = 50
+	DefaultBatchTokens      = 50000
+	DefaultBatchConcurrency = 2
+)
+
+// BatchOptions configures a BatchEmbedder. Zero values use the defaults.
+type BatchOptions struct {
+	// MaxTexts caps the texts per request.
+	MaxTexts int
+	// MaxTokens caps the estimated tokens per request. A text larger than
+	// this is sent on its own.
+	MaxTokens int
+	// Concurrency is how many requests may be in flight at once.
+	Concurrency int
+}
+
+type BatchEmbedder struct {
+	client *VoyageClient
+	opts   BatchOptions
+}
+
+func NewBatchEmbedder(client *VoyageClient, opts BatchOptions) *BatchEmbedder {
+	if opts.MaxTexts <= 0 {
+		opts.MaxTexts = DefaultBatchTexts
+	}
+	if opts.MaxTokens <= 0 {
+		opts.MaxTokens = DefaultBatchTokens
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultBatchConcurrency
+	}
+	return &BatchEmbedder{client: client, opts: opts}
+}
+
+// batchRange is the [start, end) span of texts sent in one request.
+type batchRange struct{ start, end int }
+
+// split groups consecutive texts into requests within the text and token
+// limits.
+func (b *BatchEmbedder) split(texts []string) []batchRange {
+	var ranges []batchRange
+	start, tokens := 0, 0
+	for i, t := range texts {
+		n := EstimateTokens(t)
+		if i > start && (i-start >= b.opts.MaxTexts || tokens+n > b.opts.MaxTokens) {
+			ranges = append(ranges, batchRange{start, i})
+			start, tokens = i, 0
+		}
+		tokens += n
+	}
+	if start < len(texts) {
+		ranges = append(ranges, batchRange{start, len(texts)})
+	}
+	return ranges
+}
+
+// EmbedBatches embeds texts in batches, up to Concurrency at a time, handing
+// each batch's embeddings to onBatch along with the offset of its first text.
+// Batches may finish out of order, but onBatch calls never overlap. Callers
+// can persist each batch as it arrives so an interrupted run keeps its
+// progress. The first error, or ctx ending, stops further requests. It
+// returns the tokens the provider billed, which include those of batches
+// finished before an error.
+func (b *BatchEmbedder) EmbedBatches(ctx context.Context, texts []string, model string, onBatch func(offset int, embeddings [][]float32) error) (int, error) {
+	if len(texts) == 0 {
+		return 0, fmt.Errorf("no texts provided")
+	}
+	if model == "" {
+		model = "voyage-3.5"
+	}
+	outputDim := b.client.outputDimension(model)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex // serializes onBatch and guards firstErr and tokens
+		firstErr error
+		tokens   int
+	)
+	fail := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+	sem := make(chan struct{}, b.opts.Concurrency)
+
+dispatch:
+	for _, r := range b.split(texts) {
+		select {
+		case sem <- struct{}{}:
+		case <-runCtx.Done():
+			break dispatch
+		}
+		wg.Add(1)
+		go func(r batchRange) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			embeddings, used, err := b.client.embed(runCtx, texts[r.start:r.end], model, outputDim, Bulk)
+			mu.Lock()
+			defer mu.Unlock()
+			tokens += used
+			if firstErr != nil {
+				return
+			}
+			if err != nil {
+				fail(fmt.Errorf("embedding batch at offset %d: %w", r.start, err))
+				return
+			}
+			if err := onBatch(r.start, embeddings); err != nil {
+				fail(err)
+			}
+		}(r)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return tokens, firstErr
+	}
+	return tokens, ctx.Err()
+}
@@ -0,0 +1,133 @@
+package embeddings
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBatchSplit(t *testing.T) {
+	t.Parallel()
+	b := NewBatchEmbedder(nil, BatchOptions{MaxTexts: 3, MaxTokens: 10})
+	texts := []string{
+		strings.Repeat("a", 8), strings.Repeat("a", 8), // 2 tokens each
+		strings.Repeat("a", 40), // 10 tokens: fills a batch
+		strings.Repeat("a", 80), // 20 tokens: over the limit, sent alone
+		"a", "a", "a", "a",
+	}
+	got := fmt.Sprint(b.split(texts))
+	if want := "[{0 2} {2 3} {3 4} {4 7} {7 8}]"; got != want {
+		t.Errorf("split = %s, want %s", got, want)
+	}
+}
+
+// embedServer answers embedding requests with one-element vectors holding
+// each text's length, billing a token per byte, counting requests and the
+// peak concurrency.
+func embedServer(t *testing.T, fail func(req EmbedRequest) bool) (*VoyageClient, *atomic.Int32, *atomic.Int32) {
+	var requests, inFlight, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		var req EmbedRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if fail != nil && fail(req) {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		var data []string
+		tokens := 0
+		for i, text := range req.Input {
+			data = append(data, fmt.Sprintf(`{"embedding":[%d],"index":%d}`, len(text), i))
+			tokens += len(text)
+		}
+		fmt.Fprintf(w, `{"data":[%s],"usage":{"total_tokens":%d}}`, strings.Join(data, ","), tokens)
+	}))
+	t.Cleanup(srv.Close)
+	c := NewVoyageClient("key")
+	c.baseURL = srv.URL
+	return c, &requests, &peak
+}
+
+func TestEmbedBatches(t *testing.T) {
+	t.Parallel()
+	c, requests, peak := embedServer(t, nil)
+	b := NewBatchEmbedder(c, BatchOptions{MaxTexts: 2, Concurrency: 3})
+
+	texts := make([]string, 11)
+	for i := range texts {
+		texts[i] = strings.Repeat("x", i+1)
+	}
+	got := make([]float32, len(texts))
+	tokens, err := b.EmbedBatches(context.Background(), texts, "voyage-3.5", func(offset int, embs [][]float32) error {
+		for i, e := range embs {
+			got[offset+i] = e[0]
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range got {
+		if int(v) != i+1 {
+			t.Errorf("text %d got embedding %v", i, v)
+		}
+	}
+	if n := requests.Load(); n != 6 {
+		t.Errorf("sent %d requests, want 6", n)
+	}
+	if tokens != 66 {
+		t.Errorf("billed %d tokens, want 66", tokens)
+	}
+	if p := peak.Load(); p > 3 {
+		t.Errorf("%d requests in flight, want at most 3", p)
+	}
+}
+
+func TestEmbedBatchesStopsOnError(t *testing.T) {
+	t.Parallel()
+	c, requests, _ := embedServer(t, func(req EmbedRequest) bool { return true })
+	b := NewBatchEmbedder(c, BatchOptions{MaxTexts: 1, Concurrency: 1})
+	_, err := b.EmbedBatches(context.Background(), []string{"a", "b", "c"}, "voyage-3.5", func(int, [][]float32) error {
+		t.Error("onBatch called for a failed batch")
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "offset 0") {
+		t.Errorf("got %v, want an error for the first batch", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("sent %d requests after the first failed", n)
+	}
+}
+
+func TestEmbedBatchesCanceled(t *testing.T) {
+	t.Parallel()
+	c, requests, _ := embedServer(t, nil)
+	b := NewBatchEmbedder(c, BatchOptions{MaxTexts: 1, Concurrency: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	tokens, err := b.EmbedBatches(ctx, []string{"a", "b", "c"}, "voyage-3.5", func(int, [][]float32) error {
+		cancel()
+		return nil
+	})
+	if err != context.Canceled {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+	if tokens != 1 {
+		t.Errorf("billed %d tokens, want the finished batch's 1", tokens)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("sent %d requests after cancellation", n)
+	}
+}
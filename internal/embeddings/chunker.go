@@ -13,17 +13,36 @@ type Chunk struct {
 	Index int
 }
 
+// section is a heading-delimited span of the markdown, with the titles of
+// the headings it is nested under.
+type section struct {
+	text  string
+	title string
+	trail []string
+}
+
 // ChunkSections splits markdown into semantically meaningful chunks using
 // AST-based heading detection. Each chunk gets the preamble prepended so
-// every chunk carries the item's identity (path + signature).
+// every chunk carries the item's identity (path + signature). Sections
+// nested under other headings also get the trail of their ancestors'
+// titles ("Runtime > Multi-thread scheduler"), so a chunk from deep in a
+// crate root's docs keeps its place in the document.
 //
 // Additionally:
 // - The first paragraph (summary line) is emitted as a standalone chunk
 //   for double representation in vector space.
 // - Fenced code blocks >= 80 chars are extracted as standalone chunks.
 //
-// No max size enforcement — Voyage.ai truncates if needed.
+// No max size enforcement — Voyage.ai truncates if needed. See
+// ChunkSectionsWith to split long sections.
 func ChunkSections(preamble, markdown string) []Chunk {
+	return ChunkSectionsWith(preamble, markdown, ChunkOptions{})
+}
+
+// ChunkSectionsWith is ChunkSections with sections longer than
+// opts.MaxTokens split between paragraphs. Pieces after the first add the
+// section's own heading to their trail, since they no longer start with it.
+func ChunkSectionsWith(preamble, markdown string, opts ChunkOptions) []Chunk {
 	markdown = strings.TrimSpace(markdown)
 	if markdown == "" {
 		return []Chunk{{Text: preamble, Index: 0}}
@@ -47,17 +66,27 @@ func ChunkSections(preamble, markdown string) []Chunk {
 
 	// Section chunks
 	for _, sec := range sections {
-		text := strings.TrimSpace(sec)
+		text := strings.TrimSpace(sec.text)
 		if text == "" {
 			continue
 		}
-		chunks = append(chunks, Chunk{Text: preamble + "\n\n" + text, Index: idx})
-		idx++
+		trail := sec.trail
+		if sec.title != "" {
+			trail = append(trail[:len(trail):len(trail)], sec.title)
+		}
+		for i, piece := range opts.split(withTrail(preamble, trail), text) {
+			header := withTrail(preamble, sec.trail)
+			if i > 0 {
+				header = withTrail(preamble, trail)
+			}
+			chunks = append(chunks, Chunk{Text: header + "\n\n" + piece, Index: idx})
+			idx++
+		}
 	}
 
 	// Code block chunks
 	for _, code := range codeBlocks {
-		chunks = append(chunks, Chunk{Text: preamble + "\n\n```\n" + code + "\n```", Index: idx})
+		chunks = append(chunks, Chunk{Text: withTrail(preamble, code.trail) + "\n\n```\n" + code.text + "\n```", Index: idx})
 		idx++
 	}
 
@@ -68,27 +97,58 @@ func ChunkSections(preamble, markdown string) []Chunk {
 	return chunks
 }
 
+// withTrail appends the heading trail to a chunk's preamble. It goes before
+// the blank line so the chunk body, which snippets are cut from, is unchanged.
+func withTrail(preamble string, trail []string) string {
+	if len(trail) == 0 {
+		return preamble
+	}
+	return preamble + "\nSection: " + strings.Join(trail, " > ")
+}
+
 // splitSections walks the AST and splits text into heading-delimited sections.
 // Returns the sections, an optional summary (first paragraph text), and
-// extracted code blocks (>= 80 chars).
-func splitSections(doc ast.Node, source []byte) (sections []string, summary string, codeBlocks []string) {
+// extracted code blocks (>= 80 chars), each with its heading trail.
+func splitSections(doc ast.Node, source []byte) (sections []section, summary string, codeBlocks []section) {
 	children := doc.GetChildren()
 	if len(children) == 0 {
-		return []string{string(source)}, "", nil
+		return []section{{text: string(source)}}, "", nil
 	}
 
 	var headingOffsets []int
+	var headingTrails [][]string
+	var headingTitles []string
 	var firstParagraph *ast.Paragraph
 	foundHeading := false
 
+	// open holds the headings enclosing the current position, outermost first.
+	type openHeading struct {
+		level int
+		title string
+	}
+	var open []openHeading
+	titles := func() []string {
+		trail := make([]string, len(open))
+		for i, h := range open {
+			trail[i] = h.title
+		}
+		return trail
+	}
+
 	for _, child := range children {
 		switch n := child.(type) {
 		case *ast.Heading:
 			foundHeading = true
+			for len(open) > 0 && open[len(open)-1].level >= n.Level {
+				open = open[:len(open)-1]
+			}
 			offset := findHeadingOffset(source, n, headingOffsets)
 			if offset >= 0 {
 				headingOffsets = append(headingOffsets, offset)
+				headingTrails = append(headingTrails, titles())
+				headingTitles = append(headingTitles, extractNodeText(n))
 			}
+			open = append(open, openHeading{level: n.Level, title: extractNodeText(n)})
 		case *ast.Paragraph:
 			if !foundHeading && firstParagraph == nil {
 				firstParagraph = n
@@ -99,7 +159,7 @@ func splitSections(doc ast.Node, source []byte) (sections []string, summary stri
 		if cb, ok := child.(*ast.CodeBlock); ok {
 			code := strings.TrimSpace(string(cb.Literal))
 			if len(code) >= 80 {
-				codeBlocks = append(codeBlocks, code)
+				codeBlocks = append(codeBlocks, section{text: code, trail: titles()})
 			}
 		}
 	}
@@ -111,7 +171,7 @@ func splitSections(doc ast.Node, source []byte) (sections []string, summary stri
 
 	// Split source on heading offsets
 	if len(headingOffsets) == 0 {
-		return []string{string(source)}, summary, codeBlocks
+		return []section{{text: string(source)}}, summary, codeBlocks
 	}
 
 	src := string(source)
@@ -120,7 +180,7 @@ func splitSections(doc ast.Node, source []byte) (sections []string, summary stri
 			// Content before first heading = intro section
 			intro := strings.TrimSpace(src[:offset])
 			if intro != "" {
-				sections = append(sections, intro)
+				sections = append(sections, section{text: intro})
 			}
 		}
 		end := len(src)
@@ -129,7 +189,7 @@ func splitSections(doc ast.Node, source []byte) (sections []string, summary stri
 		}
 		sec := strings.TrimSpace(src[offset:end])
 		if sec != "" {
-			sections = append(sections, sec)
+			sections = append(sections, section{text: sec, title: headingTitles[i], trail: headingTrails[i]})
 		}
 	}
 
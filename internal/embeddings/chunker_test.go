@@ -222,6 +222,35 @@ func TestChunkSections_MultipleCodeBlocks(t *testing.T) {
 	}
 }
 
+func TestChunkSections_HeadingTrail(t *testing.T) {
+	code := strings.Repeat("let rt = Runtime::new();\n", 4)
+	md := "Summary.\n\n# Runtime\n\nAbout runtimes.\n\n## Scheduling\n\nHow tasks run.\n\n### Multi-thread\n\nWork stealing.\n\n```rust\n" + code + "```\n\n## Shutdown\n\nStopping.\n\n# Tasks\n\nSpawning.\n"
+	chunks := ChunkSections("tokio", md)
+
+	want := map[string]string{
+		"# Runtime":        "tokio\n\n",
+		"## Scheduling":    "tokio\nSection: Runtime\n\n",
+		"### Multi-thread": "tokio\nSection: Runtime > Scheduling\n\n",
+		"## Shutdown":      "tokio\nSection: Runtime\n\n",
+		"# Tasks":          "tokio\n\n",
+		"```":              "tokio\nSection: Runtime > Scheduling > Multi-thread\n\n",
+	}
+	for heading, prefix := range want {
+		found := false
+		for _, c := range chunks {
+			if _, body, ok := strings.Cut(c.Text, "\n\n"); ok && strings.HasPrefix(body, heading) {
+				found = true
+				if !strings.HasPrefix(c.Text, prefix) {
+					t.Errorf("chunk starting %q = %q, want prefix %q", heading, c.Text, prefix)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("no chunk starts with %q in %v", heading, chunkTexts(chunks))
+		}
+	}
+}
+
 func chunkTexts(chunks []Chunk) []string {
 	texts := make([]string, len(chunks))
 	for i, c := range chunks {
@@ -0,0 +1,143 @@
+package embeddings
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// FakeModel is the model pseudo-embeddings are recorded under, so an index
+// built with them is never taken for one built by Voyage.
+const FakeModel = "fake"
+
+// FakeTransport answers Voyage's embeddings and rerank requests locally
+// with pseudo-embeddings: each word and pair of adjacent words is hashed
+// into a dimension, so texts sharing words land near each other. Results
+// are deterministic and need no key or network, for CI and trying things
+// out; they say nothing about meaning. Only FakeModel is embedded, so
+// vectors can't mix with a real model's.
+type FakeTransport struct{}
+
+func (FakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		defer req.Body.Close()
+	}
+	switch {
+	case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/embeddings"):
+		var er EmbedRequest
+		if err := json.NewDecoder(req.Body).Decode(&er); err != nil {
+			return fakeResponse(req, http.StatusBadRequest, map[string]string{"detail": err.Error()}), nil
+		}
+		if er.Model != FakeModel {
+			return fakeResponse(req, http.StatusBadRequest, map[string]string{
+				"detail": fmt.Sprintf("the fake provider only embeds with model %q, not %q; run \"rsdoc reembed\"", FakeModel, er.Model),
+			}), nil
+		}
+		dim := er.OutputDimension
+		if dim <= 0 {
+			dim = models[FakeModel].dim
+		}
+		var resp EmbedResponse
+		for i, text := range er.Input {
+			resp.Data = append(resp.Data, struct {
+				Embedding []float32 `json:"embedding"`
+				Index     int       `json:"index"`
+			}{fakeEmbedding(text, dim), i})
+		}
+		resp.Usage.TotalTokens = EstimateTokens(er.Input...)
+		return fakeResponse(req, http.StatusOK, resp), nil
+
+	case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/rerank"):
+		var rr RerankRequest
+		if err := json.NewDecoder(req.Body).Decode(&rr); err != nil {
+			return fakeResponse(req, http.StatusBadRequest, map[string]string{"detail": err.Error()}), nil
+		}
+		return fakeResponse(req, http.StatusOK, fakeRerank(rr)), nil
+	}
+	return fakeResponse(req, http.StatusNotFound, map[string]string{"detail": "not found"}), nil
+}
+
+// fakeRerank scores each document by its pseudo-embedding's similarity to
+// the query's, best first.
+func fakeRerank(rr RerankRequest) RerankResponse {
+	const dim = 256
+	query := fakeEmbedding(rr.Query, dim)
+	var resp RerankResponse
+	for i, doc := range rr.Documents {
+		var dot float32
+		for j, v := range fakeEmbedding(doc, dim) {
+			dot += v * query[j]
+		}
+		resp.Data = append(resp.Data, struct {
+			Index          int     `json:"index"`
+			RelevanceScore float32 `json:"relevance_score"`
+		}{i, max(dot, 0)})
+	}
+	sort.SliceStable(resp.Data, func(a, b int) bool { return resp.Data[a].RelevanceScore > resp.Data[b].RelevanceScore })
+	if rr.TopK > 0 && len(resp.Data) > rr.TopK {
+		resp.Data = resp.Data[:rr.TopK]
+	}
+	resp.Usage.TotalTokens = EstimateTokens(rr.Documents...) + len(rr.Documents)*EstimateTokens(rr.Query)
+	return resp
+}
+
+// fakeEmbedding hashes text's lowercased words and word pairs into a unit
+// vector of dim dimensions. A hash bit picks each feature's sign, so
+// collisions tend to cancel out rather than pile up.
+func fakeEmbedding(text string, dim int) []float32 {
+	vec := make([]float32, dim)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	add := func(feature string, weight float32) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		if sum&1 == 1 {
+			weight = -weight
+		}
+		vec[(sum>>1)%uint64(dim)] += weight
+	}
+	for i, w := range words {
+		add(w, 1)
+		if i > 0 {
+			add(words[i-1]+" "+w, 0.5)
+		}
+	}
+
+	var norm float64
+	for _, v := range vec {
+		norm += float64(v) * float64(v)
+	}
+	if norm == 0 {
+		vec[0] = 1
+		return vec
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for i := range vec {
+		vec[i] *= scale
+	}
+	return vec
+}
+
+func fakeResponse(req *http.Request, status int, v any) *http.Response {
+	body, _ := json.Marshal(v)
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
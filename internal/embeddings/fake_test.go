@@ -0,0 +1,68 @@
+package embeddings
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestFakeTransport_Embed(t *testing.T) {
+	t.Parallel()
+	c := NewVoyageClient("")
+	c.SetTransport(FakeTransport{})
+
+	texts := []string{
+		"Parses a string into an integer.",
+		"Parse a string to an integer",
+		"A lock-free queue for passing messages between threads.",
+	}
+	got, err := c.EmbedTexts(texts, FakeModel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || len(got[0]) != 1024 {
+		t.Fatalf("got %d embeddings of %d dimensions", len(got), len(got[0]))
+	}
+	dot := func(a, b []float32) float64 {
+		var d float64
+		for i := range a {
+			d += float64(a[i] * b[i])
+		}
+		return d
+	}
+	if n := dot(got[0], got[0]); math.Abs(n-1) > 1e-4 {
+		t.Errorf("embedding norm² = %v, want 1", n)
+	}
+	if near, far := dot(got[0], got[1]), dot(got[0], got[2]); near <= far {
+		t.Errorf("similar texts scored %v, unrelated %v", near, far)
+	}
+
+	again, err := c.EmbedTexts(texts[:1], FakeModel)
+	if err != nil || dot(again[0], got[0]) < 1-1e-4 {
+		t.Errorf("embeddings aren't deterministic: %v", err)
+	}
+
+	c.SetOutputDimension(FakeModel, 256)
+	if small, err := c.EmbedTexts(texts[:1], FakeModel); err != nil || len(small[0]) != 256 {
+		t.Errorf("output dimension 256: %v", err)
+	}
+
+	if _, err := c.EmbedTexts(texts, "voyage-3.5"); err == nil || !strings.Contains(err.Error(), "fake provider") {
+		t.Errorf("embedding with a real model: %v", err)
+	}
+}
+
+func TestFakeTransport_Rerank(t *testing.T) {
+	t.Parallel()
+	c := NewVoyageClient("")
+	c.SetTransport(FakeTransport{})
+
+	docs := []string{"Spawns a thread.", "Formats an integer as a string.", "Reads a file."}
+	got, err := c.Rerank("integer to string", docs, "", 2, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].OriginalIndex != 1 || got[0].RelevanceScore <= got[1].RelevanceScore {
+		t.Errorf("rerank = %+v, want document 1 first of 2", got)
+	}
+}
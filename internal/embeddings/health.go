@@ -0,0 +1,129 @@
+package embeddings
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Provider states reported by VoyageClient.Health.
+const (
+	ProviderUnknown      = "unknown"      // no request made yet
+	ProviderOK           = "ok"           // recent requests succeeded
+	ProviderDegraded     = "degraded"     // recent rate limiting, server or network errors
+	ProviderOverQuota    = "over_quota"   // the last request was rate limited
+	ProviderUnauthorized = "unauthorized" // the API key is missing or was rejected
+)
+
+// healthWindow is how far back failures count towards a degraded state.
+const healthWindow = 15 * time.Minute
+
+// Health summarizes recent Voyage API responses.
+type Health struct {
+	State       string
+	LastError   string
+	LastErrorAt time.Time
+	// LastRequestAt is when the last request of any kind completed.
+	LastRequestAt time.Time
+	// RateLimited counts 429 responses within the health window.
+	RateLimited int
+	// RemainingRequests and RemainingTokens echo the provider's rate-limit
+	// headers from the last successful response, if it sent any.
+	RemainingRequests string
+	RemainingTokens   string
+}
+
+type healthTracker struct {
+	mu                sync.Mutex
+	lastStatus        int // 0 for network errors
+	lastAt            time.Time
+	lastErr           string
+	lastErrAt         time.Time
+	failures          []time.Time
+	throttled         []time.Time
+	remainingRequests string
+	remainingTokens   string
+}
+
+// record notes the outcome of a request. resp is nil when the request
+// failed before a response arrived.
+func (h *healthTracker) record(resp *http.Response, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now()
+	h.lastAt = now
+	h.lastStatus = 0
+	if resp != nil {
+		h.lastStatus = resp.StatusCode
+	}
+
+	switch {
+	case err != nil:
+		h.lastErr, h.lastErrAt = err.Error(), now
+		if h.lastStatus == http.StatusTooManyRequests {
+			h.throttled = append(h.throttled, now)
+		}
+		if h.lastStatus == 0 || h.lastStatus == http.StatusTooManyRequests || h.lastStatus >= 500 {
+			h.failures = append(h.failures, now)
+		}
+	case resp != nil:
+		if v := resp.Header.Get("X-Ratelimit-Remaining-Requests"); v != "" {
+			h.remainingRequests = v
+		}
+		if v := resp.Header.Get("X-Ratelimit-Remaining-Tokens"); v != "" {
+			h.remainingTokens = v
+		}
+	}
+}
+
+func (h *healthTracker) snapshot(hasKey bool) Health {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	cutoff := time.Now().Add(-healthWindow)
+	h.failures = pruneBefore(h.failures, cutoff)
+	h.throttled = pruneBefore(h.throttled, cutoff)
+
+	out := Health{
+		LastError:         h.lastErr,
+		LastErrorAt:       h.lastErrAt,
+		LastRequestAt:     h.lastAt,
+		RateLimited:       len(h.throttled),
+		RemainingRequests: h.remainingRequests,
+		RemainingTokens:   h.remainingTokens,
+	}
+	switch {
+	case !hasKey:
+		out.State = ProviderUnauthorized
+		out.LastError = "no Voyage AI API key configured"
+	case h.lastAt.IsZero():
+		out.State = ProviderUnknown
+	case h.lastStatus == http.StatusUnauthorized || h.lastStatus == http.StatusForbidden:
+		out.State = ProviderUnauthorized
+	case h.lastStatus == http.StatusTooManyRequests:
+		out.State = ProviderOverQuota
+	case len(h.failures) > 0:
+		out.State = ProviderDegraded
+	default:
+		out.State = ProviderOK
+	}
+	return out
+}
+
+func pruneBefore(times []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(times) && times[i].Before(cutoff) {
+		i++
+	}
+	return times[i:]
+}
+
+// Health reports the provider state seen by recent requests.
+func (c *VoyageClient) Health() Health {
+	return c.health.snapshot(c.key() != "")
+}
+
+// Check makes the smallest possible embedding request to refresh Health.
+func (c *VoyageClient) Check(model string) error {
+	_, err := c.EmbedSingle("ping", model)
+	return err
+}
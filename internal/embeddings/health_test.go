@@ -0,0 +1,57 @@
+package embeddings
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestVoyageHealth(t *testing.T) {
+	t.Parallel()
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Ratelimit-Remaining-Requests", "42")
+		w.WriteHeader(int(status.Load()))
+		w.Write([]byte(`{"data":[{"embedding":[1],"index":0}]}`))
+	}))
+	defer srv.Close()
+
+	c := NewVoyageClient("key")
+	c.baseURL = srv.URL
+	if h := c.Health(); h.State != ProviderUnknown {
+		t.Errorf("before any request: state %q", h.State)
+	}
+
+	if err := c.Check(""); err != nil {
+		t.Fatal(err)
+	}
+	if h := c.Health(); h.State != ProviderOK || h.RemainingRequests != "42" {
+		t.Errorf("after success: %+v", h)
+	}
+
+	status.Store(http.StatusTooManyRequests)
+	if err := c.Check(""); err == nil {
+		t.Fatal("expected error on 429")
+	}
+	if h := c.Health(); h.State != ProviderOverQuota || h.RateLimited != 1 {
+		t.Errorf("after 429: %+v", h)
+	}
+
+	status.Store(http.StatusOK)
+	c.Check("")
+	if h := c.Health(); h.State != ProviderDegraded {
+		t.Errorf("after recovery: state %q, want degraded", h.State)
+	}
+
+	status.Store(http.StatusUnauthorized)
+	c.Check("")
+	if h := c.Health(); h.State != ProviderUnauthorized {
+		t.Errorf("after 401: state %q", h.State)
+	}
+
+	if h := NewVoyageClient("").Health(); h.State != ProviderUnauthorized {
+		t.Errorf("no key: state %q", h.State)
+	}
+}
@@ -0,0 +1,63 @@
+package embeddings
+
+import (
+	"fmt"
+	"slices"
+)
+
+type modelInfo struct {
+	dim int
+	// outputDims lists the output_dimension values the model accepts; nil
+	// for models with a fixed size.
+	outputDims []int
+}
+
+// matryoshkaDims are the sizes Voyage's Matryoshka-trained models produce.
+var matryoshkaDims = []int{256, 512, 1024, 2048}
+
+// models lists the default output dimension of Voyage embedding models.
+var models = map[string]modelInfo{
+	"voyage-3.5":            {1024, matryoshkaDims},
+	"voyage-3.5-lite":       {1024, matryoshkaDims},
+	"voyage-3-large":        {1024, matryoshkaDims},
+	"voyage-code-3":         {1024, matryoshkaDims},
+	"voyage-3":              {1024, nil},
+	"voyage-3-lite":         {512, nil},
+	"voyage-code-2":         {1536, nil},
+	"voyage-finance-2":      {1024, nil},
+	"voyage-law-2":          {1024, nil},
+	"voyage-multilingual-2": {1024, nil},
+	"voyage-large-2":        {1536, nil},
+	"voyage-2":              {1024, nil},
+	// FakeTransport's pseudo-embeddings take any of the same sizes.
+	FakeModel: {1024, matryoshkaDims},
+}
+
+// ModelDimension returns the embedding dimension of model. An override > 0
+// wins, for reduced dimensions or models missing from the registry; ok is
+// false when neither knows the dimension.
+func ModelDimension(model string, override int) (dim int, ok bool) {
+	if override > 0 {
+		return override, true
+	}
+	info, ok := models[model]
+	return info.dim, ok
+}
+
+// OutputDimension checks a configured dimension against model and returns
+// the output_dimension to request, 0 for the model's default size. For
+// models missing from the registry the dimension is only the expected size
+// and isn't requested.
+func OutputDimension(model string, dim int) (int, error) {
+	info, ok := models[model]
+	if dim <= 0 || !ok || dim == info.dim {
+		return 0, nil
+	}
+	if slices.Contains(info.outputDims, dim) {
+		return dim, nil
+	}
+	if info.outputDims == nil {
+		return 0, fmt.Errorf("%s only produces %d-dimensional embeddings", model, info.dim)
+	}
+	return 0, fmt.Errorf("%s supports output dimensions %v, not %d", model, info.outputDims, dim)
+}
@@ -0,0 +1,47 @@
+package embeddings
+
+import "testing"
+
+func TestModelDimension(t *testing.T) {
+	tests := []struct {
+		model    string
+		override int
+		want     int
+		ok       bool
+	}{
+		{"voyage-3.5", 0, 1024, true},
+		{"voyage-3-lite", 0, 512, true},
+		{"voyage-3.5", 2048, 2048, true},
+		{"voyage-future-9", 0, 0, false},
+		{"voyage-future-9", 768, 768, true},
+	}
+	for _, tt := range tests {
+		dim, ok := ModelDimension(tt.model, tt.override)
+		if dim != tt.want || ok != tt.ok {
+			t.Errorf("ModelDimension(%q, %d) = %d, %v; want %d, %v", tt.model, tt.override, dim, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestOutputDimension(t *testing.T) {
+	tests := []struct {
+		model   string
+		dim     int
+		want    int
+		wantErr bool
+	}{
+		{"voyage-3.5", 0, 0, false},
+		{"voyage-3.5", 1024, 0, false},
+		{"voyage-3.5", 256, 256, false},
+		{"voyage-3.5", 300, 0, true},
+		{"voyage-3-lite", 512, 0, false},
+		{"voyage-3-lite", 256, 0, true},
+		{"voyage-future-9", 768, 0, false},
+	}
+	for _, tt := range tests {
+		got, err := OutputDimension(tt.model, tt.dim)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("OutputDimension(%q, %d) = %d, %v; want %d, error %v", tt.model, tt.dim, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package embeddings
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// interactiveReserve is the share of each per-minute budget that bulk
+// requests leave untouched, so searches aren't queued behind indexing.
+const interactiveReserve = 0.2
+
+// Priority orders requests competing for the rate limit.
+type Priority int
+
+const (
+	// Interactive requests (query embeddings, reranks) may use the whole
+	// budget.
+	Interactive Priority = iota
+	// Bulk requests (indexing batches) stop short of the interactive reserve.
+	Bulk
+)
+
+// RateLimiter paces Voyage API requests to a requests-per-minute and
+// tokens-per-minute budget. Both refill continuously. A nil *RateLimiter
+// doesn't limit anything.
+type RateLimiter struct {
+	mu       sync.Mutex
+	requests *bucket
+	tokens   *bucket
+	last     time.Time
+
+	now   func() time.Time
+	sleep func(context.Context, time.Duration) error
+}
+
+// NewRateLimiter returns a limiter for the given per-minute budgets, where 0
+// leaves that dimension unlimited, or nil if both are 0.
+func NewRateLimiter(requestsPerMinute, tokensPerMinute int) *RateLimiter {
+	if requestsPerMinute <= 0 && tokensPerMinute <= 0 {
+		return nil
+	}
+	l := &RateLimiter{now: time.Now, sleep: sleepContext}
+	l.requests = newBucket(requestsPerMinute)
+	l.tokens = newBucket(tokensPerMinute)
+	l.last = l.now()
+	return l
+}
+
+// EstimateTokens approximates the tokens Voyage counts for texts, at ~4
+// bytes per token.
+func EstimateTokens(texts ...string) int {
+	n := 0
+	for _, t := range texts {
+		n += (len(t) + 3) / 4
+	}
+	return n
+}
+
+// Wait blocks until a request of about tokens tokens fits the budget at
+// priority p, then reserves it. It returns ctx's error if ctx ends first.
+func (l *RateLimiter) Wait(ctx context.Context, tokens int, p Priority) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	for {
+		l.mu.Lock()
+		l.refill()
+		wait := max(l.requests.wait(1, p), l.tokens.wait(float64(tokens), p))
+		if wait <= 0 {
+			l.requests.take(1)
+			l.tokens.take(float64(tokens))
+			l.mu.Unlock()
+			return nil
+		}
+		l.mu.Unlock()
+		if err := l.sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Settle corrects a reservation made by Wait once the provider has reported
+// the tokens actually used.
+func (l *RateLimiter) Settle(estimated, actual int) {
+	if l == nil || actual <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens.take(float64(actual - estimated))
+}
+
+func (l *RateLimiter) refill() {
+	now := l.now()
+	elapsed := now.Sub(l.last)
+	l.last = now
+	l.requests.refill(elapsed)
+	l.tokens.refill(elapsed)
+}
+
+// bucket is a token bucket holding up to one minute of budget. A nil
+// bucket is unlimited.
+type bucket struct {
+	capacity float64
+	level    float64
+}
+
+func newBucket(perMinute int) *bucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &bucket{capacity: float64(perMinute), level: float64(perMinute)}
+}
+
+func (b *bucket) refill(elapsed time.Duration) {
+	if b == nil {
+		return
+	}
+	b.level = min(b.capacity, b.level+b.capacity*elapsed.Minutes())
+}
+
+// wait returns how long until n fits above the floor for priority p. A
+// request larger than the usable budget goes through once the bucket is
+// full, so it can't wait forever.
+func (b *bucket) wait(n float64, p Priority) time.Duration {
+	if b == nil {
+		return 0
+	}
+	floor := 0.0
+	if p == Bulk {
+		floor = b.capacity * interactiveReserve
+	}
+	need := min(n, b.capacity-floor) + floor
+	if b.level >= need {
+		return 0
+	}
+	return time.Duration((need - b.level) / b.capacity * float64(time.Minute))
+}
+
+func (b *bucket) take(n float64) {
+	if b == nil {
+		return
+	}
+	b.level -= n
+}
@@ -0,0 +1,101 @@
+package embeddings
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// fakeClock drives a RateLimiter without real sleeps.
+type fakeClock struct {
+	t     time.Time
+	slept time.Duration
+}
+
+func newTestLimiter(rpm, tpm int) (*RateLimiter, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	l := NewRateLimiter(rpm, tpm)
+	l.now = func() time.Time { return clock.t }
+	l.sleep = func(_ context.Context, d time.Duration) error {
+		clock.slept += d
+		clock.t = clock.t.Add(d)
+		return nil
+	}
+	l.last = clock.t
+	return l, clock
+}
+
+func TestRateLimiterUnlimited(t *testing.T) {
+	if l := NewRateLimiter(0, 0); l != nil {
+		t.Fatalf("expected nil limiter, got %+v", l)
+	}
+	var l *RateLimiter
+	l.Wait(context.Background(), 1000, Bulk)
+	l.Settle(10, 20)
+}
+
+func TestRateLimiterRequests(t *testing.T) {
+	ctx := context.Background()
+	l, clock := newTestLimiter(60, 0)
+	for range 60 {
+		l.Wait(ctx, 0, Interactive)
+	}
+	if clock.slept != 0 {
+		t.Fatalf("slept %v within the budget", clock.slept)
+	}
+	l.Wait(ctx, 0, Interactive)
+	if clock.slept != time.Second {
+		t.Errorf("slept %v, want 1s for one request at 60/min", clock.slept)
+	}
+}
+
+func TestRateLimiterReserve(t *testing.T) {
+	ctx := context.Background()
+	l, clock := newTestLimiter(0, 1000)
+	l.Wait(ctx, 800, Bulk)
+	if clock.slept != 0 {
+		t.Fatalf("slept %v within the bulk budget", clock.slept)
+	}
+
+	// Bulk can't dip into the last 20%, but interactive can.
+	l.Wait(ctx, 200, Interactive)
+	if clock.slept != 0 {
+		t.Errorf("interactive request waited %v on the reserve", clock.slept)
+	}
+	l.Wait(ctx, 100, Bulk)
+	if want := 18 * time.Second; clock.slept != want {
+		t.Errorf("bulk slept %v, want %v to refill 300 tokens at 1000/min", clock.slept, want)
+	}
+}
+
+func TestRateLimiterOversized(t *testing.T) {
+	ctx := context.Background()
+	l, clock := newTestLimiter(0, 100)
+	l.Wait(ctx, 500, Bulk)
+	if clock.slept != 0 {
+		t.Errorf("oversized request on a full bucket slept %v", clock.slept)
+	}
+}
+
+func TestRateLimiterCancel(t *testing.T) {
+	l := NewRateLimiter(1, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := l.Wait(ctx, 0, Interactive); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if err := l.Wait(ctx, 0, Interactive); err != context.Canceled {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+}
+
+func TestRateLimiterSettle(t *testing.T) {
+	ctx := context.Background()
+	l, clock := newTestLimiter(0, 600)
+	l.Wait(ctx, 100, Interactive)
+	l.Settle(100, 600)
+	l.Wait(ctx, 60, Interactive)
+	if want := 6 * time.Second; clock.slept != want {
+		t.Errorf("slept %v, want %v after settling the actual usage", clock.slept, want)
+	}
+}
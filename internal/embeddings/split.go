@@ -0,0 +1,318 @@
+package embeddings
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// bytesPerToken matches the ratio EstimateTokens assumes.
+const bytesPerToken = 4
+
+// minPieceBytes keeps a piece useful when the preamble alone nearly fills
+// the token limit.
+const minPieceBytes = 256
+
+// ChunkerVersion changes whenever chunking changes the chunks it makes for
+// the same content, so embeddings of the old chunks can be found and
+// redone with "rsdoc reindex --changed-only".
+const ChunkerVersion = 1
+
+// ChunkOptions limits chunk size. The zero value leaves chunks unlimited.
+type ChunkOptions struct {
+	// MaxTokens caps each chunk's estimated tokens, preamble included.
+	// Longer sections are split between paragraphs, list items or table
+	// rows; a code block is never split, so one longer than this is sent
+	// whole and Voyage truncates it.
+	MaxTokens int
+	// OverlapTokens repeats up to this many tokens from the end of one
+	// piece of a split section at the start of the next, so text near the
+	// boundary keeps its context.
+	OverlapTokens int
+}
+
+// Version identifies the chunks these options produce: ChunkerVersion,
+// followed by the limits when there are any, e.g. "1" or "1/512+64".
+func (o ChunkOptions) Version() string {
+	if o.MaxTokens <= 0 {
+		return strconv.Itoa(ChunkerVersion)
+	}
+	return fmt.Sprintf("%d/%d+%d", ChunkerVersion, o.MaxTokens, max(o.OverlapTokens, 0))
+}
+
+// block is a paragraph-level unit of markdown. Whole blocks (code, tables
+// and list items) are never cut or partly repeated as overlap.
+type block struct {
+	text  string
+	whole bool
+}
+
+// split breaks a section's text into pieces that fit MaxTokens once header
+// (the chunk's preamble) is put in front of them.
+func (o ChunkOptions) split(header, text string) []string {
+	if o.MaxTokens <= 0 || EstimateTokens(header+"\n\n"+text) <= o.MaxTokens {
+		return []string{text}
+	}
+	budget := max((o.MaxTokens-EstimateTokens(header+"\n\n"))*bytesPerToken, minPieceBytes)
+	overlap := min(o.OverlapTokens*bytesPerToken, budget/2)
+
+	var pieces []string
+	var cur []block
+	size := 0
+	for _, b := range splitBlocks(text, budget) {
+		if size > 0 && size+2+len(b.text) > budget {
+			pieces = append(pieces, joinBlocks(cur))
+			cur = overlapTail(cur, overlap)
+			size = len(joinBlocks(cur))
+			if size > 0 && size+2+len(b.text) > budget {
+				cur, size = nil, 0
+			}
+		}
+		if size > 0 {
+			size += 2
+		}
+		cur = append(cur, b)
+		size += len(b.text)
+	}
+	if len(cur) > 0 {
+		pieces = append(pieces, joinBlocks(cur))
+	}
+	return pieces
+}
+
+// splitBlocks cuts markdown at blank lines outside code fences, keeping a
+// list's items together. Blocks longer than budget are cut further: tables
+// between rows, with their header repeated, lists between items, and prose
+// at line breaks or spaces. Code blocks are never cut.
+func splitBlocks(text string, budget int) []block {
+	var blocks []block
+	for _, raw := range rawBlocks(text) {
+		t := strings.Join(raw, "\n")
+		switch {
+		case isFence(raw[0]) || len(t) <= budget && isStructured(raw):
+			blocks = append(blocks, block{text: t, whole: true})
+		case isTable(raw):
+			for _, part := range splitTable(raw, budget) {
+				blocks = append(blocks, block{text: part, whole: true})
+			}
+		case listItem.MatchString(raw[0]):
+			blocks = append(blocks, splitList(raw, budget)...)
+		default:
+			for _, p := range splitProse(t, budget, "\n", " ") {
+				blocks = append(blocks, block{text: p})
+			}
+		}
+	}
+	return blocks
+}
+
+// listItem matches the first line of a bullet or numbered list item.
+var listItem = regexp.MustCompile(`^ {0,3}([-*+]|\d{1,9}[.)])(\s|$)`)
+
+// tableDelimiter matches a table's header separator row, e.g. "|---|:-:|".
+var tableDelimiter = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+
+// rawBlocks groups lines into blocks separated by blank lines, keeping code
+// fences whole and the items of a loose list (blank lines between items)
+// in one block.
+func rawBlocks(text string) [][]string {
+	var blocks [][]string
+	var cur []string
+	fence := ""
+	inList := false
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+		case isFence(trimmed) && !inList:
+			if len(cur) > 0 {
+				blocks = append(blocks, cur)
+				cur = nil
+			}
+			fence = trimmed[:3]
+		case trimmed == "":
+			if len(cur) > 0 && !inList {
+				blocks = append(blocks, cur)
+				cur = nil
+			}
+			if inList {
+				cur = append(cur, line)
+			}
+			continue
+		case inList && !listItem.MatchString(line) && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") && strings.TrimSpace(cur[len(cur)-1]) == "":
+			// A paragraph after a blank line ends the list.
+			blocks = append(blocks, trimBlank(cur))
+			cur, inList = nil, false
+		}
+		if len(cur) == 0 && fence == "" && listItem.MatchString(line) {
+			inList = true
+		}
+		cur = append(cur, line)
+	}
+	if len(cur) > 0 {
+		blocks = append(blocks, trimBlank(cur))
+	}
+	return blocks
+}
+
+// trimBlank drops the blank lines ending a block.
+func trimBlank(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func isFence(line string) bool {
+	line = strings.TrimSpace(line)
+	return strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~")
+}
+
+func isTable(lines []string) bool {
+	return len(lines) >= 2 && strings.Contains(lines[0], "|") && tableDelimiter.MatchString(lines[1])
+}
+
+func isStructured(lines []string) bool {
+	return isTable(lines) || listItem.MatchString(lines[0])
+}
+
+// splitTable cuts a table between rows into parts of about budget bytes,
+// each starting with the header and delimiter rows so it still reads as a
+// table.
+func splitTable(lines []string, budget int) []string {
+	header := strings.Join(lines[:2], "\n")
+	var parts []string
+	cur := header
+	for _, row := range lines[2:] {
+		if cur != header && len(cur)+1+len(row) > budget {
+			parts = append(parts, cur)
+			cur = header
+		}
+		cur += "\n" + row
+	}
+	return append(parts, cur)
+}
+
+// splitList cuts a list between its items into blocks of about budget
+// bytes. An item longer than that is cut like prose.
+func splitList(lines []string, budget int) []block {
+	indent := len(lines[0]) - len(strings.TrimLeft(lines[0], " "))
+	var items []string
+	for _, line := range lines {
+		lineIndent := len(line) - len(strings.TrimLeft(line, " "))
+		if len(items) == 0 || lineIndent <= indent && listItem.MatchString(line) {
+			items = append(items, line)
+		} else {
+			items[len(items)-1] += "\n" + line
+		}
+	}
+
+	var blocks []block
+	cur := ""
+	for _, item := range items {
+		item = strings.TrimRight(item, "\n ")
+		if cur != "" && len(cur)+1+len(item) > budget {
+			blocks = append(blocks, block{text: cur, whole: true})
+			cur = ""
+		}
+		if len(item) > budget {
+			for _, p := range splitProse(item, budget, "\n", " ") {
+				blocks = append(blocks, block{text: p})
+			}
+			continue
+		}
+		if cur != "" {
+			cur += "\n"
+		}
+		cur += item
+	}
+	if cur != "" {
+		blocks = append(blocks, block{text: cur, whole: true})
+	}
+	return blocks
+}
+
+// splitProse cuts s into parts of at most budget bytes at the first of seps
+// that occurs in it, falling back to the next separator for parts that are
+// still too long, and finally to a cut at a character boundary.
+func splitProse(s string, budget int, seps ...string) []string {
+	if len(s) <= budget {
+		return []string{s}
+	}
+	if len(seps) == 0 {
+		cut := budget
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		if cut == 0 {
+			cut = budget
+		}
+		return append([]string{s[:cut]}, splitProse(s[cut:], budget)...)
+	}
+	sep := seps[0]
+	var parts []string
+	cur := ""
+	for _, word := range strings.Split(s, sep) {
+		if cur != "" && len(cur)+len(sep)+len(word) > budget {
+			parts = append(parts, cur)
+			cur = ""
+		}
+		if cur != "" {
+			cur += sep
+		}
+		cur += word
+		if len(cur) > budget {
+			long := splitProse(cur, budget, seps[1:]...)
+			parts = append(parts, long[:len(long)-1]...)
+			cur = long[len(long)-1]
+		}
+	}
+	if strings.TrimSpace(cur) != "" {
+		parts = append(parts, cur)
+	}
+	return parts
+}
+
+// overlapTail returns the blocks ending blocks that fit in overlap bytes,
+// or, if even the last doesn't, the words ending it. Whole blocks are only
+// repeated whole.
+func overlapTail(blocks []block, overlap int) []block {
+	if overlap <= 0 || len(blocks) == 0 {
+		return nil
+	}
+	start, size := len(blocks), 0
+	for start > 0 && size+len(blocks[start-1].text)+2 <= overlap {
+		start--
+		size += len(blocks[start].text) + 2
+	}
+	if start < len(blocks) {
+		return append([]block(nil), blocks[start:]...)
+	}
+	last := blocks[len(blocks)-1]
+	if last.whole {
+		return nil
+	}
+	tail := last.text[max(len(last.text)-overlap, 0):]
+	if i := strings.IndexAny(tail, " \n"); i >= 0 {
+		tail = tail[i+1:]
+	} else {
+		return nil
+	}
+	if tail = strings.TrimSpace(tail); tail == "" {
+		return nil
+	}
+	return []block{{text: tail}}
+}
+
+func joinBlocks(blocks []block) string {
+	texts := make([]string, len(blocks))
+	for i, b := range blocks {
+		texts[i] = b.text
+	}
+	return strings.Join(texts, "\n\n")
+}
@@ -0,0 +1,152 @@
+package embeddings
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestChunkSectionsWith_SplitsLongSections(t *testing.T) {
+	para := func(word string) string { return strings.TrimSpace(strings.Repeat(word+" ", 60)) }
+	code := "```rust\n" + strings.Repeat("let x = compute();\n", 30) + "```"
+	md := "# Guide\n\n" + para("alpha") + "\n\n" + para("bravo") + "\n\n" + code + "\n\n" + para("charlie") + "\n\n" + para("delta")
+	opts := ChunkOptions{MaxTokens: 150, OverlapTokens: 20}
+
+	chunks := ChunkSectionsWith("tokio", md, opts)
+	var sections []string
+	for _, c := range chunks {
+		if !strings.HasPrefix(c.Text, "tokio\n") {
+			t.Errorf("chunk missing preamble: %q", c.Text)
+		}
+		if _, body, _ := strings.Cut(c.Text, "\n\n"); strings.HasPrefix(body, "```\n") {
+			continue // the standalone code block chunk
+		}
+		sections = append(sections, c.Text)
+	}
+	if len(sections) < 3 {
+		t.Fatalf("expected the section split into several chunks, got %d:\n%s", len(sections), strings.Join(sections, "\n---\n"))
+	}
+
+	for i, text := range sections {
+		header, body, _ := strings.Cut(text, "\n\n")
+		if i == 0 {
+			if header != "tokio" || !strings.HasPrefix(body, "# Guide") {
+				t.Errorf("first piece should start with the heading: %q", text)
+			}
+		} else if header != "tokio\nSection: Guide" {
+			t.Errorf("piece %d header = %q, want the section's heading in its trail", i, header)
+		}
+		if strings.Contains(body, "```") {
+			if strings.Count(body, "```") != 2 || !strings.Contains(body, code) {
+				t.Errorf("piece %d splits the code block: %q", i, body)
+			}
+		} else if EstimateTokens(text) > opts.MaxTokens {
+			t.Errorf("piece %d is %d tokens, over the limit of %d", i, EstimateTokens(text), opts.MaxTokens)
+		}
+	}
+
+	// The piece after "alpha" starts with the end of it.
+	if !strings.HasPrefix(strings.SplitN(sections[1], "\n\n", 2)[1], "alpha") {
+		t.Errorf("second piece should overlap the first: %q", sections[1])
+	}
+}
+
+func TestChunkSectionsWith_ShortSectionsUnchanged(t *testing.T) {
+	md := "Summary.\n\n# A\n\nShort text.\n\n# B\n\nMore text."
+	got := ChunkSectionsWith("p", md, ChunkOptions{MaxTokens: 1000, OverlapTokens: 100})
+	want := ChunkSections("p", md)
+	if strings.Join(chunkTexts(got), "|") != strings.Join(chunkTexts(want), "|") {
+		t.Errorf("chunks = %q, want %q", chunkTexts(got), chunkTexts(want))
+	}
+}
+
+func TestSplitProse_LongLine(t *testing.T) {
+	s := strings.Repeat("word ", 50) + strings.Repeat("é", 100)
+	for _, part := range splitProse(s, 64, "\n", " ") {
+		if len(part) > 64 {
+			t.Errorf("part of %d bytes exceeds budget: %q", len(part), part)
+		}
+		if strings.ToValidUTF8(part, "?") != part {
+			t.Errorf("part cut inside a character: %q", part)
+		}
+	}
+}
+
+func TestSplitBlocks_TablesAndLists(t *testing.T) {
+	var rows []string
+	for i := range 20 {
+		rows = append(rows, "| `feature_"+string(rune('a'+i))+"` | enables the thing |")
+	}
+	table := "| Feature | Description |\n|---|---|\n" + strings.Join(rows, "\n")
+	list := "- first item\n  continued\n\n- second item\n\n- third item\n  1. nested"
+	md := "Intro.\n\n" + table + "\n\n" + list + "\n\nAfter the list."
+
+	blocks := splitBlocks(md, 300)
+	var texts []string
+	for _, b := range blocks {
+		texts = append(texts, b.text)
+	}
+
+	var tableParts int
+	for _, b := range blocks {
+		if !strings.Contains(b.text, "`feature_") {
+			continue
+		}
+		tableParts++
+		if !strings.HasPrefix(b.text, "| Feature | Description |\n|---|---|\n") || !b.whole {
+			t.Errorf("table part doesn't start with the header: %q", b.text)
+		}
+		if len(b.text) > 300 {
+			t.Errorf("table part of %d bytes exceeds the budget", len(b.text))
+		}
+	}
+	if tableParts < 2 {
+		t.Errorf("expected the table split into parts, got %q", texts)
+	}
+
+	var listBlock string
+	for _, b := range blocks {
+		if strings.Contains(b.text, "first item") {
+			listBlock = b.text
+		}
+	}
+	for _, want := range []string{"continued", "second item", "third item", "1. nested"} {
+		if !strings.Contains(listBlock, want) {
+			t.Errorf("list block %q should keep %q with the other items", listBlock, want)
+		}
+	}
+	if last := blocks[len(blocks)-1].text; last != "After the list." {
+		t.Errorf("last block = %q, want the paragraph after the list", last)
+	}
+}
+
+func TestSplitList_AtItemBoundaries(t *testing.T) {
+	var items []string
+	for i := range 10 {
+		items = append(items, "- item "+string(rune('a'+i))+" "+strings.Repeat("x", 40))
+	}
+	blocks := splitBlocks(strings.Join(items, "\n"), 120)
+	if len(blocks) < 4 {
+		t.Fatalf("expected the list split, got %d blocks", len(blocks))
+	}
+	for _, b := range blocks {
+		for _, line := range strings.Split(b.text, "\n") {
+			if !strings.HasPrefix(line, "- item ") {
+				t.Errorf("block split inside an item: %q", b.text)
+			}
+		}
+	}
+}
+
+func TestChunkOptionsVersion(t *testing.T) {
+	if got, want := (ChunkOptions{}).Version(), strconv.Itoa(ChunkerVersion); got != want {
+		t.Errorf("unlimited Version() = %q, want %q", got, want)
+	}
+	limited := ChunkOptions{MaxTokens: 512, OverlapTokens: 64}
+	if limited.Version() == (ChunkOptions{MaxTokens: 512}).Version() {
+		t.Error("overlap doesn't change the version")
+	}
+	if limited.Version() == (ChunkOptions{}).Version() {
+		t.Error("a token limit doesn't change the version")
+	}
+}
@@ -2,17 +2,34 @@ package embeddings
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"sync"
 	"time"
 )
 
 type VoyageClient struct {
 	apiKey  string
+	keyMu   sync.RWMutex
 	baseURL string
 	client  *http.Client
+	health  healthTracker
+
+	// outputDims holds the output_dimension requested per model.
+	outputDims   map[string]int
+	outputDimsMu sync.RWMutex
+
+	limiter *RateLimiter
+
+	// refreshKey re-resolves the API key after the API rejects it; see
+	// SetKeyRefresher.
+	refreshKey  func() (string, error)
+	lastRefresh time.Time
+	refreshMu   sync.Mutex
 }
 
 func NewVoyageClient(apiKey string) *VoyageClient {
@@ -23,9 +40,97 @@ func NewVoyageClient(apiKey string) *VoyageClient {
 	}
 }
 
+// SetTransport replaces the HTTP transport used for Voyage API requests.
+func (c *VoyageClient) SetTransport(rt http.RoundTripper) {
+	c.client.Transport = rt
+}
+
+// SetRateLimiter paces all requests made through c, including those of
+// BatchEmbedders wrapping it. Call before the client is in use.
+func (c *VoyageClient) SetRateLimiter(l *RateLimiter) {
+	c.limiter = l
+}
+
+// SetKeyRefresher lets c recover from a rotated API key: when the API
+// rejects the key, refresh is asked for the current one and, if it differs,
+// the request is retried once with it. Refreshes are at most a minute apart.
+// Call before the client is in use.
+func (c *VoyageClient) SetKeyRefresher(refresh func() (string, error)) {
+	c.refreshKey = refresh
+}
+
+func (c *VoyageClient) key() string {
+	c.keyMu.RLock()
+	defer c.keyMu.RUnlock()
+	return c.apiKey
+}
+
+// keyRefreshInterval bounds how often a rejected key is re-resolved, since
+// resolving may run a password manager.
+const keyRefreshInterval = time.Minute
+
+// renewKey replaces rejected with a freshly resolved key, reporting whether
+// a retry could succeed: the key changed, here or in a concurrent request.
+func (c *VoyageClient) renewKey(rejected string) bool {
+	if c.refreshKey == nil {
+		return false
+	}
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	if c.key() != rejected {
+		return true
+	}
+	if time.Since(c.lastRefresh) < keyRefreshInterval {
+		return false
+	}
+	c.lastRefresh = time.Now()
+	key, err := c.refreshKey()
+	if err != nil {
+		slog.Warn("re-resolving rejected API key failed", "error", err)
+		return false
+	}
+	if key == "" || key == rejected {
+		return false
+	}
+	slog.Info("API key was rejected; retrying with the re-resolved key")
+	c.keyMu.Lock()
+	c.apiKey = key
+	c.keyMu.Unlock()
+	return true
+}
+
+// post sends a JSON body to the API, retrying once with a re-resolved key
+// if the current one is rejected.
+func (c *VoyageClient) post(ctx context.Context, path string, body []byte) (*http.Response, error) {
+	send := func(key string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("creating request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+key)
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("sending request: %w", err)
+		}
+		return resp, nil
+	}
+
+	key := c.key()
+	resp, err := send(key)
+	if err == nil && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden) && c.renewKey(key) {
+		resp.Body.Close()
+		resp, err = send(c.key())
+	}
+	return resp, err
+}
+
 type EmbedRequest struct {
 	Input []string `json:"input"`
 	Model string   `json:"model"`
+	// OutputDimension shrinks embeddings from models trained for it
+	// (Matryoshka); 0 leaves the model's default.
+	OutputDimension int `json:"output_dimension,omitempty"`
 }
 
 type EmbedResponse struct {
@@ -38,56 +143,93 @@ type EmbedResponse struct {
 	} `json:"usage"`
 }
 
-func (c *VoyageClient) EmbedTexts(texts []string, model string) ([][]float32, error) {
-	if len(texts) == 0 {
-		return nil, fmt.Errorf("no texts provided")
+// SetOutputDimension makes EmbedTexts request dim dimensions from model; 0
+// restores the model's default.
+func (c *VoyageClient) SetOutputDimension(model string, dim int) {
+	c.outputDimsMu.Lock()
+	defer c.outputDimsMu.Unlock()
+	if c.outputDims == nil {
+		c.outputDims = make(map[string]int)
 	}
+	c.outputDims[model] = dim
+}
+
+func (c *VoyageClient) outputDimension(model string) int {
+	c.outputDimsMu.RLock()
+	defer c.outputDimsMu.RUnlock()
+	return c.outputDims[model]
+}
+
+// EmbedTexts embeds texts with model, at the output dimension set by
+// SetOutputDimension.
+func (c *VoyageClient) EmbedTexts(texts []string, model string) ([][]float32, error) {
 	if model == "" {
 		model = "voyage-3.5"
 	}
+	return c.EmbedTextsAt(texts, model, c.outputDimension(model))
+}
 
-	reqData := EmbedRequest{Input: texts, Model: model}
-	jsonData, err := json.Marshal(reqData)
-	if err != nil {
-		return nil, fmt.Errorf("marshaling request: %w", err)
+// EmbedTextsAt embeds texts with model, requesting outputDim dimensions; 0
+// uses the model's default.
+func (c *VoyageClient) EmbedTextsAt(texts []string, model string, outputDim int) ([][]float32, error) {
+	embeddings, _, err := c.embed(context.Background(), texts, model, outputDim, Interactive)
+	return embeddings, err
+}
+
+// embed embeds texts, returning the embeddings and the tokens the provider
+// billed for them.
+func (c *VoyageClient) embed(ctx context.Context, texts []string, model string, outputDim int, p Priority) ([][]float32, int, error) {
+	if len(texts) == 0 {
+		return nil, 0, fmt.Errorf("no texts provided")
+	}
+	estimated := EstimateTokens(texts...)
+	if err := c.limiter.Wait(ctx, estimated, p); err != nil {
+		return nil, 0, err
 	}
 
-	req, err := http.NewRequest("POST", c.baseURL+"/embeddings", bytes.NewBuffer(jsonData))
+	reqData := EmbedRequest{Input: texts, Model: model, OutputDimension: outputDim}
+	jsonData, err := json.Marshal(reqData)
 	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+		return nil, 0, fmt.Errorf("marshaling request: %w", err)
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
 
-	resp, err := c.client.Do(req)
+	resp, err := c.post(ctx, "/embeddings", jsonData)
 	if err != nil {
-		return nil, fmt.Errorf("sending request: %w", err)
+		// A cancelled caller says nothing about the provider's health.
+		if ctx.Err() == nil {
+			c.health.record(nil, err)
+		}
+		return nil, 0, err
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("reading response: %w", err)
+		return nil, 0, fmt.Errorf("reading response: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("voyage API returned %d: %s", resp.StatusCode, string(body))
+		err := fmt.Errorf("voyage API returned %d: %s", resp.StatusCode, string(body))
+		c.health.record(resp, err)
+		return nil, 0, err
 	}
+	c.health.record(resp, nil)
 
 	var embedResp EmbedResponse
 	if err := json.Unmarshal(body, &embedResp); err != nil {
-		return nil, fmt.Errorf("parsing response: %w", err)
+		return nil, 0, fmt.Errorf("parsing response: %w", err)
 	}
+	c.limiter.Settle(estimated, embedResp.Usage.TotalTokens)
 
 	embeddings := make([][]float32, len(texts))
 	for _, item := range embedResp.Data {
 		if item.Index >= len(embeddings) {
-			return nil, fmt.Errorf("invalid embedding index: %d", item.Index)
+			return nil, embedResp.Usage.TotalTokens, fmt.Errorf("invalid embedding index: %d", item.Index)
 		}
 		embeddings[item.Index] = item.Embedding
 	}
 
-	return embeddings, nil
+	return embeddings, embedResp.Usage.TotalTokens, nil
 }
 
 func (c *VoyageClient) EmbedSingle(text string, model string) ([]float32, error) {
@@ -101,54 +243,6 @@ func (c *VoyageClient) EmbedSingle(text string, model string) ([]float32, error)
 	return results[0], nil
 }
 
-type BatchEmbedder struct {
-	client    *VoyageClient
-	batchSize int
-	delay     time.Duration
-}
-
-func NewBatchEmbedder(client *VoyageClient, batchSize int, delay time.Duration) *BatchEmbedder {
-	if batchSize <= 0 {
-		batchSize = 50
-	}
-	if delay <= 0 {
-		delay = 200 * time.Millisecond
-	}
-	return &BatchEmbedder{client: client, batchSize: batchSize, delay: delay}
-}
-
-func (b *BatchEmbedder) EmbedAll(texts []string, model string, progress func(done, total int)) ([][]float32, error) {
-	if len(texts) == 0 {
-		return nil, fmt.Errorf("no texts provided")
-	}
-
-	var all [][]float32
-	for i := 0; i < len(texts); i += b.batchSize {
-		end := i + b.batchSize
-		if end > len(texts) {
-			end = len(texts)
-		}
-
-		batch := texts[i:end]
-		embeddings, err := b.client.EmbedTexts(batch, model)
-		if err != nil {
-			return nil, fmt.Errorf("embedding batch at offset %d: %w", i, err)
-		}
-
-		all = append(all, embeddings...)
-
-		if progress != nil {
-			progress(end, len(texts))
-		}
-
-		if end < len(texts) {
-			time.Sleep(b.delay)
-		}
-	}
-
-	return all, nil
-}
-
 type RerankRequest struct {
 	Query       string   `json:"query"`
 	Documents   []string `json:"documents"`
@@ -162,6 +256,9 @@ type RerankResponse struct {
 		Index          int     `json:"index"`
 		RelevanceScore float32 `json:"relevance_score"`
 	} `json:"data"`
+	Usage struct {
+		TotalTokens int `json:"total_tokens"`
+	} `json:"usage"`
 }
 
 type RerankResult struct {
@@ -177,22 +274,23 @@ func (c *VoyageClient) Rerank(query string, documents []string, model string, to
 		model = "rerank-lite-1"
 	}
 
+	// Every document is scored against the query, so the query counts once
+	// per document.
+	estimated := EstimateTokens(documents...) + len(documents)*EstimateTokens(query)
+	if err := c.limiter.Wait(context.Background(), estimated, Interactive); err != nil {
+		return nil, err
+	}
+
 	reqData := RerankRequest{Query: query, Documents: documents, Model: model, TopK: topK, Instruction: instruction}
 	jsonData, err := json.Marshal(reqData)
 	if err != nil {
 		return nil, fmt.Errorf("marshaling request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", c.baseURL+"/rerank", bytes.NewBuffer(jsonData))
+	resp, err := c.post(context.Background(), "/rerank", jsonData)
 	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
-
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("sending request: %w", err)
+		c.health.record(nil, err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -202,13 +300,17 @@ func (c *VoyageClient) Rerank(query string, documents []string, model string, to
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("voyage rerank API returned %d: %s", resp.StatusCode, string(body))
+		err := fmt.Errorf("voyage rerank API returned %d: %s", resp.StatusCode, string(body))
+		c.health.record(resp, err)
+		return nil, err
 	}
+	c.health.record(resp, nil)
 
 	var rerankResp RerankResponse
 	if err := json.Unmarshal(body, &rerankResp); err != nil {
 		return nil, fmt.Errorf("parsing response: %w", err)
 	}
+	c.limiter.Settle(estimated, rerankResp.Usage.TotalTokens)
 
 	var results []RerankResult
 	for _, item := range rerankResp.Data {
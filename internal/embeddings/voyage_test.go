@@ -0,0 +1,72 @@
+package embeddings
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEmbedTexts_OutputDimension(t *testing.T) {
+	t.Parallel()
+	got := make(chan int, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req EmbedRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		got <- req.OutputDimension
+		w.Write([]byte(`{"data":[{"embedding":[1],"index":0}]}`))
+	}))
+	defer srv.Close()
+
+	c := NewVoyageClient("key")
+	c.baseURL = srv.URL
+	if _, err := c.EmbedSingle("a", "voyage-3.5"); err != nil {
+		t.Fatal(err)
+	}
+	if d := <-got; d != 0 {
+		t.Errorf("default: sent output_dimension %d", d)
+	}
+
+	c.SetOutputDimension("voyage-3.5", 512)
+	if _, err := c.EmbedSingle("a", "voyage-3.5"); err != nil {
+		t.Fatal(err)
+	}
+	if d := <-got; d != 512 {
+		t.Errorf("after SetOutputDimension: sent output_dimension %d, want 512", d)
+	}
+}
+
+func TestKeyRefresh(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer new" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"data":[{"embedding":[1],"index":0}]}`))
+	}))
+	defer srv.Close()
+
+	c := NewVoyageClient("old")
+	c.baseURL = srv.URL
+	refreshes := 0
+	c.SetKeyRefresher(func() (string, error) {
+		refreshes++
+		return "new", nil
+	})
+	for range 2 {
+		if _, err := c.EmbedSingle("a", "voyage-3.5"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if refreshes != 1 {
+		t.Errorf("refreshed %d times, want 1", refreshes)
+	}
+
+	c = NewVoyageClient("old")
+	c.baseURL = srv.URL
+	c.SetKeyRefresher(func() (string, error) { return "old", nil })
+	if _, err := c.EmbedSingle("a", "voyage-3.5"); err == nil {
+		t.Error("rejected key with an unchanged refresh succeeded")
+	}
+}
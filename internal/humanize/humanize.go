@@ -0,0 +1,203 @@
+// Package humanize formats sizes, counts, durations and timestamps for CLI
+// reports.
+package humanize
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// separators holds the digit grouping and decimal marks for a locale.
+type separators struct {
+	group   string
+	decimal string
+}
+
+// localeSeparators maps language codes to their number separators. Languages
+// not listed use English conventions.
+var localeSeparators = map[string]separators{
+	"C":     {"", "."},
+	"POSIX": {"", "."},
+	"en":    {",", "."},
+	"ja":    {",", "."},
+	"zh":    {",", "."},
+	"ko":    {",", "."},
+	"de":    {".", ","},
+	"es":    {".", ","},
+	"it":    {".", ","},
+	"nl":    {".", ","},
+	"pt":    {".", ","},
+	"da":    {".", ","},
+	"id":    {".", ","},
+	"tr":    {".", ","},
+	"fr":    {" ", ","},
+	"ru":    {" ", ","},
+	"pl":    {" ", ","},
+	"cs":    {" ", ","},
+	"sv":    {" ", ","},
+	"fi":    {" ", ","},
+	"nb":    {" ", ","},
+	"uk":    {" ", ","},
+}
+
+// Formatter renders values according to a locale and time zone preference.
+type Formatter struct {
+	sep separators
+	utc bool
+}
+
+// New returns a Formatter for a POSIX-style locale name (e.g. "de_DE.UTF-8").
+// An empty locale is read from the environment. With utc set, timestamps are
+// printed as RFC 3339 in UTC, which is stable for scripts.
+func New(locale string, utc bool) Formatter {
+	if locale == "" {
+		locale = DetectLocale()
+	}
+	return Formatter{sep: lookupSeparators(locale), utc: utc}
+}
+
+// DetectLocale returns the numeric locale from LC_ALL, LC_NUMERIC or LANG.
+func DetectLocale() string {
+	for _, env := range []string{"LC_ALL", "LC_NUMERIC", "LANG"} {
+		if v := os.Getenv(env); v != "" {
+			return v
+		}
+	}
+	return "C"
+}
+
+func lookupSeparators(locale string) separators {
+	if s, ok := localeSeparators[locale]; ok {
+		return s
+	}
+	lang := locale
+	if i := strings.IndexAny(lang, "_.@"); i >= 0 {
+		lang = lang[:i]
+	}
+	if s, ok := localeSeparators[strings.ToLower(lang)]; ok {
+		return s
+	}
+	return localeSeparators["en"]
+}
+
+// Count formats an integer with digit grouping, e.g. 1,234,567.
+func (f Formatter) Count(n int64) string {
+	s := strconv.FormatInt(n, 10)
+	neg := strings.HasPrefix(s, "-")
+	if neg {
+		s = s[1:]
+	}
+	if f.sep.group != "" && len(s) > 3 {
+		var b strings.Builder
+		head := len(s) % 3
+		if head > 0 {
+			b.WriteString(s[:head])
+		}
+		for i := head; i < len(s); i += 3 {
+			if b.Len() > 0 {
+				b.WriteString(f.sep.group)
+			}
+			b.WriteString(s[i : i+3])
+		}
+		s = b.String()
+	}
+	if neg {
+		s = "-" + s
+	}
+	return s
+}
+
+// Bytes formats a size with binary units, e.g. 1.5 MiB.
+func (f Formatter) Bytes(n int64) string {
+	const unit = 1024
+	if n < unit && n > -unit {
+		return f.Count(n) + " B"
+	}
+	v := float64(n)
+	units := []string{"KiB", "MiB", "GiB", "TiB", "PiB"}
+	i := -1
+	for (v >= unit || v <= -unit) && i < len(units)-1 {
+		v /= unit
+		i++
+	}
+	return f.decimal(v, 1) + " " + units[i]
+}
+
+// Dollars formats an amount in US dollars to the cent, e.g. $1.25. Amounts
+// that round to nothing show as <$0.01 rather than $0.00.
+func (f Formatter) Dollars(v float64) string {
+	if v > 0 && v < 0.005 {
+		return "<$" + f.decimal(0.01, 2)
+	}
+	return "$" + f.decimal(v, 2)
+}
+
+// Duration formats a duration with its two most significant units, e.g.
+// "2d 4h", "3m 12s" or "850ms".
+func (f Formatter) Duration(d time.Duration) string {
+	if d < 0 {
+		return "-" + f.Duration(-d)
+	}
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	d = d.Round(time.Second)
+	parts := []struct {
+		n      int64
+		suffix string
+	}{
+		{int64(d / (24 * time.Hour)), "d"},
+		{int64(d/time.Hour) % 24, "h"},
+		{int64(d/time.Minute) % 60, "m"},
+		{int64(d/time.Second) % 60, "s"},
+	}
+	var out []string
+	for _, p := range parts {
+		if p.n == 0 && len(out) == 0 {
+			continue
+		}
+		out = append(out, fmt.Sprintf("%d%s", p.n, p.suffix))
+		if len(out) == 2 {
+			break
+		}
+	}
+	if len(out) == 2 && strings.HasPrefix(out[1], "0") {
+		out = out[:1]
+	}
+	return strings.Join(out, " ")
+}
+
+// Time formats a timestamp in local time, or RFC 3339 UTC if the Formatter
+// was created with utc set.
+func (f Formatter) Time(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	if f.utc {
+		return t.UTC().Format(time.RFC3339)
+	}
+	return t.Local().Format("2006-01-02 15:04 MST")
+}
+
+// Ago formats the time elapsed since t relative to now, e.g. "3h 5m ago".
+func (f Formatter) Ago(t, now time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	d := now.Sub(t)
+	if d < time.Second {
+		return "just now"
+	}
+	return f.Duration(d) + " ago"
+}
+
+func (f Formatter) decimal(v float64, prec int) string {
+	s := strconv.FormatFloat(v, 'f', prec, 64)
+	if f.sep.decimal != "." {
+		s = strings.Replace(s, ".", f.sep.decimal, 1)
+	}
+	return s
+}
@@ -0,0 +1,112 @@
+package humanize
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCount(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		locale string
+		n      int64
+		want   string
+	}{
+		{"en_US.UTF-8", 0, "0"},
+		{"en_US.UTF-8", 999, "999"},
+		{"en_US.UTF-8", 1234567, "1,234,567"},
+		{"en_US.UTF-8", -1234, "-1,234"},
+		{"de_DE.UTF-8", 1234567, "1.234.567"},
+		{"fr_FR", 12345, "12 345"},
+		{"C", 1234567, "1234567"},
+		{"xx_YY", 1234, "1,234"},
+	}
+	for _, tt := range tests {
+		if got := New(tt.locale, false).Count(tt.n); got != tt.want {
+			t.Errorf("Count(%q, %d) = %q, want %q", tt.locale, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestBytes(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		locale string
+		n      int64
+		want   string
+	}{
+		{"en", 512, "512 B"},
+		{"en", 1536, "1.5 KiB"},
+		{"en", 5 * 1024 * 1024, "5.0 MiB"},
+		{"de", 1536, "1,5 KiB"},
+	}
+	for _, tt := range tests {
+		if got := New(tt.locale, false).Bytes(tt.n); got != tt.want {
+			t.Errorf("Bytes(%q, %d) = %q, want %q", tt.locale, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestDollars(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		locale string
+		v      float64
+		want   string
+	}{
+		{"en", 0, "$0.00"},
+		{"en", 0.0012, "<$0.01"},
+		{"en", 0.09, "$0.09"},
+		{"en", 12.345, "$12.35"},
+		{"de", 1.5, "$1,50"},
+	}
+	for _, tt := range tests {
+		if got := New(tt.locale, false).Dollars(tt.v); got != tt.want {
+			t.Errorf("Dollars(%q, %v) = %q, want %q", tt.locale, tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestDuration(t *testing.T) {
+	t.Parallel()
+	f := New("C", false)
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{850 * time.Millisecond, "850ms"},
+		{45 * time.Second, "45s"},
+		{3*time.Minute + 12*time.Second, "3m 12s"},
+		{2 * time.Hour, "2h"},
+		{2*time.Hour + 30*time.Second, "2h"},
+		{52*time.Hour + 10*time.Minute, "2d 4h"},
+	}
+	for _, tt := range tests {
+		if got := f.Duration(tt.d); got != tt.want {
+			t.Errorf("Duration(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestTime_UTC(t *testing.T) {
+	t.Parallel()
+	ts := time.Date(2024, 3, 1, 12, 30, 0, 0, time.FixedZone("X", 3600))
+	if got := New("C", true).Time(ts); got != "2024-03-01T11:30:00Z" {
+		t.Errorf("got %q", got)
+	}
+	if got := New("C", true).Time(time.Time{}); got != "never" {
+		t.Errorf("zero time: got %q", got)
+	}
+}
+
+func TestAgo(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	f := New("C", false)
+	if got := f.Ago(now.Add(-90*time.Minute), now); got != "1h 30m ago" {
+		t.Errorf("got %q", got)
+	}
+	if got := f.Ago(now, now); got != "just now" {
+		t.Errorf("got %q", got)
+	}
+}
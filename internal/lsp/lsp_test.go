@@ -0,0 +1,153 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+)
+
+func TestSymbolAt(t *testing.T) {
+	text := "use tokio::sync::Mutex;\nlet m = tokio::sync::Mutex::new(0); // 🦀 Mutex\n"
+	tests := []struct {
+		line, char int
+		want       string
+		start, end int
+	}{
+		{1, 22, "tokio::sync::Mutex", 8, 26},
+		{1, 15, "tokio::sync", 8, 19},
+		{1, 26, "tokio::sync::Mutex", 8, 26}, // just past the word
+		{1, 29, "tokio::sync::Mutex::new", 8, 31},
+		{1, 7, "", 0, 0},
+		{1, 43, "Mutex", 42, 47}, // columns count UTF-16 units
+		{5, 0, "", 0, 0},
+	}
+	for _, tt := range tests {
+		got, start, end := symbolAt(text, tt.line, tt.char)
+		if got != tt.want || start != tt.start || end != tt.end {
+			t.Errorf("symbolAt(%d:%d) = %q [%d,%d), want %q [%d,%d)", tt.line, tt.char, got, start, end, tt.want, tt.start, tt.end)
+		}
+	}
+}
+
+func TestUseMap(t *testing.T) {
+	text := `use std::collections::HashMap;
+pub use tokio::sync::{
+    Mutex,
+    RwLock as Lock,
+    mpsc::{self, Sender},
+};
+use serde::*;
+use base64::Engine as _;
+`
+	got := useMap(text)
+	want := map[string]string{
+		"HashMap": "std::collections::HashMap",
+		"Mutex":   "tokio::sync::Mutex",
+		"Lock":    "tokio::sync::RwLock",
+		"mpsc":    "tokio::sync::mpsc",
+		"Sender":  "tokio::sync::mpsc::Sender",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("useMap = %v, want %v", got, want)
+	}
+
+	if got := resolvePath("mpsc::channel", got); got != "tokio::sync::mpsc::channel" {
+		t.Errorf("resolvePath(mpsc::channel) = %q", got)
+	}
+	if got := resolvePath("crate::config::Config", nil); got != "config::Config" {
+		t.Errorf("resolvePath(crate::config::Config) = %q", got)
+	}
+}
+
+type fakeBackend struct {
+	symbols []rpc.SymbolsRequest
+	docs    []rpc.GetDocRequest
+}
+
+func (b *fakeBackend) Symbols(ctx context.Context, req rpc.SymbolsRequest) (*rpc.SymbolsResponse, error) {
+	b.symbols = append(b.symbols, req)
+	if req.Query != "tokio::sync::Mutex" && req.Query != "Mutex" {
+		return &rpc.SymbolsResponse{}, nil
+	}
+	return &rpc.SymbolsResponse{Results: []rpc.DocResult{{
+		URI: "rsdoc://tokio/1.0.0/tokio::sync::Mutex", CrateName: "tokio", CrateVersion: "1.0.0", Path: "tokio::sync::Mutex", Kind: "struct",
+	}}}, nil
+}
+
+func (b *fakeBackend) GetDoc(ctx context.Context, req rpc.GetDocRequest) (*rpc.GetDocResponse, error) {
+	b.docs = append(b.docs, req)
+	return &rpc.GetDocResponse{Markdown: "---\nexamples: rsdoc://tokio/1.0.0/tokio::sync::Mutex#examples\n---\n\n# tokio::sync::Mutex\n"}, nil
+}
+
+func TestServe(t *testing.T) {
+	var in bytes.Buffer
+	send := func(msg string) {
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+	}
+	send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	send(`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///main.rs","text":"use tokio::sync::Mutex;\nlet m = Mutex::new(0);\n"}}}`)
+	send(`{"jsonrpc":"2.0","id":2,"method":"textDocument/hover","params":{"textDocument":{"uri":"file:///main.rs"},"position":{"line":1,"character":10}}}`)
+	send(`{"jsonrpc":"2.0","id":3,"method":"textDocument/hover","params":{"textDocument":{"uri":"file:///main.rs"},"position":{"line":1,"character":3}}}`)
+	send(`{"jsonrpc":"2.0","id":4,"method":"workspace/symbol","params":{"query":"Mutex"}}`)
+	send(`{"jsonrpc":"2.0","id":5,"method":"textDocument/definition","params":{}}`)
+	send(`{"jsonrpc":"2.0","method":"exit"}`)
+
+	backend := &fakeBackend{}
+	var out bytes.Buffer
+	if err := NewServer(backend).Serve(context.Background(), &in, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	r := bufio.NewReader(&out)
+	var responses []map[string]json.RawMessage
+	for {
+		body, err := readMessage(r)
+		if err != nil {
+			break
+		}
+		var resp map[string]json.RawMessage
+		if err := json.Unmarshal(body, &resp); err != nil {
+			t.Fatal(err)
+		}
+		responses = append(responses, resp)
+	}
+	if len(responses) != 5 {
+		t.Fatalf("got %d responses, want 5", len(responses))
+	}
+
+	if !strings.Contains(string(responses[0]["result"]), `"hoverProvider":true`) {
+		t.Errorf("initialize result = %s", responses[0]["result"])
+	}
+	var h hover
+	if err := json.Unmarshal(responses[1]["result"], &h); err != nil {
+		t.Fatal(err)
+	}
+	if h.Contents.Value != "# tokio::sync::Mutex\n" || h.Range == nil || h.Range.Start.Character != 8 || h.Range.End.Character != 13 {
+		t.Errorf("hover = %+v", h)
+	}
+	if len(backend.docs) != 1 || backend.docs[0].Path != "tokio::sync::Mutex" || backend.docs[0].Version != "1.0.0" {
+		t.Errorf("get-doc requests = %+v", backend.docs)
+	}
+	if backend.symbols[0].Query != "tokio::sync::Mutex" || !backend.symbols[0].Exact {
+		t.Errorf("hover looked up %+v", backend.symbols[0])
+	}
+	if got := string(responses[2]["result"]); got != "null" {
+		t.Errorf("hover on a keyword = %s, want null", got)
+	}
+	var syms []symbolInformation
+	if err := json.Unmarshal(responses[3]["result"], &syms); err != nil {
+		t.Fatal(err)
+	}
+	if len(syms) != 1 || syms[0].Name != "Mutex" || syms[0].Kind != 23 || syms[0].ContainerName != "tokio::sync (tokio@1.0.0)" {
+		t.Errorf("workspace symbols = %+v", syms)
+	}
+	if !strings.Contains(string(responses[4]["error"]), fmt.Sprint(codeMethodNotFound)) {
+		t.Errorf("unknown method response = %v", responses[4])
+	}
+}
@@ -0,0 +1,324 @@
+// Package lsp serves indexed documentation to editors over the Language
+// Server Protocol: hover docs for the symbol under the cursor, and workspace
+// symbol search over indexed items.
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/jcdickinson/ferrisfetch/internal/markdown"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+)
+
+// workspaceSymbolLimit caps workspace/symbol results.
+const workspaceSymbolLimit = 50
+
+// Backend answers the server's lookups; *daemon.Client implements it.
+type Backend interface {
+	Symbols(ctx context.Context, req rpc.SymbolsRequest) (*rpc.SymbolsResponse, error)
+	GetDoc(ctx context.Context, req rpc.GetDocRequest) (*rpc.GetDocResponse, error)
+}
+
+// Server is a language server for one editor session.
+type Server struct {
+	backend Backend
+	// docs holds the text of open documents by URI.
+	docs map[string]string
+}
+
+func NewServer(backend Backend) *Server {
+	return &Server{backend: backend, docs: make(map[string]string)}
+}
+
+type position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+type textDocumentID struct {
+	URI string `json:"uri"`
+}
+
+type hoverParams struct {
+	TextDocument textDocumentID `json:"textDocument"`
+	Position     position       `json:"position"`
+	// RsdocPath is the fully qualified path of the symbol, for editors
+	// that can ask rust-analyzer for it. Without it the path is guessed
+	// from the text and use declarations.
+	RsdocPath string `json:"rsdocPath,omitempty"`
+}
+
+type hover struct {
+	Contents markupContent `json:"contents"`
+	Range    *lspRange     `json:"range,omitempty"`
+}
+
+type markupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type symbolInformation struct {
+	Name          string   `json:"name"`
+	Kind          int      `json:"kind"`
+	Location      location `json:"location"`
+	ContainerName string   `json:"containerName,omitempty"`
+}
+
+type location struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+// Serve answers requests read from in, writing responses to out, until the
+// client sends exit or closes in.
+func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	r := bufio.NewReader(in)
+	for {
+		body, err := readMessage(r)
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			if err := writeMessage(out, errorResponse{JSONRPC: "2.0", Error: responseError{Code: codeParseError, Message: err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+		if req.Method == "exit" {
+			return nil
+		}
+
+		result, rerr := s.handle(ctx, req)
+		if req.ID == nil {
+			continue // notifications get no response
+		}
+		if rerr != nil {
+			err = writeMessage(out, errorResponse{JSONRPC: "2.0", ID: req.ID, Error: *rerr})
+		} else {
+			err = writeMessage(out, response{JSONRPC: "2.0", ID: req.ID, Result: result})
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(ctx context.Context, req request) (any, *responseError) {
+	switch req.Method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":        1, // full
+				"hoverProvider":           true,
+				"workspaceSymbolProvider": true,
+			},
+			"serverInfo": map[string]string{"name": "rsdoc"},
+		}, nil
+	case "shutdown":
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var p struct {
+			TextDocument struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"textDocument"`
+		}
+		if json.Unmarshal(req.Params, &p) == nil {
+			s.docs[p.TextDocument.URI] = p.TextDocument.Text
+		}
+		return nil, nil
+	case "textDocument/didChange":
+		var p struct {
+			TextDocument   textDocumentID `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if json.Unmarshal(req.Params, &p) == nil && len(p.ContentChanges) > 0 {
+			s.docs[p.TextDocument.URI] = p.ContentChanges[len(p.ContentChanges)-1].Text
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var p struct {
+			TextDocument textDocumentID `json:"textDocument"`
+		}
+		if json.Unmarshal(req.Params, &p) == nil {
+			delete(s.docs, p.TextDocument.URI)
+		}
+		return nil, nil
+
+	case "textDocument/hover":
+		var p hoverParams
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		h, err := s.hover(ctx, p)
+		if err != nil {
+			return nil, &responseError{Code: codeInternalError, Message: err.Error()}
+		}
+		if h == nil {
+			return nil, nil
+		}
+		return h, nil
+	case "workspace/symbol":
+		var p struct {
+			Query string `json:"query"`
+		}
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		syms, err := s.workspaceSymbols(ctx, p.Query)
+		if err != nil {
+			return nil, &responseError{Code: codeInternalError, Message: err.Error()}
+		}
+		return syms, nil
+	}
+
+	if strings.HasPrefix(req.Method, "$/") || req.ID == nil {
+		return nil, nil
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: "method not found: " + req.Method}
+}
+
+// hover finds the docs for the symbol at p, or returns nil if nothing
+// indexed matches.
+func (s *Server) hover(ctx context.Context, p hoverParams) (*hover, error) {
+	text, ok := s.docs[p.TextDocument.URI]
+	if !ok {
+		text = readFileURI(p.TextDocument.URI)
+	}
+	uses := useMap(text)
+
+	var rng *lspRange
+	path := p.RsdocPath
+	if path == "" {
+		sym, start, end := symbolAt(text, p.Position.Line, p.Position.Character)
+		if sym == "" {
+			return nil, nil
+		}
+		path = resolvePath(sym, uses)
+		rng = &lspRange{Start: position{p.Position.Line, start}, End: position{p.Position.Line, end}}
+	}
+
+	result, err := s.lookup(ctx, path, crateHints(uses))
+	if err != nil || result == nil {
+		return nil, err
+	}
+	docReq, _, err := rpc.ParseDocURI(result.URI)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := s.backend.GetDoc(ctx, docReq)
+	if err != nil {
+		return nil, err
+	}
+	return &hover{
+		Contents: markupContent{Kind: "markdown", Value: markdown.StripFrontMatter(doc.Markdown)},
+		Range:    rng,
+	}, nil
+}
+
+// lookup resolves path to an indexed item. A bare name is looked up in the
+// crates the file imports from first, as it most likely comes from one of
+// them.
+func (s *Server) lookup(ctx context.Context, path string, hints []string) (*rpc.DocResult, error) {
+	attempts := [][]string{nil}
+	if !strings.Contains(path, "::") && len(hints) > 0 {
+		attempts = [][]string{hints, nil}
+	}
+	for _, crates := range attempts {
+		resp, err := s.backend.Symbols(ctx, rpc.SymbolsRequest{Query: path, Exact: true, Crates: crates, Limit: 1})
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Results) > 0 {
+			return &resp.Results[0], nil
+		}
+	}
+	slog.Debug("no indexed item for hover", "path", path)
+	return nil, nil
+}
+
+func (s *Server) workspaceSymbols(ctx context.Context, query string) ([]symbolInformation, error) {
+	syms := []symbolInformation{}
+	if query == "" {
+		return syms, nil
+	}
+	resp, err := s.backend.Symbols(ctx, rpc.SymbolsRequest{Query: query, Limit: workspaceSymbolLimit})
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range resp.Results {
+		name, container := r.Path, ""
+		if i := strings.LastIndex(r.Path, "::"); i >= 0 {
+			name, container = r.Path[i+2:], r.Path[:i]
+		}
+		syms = append(syms, symbolInformation{
+			Name:          name,
+			Kind:          symbolKind(r.Kind),
+			Location:      location{URI: r.URI},
+			ContainerName: fmt.Sprintf("%s (%s@%s)", container, r.CrateName, r.CrateVersion),
+		})
+	}
+	return syms, nil
+}
+
+// symbolKind maps an item kind to an LSP SymbolKind.
+func symbolKind(kind string) int {
+	switch kind {
+	case "module":
+		return 2
+	case "method":
+		return 6
+	case "field", "struct_field":
+		return 8
+	case "enum":
+		return 10
+	case "trait", "trait_alias":
+		return 11
+	case "function", "macro", "proc_attribute", "proc_derive":
+		return 12
+	case "constant", "static", "assoc_const":
+		return 14
+	case "variant":
+		return 22
+	case "struct", "union", "primitive":
+		return 23
+	case "type_alias", "assoc_type":
+		return 26
+	}
+	return 13 // variable
+}
+
+// readFileURI reads a file:// document the client hasn't opened.
+func readFileURI(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	data, err := os.ReadFile(u.Path)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
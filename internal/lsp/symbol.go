@@ -0,0 +1,179 @@
+package lsp
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// symbolAt returns the path expression under the cursor at line and
+// character (in UTF-16 code units, as LSP counts them), and the columns it
+// spans. The path runs from its first segment to the end of the segment
+// under the cursor, so hovering "sync" in tokio::sync::Mutex gives
+// "tokio::sync".
+func symbolAt(text string, line, character int) (path string, start, end int) {
+	lines := strings.Split(text, "\n")
+	if line < 0 || line >= len(lines) {
+		return "", 0, 0
+	}
+	runes := []rune(strings.TrimSuffix(lines[line], "\r"))
+
+	// Find the rune at the cursor.
+	cursor, units := len(runes), 0
+	for i, r := range runes {
+		if units >= character {
+			cursor = i
+			break
+		}
+		units += utf16.RuneLen(r)
+	}
+	// A cursor just past a word still hovers it.
+	if (cursor == len(runes) || !isIdentRune(runes[cursor])) && cursor > 0 && isIdentRune(runes[cursor-1]) {
+		cursor--
+	}
+	if cursor >= len(runes) || !isIdentRune(runes[cursor]) {
+		return "", 0, 0
+	}
+
+	left := cursor
+	for left > 0 && (isIdentRune(runes[left-1]) || runes[left-1] == ':') {
+		left--
+	}
+	right := cursor
+	for right < len(runes) && isIdentRune(runes[right]) {
+		right++
+	}
+	for left < cursor && runes[left] == ':' {
+		left++
+	}
+
+	path = string(runes[left:right])
+	for _, seg := range strings.Split(path, "::") {
+		if seg == "" || strings.Contains(seg, ":") || unicode.IsDigit([]rune(seg)[0]) {
+			return "", 0, 0
+		}
+	}
+	return path, utf16Len(runes[:left]), utf16Len(runes[:right])
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func utf16Len(runes []rune) int {
+	n := 0
+	for _, r := range runes {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+var (
+	useDeclRe = regexp.MustCompile(`(?s)\buse\s+([^;{}]*(?:\{[^;]*\})?[^;{}]*);`)
+	// useSpaceRe matches the spaces around punctuation in a use tree.
+	useSpaceRe = regexp.MustCompile(` ?(::|[{},]) ?`)
+)
+
+// useMap maps the names a file's use declarations bring into scope to the
+// paths they stand for: "use tokio::sync::{Mutex, RwLock as Lock};" maps
+// Mutex to tokio::sync::Mutex and Lock to tokio::sync::RwLock.
+func useMap(text string) map[string]string {
+	uses := make(map[string]string)
+	for _, m := range useDeclRe.FindAllStringSubmatch(text, -1) {
+		tree := useSpaceRe.ReplaceAllString(strings.Join(strings.Fields(m[1]), " "), "$1")
+		expandUse("", strings.TrimPrefix(tree, "::"), uses)
+	}
+	return uses
+}
+
+// expandUse adds the names brought in by a use tree below prefix.
+func expandUse(prefix, tree string, uses map[string]string) {
+	for _, item := range splitTopLevel(tree) {
+		if i := strings.Index(item, "{"); i >= 0 && strings.HasSuffix(item, "}") {
+			expandUse(prefix+item[:i], item[i+1:len(item)-1], uses)
+			continue
+		}
+		path, alias, _ := strings.Cut(item, " as ")
+		path = prefix + path
+		if strings.HasSuffix(path, "::self") {
+			path = strings.TrimSuffix(path, "::self")
+		}
+		if path == "" || strings.HasSuffix(path, "*") || alias == "_" {
+			continue
+		}
+		name := alias
+		if name == "" {
+			name = path[strings.LastIndex(path, ":")+1:]
+		}
+		uses[name] = path
+	}
+}
+
+// splitTopLevel splits a use tree's items at commas outside braces.
+func splitTopLevel(tree string) []string {
+	var items []string
+	depth, start := 0, 0
+	for i, r := range tree {
+		switch r {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				items = append(items, tree[start:i])
+				start = i + 1
+			}
+		}
+	}
+	items = append(items, tree[start:])
+	var out []string
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// resolvePath expands path's first segment through uses and drops the
+// crate-relative prefixes (crate, self, super) the index can't know.
+func resolvePath(path string, uses map[string]string) string {
+	first, rest, hasRest := strings.Cut(path, "::")
+	if full, ok := uses[first]; ok {
+		path = full
+		if hasRest {
+			path += "::" + rest
+		}
+	}
+	for {
+		first, rest, hasRest := strings.Cut(path, "::")
+		if !hasRest || (first != "crate" && first != "self" && first != "super") {
+			return path
+		}
+		path = rest
+	}
+}
+
+// crateHints lists the crates a file's use declarations import from, as
+// crate names: both the path form and its hyphenated spelling, since
+// tokio_util is the tokio-util crate.
+func crateHints(uses map[string]string) []string {
+	seen := make(map[string]bool)
+	var crates []string
+	for _, path := range uses {
+		root, _, _ := strings.Cut(path, "::")
+		switch root {
+		case "crate", "self", "super", "std", "core", "alloc":
+			continue
+		}
+		for _, name := range []string{root, strings.ReplaceAll(root, "_", "-")} {
+			if !seen[name] {
+				seen[name] = true
+				crates = append(crates, name)
+			}
+		}
+	}
+	return crates
+}
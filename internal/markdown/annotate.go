@@ -0,0 +1,29 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+)
+
+// uriRefRe matches an rsdoc:// URI as an inline link destination, an
+// autolink, or bare text such as a "Types Used" entry.
+var uriRefRe = regexp.MustCompile(`\]\((rsdoc://[^\s)]+)\)|<(rsdoc://[^\s>]+)>|(rsdoc://[^\s)\]>"]+)`)
+
+// AnnotateLinks passes each rsdoc:// URI in src, fragment stripped, to fn.
+// A non-empty dest replaces the URI (fragment and all); a non-empty note is
+// appended after the link in italics.
+func AnnotateLinks(src string, fn func(uri string) (dest, note string)) string {
+	return uriRefRe.ReplaceAllStringFunc(src, func(m string) string {
+		sub := uriRefRe.FindStringSubmatch(m)
+		full := sub[1] + sub[2] + sub[3]
+		uri, _, _ := strings.Cut(full, "#")
+		dest, note := fn(uri)
+		if dest != "" {
+			m = strings.Replace(m, full, dest, 1)
+		}
+		if note != "" {
+			m += " *(" + note + ")*"
+		}
+		return m
+	})
+}
@@ -0,0 +1,24 @@
+package markdown
+
+import "testing"
+
+func TestAnnotateLinks(t *testing.T) {
+	src := "See [Mutex](rsdoc://tokio/1.0.0/tokio::sync::Mutex#implementations) and [Vec](rsdoc://alloc/latest/alloc::vec::Vec).\n\n" +
+		"## Types Used\n\n- rsdoc://serde/1.0.0/serde::Serialize\n- <rsdoc://tokio/1.0.0/tokio::sync::Mutex>\n"
+
+	got := AnnotateLinks(src, func(uri string) (string, string) {
+		switch uri {
+		case "rsdoc://tokio/1.0.0/tokio::sync::Mutex":
+			return "", "not indexed"
+		case "rsdoc://serde/1.0.0/serde::Serialize":
+			return "https://docs.rs/serde/1.0.0/serde/?search=serde::Serialize", ""
+		}
+		return "", ""
+	})
+
+	want := "See [Mutex](rsdoc://tokio/1.0.0/tokio::sync::Mutex#implementations) *(not indexed)* and [Vec](rsdoc://alloc/latest/alloc::vec::Vec).\n\n" +
+		"## Types Used\n\n- https://docs.rs/serde/1.0.0/serde/?search=serde::Serialize\n- <rsdoc://tokio/1.0.0/tokio::sync::Mutex> *(not indexed)*\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,49 @@
+package markdown
+
+import (
+	"strings"
+
+	gm "github.com/gomarkdown/markdown"
+	"github.com/gomarkdown/markdown/ast"
+	gmparser "github.com/gomarkdown/markdown/parser"
+)
+
+// Link is an rsdoc:// link found in a document.
+type Link struct {
+	Text string
+	URI  string
+}
+
+// Links returns the rsdoc:// links in src: the fragments listed in its
+// front matter (see AddFrontMatter) first, then links in the body, in order
+// and without repeating a URI.
+func Links(src string) []Link {
+	var links []Link
+	seen := make(map[string]bool)
+	add := func(text, uri string) {
+		if !strings.HasPrefix(uri, "rsdoc://") || seen[uri] {
+			return
+		}
+		seen[uri] = true
+		links = append(links, Link{Text: text, URI: uri})
+	}
+
+	if fm, rest, ok := splitFrontMatter(src); ok {
+		for _, l := range fm {
+			if name, uri, ok := strings.Cut(l, ": "); ok {
+				add("#"+name, strings.TrimSpace(uri))
+			}
+		}
+		src = rest
+	}
+
+	doc := gm.Parse([]byte(src), gmparser.NewWithExtensions(gmparser.CommonExtensions))
+	ast.WalkFunc(doc, func(node ast.Node, entering bool) ast.WalkStatus {
+		if link, ok := node.(*ast.Link); ok && entering {
+			add(strings.TrimSpace(plainInline(link)), string(link.Destination))
+			return ast.SkipChildren
+		}
+		return ast.GoToNext
+	})
+	return links
+}
@@ -0,0 +1,28 @@
+package markdown
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLinks(t *testing.T) {
+	src := "---\nexamples: rsdoc://serde/1.0.0/serde::Serialize#examples\n---\n\n" +
+		"# serde::Serialize\n\n" +
+		"Supported by [Serde](rsdoc://serde/1.0.0/serde) and [`Deserialize`](rsdoc://serde/1.0.0/serde::Deserialize).\n\n" +
+		"See [Serde](rsdoc://serde/1.0.0/serde) again, or [the book](https://serde.rs).\n"
+
+	want := []Link{
+		{Text: "#examples", URI: "rsdoc://serde/1.0.0/serde::Serialize#examples"},
+		{Text: "Serde", URI: "rsdoc://serde/1.0.0/serde"},
+		{Text: "Deserialize", URI: "rsdoc://serde/1.0.0/serde::Deserialize"},
+	}
+	if got := Links(src); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestLinks_None(t *testing.T) {
+	if got := Links("# Title\n\nNo links here.\n"); len(got) != 0 {
+		t.Errorf("got %+v, want none", got)
+	}
+}
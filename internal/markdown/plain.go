@@ -0,0 +1,260 @@
+package markdown
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	gm "github.com/gomarkdown/markdown"
+	"github.com/gomarkdown/markdown/ast"
+	gmparser "github.com/gomarkdown/markdown/parser"
+)
+
+// PlainText renders markdown as plain text wrapped to width columns. Markup
+// and link targets are dropped, code blocks are indented instead of fenced,
+// and a leading front-matter block (see AddFrontMatter) becomes a
+// "Fragments:" list.
+func PlainText(src string, width int) string {
+	if width <= 0 {
+		width = 80
+	}
+
+	var lines []string
+	if fm, rest, ok := splitFrontMatter(src); ok {
+		lines = append(lines, "Fragments:")
+		for _, l := range fm {
+			lines = append(lines, "  "+l)
+		}
+		src = rest
+	}
+
+	doc := gm.Parse([]byte(src), gmparser.NewWithExtensions(gmparser.CommonExtensions))
+	if body := plainBlocks(doc.GetChildren(), width); len(body) > 0 {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, body...)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// splitFrontMatter returns the "key: value" lines of a leading front-matter
+// block and the text after it.
+func splitFrontMatter(src string) ([]string, string, bool) {
+	if !strings.HasPrefix(src, "---\n") {
+		return nil, src, false
+	}
+	end := strings.Index(src[4:], "\n---\n")
+	if end < 0 {
+		return nil, src, false
+	}
+	fm := strings.Split(src[4:4+end], "\n")
+	return fm, src[4+end+5:], true
+}
+
+// plainBlocks renders block nodes separated by blank lines.
+func plainBlocks(nodes []ast.Node, width int) []string {
+	var out []string
+	for _, n := range nodes {
+		lines := plainBlock(n, width)
+		if len(lines) == 0 {
+			continue
+		}
+		if len(out) > 0 {
+			out = append(out, "")
+		}
+		out = append(out, lines...)
+	}
+	return out
+}
+
+func plainBlock(node ast.Node, width int) []string {
+	switch n := node.(type) {
+	case *ast.Heading:
+		lines := wrapText(plainInline(n), width)
+		underline := map[int]string{1: "=", 2: "-"}[n.Level]
+		if underline != "" && len(lines) > 0 {
+			longest := 0
+			for _, l := range lines {
+				longest = max(longest, utf8.RuneCountInString(l))
+			}
+			lines = append(lines, strings.Repeat(underline, longest))
+		}
+		return lines
+	case *ast.Paragraph:
+		return wrapText(plainInline(n), width)
+	case *ast.CodeBlock:
+		var lines []string
+		for _, l := range strings.Split(strings.TrimRight(string(n.Literal), "\n"), "\n") {
+			if l == "" {
+				lines = append(lines, "")
+			} else {
+				lines = append(lines, "    "+l)
+			}
+		}
+		return lines
+	case *ast.List:
+		return plainList(n, width)
+	case *ast.BlockQuote:
+		return indentLines(plainBlocks(n.GetChildren(), width-2), "  ", "  ")
+	case *ast.HorizontalRule:
+		return []string{strings.Repeat("-", min(width, 40))}
+	case *ast.Table:
+		return plainTable(n)
+	case *ast.HTMLBlock:
+		return nil
+	}
+	if node.AsContainer() != nil {
+		return plainBlocks(node.GetChildren(), width)
+	}
+	return wrapText(string(node.AsLeaf().Literal), width)
+}
+
+func plainList(list *ast.List, width int) []string {
+	num := list.Start
+	if num == 0 {
+		num = 1
+	}
+	var out []string
+	for _, item := range list.GetChildren() {
+		marker := "- "
+		if list.ListFlags&ast.ListTypeOrdered != 0 {
+			marker = fmt.Sprintf("%d. ", num)
+			num++
+		}
+		body := plainBlocks(item.GetChildren(), width-len(marker))
+		if len(out) > 0 && !list.Tight {
+			out = append(out, "")
+		}
+		out = append(out, indentLines(body, marker, strings.Repeat(" ", len(marker)))...)
+	}
+	return out
+}
+
+// plainTable lays out a table in padded columns with a rule under the header.
+func plainTable(table *ast.Table) []string {
+	var rows [][]string
+	header := 0
+	ast.WalkFunc(table, func(node ast.Node, entering bool) ast.WalkStatus {
+		if !entering {
+			return ast.GoToNext
+		}
+		if row, ok := node.(*ast.TableRow); ok {
+			var cells []string
+			for _, c := range row.GetChildren() {
+				cells = append(cells, plainInline(c))
+			}
+			rows = append(rows, cells)
+			if _, ok := row.GetParent().(*ast.TableHeader); ok {
+				header = len(rows)
+			}
+			return ast.SkipChildren
+		}
+		return ast.GoToNext
+	})
+
+	var widths []int
+	for _, row := range rows {
+		for i, c := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], utf8.RuneCountInString(c))
+		}
+	}
+
+	var out []string
+	for r, row := range rows {
+		var b strings.Builder
+		for i, c := range row {
+			if i == len(row)-1 {
+				b.WriteString(c)
+			} else {
+				b.WriteString(c + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(c)+2))
+			}
+		}
+		out = append(out, b.String())
+		if r == header-1 {
+			total := 0
+			for _, w := range widths {
+				total += w + 2
+			}
+			out = append(out, strings.Repeat("-", max(total-2, 0)))
+		}
+	}
+	return out
+}
+
+// plainInline flattens inline markup to text. Hard line breaks are kept as
+// newlines.
+func plainInline(node ast.Node) string {
+	var b strings.Builder
+	for _, child := range node.GetChildren() {
+		switch n := child.(type) {
+		case *ast.Softbreak:
+			b.WriteString(" ")
+		case *ast.Hardbreak:
+			b.WriteString("\n")
+		case *ast.HTMLSpan:
+		case *ast.Text:
+			b.Write(n.Literal)
+		case *ast.Code:
+			b.Write(n.Literal)
+		default:
+			if child.AsContainer() != nil {
+				b.WriteString(plainInline(child))
+			} else {
+				b.Write(child.AsLeaf().Literal)
+			}
+		}
+	}
+	return b.String()
+}
+
+// wrapText greedily wraps each line of text to width. Words longer than
+// width get a line of their own.
+func wrapText(text string, width int) []string {
+	width = max(width, 20)
+	var out []string
+	for _, line := range strings.Split(text, "\n") {
+		var cur strings.Builder
+		n := 0
+		for _, word := range strings.Fields(line) {
+			w := utf8.RuneCountInString(word)
+			if n > 0 && n+1+w > width {
+				out = append(out, cur.String())
+				cur.Reset()
+				n = 0
+			}
+			if n > 0 {
+				cur.WriteByte(' ')
+				n++
+			}
+			cur.WriteString(word)
+			n += w
+		}
+		if n > 0 {
+			out = append(out, cur.String())
+		}
+	}
+	return out
+}
+
+// indentLines prefixes the first line with first and the rest with rest.
+// Blank lines stay blank.
+func indentLines(lines []string, first, rest string) []string {
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		switch {
+		case l == "":
+		case i == 0:
+			out[i] = first + l
+		default:
+			out[i] = rest + l
+		}
+	}
+	return out
+}
@@ -0,0 +1,47 @@
+package markdown
+
+import "testing"
+
+func TestPlainText(t *testing.T) {
+	src := "---\nexamples: rsdoc://serde/1.0.0/serde::Serialize#examples\n---\n\n" +
+		"# serde::Serialize\n\n" +
+		"**Kind:** trait\n\n" +
+		"```rust\npub trait Serialize {\n    fn serialize(&self);\n}\n```\n\n" +
+		"A **data structure** that can be serialized into any data format supported by [Serde](rsdoc://serde/1.0.0/serde).\n\n" +
+		"- first item\n- second `item`\n\n" +
+		"| Name | Value |\n|------|-------|\n| a | 1 |\n"
+
+	want := `Fragments:
+  examples: rsdoc://serde/1.0.0/serde::Serialize#examples
+
+serde::Serialize
+================
+
+Kind: trait
+
+    pub trait Serialize {
+        fn serialize(&self);
+    }
+
+A data structure that can be serialized
+into any data format supported by Serde.
+
+- first item
+- second item
+
+Name  Value
+-----------
+a     1
+`
+	if got := PlainText(src, 40); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPlainText_NestedList(t *testing.T) {
+	src := "1. one\n2. two is a longer item that wraps\n   - nested\n"
+	want := "1. one\n2. two is a longer item that\n   wraps\n\n   - nested\n"
+	if got := PlainText(src, 30); got != want {
+		t.Errorf("got:\n%q\nwant:\n%q", got, want)
+	}
+}
@@ -96,3 +96,12 @@ func AddFrontMatter(src string, fragments map[string]string) string {
 	b.WriteString(src)
 	return b.String()
 }
+
+// StripFrontMatter removes a leading front-matter block added by
+// AddFrontMatter.
+func StripFrontMatter(src string) string {
+	if _, rest, ok := splitFrontMatter(src); ok {
+		return strings.TrimLeft(rest, "\n")
+	}
+	return src
+}
@@ -0,0 +1,101 @@
+package markdown
+
+import "strings"
+
+// Section is the part of a markdown document under one heading.
+type Section struct {
+	// Heading is the heading's text without its #s, or "" for the text
+	// before the first heading.
+	Heading string
+	Level   int
+	Body    string
+}
+
+// Sections splits src at its ATX headings. Lines starting with # inside
+// code fences aren't headings. Sections with neither heading nor body are
+// left out.
+func Sections(src string) []Section {
+	var sections []Section
+	cur := Section{}
+	var body []string
+	flush := func() {
+		cur.Body = strings.TrimSpace(strings.Join(body, "\n"))
+		if cur.Heading != "" || cur.Body != "" {
+			sections = append(sections, cur)
+		}
+		body = nil
+	}
+	fence := ""
+	for _, line := range strings.Split(src, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:3]
+		default:
+			if level, text, ok := heading(line); ok {
+				flush()
+				cur = Section{Heading: text, Level: level}
+				continue
+			}
+		}
+		body = append(body, line)
+	}
+	flush()
+	return sections
+}
+
+// heading parses an ATX heading line such as "## Errors".
+func heading(line string) (level int, text string, ok bool) {
+	level = len(line) - len(strings.TrimLeft(line, "#"))
+	if level == 0 || level > 6 || (len(line) > level && line[level] != ' ') {
+		return 0, "", false
+	}
+	return level, strings.TrimSpace(strings.Trim(strings.TrimSpace(line[level:]), "#")), true
+}
+
+// FirstParagraph returns the first paragraph of src: up to the first blank
+// line outside a code fence.
+func FirstParagraph(src string) string {
+	lines := strings.Split(strings.TrimSpace(src), "\n")
+	fence := ""
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:3]
+		case trimmed == "":
+			return strings.Join(lines[:i], "\n")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FirstCodeBlock returns the first fenced code block in src, fences
+// included, or "" if there is none.
+func FirstCodeBlock(src string) string {
+	var block []string
+	fence := ""
+	for _, line := range strings.Split(src, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence == "" {
+			if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+				fence = trimmed[:3]
+				block = append(block, trimmed)
+			}
+			continue
+		}
+		block = append(block, line)
+		if strings.HasPrefix(trimmed, fence) {
+			return strings.Join(block, "\n")
+		}
+	}
+	return ""
+}
@@ -0,0 +1,45 @@
+package markdown
+
+import "testing"
+
+func TestSections(t *testing.T) {
+	src := "Spawns a task.\n\nMore detail.\n\n# Panics\n\nWhen called outside a runtime.\n\n" +
+		"# Examples\n\n```rust\n# fn main() {}\nlet x = 1;\n```\n\n## Nested ##\n\nText\n#hashtag is not a heading\n"
+	got := Sections(src)
+	want := []Section{
+		{Heading: "", Level: 0, Body: "Spawns a task.\n\nMore detail."},
+		{Heading: "Panics", Level: 1, Body: "When called outside a runtime."},
+		{Heading: "Examples", Level: 1, Body: "```rust\n# fn main() {}\nlet x = 1;\n```"},
+		{Heading: "Nested", Level: 2, Body: "Text\n#hashtag is not a heading"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Sections = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("section %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestFirstParagraph(t *testing.T) {
+	for _, tc := range []struct{ src, want string }{
+		{"One\nline.\n\nTwo.", "One\nline."},
+		{"\n\nOnly.\n", "Only."},
+		{"```rust\nlet a = 1;\n\nlet b = 2;\n```\n\nAfter.", "```rust\nlet a = 1;\n\nlet b = 2;\n```"},
+	} {
+		if got := FirstParagraph(tc.src); got != tc.want {
+			t.Errorf("FirstParagraph(%q) = %q, want %q", tc.src, got, tc.want)
+		}
+	}
+}
+
+func TestFirstCodeBlock(t *testing.T) {
+	src := "Use it like this:\n\n```rust\nlet a = 1;\n\nlet b = 2;\n```\n\n```\nsecond\n```\n"
+	if got, want := FirstCodeBlock(src), "```rust\nlet a = 1;\n\nlet b = 2;\n```"; got != want {
+		t.Errorf("FirstCodeBlock = %q, want %q", got, want)
+	}
+	if got := FirstCodeBlock("no code"); got != "" {
+		t.Errorf("FirstCodeBlock without code = %q", got)
+	}
+}
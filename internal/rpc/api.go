@@ -0,0 +1,244 @@
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// APIVersion prefixes the daemon's stable routes, e.g. POST /v1/search.
+// Within a version, fields are only ever added: clients must ignore fields
+// they don't know, and requests that omit new fields keep their old
+// meaning. Anything else needs a new version.
+const APIVersion = "v1"
+
+// ProtocolVersion is bumped whenever the daemon gains routes or fields that
+// clients of the same build rely on. A client finding an older daemon, e.g.
+// one left running across an upgrade, restarts it rather than have its
+// requests half understood.
+const ProtocolVersion = 1
+
+// Media types the daemon produces.
+const (
+	MediaJSON     = "application/json"
+	MediaNDJSON   = "application/x-ndjson"
+	MediaMarkdown = "text/markdown"
+)
+
+// Route describes a daemon endpoint: the contract third-party clients rely
+// on, and the source of the OpenAPI document.
+type Route struct {
+	// Pattern is the method and path without the version prefix, e.g.
+	// "POST /search". Capabilities lists routes by pattern.
+	Pattern string
+	Summary string
+	// Request is the body type of POST requests; nil for GET.
+	Request any
+	// Response is the JSON response type, or for NDJSON streams the type of
+	// each line.
+	Response any
+	// Produces lists the media types the route can answer with, the
+	// default first. Empty means MediaJSON only.
+	Produces []string
+	// Idempotent marks routes that can be repeated safely, so a client
+	// that loses its connection mid-request may send it again.
+	Idempotent bool
+}
+
+// Path is the route's versioned path, e.g. "/v1/search".
+func (r Route) Path() string {
+	_, path, _ := strings.Cut(r.Pattern, " ")
+	return "/" + APIVersion + path
+}
+
+// Method is the route's HTTP method.
+func (r Route) Method() string {
+	method, _, _ := strings.Cut(r.Pattern, " ")
+	return method
+}
+
+// MediaTypes is Produces, defaulting to MediaJSON.
+func (r Route) MediaTypes() []string {
+	if len(r.Produces) == 0 {
+		return []string{MediaJSON}
+	}
+	return r.Produces
+}
+
+// ErrorResponse is the body of every non-2xx response, whatever the Accept
+// header asked for.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// StatusMessage is the response body for endpoints with nothing else to
+// report.
+type StatusMessage struct {
+	Status string `json:"status"`
+}
+
+// Routes lists every endpoint of the current API version.
+var Routes = []Route{
+	{Pattern: "POST /add-crates", Summary: "Fetch and index crates, streaming progress", Request: AddCratesRequest{}, Response: ProgressLine{}, Produces: []string{MediaNDJSON}, Idempotent: true},
+	{Pattern: "POST /search", Summary: "Semantic search across indexed crates", Request: SearchRequest{}, Response: SearchResponse{}, Idempotent: true},
+	{Pattern: "POST /get-doc", Summary: "Read a documentation item", Request: GetDocRequest{}, Response: GetDocResponse{}, Produces: []string{MediaJSON, MediaMarkdown}, Idempotent: true},
+	{Pattern: "POST /summarize", Summary: "Summarize a documentation item within a token budget", Request: SummarizeRequest{}, Response: SummarizeResponse{}, Produces: []string{MediaJSON, MediaMarkdown}, Idempotent: true},
+	{Pattern: "POST /build-context", Summary: "Bundle documentation items within a token budget", Request: BuildContextRequest{}, Response: BuildContextResponse{}, Produces: []string{MediaJSON, MediaMarkdown}, Idempotent: true},
+	{Pattern: "POST /context", Summary: "Search and bundle the top hits", Request: ContextRequest{}, Response: ContextResponse{}, Idempotent: true},
+	{Pattern: "POST /diff", Summary: "API changes between two crate versions", Request: DiffRequest{}, Response: DiffResponse{}, Idempotent: true},
+	{Pattern: "POST /deps", Summary: "A crate's dependencies and indexed dependents", Request: DepsRequest{}, Response: DepsResponse{}, Idempotent: true},
+	{Pattern: "POST /similar", Summary: "Items related to an item, from stored embeddings", Request: SimilarRequest{}, Response: SimilarResponse{}, Idempotent: true},
+	{Pattern: "POST /symbols", Summary: "Look indexed items up by name or path", Request: SymbolsRequest{}, Response: SymbolsResponse{}, Idempotent: true},
+	{Pattern: "POST /verify", Summary: "Check index integrity", Request: VerifyRequest{}, Response: VerifyResponse{}},
+	{Pattern: "POST /export", Summary: "Bundle the index into an archive", Request: ExportRequest{}, Response: ExportResponse{}},
+	{Pattern: "POST /snapshot", Summary: "Copy the database and vector index", Request: SnapshotRequest{}, Response: SnapshotResponse{}},
+	{Pattern: "POST /restore", Summary: "Roll the index back to a snapshot", Request: RestoreRequest{}, Response: RestoreResponse{}},
+	{Pattern: "POST /repack", Summary: "Move loose content store files into a pack", Request: RepackRequest{}, Response: RepackResponse{}},
+	{Pattern: "POST /reindex", Summary: "Re-parse crates from the rustdoc JSON cache, streaming progress", Request: ReindexRequest{}, Response: ProgressLine{}, Produces: []string{MediaNDJSON}},
+	{Pattern: "POST /reembed", Summary: "Re-embed every crate with the configured model, streaming progress", Request: ReembedRequest{}, Response: ProgressLine{}, Produces: []string{MediaNDJSON}},
+	{Pattern: "POST /watch", Summary: "Keep a Cargo project's dependencies indexed as its Cargo.lock changes", Request: WatchRequest{}, Response: WatchStatus{}, Idempotent: true},
+	{Pattern: "GET /status", Summary: "Indexed crates and daemon state; ?namespace= selects the namespace", Response: StatusResponse{}, Idempotent: true},
+	{Pattern: "GET /namespaces", Summary: "Namespaces holding crates", Response: NamespacesResponse{}, Idempotent: true},
+	{Pattern: "POST /delete-namespace", Summary: "Delete a namespace and the content only it used", Request: DeleteNamespaceRequest{}, Response: DeleteNamespaceResponse{}},
+	{Pattern: "POST /search-crates", Summary: "Search crates.io", Request: SearchCratesRequest{}, Response: SearchCratesResponse{}, Idempotent: true},
+	{Pattern: "POST /reload-config", Summary: "Re-read the config file and apply what can change without a restart", Response: ReloadConfigResponse{}, Idempotent: true},
+	{Pattern: "POST /clear-cache", Summary: "Clear the version resolution cache", Response: StatusMessage{}, Idempotent: true},
+	{Pattern: "GET /version", Summary: "The daemon's protocol version", Response: VersionResponse{}, Idempotent: true},
+	{Pattern: "GET /capabilities", Summary: "Optional subsystems and routes the daemon has", Response: CapabilitiesResponse{}, Idempotent: true},
+	{Pattern: "GET /health", Summary: "Check the daemon's dependencies", Response: HealthResponse{}, Idempotent: true},
+	{Pattern: "GET /stats", Summary: "Per-crate storage use", Response: StatsResponse{}, Idempotent: true},
+	{Pattern: "POST /coverage", Summary: "How much of a crate is documented and embedded", Request: CoverageRequest{}, Response: CoverageResponse{}, Idempotent: true},
+	{Pattern: "GET /openapi.json", Summary: "This OpenAPI document", Idempotent: true},
+	{Pattern: "POST /shutdown", Summary: "Stop the daemon after in-flight work drains", Response: StatusMessage{}},
+}
+
+// Negotiate picks the media type in offers that best satisfies an Accept
+// header. Each offer takes the quality of the most specific range matching
+// it; the best quality wins, then the earliest offer. An empty header
+// accepts the first offer.
+func Negotiate(accept string, offers []string) (string, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0], true
+	}
+	type mediaRange struct {
+		pattern string
+		q       float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType, q})
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q, specificity := 0.0, -1
+		for _, r := range ranges {
+			if s := matchMedia(r.pattern, offer); s > specificity {
+				q, specificity = r.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best, best != ""
+}
+
+// matchMedia reports how specifically media range pattern matches offer:
+// 2 exactly, 1 as type/*, 0 as */*, or -1 not at all.
+func matchMedia(pattern, offer string) int {
+	switch {
+	case pattern == offer:
+		return 2
+	case pattern == "*/*":
+		return 0
+	case strings.HasSuffix(pattern, "/*") && strings.HasPrefix(offer, strings.TrimSuffix(pattern, "*")):
+		return 1
+	}
+	return -1
+}
+
+// operationIDs turns a route path into an operation ID, e.g. get_doc.
+var operationIDs = strings.NewReplacer("-", "_", ".", "_")
+
+// OpenAPI returns an OpenAPI 3.1 document describing Routes. Schemas are
+// generated from the request and response types.
+func OpenAPI() ([]byte, error) {
+	reflector := &jsonschema.Reflector{Anonymous: true, AllowAdditionalProperties: true}
+	schemas := map[string]any{}
+	ref := func(v any) map[string]any {
+		for name, def := range reflector.Reflect(v).Definitions {
+			schemas[name] = def
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + reflect.TypeOf(v).Name()}
+	}
+
+	errorSchema := ref(ErrorResponse{})
+	paths := map[string]map[string]any{}
+	for _, route := range Routes {
+		op := map[string]any{
+			"operationId": operationIDs.Replace(strings.TrimPrefix(route.Path(), "/"+APIVersion+"/")),
+			"summary":     route.Summary,
+		}
+		if route.Request != nil {
+			op["requestBody"] = map[string]any{
+				"required": true,
+				"content":  map[string]any{MediaJSON: map[string]any{"schema": ref(route.Request)}},
+			}
+		}
+
+		content := map[string]any{}
+		for _, media := range route.MediaTypes() {
+			var schema any = map[string]any{"type": "object"}
+			switch {
+			case media == MediaMarkdown:
+				schema = map[string]any{"type": "string"}
+			case route.Response != nil:
+				schema = ref(route.Response)
+			}
+			content[media] = map[string]any{"schema": schema}
+		}
+		op["responses"] = map[string]any{
+			"200":     map[string]any{"description": "OK", "content": content},
+			"default": map[string]any{"description": "Error", "content": map[string]any{MediaJSON: map[string]any{"schema": errorSchema}}},
+		}
+
+		if paths[route.Path()] == nil {
+			paths[route.Path()] = map[string]any{}
+		}
+		paths[route.Path()][strings.ToLower(route.Method())] = op
+	}
+
+	doc := map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":       "ferrisfetch daemon API",
+			"version":     strings.TrimPrefix(APIVersion, "v"),
+			"description": "The rsdoc daemon's HTTP API, served on its Unix socket. Unversioned paths are deprecated aliases kept for older clients.",
+		},
+		"paths":      paths,
+		"components": map[string]any{"schemas": schemas},
+	}
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding OpenAPI document: %w", err)
+	}
+	// Definitions refer to each other under $defs; they live under
+	// components here.
+	return []byte(strings.ReplaceAll(string(out), `"#/$defs/`, `"#/components/schemas/`)), nil
+}
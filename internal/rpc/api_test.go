@@ -0,0 +1,77 @@
+package rpc
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{MediaJSON, MediaMarkdown}
+	tests := []struct {
+		accept string
+		want   string
+		ok     bool
+	}{
+		{"", MediaJSON, true},
+		{"*/*", MediaJSON, true},
+		{"text/markdown", MediaMarkdown, true},
+		{"text/*", MediaMarkdown, true},
+		{"text/markdown;q=0.5, application/json", MediaJSON, true},
+		{"text/markdown, application/json;q=0.9", MediaMarkdown, true},
+		{"*/*;q=0.1, text/markdown", MediaMarkdown, true},
+		{"application/json;q=0, */*", MediaMarkdown, true},
+		{"text/html", "", false},
+		{"garbage", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := Negotiate(tt.accept, offers)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Negotiate(%q) = %q, %v; want %q, %v", tt.accept, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	out, err := OpenAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(out, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range Routes {
+		methods, ok := doc.Paths[r.Path()]
+		if !ok {
+			t.Errorf("missing path %s", r.Path())
+			continue
+		}
+		if _, ok := methods[map[string]string{"GET": "get", "POST": "post"}[r.Method()]]; !ok {
+			t.Errorf("missing %s", r.Pattern)
+		}
+	}
+
+	for _, m := range regexp.MustCompile(`"\$ref": "#/components/schemas/(\w+)"`).FindAllStringSubmatch(string(out), -1) {
+		if _, ok := doc.Components.Schemas[m[1]]; !ok {
+			t.Errorf("unresolved $ref to %s", m[1])
+		}
+	}
+	if _, ok := doc.Components.Schemas["DocResult"]; !ok {
+		t.Error("nested DocResult schema missing")
+	}
+}
+
+func TestRoutes_GETIdempotent(t *testing.T) {
+	for _, r := range Routes {
+		if r.Method() == "GET" && !r.Idempotent {
+			t.Errorf("%s isn't marked idempotent", r.Pattern)
+		}
+	}
+}
@@ -1,14 +1,57 @@
 package rpc
 
+import (
+	"slices"
+	"time"
+)
+
 // AddCratesRequest is the request body for POST /add-crates.
 type AddCratesRequest struct {
 	Crates []CrateSpec `json:"crates"`
+	// MaxDuration (a Go duration such as "90s") bounds how long the request
+	// waits. Crates still indexing when it runs out finish in the background
+	// and come back with Partial set.
+	MaxDuration string `json:"max_duration,omitempty"`
+	// DependencyDepth also indexes the dependencies of each crate: 1 adds
+	// its direct dependencies, 2 theirs as well, and so on. Their results
+	// follow the requested crates'.
+	DependencyDepth int `json:"dependency_depth,omitempty"`
+	// Namespace selects an isolated index within the daemon, so projects
+	// or agents sharing it don't see each other's crates. Empty is the
+	// default namespace. Every request naming crates takes one.
+	Namespace string `json:"namespace,omitempty"`
 }
 
 type CrateSpec struct {
 	Name    string `json:"name"`
 	Version string `json:"version,omitempty"`
-	Force   bool   `json:"force,omitempty"`
+	// Force re-indexes a crate that is already indexed.
+	Force bool `json:"force,omitempty"`
+	// OverBudget indexes a crate even when that goes over the embedding
+	// budget.
+	OverBudget bool `json:"over_budget,omitempty"`
+	// Registry names an alternative registry from the config; empty means
+	// crates.io and docs.rs.
+	Registry string `json:"registry,omitempty"`
+	// RustdocJSON, when set, is indexed instead of fetching from docs.rs. It
+	// may be zstd-compressed. Name and Version default to the ones recorded
+	// in the JSON.
+	RustdocJSON []byte `json:"rustdoc_json,omitempty"`
+	// Ignore adjusts the index.ignore settings for this crate. It only
+	// applies when the crate is indexed, so combine it with Force to
+	// re-index one already in the index.
+	Ignore *IgnoreOptions `json:"ignore,omitempty"`
+}
+
+// IgnoreOptions adjusts which items indexing leaves out.
+type IgnoreOptions struct {
+	// Patterns are item paths to leave out as well as those in
+	// index.ignore.patterns.
+	Patterns []string `json:"patterns,omitempty"`
+	// Hidden and Deprecated override index.ignore.hidden and
+	// index.ignore.deprecated when set.
+	Hidden     *bool `json:"hidden,omitempty"`
+	Deprecated *bool `json:"deprecated,omitempty"`
 }
 
 // AddCratesResponse is the response body for POST /add-crates.
@@ -21,6 +64,25 @@ type CrateResult struct {
 	Version string `json:"version"`
 	Items   int    `json:"items"`
 	Error   string `json:"error,omitempty"`
+	// Suggestions names existing crates close to Name when the registry has
+	// no crate by that name, e.g. serde_json for serde-jsn.
+	Suggestions []string `json:"suggestions,omitempty"`
+	// SuggestedVersion is the newest release with rustdoc JSON that can be
+	// indexed, when the requested one has none: its build failed, it was
+	// yanked or its JSON predates the supported format.
+	SuggestedVersion string `json:"suggested_version,omitempty"`
+	// Partial means the time box ran out: Items have been stored and any
+	// finished embeddings are searchable, but indexing continues in the background.
+	Partial bool `json:"partial,omitempty"`
+	// Tokens is what the embedding provider billed for this add, and Cost
+	// its estimate in dollars at voyage_ai.price_per_million_tokens (0 when
+	// no price is set).
+	Tokens int     `json:"tokens,omitempty"`
+	Cost   float64 `json:"cost,omitempty"`
+	// EstimatedTokens is set when the add was refused for going over
+	// embedding.max_tokens_per_crate or embedding.monthly_budget_tokens:
+	// what it would have embedded. OverBudget indexes it anyway.
+	EstimatedTokens int `json:"estimated_tokens,omitempty"`
 }
 
 // ProgressLine is a single line of NDJSON streamed from the add-crates endpoint.
@@ -32,45 +94,470 @@ type ProgressLine struct {
 
 // SearchRequest is the request body for POST /search.
 type SearchRequest struct {
-	Query             string   `json:"query"`
+	Query string `json:"query"`
+	// Crates restricts results to crates given as "name" (any indexed
+	// version) or "name@version" (pinned; partial versions match).
 	Crates            []string `json:"crates,omitempty"`
 	Threshold         float32  `json:"threshold,omitempty"`
 	Limit             int      `json:"limit,omitempty"`
 	RerankInstruction string   `json:"rerank_instruction,omitempty"`
+	Features          []string `json:"features,omitempty"`
+	ExamplesOnly      bool     `json:"examples_only,omitempty"`
+	Explain           bool     `json:"explain,omitempty"`
+	// Kinds restricts results to items of these kinds ("struct", "trait",
+	// "function", ...). "macro" also matches attribute and derive macros.
+	Kinds []string `json:"kinds,omitempty"`
+	// ExcludeCrates and ExcludeKinds drop items of these crates (specs as
+	// in Crates) and kinds (as in Kinds) before ranking.
+	ExcludeCrates []string `json:"exclude_crates,omitempty"`
+	ExcludeKinds  []string `json:"exclude_kinds,omitempty"`
+	// ExcludeDeprecated drops #[deprecated] items. Without it they rank
+	// below others by search.deprecated_penalty.
+	ExcludeDeprecated bool `json:"exclude_deprecated,omitempty"`
+	// ExcludeUnstable drops #[unstable] items, which need a nightly
+	// toolchain. Only staged_api crates such as std mark them.
+	ExcludeUnstable bool `json:"exclude_unstable,omitempty"`
+	// Expand also searches lexical variants of Query (identifier spellings,
+	// Rust synonyms such as "future" for "async task") and merges their
+	// candidates before reranking.
+	Expand bool `json:"expand,omitempty"`
+	// WithDependencies widens Crates to their indexed direct (non-dev)
+	// dependencies.
+	WithDependencies bool `json:"with_dependencies,omitempty"`
+	// Rerank forces reranking on or off; nil follows the search.rerank
+	// setting.
+	Rerank *bool `json:"rerank,omitempty"`
+	// Flat returns every hit separately instead of folding members and
+	// repeated hits under their parent item's Children.
+	Flat bool `json:"flat,omitempty"`
+	// SnippetLength caps each result's snippet in bytes; 0 uses
+	// search.snippet_length.
+	SnippetLength int    `json:"snippet_length,omitempty"`
+	Namespace     string `json:"namespace,omitempty"`
 }
 
 // SearchResponse is the response body for POST /search.
 type SearchResponse struct {
-	Results []DocResult `json:"results"`
+	Results []DocResult    `json:"results"`
+	Explain *SearchExplain `json:"explain,omitempty"`
+	// Suggestions lists unindexed crates that may cover the query. Only set
+	// when nothing matched.
+	Suggestions *CrateSuggestions `json:"suggestions,omitempty"`
+	// RerankError is set when reranking failed and results fell back to
+	// vector order.
+	RerankError string `json:"rerank_error,omitempty"`
+	// FetchErrors describes requested crates that couldn't be indexed for
+	// the search, e.g. misspelled names, with suggestions where there are any.
+	FetchErrors []string `json:"fetch_errors,omitempty"`
+}
+
+// CrateSuggestions is a hint to index more crates when a search comes up empty.
+type CrateSuggestions struct {
+	Message string              `json:"message"`
+	Crates  []CrateSearchResult `json:"crates"`
+}
+
+// SearchExplain describes how a search was ranked. Only returned when
+// SearchRequest.Explain is set.
+type SearchExplain struct {
+	Candidates    int     `json:"candidates"`
+	TopSimilarity float32 `json:"top_similarity"`
+	Margin        float32 `json:"margin"`
+	Reranked      bool    `json:"reranked"`
+	// RerankSkipped says why the rerank call was not made, if it wasn't.
+	RerankSkipped string `json:"rerank_skipped,omitempty"`
+	RerankError   string `json:"rerank_error,omitempty"`
+	// Queries lists the queries an expanded search ran, the original first.
+	Queries []string `json:"queries,omitempty"`
 }
 
 type DocResult struct {
-	URI          string  `json:"uri"`
-	CrateName    string  `json:"crate_name"`
-	CrateVersion string  `json:"crate_version"`
-	Path         string  `json:"path"`
-	Kind         string  `json:"kind"`
-	Score        float32 `json:"score"`
-	Snippet      string  `json:"snippet"`
+	URI          string   `json:"uri"`
+	CrateName    string   `json:"crate_name"`
+	CrateVersion string   `json:"crate_version"`
+	Path         string   `json:"path"`
+	Kind         string   `json:"kind"`
+	Score        float32  `json:"score"`
+	Snippet      string   `json:"snippet"` // best-matching passage, query terms in **bold**
+	Features     []string `json:"features,omitempty"`
+	Code         string   `json:"code,omitempty"` // full example code, for examples-only searches
+	// Deprecated is set for #[deprecated] items; get-doc shows the note.
+	Deprecated bool `json:"deprecated"`
+	// Stability is "stable since 1.0.0" or "unstable: <feature>" for items
+	// carrying #[stable] or #[unstable], as the standard library's do.
+	Stability string `json:"stability,omitempty"`
+	// ScoreKind says whether Score is a rerank relevance score or a vector
+	// similarity; the two aren't comparable.
+	ScoreKind string `json:"score_kind,omitempty"`
+	// Fragment names the fragment the hit came from (e.g. "implementations");
+	// URI then ends in "#" + Fragment.
+	Fragment string `json:"fragment,omitempty"`
+	// Chunk is the text of the embedded chunk that matched.
+	Chunk string `json:"chunk,omitempty"`
+	// Children are further hits on this item or its members, best first.
+	Children []DocResult `json:"children,omitempty"`
 }
 
+// Score kinds for DocResult.ScoreKind.
+const (
+	ScoreRerank = "rerank"
+	ScoreVector = "vector"
+)
+
 // GetDocRequest is the request body for POST /get-doc.
 type GetDocRequest struct {
 	Crate    string `json:"crate"`
 	Version  string `json:"version"`
 	Path     string `json:"path"`
 	Fragment string `json:"fragment,omitempty"`
+	// Format is "markdown" (the default) or "plain", which strips markup and
+	// wraps to Width columns (default 80).
+	Format string `json:"format,omitempty"`
+	Width  int    `json:"width,omitempty"`
+	// IncludeLinked appends the summaries of up to this many linked items
+	// (signature types first, then doc links) from already-indexed crates.
+	IncludeLinked int `json:"include_linked,omitempty"`
+	// ResolveLinks checks every rsdoc:// link in the output against the
+	// index. "annotate" marks links that won't resolve without fetching, or
+	// at all; "rewrite" also points links into unindexed crates at docs.rs.
+	// Empty leaves links alone.
+	ResolveLinks string `json:"resolve_links,omitempty"`
+	Namespace    string `json:"namespace,omitempty"`
 }
 
-// GetDocResponse is the response body for POST /get-doc.
+// GetDocResponse is the response body for POST /get-doc. Markdown holds plain
+// text when GetDocRequest.Format is "plain". Links is filled in when
+// GetDocRequest.ResolveLinks is set.
 type GetDocResponse struct {
+	Markdown string       `json:"markdown"`
+	Links    []LinkStatus `json:"links,omitempty"`
+}
+
+// Link statuses reported in LinkStatus.
+const (
+	// LinkIndexed links resolve from the index.
+	LinkIndexed = "indexed"
+	// LinkUnindexed links point into a crate, or crate version, that isn't
+	// indexed; following one fetches it first.
+	LinkUnindexed = "unindexed"
+	// LinkMissing links point at an item the indexed crate doesn't have.
+	LinkMissing = "missing"
+)
+
+// LinkStatus is an rsdoc:// link found in a document and whether it
+// resolves. DocsURL is the docs.rs page for unindexed links.
+type LinkStatus struct {
+	URI     string `json:"uri"`
+	Status  string `json:"status"`
+	DocsURL string `json:"docs_url,omitempty"`
+}
+
+// BuildContextRequest is the request body for POST /build-context.
+type BuildContextRequest struct {
+	URIs        []string `json:"uris"`
+	TokenBudget int      `json:"token_budget,omitempty"`
+	Namespace   string   `json:"namespace,omitempty"`
+}
+
+// SummarizeRequest is the request body for POST /summarize.
+type SummarizeRequest struct {
+	URI         string `json:"uri"`
+	TokenBudget int    `json:"token_budget,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+}
+
+// SummarizeResponse is the response body for POST /summarize: an
+// extractive summary of the document at URI, the canonical form of the
+// requested one. Truncated is set when parts were left out to fit the
+// budget.
+type SummarizeResponse struct {
+	URI       string `json:"uri"`
+	Markdown  string `json:"markdown"`
+	Tokens    int    `json:"tokens"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// BuildContextResponse is the response body for POST /build-context.
+type BuildContextResponse struct {
 	Markdown string `json:"markdown"`
+	Tokens   int    `json:"tokens"`
+	// Truncated lists URIs whose docs were cut to fit the budget.
+	Truncated []string `json:"truncated,omitempty"`
+	// Omitted lists URIs that didn't fit at all or couldn't be resolved.
+	Omitted []string `json:"omitted,omitempty"`
+}
+
+// ContextRequest is the request body for POST /context.
+type ContextRequest struct {
+	Query            string   `json:"query"`
+	Crates           []string `json:"crates,omitempty"`
+	Features         []string `json:"features,omitempty"`
+	WithDependencies bool     `json:"with_dependencies,omitempty"`
+	// Limit is how many search hits to bundle (default 8).
+	Limit       int    `json:"limit,omitempty"`
+	TokenBudget int    `json:"token_budget,omitempty"`
+	Namespace   string `json:"namespace,omitempty"`
+}
+
+// ContextResponse is the response body for POST /context: the bundle plus
+// the search hits it was built from, best first.
+type ContextResponse struct {
+	BuildContextResponse
+	Sources []DocResult `json:"sources"`
+	// Suggestions is set like SearchResponse.Suggestions when nothing matched.
+	Suggestions *CrateSuggestions `json:"suggestions,omitempty"`
+}
+
+// SimilarRequest is the request body for POST /similar.
+type SimilarRequest struct {
+	// URI is the rsdoc:// URI of the item to find neighbours of; with a
+	// #fragment, that fragment's content is compared instead.
+	URI string `json:"uri"`
+	// Crates restricts results like SearchRequest.Crates.
+	Crates    []string `json:"crates,omitempty"`
+	Limit     int      `json:"limit,omitempty"`
+	Namespace string   `json:"namespace,omitempty"`
+}
+
+// SimilarResponse is the response body for POST /similar. URI is the item
+// compared against, after resolving "latest" and re-exports.
+type SimilarResponse struct {
+	URI     string      `json:"uri"`
+	Results []DocResult `json:"results"`
+}
+
+// SymbolsRequest is the request body for POST /symbols.
+type SymbolsRequest struct {
+	// Query is an item name, or a path (or path suffix) containing "::".
+	Query string `json:"query"`
+	// Exact only returns items whose name or path matches Query exactly,
+	// rather than also those starting with or containing it.
+	Exact bool `json:"exact,omitempty"`
+	// Crates restricts results like SearchRequest.Crates.
+	Crates    []string `json:"crates,omitempty"`
+	Limit     int      `json:"limit,omitempty"`
+	Namespace string   `json:"namespace,omitempty"`
+}
+
+// SymbolsResponse is the response body for POST /symbols. Results carry no
+// score or snippet.
+type SymbolsResponse struct {
+	Results []DocResult `json:"results"`
+}
+
+// DepsRequest is the request body for POST /deps.
+type DepsRequest struct {
+	Crate     string `json:"crate"`
+	Version   string `json:"version,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// DepsResponse is the response body for POST /deps. Dependents lists the
+// indexed crates (as name@version) that depend on this one.
+type DepsResponse struct {
+	Crate        string       `json:"crate"`
+	Version      string       `json:"version"`
+	Dependencies []Dependency `json:"dependencies"`
+	Dependents   []string     `json:"dependents,omitempty"`
+}
+
+// Dependency is a direct dependency. Req is the registry version requirement;
+// Version is the version docs.rs built against, when known. IndexedVersion is
+// set if some version of the dependency is indexed locally.
+type Dependency struct {
+	Name           string `json:"name"`
+	Req            string `json:"req"`
+	Version        string `json:"version,omitempty"`
+	Kind           string `json:"kind"`
+	Optional       bool   `json:"optional,omitempty"`
+	IndexedVersion string `json:"indexed_version,omitempty"`
+}
+
+// DiffRequest is the request body for POST /diff.
+type DiffRequest struct {
+	Crate     string `json:"crate"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// DiffResponse is the response body for POST /diff. Versions are resolved
+// (never "latest").
+type DiffResponse struct {
+	Crate   string       `json:"crate"`
+	From    string       `json:"from"`
+	To      string       `json:"to"`
+	Added   []DiffItem   `json:"added,omitempty"`
+	Removed []DiffItem   `json:"removed,omitempty"`
+	Changed []DiffChange `json:"changed,omitempty"`
+}
+
+type DiffItem struct {
+	URI       string `json:"uri"`
+	Path      string `json:"path"`
+	Kind      string `json:"kind"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// DiffChange is an item present in both versions whose signature or kind changed.
+type DiffChange struct {
+	URI          string `json:"uri"`
+	Path         string `json:"path"`
+	Kind         string `json:"kind"`
+	OldKind      string `json:"old_kind,omitempty"`
+	OldSignature string `json:"old_signature"`
+	NewSignature string `json:"new_signature"`
+}
+
+// VerifyRequest is the request body for POST /verify.
+type VerifyRequest struct {
+	Repair bool `json:"repair,omitempty"`
+}
+
+// VerifyResponse is the response body for POST /verify.
+type VerifyResponse struct {
+	ContentHashes int `json:"content_hashes"`
+	// MissingContent lists content hashes that can't be read from the CAS.
+	MissingContent []string `json:"missing_content,omitempty"`
+	// Unembedded lists item and example content hashes with no embeddings.
+	Unembedded []string `json:"unembedded,omitempty"`
+	// MissingFromIndex counts embedding rows absent from the HNSW index.
+	MissingFromIndex int `json:"missing_from_index"`
+	// OrphanedInIndex counts HNSW nodes with no embedding row.
+	OrphanedInIndex int `json:"orphaned_in_index"`
+	// MissingJSONCache lists processed crates (name@version) without cached rustdoc JSON.
+	MissingJSONCache []string `json:"missing_json_cache,omitempty"`
+	// OrphanedJSONCache lists cached rustdoc JSON (name@version) with no crate.
+	OrphanedJSONCache []string `json:"orphaned_json_cache,omitempty"`
+	// Repaired describes repairs made when VerifyRequest.Repair was set.
+	Repaired []string `json:"repaired,omitempty"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// OK reports whether verification found no problems.
+func (r *VerifyResponse) OK() bool {
+	return len(r.MissingContent) == 0 && len(r.Unembedded) == 0 &&
+		r.MissingFromIndex == 0 && r.OrphanedInIndex == 0 &&
+		len(r.MissingJSONCache) == 0 && len(r.OrphanedJSONCache) == 0
+}
+
+// ExportRequest is the request body for POST /export. Path is where the
+// daemon writes the archive; it must be absolute and not exist yet.
+type ExportRequest struct {
+	Path string `json:"path"`
+}
+
+// ExportResponse is the response body for POST /export.
+type ExportResponse struct {
+	Path  string `json:"path"`
+	Files int    `json:"files"`
+	Bytes int64  `json:"bytes"`
+}
+
+// SnapshotRequest is the request body for POST /snapshot. Path is where the
+// database copy goes; the HNSW index is written next to it with a .hnsw
+// extension. Path must be absolute and not exist yet.
+type SnapshotRequest struct {
+	Path string `json:"path"`
+}
+
+// SnapshotResponse is the response body for POST /snapshot. HNSWPath is
+// empty when the daemon keeps no HNSW index (index.storage = "disk").
+type SnapshotResponse struct {
+	Path     string `json:"path"`
+	HNSWPath string `json:"hnsw_path,omitempty"`
+	Bytes    int64  `json:"bytes"`
+}
+
+// RestoreRequest is the request body for POST /restore. Path is a database
+// written by POST /snapshot.
+type RestoreRequest struct {
+	Path string `json:"path"`
+}
+
+// RestoreResponse is the response body for POST /restore.
+type RestoreResponse struct {
+	Crates int `json:"crates"`
+}
+
+// RepackRequest is the request body for POST /repack. All also rewrites the
+// existing packs into the new one, dropping content removed from them.
+type RepackRequest struct {
+	All bool `json:"all,omitempty"`
+}
+
+// RepackResponse is the response body for POST /repack. Bytes is the size
+// of the new pack; Packs counts the packs afterwards.
+type RepackResponse struct {
+	Loose   int   `json:"loose"`
+	Packed  int   `json:"packed"`
+	Dropped int   `json:"dropped"`
+	Packs   int   `json:"packs"`
+	Bytes   int64 `json:"bytes"`
+}
+
+// ReembedRequest is the request body for POST /reembed, which discards all
+// embeddings and re-indexes every crate with a new model. The response
+// streams ProgressLines like /add-crates.
+type ReembedRequest struct {
+	// Model and Dimensions select the new model; empty uses the daemon's
+	// voyage_ai settings.
+	Model      string `json:"model,omitempty"`
+	Dimensions int    `json:"dimensions,omitempty"`
+}
+
+// ReindexRequest is the request body for POST /reindex, which re-parses
+// crates from the rustdoc JSON cache with the running build's parser and
+// chunker. The response streams ProgressLines like /add-crates.
+type ReindexRequest struct {
+	// Crates limits it to these crates in Namespace, given as "name" (every
+	// indexed version) or "name@version". Empty re-indexes every crate in
+	// every namespace.
+	Crates    []string `json:"crates,omitempty"`
+	Namespace string   `json:"namespace,omitempty"`
+	// ChangedOnly limits it to crates with content embedded by another
+	// model or chunker version, and re-embeds only that content.
+	ChangedOnly bool `json:"changed_only,omitempty"`
+	// Pause (a Go duration such as "2s") waits between crates, leaving the
+	// embedding provider's quota and the daemon to other work.
+	Pause string `json:"pause,omitempty"`
+	// OverBudget re-embeds crates even over the embedding budget.
+	OverBudget bool `json:"over_budget,omitempty"`
+}
+
+// WatchRequest is the request body for POST /watch.
+type WatchRequest struct {
+	// Manifest is the project's Cargo.toml, as a path on the daemon's
+	// machine.
+	Manifest  string `json:"manifest"`
+	Namespace string `json:"namespace,omitempty"`
+	// Stop ends the watch on Manifest instead of starting one.
+	Stop bool `json:"stop,omitempty"`
+}
+
+// WatchStatus is a project whose dependencies the daemon keeps indexed:
+// the crates.io crates its own packages depend on directly, at the versions
+// its Cargo.lock resolves.
+type WatchStatus struct {
+	Manifest  string `json:"manifest"`
+	Lockfile  string `json:"lockfile"`
+	Namespace string `json:"namespace,omitempty"`
+	// Dependencies counts the locked dependencies and Indexed those of them
+	// in the index; Indexing is the one being added now, if any.
+	Dependencies int        `json:"dependencies"`
+	Indexed      int        `json:"indexed"`
+	Indexing     string     `json:"indexing,omitempty"`
+	SyncedAt     *time.Time `json:"synced_at,omitempty"`
+	// Errors are the dependencies the last sync couldn't add, or why it
+	// couldn't read the lockfile.
+	Errors []string `json:"errors,omitempty"`
 }
 
 // SearchCratesRequest is the request body for POST /search-crates.
 type SearchCratesRequest struct {
-	Query string `json:"query"`
-	Limit int    `json:"limit,omitempty"`
+	Query    string `json:"query"`
+	Limit    int    `json:"limit,omitempty"`
+	Registry string `json:"registry,omitempty"`
 }
 
 // SearchCratesResponse is the response body for POST /search-crates.
@@ -89,11 +576,243 @@ type CrateSearchResult struct {
 
 // StatusResponse is the response body for GET /status.
 type StatusResponse struct {
-	Crates []CrateStatus `json:"crates"`
+	Crates        []CrateStatus `json:"crates"`
+	StartedAt     time.Time     `json:"started_at"`
+	DatabaseBytes int64         `json:"database_bytes"`
+	// Embeddings is the embedding provider's state as seen by recent requests.
+	Embeddings *ProviderHealth `json:"embeddings,omitempty"`
+	// Model is the embedding model the index was built with.
+	Model *EmbeddingModel `json:"model,omitempty"`
+	// Watches are the projects whose dependencies the daemon keeps indexed.
+	Watches []WatchStatus `json:"watches,omitempty"`
 }
 
-type CrateStatus struct {
+// CrateStats is one crate's share of the index. Content shared with other
+// crates, such as docs unchanged between versions, counts toward each of
+// them; SharedBytes is how much of CASBytes that is.
+type CrateStats struct {
 	Name      string `json:"name"`
 	Version   string `json:"version"`
-	Processed bool   `json:"processed"`
+	Namespace string `json:"namespace,omitempty"`
+	Items     int    `json:"items"`
+	// Documents counts the distinct item docs, fragments and examples the
+	// crate's items reference, and Chunks their embedded chunks.
+	Documents      int   `json:"documents"`
+	Chunks         int   `json:"chunks"`
+	EmbeddingBytes int64 `json:"embedding_bytes"`
+	CASBytes       int64 `json:"cas_bytes"`
+	SharedBytes    int64 `json:"shared_bytes"`
+	JSONCacheBytes int64 `json:"json_cache_bytes"`
+	// Tokens is what embedding the crate has been billed over every add,
+	// and Cost its estimate at voyage_ai.price_per_million_tokens.
+	Tokens int64   `json:"tokens"`
+	Cost   float64 `json:"cost,omitempty"`
+}
+
+// StatsResponse is the response body for GET /stats. The totals count
+// shared content once.
+type StatsResponse struct {
+	Crates         []CrateStats `json:"crates"`
+	Items          int          `json:"items"`
+	Documents      int          `json:"documents"`
+	Chunks         int          `json:"chunks"`
+	EmbeddingBytes int64        `json:"embedding_bytes"`
+	CASBytes       int64        `json:"cas_bytes"`
+	JSONCacheBytes int64        `json:"json_cache_bytes"`
+	Tokens         int64        `json:"tokens"`
+	Cost           float64      `json:"cost,omitempty"`
+	// DedupSavedBytes is the CAS and embedding storage that content
+	// addressing avoided: what the crates would take stored separately,
+	// less what they take together.
+	DedupSavedBytes int64 `json:"dedup_saved_bytes"`
+	// CASDiskBytes is everything in the CAS directory; the excess over
+	// CASBytes is content no indexed crate references.
+	CASDiskBytes  int64 `json:"cas_disk_bytes"`
+	DatabaseBytes int64 `json:"database_bytes"`
+	HNSWBytes     int64 `json:"hnsw_bytes"`
+	IndexNodes    int   `json:"index_nodes"`
+}
+
+// CoverageRequest is the request body for POST /coverage.
+type CoverageRequest struct {
+	Crate     string `json:"crate"`
+	Version   string `json:"version,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// KindCoverage is the coverage of one item kind.
+type KindCoverage struct {
+	Kind       string `json:"kind"`
+	Items      int    `json:"items"`
+	Documented int    `json:"documented"`
+	Embedded   int    `json:"embedded"`
+}
+
+// CoverageItem names an item listed in a CoverageResponse.
+type CoverageItem struct {
+	Path string `json:"path"`
+	Kind string `json:"kind"`
+	// MissingFragments counts the item's fragments without embeddings.
+	MissingFragments int `json:"missing_fragments,omitempty"`
+}
+
+// CoverageResponse is the response body for POST /coverage. Only
+// documented items have anything to embed, so Embedded counts out of
+// Documented.
+type CoverageResponse struct {
+	Crate             string         `json:"crate"`
+	Version           string         `json:"version"`
+	Items             int            `json:"items"`
+	Documented        int            `json:"documented"`
+	Embedded          int            `json:"embedded"`
+	Fragments         int            `json:"fragments"`
+	EmbeddedFragments int            `json:"embedded_fragments"`
+	ByKind            []KindCoverage `json:"by_kind"`
+	Undocumented      []CoverageItem `json:"undocumented"`
+	// Unembedded lists items whose docs or fragments lack embeddings,
+	// usually from an interrupted add.
+	Unembedded []CoverageItem `json:"unembedded"`
+}
+
+// HealthResponse is the response body for GET /health.
+type HealthResponse struct {
+	// OK is set when no check failed; warnings don't count.
+	OK        bool          `json:"ok"`
+	StartedAt time.Time     `json:"started_at"`
+	Checks    []HealthCheck `json:"checks"`
+	Crates    int           `json:"crates"`
+	Items     int           `json:"items"`
+	// Embeddings counts stored chunk embeddings and IndexNodes the vectors
+	// in the HNSW index; they match in a healthy index.
+	Embeddings int `json:"embeddings"`
+	IndexNodes int `json:"index_nodes"`
+}
+
+// Health check statuses.
+const (
+	HealthOK   = "ok"
+	HealthWarn = "warn"
+	HealthFail = "fail"
+)
+
+// HealthCheck is the result of checking one of the daemon's dependencies.
+// Fix suggests what to do when Status isn't HealthOK.
+type HealthCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Fix    string `json:"fix,omitempty"`
+}
+
+// VersionResponse is the response body for GET /version. Daemons that
+// predate the endpoint answer 404 and count as protocol 0.
+type VersionResponse struct {
+	Protocol   int    `json:"protocol"`
+	APIVersion string `json:"api_version"`
+}
+
+// CapabilitiesResponse is the response body for GET /capabilities. Clients
+// use it to adapt to the daemon they are talking to; daemons that predate
+// the endpoint answer 404.
+type CapabilitiesResponse struct {
+	// Endpoints lists the daemon's routes, e.g. "POST /search".
+	Endpoints []string `json:"endpoints"`
+	// APIVersions lists the version prefixes the routes are served under,
+	// e.g. "v1" for POST /v1/search. Empty from daemons that predate them.
+	APIVersions []string `json:"api_versions,omitempty"`
+	// Embeddings names the embedding provider, or is empty when none is
+	// configured and semantic search is unavailable.
+	Embeddings     string `json:"embeddings,omitempty"`
+	EmbeddingModel string `json:"embedding_model,omitempty"`
+	// Rerank names the rerank model; empty when results aren't reranked.
+	Rerank string `json:"rerank,omitempty"`
+	// Auth is set when requests must authenticate beyond the socket's file
+	// permissions.
+	Auth bool `json:"auth"`
+	// Registries lists the configured alternative registries.
+	Registries []string `json:"registries,omitempty"`
+}
+
+// HasEndpoint reports whether the daemon serves pattern, e.g. "POST /deps".
+func (c *CapabilitiesResponse) HasEndpoint(pattern string) bool {
+	return slices.Contains(c.Endpoints, pattern)
+}
+
+// EmbeddingModel describes the model the index was embedded with.
+type EmbeddingModel struct {
+	Model      string `json:"model"`
+	Dimensions int    `json:"dimensions"`
+	// Configured is set when voyage_ai.model differs from Model. Searches
+	// keep using Model until the index is re-embedded.
+	Configured           string `json:"configured,omitempty"`
+	ConfiguredDimensions int    `json:"configured_dimensions,omitempty"`
+}
+
+// ProviderHealth describes the embedding provider. State is one of
+// "unknown", "ok", "degraded", "over_quota" or "unauthorized".
+type ProviderHealth struct {
+	State       string     `json:"state"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+	// RateLimited counts rate-limited requests in the last 15 minutes.
+	RateLimited       int    `json:"rate_limited,omitempty"`
+	RemainingRequests string `json:"remaining_requests,omitempty"`
+	RemainingTokens   string `json:"remaining_tokens,omitempty"`
+}
+
+type CrateStatus struct {
+	Name        string     `json:"name"`
+	Version     string     `json:"version"`
+	Processed   bool       `json:"processed"`
+	Items       int        `json:"items"`
+	ProcessedAt *time.Time `json:"processed_at,omitempty"`
+	LastUsedAt  time.Time  `json:"last_used_at"`
+	// Source is "html" when the crate was scraped from docs.rs HTML because
+	// no rustdoc JSON was published; such items have no fragments. It is
+	// "file" for crates imported from user-provided rustdoc JSON.
+	Source   string `json:"source,omitempty"`
+	Registry string `json:"registry,omitempty"`
+	// Partial is set while a time-boxed add is still indexing in the background.
+	Partial bool `json:"partial,omitempty"`
+}
+
+// NamespacesResponse is the response body for GET /namespaces: every
+// namespace holding crates, the default ("") first.
+type NamespacesResponse struct {
+	Namespaces []NamespaceInfo `json:"namespaces"`
+}
+
+type NamespaceInfo struct {
+	Name   string `json:"name"`
+	Crates int    `json:"crates"`
+	Items  int    `json:"items"`
+}
+
+// DeleteNamespaceRequest is the request body for POST /delete-namespace.
+type DeleteNamespaceRequest struct {
+	Namespace string `json:"namespace"`
+}
+
+// DeleteNamespaceResponse is the response body for POST /delete-namespace.
+// RemovedContent counts the stored docs no other namespace used, whose
+// embeddings and CAS files went with it.
+type DeleteNamespaceResponse struct {
+	Crates         int `json:"crates"`
+	RemovedContent int `json:"removed_content"`
+}
+
+// ReloadConfigResponse is the response body for POST /reload-config.
+type ReloadConfigResponse struct {
+	Changes []ConfigChange `json:"changes"`
+}
+
+// ConfigChange is a setting that differs from the daemon's running config.
+// Secrets are reported only as "set" or "unset". Restart marks settings the
+// daemon only reads at startup, which keep their old values until it is
+// restarted.
+type ConfigChange struct {
+	Key     string `json:"key"`
+	Old     string `json:"old"`
+	New     string `json:"new"`
+	Restart bool   `json:"restart,omitempty"`
 }
@@ -0,0 +1,51 @@
+package rpc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseDocURI parses rsdoc://crate/version/path#fragment (or the
+// crate@version/path shorthand) into a get-doc request. A missing path
+// defaults to the crate root, reported by defaultPath.
+func ParseDocURI(raw string) (req GetDocRequest, defaultPath bool, err error) {
+	uri := strings.TrimPrefix(raw, "rsdoc://")
+
+	// Support crate@version/path as alternative to crate/version/path
+	var crate, version, path string
+	if idx := strings.Index(uri, "@"); idx >= 0 {
+		crate = uri[:idx]
+		rest := strings.SplitN(uri[idx+1:], "/", 2)
+		version = rest[0]
+		if len(rest) == 2 {
+			path = rest[1]
+		}
+	} else {
+		parts := strings.SplitN(uri, "/", 3)
+		if len(parts) < 2 {
+			return GetDocRequest{}, false, fmt.Errorf("invalid URI: need crate/version/path or crate@version/path")
+		}
+		crate = parts[0]
+		version = parts[1]
+		if len(parts) == 3 {
+			path = parts[2]
+		}
+	}
+
+	if path == "" {
+		path = crate
+		defaultPath = true
+	}
+	var fragment string
+	if idx := strings.LastIndex(path, "#"); idx >= 0 {
+		fragment = path[idx+1:]
+		path = path[:idx]
+	}
+
+	return GetDocRequest{
+		Crate:    crate,
+		Version:  version,
+		Path:     path,
+		Fragment: fragment,
+	}, defaultPath, nil
+}
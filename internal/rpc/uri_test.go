@@ -0,0 +1,30 @@
+package rpc
+
+import "testing"
+
+func TestParseDocURI(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		uri         string
+		want        GetDocRequest
+		defaultPath bool
+	}{
+		{"rsdoc://serde/latest/serde::Serialize", GetDocRequest{Crate: "serde", Version: "latest", Path: "serde::Serialize"}, false},
+		{"tokio/1.0.0/tokio::spawn#examples", GetDocRequest{Crate: "tokio", Version: "1.0.0", Path: "tokio::spawn", Fragment: "examples"}, false},
+		{"serde@1.0.0/serde::Serialize", GetDocRequest{Crate: "serde", Version: "1.0.0", Path: "serde::Serialize"}, false},
+		{"rsdoc://serde/latest", GetDocRequest{Crate: "serde", Version: "latest", Path: "serde"}, true},
+	}
+	for _, tt := range tests {
+		got, defaultPath, err := ParseDocURI(tt.uri)
+		if err != nil {
+			t.Fatalf("ParseDocURI(%q): %v", tt.uri, err)
+		}
+		if got != tt.want || defaultPath != tt.defaultPath {
+			t.Errorf("ParseDocURI(%q) = %+v, %v; want %+v, %v", tt.uri, got, defaultPath, tt.want, tt.defaultPath)
+		}
+	}
+
+	if _, _, err := ParseDocURI("serde"); err == nil {
+		t.Error("expected error for URI without version")
+	}
+}
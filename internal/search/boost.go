@@ -0,0 +1,59 @@
+package search
+
+import (
+	"log/slog"
+	"math"
+
+	"github.com/jcdickinson/ferrisfetch/internal/db"
+)
+
+// popularityScale is the recent (90-day) download count that earns a crate
+// the full popularity boost. Downloads count on a log scale, so a crate with
+// a thousandth of them still gets over half the boost.
+const popularityScale = 10_000_000
+
+// crateBoosts returns score multipliers by item ID for the items in
+// crates, from their crate's popularity and whether it is the registry's
+// newest version. Items of crates with no stored registry info keep a
+// multiplier of 1. It returns nil when boosting is off.
+func (s *Searcher) crateBoosts(crates map[int]*db.Crate) map[int]float32 {
+	if s.opts.PopularityWeight <= 0 && s.opts.LatestWeight <= 0 || len(crates) == 0 {
+		return nil
+	}
+	seen := make(map[int]bool)
+	var crateIDs []int
+	for _, c := range crates {
+		if !seen[c.ID] {
+			seen[c.ID] = true
+			crateIDs = append(crateIDs, c.ID)
+		}
+	}
+	infos, err := s.db.CrateInfoByID(crateIDs)
+	if err != nil {
+		slog.Warn("crate info lookup failed; not boosting", "error", err)
+		return nil
+	}
+
+	boosts := make(map[int]float32, len(crates))
+	for itemID, c := range crates {
+		info, ok := infos[c.ID]
+		if !ok {
+			continue
+		}
+		boosts[itemID] = crateBoost(info, c.Version, s.opts.PopularityWeight, s.opts.LatestWeight)
+	}
+	return boosts
+}
+
+// crateBoost is the score multiplier for a version of a crate.
+func crateBoost(info db.CrateInfo, version string, popularityWeight, latestWeight float32) float32 {
+	boost := float32(1)
+	if popularityWeight > 0 && info.RecentDownloads > 0 {
+		norm := math.Log10(float64(info.RecentDownloads)+1) / math.Log10(popularityScale+1)
+		boost *= 1 + popularityWeight*float32(min(norm, 1))
+	}
+	if latestWeight > 0 && info.LatestVersion != "" && version == info.LatestVersion {
+		boost *= 1 + latestWeight
+	}
+	return boost
+}
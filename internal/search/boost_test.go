@@ -0,0 +1,25 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/jcdickinson/ferrisfetch/internal/db"
+)
+
+func TestCrateBoost(t *testing.T) {
+	popular := db.CrateInfo{RecentDownloads: popularityScale * 10, LatestVersion: "1.0.200"}
+	fork := db.CrateInfo{RecentDownloads: 50, LatestVersion: "0.3.0"}
+
+	if got := crateBoost(popular, "1.0.200", 0.1, 0.05); got < 1.15 || got > 1.16 {
+		t.Errorf("popular latest boost = %v, want 1.1 * 1.05", got)
+	}
+	if got := crateBoost(popular, "1.0.100", 0.1, 0.05); got < 1.099 || got > 1.101 {
+		t.Errorf("popular old version boost = %v, want 1.1", got)
+	}
+	if got := crateBoost(fork, "0.3.0", 0.1, 0); got <= 1 || got >= crateBoost(popular, "1.0.100", 0.1, 0) {
+		t.Errorf("fork boost = %v, want above 1 and below the popular crate's", got)
+	}
+	if got := crateBoost(db.CrateInfo{}, "1.0.0", 0.1, 0.05); got != 1 {
+		t.Errorf("boost without downloads or versions = %v, want 1", got)
+	}
+}
@@ -0,0 +1,124 @@
+package search
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// maxExpansions caps the queries an expanded search embeds, the original
+// included.
+const maxExpansions = 5
+
+// synonyms are groups of phrases that name the same thing, in Rust terms
+// and in the words people bring from other languages. A query using one is
+// also run with each of the others.
+var synonyms = [][]string{
+	{"async task", "future"},
+	{"promise", "future"},
+	{"coroutine", "async fn"},
+	{"goroutine", "spawned task"},
+	{"interface", "trait"},
+	{"inheritance", "trait"},
+	{"class", "struct"},
+	{"exception", "error", "Result"},
+	{"null", "None", "Option"},
+	{"nullable", "Option"},
+	{"callback", "closure", "Fn"},
+	{"lambda", "closure"},
+	{"array list", "Vec"},
+	{"dynamic array", "Vec"},
+	{"dictionary", "HashMap"},
+	{"hash table", "HashMap"},
+	{"smart pointer", "Box"},
+	{"reference counting", "Rc", "Arc"},
+	{"lock", "Mutex"},
+	{"channel", "mpsc"},
+	{"destructor", "Drop"},
+	{"copy constructor", "Clone"},
+	{"tostring", "Display"},
+	{"generic", "type parameter"},
+}
+
+// identifier matches words written as code: snake_case or CamelCase.
+var identifier = regexp.MustCompile(`\b[A-Za-z][A-Za-z0-9]*(?:_[A-Za-z0-9]+)+\b|\b[A-Z][a-z0-9]+(?:[A-Z][a-z0-9]*)+\b`)
+
+// expandQuery returns query followed by lexical variants of it: identifiers
+// respelled (read_to_string, ReadToString, "read to string") and phrases
+// swapped for their Rust synonyms. Docs phrase things inconsistently, so
+// each variant can surface candidates the others miss.
+func expandQuery(query string) []string {
+	queries := []string{query}
+	seen := map[string]bool{strings.ToLower(query): true}
+	add := func(q string) {
+		q = strings.Join(strings.Fields(q), " ")
+		if q == "" || seen[strings.ToLower(q)] || len(queries) >= maxExpansions {
+			return
+		}
+		seen[strings.ToLower(q)] = true
+		queries = append(queries, q)
+	}
+
+	for _, id := range identifier.FindAllString(query, -1) {
+		words := identWords(id)
+		add(strings.Replace(query, id, strings.Join(words, " "), 1))
+		if strings.Contains(id, "_") {
+			add(strings.Replace(query, id, camelCase(words), 1))
+		} else {
+			add(strings.Replace(query, id, strings.Join(words, "_"), 1))
+		}
+	}
+
+	for _, group := range synonyms {
+		for _, phrase := range group {
+			loc := phraseIndex(query, phrase)
+			if loc == nil {
+				continue
+			}
+			for _, alt := range group {
+				if alt != phrase {
+					add(query[:loc[0]] + alt + query[loc[1]:])
+				}
+			}
+			break
+		}
+	}
+	return queries
+}
+
+// phraseIndex finds phrase in s as whole words, ignoring case.
+func phraseIndex(s, phrase string) []int {
+	re := regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(phrase) + `\b`)
+	return re.FindStringIndex(s)
+}
+
+// identWords splits a snake_case or CamelCase identifier into lowercase
+// words.
+func identWords(id string) []string {
+	var words []string
+	for _, part := range strings.Split(id, "_") {
+		start := 0
+		for i, r := range part {
+			if i > 0 && unicode.IsUpper(r) {
+				words = append(words, strings.ToLower(part[start:i]))
+				start = i
+			}
+		}
+		if start < len(part) {
+			words = append(words, strings.ToLower(part[start:]))
+		}
+	}
+	return words
+}
+
+func camelCase(words []string) string {
+	var b strings.Builder
+	for _, w := range words {
+		if w == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(w[:1]))
+		b.WriteString(w[1:])
+	}
+	return b.String()
+}
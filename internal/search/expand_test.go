@@ -0,0 +1,31 @@
+package search
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExpandQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"read_to_string from a file", []string{"read_to_string from a file", "read to string from a file", "ReadToString from a file"}},
+		{"HashMap entry api", []string{"HashMap entry api", "hash map entry api", "hash_map entry api", "dictionary entry api", "hash table entry api"}},
+		{"spawn an async task", []string{"spawn an async task", "spawn an future"}},
+		{"Interface for readers", []string{"Interface for readers", "trait for readers"}},
+		{"parse json", []string{"parse json"}},
+	}
+	for _, tt := range tests {
+		if got := expandQuery(tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("expandQuery(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestExpandQuery_Capped(t *testing.T) {
+	got := expandQuery("null exception callback in hash_map")
+	if len(got) != maxExpansions {
+		t.Errorf("expandQuery returned %d queries, want %d: %q", len(got), maxExpansions, got)
+	}
+}
@@ -0,0 +1,66 @@
+package search
+
+import (
+	"strings"
+
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+)
+
+// containerKinds are the item kinds whose members (variants, associated
+// items, fragments) are folded into them by groupResults. Modules are left
+// out: the items in a module are separate concepts.
+var containerKinds = map[string]bool{
+	"struct": true,
+	"enum":   true,
+	"union":  true,
+	"trait":  true,
+}
+
+// groupResults collapses repeated hits on an item, and hits on members of a
+// struct, enum, union or trait that also matched, into one entry holding the
+// others as Children. results must be sorted best first; each group takes the
+// position and score of its best hit.
+func groupResults(results []rpc.DocResult) []rpc.DocResult {
+	type key struct{ crate, version, path string }
+	first := make(map[key]int, len(results))
+	for i, r := range results {
+		k := key{r.CrateName, r.CrateVersion, r.Path}
+		if _, ok := first[k]; !ok {
+			first[k] = i
+		}
+	}
+
+	// container returns the outermost matched container of results[i], or i.
+	container := func(i int) int {
+		r := results[i]
+		for end := strings.Index(r.Path, "::"); end >= 0; {
+			if p, ok := first[key{r.CrateName, r.CrateVersion, r.Path[:end]}]; ok && containerKinds[results[p].Kind] {
+				return p
+			}
+			next := strings.Index(r.Path[end+2:], "::")
+			if next < 0 {
+				break
+			}
+			end += 2 + next
+		}
+		return i
+	}
+
+	var grouped []rpc.DocResult
+	pos := make(map[int]int) // root hit -> index in grouped
+	for i, r := range results {
+		root := container(first[key{r.CrateName, r.CrateVersion, r.Path}])
+		at, ok := pos[root]
+		if !ok {
+			parent := results[root]
+			parent.Score = r.Score
+			at = len(grouped)
+			pos[root] = at
+			grouped = append(grouped, parent)
+		}
+		if i != root {
+			grouped[at].Children = append(grouped[at].Children, r)
+		}
+	}
+	return grouped
+}
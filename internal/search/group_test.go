@@ -0,0 +1,66 @@
+package search
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+)
+
+func hit(path, kind string, score float32) rpc.DocResult {
+	return rpc.DocResult{CrateName: "tokio", CrateVersion: "1.0.0", Path: path, Kind: kind, Score: score}
+}
+
+func TestGroupResults(t *testing.T) {
+	results := []rpc.DocResult{
+		hit("tokio::sync::Mutex::lock", "method", 0.9),
+		hit("tokio::spawn", "fn", 0.85),
+		hit("tokio::sync::Mutex", "struct", 0.8),
+		hit("tokio::sync::RwLock::read", "method", 0.7),
+		hit("tokio::sync::Mutex", "struct", 0.6),
+		hit("tokio::sync", "module", 0.5),
+		hit("tokio::sync::oneshot", "module", 0.4),
+	}
+	other := hit("tokio::sync::Mutex::lock", "method", 0.3)
+	other.CrateVersion = "0.2.0"
+	results = append(results, other)
+
+	type group struct {
+		path     string
+		score    float32
+		children []string
+	}
+	want := []group{
+		// The struct takes the place and score of its best member hit.
+		{"tokio::sync::Mutex", 0.9, []string{"tokio::sync::Mutex::lock", "tokio::sync::Mutex"}},
+		{"tokio::spawn", 0.85, nil},
+		// Members whose parent didn't match stay on their own.
+		{"tokio::sync::RwLock::read", 0.7, nil},
+		// Modules don't gather their items.
+		{"tokio::sync", 0.5, nil},
+		{"tokio::sync::oneshot", 0.4, nil},
+		// Nor does a parent from another version.
+		{"tokio::sync::Mutex::lock", 0.3, nil},
+	}
+
+	grouped := groupResults(results)
+	var got []group
+	for _, g := range grouped {
+		var children []string
+		for _, c := range g.Children {
+			children = append(children, c.Path)
+		}
+		got = append(got, group{g.Path, g.Score, children})
+	}
+	if !slices.EqualFunc(got, want, func(a, b group) bool {
+		return a.path == b.path && a.score == b.score && slices.Equal(a.children, b.children)
+	}) {
+		t.Errorf("groupResults =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestGroupResults_Empty(t *testing.T) {
+	if got := groupResults(nil); len(got) != 0 {
+		t.Errorf("groupResults(nil) = %+v", got)
+	}
+}
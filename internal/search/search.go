@@ -4,9 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"sort"
+	"strings"
+	"sync/atomic"
 
 	"github.com/jcdickinson/ferrisfetch/internal/cas"
 	"github.com/jcdickinson/ferrisfetch/internal/db"
+	"github.com/jcdickinson/ferrisfetch/internal/docs"
 	"github.com/jcdickinson/ferrisfetch/internal/embeddings"
 	md "github.com/jcdickinson/ferrisfetch/internal/markdown"
 	"github.com/jcdickinson/ferrisfetch/internal/rpc"
@@ -15,62 +19,207 @@ import (
 type Searcher struct {
 	db          *db.DB
 	voyage      *embeddings.VoyageClient
-	model       string
+	model       atomic.Value // string; see SetModel
 	rerankModel string
+	opts        Options
 }
 
-func NewSearcher(database *db.DB, voyage *embeddings.VoyageClient, model, rerankModel string) *Searcher {
+// Options tunes result ranking.
+type Options struct {
+	// KindWeights multiplies scores by item kind. Missing kinds weigh 1.
+	KindWeights map[string]float32
+	// RerankSkipSimilarity is the top-hit similarity at or above which rerank
+	// may be skipped. Zero disables the shortcut.
+	RerankSkipSimilarity float32
+	// RerankSkipMargin is how far the top hit must lead the runner-up for
+	// rerank to be skipped.
+	RerankSkipMargin float32
+	// Rerank is RerankOff, RerankAuto (the default) or RerankAlways.
+	Rerank string
+	// PopularityWeight scales the boost given to crates by their recent
+	// downloads; a crate at popularityScale downloads scores
+	// 1+PopularityWeight times as much. Zero disables it.
+	PopularityWeight float32
+	// LatestWeight boosts items from the registry's newest version of their
+	// crate by 1+LatestWeight. Zero disables it.
+	LatestWeight float32
+	// DeprecatedPenalty scales the scores of #[deprecated] items by
+	// 1-DeprecatedPenalty. Zero ranks them like any other item.
+	DeprecatedPenalty float32
+	// SnippetLength caps DocResult.Snippet in bytes; 0 means
+	// DefaultSnippetLength.
+	SnippetLength int
+}
+
+// Rerank modes. Auto reranks unless the vector ranking is already confident
+// (see Options.RerankSkipSimilarity); always ignores that shortcut.
+const (
+	RerankOff    = "off"
+	RerankAuto   = "auto"
+	RerankAlways = "always"
+)
+
+// ParseRerankMode validates a rerank mode; "" means RerankAuto.
+func ParseRerankMode(mode string) (string, error) {
+	switch mode {
+	case "":
+		return RerankAuto, nil
+	case RerankOff, RerankAuto, RerankAlways:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown rerank mode %q (want off, auto or always)", mode)
+}
+
+func NewSearcher(database *db.DB, voyage *embeddings.VoyageClient, model, rerankModel string, opts Options) *Searcher {
 	if model == "" {
 		model = "voyage-3.5"
 	}
 	if rerankModel == "" {
 		rerankModel = "rerank-lite-1"
 	}
-	return &Searcher{db: database, voyage: voyage, model: model, rerankModel: rerankModel}
+	s := &Searcher{db: database, voyage: voyage, rerankModel: rerankModel, opts: opts}
+	s.model.Store(model)
+	return s
+}
+
+// SetModel switches the model queries are embedded with, which must match
+// the model the index was embedded with.
+func (s *Searcher) SetModel(model string) {
+	s.model.Store(model)
+}
+
+// itemWeight returns the configured score multiplier for an item: its
+// kind's weight, less the penalty for deprecated items.
+func (s *Searcher) itemWeight(item *db.Item) float32 {
+	w := float32(1)
+	if kw, ok := s.opts.KindWeights[item.Kind]; ok {
+		w = kw
+	}
+	if item.Deprecation != "" {
+		w *= 1 - s.opts.DeprecatedPenalty
+	}
+	return w
+}
+
+// skipRerank reports whether the vector ranking is confident enough to return
+// as-is, and why.
+func (s *Searcher) skipRerank(top, margin float32) (string, bool) {
+	if s.opts.RerankSkipSimilarity <= 0 {
+		return "", false
+	}
+	if top < s.opts.RerankSkipSimilarity || margin < s.opts.RerankSkipMargin {
+		return "", false
+	}
+	return fmt.Sprintf("top similarity %.3f >= %.3f with margin %.3f >= %.3f",
+		top, s.opts.RerankSkipSimilarity, margin, s.opts.RerankSkipMargin), true
+}
+
+// kindAliases maps a kind filter to the item kinds it covers. Rustdoc files
+// proc macros under their own kinds, but to a reader they are all macros.
+var kindAliases = map[string][]string{
+	"macro": {"macro", "proc_attribute", "proc_derive"},
+	"fn":    {"function"},
+}
+
+// expandKinds resolves the aliases in a kind filter.
+func expandKinds(kinds []string) []string {
+	var out []string
+	for _, k := range kinds {
+		if alias, ok := kindAliases[k]; ok {
+			out = append(out, alias...)
+		} else {
+			out = append(out, k)
+		}
+	}
+	return out
 }
 
 // Search performs vector search with reranking.
 // Operates on content hashes to deduplicate across crate versions.
-func (s *Searcher) Search(query string, crateNames []string, threshold float32, limit int, rerankInstruction string) ([]rpc.DocResult, error) {
-	slog.Info("search", "query", query, "threshold", threshold, "limit", limit, "crates", crateNames, "model", s.model)
+func (s *Searcher) Search(req rpc.SearchRequest) ([]rpc.DocResult, *rpc.SearchExplain, error) {
+	query, crateNames, threshold, limit := req.Query, req.Crates, req.Threshold, req.Limit
+	model := s.model.Load().(string)
+	slog.Info("search", "query", query, "threshold", threshold, "limit", limit, "crates", crateNames, "features", req.Features, "model", model)
 
-	queryEmb, err := s.voyage.EmbedSingle(query, s.model)
+	queries := []string{query}
+	if req.Expand {
+		queries = expandQuery(query)
+		slog.Debug("query expanded", "queries", queries)
+	}
+	queryEmbs, err := s.voyage.EmbedTexts(queries, model)
 	if err != nil {
-		return nil, fmt.Errorf("embedding query: %w", err)
+		return nil, nil, fmt.Errorf("embedding query: %w", err)
 	}
-	slog.Debug("query embedded", "dimension", len(queryEmb))
+	slog.Debug("query embedded", "queries", len(queryEmbs), "dimension", len(queryEmbs[0]))
 
-	var crateIDs []int
+	filter := db.Filter{Features: req.Features, Kinds: expandKinds(req.Kinds), Examples: req.ExamplesOnly}
+	scope := s.db.InNamespace(req.Namespace)
 	if len(crateNames) > 0 {
-		crateIDs, err = s.db.GetCrateIDsByNames(crateNames)
+		filter.CrateIDs, err = scope.GetCrateIDsForSpecs(crateNames)
 		if err != nil {
-			return nil, fmt.Errorf("resolving crate names: %w", err)
+			return nil, nil, fmt.Errorf("resolving crate names: %w", err)
 		}
-		slog.Debug("resolved crate names", "names", crateNames, "ids", crateIDs)
+		slog.Debug("resolved crate names", "names", crateNames, "ids", filter.CrateIDs)
+	}
+	if len(req.ExcludeCrates) > 0 {
+		filter.ExcludeCrateIDs, err = scope.GetCrateIDsForSpecs(req.ExcludeCrates)
+		if err != nil {
+			return nil, nil, fmt.Errorf("resolving excluded crates: %w", err)
+		}
+	}
+	filter.ExcludeKinds = expandKinds(req.ExcludeKinds)
+	filter.ExcludeDeprecated = req.ExcludeDeprecated
+	filter.ExcludeUnstable = req.ExcludeUnstable
+	filter, ok, err := scope.Scope(filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return nil, &rpc.SearchExplain{}, nil
 	}
 
-	candidates, err := s.db.VectorSearch(queryEmb, threshold, limit*3, crateIDs)
+	candidates, err := s.vectorSearch(queryEmbs, threshold, limit*3, filter)
 	if err != nil {
-		return nil, fmt.Errorf("vector search: %w", err)
+		return nil, nil, fmt.Errorf("vector search: %w", err)
 	}
 	slog.Debug("vector search done", "candidates", len(candidates))
+	explain := &rpc.SearchExplain{Candidates: len(candidates)}
+	if len(queries) > 1 {
+		explain.Queries = queries
+	}
 	if len(candidates) == 0 {
-		return nil, nil
+		return nil, explain, nil
 	}
 
 	// Resolve representative items for each candidate.
 	type resolvedItem struct {
-		item  *db.Item
-		score float32
+		item     *db.Item
+		score    float32
+		code     string // set for example hits
+		fragment string // set for fragment hits
+		chunk    string
 	}
 	var resolved []resolvedItem
 	var documents []string
 	for _, c := range candidates {
-		item, err := s.db.GetItemForHash(c.ContentHash, crateIDs)
-		if err != nil || item == nil {
+		if req.ExamplesOnly {
+			item, code := s.resolveExample(c.ContentHash, filter)
+			if item == nil {
+				continue
+			}
+			resolved = append(resolved, resolvedItem{item: item, score: c.Similarity * s.itemWeight(item), code: code})
+			documents = append(documents, item.Path+"\n"+code)
+			continue
+		}
+
+		item, fragment := s.resolveHash(c.ContentHash, filter)
+		if item == nil {
 			continue
 		}
 		doc := item.Path
+		if fragment != "" {
+			doc += "#" + fragment
+		}
 		if item.Signature != "" {
 			doc += "\n" + item.Signature
 		}
@@ -81,12 +230,12 @@ func (s *Searcher) Search(query string, crateNames []string, threshold float32,
 			}
 			doc += "\n" + d
 		}
-		resolved = append(resolved, resolvedItem{item: item, score: c.Similarity})
+		resolved = append(resolved, resolvedItem{item: item, score: c.Similarity * s.itemWeight(item), fragment: fragment, chunk: c.ChunkText})
 		documents = append(documents, doc)
 	}
 
 	if len(resolved) == 0 {
-		return nil, nil
+		return nil, explain, nil
 	}
 
 	// Batch-fetch crates for all resolved items.
@@ -100,29 +249,91 @@ func (s *Searcher) Search(query string, crateNames []string, threshold float32,
 		crateMap = nil
 	}
 
-	buildResult := func(item *db.Item, score float32) rpc.DocResult {
-		crateName, crateVersion := "", ""
-		if c := crateMap[item.ID]; c != nil {
-			crateName = c.Name
-			crateVersion = c.Version
+	// Favor widely used crates and their current versions.
+	boosts := s.crateBoosts(crateMap)
+	boost := func(r resolvedItem) float32 {
+		if b, ok := boosts[r.item.ID]; ok {
+			return b
 		}
-		return rpc.DocResult{
-			URI:          fmt.Sprintf("rsdoc://%s/%s/%s", crateName, crateVersion, item.Path),
-			CrateName:    crateName,
-			CrateVersion: crateVersion,
-			Path:         item.Path,
-			Kind:         item.Kind,
-			Score:        score,
-			Snippet:      snippetForItem(item),
+		return 1
+	}
+	for i := range resolved {
+		resolved[i].score *= boost(resolved[i])
+	}
+	weighted := len(s.opts.KindWeights) > 0 || len(boosts) > 0 || s.opts.DeprecatedPenalty > 0
+
+	// Reorder by weighted score so boosted kinds and crates lead the rerank
+	// input and the vector-score fallback.
+	if weighted {
+		order := make([]int, len(resolved))
+		for i := range order {
+			order[i] = i
 		}
+		sort.SliceStable(order, func(a, b int) bool {
+			return resolved[order[a]].score > resolved[order[b]].score
+		})
+		sortedResolved := make([]resolvedItem, len(resolved))
+		sortedDocs := make([]string, len(documents))
+		for i, idx := range order {
+			sortedResolved[i] = resolved[idx]
+			sortedDocs[i] = documents[idx]
+		}
+		resolved, documents = sortedResolved, sortedDocs
 	}
 
-	reranked, err := s.voyage.Rerank(query, documents, s.rerankModel, limit, rerankInstruction)
-	if err != nil {
-		slog.Warn("reranking failed, falling back to vector scores", "error", err)
-		reranked = nil
-	} else {
-		slog.Debug("reranking done", "results", len(reranked))
+	snippetLen := s.snippetLength(req.SnippetLength)
+	terms := queryTerms(query)
+
+	buildResult := func(r resolvedItem, score float32) rpc.DocResult {
+		if r.code != "" {
+			result := itemResult(r.item, crateMap[r.item.ID], score, "", "")
+			if hasFragment(r.item, docs.FragExamples) {
+				result.URI += "#" + docs.FragExamples
+			}
+			result.Snippet = truncate(r.code, snippetLen)
+			result.Code = r.code
+			return result
+		}
+		result := itemResult(r.item, crateMap[r.item.ID], score, r.fragment, r.chunk)
+		result.Snippet = snippetForItem(r.item, r.chunk, terms, snippetLen)
+		return result
+	}
+
+	explain.TopSimilarity = resolved[0].score
+	explain.Margin = resolved[0].score
+	if len(resolved) > 1 {
+		explain.Margin -= resolved[1].score
+	}
+
+	mode := s.opts.Rerank
+	if req.Rerank != nil {
+		mode = RerankOff
+		if *req.Rerank {
+			mode = RerankAlways
+		}
+	}
+
+	var reranked []embeddings.RerankResult
+	reason, skip := s.skipRerank(explain.TopSimilarity, explain.Margin)
+	switch {
+	case mode == RerankOff:
+		explain.RerankSkipped = "rerank disabled"
+	case mode != RerankAlways && skip:
+		slog.Debug("skipping rerank", "reason", reason)
+		explain.RerankSkipped = reason
+	default:
+		// Rank every candidate: grouping may fold several hits into one
+		// entry, and the limit applies afterwards.
+		reranked, err = s.voyage.Rerank(query, documents, s.rerankModel, len(documents), req.RerankInstruction)
+		if err != nil {
+			slog.Warn("reranking failed, falling back to vector scores", "error", err)
+			explain.RerankSkipped = "rerank failed: " + err.Error()
+			explain.RerankError = err.Error()
+			reranked = nil
+		} else {
+			slog.Debug("reranking done", "results", len(reranked))
+			explain.Reranked = len(reranked) > 0
+		}
 	}
 
 	var results []rpc.DocResult
@@ -132,21 +343,187 @@ func (s *Searcher) Search(query string, crateNames []string, threshold float32,
 				continue
 			}
 			r := resolved[rr.OriginalIndex]
-			results = append(results, buildResult(r.item, rr.RelevanceScore))
+			result := buildResult(r, rr.RelevanceScore*s.itemWeight(r.item)*boost(r))
+			result.ScoreKind = rpc.ScoreRerank
+			results = append(results, result)
+		}
+		if weighted {
+			sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
 		}
 	} else {
-		for i, r := range resolved {
-			if i >= limit {
-				break
+		for _, r := range resolved {
+			result := buildResult(r, r.score)
+			result.ScoreKind = rpc.ScoreVector
+			results = append(results, result)
+		}
+	}
+
+	if !req.Flat && !req.ExamplesOnly {
+		results = groupResults(results)
+	}
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, explain, nil
+}
+
+// vectorSearch runs a vector search for each query embedding and merges
+// the candidates, keeping each content hash's best match. The merged set is
+// capped at twice limit, so variants add candidates without making the
+// rerank call much larger.
+func (s *Searcher) vectorSearch(queryEmbs [][]float32, threshold float32, limit int, filter db.Filter) ([]db.SearchResult, error) {
+	if len(queryEmbs) == 1 {
+		return s.db.VectorSearch(queryEmbs[0], threshold, limit, filter)
+	}
+	best := make(map[string]db.SearchResult)
+	for _, emb := range queryEmbs {
+		results, err := s.db.VectorSearch(emb, threshold, limit, filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range results {
+			if prev, ok := best[r.ContentHash]; !ok || r.Similarity > prev.Similarity {
+				best[r.ContentHash] = r
 			}
-			results = append(results, buildResult(r.item, r.score))
 		}
 	}
+	merged := make([]db.SearchResult, 0, len(best))
+	for _, r := range best {
+		merged = append(merged, r)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i].Similarity != merged[j].Similarity {
+			return merged[i].Similarity > merged[j].Similarity
+		}
+		return merged[i].ContentHash < merged[j].ContentHash
+	})
+	if len(merged) > 2*limit {
+		merged = merged[:2*limit]
+	}
+	return merged, nil
+}
+
+// snippetLength returns the snippet length to use for a request asking for
+// requested bytes (0 for the configured default).
+func (s *Searcher) snippetLength(requested int) int {
+	if requested > 0 {
+		return requested
+	}
+	if s.opts.SnippetLength > 0 {
+		return s.opts.SnippetLength
+	}
+	return DefaultSnippetLength
+}
+
+// itemResult builds the result for a hit on item, or on its fragment when
+// fragment is set, leaving the snippet to the caller. c may be nil if the
+// crate lookup failed.
+func itemResult(item *db.Item, c *db.Crate, score float32, fragment, chunk string) rpc.DocResult {
+	crateName, crateVersion := "", ""
+	if c != nil {
+		crateName = c.Name
+		crateVersion = c.Version
+	}
+	uri := fmt.Sprintf("rsdoc://%s/%s/%s", crateName, crateVersion, item.Path)
+	if fragment != "" {
+		uri += "#" + fragment
+	}
+	return rpc.DocResult{
+		URI:          uri,
+		CrateName:    crateName,
+		CrateVersion: crateVersion,
+		Path:         item.Path,
+		Kind:         item.Kind,
+		Score:        score,
+		Features:     decodeFeatures(item.Features),
+		Deprecated:   item.Deprecation != "",
+		Stability:    decodeStability(item.Stability),
+		Fragment:     fragment,
+		Chunk:        chunk,
+	}
+}
+
+// resolveHash returns the item whose docs, or one of whose fragments, were
+// embedded under contentHash, plus the fragment name for fragment hits. A
+// hit on a crate's metadata resolves to its root module, as kind
+// db.KindCrate.
+func (s *Searcher) resolveHash(contentHash string, filter db.Filter) (*db.Item, string) {
+	item, err := s.db.GetItemForHash(contentHash, filter)
+	if err != nil {
+		return nil, ""
+	}
+	if item != nil {
+		return item, ""
+	}
+	frag, err := s.db.GetFragmentForHash(contentHash, filter)
+	if err != nil {
+		return nil, ""
+	}
+	if frag == nil {
+		return s.resolveCrateMeta(contentHash, filter), ""
+	}
+	item, err = s.db.GetItem(frag.ItemID)
+	if err != nil || item == nil {
+		return nil, ""
+	}
+	return item, frag.Name
+}
+
+// resolveCrateMeta returns the root module of the crate whose metadata was
+// embedded under contentHash, reported as kind db.KindCrate.
+func (s *Searcher) resolveCrateMeta(contentHash string, filter db.Filter) *db.Item {
+	meta, err := s.db.GetCrateMetaForHash(contentHash, filter)
+	if err != nil || meta == nil {
+		return nil
+	}
+	root, err := s.db.GetRootItem(meta.CrateID)
+	if err != nil || root == nil {
+		return nil
+	}
+	root.Kind = db.KindCrate
+	return root
+}
 
-	return results, nil
+// resolveExample returns the parent item and code for an example content hash.
+func (s *Searcher) resolveExample(contentHash string, filter db.Filter) (*db.Item, string) {
+	ex, err := s.db.GetExampleForHash(contentHash, filter)
+	if err != nil || ex == nil {
+		return nil, ""
+	}
+	item, err := s.db.GetItem(ex.ItemID)
+	if err != nil || item == nil {
+		return nil, ""
+	}
+	code, err := cas.Read(contentHash)
+	if err != nil {
+		slog.Warn("failed to read example", "hash", contentHash, "error", err)
+		return nil, ""
+	}
+	return item, code
+}
+
+func hasFragment(item *db.Item, name string) bool {
+	if item.FragmentNames == "" {
+		return false
+	}
+	var names []string
+	if err := json.Unmarshal([]byte(item.FragmentNames), &names); err != nil {
+		return false
+	}
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
 }
 
-func snippetForItem(item *db.Item) string {
+// snippetForItem builds a snippet from the chunk that matched, falling back
+// to the item's docs when the chunk has no body beyond its preamble.
+func snippetForItem(item *db.Item, chunk string, terms []string, maxLen int) string {
+	if _, body, ok := strings.Cut(chunk, "\n\n"); ok && strings.TrimSpace(body) != "" {
+		return makeSnippet(body, terms, maxLen)
+	}
 	if item.ContentHash == "" {
 		return ""
 	}
@@ -155,7 +532,7 @@ func snippetForItem(item *db.Item) string {
 		return ""
 	}
 	docsText = rewriteItemLinks(docsText, item.DocLinks)
-	return truncate(docsText, 200)
+	return makeSnippet(docsText, terms, maxLen)
 }
 
 func rewriteItemLinks(text, docLinksJSON string) string {
@@ -169,6 +546,28 @@ func rewriteItemLinks(text, docLinksJSON string) string {
 	return md.RewriteLinks(text, linkMap)
 }
 
+func decodeFeatures(featuresJSON string) []string {
+	if featuresJSON == "" {
+		return nil
+	}
+	var features []string
+	if err := json.Unmarshal([]byte(featuresJSON), &features); err != nil {
+		return nil
+	}
+	return features
+}
+
+func decodeStability(stabilityJSON string) string {
+	if stabilityJSON == "" {
+		return ""
+	}
+	var stab docs.Stability
+	if err := json.Unmarshal([]byte(stabilityJSON), &stab); err != nil {
+		return ""
+	}
+	return stab.Label()
+}
+
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
@@ -0,0 +1,99 @@
+package search
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/jcdickinson/ferrisfetch/internal/db"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+)
+
+// Similar returns the items closest to the docs stored under contentHash
+// (item's own docs or one of its fragments), using the centroid of their
+// chunk embeddings as the query vector so no embedding request is made.
+// item itself is left out; namespace and crateSpecs restrict results like
+// SearchRequest.Namespace and Crates.
+func (s *Searcher) Similar(namespace string, item *db.Item, contentHash string, crateSpecs []string, limit int) ([]rpc.DocResult, error) {
+	vecs, err := s.db.EmbeddingsForHash(contentHash)
+	if err != nil {
+		return nil, err
+	}
+	if len(vecs) == 0 {
+		return nil, fmt.Errorf("%s has no embeddings", item.Path)
+	}
+	centroid := make([]float32, len(vecs[0]))
+	for _, v := range vecs {
+		for i, x := range v {
+			centroid[i] += x
+		}
+	}
+	var norm float64
+	for _, x := range centroid {
+		norm += float64(x) * float64(x)
+	}
+	if norm == 0 {
+		return nil, fmt.Errorf("%s has a zero embedding", item.Path)
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for i := range centroid {
+		centroid[i] *= scale
+	}
+
+	var filter db.Filter
+	scope := s.db.InNamespace(namespace)
+	if len(crateSpecs) > 0 {
+		filter.CrateIDs, err = scope.GetCrateIDsForSpecs(crateSpecs)
+		if err != nil {
+			return nil, fmt.Errorf("resolving crate names: %w", err)
+		}
+	}
+	filter, ok, err := scope.Scope(filter)
+	if err != nil || !ok {
+		return nil, err
+	}
+	// Over-fetch: the item's other fragments and repeated items are dropped.
+	candidates, err := s.db.VectorSearch(centroid, 0, (limit+1)*3, filter)
+	if err != nil {
+		return nil, fmt.Errorf("vector search: %w", err)
+	}
+
+	type hit struct {
+		item     *db.Item
+		score    float32
+		fragment string
+		chunk    string
+	}
+	var hits []hit
+	seen := map[int]bool{item.ID: true}
+	for _, c := range candidates {
+		if len(hits) >= limit {
+			break
+		}
+		other, fragment := s.resolveHash(c.ContentHash, filter)
+		if other == nil || seen[other.ID] {
+			continue
+		}
+		seen[other.ID] = true
+		hits = append(hits, hit{item: other, score: c.Similarity, fragment: fragment, chunk: c.ChunkText})
+	}
+	if len(hits) == 0 {
+		return nil, nil
+	}
+
+	itemIDs := make([]int, len(hits))
+	for i, h := range hits {
+		itemIDs[i] = h.item.ID
+	}
+	crateMap, err := s.db.GetCratesForItems(itemIDs)
+	if err != nil {
+		return nil, fmt.Errorf("batch crate lookup: %w", err)
+	}
+	snippetLen := s.snippetLength(0)
+	results := make([]rpc.DocResult, len(hits))
+	for i, h := range hits {
+		results[i] = itemResult(h.item, crateMap[h.item.ID], h.score, h.fragment, h.chunk)
+		results[i].Snippet = snippetForItem(h.item, h.chunk, nil, snippetLen)
+		results[i].ScoreKind = rpc.ScoreVector
+	}
+	return results, nil
+}
@@ -0,0 +1,162 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultSnippetLength is the snippet length used when neither the request
+// nor search.snippet_length sets one.
+const DefaultSnippetLength = 200
+
+// stopWords are query words too common to be worth highlighting.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "can": true, "do": true, "for": true, "from": true,
+	"how": true, "i": true, "in": true, "is": true, "it": true, "of": true,
+	"on": true, "or": true, "the": true, "to": true, "use": true, "what": true,
+	"with": true,
+}
+
+// queryTerms returns the distinct lowercase words of query worth matching.
+func queryTerms(query string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToLower(query), notWordRune) {
+		if len(w) < 2 || stopWords[w] || seen[w] {
+			continue
+		}
+		seen[w] = true
+		terms = append(terms, w)
+	}
+	return terms
+}
+
+func notWordRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+}
+
+// makeSnippet picks the sentence of text that mentions the most query terms,
+// extends it with the sentences after it up to maxLen bytes and highlights
+// the terms in bold. Without matches it falls back to the start of text.
+func makeSnippet(text string, terms []string, maxLen int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return ""
+	}
+	sentences := splitSentences(text)
+	best, bestScore := 0, 0
+	for i, s := range sentences {
+		if score := countTerms(s, terms); score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+
+	snippet := strings.Join(sentences[best:], " ")
+	prefix := ""
+	if best > 0 {
+		prefix = "..."
+	}
+	return prefix + highlight(truncateWords(snippet, maxLen), terms)
+}
+
+// splitSentences splits whitespace-normalized text after sentence-ending
+// punctuation.
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	for i := 0; i < len(text)-1; i++ {
+		if (text[i] == '.' || text[i] == '!' || text[i] == '?') && text[i+1] == ' ' {
+			sentences = append(sentences, text[start:i+1])
+			start = i + 2
+		}
+	}
+	return append(sentences, text[start:])
+}
+
+// countTerms returns how many distinct terms occur in s.
+func countTerms(s string, terms []string) int {
+	lower := strings.ToLower(s)
+	n := 0
+	for _, t := range terms {
+		if wordIndex(lower, t, 0) >= 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// wordIndex finds term in lower at or after from, starting at a word
+// boundary so "spawn" matches "spawning" but not "respawn".
+func wordIndex(lower, term string, from int) int {
+	for from <= len(lower) {
+		i := strings.Index(lower[from:], term)
+		if i < 0 {
+			return -1
+		}
+		i += from
+		if i == 0 {
+			return i
+		}
+		if r, _ := utf8.DecodeLastRuneInString(lower[:i]); notWordRune(r) {
+			return i
+		}
+		from = i + 1
+	}
+	return -1
+}
+
+// highlight wraps each word that starts with a term in ** markers.
+func highlight(s string, terms []string) string {
+	if len(terms) == 0 {
+		return s
+	}
+	lower := strings.ToLower(s)
+	if len(lower) != len(s) {
+		// Case folding changed byte offsets; don't risk misplaced markers.
+		return s
+	}
+	var b strings.Builder
+	pos := 0
+	for pos < len(s) {
+		at, end := -1, 0
+		for _, t := range terms {
+			if i := wordIndex(lower, t, pos); i >= 0 && (at < 0 || i < at) {
+				at, end = i, i+len(t)
+			}
+		}
+		if at < 0 {
+			break
+		}
+		// Extend to the end of the word.
+		for end < len(s) {
+			r, size := utf8.DecodeRuneInString(s[end:])
+			if notWordRune(r) {
+				break
+			}
+			end += size
+		}
+		b.WriteString(s[pos:at])
+		b.WriteString("**" + s[at:end] + "**")
+		pos = end
+	}
+	b.WriteString(s[pos:])
+	return b.String()
+}
+
+// truncateWords shortens s to at most maxLen bytes, cutting at a word
+// boundary when one is close, and marks the cut with "...".
+func truncateWords(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	cut := maxLen
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	if space := strings.LastIndexByte(s[:cut], ' '); space > maxLen/2 {
+		cut = space
+	}
+	return strings.TrimRight(s[:cut], " ") + "..."
+}
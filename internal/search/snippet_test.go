@@ -0,0 +1,50 @@
+package search
+
+import "testing"
+
+func TestQueryTerms(t *testing.T) {
+	got := queryTerms("How to spawn a task, spawn_blocking?")
+	want := []string{"spawn", "task", "spawn_blocking"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("term %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestMakeSnippet(t *testing.T) {
+	text := "A runtime for writing reliable applications.\n\nUse spawn to start a new Task. Tasks are cheap."
+	terms := queryTerms("spawn task")
+
+	t.Run("best_sentence", func(t *testing.T) {
+		got := makeSnippet(text, terms, 200)
+		want := "...Use **spawn** to start a new **Task**. **Tasks** are cheap."
+		if got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("word_start_only", func(t *testing.T) {
+		got := makeSnippet("Respawn the worker.", queryTerms("spawn"), 200)
+		if got != "Respawn the worker." {
+			t.Errorf("got %q", got)
+		}
+	})
+
+	t.Run("no_match_uses_start", func(t *testing.T) {
+		got := makeSnippet(text, queryTerms("mutex"), 20)
+		if got != "A runtime for..." {
+			t.Errorf("got %q", got)
+		}
+	})
+
+	t.Run("truncates_utf8_safely", func(t *testing.T) {
+		got := makeSnippet("ééééé", nil, 3)
+		if got != "é..." {
+			t.Errorf("got %q", got)
+		}
+	})
+}
@@ -0,0 +1,36 @@
+package search
+
+import (
+	"fmt"
+
+	"github.com/jcdickinson/ferrisfetch/internal/db"
+	"github.com/jcdickinson/ferrisfetch/internal/rpc"
+)
+
+// Symbols looks items up by name or path (see db.FindItems). Unlike Search
+// it needs no embedding provider, so it suits editors resolving the symbol
+// under the cursor.
+func (s *Searcher) Symbols(req rpc.SymbolsRequest) ([]rpc.DocResult, error) {
+	var filter db.Filter
+	scope := s.db.InNamespace(req.Namespace)
+	if len(req.Crates) > 0 {
+		ids, err := scope.GetCrateIDsForSpecs(req.Crates)
+		if err != nil {
+			return nil, fmt.Errorf("resolving crate names: %w", err)
+		}
+		if len(ids) == 0 {
+			return nil, nil
+		}
+		filter.CrateIDs = ids
+	}
+
+	matches, err := scope.FindItems(req.Query, req.Exact, req.Limit, filter)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]rpc.DocResult, 0, len(matches))
+	for _, m := range matches {
+		results = append(results, itemResult(&m.Item, &db.Crate{Name: m.CrateName, Version: m.CrateVersion}, 0, "", ""))
+	}
+	return results, nil
+}
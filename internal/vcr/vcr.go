@@ -0,0 +1,199 @@
+// Package vcr records and replays outbound HTTP interactions so docs.rs,
+// crates.io and Voyage traffic can be captured as fixtures for offline tests
+// and reproducible bug reports.
+package vcr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+type Mode string
+
+const (
+	// ModeOff passes requests through untouched.
+	ModeOff Mode = ""
+	// ModeRecord performs requests and saves every response.
+	ModeRecord Mode = "record"
+	// ModeReplay serves saved responses and fails requests that weren't recorded.
+	ModeReplay Mode = "replay"
+)
+
+// ParseMode validates a mode name from config.
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(strings.ToLower(strings.TrimSpace(s))); m {
+	case ModeOff, "off":
+		return ModeOff, nil
+	case ModeRecord, ModeReplay:
+		return m, nil
+	}
+	return ModeOff, fmt.Errorf("unknown vcr mode %q (want record, replay or off)", s)
+}
+
+// Recording is one captured request/response pair as stored on disk.
+// Request headers are not stored so API keys never end up in fixtures.
+type Recording struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	RequestBody string      `json:"request_body,omitempty"`
+	Status      int         `json:"status"`
+	Header      http.Header `json:"header"`
+	Body        []byte      `json:"body"`
+}
+
+// Transport is an http.RoundTripper that records to or replays from dir.
+type Transport struct {
+	mode     Mode
+	dir      string
+	next     http.RoundTripper
+	compress bool
+}
+
+// New returns a Transport wrapping next (http.DefaultTransport if nil).
+func New(mode Mode, dir string, next http.RoundTripper) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Transport{mode: mode, dir: dir, next: next}
+}
+
+// NewCompressed is like New but saves recordings zstd-compressed, as
+// <hash>.json.zst, for fixtures checked into a repository. Any Transport
+// replays recordings saved either way.
+func NewCompressed(mode Mode, dir string, next http.RoundTripper) *Transport {
+	t := New(mode, dir, next)
+	t.compress = true
+	return t
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.mode == ModeOff {
+		return t.next.RoundTrip(req)
+	}
+
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("vcr: reading request body: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+	path := t.path(req, reqBody)
+
+	if t.mode == ModeReplay {
+		data, err := readRecording(path)
+		if err != nil {
+			return nil, fmt.Errorf("vcr: no recording for %s %s (%s): %w", req.Method, req.URL, path, err)
+		}
+		var rec Recording
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return nil, fmt.Errorf("vcr: decoding %s: %w", path, err)
+		}
+		return rec.response(req), nil
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("vcr: reading response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	rec := Recording{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		RequestBody: string(reqBody),
+		Status:      resp.StatusCode,
+		Header:      resp.Header,
+		Body:        body,
+	}
+	if t.compress {
+		path += ".zst"
+	}
+	if err := rec.save(path); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// path returns the fixture file for a request: dir/<host>/<hash>.json, where
+// the hash covers the method, URL and body. Compressed recordings add ".zst".
+func (t *Transport) path(req *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", req.Method, req.URL.String())
+	h.Write(body)
+	return filepath.Join(t.dir, req.URL.Host, hex.EncodeToString(h.Sum(nil))[:24]+".json")
+}
+
+// readRecording reads the recording at path, or failing that its
+// compressed form at path.zst.
+func readRecording(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err == nil || !os.IsNotExist(err) {
+		return data, err
+	}
+	compressed, zerr := os.ReadFile(path + ".zst")
+	if zerr != nil {
+		return nil, err
+	}
+	dec, zerr := zstd.NewReader(nil)
+	if zerr != nil {
+		return nil, zerr
+	}
+	defer dec.Close()
+	return dec.DecodeAll(compressed, nil)
+}
+
+// save writes the recording to path, compressed if path ends in ".zst".
+func (r *Recording) save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("vcr: encoding recording: %w", err)
+	}
+	if strings.HasSuffix(path, ".zst") {
+		enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+		if err != nil {
+			return fmt.Errorf("vcr: compressing recording: %w", err)
+		}
+		data = enc.EncodeAll(data, nil)
+		enc.Close()
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("vcr: creating fixture directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("vcr: writing %s: %w", path, err)
+	}
+	return nil
+}
+
+func (r *Recording) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.Status, http.StatusText(r.Status)),
+		StatusCode:    r.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,104 @@
+package vcr
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordReplay(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Echo", string(body))
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("hello " + string(body)))
+	}))
+
+	do := func(client *http.Client, body string) (*http.Response, string) {
+		t.Helper()
+		req, err := http.NewRequest("POST", srv.URL+"/x", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp, string(data)
+	}
+
+	recorder := &http.Client{Transport: New(ModeRecord, dir, nil)}
+	if _, got := do(recorder, "a"); got != "hello a" {
+		t.Fatalf("record: got %q", got)
+	}
+	srv.Close()
+
+	replayer := &http.Client{Transport: New(ModeReplay, dir, nil)}
+	resp, got := do(replayer, "a")
+	if got != "hello a" || resp.StatusCode != http.StatusTeapot || resp.Header.Get("X-Echo") != "a" {
+		t.Errorf("replay: got %d %q %v", resp.StatusCode, got, resp.Header)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 upstream call, got %d", calls)
+	}
+
+	req, _ := http.NewRequest("POST", srv.URL+"/x", strings.NewReader("b"))
+	if _, err := replayer.Do(req); err == nil || !strings.Contains(err.Error(), "no recording") {
+		t.Errorf("expected missing recording error, got %v", err)
+	}
+}
+
+func TestRecordReplay_Compressed(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("docs ", 100)))
+	}))
+
+	get := func(rt http.RoundTripper) string {
+		t.Helper()
+		resp, err := (&http.Client{Transport: rt}).Get(srv.URL + "/crate")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return string(data)
+	}
+
+	get(NewCompressed(ModeRecord, dir, nil))
+	srv.Close()
+	files, _ := filepath.Glob(filepath.Join(dir, "*", "*"))
+	if len(files) != 1 || !strings.HasSuffix(files[0], ".json.zst") {
+		t.Fatalf("recorded %v, want one .json.zst", files)
+	}
+
+	// A plain transport replays compressed recordings too.
+	if got := get(New(ModeReplay, dir, nil)); got != strings.Repeat("docs ", 100) {
+		t.Errorf("replay: got %q", got)
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	t.Parallel()
+	for in, want := range map[string]Mode{"": ModeOff, "off": ModeOff, "Record": ModeRecord, "replay": ModeReplay} {
+		got, err := ParseMode(in)
+		if err != nil || got != want {
+			t.Errorf("ParseMode(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := ParseMode("rewind"); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}